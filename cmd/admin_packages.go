@@ -0,0 +1,62 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"fmt"
+
+	vulnerability_service "code.gitea.io/gitea/services/packages/vulnerability"
+
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	subcmdPackages = &cli.Command{
+		Name:  "packages",
+		Usage: "Manage the package registry",
+		Subcommands: []*cli.Command{
+			microcmdPackagesImportAdvisories,
+		},
+	}
+
+	microcmdPackagesImportAdvisories = &cli.Command{
+		Name:   "import-advisories",
+		Usage:  "Import OSV advisories from a JSON file, a zip archive or a directory",
+		Action: runPackagesImportAdvisories,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "path",
+				Usage:    "Path of the OSV dump",
+				Required: true,
+			},
+			&cli.BoolFlag{
+				Name:  "scan",
+				Usage: "Match the packages against the advisories after the import instead of waiting for the scheduled task",
+			},
+		},
+	}
+)
+
+func runPackagesImportAdvisories(c *cli.Context) error {
+	ctx, cancel := installSignals()
+	defer cancel()
+
+	if err := initDB(ctx); err != nil {
+		return err
+	}
+
+	count, err := vulnerability_service.ImportAdvisories(ctx, c.String("path"))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d new or updated advisories\n", count)
+
+	if c.Bool("scan") {
+		if err := vulnerability_service.ScanTask(ctx); err != nil {
+			return err
+		}
+		fmt.Println("Matched packages against the advisories")
+	}
+	return nil
+}
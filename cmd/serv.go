@@ -262,7 +262,10 @@ func runServ(c *cli.Context) error {
 		}
 	}
 
-	results, extra := private.ServCommand(ctx, keyID, username, reponame, requestedMode, verb, lfsVerb)
+	// SSH_CONNECTION is provided by OpenSSH and the builtin SSH server: "<client ip> <client port> <server ip> <server port>"
+	remoteIP, _, _ := strings.Cut(os.Getenv("SSH_CONNECTION"), " ")
+
+	results, extra := private.ServCommand(ctx, keyID, username, reponame, requestedMode, remoteIP, verb, lfsVerb)
 	if extra.HasError() {
 		return fail(ctx, extra.UserMsg, "ServCommand failed: %s", extra.Error)
 	}
@@ -65,6 +65,7 @@ const (
 	ActionPullReviewDismissed                             // 25
 	ActionPullRequestReadyForReview                       // 26
 	ActionAutoMergePullRequest                            // 27
+	ActionFederatedPush                                   // 28
 )
 
 func (at ActionType) String() string {
@@ -123,6 +124,8 @@ func (at ActionType) String() string {
 		return "pull_request_ready_for_review"
 	case ActionAutoMergePullRequest:
 		return "auto_merge_pull_request"
+	case ActionFederatedPush:
+		return "federated_push"
 	default:
 		return "action-" + strconv.Itoa(int(at))
 	}
@@ -0,0 +1,193 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package audit
+
+import (
+	"context"
+	"time"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/builder"
+)
+
+// Action is the kind of a security-relevant event
+type Action string
+
+// The actions recorded in the audit log
+const (
+	ActionUserSignUp               Action = "user.signup"
+	ActionUserAdminGrant           Action = "user.admin.grant"
+	ActionUserAdminRevoke          Action = "user.admin.revoke"
+	ActionUserDelete               Action = "user.delete"
+	ActionAccessTokenCreate        Action = "user.access_token.create"
+	ActionAccessTokenDelete        Action = "user.access_token.delete"
+	ActionPublicKeyAdd             Action = "user.public_key.add"
+	ActionPublicKeyDelete          Action = "user.public_key.delete"
+	ActionGPGKeyAdd                Action = "user.gpg_key.add"
+	ActionGPGKeyDelete             Action = "user.gpg_key.delete"
+	ActionActivityPubKeyRotate     Action = "user.activitypub_key.rotate"
+	ActionRepoCreate               Action = "repo.create"
+	ActionRepoDelete               Action = "repo.delete"
+	ActionRepoTransfer             Action = "repo.transfer"
+	ActionRepoRename               Action = "repo.rename"
+	ActionRepoVisibility           Action = "repo.visibility"
+	ActionRepoCollaboratorAdd      Action = "repo.collaborator.add"
+	ActionRepoCollaboratorRemove   Action = "repo.collaborator.remove"
+	ActionRepoCollaboratorMode     Action = "repo.collaborator.mode"
+	ActionBranchProtectionUpdate   Action = "repo.branch_protection.update"
+	ActionBranchProtectionDelete   Action = "repo.branch_protection.delete"
+	ActionTeamMemberAdd            Action = "org.team.member.add"
+	ActionTeamMemberRemove         Action = "org.team.member.remove"
+	ActionOrgTwoFactorPolicy       Action = "org.two_factor_policy.update"
+	ActionOrgIPAllowlist           Action = "org.ip_allowlist.update"
+	ActionFederationHostRuleUpdate Action = "federation.host_rule.update"
+	ActionFederationHostRuleDelete Action = "federation.host_rule.delete"
+)
+
+// Actions returns all actions recorded in the audit log
+func Actions() []Action {
+	return []Action{
+		ActionUserSignUp,
+		ActionUserAdminGrant,
+		ActionUserAdminRevoke,
+		ActionUserDelete,
+		ActionAccessTokenCreate,
+		ActionAccessTokenDelete,
+		ActionPublicKeyAdd,
+		ActionPublicKeyDelete,
+		ActionGPGKeyAdd,
+		ActionGPGKeyDelete,
+		ActionActivityPubKeyRotate,
+		ActionRepoCreate,
+		ActionRepoDelete,
+		ActionRepoTransfer,
+		ActionRepoRename,
+		ActionRepoVisibility,
+		ActionRepoCollaboratorAdd,
+		ActionRepoCollaboratorRemove,
+		ActionRepoCollaboratorMode,
+		ActionBranchProtectionUpdate,
+		ActionBranchProtectionDelete,
+		ActionTeamMemberAdd,
+		ActionTeamMemberRemove,
+		ActionOrgTwoFactorPolicy,
+		ActionOrgIPAllowlist,
+		ActionFederationHostRuleUpdate,
+		ActionFederationHostRuleDelete,
+	}
+}
+
+// The types of the targets of audit events
+const (
+	TargetTypeUser               = "user"
+	TargetTypeOrganization       = "organization"
+	TargetTypeRepository         = "repository"
+	TargetTypeAccessToken        = "access_token"
+	TargetTypePublicKey          = "public_key"
+	TargetTypeGPGKey             = "gpg_key"
+	TargetTypeProtectedBranch    = "protected_branch"
+	TargetTypeTeam               = "team"
+	TargetTypeFederationHostRule = "federation_host_rule"
+)
+
+// Change is the old and the new value of a changed field
+type Change struct {
+	Old any `json:"old,omitempty"`
+	New any `json:"new,omitempty"`
+}
+
+// HasOld returns true if the field had a value before the change
+func (c Change) HasOld() bool {
+	return c.Old != nil
+}
+
+// Diff are the changed fields of the target of an event by field name
+type Diff map[string]Change
+
+// Event is a persisted security-relevant event
+type Event struct {
+	ID     int64  `xorm:"pk autoincr"`
+	Action Action `xorm:"VARCHAR(64) INDEX NOT NULL"`
+	// ActorID is 0 for events which aren't caused by a signed-in user, e.g. cron tasks,
+	// the name is kept to identify the actor after the account is deleted.
+	ActorID   int64  `xorm:"INDEX NOT NULL DEFAULT 0"`
+	ActorName string `xorm:"NOT NULL DEFAULT ''"`
+	IPAddress string `xorm:"VARCHAR(64) NOT NULL DEFAULT ''"`
+	// OwnerID is the user or the organization whose audit log contains the event,
+	// 0 for events which are only visible to site administrators.
+	OwnerID     int64              `xorm:"INDEX NOT NULL DEFAULT 0"`
+	TargetType  string             `xorm:"VARCHAR(32) INDEX NOT NULL DEFAULT ''"`
+	TargetID    int64              `xorm:"NOT NULL DEFAULT 0"`
+	TargetName  string             `xorm:"NOT NULL DEFAULT ''"`
+	Diff        Diff               `xorm:"TEXT JSON"`
+	CreatedUnix timeutil.TimeStamp `xorm:"INDEX created"`
+}
+
+func init() {
+	db.RegisterModel(new(Event))
+}
+
+// TableName sets the table name to `audit_event`
+func (e *Event) TableName() string {
+	return "audit_event"
+}
+
+// InsertEvent persists an audit event
+func InsertEvent(ctx context.Context, e *Event) error {
+	return db.Insert(ctx, e)
+}
+
+// FindEventsOptions contain the filter options of audit events
+type FindEventsOptions struct {
+	db.ListOptions
+	Action     Action
+	ActorID    int64
+	ActorName  string
+	OwnerID    int64
+	TargetType string
+	Since      timeutil.TimeStamp
+	Until      timeutil.TimeStamp
+}
+
+func (opts FindEventsOptions) ToConds() builder.Cond {
+	cond := builder.NewCond()
+	if opts.Action != "" {
+		cond = cond.And(builder.Eq{"action": opts.Action})
+	}
+	if opts.ActorID != 0 {
+		cond = cond.And(builder.Eq{"actor_id": opts.ActorID})
+	}
+	if opts.ActorName != "" {
+		cond = cond.And(builder.Eq{"actor_name": opts.ActorName})
+	}
+	if opts.OwnerID != 0 {
+		cond = cond.And(builder.Eq{"owner_id": opts.OwnerID})
+	}
+	if opts.TargetType != "" {
+		cond = cond.And(builder.Eq{"target_type": opts.TargetType})
+	}
+	if opts.Since != 0 {
+		cond = cond.And(builder.Gte{"created_unix": opts.Since})
+	}
+	if opts.Until != 0 {
+		cond = cond.And(builder.Lt{"created_unix": opts.Until})
+	}
+	return cond
+}
+
+func (opts FindEventsOptions) ToOrders() string {
+	return "created_unix DESC, id DESC"
+}
+
+// DeleteOldEvents deletes the audit events older than the given duration
+func DeleteOldEvents(ctx context.Context, olderThan time.Duration) error {
+	if olderThan <= 0 {
+		return nil
+	}
+
+	_, err := db.GetEngine(ctx).Where(builder.Lt{"created_unix": time.Now().Add(-olderThan).Unix()}).Delete(new(Event))
+	return err
+}
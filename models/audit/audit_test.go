@@ -0,0 +1,67 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package audit_test
+
+import (
+	"testing"
+	"time"
+
+	audit_model "code.gitea.io/gitea/models/audit"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindEvents(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	events := []*audit_model.Event{
+		{Action: audit_model.ActionUserAdminGrant, ActorID: 1, ActorName: "user1", TargetType: audit_model.TargetTypeUser, TargetID: 2, TargetName: "user2", Diff: audit_model.Diff{"is_admin": {Old: false, New: true}}},
+		{Action: audit_model.ActionRepoVisibility, ActorID: 2, ActorName: "user2", OwnerID: 3, TargetType: audit_model.TargetTypeRepository, TargetID: 3, TargetName: "org3/repo3"},
+		{Action: audit_model.ActionTeamMemberAdd, ActorID: 2, ActorName: "user2", OwnerID: 3, TargetType: audit_model.TargetTypeTeam, TargetID: 1, TargetName: "Owners"},
+	}
+	for _, e := range events {
+		require.NoError(t, audit_model.InsertEvent(db.DefaultContext, e))
+	}
+
+	found, count, err := db.FindAndCount[audit_model.Event](db.DefaultContext, audit_model.FindEventsOptions{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, count)
+	assert.Len(t, found, 3)
+
+	found, err = db.Find[audit_model.Event](db.DefaultContext, audit_model.FindEventsOptions{OwnerID: 3})
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	// newest first
+	assert.Equal(t, audit_model.ActionTeamMemberAdd, found[0].Action)
+	assert.Equal(t, audit_model.ActionRepoVisibility, found[1].Action)
+
+	found, err = db.Find[audit_model.Event](db.DefaultContext, audit_model.FindEventsOptions{Action: audit_model.ActionUserAdminGrant})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, audit_model.Diff{"is_admin": {Old: false, New: true}}, found[0].Diff)
+
+	found, err = db.Find[audit_model.Event](db.DefaultContext, audit_model.FindEventsOptions{ActorName: "user2", TargetType: audit_model.TargetTypeTeam})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "Owners", found[0].TargetName)
+
+	found, err = db.Find[audit_model.Event](db.DefaultContext, audit_model.FindEventsOptions{Since: timeutil.TimeStampNow() + 60})
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	require.NoError(t, audit_model.DeleteOldEvents(db.DefaultContext, time.Hour))
+	unittest.AssertCount(t, &audit_model.Event{}, 3)
+
+	old := &audit_model.Event{Action: audit_model.ActionUserSignUp, ActorID: 8, ActorName: "user8"}
+	require.NoError(t, audit_model.InsertEvent(db.DefaultContext, old))
+	_, err = db.GetEngine(db.DefaultContext).Exec("UPDATE audit_event SET created_unix = ? WHERE id = ?", timeutil.TimeStampNow().AddDuration(-2*time.Hour), old.ID)
+	require.NoError(t, err)
+	require.NoError(t, audit_model.DeleteOldEvents(db.DefaultContext, time.Hour))
+	unittest.AssertCount(t, &audit_model.Event{}, 3)
+	unittest.AssertNotExistsBean(t, &audit_model.Event{ID: old.ID})
+}
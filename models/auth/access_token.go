@@ -9,6 +9,7 @@ import (
 	"crypto/subtle"
 	"encoding/hex"
 	"fmt"
+	"slices"
 	"time"
 
 	"code.gitea.io/gitea/models/db"
@@ -69,6 +70,15 @@ type AccessToken struct {
 	TokenLastEight string `xorm:"INDEX token_last_eight"`
 	Scope          AccessTokenScope
 
+	// A fine-grained token is restricted to the repositories of an organization or to an explicit list of repositories
+	// and only grants its permissions on these repositories.
+	OrgID       int64                  `xorm:"NOT NULL DEFAULT 0"`
+	RepoIDs     []int64                `xorm:"-"`
+	Permissions AccessTokenPermissions `xorm:"TEXT JSON"`
+
+	ExpiresUnix        timeutil.TimeStamp `xorm:"INDEX NOT NULL DEFAULT 0"`
+	ExpiryNotifiedUnix timeutil.TimeStamp `xorm:"NOT NULL DEFAULT 0"`
+
 	CreatedUnix       timeutil.TimeStamp `xorm:"INDEX created"`
 	UpdatedUnix       timeutil.TimeStamp `xorm:"INDEX updated"`
 	HasRecentActivity bool               `xorm:"-"`
@@ -96,6 +106,33 @@ func init() {
 	})
 }
 
+// IsFineGrained returns true if the token is restricted to specific repositories
+func (t *AccessToken) IsFineGrained() bool {
+	return len(t.Permissions) > 0
+}
+
+// HasExpiry returns true if the token has an expiration date
+func (t *AccessToken) HasExpiry() bool {
+	return t.ExpiresUnix > 0
+}
+
+// IsExpired returns true if the token can't be used anymore
+func (t *AccessToken) IsExpired() bool {
+	return t.HasExpiry() && t.ExpiresUnix <= timeutil.TimeStampNow()
+}
+
+// CanAccessRepository checks if the resource restriction of a fine-grained token includes the repository.
+// The repository ids of the token must be loaded.
+func (t *AccessToken) CanAccessRepository(repoID, ownerID int64) bool {
+	if !t.IsFineGrained() {
+		return true
+	}
+	if t.OrgID > 0 {
+		return t.OrgID == ownerID
+	}
+	return slices.Contains(t.RepoIDs, repoID)
+}
+
 // NewAccessToken creates new access token.
 func NewAccessToken(ctx context.Context, t *AccessToken) error {
 	salt, err := util.CryptoRandomString(10)
@@ -110,8 +147,18 @@ func NewAccessToken(ctx context.Context, t *AccessToken) error {
 	t.Token = hex.EncodeToString(token)
 	t.TokenHash = HashToken(t.Token, t.TokenSalt)
 	t.TokenLastEight = t.Token[len(t.Token)-8:]
-	_, err = db.GetEngine(ctx).Insert(t)
-	return err
+
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := db.GetEngine(ctx).Insert(t); err != nil {
+			return err
+		}
+		for _, repoID := range t.RepoIDs {
+			if err := db.Insert(ctx, &AccessTokenRepository{TokenID: t.ID, RepoID: repoID}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 // DisplayPublicOnly whether to display this as a public-only token.
@@ -190,6 +237,18 @@ func GetAccessTokenBySHA(ctx context.Context, token string) (*AccessToken, error
 	return nil, ErrAccessTokenNotExist{token}
 }
 
+// GetAccessTokenByID returns the access token by its id
+func GetAccessTokenByID(ctx context.Context, id int64) (*AccessToken, error) {
+	t := &AccessToken{}
+	has, err := db.GetEngine(ctx).ID(id).Get(t)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrAccessTokenNotExist{}
+	}
+	return t, nil
+}
+
 // AccessTokenByNameExists checks if a token name has been used already by a user.
 func AccessTokenByNameExists(ctx context.Context, token *AccessToken) (bool, error) {
 	return db.GetEngine(ctx).Table("access_token").Where("name = ?", token.Name).And("uid = ?", token.UID).Exist()
@@ -224,13 +283,39 @@ func UpdateAccessToken(ctx context.Context, t *AccessToken) error {
 
 // DeleteAccessTokenByID deletes access token by given ID.
 func DeleteAccessTokenByID(ctx context.Context, id, userID int64) error {
-	cnt, err := db.GetEngine(ctx).ID(id).Delete(&AccessToken{
-		UID: userID,
-	})
-	if err != nil {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		cnt, err := db.GetEngine(ctx).ID(id).Delete(&AccessToken{
+			UID: userID,
+		})
+		if err != nil {
+			return err
+		} else if cnt != 1 {
+			return ErrAccessTokenNotExist{}
+		}
+
+		_, err = db.GetEngine(ctx).Delete(&AccessTokenRepository{TokenID: id})
 		return err
-	} else if cnt != 1 {
-		return ErrAccessTokenNotExist{}
+	})
+}
+
+// FindExpiringAccessTokens returns the tokens which expire before the deadline and whose owners haven't been notified yet
+func FindExpiringAccessTokens(ctx context.Context, deadline timeutil.TimeStamp) ([]*AccessToken, error) {
+	tokens := make([]*AccessToken, 0, 10)
+	return tokens, db.GetEngine(ctx).
+		Where("expires_unix > ?", timeutil.TimeStampNow()).
+		And("expires_unix <= ?", deadline).
+		And("expiry_notified_unix = 0").
+		OrderBy("uid, expires_unix").
+		Find(&tokens)
+}
+
+// SetAccessTokensExpiryNotified marks the owners of the tokens as notified about the upcoming expiry
+func SetAccessTokensExpiryNotified(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
 	}
-	return nil
+	_, err := db.GetEngine(ctx).In("id", ids).Cols("expiry_notified_unix").NoAutoTime().Update(&AccessToken{
+		ExpiryNotifiedUnix: timeutil.TimeStampNow(),
+	})
+	return err
 }
@@ -0,0 +1,156 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/perm"
+	"code.gitea.io/gitea/models/unit"
+)
+
+// AccessTokenPermission represents a permission a fine-grained access token grants on its repositories
+type AccessTokenPermission string
+
+const (
+	AccessTokenPermissionContents AccessTokenPermission = "contents"
+	AccessTokenPermissionIssues   AccessTokenPermission = "issues"
+	AccessTokenPermissionPulls    AccessTokenPermission = "pulls"
+	AccessTokenPermissionPackages AccessTokenPermission = "packages"
+	AccessTokenPermissionActions  AccessTokenPermission = "actions"
+)
+
+// AllAccessTokenPermissions contains all permissions of fine-grained access tokens
+var AllAccessTokenPermissions = []AccessTokenPermission{
+	AccessTokenPermissionContents,
+	AccessTokenPermissionIssues,
+	AccessTokenPermissionPulls,
+	AccessTokenPermissionPackages,
+	AccessTokenPermissionActions,
+}
+
+// unitPermissions maps the repository units to the permission which grants access to them
+var unitPermissions = map[unit.Type]AccessTokenPermission{
+	unit.TypeCode:            AccessTokenPermissionContents,
+	unit.TypeReleases:        AccessTokenPermissionContents,
+	unit.TypeWiki:            AccessTokenPermissionContents,
+	unit.TypeExternalWiki:    AccessTokenPermissionContents,
+	unit.TypeIssues:          AccessTokenPermissionIssues,
+	unit.TypeExternalTracker: AccessTokenPermissionIssues,
+	unit.TypeProjects:        AccessTokenPermissionIssues,
+	unit.TypePullRequests:    AccessTokenPermissionPulls,
+	unit.TypePackages:        AccessTokenPermissionPackages,
+	unit.TypeActions:         AccessTokenPermissionActions,
+}
+
+// permissionScopeCategories maps the permissions to the scope categories of the API endpoints they need.
+// Pull requests are commented and labeled through the issue endpoints.
+var permissionScopeCategories = map[AccessTokenPermission][]AccessTokenScopeCategory{
+	AccessTokenPermissionContents: {AccessTokenScopeCategoryRepository},
+	AccessTokenPermissionIssues:   {AccessTokenScopeCategoryIssue},
+	AccessTokenPermissionPulls:    {AccessTokenScopeCategoryRepository, AccessTokenScopeCategoryIssue},
+	AccessTokenPermissionPackages: {AccessTokenScopeCategoryPackage},
+	AccessTokenPermissionActions:  {AccessTokenScopeCategoryRepository},
+}
+
+// AccessTokenPermissions maps the permissions of a fine-grained access token to their access mode
+type AccessTokenPermissions map[AccessTokenPermission]perm.AccessMode
+
+// ParseAccessTokenPermissions parses permissions given as name and "read" or "write".
+// Permissions without access ("" or "none") are skipped.
+func ParseAccessTokenPermissions(permissions map[string]string) (AccessTokenPermissions, error) {
+	p := make(AccessTokenPermissions, len(permissions))
+	for name, mode := range permissions {
+		permission := AccessTokenPermission(strings.ToLower(name))
+		if _, ok := permissionScopeCategories[permission]; !ok {
+			return nil, fmt.Errorf("invalid access token permission: %s", name)
+		}
+
+		switch strings.ToLower(mode) {
+		case "", "none":
+		case "read":
+			p[permission] = perm.AccessModeRead
+		case "write":
+			p[permission] = perm.AccessModeWrite
+		default:
+			return nil, fmt.Errorf("invalid access mode for access token permission %s: %s", name, mode)
+		}
+	}
+	return p, nil
+}
+
+// UnitAccessMode returns the access mode the permissions grant on the unit
+func (p AccessTokenPermissions) UnitAccessMode(unitType unit.Type) perm.AccessMode {
+	permission, ok := unitPermissions[unitType]
+	if !ok {
+		return perm.AccessModeNone
+	}
+	return p[permission]
+}
+
+// ToStringMap returns the permissions as name and "read" or "write"
+func (p AccessTokenPermissions) ToStringMap() map[string]string {
+	m := make(map[string]string, len(p))
+	for permission, mode := range p {
+		m[string(permission)] = mode.String()
+	}
+	return m
+}
+
+// Scope returns the scope which allows the API endpoints the permissions need.
+// Every fine-grained token may read the metadata of its repositories.
+func (p AccessTokenPermissions) Scope() (AccessTokenScope, error) {
+	scopes := []string{string(AccessTokenScopeReadRepository)}
+	for permission, mode := range p {
+		level := GetScopeLevelFromAccessMode(mode)
+		if level == NoAccess {
+			continue
+		}
+		for _, scope := range GetRequiredScopes(level, permissionScopeCategories[permission]...) {
+			scopes = append(scopes, string(scope))
+		}
+	}
+	return AccessTokenScope(strings.Join(scopes, ",")).Normalize()
+}
+
+// AccessTokenRepository is a repository a fine-grained access token is restricted to
+type AccessTokenRepository struct {
+	ID      int64 `xorm:"pk autoincr"`
+	TokenID int64 `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	RepoID  int64 `xorm:"UNIQUE(s) INDEX NOT NULL"`
+}
+
+func init() {
+	db.RegisterModel(new(AccessTokenRepository))
+}
+
+// LoadRepositoryIDs loads the ids of the repositories the token is restricted to
+func (t *AccessToken) LoadRepositoryIDs(ctx context.Context) error {
+	if t.RepoIDs != nil || !t.IsFineGrained() || t.OrgID > 0 {
+		return nil
+	}
+
+	repoIDs := make([]int64, 0, 5)
+	if err := db.GetEngine(ctx).
+		Table("access_token_repository").
+		Where("token_id = ?", t.ID).
+		Cols("repo_id").
+		OrderBy("repo_id").
+		Find(&repoIDs); err != nil {
+		return err
+	}
+	t.RepoIDs = repoIDs
+	return nil
+}
+
+// DeleteAccessTokenRepositoriesByUserID removes the repository restrictions of all tokens of the user
+func DeleteAccessTokenRepositoriesByUserID(ctx context.Context, userID int64) error {
+	_, err := db.GetEngine(ctx).
+		Where("token_id IN (SELECT id FROM access_token WHERE uid = ?)", userID).
+		Delete(&AccessTokenRepository{})
+	return err
+}
@@ -0,0 +1,86 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models/perm"
+	"code.gitea.io/gitea/models/unit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAccessTokenPermissions(t *testing.T) {
+	p, err := ParseAccessTokenPermissions(map[string]string{
+		"contents": "write",
+		"Issues":   "READ",
+		"pulls":    "none",
+		"packages": "",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, AccessTokenPermissions{
+		AccessTokenPermissionContents: perm.AccessModeWrite,
+		AccessTokenPermissionIssues:   perm.AccessModeRead,
+	}, p)
+	assert.Equal(t, map[string]string{"contents": "write", "issues": "read"}, p.ToStringMap())
+
+	_, err = ParseAccessTokenPermissions(map[string]string{"wiki": "read"})
+	require.Error(t, err)
+
+	_, err = ParseAccessTokenPermissions(map[string]string{"contents": "admin"})
+	require.Error(t, err)
+}
+
+func TestAccessTokenPermissions_UnitAccessMode(t *testing.T) {
+	p := AccessTokenPermissions{
+		AccessTokenPermissionContents: perm.AccessModeRead,
+		AccessTokenPermissionPulls:    perm.AccessModeWrite,
+	}
+	assert.Equal(t, perm.AccessModeRead, p.UnitAccessMode(unit.TypeCode))
+	assert.Equal(t, perm.AccessModeRead, p.UnitAccessMode(unit.TypeWiki))
+	assert.Equal(t, perm.AccessModeWrite, p.UnitAccessMode(unit.TypePullRequests))
+	assert.Equal(t, perm.AccessModeNone, p.UnitAccessMode(unit.TypeIssues))
+	assert.Equal(t, perm.AccessModeNone, p.UnitAccessMode(unit.TypeActions))
+}
+
+func TestAccessTokenPermissions_Scope(t *testing.T) {
+	cases := []struct {
+		permissions AccessTokenPermissions
+		scope       AccessTokenScope
+	}{
+		{AccessTokenPermissions{}, "read:repository"},
+		{AccessTokenPermissions{AccessTokenPermissionContents: perm.AccessModeWrite}, "write:repository"},
+		{AccessTokenPermissions{AccessTokenPermissionIssues: perm.AccessModeRead}, "read:issue,read:repository"},
+		{AccessTokenPermissions{AccessTokenPermissionPulls: perm.AccessModeWrite}, "write:issue,write:repository"},
+		{AccessTokenPermissions{AccessTokenPermissionPackages: perm.AccessModeWrite}, "write:package,read:repository"},
+	}
+	for _, c := range cases {
+		scope, err := c.permissions.Scope()
+		require.NoError(t, err)
+		assert.Equal(t, c.scope, scope)
+	}
+}
+
+func TestAccessToken_CanAccessRepository(t *testing.T) {
+	token := &AccessToken{}
+	assert.False(t, token.IsFineGrained())
+	assert.True(t, token.CanAccessRepository(1, 2))
+
+	token = &AccessToken{
+		Permissions: AccessTokenPermissions{AccessTokenPermissionContents: perm.AccessModeRead},
+		RepoIDs:     []int64{1, 3},
+	}
+	assert.True(t, token.IsFineGrained())
+	assert.True(t, token.CanAccessRepository(1, 2))
+	assert.False(t, token.CanAccessRepository(2, 2))
+
+	token = &AccessToken{
+		Permissions: AccessTokenPermissions{AccessTokenPermissionContents: perm.AccessModeRead},
+		OrgID:       3,
+	}
+	assert.True(t, token.CanAccessRepository(5, 3))
+	assert.False(t, token.CanAccessRepository(1, 2))
+}
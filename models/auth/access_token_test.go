@@ -8,6 +8,7 @@ import (
 
 	auth_model "code.gitea.io/gitea/models/auth"
 	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/perm"
 	"code.gitea.io/gitea/models/unittest"
 
 	"github.com/stretchr/testify/assert"
@@ -131,3 +132,27 @@ func TestDeleteAccessTokenByID(t *testing.T) {
 	require.Error(t, err)
 	assert.True(t, auth_model.IsErrAccessTokenNotExist(err))
 }
+
+func TestNewAccessTokenWithRepositories(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+	token := &auth_model.AccessToken{
+		UID:         2,
+		Name:        "Fine-grained Token",
+		Scope:       auth_model.AccessTokenScopeReadRepository,
+		Permissions: auth_model.AccessTokenPermissions{auth_model.AccessTokenPermissionContents: perm.AccessModeRead},
+		RepoIDs:     []int64{1, 2},
+	}
+	require.NoError(t, auth_model.NewAccessToken(db.DefaultContext, token))
+
+	loaded, err := auth_model.GetAccessTokenByID(db.DefaultContext, token.ID)
+	require.NoError(t, err)
+	assert.True(t, loaded.IsFineGrained())
+	require.NoError(t, loaded.LoadRepositoryIDs(db.DefaultContext))
+	assert.Equal(t, []int64{1, 2}, loaded.RepoIDs)
+
+	require.NoError(t, auth_model.DeleteAccessTokenByID(db.DefaultContext, token.ID, 2))
+	unittest.AssertNotExistsBean(t, &auth_model.AccessTokenRepository{TokenID: token.ID})
+
+	_, err = auth_model.GetAccessTokenByID(db.DefaultContext, token.ID)
+	assert.True(t, auth_model.IsErrAccessTokenNotExist(err))
+}
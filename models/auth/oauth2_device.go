@@ -0,0 +1,219 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+
+	"xorm.io/builder"
+)
+
+// userCodeChars are the characters of user codes, vowels are left out to avoid words
+// and the user code is case insensitive, see https://datatracker.ietf.org/doc/html/rfc8628#section-6.1
+const userCodeChars = "BCDFGHJKLMNPQRSTVWXZ"
+
+const userCodeLength = 8
+
+// DeviceCodeSlowDownInterval is added to the polling interval of a device code if the client polls too fast
+const DeviceCodeSlowDownInterval = 5
+
+// ErrOAuth2DeviceCodeNotExist represents a "OAuth2DeviceCodeNotExist" kind of error.
+type ErrOAuth2DeviceCodeNotExist struct {
+	Code string
+}
+
+// IsErrOAuth2DeviceCodeNotExist checks if an error is a ErrOAuth2DeviceCodeNotExist.
+func IsErrOAuth2DeviceCodeNotExist(err error) bool {
+	_, ok := err.(ErrOAuth2DeviceCodeNotExist)
+	return ok
+}
+
+func (err ErrOAuth2DeviceCodeNotExist) Error() string {
+	return fmt.Sprintf("device code does not exist [code: %s]", err.Code)
+}
+
+func (err ErrOAuth2DeviceCodeNotExist) Unwrap() error {
+	return util.ErrNotExist
+}
+
+// OAuth2DeviceCode is an authorization request of the device authorization grant (RFC 8628).
+// The device polls with the device code until the user approved or denied the request with the user code.
+type OAuth2DeviceCode struct {
+	ID             int64              `xorm:"pk autoincr"`
+	Application    *OAuth2Application `xorm:"-"`
+	ApplicationID  int64              `xorm:"INDEX"`
+	DeviceCode     string             `xorm:"UNIQUE"`
+	UserCode       string             `xorm:"UNIQUE"`
+	Scope          string             `xorm:"TEXT"`
+	GrantID        int64              `xorm:"INDEX"`
+	Denied         bool               `xorm:"NOT NULL DEFAULT false"`
+	Interval       int64              `xorm:"NOT NULL DEFAULT 5"`
+	LastPolledUnix timeutil.TimeStamp
+	ValidUntil     timeutil.TimeStamp `xorm:"INDEX"`
+	CreatedUnix    timeutil.TimeStamp `xorm:"created"`
+}
+
+func init() {
+	db.RegisterModel(new(OAuth2DeviceCode))
+}
+
+// TableName sets the table name to `oauth2_device_code`
+func (code *OAuth2DeviceCode) TableName() string {
+	return "oauth2_device_code"
+}
+
+// IsExpired returns true if the device code can't be used anymore
+func (code *OAuth2DeviceCode) IsExpired() bool {
+	return code.ValidUntil < timeutil.TimeStampNow()
+}
+
+// IsPending returns true if the user neither approved nor denied the request yet
+func (code *OAuth2DeviceCode) IsPending() bool {
+	return code.GrantID == 0 && !code.Denied
+}
+
+// FormattedUserCode returns the user code in the format shown to users, e.g. WDJB-MJHT
+func (code *OAuth2DeviceCode) FormattedUserCode() string {
+	return code.UserCode[:userCodeLength/2] + "-" + code.UserCode[userCodeLength/2:]
+}
+
+// LoadApplication loads the application of the device code
+func (code *OAuth2DeviceCode) LoadApplication(ctx context.Context) (err error) {
+	if code.Application != nil {
+		return nil
+	}
+	code.Application, err = GetOAuth2ApplicationByID(ctx, code.ApplicationID)
+	return err
+}
+
+// Approve marks the request as approved by the grant of the user
+func (code *OAuth2DeviceCode) Approve(ctx context.Context, grantID int64) error {
+	code.GrantID = grantID
+	_, err := db.GetEngine(ctx).ID(code.ID).Cols("grant_id").Update(code)
+	return err
+}
+
+// Deny marks the request as denied by the user
+func (code *OAuth2DeviceCode) Deny(ctx context.Context) error {
+	code.Denied = true
+	_, err := db.GetEngine(ctx).ID(code.ID).Cols("denied").Update(code)
+	return err
+}
+
+// Poll records a token request of the device. It returns true if the device polls faster
+// than the interval, the interval is increased in that case as required by RFC 8628.
+func (code *OAuth2DeviceCode) Poll(ctx context.Context) (slowDown bool, err error) {
+	now := timeutil.TimeStampNow()
+	if code.LastPolledUnix > 0 && now < code.LastPolledUnix.Add(code.Interval) {
+		code.Interval += DeviceCodeSlowDownInterval
+		slowDown = true
+	}
+	code.LastPolledUnix = now
+	_, err = db.GetEngine(ctx).ID(code.ID).Cols("interval", "last_polled_unix").Update(code)
+	return slowDown, err
+}
+
+// Invalidate deletes the device code from the database to deny duplicate usage
+func (code *OAuth2DeviceCode) Invalidate(ctx context.Context) error {
+	_, err := db.GetEngine(ctx).ID(code.ID).NoAutoCondition().Delete(code)
+	return err
+}
+
+// CreateDeviceCode creates a new device authorization request for the application
+func (app *OAuth2Application) CreateDeviceCode(ctx context.Context, scope string) (*OAuth2DeviceCode, error) {
+	if err := DeleteExpiredOAuth2DeviceCodes(ctx); err != nil {
+		return nil, err
+	}
+
+	rBytes, err := util.CryptoRandomBytes(32)
+	if err != nil {
+		return nil, err
+	}
+	userCode, err := generateUserCode()
+	if err != nil {
+		return nil, err
+	}
+
+	code := &OAuth2DeviceCode{
+		Application:   app,
+		ApplicationID: app.ID,
+		DeviceCode:    "gtd_" + base32Lower.EncodeToString(rBytes),
+		UserCode:      userCode,
+		Scope:         scope,
+		Interval:      setting.OAuth2.DeviceCodePollingInterval,
+		ValidUntil:    timeutil.TimeStampNow().Add(setting.OAuth2.DeviceCodeExpirationTime),
+	}
+	if err := db.Insert(ctx, code); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+func generateUserCode() (string, error) {
+	var sb strings.Builder
+	charCount := big.NewInt(int64(len(userCodeChars)))
+	for i := 0; i < userCodeLength; i++ {
+		n, err := rand.Int(rand.Reader, charCount)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(userCodeChars[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// NormalizeUserCode converts a user code entered by a user to the stored format,
+// the code is case insensitive and separators are ignored.
+func NormalizeUserCode(userCode string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		if strings.ContainsRune(userCodeChars, r) {
+			return r
+		}
+		return -1
+	}, userCode)
+}
+
+// GetOAuth2DeviceCodeByUserCode returns the unexpired device code with the given user code
+func GetOAuth2DeviceCodeByUserCode(ctx context.Context, userCode string) (*OAuth2DeviceCode, error) {
+	userCode = NormalizeUserCode(userCode)
+	if len(userCode) != userCodeLength {
+		return nil, ErrOAuth2DeviceCodeNotExist{Code: userCode}
+	}
+	code := new(OAuth2DeviceCode)
+	if has, err := db.GetEngine(ctx).Where(builder.Eq{"user_code": userCode}.And(builder.Gte{"valid_until": timeutil.TimeStampNow()})).Get(code); err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrOAuth2DeviceCodeNotExist{Code: userCode}
+	}
+	return code, nil
+}
+
+// GetOAuth2DeviceCodeByDeviceCode returns the device code with the given device code, it may be expired
+func GetOAuth2DeviceCodeByDeviceCode(ctx context.Context, deviceCode string) (*OAuth2DeviceCode, error) {
+	code := new(OAuth2DeviceCode)
+	if has, err := db.GetEngine(ctx).Where("device_code = ?", deviceCode).Get(code); err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrOAuth2DeviceCodeNotExist{}
+	}
+	return code, nil
+}
+
+// DeleteExpiredOAuth2DeviceCodes deletes all device codes which are expired
+func DeleteExpiredOAuth2DeviceCodes(ctx context.Context) error {
+	_, err := db.GetEngine(ctx).Where(builder.Lt{"valid_until": timeutil.TimeStampNow()}).Delete(new(OAuth2DeviceCode))
+	return err
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package auth_test
+
+import (
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuth2DeviceCode(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	app := unittest.AssertExistsAndLoadBean(t, &auth_model.OAuth2Application{ID: 2})
+	code, err := app.CreateDeviceCode(db.DefaultContext, "read:user")
+	require.NoError(t, err)
+	assert.True(t, code.IsPending())
+	assert.Len(t, code.UserCode, 8)
+	assert.Regexp(t, "^[A-Z]{4}-[A-Z]{4}$", code.FormattedUserCode())
+
+	// the user code is case insensitive and separators are ignored
+	loaded, err := auth_model.GetOAuth2DeviceCodeByUserCode(db.DefaultContext, " "+code.FormattedUserCode()[:4]+" "+code.UserCode[4:])
+	require.NoError(t, err)
+	assert.Equal(t, code.ID, loaded.ID)
+
+	loaded, err = auth_model.GetOAuth2DeviceCodeByDeviceCode(db.DefaultContext, code.DeviceCode)
+	require.NoError(t, err)
+	assert.Equal(t, code.ID, loaded.ID)
+
+	_, err = auth_model.GetOAuth2DeviceCodeByUserCode(db.DefaultContext, "BCDF-GHJ")
+	assert.True(t, auth_model.IsErrOAuth2DeviceCodeNotExist(err))
+
+	// polling faster than the interval slows the device down
+	slowDown, err := code.Poll(db.DefaultContext)
+	require.NoError(t, err)
+	assert.False(t, slowDown)
+	slowDown, err = code.Poll(db.DefaultContext)
+	require.NoError(t, err)
+	assert.True(t, slowDown)
+	loaded = unittest.AssertExistsAndLoadBean(t, &auth_model.OAuth2DeviceCode{ID: code.ID})
+	assert.EqualValues(t, 5+auth_model.DeviceCodeSlowDownInterval, loaded.Interval)
+
+	require.NoError(t, code.Approve(db.DefaultContext, 4))
+	loaded = unittest.AssertExistsAndLoadBean(t, &auth_model.OAuth2DeviceCode{ID: code.ID})
+	assert.False(t, loaded.IsPending())
+	assert.EqualValues(t, 4, loaded.GrantID)
+
+	// expired codes can't be entered by users
+	expired, err := app.CreateDeviceCode(db.DefaultContext, "")
+	require.NoError(t, err)
+	expired.ValidUntil = timeutil.TimeStampNow().Add(-1)
+	_, err = db.GetEngine(db.DefaultContext).ID(expired.ID).Cols("valid_until").Update(expired)
+	require.NoError(t, err)
+	assert.True(t, expired.IsExpired())
+	_, err = auth_model.GetOAuth2DeviceCodeByUserCode(db.DefaultContext, expired.UserCode)
+	assert.True(t, auth_model.IsErrOAuth2DeviceCodeNotExist(err))
+
+	require.NoError(t, auth_model.DeleteExpiredOAuth2DeviceCodes(db.DefaultContext))
+	unittest.AssertNotExistsBean(t, &auth_model.OAuth2DeviceCode{ID: expired.ID})
+	unittest.AssertExistsAndLoadBean(t, &auth_model.OAuth2DeviceCode{ID: code.ID})
+}
@@ -0,0 +1,156 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+)
+
+// ErrSCIMTokenNotExist represents a "SCIMTokenNotExist" kind of error.
+type ErrSCIMTokenNotExist struct {
+	SourceID int64
+}
+
+// IsErrSCIMTokenNotExist checks if an error is a ErrSCIMTokenNotExist.
+func IsErrSCIMTokenNotExist(err error) bool {
+	_, ok := err.(ErrSCIMTokenNotExist)
+	return ok
+}
+
+func (err ErrSCIMTokenNotExist) Error() string {
+	return fmt.Sprintf("scim token does not exist [source_id: %d]", err.SourceID)
+}
+
+func (err ErrSCIMTokenNotExist) Unwrap() error {
+	return util.ErrNotExist
+}
+
+// SCIMToken is the bearer token an identity provider uses to provision the users of an authentication source.
+// Every source has at most one token.
+type SCIMToken struct {
+	ID             int64  `xorm:"pk autoincr"`
+	SourceID       int64  `xorm:"UNIQUE NOT NULL"`
+	TokenHash      string `xorm:"UNIQUE"` // sha256 of token
+	TokenSalt      string
+	TokenLastEight string             `xorm:"INDEX token_last_eight"`
+	CreatedUnix    timeutil.TimeStamp `xorm:"created"`
+}
+
+// SCIMGroup links a team to a SCIM group of an authentication source.
+type SCIMGroup struct {
+	ID          int64              `xorm:"pk autoincr"`
+	SourceID    int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	TeamID      int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	ExternalID  string             `xorm:"VARCHAR(255)"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+}
+
+func init() {
+	db.RegisterModel(new(SCIMToken))
+	db.RegisterModel(new(SCIMGroup))
+}
+
+// GenerateSCIMToken creates a new token for the source, replacing an existing one.
+// The plain token is returned as it can't be retrieved later.
+func GenerateSCIMToken(ctx context.Context, sourceID int64) (string, error) {
+	salt, err := util.CryptoRandomString(10)
+	if err != nil {
+		return "", err
+	}
+	bytes, err := util.CryptoRandomBytes(20)
+	if err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(bytes)
+
+	return token, db.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := db.DeleteByBean(ctx, &SCIMToken{SourceID: sourceID}); err != nil {
+			return err
+		}
+		return db.Insert(ctx, &SCIMToken{
+			SourceID:       sourceID,
+			TokenHash:      HashToken(token, salt),
+			TokenSalt:      salt,
+			TokenLastEight: token[len(token)-8:],
+		})
+	})
+}
+
+// GetSCIMToken returns the token of the source
+func GetSCIMToken(ctx context.Context, sourceID int64) (*SCIMToken, error) {
+	t := &SCIMToken{}
+	has, err := db.GetEngine(ctx).Where("source_id = ?", sourceID).Get(t)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrSCIMTokenNotExist{SourceID: sourceID}
+	}
+	return t, nil
+}
+
+// DeleteSCIMToken revokes the token of the source
+func DeleteSCIMToken(ctx context.Context, sourceID int64) error {
+	_, err := db.DeleteByBean(ctx, &SCIMToken{SourceID: sourceID})
+	return err
+}
+
+// GetSCIMTokenByToken returns the token matching the plain token value
+func GetSCIMTokenByToken(ctx context.Context, token string) (*SCIMToken, error) {
+	if len(token) != 40 {
+		return nil, ErrSCIMTokenNotExist{}
+	}
+
+	var tokens []*SCIMToken
+	if err := db.GetEngine(ctx).Where("token_last_eight = ?", token[len(token)-8:]).Find(&tokens); err != nil {
+		return nil, err
+	}
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare([]byte(t.TokenHash), []byte(HashToken(token, t.TokenSalt))) == 1 {
+			return t, nil
+		}
+	}
+	return nil, ErrSCIMTokenNotExist{}
+}
+
+// FindSCIMGroups returns the teams linked to SCIM groups of the source
+func FindSCIMGroups(ctx context.Context, sourceID int64) ([]*SCIMGroup, error) {
+	groups := make([]*SCIMGroup, 0, 10)
+	return groups, db.GetEngine(ctx).Where("source_id = ?", sourceID).OrderBy("id").Find(&groups)
+}
+
+// GetSCIMGroup returns the SCIM group of the source linked to the team
+func GetSCIMGroup(ctx context.Context, sourceID, teamID int64) (*SCIMGroup, error) {
+	g := &SCIMGroup{}
+	has, err := db.GetEngine(ctx).Where("source_id = ? AND team_id = ?", sourceID, teamID).Get(g)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, util.NewNotExistErrorf("scim group does not exist [source_id: %d, team_id: %d]", sourceID, teamID)
+	}
+	return g, nil
+}
+
+// CreateSCIMGroup links the team to a SCIM group of the source
+func CreateSCIMGroup(ctx context.Context, g *SCIMGroup) error {
+	return db.Insert(ctx, g)
+}
+
+// UpdateSCIMGroupExternalID updates the identifier the identity provider uses for the group
+func UpdateSCIMGroupExternalID(ctx context.Context, g *SCIMGroup) error {
+	_, err := db.GetEngine(ctx).ID(g.ID).Cols("external_id").Update(g)
+	return err
+}
+
+// DeleteSCIMGroup removes the link between the team and the SCIM group
+func DeleteSCIMGroup(ctx context.Context, g *SCIMGroup) error {
+	_, err := db.DeleteByID[SCIMGroup](ctx, g.ID)
+	return err
+}
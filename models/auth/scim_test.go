@@ -0,0 +1,46 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package auth_test
+
+import (
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/unittest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSCIMToken(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	_, err := auth_model.GetSCIMToken(db.DefaultContext, 1)
+	assert.True(t, auth_model.IsErrSCIMTokenNotExist(err))
+
+	token, err := auth_model.GenerateSCIMToken(db.DefaultContext, 1)
+	require.NoError(t, err)
+	assert.Len(t, token, 40)
+
+	scimToken, err := auth_model.GetSCIMTokenByToken(db.DefaultContext, token)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, scimToken.SourceID)
+	assert.NotEqual(t, token, scimToken.TokenHash)
+
+	// generating a new token revokes the previous one
+	newToken, err := auth_model.GenerateSCIMToken(db.DefaultContext, 1)
+	require.NoError(t, err)
+	_, err = auth_model.GetSCIMTokenByToken(db.DefaultContext, token)
+	assert.True(t, auth_model.IsErrSCIMTokenNotExist(err))
+	_, err = auth_model.GetSCIMTokenByToken(db.DefaultContext, newToken)
+	require.NoError(t, err)
+
+	_, err = auth_model.GetSCIMTokenByToken(db.DefaultContext, "invalid")
+	assert.True(t, auth_model.IsErrSCIMTokenNotExist(err))
+
+	require.NoError(t, auth_model.DeleteSCIMToken(db.DefaultContext, 1))
+	_, err = auth_model.GetSCIMTokenByToken(db.DefaultContext, newToken)
+	assert.True(t, auth_model.IsErrSCIMTokenNotExist(err))
+}
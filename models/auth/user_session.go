@@ -0,0 +1,138 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+
+	"xorm.io/builder"
+)
+
+// UserSession is a signed-in web session of a user. The session data only
+// refers to it by ID, so it works with every session provider: once the
+// row is deleted the session is signed out on its next request.
+type UserSession struct {
+	ID  int64 `xorm:"pk autoincr"`
+	UID int64 `xorm:"INDEX NOT NULL"`
+	// AuthTokenID is the long-term authentication token ("remember me") the session
+	// was signed in with, it is deleted together with the session.
+	AuthTokenID  int64              `xorm:"NOT NULL DEFAULT 0"`
+	UserAgent    string             `xorm:"TEXT"`
+	IPAddress    string             `xorm:"VARCHAR(64) NOT NULL DEFAULT ''"`
+	CreatedUnix  timeutil.TimeStamp `xorm:"created"`
+	LastSeenUnix timeutil.TimeStamp `xorm:"INDEX"`
+}
+
+func init() {
+	db.RegisterModel(new(UserSession))
+}
+
+// ErrUserSessionNotExist represents a "UserSessionNotExist" kind of error.
+type ErrUserSessionNotExist struct {
+	ID int64
+}
+
+func (err ErrUserSessionNotExist) Error() string {
+	return fmt.Sprintf("user session does not exist [id: %d]", err.ID)
+}
+
+func (err ErrUserSessionNotExist) Unwrap() error {
+	return util.ErrNotExist
+}
+
+// CreateUserSession inserts a new user session which is seen now
+func CreateUserSession(ctx context.Context, s *UserSession) error {
+	s.LastSeenUnix = timeutil.TimeStampNow()
+	return db.Insert(ctx, s)
+}
+
+// GetUserSessionByID returns the user session with the given ID
+func GetUserSessionByID(ctx context.Context, id int64) (*UserSession, error) {
+	s, exist, err := db.GetByID[UserSession](ctx, id)
+	if err != nil {
+		return nil, err
+	} else if !exist {
+		return nil, ErrUserSessionNotExist{ID: id}
+	}
+	return s, nil
+}
+
+// UpdateUserSessionLastSeen marks the user session as seen now from the given IP address
+func UpdateUserSessionLastSeen(ctx context.Context, s *UserSession, ipAddress string) error {
+	s.LastSeenUnix = timeutil.TimeStampNow()
+	s.IPAddress = ipAddress
+	_, err := db.GetEngine(ctx).ID(s.ID).Cols("last_seen_unix", "ip_address").Update(s)
+	return err
+}
+
+// UpdateUserSessionAuthToken links the long-term authentication token the user session was signed in with
+func UpdateUserSessionAuthToken(ctx context.Context, s *UserSession, authTokenID int64) error {
+	s.AuthTokenID = authTokenID
+	_, err := db.GetEngine(ctx).ID(s.ID).Cols("auth_token_id").Update(s)
+	return err
+}
+
+// IsExpired returns true if the session was not seen for longer than the session lifetime
+func (s *UserSession) IsExpired() bool {
+	return s.LastSeenUnix <= timeutil.TimeStampNow().Add(-setting.SessionConfig.Maxlifetime)
+}
+
+// FindUserSessionsOptions contain the filter options of user sessions,
+// sessions which outlived the session lifetime are never found.
+type FindUserSessionsOptions struct {
+	db.ListOptions
+	UID int64
+}
+
+func (opts FindUserSessionsOptions) ToConds() builder.Cond {
+	cond := builder.NewCond().And(builder.Gt{"last_seen_unix": timeutil.TimeStampNow().Add(-setting.SessionConfig.Maxlifetime)})
+	if opts.UID != 0 {
+		cond = cond.And(builder.Eq{"uid": opts.UID})
+	}
+	return cond
+}
+
+func (opts FindUserSessionsOptions) ToOrders() string {
+	return "last_seen_unix DESC, id DESC"
+}
+
+// RevokeUserSession deletes the user session and the long-term authentication token it was signed in with
+func RevokeUserSession(ctx context.Context, s *UserSession) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := db.DeleteByID[UserSession](ctx, s.ID); err != nil {
+			return err
+		}
+		if s.AuthTokenID == 0 {
+			return nil
+		}
+		_, err := db.DeleteByID[AuthorizationToken](ctx, s.AuthTokenID)
+		return err
+	})
+}
+
+// RevokeUserSessionsByUID deletes all sessions and long-term authentication tokens of the user
+func RevokeUserSessionsByUID(ctx context.Context, uid int64) error {
+	if uid == 0 {
+		return nil
+	}
+
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := db.DeleteByBean(ctx, &UserSession{UID: uid}); err != nil {
+			return err
+		}
+		return DeleteAuthTokenByUser(ctx, uid)
+	})
+}
+
+// DeleteExpiredUserSessions deletes the user sessions which outlived the session lifetime
+func DeleteExpiredUserSessions(ctx context.Context) error {
+	_, err := db.GetEngine(ctx).Where(builder.Lte{"last_seen_unix": timeutil.TimeStampNow().Add(-setting.SessionConfig.Maxlifetime)}).Delete(new(UserSession))
+	return err
+}
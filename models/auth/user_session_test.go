@@ -0,0 +1,89 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/test"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserSession(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+	defer timeutil.MockUnset()
+	defer test.MockVariableValue(&setting.SessionConfig.Maxlifetime, int64(3600))()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	timeutil.MockSet(now.Add(-2 * time.Hour))
+
+	_, validator, err := auth.GenerateAuthToken(db.DefaultContext, 2, timeutil.TimeStampNow().Add(86400))
+	require.NoError(t, err)
+	assert.NotEmpty(t, validator)
+	token := unittest.AssertExistsAndLoadBean(t, &auth.AuthorizationToken{UID: 2})
+
+	expired := &auth.UserSession{UID: 2, UserAgent: "old browser", IPAddress: "192.0.2.1"}
+	require.NoError(t, auth.CreateUserSession(db.DefaultContext, expired))
+
+	timeutil.MockSet(now)
+	remembered := &auth.UserSession{UID: 2, AuthTokenID: token.ID, UserAgent: "browser", IPAddress: "192.0.2.2"}
+	require.NoError(t, auth.CreateUserSession(db.DefaultContext, remembered))
+	other := &auth.UserSession{UID: 2, UserAgent: "other browser", IPAddress: "192.0.2.3"}
+	require.NoError(t, auth.CreateUserSession(db.DefaultContext, other))
+	require.NoError(t, auth.CreateUserSession(db.DefaultContext, &auth.UserSession{UID: 4}))
+
+	t.Run("Find", func(t *testing.T) {
+		assert.True(t, expired.IsExpired())
+		assert.False(t, other.IsExpired())
+
+		sessions, err := db.Find[auth.UserSession](db.DefaultContext, auth.FindUserSessionsOptions{UID: 2})
+		require.NoError(t, err)
+		if assert.Len(t, sessions, 2) {
+			assert.Equal(t, other.ID, sessions[0].ID)
+			assert.Equal(t, remembered.ID, sessions[1].ID)
+		}
+	})
+
+	t.Run("LastSeen", func(t *testing.T) {
+		timeutil.MockSet(now.Add(time.Minute))
+		require.NoError(t, auth.UpdateUserSessionLastSeen(db.DefaultContext, remembered, "192.0.2.4"))
+
+		s, err := auth.GetUserSessionByID(db.DefaultContext, remembered.ID)
+		require.NoError(t, err)
+		assert.EqualValues(t, now.Add(time.Minute).Unix(), s.LastSeenUnix)
+		assert.Equal(t, "192.0.2.4", s.IPAddress)
+		assert.Equal(t, "browser", s.UserAgent)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		require.NoError(t, auth.RevokeUserSession(db.DefaultContext, remembered))
+
+		_, err := auth.GetUserSessionByID(db.DefaultContext, remembered.ID)
+		assert.ErrorAs(t, err, &auth.ErrUserSessionNotExist{})
+		unittest.AssertNotExistsBean(t, &auth.AuthorizationToken{ID: token.ID})
+		unittest.AssertExistsIf(t, true, &auth.UserSession{ID: other.ID})
+	})
+
+	t.Run("DeleteExpired", func(t *testing.T) {
+		require.NoError(t, auth.DeleteExpiredUserSessions(db.DefaultContext))
+
+		unittest.AssertNotExistsBean(t, &auth.UserSession{ID: expired.ID})
+		unittest.AssertExistsIf(t, true, &auth.UserSession{ID: other.ID})
+	})
+
+	t.Run("RevokeAll", func(t *testing.T) {
+		require.NoError(t, auth.RevokeUserSessionsByUID(db.DefaultContext, 2))
+
+		unittest.AssertNotExistsBean(t, &auth.UserSession{UID: 2})
+		unittest.AssertExistsIf(t, true, &auth.UserSession{UID: 4})
+	})
+}
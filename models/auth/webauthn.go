@@ -55,9 +55,11 @@ type WebAuthnCredential struct {
 	BackupEligible  bool `XORM:"NOT NULL DEFAULT false"`
 	BackupState     bool `XORM:"NOT NULL DEFAULT false"`
 	// If legacy is set to true, backup_eligible and backup_state isn't set.
-	Legacy      bool               `XORM:"NOT NULL DEFAULT true"`
-	CreatedUnix timeutil.TimeStamp `xorm:"INDEX created"`
-	UpdatedUnix timeutil.TimeStamp `xorm:"INDEX updated"`
+	Legacy bool `XORM:"NOT NULL DEFAULT true"`
+	// Discoverable credentials (passkeys) verify the user and can be used to sign in without a password.
+	Discoverable bool               `xorm:"NOT NULL DEFAULT false"`
+	CreatedUnix  timeutil.TimeStamp `xorm:"INDEX created"`
+	UpdatedUnix  timeutil.TimeStamp `xorm:"INDEX updated"`
 }
 
 func init() {
@@ -132,6 +134,16 @@ func ExistsWebAuthnCredentialsForUID(ctx context.Context, uid int64) (bool, erro
 	return db.GetEngine(ctx).Where("user_id = ?", uid).Exist(&WebAuthnCredential{})
 }
 
+// HasDiscoverableWebAuthnCredentialsByUID returns if the given user has passkeys
+func HasDiscoverableWebAuthnCredentialsByUID(ctx context.Context, uid int64) (bool, error) {
+	return db.GetEngine(ctx).Where("user_id = ? AND discoverable = ?", uid, true).Exist(&WebAuthnCredential{})
+}
+
+// CountDiscoverableWebAuthnCredentialsByUID returns the number of passkeys of the given user
+func CountDiscoverableWebAuthnCredentialsByUID(ctx context.Context, uid int64) (int64, error) {
+	return db.GetEngine(ctx).Where("user_id = ? AND discoverable = ?", uid, true).Count(&WebAuthnCredential{})
+}
+
 // GetWebAuthnCredentialByName returns WebAuthn credential by id
 func GetWebAuthnCredentialByName(ctx context.Context, uid int64, name string) (*WebAuthnCredential, error) {
 	cred := new(WebAuthnCredential)
@@ -170,8 +182,9 @@ func GetWebAuthnCredentialByCredID(ctx context.Context, userID int64, credID []b
 	return cred, nil
 }
 
-// CreateCredential will create a new WebAuthnCredential from the given Credential
-func CreateCredential(ctx context.Context, userID int64, name string, cred *webauthn.Credential) (*WebAuthnCredential, error) {
+// CreateCredential will create a new WebAuthnCredential from the given Credential,
+// discoverable marks it as a passkey that can be used to sign in without a password
+func CreateCredential(ctx context.Context, userID int64, name string, cred *webauthn.Credential, discoverable bool) (*WebAuthnCredential, error) {
 	c := &WebAuthnCredential{
 		UserID:          userID,
 		Name:            name,
@@ -184,6 +197,7 @@ func CreateCredential(ctx context.Context, userID int64, name string, cred *weba
 		BackupEligible:  cred.Flags.BackupEligible,
 		BackupState:     cred.Flags.BackupState,
 		Legacy:          false,
+		Discoverable:    discoverable,
 	}
 
 	if err := db.Insert(ctx, c); err != nil {
@@ -69,10 +69,29 @@ func TestWebAuthenCredential_UpdateFromLegacy(t *testing.T) {
 func TestCreateCredential(t *testing.T) {
 	require.NoError(t, unittest.PrepareTestDatabase())
 
-	res, err := auth_model.CreateCredential(db.DefaultContext, 1, "WebAuthn Created Credential", &webauthn.Credential{ID: []byte("Test"), Flags: webauthn.CredentialFlags{BackupEligible: true, BackupState: true}})
+	res, err := auth_model.CreateCredential(db.DefaultContext, 1, "WebAuthn Created Credential", &webauthn.Credential{ID: []byte("Test"), Flags: webauthn.CredentialFlags{BackupEligible: true, BackupState: true}}, false)
 	require.NoError(t, err)
 	assert.Equal(t, "WebAuthn Created Credential", res.Name)
 	assert.Equal(t, []byte("Test"), res.CredentialID)
 
 	unittest.AssertExistsIf(t, true, &auth_model.WebAuthnCredential{Name: "WebAuthn Created Credential", UserID: 1, BackupEligible: true, BackupState: true}, "legacy = false")
 }
+
+func TestDiscoverableWebAuthnCredentials(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	has, err := auth_model.HasDiscoverableWebAuthnCredentialsByUID(db.DefaultContext, 32)
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	_, err = auth_model.CreateCredential(db.DefaultContext, 32, "Passkey", &webauthn.Credential{ID: []byte("Passkey")}, true)
+	require.NoError(t, err)
+
+	has, err = auth_model.HasDiscoverableWebAuthnCredentialsByUID(db.DefaultContext, 32)
+	require.NoError(t, err)
+	assert.True(t, has)
+
+	count, err := auth_model.CountDiscoverableWebAuthnCredentialsByUID(db.DefaultContext, 32)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+}
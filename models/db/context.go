@@ -6,6 +6,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"sync"
 
 	"xorm.io/builder"
 	"xorm.io/xorm"
@@ -118,6 +119,54 @@ func (c *halfCommitter) Close() error {
 	return c.committer.Close()
 }
 
+// txCommitter commits or rolls back the transaction of the session,
+// the functions registered with AfterTx are called once it is committed
+type txCommitter struct {
+	sess *xorm.Session
+}
+
+func (c txCommitter) Commit() error {
+	if err := c.sess.Commit(); err != nil {
+		return err
+	}
+	for _, f := range popAfterTxHooks(c.sess) {
+		f()
+	}
+	return nil
+}
+
+func (c txCommitter) Close() error {
+	// the transaction is rolled back if it wasn't committed
+	popAfterTxHooks(c.sess)
+	return c.sess.Close()
+}
+
+var (
+	afterTxHooksMutex sync.Mutex
+	afterTxHooks      = map[*xorm.Session][]func(){}
+)
+
+// AfterTx calls f once the transaction of the context is committed, or right away if the context has no transaction.
+// f is never called if the transaction is rolled back, so it can announce changes made in the transaction.
+func AfterTx(ctx context.Context, f func()) {
+	sess, ok := inTransaction(ctx)
+	if !ok {
+		f()
+		return
+	}
+	afterTxHooksMutex.Lock()
+	defer afterTxHooksMutex.Unlock()
+	afterTxHooks[sess] = append(afterTxHooks[sess], f)
+}
+
+func popAfterTxHooks(sess *xorm.Session) []func() {
+	afterTxHooksMutex.Lock()
+	defer afterTxHooksMutex.Unlock()
+	hooks := afterTxHooks[sess]
+	delete(afterTxHooks, sess)
+	return hooks
+}
+
 // TxContext represents a transaction Context,
 // it will reuse the existing transaction in the parent context or create a new one.
 // Some tips to use:
@@ -132,7 +181,7 @@ func (c *halfCommitter) Close() error {
 //	  d. It doesn't mean rollback is forbidden, but always do it only when there is an error, and you do want to rollback.
 func TxContext(parentCtx context.Context) (*Context, Committer, error) {
 	if sess, ok := inTransaction(parentCtx); ok {
-		return newContext(parentCtx, sess, true), &halfCommitter{committer: sess}, nil
+		return newContext(parentCtx, sess, true), &halfCommitter{committer: txCommitter{sess}}, nil
 	}
 
 	sess := x.NewSession()
@@ -141,7 +190,7 @@ func TxContext(parentCtx context.Context) (*Context, Committer, error) {
 		return nil, nil, err
 	}
 
-	return newContext(DefaultContext, sess, true), sess, nil
+	return newContext(DefaultContext, sess, true), txCommitter{sess}, nil
 }
 
 // WithTx represents executing database operations on a transaction, if the transaction exist,
@@ -151,7 +200,7 @@ func WithTx(parentCtx context.Context, f func(ctx context.Context) error) error
 		err := f(newContext(parentCtx, sess, true))
 		if err != nil {
 			// rollback immediately, in case the caller ignores returned error and tries to commit the transaction.
-			_ = sess.Close()
+			_ = txCommitter{sess}.Close()
 		}
 		return err
 	}
@@ -160,7 +209,8 @@ func WithTx(parentCtx context.Context, f func(ctx context.Context) error) error
 
 func txWithNoCheck(parentCtx context.Context, f func(ctx context.Context) error) error {
 	sess := x.NewSession()
-	defer sess.Close()
+	committer := txCommitter{sess}
+	defer committer.Close()
 	if err := sess.Begin(); err != nil {
 		return err
 	}
@@ -169,7 +219,7 @@ func txWithNoCheck(parentCtx context.Context, f func(ctx context.Context) error)
 		return err
 	}
 
-	return sess.Commit()
+	return committer.Commit()
 }
 
 // Insert inserts records into database
@@ -5,6 +5,7 @@ package db_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"code.gitea.io/gitea/models/db"
@@ -85,3 +86,46 @@ func TestTxContext(t *testing.T) {
 		}))
 	}
 }
+
+func TestAfterTx(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	called := 0
+	db.AfterTx(db.DefaultContext, func() { called++ })
+	assert.Equal(t, 1, called)
+
+	called = 0
+	require.NoError(t, db.WithTx(db.DefaultContext, func(ctx context.Context) error {
+		db.AfterTx(ctx, func() { called++ })
+		assert.Zero(t, called)
+		return nil
+	}))
+	assert.Equal(t, 1, called)
+
+	called = 0
+	require.Error(t, db.WithTx(db.DefaultContext, func(ctx context.Context) error {
+		db.AfterTx(ctx, func() { called++ })
+		return errors.New("rollback")
+	}))
+	assert.Zero(t, called)
+
+	// the functions registered in a reused transaction are called after the outer commit
+	called = 0
+	ctx, committer, err := db.TxContext(db.DefaultContext)
+	require.NoError(t, err)
+	require.NoError(t, db.WithTx(ctx, func(ctx context.Context) error {
+		db.AfterTx(ctx, func() { called++ })
+		return nil
+	}))
+	assert.Zero(t, called)
+	require.NoError(t, committer.Commit())
+	require.NoError(t, committer.Close())
+	assert.Equal(t, 1, called)
+
+	called = 0
+	ctx, committer, err = db.TxContext(db.DefaultContext)
+	require.NoError(t, err)
+	db.AfterTx(ctx, func() { called++ })
+	require.NoError(t, committer.Close())
+	assert.Zero(t, called)
+}
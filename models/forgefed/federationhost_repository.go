@@ -59,3 +59,14 @@ func UpdateFederationHost(ctx context.Context, host *FederationHost) error {
 	_, err := db.GetEngine(ctx).ID(host.ID).Update(host)
 	return err
 }
+
+// FindFederationHosts returns the hosts which contacted this instance ordered by their name
+func FindFederationHosts(ctx context.Context, opts db.ListOptions) ([]*FederationHost, int64, error) {
+	sess := db.GetEngine(ctx).OrderBy("host_fqdn")
+	if !opts.IsListAll() {
+		sess = db.SetSessionPagination(sess, &opts)
+	}
+	hosts := make([]*FederationHost, 0, 10)
+	count, err := sess.FindAndCount(&hosts)
+	return hosts, count, err
+}
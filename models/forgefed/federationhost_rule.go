@@ -0,0 +1,182 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgefed
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+)
+
+// FederationHostAction is how the activities of the hosts matching a FederationHostRule are treated
+type FederationHostAction int
+
+const (
+	// FederationHostActionNone applies to the hosts without a rule
+	FederationHostActionNone FederationHostAction = iota
+	// FederationHostActionAllow lets the host federate, also in allowlist mode
+	FederationHostActionAllow
+	// FederationHostActionSilence drops the stars, issues and comments sent by the users of the host
+	FederationHostActionSilence
+	// FederationHostActionBlock rejects all requests of the host and stops federating with it
+	FederationHostActionBlock
+)
+
+var federationHostActionNames = map[FederationHostAction]string{
+	FederationHostActionNone:    "none",
+	FederationHostActionAllow:   "allow",
+	FederationHostActionSilence: "silence",
+	FederationHostActionBlock:   "block",
+}
+
+func (a FederationHostAction) String() string {
+	return federationHostActionNames[a]
+}
+
+// ParseFederationHostAction returns the action of a rule by its name
+func ParseFederationHostAction(name string) (FederationHostAction, bool) {
+	for a, n := range federationHostActionNames {
+		if a != FederationHostActionNone && n == name {
+			return a, true
+		}
+	}
+	return FederationHostActionNone, false
+}
+
+// FederationHostRule moderates the federation with a host, or with all subdomains of a domain
+// if the pattern starts with "*.", e.g. "*.example.com".
+type FederationHostRule struct {
+	ID          int64                `xorm:"pk autoincr"`
+	Pattern     string               `xorm:"UNIQUE VARCHAR(255) NOT NULL"`
+	Action      FederationHostAction `xorm:"NOT NULL DEFAULT 0"`
+	Reason      string               `xorm:"TEXT"`
+	CreatedUnix timeutil.TimeStamp   `xorm:"created"`
+	UpdatedUnix timeutil.TimeStamp   `xorm:"updated"`
+}
+
+func init() {
+	db.RegisterModel(new(FederationHostRule))
+}
+
+// ErrInvalidFederationHostPattern represents a pattern which is neither a host name nor a wildcard domain
+type ErrInvalidFederationHostPattern struct {
+	Pattern string
+}
+
+func (err ErrInvalidFederationHostPattern) Error() string {
+	return fmt.Sprintf("invalid federation host pattern [pattern: %s]", err.Pattern)
+}
+
+func (err ErrInvalidFederationHostPattern) Unwrap() error {
+	return util.ErrInvalidArgument
+}
+
+var hostNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+
+// NormalizeFederationHostPattern validates a host name or a wildcard domain and returns it in its stored form
+func NormalizeFederationHostPattern(pattern string) (string, error) {
+	normalized := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(pattern)), ".")
+	if len(normalized) > 255 || !hostNamePattern.MatchString(strings.TrimPrefix(normalized, "*.")) {
+		return "", ErrInvalidFederationHostPattern{Pattern: pattern}
+	}
+	return normalized, nil
+}
+
+// IsWildcard returns true if the rule applies to the subdomains of a domain
+func (rule *FederationHostRule) IsWildcard() bool {
+	return strings.HasPrefix(rule.Pattern, "*.")
+}
+
+// Matches returns true if the rule applies to the host, a wildcard rule doesn't apply to the domain itself
+func (rule *FederationHostRule) Matches(host string) bool {
+	host = strings.ToLower(host)
+	if rule.IsWildcard() {
+		return strings.HasSuffix(host, rule.Pattern[1:])
+	}
+	return host == rule.Pattern
+}
+
+// MatchFederationHostRule returns the most specific of the rules applying to the host:
+// a rule for the host itself wins over wildcard rules, and rules for subdomains win over their parent domains.
+func MatchFederationHostRule(rules []*FederationHostRule, host string) *FederationHostRule {
+	var match *FederationHostRule
+	for _, rule := range rules {
+		if !rule.Matches(host) {
+			continue
+		}
+		if !rule.IsWildcard() {
+			return rule
+		}
+		if match == nil || len(rule.Pattern) > len(match.Pattern) {
+			match = rule
+		}
+	}
+	return match
+}
+
+// FindFederationHostRules returns all rules ordered by their pattern
+func FindFederationHostRules(ctx context.Context) ([]*FederationHostRule, error) {
+	rules := make([]*FederationHostRule, 0, 10)
+	return rules, db.GetEngine(ctx).OrderBy("pattern").Find(&rules)
+}
+
+// GetFederationHostRule returns the rule with the pattern, nil if there is none
+func GetFederationHostRule(ctx context.Context, pattern string) (*FederationHostRule, error) {
+	pattern, err := NormalizeFederationHostPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	rule := new(FederationHostRule)
+	if has, err := db.GetEngine(ctx).Where("pattern = ?", pattern).Get(rule); err != nil {
+		return nil, err
+	} else if !has {
+		return nil, nil
+	}
+	return rule, nil
+}
+
+// SetFederationHostRule creates the rule or updates the action and reason of the rule with the same pattern
+func SetFederationHostRule(ctx context.Context, rule *FederationHostRule) error {
+	pattern, err := NormalizeFederationHostPattern(rule.Pattern)
+	if err != nil {
+		return err
+	}
+	if _, ok := federationHostActionNames[rule.Action]; !ok || rule.Action == FederationHostActionNone {
+		return util.NewInvalidArgumentErrorf("invalid federation host action %d", rule.Action)
+	}
+	rule.Pattern = pattern
+
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		existing, err := GetFederationHostRule(ctx, pattern)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return db.Insert(ctx, rule)
+		}
+		rule.ID = existing.ID
+		rule.CreatedUnix = existing.CreatedUnix
+		_, err = db.GetEngine(ctx).ID(rule.ID).Cols("action", "reason").Update(rule)
+		return err
+	})
+}
+
+// DeleteFederationHostRule deletes the rule with the pattern
+func DeleteFederationHostRule(ctx context.Context, pattern string) error {
+	pattern, err := NormalizeFederationHostPattern(pattern)
+	if err != nil {
+		return err
+	}
+	if n, err := db.GetEngine(ctx).Where("pattern = ?", pattern).Delete(new(FederationHostRule)); err != nil {
+		return err
+	} else if n == 0 {
+		return util.NewNotExistErrorf("federation host rule %s does not exist", pattern)
+	}
+	return nil
+}
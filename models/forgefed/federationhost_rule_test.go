@@ -0,0 +1,67 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgefed
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NormalizeFederationHostPattern(t *testing.T) {
+	for pattern, want := range map[string]string{
+		"Example.COM":      "example.com",
+		" example.com. ":   "example.com",
+		"*.Example.com":    "*.example.com",
+		"127.0.0.1":        "127.0.0.1",
+		"xn--bcher-kva.ch": "xn--bcher-kva.ch",
+	} {
+		got, err := NormalizeFederationHostPattern(pattern)
+		require.NoError(t, err, pattern)
+		assert.Equal(t, want, got)
+	}
+
+	for _, pattern := range []string{"", "*", "*.", "example.*", "a.*.example.com", "https://example.com", "example.com:3000", "-example.com"} {
+		_, err := NormalizeFederationHostPattern(pattern)
+		assert.ErrorAs(t, err, &ErrInvalidFederationHostPattern{}, pattern)
+	}
+}
+
+func Test_MatchFederationHostRule(t *testing.T) {
+	rules := []*FederationHostRule{
+		{Pattern: "*.example.com", Action: FederationHostActionBlock},
+		{Pattern: "*.forge.example.com", Action: FederationHostActionSilence},
+		{Pattern: "code.forge.example.com", Action: FederationHostActionAllow},
+	}
+
+	for host, want := range map[string]FederationHostAction{
+		"example.com":              FederationHostActionNone,
+		"notexample.com":           FederationHostActionNone,
+		"www.example.com":          FederationHostActionBlock,
+		"forge.example.com":        FederationHostActionBlock,
+		"git.forge.example.com":    FederationHostActionSilence,
+		"code.forge.example.com":   FederationHostActionAllow,
+		"CODE.forge.example.com":   FederationHostActionAllow,
+		"a.code.forge.example.com": FederationHostActionSilence,
+	} {
+		action := FederationHostActionNone
+		if rule := MatchFederationHostRule(rules, host); rule != nil {
+			action = rule.Action
+		}
+		assert.Equal(t, want, action, host)
+	}
+}
+
+func Test_ParseFederationHostAction(t *testing.T) {
+	action, ok := ParseFederationHostAction("silence")
+	assert.True(t, ok)
+	assert.Equal(t, FederationHostActionSilence, action)
+	assert.Equal(t, "silence", action.String())
+
+	_, ok = ParseFederationHostAction("none")
+	assert.False(t, ok)
+	_, ok = ParseFederationHostAction("unknown")
+	assert.False(t, ok)
+}
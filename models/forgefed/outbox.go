@@ -0,0 +1,203 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgefed
+
+import (
+	"context"
+	"fmt"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+
+	"xorm.io/builder"
+)
+
+// OutboxActivity is an ActivityPub activity published by a local user,
+// it's listed in the outbox of the user and delivered to the inboxes of its recipients.
+type OutboxActivity struct {
+	ID     int64  `xorm:"pk autoincr"`
+	UserID int64  `xorm:"INDEX NOT NULL"`
+	Type   string `xorm:"VARCHAR(32) NOT NULL"`
+	// Content is the JSON-LD serialization of the activity
+	Content     string             `xorm:"LONGTEXT NOT NULL"`
+	CreatedUnix timeutil.TimeStamp `xorm:"INDEX created"`
+}
+
+// TableName sets the table name to `federation_outbox_activity`
+func (a *OutboxActivity) TableName() string {
+	return "federation_outbox_activity"
+}
+
+// OutboxRecipient is a recipient an outbox activity is addressed to, the activity is listed in the outbox
+// of the user for it or for everyone if it is the public collection
+type OutboxRecipient struct {
+	ID         int64  `xorm:"pk autoincr"`
+	ActivityID int64  `xorm:"INDEX NOT NULL"`
+	Recipient  string `xorm:"TEXT NOT NULL"`
+}
+
+// TableName sets the table name to `federation_outbox_recipient`
+func (r *OutboxRecipient) TableName() string {
+	return "federation_outbox_recipient"
+}
+
+// Delivery is a pending delivery of an outbox activity to the inbox of a recipient
+type Delivery struct {
+	ID              int64              `xorm:"pk autoincr"`
+	ActivityID      int64              `xorm:"INDEX NOT NULL"`
+	Inbox           string             `xorm:"TEXT NOT NULL"`
+	Attempts        int                `xorm:"NOT NULL DEFAULT 0"`
+	LastError       string             `xorm:"TEXT"`
+	NextAttemptUnix timeutil.TimeStamp `xorm:"INDEX NOT NULL"`
+	CreatedUnix     timeutil.TimeStamp `xorm:"created"`
+}
+
+// TableName sets the table name to `federation_delivery`
+func (d *Delivery) TableName() string {
+	return "federation_delivery"
+}
+
+func init() {
+	db.RegisterModel(new(OutboxActivity))
+	db.RegisterModel(new(OutboxRecipient))
+	db.RegisterModel(new(Delivery))
+}
+
+// ErrOutboxActivityNotExist represents a "OutboxActivityNotExist" kind of error.
+type ErrOutboxActivityNotExist struct {
+	ID int64
+}
+
+func (err ErrOutboxActivityNotExist) Error() string {
+	return fmt.Sprintf("outbox activity does not exist [id: %d]", err.ID)
+}
+
+func (err ErrOutboxActivityNotExist) Unwrap() error {
+	return util.ErrNotExist
+}
+
+// ErrDeliveryNotExist represents a "DeliveryNotExist" kind of error.
+type ErrDeliveryNotExist struct {
+	ID int64
+}
+
+func (err ErrDeliveryNotExist) Error() string {
+	return fmt.Sprintf("delivery does not exist [id: %d]", err.ID)
+}
+
+func (err ErrDeliveryNotExist) Unwrap() error {
+	return util.ErrNotExist
+}
+
+// CreateOutboxActivity inserts the activity addressed to the recipients, content is called with its ID
+// to serialize it and a delivery to each of the inboxes is scheduled
+func CreateOutboxActivity(ctx context.Context, a *OutboxActivity, content func(id int64) ([]byte, error), recipients, inboxes []string) ([]*Delivery, error) {
+	deliveries := make([]*Delivery, 0, len(inboxes))
+	return deliveries, db.WithTx(ctx, func(ctx context.Context) error {
+		if err := db.Insert(ctx, a); err != nil {
+			return err
+		}
+		b, err := content(a.ID)
+		if err != nil {
+			return err
+		}
+		a.Content = string(b)
+		if _, err := db.GetEngine(ctx).ID(a.ID).Cols("content").Update(a); err != nil {
+			return err
+		}
+
+		for _, recipient := range recipients {
+			if err := db.Insert(ctx, &OutboxRecipient{ActivityID: a.ID, Recipient: recipient}); err != nil {
+				return err
+			}
+		}
+
+		for _, inbox := range inboxes {
+			d := &Delivery{ActivityID: a.ID, Inbox: inbox, NextAttemptUnix: timeutil.TimeStampNow()}
+			if err := db.Insert(ctx, d); err != nil {
+				return err
+			}
+			deliveries = append(deliveries, d)
+		}
+		return nil
+	})
+}
+
+// GetOutboxActivityByID returns the outbox activity with the given ID
+func GetOutboxActivityByID(ctx context.Context, id int64) (*OutboxActivity, error) {
+	a, exist, err := db.GetByID[OutboxActivity](ctx, id)
+	if err != nil {
+		return nil, err
+	} else if !exist {
+		return nil, ErrOutboxActivityNotExist{ID: id}
+	}
+	return a, nil
+}
+
+// FindOutboxActivitiesOptions represents the options to find the activities in the outbox of a user
+type FindOutboxActivitiesOptions struct {
+	db.ListOptions
+	UserID int64
+	// Recipients limits the activities to the ones addressed to any of them
+	Recipients []string
+}
+
+func (opts FindOutboxActivitiesOptions) ToConds() builder.Cond {
+	cond := builder.NewCond().And(builder.Eq{"user_id": opts.UserID})
+	if opts.Recipients != nil {
+		cond = cond.And(builder.In("id", builder.Select("activity_id").From("federation_outbox_recipient").Where(builder.In("recipient", opts.Recipients))))
+	}
+	return cond
+}
+
+func (opts FindOutboxActivitiesOptions) ToOrders() string {
+	return "id DESC"
+}
+
+// DeleteOutboxActivitiesByUserID deletes the outbox activities of the user, their recipients and pending deliveries
+func DeleteOutboxActivitiesByUserID(ctx context.Context, userID int64) error {
+	activityIDs := builder.Select("id").From("federation_outbox_activity").Where(builder.Eq{"user_id": userID})
+	if _, err := db.GetEngine(ctx).Where(builder.In("activity_id", activityIDs)).Delete(new(OutboxRecipient)); err != nil {
+		return err
+	}
+	if _, err := db.GetEngine(ctx).Where(builder.In("activity_id", activityIDs)).Delete(new(Delivery)); err != nil {
+		return err
+	}
+	_, err := db.DeleteByBean(ctx, &OutboxActivity{UserID: userID})
+	return err
+}
+
+// GetDeliveryByID returns the delivery with the given ID
+func GetDeliveryByID(ctx context.Context, id int64) (*Delivery, error) {
+	d, exist, err := db.GetByID[Delivery](ctx, id)
+	if err != nil {
+		return nil, err
+	} else if !exist {
+		return nil, ErrDeliveryNotExist{ID: id}
+	}
+	return d, nil
+}
+
+// FindDueDeliveryIDs returns the IDs of the deliveries whose next attempt is due
+func FindDueDeliveryIDs(ctx context.Context) ([]int64, error) {
+	ids := make([]int64, 0, 10)
+	return ids, db.GetEngine(ctx).Table("federation_delivery").
+		Where(builder.Lte{"next_attempt_unix": timeutil.TimeStampNow()}).
+		Cols("id").
+		OrderBy("id").
+		Find(&ids)
+}
+
+// UpdateDeliveryAttempt records a failed attempt of the delivery and when it is attempted next
+func UpdateDeliveryAttempt(ctx context.Context, d *Delivery) error {
+	_, err := db.GetEngine(ctx).ID(d.ID).Cols("attempts", "last_error", "next_attempt_unix").Update(d)
+	return err
+}
+
+// DeleteDelivery deletes the delivery once it succeeded or was given up
+func DeleteDelivery(ctx context.Context, d *Delivery) error {
+	_, err := db.DeleteByID[Delivery](ctx, d.ID)
+	return err
+}
@@ -0,0 +1,72 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgefed
+
+import (
+	"context"
+	"time"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+)
+
+// RemoteKey is a cached public key of an actor of another instance,
+// used to verify the signatures of the requests of the actor
+type RemoteKey struct {
+	ID           int64              `xorm:"pk autoincr"`
+	KeyID        string             `xorm:"UNIQUE VARCHAR(255) NOT NULL"`
+	OwnerURI     string             `xorm:"TEXT NOT NULL"`
+	PublicKeyPem string             `xorm:"TEXT NOT NULL"`
+	FetchedUnix  timeutil.TimeStamp `xorm:"NOT NULL"`
+}
+
+// TableName sets the table name to `federation_remote_key`
+func (k *RemoteKey) TableName() string {
+	return "federation_remote_key"
+}
+
+func init() {
+	db.RegisterModel(new(RemoteKey))
+}
+
+// IsStale returns true if the key was fetched longer than ttl ago
+func (k *RemoteKey) IsStale(ttl time.Duration) bool {
+	return k.FetchedUnix.AddDuration(ttl) <= timeutil.TimeStampNow()
+}
+
+// GetRemoteKey returns the cached key with the ID, nil if there is none
+func GetRemoteKey(ctx context.Context, keyID string) (*RemoteKey, error) {
+	k := new(RemoteKey)
+	if has, err := db.GetEngine(ctx).Where("key_id = ?", keyID).Get(k); err != nil {
+		return nil, err
+	} else if !has {
+		return nil, nil
+	}
+	return k, nil
+}
+
+// SetRemoteKey caches the key or replaces the cached key with the same ID
+func SetRemoteKey(ctx context.Context, k *RemoteKey) error {
+	if k.FetchedUnix == 0 {
+		k.FetchedUnix = timeutil.TimeStampNow()
+	}
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		existing, err := GetRemoteKey(ctx, k.KeyID)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return db.Insert(ctx, k)
+		}
+		k.ID = existing.ID
+		_, err = db.GetEngine(ctx).ID(k.ID).Cols("owner_uri", "public_key_pem", "fetched_unix").Update(k)
+		return err
+	})
+}
+
+// DeleteRemoteKey removes the key with the ID from the cache
+func DeleteRemoteKey(ctx context.Context, keyID string) error {
+	_, err := db.GetEngine(ctx).Where("key_id = ?", keyID).Delete(new(RemoteKey))
+	return err
+}
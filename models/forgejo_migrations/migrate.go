@@ -82,6 +82,50 @@ var migrations = []*Migration{
 	NewMigration("Add SSH keypair to `pull_mirror` table", AddSSHKeypairToPushMirror),
 	// v22 -> v23
 	NewMigration("Add `legacy` to `web_authn_credential` table", AddLegacyToWebAuthnCredential),
+	// v23 -> v24
+	NewMigration("Create the `package_remote` table", CreatePackageRemoteTable),
+	// v24 -> v25
+	NewMigration("Create the `package_virtual_registry` table", CreatePackageVirtualRegistryTable),
+	// v25 -> v26
+	NewMigration("Add retention policies to `package_cleanup_rule` and `last_download_unix` to `package_version`", AddPackageCleanupRetentionPolicies),
+	// v26 -> v27
+	NewMigration("Create the `package_attestation` and `package_trusted_key` tables", CreatePackageAttestationTables),
+	// v27 -> v28
+	NewMigration("Create the `package_advisory` and `package_version_advisory` tables", CreatePackageAdvisoryTables),
+	// v28 -> v29
+	NewMigration("Create the `package_protection_rule` table", CreatePackageProtectionRuleTable),
+	// v29 -> v30
+	NewMigration("Add `inherit_repo_permissions` to `package`", AddInheritRepoPermissionsToPackage),
+	// v30 -> v31
+	NewMigration("Add fine-grained restrictions and expiry to `access_token`", AddFineGrainedAccessTokens),
+	// v31 -> v32
+	NewMigration("Create the `scim_token` and `scim_group` tables", CreateSCIMTables),
+	// v32 -> v33
+	NewMigration("Create the `oauth2_device_code` table", CreateOAuth2DeviceCodeTable),
+	// v33 -> v34
+	NewMigration("Add `discoverable` column to the `webauthn_credential` table", AddDiscoverableToWebAuthnCredential),
+	// v34 -> v35
+	NewMigration("Create the `org_two_factor_policy` table", CreateOrgTwoFactorPolicyTable),
+	// v35 -> v36
+	NewMigration("Create the `audit_event` table", CreateAuditEventTable),
+	// v36 -> v37
+	NewMigration("Create the `user_session` table", CreateUserSessionTable),
+	// v37 -> v38
+	NewMigration("Create the `org_ip_allowlist` table", CreateOrgIPAllowlistTable),
+	// v38 -> v39
+	NewMigration("Create the `federated_follow`, `federation_outbox_activity` and `federation_delivery` tables", CreateFederatedFollowTables),
+	// v39 -> v40
+	NewMigration("Create the `federated_issue` and `federated_comment` tables", CreateFederatedIssueTables),
+	// v40 -> v41
+	NewMigration("Create the `federation_host_rule` table", CreateFederationHostRuleTable),
+	// v41 -> v42
+	NewMigration("Create the `federation_remote_key` table", CreateFederationRemoteKeyTable),
+	// v42 -> v43
+	NewMigration("Create the `follower_repo` table", CreateFollowerRepoTable),
+	// v43 -> v44
+	NewMigration("Create the `federation_outbox_recipient` table", CreateFederationOutboxRecipientTable),
+	// v44 -> v45
+	NewMigration("Add `auth_host` column to the `package_remote` table", AddAuthHostToPackageRemote),
 }
 
 // GetCurrentDBVersion returns the current Forgejo database version.
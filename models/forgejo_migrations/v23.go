@@ -0,0 +1,26 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func CreatePackageRemoteTable(x *xorm.Engine) error {
+	type PackageRemote struct {
+		ID                int64              `xorm:"pk autoincr"`
+		Enabled           bool               `xorm:"INDEX NOT NULL DEFAULT false"`
+		OwnerID           int64              `xorm:"UNIQUE(s) INDEX NOT NULL DEFAULT 0"`
+		Type              string             `xorm:"UNIQUE(s) INDEX NOT NULL"`
+		URL               string             `xorm:"TEXT NOT NULL"`
+		Username          string             `xorm:"NOT NULL DEFAULT ''"`
+		PasswordEncrypted string             `xorm:"TEXT"`
+		CreatedUnix       timeutil.TimeStamp `xorm:"created NOT NULL DEFAULT 0"`
+		UpdatedUnix       timeutil.TimeStamp `xorm:"updated NOT NULL DEFAULT 0"`
+	}
+
+	return x.Sync(new(PackageRemote))
+}
@@ -0,0 +1,25 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func AddPackageCleanupRetentionPolicies(x *xorm.Engine) error {
+	type PackageCleanupRule struct {
+		ID                int64 `xorm:"pk autoincr"`
+		KeepSemverPatches int   `xorm:"NOT NULL DEFAULT 0"`
+		RemoveUnusedDays  int   `xorm:"NOT NULL DEFAULT 0"`
+	}
+
+	type PackageVersion struct {
+		ID               int64              `xorm:"pk autoincr"`
+		LastDownloadUnix timeutil.TimeStamp `xorm:"INDEX NOT NULL DEFAULT 0"`
+	}
+
+	return x.Sync(new(PackageCleanupRule), new(PackageVersion))
+}
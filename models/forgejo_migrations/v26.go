@@ -0,0 +1,35 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func CreatePackageAttestationTables(x *xorm.Engine) error {
+	type PackageAttestation struct {
+		ID            int64              `xorm:"pk autoincr"`
+		VersionID     int64              `xorm:"INDEX NOT NULL"`
+		CreatorID     int64              `xorm:"NOT NULL DEFAULT 0"`
+		PredicateType string             `xorm:"NOT NULL"`
+		Envelope      string             `xorm:"LONGTEXT NOT NULL"`
+		IsVerified    bool               `xorm:"INDEX NOT NULL DEFAULT false"`
+		KeyID         string             `xorm:"NOT NULL DEFAULT ''"`
+		CreatedUnix   timeutil.TimeStamp `xorm:"created INDEX NOT NULL"`
+	}
+
+	type PackageTrustedKey struct {
+		ID          int64              `xorm:"pk autoincr"`
+		OwnerID     int64              `xorm:"UNIQUE(s) INDEX NOT NULL DEFAULT 0"`
+		Name        string             `xorm:"NOT NULL"`
+		Content     string             `xorm:"TEXT NOT NULL"`
+		Fingerprint string             `xorm:"UNIQUE(s) NOT NULL"`
+		CreatedUnix timeutil.TimeStamp `xorm:"created NOT NULL DEFAULT 0"`
+		UpdatedUnix timeutil.TimeStamp `xorm:"updated NOT NULL DEFAULT 0"`
+	}
+
+	return x.Sync(new(PackageAttestation), new(PackageTrustedKey))
+}
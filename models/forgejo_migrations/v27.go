@@ -0,0 +1,35 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func CreatePackageAdvisoryTables(x *xorm.Engine) error {
+	type PackageAdvisory struct {
+		ID           int64              `xorm:"pk autoincr"`
+		AdvisoryID   string             `xorm:"UNIQUE(s) INDEX NOT NULL"`
+		PackageType  string             `xorm:"UNIQUE(s) INDEX NOT NULL"`
+		LowerName    string             `xorm:"UNIQUE(s) INDEX NOT NULL"`
+		Aliases      []string           `xorm:"TEXT JSON"`
+		Summary      string             `xorm:"TEXT"`
+		Severity     string             `xorm:"INDEX NOT NULL DEFAULT ''"`
+		Affected     string             `xorm:"LONGTEXT NOT NULL"`
+		ModifiedUnix timeutil.TimeStamp `xorm:"NOT NULL DEFAULT 0"`
+		CreatedUnix  timeutil.TimeStamp `xorm:"created NOT NULL DEFAULT 0"`
+		UpdatedUnix  timeutil.TimeStamp `xorm:"updated NOT NULL DEFAULT 0"`
+	}
+
+	type PackageVersionAdvisory struct {
+		ID          int64              `xorm:"pk autoincr"`
+		VersionID   int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+		AdvisoryID  int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+		CreatedUnix timeutil.TimeStamp `xorm:"created NOT NULL DEFAULT 0"`
+	}
+
+	return x.Sync(new(PackageAdvisory), new(PackageVersionAdvisory))
+}
@@ -0,0 +1,15 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import "xorm.io/xorm"
+
+func AddInheritRepoPermissionsToPackage(x *xorm.Engine) error {
+	type Package struct {
+		ID                     int64 `xorm:"pk autoincr"`
+		InheritRepoPermissions bool  `xorm:"NOT NULL DEFAULT false"`
+	}
+
+	return x.Sync(new(Package))
+}
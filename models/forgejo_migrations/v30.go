@@ -0,0 +1,28 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func AddFineGrainedAccessTokens(x *xorm.Engine) error {
+	type AccessToken struct {
+		ID                 int64              `xorm:"pk autoincr"`
+		OrgID              int64              `xorm:"NOT NULL DEFAULT 0"`
+		Permissions        map[string]int     `xorm:"TEXT JSON"`
+		ExpiresUnix        timeutil.TimeStamp `xorm:"INDEX NOT NULL DEFAULT 0"`
+		ExpiryNotifiedUnix timeutil.TimeStamp `xorm:"NOT NULL DEFAULT 0"`
+	}
+
+	type AccessTokenRepository struct {
+		ID      int64 `xorm:"pk autoincr"`
+		TokenID int64 `xorm:"UNIQUE(s) INDEX NOT NULL"`
+		RepoID  int64 `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	}
+
+	return x.Sync(new(AccessToken), new(AccessTokenRepository))
+}
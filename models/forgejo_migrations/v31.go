@@ -0,0 +1,31 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func CreateSCIMTables(x *xorm.Engine) error {
+	type SCIMToken struct {
+		ID             int64  `xorm:"pk autoincr"`
+		SourceID       int64  `xorm:"UNIQUE NOT NULL"`
+		TokenHash      string `xorm:"UNIQUE"`
+		TokenSalt      string
+		TokenLastEight string             `xorm:"INDEX token_last_eight"`
+		CreatedUnix    timeutil.TimeStamp `xorm:"created"`
+	}
+
+	type SCIMGroup struct {
+		ID          int64              `xorm:"pk autoincr"`
+		SourceID    int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+		TeamID      int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+		ExternalID  string             `xorm:"VARCHAR(255)"`
+		CreatedUnix timeutil.TimeStamp `xorm:"created"`
+	}
+
+	return x.Sync(new(SCIMToken), new(SCIMGroup))
+}
@@ -0,0 +1,35 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+// OAuth2DeviceCode is a snapshot of auth.OAuth2DeviceCode for this version of the database
+type OAuth2DeviceCode struct {
+	ID             int64  `xorm:"pk autoincr"`
+	ApplicationID  int64  `xorm:"INDEX"`
+	DeviceCode     string `xorm:"UNIQUE"`
+	UserCode       string `xorm:"UNIQUE"`
+	Scope          string `xorm:"TEXT"`
+	GrantID        int64  `xorm:"INDEX"`
+	Denied         bool   `xorm:"NOT NULL DEFAULT false"`
+	Interval       int64  `xorm:"NOT NULL DEFAULT 5"`
+	LastPolledUnix timeutil.TimeStamp
+	ValidUntil     timeutil.TimeStamp `xorm:"INDEX"`
+	CreatedUnix    timeutil.TimeStamp `xorm:"created"`
+}
+
+// TableName sets the database table name to be the correct one, as the
+// autogenerated table name for this struct is "o_auth2_device_code".
+func (code *OAuth2DeviceCode) TableName() string {
+	return "oauth2_device_code"
+}
+
+func CreateOAuth2DeviceCodeTable(x *xorm.Engine) error {
+	return x.Sync(new(OAuth2DeviceCode))
+}
@@ -0,0 +1,14 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import "xorm.io/xorm"
+
+func AddDiscoverableToWebAuthnCredential(x *xorm.Engine) error {
+	type WebauthnCredential struct {
+		Discoverable bool `xorm:"NOT NULL DEFAULT false"`
+	}
+
+	return x.Sync(new(WebauthnCredential))
+}
@@ -0,0 +1,23 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+// OrgTwoFactorPolicy is a snapshot of organization.TwoFactorPolicy for this version of the database
+type OrgTwoFactorPolicy struct {
+	ID              int64              `xorm:"pk autoincr"`
+	OrgID           int64              `xorm:"UNIQUE NOT NULL"`
+	RemoveAfterUnix timeutil.TimeStamp `xorm:"INDEX NOT NULL DEFAULT 0"`
+	CreatedUnix     timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix     timeutil.TimeStamp `xorm:"updated"`
+}
+
+func CreateOrgTwoFactorPolicyTable(x *xorm.Engine) error {
+	return x.Sync(new(OrgTwoFactorPolicy))
+}
@@ -0,0 +1,29 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+// AuditEvent is a snapshot of audit.Event for this version of the database
+type AuditEvent struct {
+	ID          int64              `xorm:"pk autoincr"`
+	Action      string             `xorm:"VARCHAR(64) INDEX NOT NULL"`
+	ActorID     int64              `xorm:"INDEX NOT NULL DEFAULT 0"`
+	ActorName   string             `xorm:"NOT NULL DEFAULT ''"`
+	IPAddress   string             `xorm:"VARCHAR(64) NOT NULL DEFAULT ''"`
+	OwnerID     int64              `xorm:"INDEX NOT NULL DEFAULT 0"`
+	TargetType  string             `xorm:"VARCHAR(32) INDEX NOT NULL DEFAULT ''"`
+	TargetID    int64              `xorm:"NOT NULL DEFAULT 0"`
+	TargetName  string             `xorm:"NOT NULL DEFAULT ''"`
+	Diff        map[string]any     `xorm:"TEXT JSON"`
+	CreatedUnix timeutil.TimeStamp `xorm:"INDEX created"`
+}
+
+func CreateAuditEventTable(x *xorm.Engine) error {
+	return x.Sync(new(AuditEvent))
+}
@@ -0,0 +1,25 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+// UserSession is a snapshot of auth.UserSession for this version of the database
+type UserSession struct {
+	ID           int64              `xorm:"pk autoincr"`
+	UID          int64              `xorm:"INDEX NOT NULL"`
+	AuthTokenID  int64              `xorm:"NOT NULL DEFAULT 0"`
+	UserAgent    string             `xorm:"TEXT"`
+	IPAddress    string             `xorm:"VARCHAR(64) NOT NULL DEFAULT ''"`
+	CreatedUnix  timeutil.TimeStamp `xorm:"created"`
+	LastSeenUnix timeutil.TimeStamp `xorm:"INDEX"`
+}
+
+func CreateUserSessionTable(x *xorm.Engine) error {
+	return x.Sync(new(UserSession))
+}
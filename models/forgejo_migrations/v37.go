@@ -0,0 +1,23 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+// OrgIPAllowlist is a snapshot of organization.IPAllowlist for this version of the database
+type OrgIPAllowlist struct {
+	ID          int64              `xorm:"pk autoincr"`
+	OrgID       int64              `xorm:"UNIQUE NOT NULL"`
+	Allowlist   string             `xorm:"TEXT NOT NULL"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
+}
+
+func CreateOrgIPAllowlistTable(x *xorm.Engine) error {
+	return x.Sync(new(OrgIPAllowlist))
+}
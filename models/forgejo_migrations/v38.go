@@ -0,0 +1,44 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+// FederatedFollow is a snapshot of user.FederatedFollow for this version of the database
+type FederatedFollow struct {
+	ID          int64              `xorm:"pk autoincr"`
+	UserID      int64              `xorm:"UNIQUE(federated_follow) NOT NULL"`
+	FollowID    int64              `xorm:"UNIQUE(federated_follow) NOT NULL"`
+	ActivityID  string             `xorm:"VARCHAR(255) INDEX NOT NULL"`
+	Accepted    bool               `xorm:"NOT NULL DEFAULT false"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+}
+
+// FederationOutboxActivity is a snapshot of forgefed.OutboxActivity for this version of the database
+type FederationOutboxActivity struct {
+	ID          int64              `xorm:"pk autoincr"`
+	UserID      int64              `xorm:"INDEX NOT NULL"`
+	Type        string             `xorm:"VARCHAR(32) NOT NULL"`
+	Content     string             `xorm:"LONGTEXT NOT NULL"`
+	CreatedUnix timeutil.TimeStamp `xorm:"INDEX created"`
+}
+
+// FederationDelivery is a snapshot of forgefed.Delivery for this version of the database
+type FederationDelivery struct {
+	ID              int64              `xorm:"pk autoincr"`
+	ActivityID      int64              `xorm:"INDEX NOT NULL"`
+	Inbox           string             `xorm:"TEXT NOT NULL"`
+	Attempts        int                `xorm:"NOT NULL DEFAULT 0"`
+	LastError       string             `xorm:"TEXT"`
+	NextAttemptUnix timeutil.TimeStamp `xorm:"INDEX NOT NULL"`
+	CreatedUnix     timeutil.TimeStamp `xorm:"created"`
+}
+
+func CreateFederatedFollowTables(x *xorm.Engine) error {
+	return x.Sync(new(FederatedFollow), new(FederationOutboxActivity), new(FederationDelivery))
+}
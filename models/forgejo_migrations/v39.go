@@ -0,0 +1,31 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+// FederatedIssue is a snapshot of issues.FederatedIssue for this version of the database
+type FederatedIssue struct {
+	ID          int64              `xorm:"pk autoincr"`
+	IssueID     int64              `xorm:"UNIQUE NOT NULL"`
+	OfferID     string             `xorm:"VARCHAR(255) INDEX NOT NULL"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+}
+
+// FederatedComment is a snapshot of issues.FederatedComment for this version of the database
+type FederatedComment struct {
+	ID          int64              `xorm:"pk autoincr"`
+	IssueID     int64              `xorm:"INDEX NOT NULL"`
+	CommentID   int64              `xorm:"UNIQUE NOT NULL"`
+	NoteID      string             `xorm:"VARCHAR(255) INDEX NOT NULL"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+}
+
+func CreateFederatedIssueTables(x *xorm.Engine) error {
+	return x.Sync(new(FederatedIssue), new(FederatedComment))
+}
@@ -0,0 +1,24 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+// FederationHostRule is a snapshot of forgefed.FederationHostRule for this version of the database
+type FederationHostRule struct {
+	ID          int64              `xorm:"pk autoincr"`
+	Pattern     string             `xorm:"UNIQUE VARCHAR(255) NOT NULL"`
+	Action      int                `xorm:"NOT NULL DEFAULT 0"`
+	Reason      string             `xorm:"TEXT"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
+}
+
+func CreateFederationHostRuleTable(x *xorm.Engine) error {
+	return x.Sync(new(FederationHostRule))
+}
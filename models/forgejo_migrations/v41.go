@@ -0,0 +1,23 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+// FederationRemoteKey is a snapshot of forgefed.RemoteKey for this version of the database
+type FederationRemoteKey struct {
+	ID           int64              `xorm:"pk autoincr"`
+	KeyID        string             `xorm:"UNIQUE VARCHAR(255) NOT NULL"`
+	OwnerURI     string             `xorm:"TEXT NOT NULL"`
+	PublicKeyPem string             `xorm:"TEXT NOT NULL"`
+	FetchedUnix  timeutil.TimeStamp `xorm:"NOT NULL"`
+}
+
+func CreateFederationRemoteKeyTable(x *xorm.Engine) error {
+	return x.Sync(new(FederationRemoteKey))
+}
@@ -0,0 +1,23 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+// FollowerRepo is a snapshot of repo.FollowerRepo for this version of the database
+type FollowerRepo struct {
+	ID               int64              `xorm:"pk autoincr"`
+	RepoID           int64              `xorm:"UNIQUE(follower_repo_mapping) NOT NULL"`
+	URI              string             `xorm:"UNIQUE(follower_repo_mapping) VARCHAR(255) NOT NULL"`
+	FederationHostID int64              `xorm:"INDEX NOT NULL"`
+	CreatedUnix      timeutil.TimeStamp `xorm:"created"`
+}
+
+func CreateFollowerRepoTable(x *xorm.Engine) error {
+	return x.Sync(new(FollowerRepo))
+}
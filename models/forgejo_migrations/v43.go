@@ -0,0 +1,17 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import "xorm.io/xorm"
+
+// FederationOutboxRecipient is a snapshot of forgefed.OutboxRecipient for this version of the database
+type FederationOutboxRecipient struct {
+	ID         int64  `xorm:"pk autoincr"`
+	ActivityID int64  `xorm:"INDEX NOT NULL"`
+	Recipient  string `xorm:"TEXT NOT NULL"`
+}
+
+func CreateFederationOutboxRecipientTable(x *xorm.Engine) error {
+	return x.Sync(new(FederationOutboxRecipient))
+}
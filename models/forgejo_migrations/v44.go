@@ -0,0 +1,14 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import "xorm.io/xorm"
+
+func AddAuthHostToPackageRemote(x *xorm.Engine) error {
+	type PackageRemote struct {
+		ID       int64  `xorm:"pk autoincr"`
+		AuthHost string `xorm:"NOT NULL DEFAULT ''"`
+	}
+	return x.Sync(new(PackageRemote))
+}
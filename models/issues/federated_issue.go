@@ -0,0 +1,76 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package issues
+
+import (
+	"context"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+)
+
+// FederatedIssue links an issue to the ForgeFed Offer activity which opened it on behalf of a user of another instance
+type FederatedIssue struct {
+	ID          int64              `xorm:"pk autoincr"`
+	IssueID     int64              `xorm:"UNIQUE NOT NULL"`
+	OfferID     string             `xorm:"VARCHAR(255) INDEX NOT NULL"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+}
+
+// FederatedComment links a comment to the ActivityPub Note it was created from
+type FederatedComment struct {
+	ID          int64              `xorm:"pk autoincr"`
+	IssueID     int64              `xorm:"INDEX NOT NULL"`
+	CommentID   int64              `xorm:"UNIQUE NOT NULL"`
+	NoteID      string             `xorm:"VARCHAR(255) INDEX NOT NULL"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+}
+
+func init() {
+	db.RegisterModel(new(FederatedIssue))
+	db.RegisterModel(new(FederatedComment))
+}
+
+// GetFederatedIssue returns the federation link of the issue, nil if it wasn't opened from another instance
+func GetFederatedIssue(ctx context.Context, issueID int64) (*FederatedIssue, error) {
+	f := new(FederatedIssue)
+	if has, err := db.GetEngine(ctx).Where("issue_id = ?", issueID).Get(f); err != nil {
+		return nil, err
+	} else if !has {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// GetFederatedIssueByOfferID returns the federation link of the issue opened by the Offer activity, nil if there is none
+func GetFederatedIssueByOfferID(ctx context.Context, offerID string) (*FederatedIssue, error) {
+	f := new(FederatedIssue)
+	if has, err := db.GetEngine(ctx).Where("offer_id = ?", offerID).Get(f); err != nil {
+		return nil, err
+	} else if !has {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// CreateFederatedIssue links the issue to the Offer activity which opened it
+func CreateFederatedIssue(ctx context.Context, f *FederatedIssue) error {
+	return db.Insert(ctx, f)
+}
+
+// GetFederatedCommentByNoteID returns the federation link of the comment created from the Note, nil if there is none
+func GetFederatedCommentByNoteID(ctx context.Context, issueID int64, noteID string) (*FederatedComment, error) {
+	f := new(FederatedComment)
+	if has, err := db.GetEngine(ctx).Where("issue_id = ? AND note_id = ?", issueID, noteID).Get(f); err != nil {
+		return nil, err
+	} else if !has {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// CreateFederatedComment links the comment to the Note it was created from
+func CreateFederatedComment(ctx context.Context, f *FederatedComment) error {
+	return db.Insert(ctx, f)
+}
@@ -0,0 +1,57 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package issues_test
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	issues_model "code.gitea.io/gitea/models/issues"
+	"code.gitea.io/gitea/models/unittest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFederatedIssue(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	f, err := issues_model.GetFederatedIssue(db.DefaultContext, 1)
+	require.NoError(t, err)
+	assert.Nil(t, f)
+
+	offerID := "https://example.com/api/v1/activitypub/user-id/1/outbox/1"
+	require.NoError(t, issues_model.CreateFederatedIssue(db.DefaultContext, &issues_model.FederatedIssue{IssueID: 1, OfferID: offerID}))
+
+	f, err = issues_model.GetFederatedIssue(db.DefaultContext, 1)
+	require.NoError(t, err)
+	require.NotNil(t, f)
+	assert.Equal(t, offerID, f.OfferID)
+
+	f, err = issues_model.GetFederatedIssueByOfferID(db.DefaultContext, offerID)
+	require.NoError(t, err)
+	require.NotNil(t, f)
+	assert.EqualValues(t, 1, f.IssueID)
+
+	f, err = issues_model.GetFederatedIssueByOfferID(db.DefaultContext, offerID+"0")
+	require.NoError(t, err)
+	assert.Nil(t, f)
+}
+
+func TestFederatedComment(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	noteID := "https://example.com/api/v1/activitypub/user-id/1/outbox/2"
+	require.NoError(t, issues_model.CreateFederatedComment(db.DefaultContext, &issues_model.FederatedComment{IssueID: 1, CommentID: 2, NoteID: noteID}))
+
+	f, err := issues_model.GetFederatedCommentByNoteID(db.DefaultContext, 1, noteID)
+	require.NoError(t, err)
+	require.NotNil(t, f)
+	assert.EqualValues(t, 2, f.CommentID)
+
+	// notes are only looked up in the issue they were created in
+	f, err = issues_model.GetFederatedCommentByNoteID(db.DefaultContext, 2, noteID)
+	require.NoError(t, err)
+	assert.Nil(t, f)
+}
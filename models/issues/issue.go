@@ -411,6 +411,11 @@ func (issue *Issue) HTMLURL() string {
 	return fmt.Sprintf("%s/%s/%d", issue.Repo.HTMLURL(), path, issue.Index)
 }
 
+// APObjectID returns the IRI of the issue as a ForgeFed Ticket
+func (issue *Issue) APObjectID() string {
+	return fmt.Sprintf("%s/issues/%d", issue.Repo.APActorID(), issue.Index)
+}
+
 // Link returns the issue's relative URL.
 func (issue *Issue) Link() string {
 	var path string
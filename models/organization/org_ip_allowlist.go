@@ -0,0 +1,251 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package organization
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"code.gitea.io/gitea/models/db"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/cache"
+	"code.gitea.io/gitea/modules/hostmatcher"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/web/middleware"
+)
+
+// IPAllowlist restricts the access to the repositories and packages of an organization
+// to the given networks, requests from other addresses only get the access of anonymous users.
+type IPAllowlist struct {
+	ID    int64 `xorm:"pk autoincr"`
+	OrgID int64 `xorm:"UNIQUE NOT NULL"`
+	// Allowlist is a comma separated list of CIDRs and the builtin networks of the hostmatcher
+	Allowlist   string             `xorm:"TEXT NOT NULL"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
+}
+
+func init() {
+	db.RegisterModel(new(IPAllowlist))
+}
+
+// TableName sets the table name to `org_ip_allowlist`
+func (a *IPAllowlist) TableName() string {
+	return "org_ip_allowlist"
+}
+
+// ErrInvalidIPAllowlistEntry represents an entry of an IP allowlist which is neither an IP address nor a network
+type ErrInvalidIPAllowlistEntry struct {
+	Entry string
+}
+
+func (err ErrInvalidIPAllowlistEntry) Error() string {
+	return fmt.Sprintf("invalid IP allowlist entry [entry: %s]", err.Entry)
+}
+
+func (err ErrInvalidIPAllowlistEntry) Unwrap() error {
+	return util.ErrInvalidArgument
+}
+
+// ParseIPAllowlist validates an IP allowlist given one entry per line or comma separated
+// and returns it in its stored form. Entries are IP addresses, CIDRs or one of the
+// builtin networks "private", "loopback" and "external".
+func ParseIPAllowlist(list string) (string, error) {
+	entries := make([]string, 0, 5)
+	for _, entry := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		switch entry {
+		case hostmatcher.MatchBuiltinPrivate, hostmatcher.MatchBuiltinLoopback, hostmatcher.MatchBuiltinExternal:
+			entries = append(entries, entry)
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			entries = append(entries, ipNet.String())
+			continue
+		}
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return "", ErrInvalidIPAllowlistEntry{Entry: entry}
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			entries = append(entries, (&net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}).String())
+		} else {
+			entries = append(entries, (&net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}).String())
+		}
+	}
+	return strings.Join(entries, ","), nil
+}
+
+// Entries returns the entries of the allowlist
+func (a *IPAllowlist) Entries() []string {
+	if a.Allowlist == "" {
+		return nil
+	}
+	return strings.Split(a.Allowlist, ",")
+}
+
+// AllowsIP returns true if the IP address is in one of the networks of the allowlist
+func (a *IPAllowlist) AllowsIP(ip string) bool {
+	return ipAllowlistMatcher(a.Allowlist).MatchIPAddr(net.ParseIP(ip))
+}
+
+// maxParsedIPAllowlists caps the number of parsed allowlists kept in memory
+const maxParsedIPAllowlists = 1000
+
+var (
+	parsedIPAllowlistsMutex sync.Mutex
+	parsedIPAllowlists      = make(map[string]*hostmatcher.HostMatchList)
+)
+
+// ipAllowlistMatcher returns the parsed form of the stored allowlist, allowlists are only parsed once
+func ipAllowlistMatcher(allowlist string) *hostmatcher.HostMatchList {
+	parsedIPAllowlistsMutex.Lock()
+	defer parsedIPAllowlistsMutex.Unlock()
+	if m, ok := parsedIPAllowlists[allowlist]; ok {
+		return m
+	}
+	if len(parsedIPAllowlists) >= maxParsedIPAllowlists {
+		clear(parsedIPAllowlists)
+	}
+	m := hostmatcher.ParseHostMatchList("org_ip_allowlist", allowlist)
+	parsedIPAllowlists[allowlist] = m
+	return m
+}
+
+const ipAllowlistsCacheKey = "org.ip_allowlists"
+
+// getIPAllowlists returns the allowlists of all organizations by organization ID. They are cached because
+// every permission check and search of a signed in user consults them, the cache is reset when they change.
+func getIPAllowlists(ctx context.Context) (map[int64]string, error) {
+	s, err := cache.GetString(ipAllowlistsCacheKey, func() (string, error) {
+		allowlists := make([]*IPAllowlist, 0, 10)
+		if err := db.GetEngine(ctx).Find(&allowlists); err != nil {
+			return "", err
+		}
+		m := make(map[int64]string, len(allowlists))
+		for _, a := range allowlists {
+			m[a.OrgID] = a.Allowlist
+		}
+		bs, err := json.Marshal(m)
+		return string(bs), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[int64]string)
+	return m, json.Unmarshal([]byte(s), &m)
+}
+
+// removeIPAllowlistsCache resets the cached allowlists once the transaction of the context is committed
+func removeIPAllowlistsCache(ctx context.Context) {
+	db.AfterTx(ctx, func() {
+		cache.Remove(ipAllowlistsCacheKey)
+	})
+}
+
+// GetIPAllowlist returns the IP allowlist of the organization,
+// it returns nil if the organization doesn't restrict the access by IP address
+func GetIPAllowlist(ctx context.Context, orgID int64) (*IPAllowlist, error) {
+	a := new(IPAllowlist)
+	if has, err := db.GetEngine(ctx).Where("org_id = ?", orgID).Get(a); err != nil {
+		return nil, err
+	} else if !has {
+		return nil, nil
+	}
+	return a, nil
+}
+
+// SetIPAllowlist restricts the access to the organization to the networks of the allowlist,
+// an empty allowlist removes the restriction
+func SetIPAllowlist(ctx context.Context, orgID int64, list string) error {
+	allowlist, err := ParseIPAllowlist(list)
+	if err != nil {
+		return err
+	}
+	if allowlist == "" {
+		return DeleteIPAllowlist(ctx, orgID)
+	}
+
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		a, err := GetIPAllowlist(ctx, orgID)
+		if err != nil {
+			return err
+		}
+		removeIPAllowlistsCache(ctx)
+		if a == nil {
+			return db.Insert(ctx, &IPAllowlist{OrgID: orgID, Allowlist: allowlist})
+		}
+		a.Allowlist = allowlist
+		_, err = db.GetEngine(ctx).ID(a.ID).Cols("allowlist").Update(a)
+		return err
+	})
+}
+
+// DeleteIPAllowlist stops restricting the access to the organization by IP address
+func DeleteIPAllowlist(ctx context.Context, orgID int64) error {
+	removeIPAllowlistsCache(ctx)
+	_, err := db.GetEngine(ctx).Where("org_id = ?", orgID).Delete(new(IPAllowlist))
+	return err
+}
+
+// IsIPAllowed returns false if the organization has an IP allowlist which doesn't contain the IP address,
+// an empty or unparsable address is never allowed by an allowlist
+func IsIPAllowed(ctx context.Context, orgID int64, ip string) (bool, error) {
+	allowlists, err := getIPAllowlists(ctx)
+	if err != nil {
+		return false, err
+	}
+	allowlist, ok := allowlists[orgID]
+	if !ok {
+		return true, nil
+	}
+	return ipAllowlistMatcher(allowlist).MatchIPAddr(net.ParseIP(ip)), nil
+}
+
+// FindOrgIDsDenyingIP returns the IDs of the organizations whose IP allowlist doesn't contain the IP address
+func FindOrgIDsDenyingIP(ctx context.Context, ip string) ([]int64, error) {
+	allowlists, err := getIPAllowlists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgIDs := make([]int64, 0, len(allowlists))
+	for orgID, allowlist := range allowlists {
+		if !ipAllowlistMatcher(allowlist).MatchIPAddr(net.ParseIP(ip)) {
+			orgIDs = append(orgIDs, orgID)
+		}
+	}
+	return orgIDs, nil
+}
+
+// RequestIP returns the IP address of the request the context belongs to if it was sent by the user,
+// it's empty for other users and outside of requests, e.g. for the SSH server which checks the allowlists itself
+func RequestIP(ctx context.Context, user *user_model.User) string {
+	doer, _ := middleware.GetContextData(ctx)[middleware.ContextDataKeySignedUser].(*user_model.User)
+	if user == nil || doer == nil || doer.ID != user.ID || user.IsAdmin || user.IsActions() {
+		return ""
+	}
+	addr := middleware.RemoteAddr(ctx)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// IsRequestIPAllowed returns false if the user sent the request the context belongs to
+// from an address the IP allowlist of the organization doesn't contain
+func IsRequestIPAllowed(ctx context.Context, orgID int64, user *user_model.User) (bool, error) {
+	ip := RequestIP(ctx, user)
+	if ip == "" {
+		return true, nil
+	}
+	return IsIPAllowed(ctx, orgID, ip)
+}
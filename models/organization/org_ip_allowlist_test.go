@@ -0,0 +1,108 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package organization_test
+
+import (
+	"context"
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/organization"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/web/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIPAllowlist(t *testing.T) {
+	allowlist, err := organization.ParseIPAllowlist("10.0.0.0/8\r\n 192.168.1.7 ,2001:db8::1\n\nLoopback,2001:db8::/32")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.0/8,192.168.1.7/32,2001:db8::1/128,loopback,2001:db8::/32", allowlist)
+
+	allowlist, err = organization.ParseIPAllowlist("")
+	require.NoError(t, err)
+	assert.Empty(t, allowlist)
+
+	_, err = organization.ParseIPAllowlist("10.0.0.0/8\n*.example.com")
+	assert.ErrorIs(t, err, organization.ErrInvalidIPAllowlistEntry{Entry: "*.example.com"})
+}
+
+func TestIPAllowlist(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	allowlist, err := organization.GetIPAllowlist(db.DefaultContext, 3)
+	require.NoError(t, err)
+	assert.Nil(t, allowlist)
+
+	allowed, err := organization.IsIPAllowed(db.DefaultContext, 3, "203.0.113.5")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	require.NoError(t, organization.SetIPAllowlist(db.DefaultContext, 3, "10.0.0.0/8\nloopback"))
+	for ip, expected := range map[string]bool{
+		"10.1.2.3":    true,
+		"127.0.0.1":   true,
+		"::1":         true,
+		"203.0.113.5": false,
+		"":            false,
+	} {
+		allowed, err := organization.IsIPAllowed(db.DefaultContext, 3, ip)
+		require.NoError(t, err)
+		assert.Equal(t, expected, allowed, ip)
+	}
+
+	// other organizations aren't restricted
+	allowed, err = organization.IsIPAllowed(db.DefaultContext, 6, "203.0.113.5")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	orgIDs, err := organization.FindOrgIDsDenyingIP(db.DefaultContext, "203.0.113.5")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3}, orgIDs)
+	orgIDs, err = organization.FindOrgIDsDenyingIP(db.DefaultContext, "10.1.2.3")
+	require.NoError(t, err)
+	assert.Empty(t, orgIDs)
+
+	require.NoError(t, organization.SetIPAllowlist(db.DefaultContext, 3, "203.0.113.0/24"))
+	allowlist, err = organization.GetIPAllowlist(db.DefaultContext, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"203.0.113.0/24"}, allowlist.Entries())
+
+	require.ErrorIs(t, organization.SetIPAllowlist(db.DefaultContext, 3, "example.com"), organization.ErrInvalidIPAllowlistEntry{Entry: "example.com"})
+
+	require.NoError(t, organization.SetIPAllowlist(db.DefaultContext, 3, ""))
+	allowlist, err = organization.GetIPAllowlist(db.DefaultContext, 3)
+	require.NoError(t, err)
+	assert.Nil(t, allowlist)
+}
+
+func TestIsRequestIPAllowed(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+	require.NoError(t, organization.SetIPAllowlist(db.DefaultContext, 3, "10.0.0.0/8"))
+
+	user4 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 4})
+	user5 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 5})
+	ctx := middleware.WithContextData(context.WithValue(db.DefaultContext, middleware.RemoteAddrContextKey, "203.0.113.5:1234"))
+
+	// outside of requests of the user
+	allowed, err := organization.IsRequestIPAllowed(ctx, 3, user4)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	middleware.GetContextData(ctx)[middleware.ContextDataKeySignedUser] = user4
+	allowed, err = organization.IsRequestIPAllowed(ctx, 3, user4)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// other users checked during the request aren't restricted
+	allowed, err = organization.IsRequestIPAllowed(ctx, 3, user5)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, err = organization.IsRequestIPAllowed(ctx, 6, user4)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+}
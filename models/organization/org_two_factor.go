@@ -0,0 +1,152 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package organization
+
+import (
+	"context"
+	"slices"
+
+	"code.gitea.io/gitea/models/db"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/cache"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/builder"
+)
+
+// TwoFactorPolicy requires the members of an organization to enroll two-factor authentication,
+// members without TOTP or a security key can't access the repositories of the organization.
+type TwoFactorPolicy struct {
+	ID    int64 `xorm:"pk autoincr"`
+	OrgID int64 `xorm:"UNIQUE NOT NULL"`
+	// Members who haven't enrolled two-factor authentication are removed from the organization
+	// after this time, 0 keeps them as members without access.
+	RemoveAfterUnix timeutil.TimeStamp `xorm:"INDEX NOT NULL DEFAULT 0"`
+	CreatedUnix     timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix     timeutil.TimeStamp `xorm:"updated"`
+}
+
+func init() {
+	db.RegisterModel(new(TwoFactorPolicy))
+}
+
+// TableName sets the table name to `org_two_factor_policy`
+func (p *TwoFactorPolicy) TableName() string {
+	return "org_two_factor_policy"
+}
+
+// GetTwoFactorPolicy returns the two-factor authentication policy of the organization,
+// it returns nil if the organization doesn't require two-factor authentication
+func GetTwoFactorPolicy(ctx context.Context, orgID int64) (*TwoFactorPolicy, error) {
+	p := new(TwoFactorPolicy)
+	if has, err := db.GetEngine(ctx).Where("org_id = ?", orgID).Get(p); err != nil {
+		return nil, err
+	} else if !has {
+		return nil, nil
+	}
+	return p, nil
+}
+
+const twoFactorOrgIDsCacheKey = "org.two_factor_policies"
+
+// getTwoFactorOrgIDs returns the IDs of the organizations requiring two-factor authentication. They are cached
+// because every permission check of a signed in user consults them, the cache is reset when they change.
+func getTwoFactorOrgIDs(ctx context.Context) ([]int64, error) {
+	s, err := cache.GetString(twoFactorOrgIDsCacheKey, func() (string, error) {
+		orgIDs := make([]int64, 0, 5)
+		if err := db.GetEngine(ctx).Table("org_two_factor_policy").Cols("org_id").Find(&orgIDs); err != nil {
+			return "", err
+		}
+		bs, err := json.Marshal(orgIDs)
+		return string(bs), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var orgIDs []int64
+	return orgIDs, json.Unmarshal([]byte(s), &orgIDs)
+}
+
+// removeTwoFactorOrgIDsCache resets the cached organization IDs once the transaction of the context is committed
+func removeTwoFactorOrgIDsCache(ctx context.Context) {
+	db.AfterTx(ctx, func() {
+		cache.Remove(twoFactorOrgIDsCacheKey)
+	})
+}
+
+// IsTwoFactorRequired returns true if the organization requires two-factor authentication
+func IsTwoFactorRequired(ctx context.Context, orgID int64) (bool, error) {
+	orgIDs, err := getTwoFactorOrgIDs(ctx)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(orgIDs, orgID), nil
+}
+
+// SetTwoFactorPolicy requires two-factor authentication for the members of the organization,
+// non-compliant members are removed after removeAfter unless it is 0
+func SetTwoFactorPolicy(ctx context.Context, orgID int64, removeAfter timeutil.TimeStamp) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		p, err := GetTwoFactorPolicy(ctx, orgID)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			removeTwoFactorOrgIDsCache(ctx)
+			return db.Insert(ctx, &TwoFactorPolicy{OrgID: orgID, RemoveAfterUnix: removeAfter})
+		}
+		p.RemoveAfterUnix = removeAfter
+		_, err = db.GetEngine(ctx).ID(p.ID).Cols("remove_after_unix").Update(p)
+		return err
+	})
+}
+
+// DeleteTwoFactorPolicy stops requiring two-factor authentication for the members of the organization
+func DeleteTwoFactorPolicy(ctx context.Context, orgID int64) error {
+	removeTwoFactorOrgIDsCache(ctx)
+	_, err := db.GetEngine(ctx).Where("org_id = ?", orgID).Delete(new(TwoFactorPolicy))
+	return err
+}
+
+// FindExpiredTwoFactorPolicies returns the policies whose grace period for non-compliant members is over
+func FindExpiredTwoFactorPolicies(ctx context.Context) ([]*TwoFactorPolicy, error) {
+	policies := make([]*TwoFactorPolicy, 0, 5)
+	return policies, db.GetEngine(ctx).
+		Where(builder.Gt{"remove_after_unix": 0}.And(builder.Lte{"remove_after_unix": timeutil.TimeStampNow()})).
+		Find(&policies)
+}
+
+// twoFactorEnrolledCond returns a condition matching the ids of users who enrolled TOTP or a security key
+func twoFactorEnrolledCond(userIDColumn string) builder.Cond {
+	return builder.Or(
+		builder.In(userIDColumn, builder.Select("uid").From("two_factor")),
+		builder.In(userIDColumn, builder.Select("user_id").From("webauthn_credential")),
+	)
+}
+
+// HasTwoFactorEnrolled returns true if the user enrolled TOTP or a security key
+func HasTwoFactorEnrolled(ctx context.Context, userID int64) (bool, error) {
+	return db.GetEngine(ctx).Where(builder.Eq{"id": userID}.And(twoFactorEnrolledCond("id"))).Exist(new(user_model.User))
+}
+
+// IsTwoFactorCompliant returns false if the organization requires two-factor authentication
+// and the user didn't enroll it
+func IsTwoFactorCompliant(ctx context.Context, orgID, userID int64) (bool, error) {
+	required, err := IsTwoFactorRequired(ctx, orgID)
+	if err != nil || !required {
+		return true, err
+	}
+	return HasTwoFactorEnrolled(ctx, userID)
+}
+
+// GetMembersWithoutTwoFactor returns the members of the organization who didn't enroll two-factor authentication
+func GetMembersWithoutTwoFactor(ctx context.Context, orgID int64) (user_model.UserList, error) {
+	users := make(user_model.UserList, 0, 10)
+	return users, db.GetEngine(ctx).
+		Where(builder.In("id", builder.Select("uid").From("org_user").Where(builder.Eq{"org_id": orgID})).
+			And(builder.Not{twoFactorEnrolledCond("id")})).
+		OrderBy("lower_name").
+		Find(&users)
+}
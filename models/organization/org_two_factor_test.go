@@ -0,0 +1,72 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package organization_test
+
+import (
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/organization"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTwoFactorPolicy(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	policy, err := organization.GetTwoFactorPolicy(db.DefaultContext, 3)
+	require.NoError(t, err)
+	assert.Nil(t, policy)
+
+	compliant, err := organization.IsTwoFactorCompliant(db.DefaultContext, 3, 2)
+	require.NoError(t, err)
+	assert.True(t, compliant)
+
+	require.NoError(t, organization.SetTwoFactorPolicy(db.DefaultContext, 3, 0))
+	compliant, err = organization.IsTwoFactorCompliant(db.DefaultContext, 3, 2)
+	require.NoError(t, err)
+	assert.False(t, compliant)
+
+	policies, err := organization.FindExpiredTwoFactorPolicies(db.DefaultContext)
+	require.NoError(t, err)
+	assert.Empty(t, policies)
+
+	require.NoError(t, organization.SetTwoFactorPolicy(db.DefaultContext, 3, timeutil.TimeStampNow().Add(-1)))
+	policies, err = organization.FindExpiredTwoFactorPolicies(db.DefaultContext)
+	require.NoError(t, err)
+	if assert.Len(t, policies, 1) {
+		assert.EqualValues(t, 3, policies[0].OrgID)
+	}
+
+	require.NoError(t, organization.DeleteTwoFactorPolicy(db.DefaultContext, 3))
+	compliant, err = organization.IsTwoFactorCompliant(db.DefaultContext, 3, 2)
+	require.NoError(t, err)
+	assert.True(t, compliant)
+}
+
+func TestGetMembersWithoutTwoFactor(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	members, err := organization.GetMembersWithoutTwoFactor(db.DefaultContext, 3)
+	require.NoError(t, err)
+	assert.Len(t, members, 3)
+
+	// user2 enrolls a security key, user4 enrolls TOTP
+	unittest.AssertSuccessfulInsert(t, &auth_model.WebAuthnCredential{UserID: 2, Name: "Security key"})
+	unittest.AssertSuccessfulInsert(t, &auth_model.TwoFactor{UID: 4})
+
+	members, err = organization.GetMembersWithoutTwoFactor(db.DefaultContext, 3)
+	require.NoError(t, err)
+	if assert.Len(t, members, 1) {
+		assert.EqualValues(t, 28, members[0].ID)
+	}
+
+	enrolled, err := organization.HasTwoFactorEnrolled(db.DefaultContext, 2)
+	require.NoError(t, err)
+	assert.True(t, enrolled)
+}
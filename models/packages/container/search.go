@@ -11,6 +11,7 @@ import (
 	"code.gitea.io/gitea/models/db"
 	"code.gitea.io/gitea/models/packages"
 	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/optional"
 	container_module "code.gitea.io/gitea/modules/packages/container"
 	"code.gitea.io/gitea/modules/util"
 
@@ -116,6 +117,25 @@ func GetManifestVersions(ctx context.Context, opts *BlobSearchOptions) ([]*packa
 		Find(&pvs)
 }
 
+// GetReferrerVersions gets all package versions of an image whose manifest declares the digest as its subject
+// https://github.com/opencontainers/distribution-spec/blob/main/spec.md#listing-referrers
+func GetReferrerVersions(ctx context.Context, ownerID int64, image, subject string) ([]*packages.PackageVersion, error) {
+	pvs, _, err := packages.SearchVersions(ctx, &packages.PackageSearchOptions{
+		OwnerID: ownerID,
+		Type:    packages.TypeContainer,
+		Name: packages.SearchValue{
+			ExactMatch: true,
+			Value:      image,
+		},
+		Properties: map[string]string{
+			container_module.PropertyManifestSubject: subject,
+		},
+		IsInternal: optional.Some(false),
+		Sort:       packages.SortCreatedAsc,
+	})
+	return pvs, err
+}
+
 // GetImageTags gets a sorted list of the tags of an image
 // The result is suitable for the api call.
 func GetImageTags(ctx context.Context, ownerID int64, image string, n int, last string) ([]string, error) {
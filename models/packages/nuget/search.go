@@ -66,5 +66,8 @@ func toConds(opts *packages_model.PackageSearchOptions) builder.Cond {
 			cond = cond.And(builder.Like{"package.lower_name", strings.ToLower(opts.Name.Value)})
 		}
 	}
+	if opts.InheritRepoIDs != nil {
+		cond = cond.And(builder.Eq{"package.inherit_repo_permissions": true}, builder.In("package.repo_id", opts.InheritRepoIDs))
+	}
 	return cond
 }
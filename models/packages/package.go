@@ -33,6 +33,7 @@ type Type string
 // List of supported packages
 const (
 	TypeAlpine    Type = "alpine"
+	TypeAnsible   Type = "ansible"
 	TypeArch      Type = "arch"
 	TypeCargo     Type = "cargo"
 	TypeChef      Type = "chef"
@@ -45,6 +46,7 @@ const (
 	TypeGeneric   Type = "generic"
 	TypeGo        Type = "go"
 	TypeHelm      Type = "helm"
+	TypeHex       Type = "hex"
 	TypeMaven     Type = "maven"
 	TypeNpm       Type = "npm"
 	TypeNuGet     Type = "nuget"
@@ -53,11 +55,13 @@ const (
 	TypeRpm       Type = "rpm"
 	TypeRubyGems  Type = "rubygems"
 	TypeSwift     Type = "swift"
+	TypeTerraform Type = "terraform"
 	TypeVagrant   Type = "vagrant"
 )
 
 var TypeList = []Type{
 	TypeAlpine,
+	TypeAnsible,
 	TypeArch,
 	TypeCargo,
 	TypeChef,
@@ -70,6 +74,7 @@ var TypeList = []Type{
 	TypeGeneric,
 	TypeGo,
 	TypeHelm,
+	TypeHex,
 	TypeMaven,
 	TypeNpm,
 	TypeNuGet,
@@ -78,6 +83,7 @@ var TypeList = []Type{
 	TypeRpm,
 	TypeRubyGems,
 	TypeSwift,
+	TypeTerraform,
 	TypeVagrant,
 }
 
@@ -86,6 +92,8 @@ func (pt Type) Name() string {
 	switch pt {
 	case TypeAlpine:
 		return "Alpine"
+	case TypeAnsible:
+		return "Ansible"
 	case TypeArch:
 		return "Arch"
 	case TypeCargo:
@@ -110,6 +118,8 @@ func (pt Type) Name() string {
 		return "Go"
 	case TypeHelm:
 		return "Helm"
+	case TypeHex:
+		return "Hex"
 	case TypeMaven:
 		return "Maven"
 	case TypeNpm:
@@ -126,6 +136,8 @@ func (pt Type) Name() string {
 		return "RubyGems"
 	case TypeSwift:
 		return "Swift"
+	case TypeTerraform:
+		return "Terraform"
 	case TypeVagrant:
 		return "Vagrant"
 	}
@@ -137,6 +149,8 @@ func (pt Type) SVGName() string {
 	switch pt {
 	case TypeAlpine:
 		return "gitea-alpine"
+	case TypeAnsible:
+		return "gitea-ansible"
 	case TypeArch:
 		return "gitea-arch"
 	case TypeCargo:
@@ -161,6 +175,8 @@ func (pt Type) SVGName() string {
 		return "gitea-go"
 	case TypeHelm:
 		return "gitea-helm"
+	case TypeHex:
+		return "gitea-hex"
 	case TypeMaven:
 		return "gitea-maven"
 	case TypeNpm:
@@ -177,6 +193,8 @@ func (pt Type) SVGName() string {
 		return "gitea-rubygems"
 	case TypeSwift:
 		return "gitea-swift"
+	case TypeTerraform:
+		return "gitea-terraform"
 	case TypeVagrant:
 		return "gitea-vagrant"
 	}
@@ -185,14 +203,15 @@ func (pt Type) SVGName() string {
 
 // Package represents a package
 type Package struct {
-	ID               int64  `xorm:"pk autoincr"`
-	OwnerID          int64  `xorm:"UNIQUE(s) INDEX NOT NULL"`
-	RepoID           int64  `xorm:"INDEX"`
-	Type             Type   `xorm:"UNIQUE(s) INDEX NOT NULL"`
-	Name             string `xorm:"NOT NULL"`
-	LowerName        string `xorm:"UNIQUE(s) INDEX NOT NULL"`
-	SemverCompatible bool   `xorm:"NOT NULL DEFAULT false"`
-	IsInternal       bool   `xorm:"NOT NULL DEFAULT false"`
+	ID                     int64  `xorm:"pk autoincr"`
+	OwnerID                int64  `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	RepoID                 int64  `xorm:"INDEX"`
+	InheritRepoPermissions bool   `xorm:"NOT NULL DEFAULT false"`
+	Type                   Type   `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	Name                   string `xorm:"NOT NULL"`
+	LowerName              string `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	SemverCompatible       bool   `xorm:"NOT NULL DEFAULT false"`
+	IsInternal             bool   `xorm:"NOT NULL DEFAULT false"`
 }
 
 // TryInsertPackage inserts a package. If a package exists already, ErrDuplicatePackage is returned
@@ -236,12 +255,28 @@ func SetRepositoryLink(ctx context.Context, packageID, repoID int64) error {
 	return err
 }
 
+// SetRepositoryPermissionInheritance sets if the package inherits the permissions of the linked repository
+func SetRepositoryPermissionInheritance(ctx context.Context, packageID int64, inherit bool) error {
+	_, err := db.GetEngine(ctx).ID(packageID).Cols("inherit_repo_permissions").Update(&Package{InheritRepoPermissions: inherit})
+	return err
+}
+
 // UnlinkRepositoryFromAllPackages unlinks every package from the repository
 func UnlinkRepositoryFromAllPackages(ctx context.Context, repoID int64) error {
-	_, err := db.GetEngine(ctx).Where("repo_id = ?", repoID).Cols("repo_id").Update(&Package{})
+	_, err := db.GetEngine(ctx).Where("repo_id = ?", repoID).Cols("repo_id", "inherit_repo_permissions").Update(&Package{})
 	return err
 }
 
+// GetRepositoryIDsInheritingPermissions returns the ids of the repositories whose permissions are inherited by packages of the owner
+func GetRepositoryIDsInheritingPermissions(ctx context.Context, ownerID int64) ([]int64, error) {
+	repoIDs := make([]int64, 0, 5)
+	return repoIDs, db.GetEngine(ctx).
+		Table("package").
+		Distinct("repo_id").
+		Where(builder.Eq{"owner_id": ownerID, "inherit_repo_permissions": true}.And(builder.Gt{"repo_id": 0})).
+		Find(&repoIDs)
+}
+
 // GetPackageByID gets a package by id
 func GetPackageByID(ctx context.Context, packageID int64) (*Package, error) {
 	p := &Package{}
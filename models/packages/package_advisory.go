@@ -0,0 +1,135 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package packages
+
+import (
+	"context"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/builder"
+)
+
+func init() {
+	db.RegisterModel(new(PackageAdvisory))
+	db.RegisterModel(new(PackageVersionAdvisory))
+}
+
+// PackageAdvisory is an imported security advisory affecting a package
+type PackageAdvisory struct {
+	ID           int64              `xorm:"pk autoincr"`
+	AdvisoryID   string             `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	PackageType  Type               `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	LowerName    string             `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	Aliases      []string           `xorm:"TEXT JSON"`
+	Summary      string             `xorm:"TEXT"`
+	Severity     string             `xorm:"INDEX NOT NULL DEFAULT ''"`
+	Affected     string             `xorm:"LONGTEXT NOT NULL"`
+	ModifiedUnix timeutil.TimeStamp `xorm:"NOT NULL DEFAULT 0"`
+	CreatedUnix  timeutil.TimeStamp `xorm:"created NOT NULL DEFAULT 0"`
+	UpdatedUnix  timeutil.TimeStamp `xorm:"updated NOT NULL DEFAULT 0"`
+}
+
+// PackageVersionAdvisory links a package version to an advisory affecting it
+type PackageVersionAdvisory struct {
+	ID          int64              `xorm:"pk autoincr"`
+	VersionID   int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	AdvisoryID  int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created NOT NULL DEFAULT 0"`
+}
+
+// UpsertAdvisory inserts the advisory or updates an existing one if the new advisory was modified later.
+// It returns if the advisory was changed.
+func UpsertAdvisory(ctx context.Context, pa *PackageAdvisory) (bool, error) {
+	existing := &PackageAdvisory{}
+	has, err := db.GetEngine(ctx).
+		Where("advisory_id = ? AND package_type = ? AND lower_name = ?", pa.AdvisoryID, pa.PackageType, pa.LowerName).
+		Get(existing)
+	if err != nil {
+		return false, err
+	}
+	if !has {
+		return true, db.Insert(ctx, pa)
+	}
+	if existing.ModifiedUnix >= pa.ModifiedUnix {
+		return false, nil
+	}
+	pa.ID = existing.ID
+	_, err = db.GetEngine(ctx).ID(pa.ID).AllCols().Update(pa)
+	return true, err
+}
+
+// DeleteAdvisoriesByAdvisoryID removes the advisories with the id and their matches
+func DeleteAdvisoriesByAdvisoryID(ctx context.Context, advisoryID string) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		ids := make([]int64, 0, 5)
+		if err := db.GetEngine(ctx).Table("package_advisory").Where("advisory_id = ?", advisoryID).Cols("id").Find(&ids); err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if _, err := db.GetEngine(ctx).In("advisory_id", ids).Delete(&PackageVersionAdvisory{}); err != nil {
+			return err
+		}
+		_, err := db.GetEngine(ctx).In("id", ids).Delete(&PackageAdvisory{})
+		return err
+	})
+}
+
+// GetAdvisoriesByPackage returns all advisories affecting the package
+func GetAdvisoriesByPackage(ctx context.Context, packageType Type, lowerName string) ([]*PackageAdvisory, error) {
+	pas := make([]*PackageAdvisory, 0, 5)
+	return pas, db.GetEngine(ctx).
+		Where("package_type = ? AND lower_name = ?", packageType, lowerName).
+		OrderBy("advisory_id").
+		Find(&pas)
+}
+
+// GetAdvisoriesByVersionID returns all advisories matched to the package version
+func GetAdvisoriesByVersionID(ctx context.Context, versionID int64) ([]*PackageAdvisory, error) {
+	pas := make([]*PackageAdvisory, 0, 5)
+	return pas, db.GetEngine(ctx).
+		Join("INNER", "package_version_advisory", "package_version_advisory.advisory_id = package_advisory.id").
+		Where("package_version_advisory.version_id = ?", versionID).
+		OrderBy("package_advisory.advisory_id").
+		Find(&pas)
+}
+
+// HasVersionAdvisoryWithSeverity checks if an advisory with the severity is matched to the package version
+func HasVersionAdvisoryWithSeverity(ctx context.Context, versionID int64, severity string) (bool, error) {
+	return db.GetEngine(ctx).
+		Join("INNER", "package_version_advisory", "package_version_advisory.advisory_id = package_advisory.id").
+		Where("package_version_advisory.version_id = ? AND package_advisory.severity = ?", versionID, severity).
+		Exist(&PackageAdvisory{})
+}
+
+// SetVersionAdvisories replaces the advisories matched to the package version
+func SetVersionAdvisories(ctx context.Context, versionID int64, advisoryIDs []int64) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if err := DeleteVersionAdvisoriesByVersionID(ctx, versionID); err != nil {
+			return err
+		}
+		if len(advisoryIDs) == 0 {
+			return nil
+		}
+		pvas := make([]*PackageVersionAdvisory, 0, len(advisoryIDs))
+		for _, id := range advisoryIDs {
+			pvas = append(pvas, &PackageVersionAdvisory{VersionID: versionID, AdvisoryID: id})
+		}
+		return db.Insert(ctx, pvas)
+	})
+}
+
+func DeleteVersionAdvisoriesByVersionID(ctx context.Context, versionID int64) error {
+	_, err := db.GetEngine(ctx).Where("version_id = ?", versionID).Delete(&PackageVersionAdvisory{})
+	return err
+}
+
+// IteratePackagesWithAdvisories calls f for every package which has an advisory
+func IteratePackagesWithAdvisories(ctx context.Context, f func(ctx context.Context, p *Package) error) error {
+	cond := builder.Expr("EXISTS (SELECT 1 FROM package_advisory WHERE package_advisory.package_type = package.type AND package_advisory.lower_name = package.lower_name)")
+	return db.Iterate(ctx, cond, f)
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package packages
+
+import (
+	"context"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+)
+
+var ErrPackageAttestationNotExist = util.NewNotExistErrorf("package attestation does not exist")
+
+func init() {
+	db.RegisterModel(new(PackageAttestation))
+}
+
+// PackageAttestation is a signed in-toto statement about the files of a package version
+type PackageAttestation struct {
+	ID            int64              `xorm:"pk autoincr"`
+	VersionID     int64              `xorm:"INDEX NOT NULL"`
+	CreatorID     int64              `xorm:"NOT NULL DEFAULT 0"`
+	PredicateType string             `xorm:"NOT NULL"`
+	Envelope      string             `xorm:"LONGTEXT NOT NULL"`
+	IsVerified    bool               `xorm:"INDEX NOT NULL DEFAULT false"`
+	KeyID         string             `xorm:"NOT NULL DEFAULT ''"`
+	CreatedUnix   timeutil.TimeStamp `xorm:"created INDEX NOT NULL"`
+}
+
+func InsertAttestation(ctx context.Context, pa *PackageAttestation) (*PackageAttestation, error) {
+	return pa, db.Insert(ctx, pa)
+}
+
+func GetAttestationByID(ctx context.Context, id int64) (*PackageAttestation, error) {
+	pa := &PackageAttestation{}
+
+	has, err := db.GetEngine(ctx).ID(id).Get(pa)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrPackageAttestationNotExist
+	}
+	return pa, nil
+}
+
+func GetAttestationsByVersionID(ctx context.Context, versionID int64) ([]*PackageAttestation, error) {
+	pas := make([]*PackageAttestation, 0, 2)
+	return pas, db.GetEngine(ctx).Where("version_id = ?", versionID).OrderBy("id").Find(&pas)
+}
+
+// HasVerifiedAttestation checks if the version has an attestation signed by a trusted key
+func HasVerifiedAttestation(ctx context.Context, versionID int64) (bool, error) {
+	return db.GetEngine(ctx).
+		Where("version_id = ? AND is_verified = ?", versionID, true).
+		Exist(&PackageAttestation{})
+}
+
+func DeleteAttestationsByVersionID(ctx context.Context, versionID int64) error {
+	_, err := db.GetEngine(ctx).Where("version_id = ?", versionID).Delete(&PackageAttestation{})
+	return err
+}
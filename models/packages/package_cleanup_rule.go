@@ -30,7 +30,9 @@ type PackageCleanupRule struct {
 	KeepCount            int                `xorm:"NOT NULL DEFAULT 0"`
 	KeepPattern          string             `xorm:"NOT NULL DEFAULT ''"`
 	KeepPatternMatcher   *regexp.Regexp     `xorm:"-"`
+	KeepSemverPatches    int                `xorm:"NOT NULL DEFAULT 0"`
 	RemoveDays           int                `xorm:"NOT NULL DEFAULT 0"`
+	RemoveUnusedDays     int                `xorm:"NOT NULL DEFAULT 0"`
 	RemovePattern        string             `xorm:"NOT NULL DEFAULT ''"`
 	RemovePatternMatcher *regexp.Regexp     `xorm:"-"`
 	MatchFullName        bool               `xorm:"NOT NULL DEFAULT false"`
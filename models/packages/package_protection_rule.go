@@ -0,0 +1,100 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package packages
+
+import (
+	"context"
+	"strings"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/gobwas/glob"
+)
+
+var ErrPackageProtectionRuleNotExist = util.NewNotExistErrorf("package protection rule does not exist")
+
+func init() {
+	db.RegisterModel(new(PackageProtectionRule))
+}
+
+// PackageProtectionRule protects the matching versions of the packages of an owner from being overwritten or deleted
+type PackageProtectionRule struct {
+	ID             int64              `xorm:"pk autoincr"`
+	OwnerID        int64              `xorm:"UNIQUE(s) INDEX NOT NULL DEFAULT 0"`
+	Type           Type               `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	VersionPattern string             `xorm:"UNIQUE(s) NOT NULL"`
+	globRule       glob.Glob          `xorm:"-"`
+	isInvalid      bool               `xorm:"-"`
+	AllowedTeamIDs []int64            `xorm:"TEXT JSON"`
+	CreatedUnix    timeutil.TimeStamp `xorm:"created NOT NULL DEFAULT 0"`
+	UpdatedUnix    timeutil.TimeStamp `xorm:"updated NOT NULL DEFAULT 0"`
+}
+
+// CompilePattern compiles the case-insensitive glob pattern of the rule
+func CompilePattern(pattern string) (glob.Glob, error) {
+	return glob.Compile(strings.ToLower(pattern))
+}
+
+func (ppr *PackageProtectionRule) loadGlob() {
+	if ppr.globRule == nil && !ppr.isInvalid {
+		var err error
+		ppr.globRule, err = CompilePattern(ppr.VersionPattern)
+		ppr.isInvalid = err != nil
+	}
+}
+
+// Match checks if the version is matched by the rule, the pattern is compiled once per loaded rule
+func (ppr *PackageProtectionRule) Match(version string) bool {
+	ppr.loadGlob()
+	if ppr.isInvalid {
+		return false
+	}
+	return ppr.globRule.Match(strings.ToLower(version))
+}
+
+func InsertProtectionRule(ctx context.Context, ppr *PackageProtectionRule) (*PackageProtectionRule, error) {
+	return ppr, db.Insert(ctx, ppr)
+}
+
+func GetProtectionRuleByID(ctx context.Context, id int64) (*PackageProtectionRule, error) {
+	ppr := &PackageProtectionRule{}
+
+	has, err := db.GetEngine(ctx).ID(id).Get(ppr)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrPackageProtectionRuleNotExist
+	}
+	return ppr, nil
+}
+
+func UpdateProtectionRule(ctx context.Context, ppr *PackageProtectionRule) error {
+	_, err := db.GetEngine(ctx).ID(ppr.ID).AllCols().Update(ppr)
+	return err
+}
+
+func GetProtectionRulesByOwner(ctx context.Context, ownerID int64) ([]*PackageProtectionRule, error) {
+	pprs := make([]*PackageProtectionRule, 0, 5)
+	return pprs, db.GetEngine(ctx).Where("owner_id = ?", ownerID).OrderBy("type, version_pattern").Find(&pprs)
+}
+
+func GetProtectionRulesByOwnerAndType(ctx context.Context, ownerID int64, packageType Type) ([]*PackageProtectionRule, error) {
+	pprs := make([]*PackageProtectionRule, 0, 5)
+	return pprs, db.GetEngine(ctx).Where("owner_id = ? AND type = ?", ownerID, packageType).Find(&pprs)
+}
+
+// HasOwnerProtectionRule checks if the owner has a rule with the pattern for the package type
+func HasOwnerProtectionRule(ctx context.Context, ownerID int64, packageType Type, versionPattern string) (bool, error) {
+	return db.GetEngine(ctx).
+		Where("owner_id = ? AND type = ? AND version_pattern = ?", ownerID, packageType, versionPattern).
+		Exist(&PackageProtectionRule{})
+}
+
+func DeleteProtectionRuleByID(ctx context.Context, ruleID int64) error {
+	_, err := db.GetEngine(ctx).ID(ruleID).Delete(&PackageProtectionRule{})
+	return err
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package packages_test
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageProtectionRuleMatch(t *testing.T) {
+	cases := []struct {
+		Pattern  string
+		Version  string
+		Expected bool
+	}{
+		{"*", "1.0.0", true},
+		{"1.*", "1.2.3", true},
+		{"1.*", "2.0.0", false},
+		{"v*", "V1", true},
+		{"release-?", "release-1", true},
+		{"release-?", "release-10", false},
+		{"[invalid", "[invalid", false},
+	}
+
+	for _, c := range cases {
+		ppr := &packages_model.PackageProtectionRule{VersionPattern: c.Pattern}
+		assert.Equal(t, c.Expected, ppr.Match(c.Version), "pattern %q version %q", c.Pattern, c.Version)
+	}
+}
+
+func TestHasOwnerProtectionRule(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	_, err := packages_model.InsertProtectionRule(db.DefaultContext, &packages_model.PackageProtectionRule{
+		OwnerID:        2,
+		Type:           packages_model.TypeGeneric,
+		VersionPattern: "1.*",
+	})
+	require.NoError(t, err)
+
+	has, err := packages_model.HasOwnerProtectionRule(db.DefaultContext, 2, packages_model.TypeGeneric, "1.*")
+	require.NoError(t, err)
+	assert.True(t, has)
+
+	has, err = packages_model.HasOwnerProtectionRule(db.DefaultContext, 2, packages_model.TypeNpm, "1.*")
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	pprs, err := packages_model.GetProtectionRulesByOwnerAndType(db.DefaultContext, 2, packages_model.TypeGeneric)
+	require.NoError(t, err)
+	assert.Len(t, pprs, 1)
+}
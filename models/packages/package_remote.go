@@ -0,0 +1,148 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package packages
+
+import (
+	"context"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/secret"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+
+	"xorm.io/builder"
+)
+
+var ErrPackageRemoteNotExist = util.NewNotExistErrorf("package remote does not exist")
+
+// PropertyRemoteURL is the name of the version property which stores the upstream url a cached package was fetched from
+const PropertyRemoteURL = "remote.url"
+
+// RemoteSupportedTypes contains the package types which can be fetched from an upstream registry
+var RemoteSupportedTypes = []Type{
+	TypeContainer,
+	TypeGo,
+	TypeMaven,
+	TypeNpm,
+	TypePyPI,
+}
+
+// IsRemoteSupported returns true if packages of the type can be fetched from an upstream registry
+func (pt Type) IsRemoteSupported() bool {
+	for _, t := range RemoteSupportedTypes {
+		if t == pt {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	db.RegisterModel(new(PackageRemote))
+}
+
+// PackageRemote represents an upstream registry which is queried if a requested package does not exist locally.
+// The credentials are sent to the upstream registry and to the AuthHost of its token service if there is one.
+type PackageRemote struct {
+	ID                int64              `xorm:"pk autoincr"`
+	Enabled           bool               `xorm:"INDEX NOT NULL DEFAULT false"`
+	OwnerID           int64              `xorm:"UNIQUE(s) INDEX NOT NULL DEFAULT 0"`
+	Type              Type               `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	URL               string             `xorm:"TEXT NOT NULL"`
+	Username          string             `xorm:"NOT NULL DEFAULT ''"`
+	PasswordEncrypted string             `xorm:"TEXT"`
+	AuthHost          string             `xorm:"NOT NULL DEFAULT ''"`
+	CreatedUnix       timeutil.TimeStamp `xorm:"created NOT NULL DEFAULT 0"`
+	UpdatedUnix       timeutil.TimeStamp `xorm:"updated NOT NULL DEFAULT 0"`
+}
+
+// Password returns the decrypted password used to authenticate against the upstream registry
+func (pr *PackageRemote) Password() (string, error) {
+	if pr.PasswordEncrypted == "" {
+		return "", nil
+	}
+	return secret.DecryptSecret(setting.SecretKey, pr.PasswordEncrypted)
+}
+
+// SetPassword encrypts and sets the password used to authenticate against the upstream registry
+func (pr *PackageRemote) SetPassword(password string) error {
+	if password == "" {
+		pr.PasswordEncrypted = ""
+		return nil
+	}
+	encrypted, err := secret.EncryptSecret(setting.SecretKey, password)
+	if err != nil {
+		return err
+	}
+	pr.PasswordEncrypted = encrypted
+	return nil
+}
+
+func InsertRemote(ctx context.Context, pr *PackageRemote) (*PackageRemote, error) {
+	return pr, db.Insert(ctx, pr)
+}
+
+func GetRemoteByID(ctx context.Context, id int64) (*PackageRemote, error) {
+	pr := &PackageRemote{}
+
+	has, err := db.GetEngine(ctx).ID(id).Get(pr)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrPackageRemoteNotExist
+	}
+	return pr, nil
+}
+
+// GetEnabledRemoteByOwnerAndType gets the enabled remote of the owner for the package type
+func GetEnabledRemoteByOwnerAndType(ctx context.Context, ownerID int64, packageType Type) (*PackageRemote, error) {
+	pr := &PackageRemote{}
+
+	has, err := db.GetEngine(ctx).
+		Where("owner_id = ? AND type = ? AND enabled = ?", ownerID, packageType, true).
+		Get(pr)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrPackageRemoteNotExist
+	}
+	return pr, nil
+}
+
+func UpdateRemote(ctx context.Context, pr *PackageRemote) error {
+	_, err := db.GetEngine(ctx).ID(pr.ID).AllCols().Update(pr)
+	return err
+}
+
+func GetRemotesByOwner(ctx context.Context, ownerID int64) ([]*PackageRemote, error) {
+	prs := make([]*PackageRemote, 0, 10)
+	return prs, db.GetEngine(ctx).Where("owner_id = ?", ownerID).Find(&prs)
+}
+
+func DeleteRemoteByID(ctx context.Context, remoteID int64) error {
+	_, err := db.GetEngine(ctx).ID(remoteID).Delete(&PackageRemote{})
+	return err
+}
+
+func HasOwnerRemoteForPackageType(ctx context.Context, ownerID int64, packageType Type) (bool, error) {
+	return db.GetEngine(ctx).
+		Where("owner_id = ? AND type = ?", ownerID, packageType).
+		Exist(&PackageRemote{})
+}
+
+// HasVersionsNotFromRemote returns true if the package contains versions which were not fetched from an upstream registry
+func HasVersionsNotFromRemote(ctx context.Context, packageID int64) (bool, error) {
+	return db.GetEngine(ctx).
+		Where(builder.Eq{"package_id": packageID, "is_internal": false}).
+		And(builder.NotIn(
+			"id",
+			builder.Select("ref_id").
+				From("package_property").
+				Where(builder.Eq{"ref_type": PropertyTypeVersion, "name": PropertyRemoteURL}),
+		)).
+		Exist(&PackageVersion{})
+}
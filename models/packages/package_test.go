@@ -143,6 +143,32 @@ func TestUnlinkRepositoryFromAllPackages(t *testing.T) {
 	deletePackage(t, p0)
 }
 
+func TestGetRepositoryIDsInheritingPermissions(t *testing.T) {
+	p0 := prepareExamplePackage(t)
+
+	// The package does not inherit the permissions of the linked repository by default
+	repoIDs, err := packages_model.GetRepositoryIDsInheritingPermissions(db.DefaultContext, p0.OwnerID)
+	require.NoError(t, err)
+	require.Empty(t, repoIDs)
+
+	err = packages_model.SetRepositoryPermissionInheritance(db.DefaultContext, p0.ID, true)
+	require.NoError(t, err)
+
+	repoIDs, err = packages_model.GetRepositoryIDsInheritingPermissions(db.DefaultContext, p0.OwnerID)
+	require.NoError(t, err)
+	require.Equal(t, []int64{p0.RepoID}, repoIDs)
+
+	// Unlinking the repository also removes the inheritance
+	err = packages_model.UnlinkRepositoryFromAllPackages(db.DefaultContext, p0.RepoID)
+	require.NoError(t, err)
+
+	p, err := packages_model.GetPackageByID(db.DefaultContext, p0.ID)
+	require.NoError(t, err)
+	require.False(t, p.InheritRepoPermissions)
+
+	deletePackage(t, p0)
+}
+
 func TestGetPackageByName(t *testing.T) {
 	p0 := prepareExamplePackage(t)
 
@@ -0,0 +1,78 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package packages
+
+import (
+	"context"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+)
+
+var (
+	ErrPackageTrustedKeyNotExist  = util.NewNotExistErrorf("package trusted key does not exist")
+	ErrDuplicatePackageTrustedKey = util.NewAlreadyExistErrorf("package trusted key already exists")
+)
+
+func init() {
+	db.RegisterModel(new(PackageTrustedKey))
+}
+
+// PackageTrustedKey is a public key which is trusted to sign attestations of the packages of an owner
+type PackageTrustedKey struct {
+	ID          int64              `xorm:"pk autoincr"`
+	OwnerID     int64              `xorm:"UNIQUE(s) INDEX NOT NULL DEFAULT 0"`
+	Name        string             `xorm:"NOT NULL"`
+	Content     string             `xorm:"TEXT NOT NULL"`
+	Fingerprint string             `xorm:"UNIQUE(s) NOT NULL"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created NOT NULL DEFAULT 0"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"updated NOT NULL DEFAULT 0"`
+}
+
+// HasOwnerTrustedKey checks if the owner already trusts a key with the fingerprint
+func HasOwnerTrustedKey(ctx context.Context, ownerID int64, fingerprint string) (bool, error) {
+	return db.GetEngine(ctx).
+		Where("owner_id = ? AND fingerprint = ?", ownerID, fingerprint).
+		Exist(&PackageTrustedKey{})
+}
+
+func InsertTrustedKey(ctx context.Context, ptk *PackageTrustedKey) (*PackageTrustedKey, error) {
+	has, err := HasOwnerTrustedKey(ctx, ptk.OwnerID, ptk.Fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	if has {
+		return nil, ErrDuplicatePackageTrustedKey
+	}
+	return ptk, db.Insert(ctx, ptk)
+}
+
+func GetTrustedKeyByID(ctx context.Context, id int64) (*PackageTrustedKey, error) {
+	ptk := &PackageTrustedKey{}
+
+	has, err := db.GetEngine(ctx).ID(id).Get(ptk)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrPackageTrustedKeyNotExist
+	}
+	return ptk, nil
+}
+
+func UpdateTrustedKey(ctx context.Context, ptk *PackageTrustedKey) error {
+	_, err := db.GetEngine(ctx).ID(ptk.ID).AllCols().Update(ptk)
+	return err
+}
+
+func GetTrustedKeysByOwner(ctx context.Context, ownerID int64) ([]*PackageTrustedKey, error) {
+	ptks := make([]*PackageTrustedKey, 0, 5)
+	return ptks, db.GetEngine(ctx).Where("owner_id = ?", ownerID).OrderBy("name").Find(&ptks)
+}
+
+func DeleteTrustedKeyByID(ctx context.Context, keyID int64) error {
+	_, err := db.GetEngine(ctx).ID(keyID).Delete(&PackageTrustedKey{})
+	return err
+}
@@ -25,15 +25,16 @@ func init() {
 
 // PackageVersion represents a package version
 type PackageVersion struct {
-	ID            int64              `xorm:"pk autoincr"`
-	PackageID     int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
-	CreatorID     int64              `xorm:"NOT NULL DEFAULT 0"`
-	Version       string             `xorm:"NOT NULL"`
-	LowerVersion  string             `xorm:"UNIQUE(s) INDEX NOT NULL"`
-	CreatedUnix   timeutil.TimeStamp `xorm:"created INDEX NOT NULL"`
-	IsInternal    bool               `xorm:"INDEX NOT NULL DEFAULT false"`
-	MetadataJSON  string             `xorm:"metadata_json LONGTEXT"`
-	DownloadCount int64              `xorm:"NOT NULL DEFAULT 0"`
+	ID               int64              `xorm:"pk autoincr"`
+	PackageID        int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	CreatorID        int64              `xorm:"NOT NULL DEFAULT 0"`
+	Version          string             `xorm:"NOT NULL"`
+	LowerVersion     string             `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	CreatedUnix      timeutil.TimeStamp `xorm:"created INDEX NOT NULL"`
+	IsInternal       bool               `xorm:"INDEX NOT NULL DEFAULT false"`
+	MetadataJSON     string             `xorm:"metadata_json LONGTEXT"`
+	DownloadCount    int64              `xorm:"NOT NULL DEFAULT 0"`
+	LastDownloadUnix timeutil.TimeStamp `xorm:"INDEX NOT NULL DEFAULT 0"`
 }
 
 // GetOrInsertVersion inserts a version. If the same version exist already ErrDuplicatePackageVersion is returned
@@ -64,9 +65,9 @@ func UpdateVersion(ctx context.Context, pv *PackageVersion) error {
 	return err
 }
 
-// IncrementDownloadCounter increments the download counter of a version
+// IncrementDownloadCounter increments the download counter of a version and records the time of the download
 func IncrementDownloadCounter(ctx context.Context, versionID int64) error {
-	_, err := db.GetEngine(ctx).Exec("UPDATE `package_version` SET `download_count` = `download_count` + 1 WHERE `id` = ?", versionID)
+	_, err := db.GetEngine(ctx).Exec("UPDATE `package_version` SET `download_count` = `download_count` + 1, `last_download_unix` = ? WHERE `id` = ?", timeutil.TimeStampNow(), versionID)
 	return err
 }
 
@@ -186,6 +187,7 @@ type PackageSearchOptions struct {
 	IsInternal      optional.Option[bool]
 	HasFileWithName string                // only results are found which are associated with a file with the specific name
 	HasFiles        optional.Option[bool] // only results are found which have associated files
+	InheritRepoIDs  []int64               // if not nil, only results are found which inherit the permissions of one of the repositories
 	Sort            VersionSort
 	db.Paginator
 }
@@ -204,6 +206,9 @@ func (opts *PackageSearchOptions) ToConds() builder.Cond {
 	if opts.RepoID != 0 {
 		cond = cond.And(builder.Eq{"package.repo_id": opts.RepoID})
 	}
+	if opts.InheritRepoIDs != nil {
+		cond = cond.And(builder.Eq{"package.inherit_repo_permissions": true}, builder.In("package.repo_id", opts.InheritRepoIDs))
+	}
 	if opts.Type != "" && opts.Type != "all" {
 		cond = cond.And(builder.Eq{"package.type": opts.Type})
 	}
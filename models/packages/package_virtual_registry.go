@@ -0,0 +1,98 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package packages
+
+import (
+	"context"
+	"slices"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+)
+
+var ErrPackageVirtualRegistryNotExist = util.NewNotExistErrorf("package virtual registry does not exist")
+
+// VirtualRegistrySupportedTypes contains the package types which can be aggregated by a virtual registry
+var VirtualRegistrySupportedTypes = []Type{
+	TypeMaven,
+	TypeNpm,
+	TypePyPI,
+}
+
+// IsVirtualRegistrySupported returns true if packages of the type can be aggregated by a virtual registry
+func (pt Type) IsVirtualRegistrySupported() bool {
+	return slices.Contains(VirtualRegistrySupportedTypes, pt)
+}
+
+func init() {
+	db.RegisterModel(new(PackageVirtualRegistry))
+}
+
+// PackageVirtualRegistry aggregates the packages of multiple owners.
+// A package is resolved from the first member which contains it, uploads are stored in the default owner.
+type PackageVirtualRegistry struct {
+	ID             int64              `xorm:"pk autoincr"`
+	Enabled        bool               `xorm:"INDEX NOT NULL DEFAULT false"`
+	OwnerID        int64              `xorm:"UNIQUE(s) INDEX NOT NULL DEFAULT 0"`
+	Type           Type               `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	MemberIDs      []int64            `xorm:"JSON TEXT"`
+	DefaultOwnerID int64              `xorm:"NOT NULL DEFAULT 0"`
+	CreatedUnix    timeutil.TimeStamp `xorm:"created NOT NULL DEFAULT 0"`
+	UpdatedUnix    timeutil.TimeStamp `xorm:"updated NOT NULL DEFAULT 0"`
+}
+
+func InsertVirtualRegistry(ctx context.Context, pvr *PackageVirtualRegistry) (*PackageVirtualRegistry, error) {
+	return pvr, db.Insert(ctx, pvr)
+}
+
+func GetVirtualRegistryByID(ctx context.Context, id int64) (*PackageVirtualRegistry, error) {
+	pvr := &PackageVirtualRegistry{}
+
+	has, err := db.GetEngine(ctx).ID(id).Get(pvr)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrPackageVirtualRegistryNotExist
+	}
+	return pvr, nil
+}
+
+// GetEnabledVirtualRegistryByOwnerAndType gets the enabled virtual registry of the owner for the package type
+func GetEnabledVirtualRegistryByOwnerAndType(ctx context.Context, ownerID int64, packageType Type) (*PackageVirtualRegistry, error) {
+	pvr := &PackageVirtualRegistry{}
+
+	has, err := db.GetEngine(ctx).
+		Where("owner_id = ? AND type = ? AND enabled = ?", ownerID, packageType, true).
+		Get(pvr)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrPackageVirtualRegistryNotExist
+	}
+	return pvr, nil
+}
+
+func UpdateVirtualRegistry(ctx context.Context, pvr *PackageVirtualRegistry) error {
+	_, err := db.GetEngine(ctx).ID(pvr.ID).AllCols().Update(pvr)
+	return err
+}
+
+func GetVirtualRegistriesByOwner(ctx context.Context, ownerID int64) ([]*PackageVirtualRegistry, error) {
+	pvrs := make([]*PackageVirtualRegistry, 0, 10)
+	return pvrs, db.GetEngine(ctx).Where("owner_id = ?", ownerID).Find(&pvrs)
+}
+
+func DeleteVirtualRegistryByID(ctx context.Context, registryID int64) error {
+	_, err := db.GetEngine(ctx).ID(registryID).Delete(&PackageVirtualRegistry{})
+	return err
+}
+
+func HasOwnerVirtualRegistryForPackageType(ctx context.Context, ownerID int64, packageType Type) (bool, error) {
+	return db.GetEngine(ctx).
+		Where("owner_id = ? AND type = ?", ownerID, packageType).
+		Exist(&PackageVirtualRegistry{})
+}
@@ -6,10 +6,13 @@ package access_test
 import (
 	"testing"
 
+	auth_model "code.gitea.io/gitea/models/auth"
 	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/organization"
 	perm_model "code.gitea.io/gitea/models/perm"
 	access_model "code.gitea.io/gitea/models/perm/access"
 	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unit"
 	"code.gitea.io/gitea/models/unittest"
 	user_model "code.gitea.io/gitea/models/user"
 
@@ -125,3 +128,61 @@ func TestRepository_RecalculateAccesses2(t *testing.T) {
 	require.NoError(t, err)
 	assert.False(t, has)
 }
+
+func TestPermissionLimitUnitsMode(t *testing.T) {
+	p := access_model.Permission{
+		AccessMode: perm_model.AccessModeOwner,
+		Units: []*repo_model.RepoUnit{
+			{Type: unit.TypeCode},
+			{Type: unit.TypeIssues},
+			{Type: unit.TypePullRequests},
+		},
+	}
+	anonymous := access_model.Permission{
+		AccessMode: perm_model.AccessModeRead,
+		Units:      []*repo_model.RepoUnit{{Type: unit.TypeCode}},
+	}
+
+	p.LimitUnitsMode(func(unitType unit.Type) perm_model.AccessMode {
+		if unitType == unit.TypeIssues {
+			return perm_model.AccessModeWrite
+		}
+		return perm_model.AccessModeNone
+	}, anonymous)
+
+	assert.Equal(t, perm_model.AccessModeWrite, p.AccessMode)
+	assert.False(t, p.IsOwner())
+	assert.True(t, p.CanRead(unit.TypeCode))
+	assert.False(t, p.CanWrite(unit.TypeCode))
+	assert.True(t, p.CanWrite(unit.TypeIssues))
+	assert.False(t, p.CanRead(unit.TypePullRequests))
+}
+
+func TestOrgTwoFactorPolicyPermission(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	admin := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1})
+	user2 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	// A private repository owned by Org 3, user2 is an owner of the organization
+	repo3 := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 3})
+
+	perm, err := access_model.GetUserRepoPermission(db.DefaultContext, repo3, user2)
+	require.NoError(t, err)
+	assert.True(t, perm.IsOwner())
+
+	require.NoError(t, organization.SetTwoFactorPolicy(db.DefaultContext, 3, 0))
+
+	perm, err = access_model.GetUserRepoPermission(db.DefaultContext, repo3, user2)
+	require.NoError(t, err)
+	assert.False(t, perm.HasAccess())
+
+	perm, err = access_model.GetUserRepoPermission(db.DefaultContext, repo3, admin)
+	require.NoError(t, err)
+	assert.True(t, perm.IsAdmin())
+
+	unittest.AssertSuccessfulInsert(t, &auth_model.TwoFactor{UID: user2.ID})
+
+	perm, err = access_model.GetUserRepoPermission(db.DefaultContext, repo3, user2)
+	require.NoError(t, err)
+	assert.True(t, perm.IsOwner())
+}
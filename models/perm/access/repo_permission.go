@@ -64,6 +64,22 @@ func (p *Permission) UnitAccessMode(unitType unit.Type) perm_model.AccessMode {
 	return p.UnitsMode[unitType]
 }
 
+// LimitUnitsMode limits the access mode of every unit to the mode returned by limit.
+// The access never drops below the access mode of the base permission, e.g. the permission of anonymous users.
+func (p *Permission) LimitUnitsMode(limit func(unit.Type) perm_model.AccessMode, base Permission) {
+	unitsMode := make(map[unit.Type]perm_model.AccessMode, len(p.Units))
+	maxMode := perm_model.AccessModeNone
+	for _, u := range p.Units {
+		mode := max(min(p.UnitAccessMode(u.Type), limit(u.Type)), base.UnitAccessMode(u.Type))
+		if mode > perm_model.AccessModeNone {
+			unitsMode[u.Type] = mode
+			maxMode = max(maxMode, mode)
+		}
+	}
+	p.UnitsMode = unitsMode
+	p.AccessMode = min(p.AccessMode, maxMode)
+}
+
 // CanAccess returns true if user has mode access to the unit of the repository
 func (p *Permission) CanAccess(mode perm_model.AccessMode, unitType unit.Type) bool {
 	return p.UnitAccessMode(unitType) >= mode
@@ -199,6 +215,27 @@ func GetUserRepoPermission(ctx context.Context, repo *repo_model.Repository, use
 		return perm, nil
 	}
 
+	// Organizations may require two-factor authentication, users who didn't enroll it
+	// are limited to the access of anonymous users
+	if repo.Owner.IsOrganization() {
+		compliant, err := organization.IsTwoFactorCompliant(ctx, repo.OwnerID, user.ID)
+		if err != nil {
+			return perm, err
+		}
+		if !compliant {
+			return GetUserRepoPermission(ctx, repo, nil)
+		}
+
+		// the same applies to requests sent from addresses the IP allowlist doesn't contain
+		allowed, err := organization.IsRequestIPAllowed(ctx, repo.OwnerID, user)
+		if err != nil {
+			return perm, err
+		}
+		if !allowed {
+			return GetUserRepoPermission(ctx, repo, nil)
+		}
+	}
+
 	// plain user
 	perm.AccessMode, err = accessLevel(ctx, user, repo)
 	if err != nil {
@@ -0,0 +1,73 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package repo
+
+import (
+	"context"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/validation"
+)
+
+// FollowerRepo represents a federated Repository Actor following a local Repo,
+// the commits pushed to the local Repo are announced to it
+type FollowerRepo struct {
+	ID               int64              `xorm:"pk autoincr"`
+	RepoID           int64              `xorm:"UNIQUE(follower_repo_mapping) NOT NULL"`
+	URI              string             `xorm:"UNIQUE(follower_repo_mapping) VARCHAR(255) NOT NULL"`
+	FederationHostID int64              `xorm:"INDEX NOT NULL"`
+	CreatedUnix      timeutil.TimeStamp `xorm:"created"`
+}
+
+func init() {
+	db.RegisterModel(new(FollowerRepo))
+}
+
+func NewFollowerRepo(repoID int64, uri string, federationHostID int64) (FollowerRepo, error) {
+	result := FollowerRepo{
+		RepoID:           repoID,
+		URI:              uri,
+		FederationHostID: federationHostID,
+	}
+	if valid, err := validation.IsValid(result); !valid {
+		return FollowerRepo{}, err
+	}
+	return result, nil
+}
+
+func (follower FollowerRepo) Validate() []string {
+	var result []string
+	result = append(result, validation.ValidateNotEmpty(follower.RepoID, "RepoID")...)
+	result = append(result, validation.ValidateNotEmpty(follower.URI, "Uri")...)
+	result = append(result, validation.ValidateMaxLen(follower.URI, 255, "Uri")...)
+	result = append(result, validation.ValidateNotEmpty(follower.FederationHostID, "FederationHostID")...)
+	return result
+}
+
+// AddFollowerRepo stores the federated repository as a follower of the local repository unless it already follows it
+func AddFollowerRepo(ctx context.Context, follower *FollowerRepo) error {
+	if res, err := validation.IsValid(*follower); !res {
+		return err
+	}
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		has, err := db.GetEngine(ctx).Where("repo_id=? AND uri=?", follower.RepoID, follower.URI).Exist(new(FollowerRepo))
+		if err != nil || has {
+			return err
+		}
+		return db.Insert(ctx, follower)
+	})
+}
+
+// DeleteFollowerRepo removes the federated repository from the followers of the local repository
+func DeleteFollowerRepo(ctx context.Context, repoID int64, uri string) error {
+	_, err := db.GetEngine(ctx).Where("repo_id=? AND uri=?", repoID, uri).Delete(new(FollowerRepo))
+	return err
+}
+
+// FindFollowerReposByRepoID returns the federated repositories following the local repository
+func FindFollowerReposByRepoID(ctx context.Context, repoID int64) ([]*FollowerRepo, error) {
+	followers := make([]*FollowerRepo, 0, 10)
+	return followers, db.GetEngine(ctx).Where("repo_id=?", repoID).OrderBy("id").Find(&followers)
+}
@@ -0,0 +1,43 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package repo_test
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFollowerRepo(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	_, err := repo_model.NewFollowerRepo(1, "", 1)
+	require.Error(t, err)
+
+	uri := "https://example.com/api/v1/activitypub/repository-id/5"
+	follower, err := repo_model.NewFollowerRepo(1, uri, 1)
+	require.NoError(t, err)
+	require.NoError(t, repo_model.AddFollowerRepo(db.DefaultContext, &follower))
+	// following again doesn't add another follower
+	again, err := repo_model.NewFollowerRepo(1, uri, 1)
+	require.NoError(t, err)
+	require.NoError(t, repo_model.AddFollowerRepo(db.DefaultContext, &again))
+
+	followers, err := repo_model.FindFollowerReposByRepoID(db.DefaultContext, 1)
+	require.NoError(t, err)
+	require.Len(t, followers, 1)
+	assert.Equal(t, uri, followers[0].URI)
+
+	followers, err = repo_model.FindFollowerReposByRepoID(db.DefaultContext, 2)
+	require.NoError(t, err)
+	assert.Empty(t, followers)
+
+	require.NoError(t, repo_model.DeleteFollowerRepo(db.DefaultContext, 1, uri))
+	unittest.AssertNotExistsBean(t, &repo_model.FollowerRepo{RepoID: 1, URI: uri})
+}
@@ -199,6 +199,9 @@ type SearchRepoOptions struct {
 	HasMilestones optional.Option[bool]
 	// LowerNames represents valid lower names to restrict to
 	LowerNames []string
+	// LimitCond restricts the results to the repositories anonymous users can see and the ones matching it,
+	// e.g. the repositories of a fine-grained access token
+	LimitCond builder.Cond
 	// When specified true, apply some filters over the conditions:
 	// - Don't show forks, when opts.Fork is OptionalBoolNone.
 	// - Do not display repositories that don't have a description, an icon and topics.
@@ -375,6 +378,10 @@ func SearchRepositoryCondition(opts *SearchRepoOptions) builder.Cond {
 		cond = cond.And(builder.Eq{"is_private": opts.IsPrivate.Value()})
 	}
 
+	if opts.LimitCond != nil {
+		cond = cond.And(builder.Or(AccessibleRepositoryCondition(nil, unit.TypeInvalid), opts.LimitCond))
+	}
+
 	if opts.Template.Has() {
 		cond = cond.And(builder.Eq{"is_template": opts.Template.Value()})
 	}
@@ -747,6 +754,10 @@ func GetUserRepositories(ctx context.Context, opts *SearchRepoOptions) (Reposito
 		cond = cond.And(builder.In("lower_name", opts.LowerNames))
 	}
 
+	if opts.LimitCond != nil {
+		cond = cond.And(builder.Or(AccessibleRepositoryCondition(nil, unit.TypeInvalid), opts.LimitCond))
+	}
+
 	sess := db.GetEngine(ctx)
 
 	count, err := sess.Where(cond).Count(new(Repository))
@@ -10,10 +10,12 @@ import (
 	"code.gitea.io/gitea/models/db"
 	repo_model "code.gitea.io/gitea/models/repo"
 	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/optional"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"xorm.io/builder"
 )
 
 func getTestCases() []struct {
@@ -403,3 +405,44 @@ func TestSearchRepositoryByTopicName(t *testing.T) {
 		})
 	}
 }
+
+func TestSearchRepositoryLimitCond(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	user2 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	opts := &repo_model.SearchRepoOptions{
+		ListOptions: db.ListOptions{PageSize: 100},
+		Actor:       user2,
+		OwnerID:     user2.ID,
+		Private:     true,
+	}
+	repos, _, err := repo_model.SearchRepository(db.DefaultContext, opts)
+	require.NoError(t, err)
+	hasPrivate := false
+	for _, repo := range repos {
+		hasPrivate = hasPrivate || (repo.IsPrivate && repo.ID != 2)
+	}
+	assert.True(t, hasPrivate)
+
+	// only the private repository matching the condition remains, public repositories outside of it are kept
+	opts.LimitCond = builder.In("`repository`.id", []int64{2})
+	repos, _, err = repo_model.SearchRepository(db.DefaultContext, opts)
+	require.NoError(t, err)
+	repoIDs := make([]int64, 0, len(repos))
+	for _, repo := range repos {
+		assert.True(t, !repo.IsPrivate || repo.ID == 2, repo.FullName())
+		repoIDs = append(repoIDs, repo.ID)
+	}
+	assert.Contains(t, repoIDs, int64(1))
+	assert.Contains(t, repoIDs, int64(2))
+
+	repos, _, err = repo_model.GetUserRepositories(db.DefaultContext, opts)
+	require.NoError(t, err)
+	repoIDs = repoIDs[:0]
+	for _, repo := range repos {
+		assert.True(t, !repo.IsPrivate || repo.ID == 2, repo.FullName())
+		repoIDs = append(repoIDs, repo.ID)
+	}
+	assert.Contains(t, repoIDs, int64(1))
+	assert.Contains(t, repoIDs, int64(2))
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package user
+
+import (
+	"context"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/builder"
+)
+
+// FederatedFollow is a follow relation between a local user and a user of another instance,
+// established by the ActivityPub Follow activity with the ID ActivityID. The Follow relation
+// itself only exists once the followed user accepted it.
+type FederatedFollow struct {
+	ID          int64              `xorm:"pk autoincr"`
+	UserID      int64              `xorm:"UNIQUE(federated_follow) NOT NULL"`
+	FollowID    int64              `xorm:"UNIQUE(federated_follow) NOT NULL"`
+	ActivityID  string             `xorm:"VARCHAR(255) INDEX NOT NULL"`
+	Accepted    bool               `xorm:"NOT NULL DEFAULT false"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+}
+
+func init() {
+	db.RegisterModel(new(FederatedFollow))
+}
+
+// GetFederatedFollow returns the federated follow relation of the users, nil if there is none
+func GetFederatedFollow(ctx context.Context, userID, followID int64) (*FederatedFollow, error) {
+	f := new(FederatedFollow)
+	if has, err := db.GetEngine(ctx).Where("user_id = ? AND follow_id = ?", userID, followID).Get(f); err != nil {
+		return nil, err
+	} else if !has {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// GetFederatedFollowByActivityID returns the federated follow relation established by the Follow activity, nil if there is none
+func GetFederatedFollowByActivityID(ctx context.Context, activityID string) (*FederatedFollow, error) {
+	f := new(FederatedFollow)
+	if has, err := db.GetEngine(ctx).Where("activity_id = ?", activityID).Get(f); err != nil {
+		return nil, err
+	} else if !has {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// CreateFederatedFollow inserts a federated follow relation, an accepted relation makes the user follow the other one
+func CreateFederatedFollow(ctx context.Context, f *FederatedFollow) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if err := db.Insert(ctx, f); err != nil {
+			return err
+		}
+		if !f.Accepted {
+			return nil
+		}
+		return FollowUser(ctx, f.UserID, f.FollowID)
+	})
+}
+
+// AcceptFederatedFollow marks the federated follow relation as accepted and makes the user follow the other one
+func AcceptFederatedFollow(ctx context.Context, f *FederatedFollow) error {
+	if f.Accepted {
+		return nil
+	}
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		f.Accepted = true
+		if _, err := db.GetEngine(ctx).ID(f.ID).Cols("accepted").Update(f); err != nil {
+			return err
+		}
+		return FollowUser(ctx, f.UserID, f.FollowID)
+	})
+}
+
+// DeleteFederatedFollow deletes the federated follow relation and the follow relation it established
+func DeleteFederatedFollow(ctx context.Context, f *FederatedFollow) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := db.DeleteByID[FederatedFollow](ctx, f.ID); err != nil {
+			return err
+		}
+		return UnfollowUser(ctx, f.UserID, f.FollowID)
+	})
+}
+
+// FindFederatedFollowPeers returns the users of other instances following the user or followed by it,
+// including the pending follow relations
+func FindFederatedFollowPeers(ctx context.Context, userID int64) ([]*User, error) {
+	users := make([]*User, 0, 10)
+	return users, db.GetEngine(ctx).
+		Where(builder.In("id", builder.Select("follow_id").From("federated_follow").Where(builder.Eq{"user_id": userID})).
+			Or(builder.In("id", builder.Select("user_id").From("federated_follow").Where(builder.Eq{"follow_id": userID})))).
+		And("type = ?", UserTypeRemoteUser).
+		OrderBy("id").
+		Find(&users)
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package user_test
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFederatedFollow(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	f, err := user_model.GetFederatedFollow(db.DefaultContext, 2, 5)
+	require.NoError(t, err)
+	assert.Nil(t, f)
+
+	activityID := "https://example.com/api/v1/activitypub/user-id/2/outbox/1"
+	require.NoError(t, user_model.CreateFederatedFollow(db.DefaultContext, &user_model.FederatedFollow{UserID: 2, FollowID: 5, ActivityID: activityID}))
+	assert.False(t, user_model.IsFollowing(db.DefaultContext, 2, 5))
+
+	f, err = user_model.GetFederatedFollowByActivityID(db.DefaultContext, activityID)
+	require.NoError(t, err)
+	require.NotNil(t, f)
+	assert.EqualValues(t, 2, f.UserID)
+	assert.EqualValues(t, 5, f.FollowID)
+
+	require.NoError(t, user_model.AcceptFederatedFollow(db.DefaultContext, f))
+	assert.True(t, user_model.IsFollowing(db.DefaultContext, 2, 5))
+	unittest.AssertExistsAndLoadBean(t, &user_model.FederatedFollow{ID: f.ID, Accepted: true})
+
+	require.NoError(t, user_model.DeleteFederatedFollow(db.DefaultContext, f))
+	assert.False(t, user_model.IsFollowing(db.DefaultContext, 2, 5))
+	unittest.AssertNotExistsBean(t, &user_model.FederatedFollow{ID: f.ID})
+
+	f, err = user_model.GetFederatedFollowByActivityID(db.DefaultContext, activityID)
+	require.NoError(t, err)
+	assert.Nil(t, f)
+
+	// an accepted follow relation makes the user follow the other one right away
+	require.NoError(t, user_model.CreateFederatedFollow(db.DefaultContext, &user_model.FederatedFollow{UserID: 5, FollowID: 2, ActivityID: activityID, Accepted: true}))
+	assert.True(t, user_model.IsFollowing(db.DefaultContext, 5, 2))
+}
+
+func TestFindFederatedFollowPeers(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	for _, id := range []int64{5, 8} {
+		_, err := db.GetEngine(db.DefaultContext).ID(id).Cols("type").Update(&user_model.User{Type: user_model.UserTypeRemoteUser})
+		require.NoError(t, err)
+	}
+	require.NoError(t, user_model.CreateFederatedFollow(db.DefaultContext, &user_model.FederatedFollow{UserID: 2, FollowID: 5, ActivityID: "https://example.com/api/v1/activitypub/user-id/2/outbox/1"}))
+	require.NoError(t, user_model.CreateFederatedFollow(db.DefaultContext, &user_model.FederatedFollow{UserID: 8, FollowID: 2, ActivityID: "https://example.org/outbox/1", Accepted: true}))
+
+	peers, err := user_model.FindFederatedFollowPeers(db.DefaultContext, 2)
+	require.NoError(t, err)
+	if assert.Len(t, peers, 2) {
+		assert.EqualValues(t, 5, peers[0].ID)
+		assert.EqualValues(t, 8, peers[1].ID)
+	}
+
+	peers, err = user_model.FindFederatedFollowPeers(db.DefaultContext, 4)
+	require.NoError(t, err)
+	assert.Empty(t, peers)
+}
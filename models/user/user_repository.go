@@ -81,3 +81,12 @@ func DeleteFederatedUser(ctx context.Context, userID int64) error {
 	_, err := db.GetEngine(ctx).Delete(&FederatedUser{UserID: userID})
 	return err
 }
+
+// FindFederatedUsersByHostID returns the users of a federation host
+func FindFederatedUsersByHostID(ctx context.Context, federationHostID int64) ([]*User, error) {
+	users := make([]*User, 0, 10)
+	return users, db.GetEngine(ctx).
+		Join("INNER", "federated_user", "federated_user.user_id = `user`.id").
+		Where("federated_user.federation_host_id = ?", federationHostID).
+		Find(&users)
+}
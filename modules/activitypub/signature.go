@@ -0,0 +1,561 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package activitypub
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.gitea.io/gitea/modules/setting"
+)
+
+// SignatureInputHeader is the header listing the parameters of RFC 9421 HTTP Message Signatures,
+// requests without it are signed according to draft-cavage-http-signatures
+const SignatureInputHeader = "Signature-Input"
+
+// CheckDate returns an error if the Date header is missing or differs from the local time by more than skew
+func CheckDate(header http.Header, skew time.Duration) error {
+	date := header.Get("Date")
+	if date == "" {
+		return errors.New("missing Date header")
+	}
+	t, err := http.ParseTime(date)
+	if err != nil {
+		return fmt.Errorf("invalid Date header: %w", err)
+	}
+	return checkClockSkew(t, skew)
+}
+
+func checkClockSkew(t time.Time, skew time.Duration) error {
+	if d := time.Since(t); d > skew || d < -skew {
+		return fmt.Errorf("signed at %s, more than %s from the local time", t.UTC().Format(time.RFC3339), skew)
+	}
+	return nil
+}
+
+// digestAlgorithms are the hash functions of the Digest and Content-Digest headers by their lower case names
+var digestAlgorithms = map[string]func() hash.Hash{
+	"sha-256": sha256.New,
+	"sha-512": sha512.New,
+}
+
+// CheckDigest verifies the Content-Digest (RFC 9530) and Digest (RFC 3230) headers of a request with the body,
+// at least one of them has to contain a digest of a supported algorithm
+func CheckDigest(header http.Header, body []byte) error {
+	checked := false
+	if v := header.Get("Content-Digest"); v != "" {
+		members, err := parseDictionary(v)
+		if err != nil {
+			return fmt.Errorf("invalid Content-Digest header: %w", err)
+		}
+		for _, m := range members {
+			digest, ok := m.value.([]byte)
+			if !ok {
+				return fmt.Errorf("invalid Content-Digest header: %s isn't a byte sequence", m.key)
+			}
+			if ok, err := checkDigest(m.key, digest, body); err != nil {
+				return err
+			} else if ok {
+				checked = true
+			}
+		}
+	}
+	if v := header.Get("Digest"); v != "" {
+		for _, d := range strings.Split(v, ",") {
+			alg, value, found := strings.Cut(strings.TrimSpace(d), "=")
+			if !found {
+				return errors.New("invalid Digest header")
+			}
+			digest, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return fmt.Errorf("invalid Digest header: %w", err)
+			}
+			if ok, err := checkDigest(strings.ToLower(alg), digest, body); err != nil {
+				return err
+			} else if ok {
+				checked = true
+			}
+		}
+	}
+	if !checked {
+		return errors.New("missing digest of the body")
+	}
+	return nil
+}
+
+// checkDigest returns false if the algorithm isn't supported and an error if the digest doesn't match the body
+func checkDigest(alg string, digest, body []byte) (bool, error) {
+	newHash, ok := digestAlgorithms[alg]
+	if !ok {
+		return false, nil
+	}
+	h := newHash()
+	h.Write(body)
+	if subtle.ConstantTimeCompare(h.Sum(nil), digest) != 1 {
+		return false, fmt.Errorf("%s digest doesn't match the body", alg)
+	}
+	return true, nil
+}
+
+// CavageSignedHeaders returns the lower case names of the headers covered by a draft-cavage signature of the request
+func CavageSignedHeaders(header http.Header) ([]string, error) {
+	v := header.Get("Signature")
+	if v == "" {
+		var found bool
+		if v, found = strings.CutPrefix(header.Get("Authorization"), "Signature "); !found {
+			return nil, errors.New("missing Signature header")
+		}
+	}
+	for _, param := range strings.Split(v, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if name == "headers" {
+			return strings.Fields(strings.ToLower(strings.Trim(value, `"`))), nil
+		}
+	}
+	// the headers default to the Date header
+	return []string{"date"}, nil
+}
+
+// MessageSignature is an RFC 9421 HTTP Message Signature of a request
+type MessageSignature struct {
+	Label      string
+	KeyID      string
+	Algorithm  string
+	Created    time.Time
+	Expires    time.Time
+	Components []string
+
+	// identifiers are the serialized component identifiers and params the serialized signature parameters,
+	// as they were sent to reproduce the signature base
+	identifiers []string
+	params      string
+	signature   []byte
+}
+
+// ParseMessageSignature returns the first signature of the request with a key ID
+func ParseMessageSignature(header http.Header) (*MessageSignature, error) {
+	inputs, err := parseDictionary(header.Get(SignatureInputHeader))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s header: %w", SignatureInputHeader, err)
+	}
+	signatures, err := parseDictionary(header.Get("Signature"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Signature header: %w", err)
+	}
+
+	for _, input := range inputs {
+		keyID, _ := input.params["keyid"].(string)
+		if keyID == "" {
+			continue
+		}
+		var signature []byte
+		for _, s := range signatures {
+			if s.key == input.key {
+				signature, _ = s.value.([]byte)
+			}
+		}
+		if signature == nil {
+			continue
+		}
+		items, ok := input.value.([]sfItem)
+		if !ok {
+			return nil, fmt.Errorf("signature %s: covered components aren't an inner list", input.key)
+		}
+
+		s := &MessageSignature{
+			Label:     input.key,
+			KeyID:     keyID,
+			params:    input.raw,
+			signature: signature,
+		}
+		s.Algorithm, _ = input.params["alg"].(string)
+		if created, ok := input.params["created"].(int64); ok {
+			s.Created = time.Unix(created, 0)
+		}
+		if expires, ok := input.params["expires"].(int64); ok {
+			s.Expires = time.Unix(expires, 0)
+		}
+		for _, item := range items {
+			name, ok := item.value.(string)
+			if !ok {
+				return nil, fmt.Errorf("signature %s: invalid component identifier %s", input.key, item.raw)
+			}
+			if len(item.params) > 0 {
+				return nil, fmt.Errorf("signature %s: unsupported component identifier %s", input.key, item.raw)
+			}
+			s.Components = append(s.Components, name)
+			s.identifiers = append(s.identifiers, item.raw)
+		}
+		return s, nil
+	}
+	return nil, errors.New("missing signature with a key ID")
+}
+
+// Covers returns true if the component is covered by the signature
+func (s *MessageSignature) Covers(component string) bool {
+	for _, c := range s.Components {
+		if c == component {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate returns an error if the signature doesn't cover the method, the target and for requests with a body
+// its digest, wasn't created within skew of the local time or expired
+func (s *MessageSignature) Validate(method string, skew time.Duration) error {
+	if !s.Covers("@method") {
+		return errors.New("the signature doesn't cover @method")
+	}
+	if !s.Covers("@target-uri") && !s.Covers("@request-target") && !s.Covers("@path") {
+		return errors.New("the signature doesn't cover @target-uri")
+	}
+	if method != http.MethodGet && method != http.MethodHead && !s.Covers("content-digest") && !s.Covers("digest") {
+		return errors.New("the signature doesn't cover content-digest")
+	}
+	if s.Created.IsZero() {
+		return errors.New("the signature has no created parameter")
+	}
+	if err := checkClockSkew(s.Created, skew); err != nil {
+		return err
+	}
+	if !s.Expires.IsZero() && time.Since(s.Expires) > skew {
+		return fmt.Errorf("the signature expired at %s", s.Expires.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Verify verifies the signature of the request with the public key,
+// without an alg parameter the algorithm is derived from the type of the key
+func (s *MessageSignature) Verify(r *http.Request, pub crypto.PublicKey) error {
+	base, err := s.signatureBase(r)
+	if err != nil {
+		return err
+	}
+
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		switch s.Algorithm {
+		case "rsa-pss-sha512":
+			h := sha512.Sum512(base)
+			return rsa.VerifyPSS(key, crypto.SHA512, h[:], s.signature, &rsa.PSSOptions{SaltLength: sha512.Size})
+		case "", "rsa-v1_5-sha256":
+			h := sha256.Sum256(base)
+			return rsa.VerifyPKCS1v15(key, crypto.SHA256, h[:], s.signature)
+		}
+	case *ecdsa.PublicKey:
+		if (s.Algorithm == "" || s.Algorithm == "ecdsa-p256-sha256") && key.Curve == elliptic.P256() {
+			h := sha256.Sum256(base)
+			if len(s.signature) != 64 || !ecdsa.Verify(key, h[:], new(big.Int).SetBytes(s.signature[:32]), new(big.Int).SetBytes(s.signature[32:])) {
+				return errors.New("invalid ecdsa-p256-sha256 signature")
+			}
+			return nil
+		}
+	case ed25519.PublicKey:
+		if s.Algorithm == "" || s.Algorithm == "ed25519" {
+			if !ed25519.Verify(key, base, s.signature) {
+				return errors.New("invalid ed25519 signature")
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported algorithm %q for a key of type %T", s.Algorithm, pub)
+}
+
+// signatureBase returns the signature base of the request as defined in section 2.5 of RFC 9421
+func (s *MessageSignature) signatureBase(r *http.Request) ([]byte, error) {
+	var base bytes.Buffer
+	for i, component := range s.Components {
+		value, err := componentValue(r, component)
+		if err != nil {
+			return nil, err
+		}
+		base.WriteString(s.identifiers[i])
+		base.WriteString(": ")
+		base.WriteString(value)
+		base.WriteByte('\n')
+	}
+	base.WriteString(`"@signature-params": `)
+	base.WriteString(s.params)
+	return base.Bytes(), nil
+}
+
+// componentValue returns the value of a derived component or header field of the request
+func componentValue(r *http.Request, component string) (string, error) {
+	switch component {
+	case "@method":
+		return r.Method, nil
+	case "@target-uri":
+		return requestScheme(r) + "://" + strings.ToLower(r.Host) + r.URL.RequestURI(), nil
+	case "@authority":
+		return strings.ToLower(r.Host), nil
+	case "@scheme":
+		return requestScheme(r), nil
+	case "@request-target":
+		return r.URL.RequestURI(), nil
+	case "@path":
+		return r.URL.EscapedPath(), nil
+	case "@query":
+		return "?" + r.URL.RawQuery, nil
+	}
+	if strings.HasPrefix(component, "@") {
+		return "", fmt.Errorf("unsupported derived component %s", component)
+	}
+
+	// Header.Values returns the slice of the request, it's copied to not modify the header when trimming
+	values := slices.Clone(r.Header.Values(component))
+	if strings.EqualFold(component, "host") && len(values) == 0 && r.Host != "" {
+		// the Host header is moved to Request.Host by net/http
+		values = []string{r.Host}
+	}
+	if len(values) == 0 {
+		return "", fmt.Errorf("missing header %s", component)
+	}
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(values, ", "), nil
+}
+
+// requestScheme returns the scheme of the URL the request was sent to, taken from the ROOT_URL
+// because requests are usually forwarded by a reverse proxy
+func requestScheme(r *http.Request) string {
+	if u, err := url.Parse(setting.AppURL); err == nil && u.Scheme != "" {
+		return u.Scheme
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// sfItem is an item or inner list of an RFC 8941 structured field,
+// raw is the item with its parameters as it was serialized
+type sfItem struct {
+	key    string
+	value  any
+	params map[string]any
+	raw    string
+}
+
+// sfParser parses the subset of RFC 8941 structured field dictionaries used by HTTP message signatures
+type sfParser struct {
+	s string
+	i int
+}
+
+func (p *sfParser) skipSpaces() {
+	for p.i < len(p.s) && (p.s[p.i] == ' ' || p.s[p.i] == '\t') {
+		p.i++
+	}
+}
+
+func (p *sfParser) consume(c byte) bool {
+	if p.i < len(p.s) && p.s[p.i] == c {
+		p.i++
+		return true
+	}
+	return false
+}
+
+// parseDictionary parses a dictionary whose members are items or inner lists
+func parseDictionary(s string) ([]sfItem, error) {
+	p := &sfParser{s: s}
+	var members []sfItem
+	p.skipSpaces()
+	for p.i < len(p.s) {
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		var member sfItem
+		if p.consume('=') {
+			if member, err = p.parseItemOrInnerList(); err != nil {
+				return nil, err
+			}
+		} else {
+			start := p.i
+			params, err := p.parseParameters()
+			if err != nil {
+				return nil, err
+			}
+			member = sfItem{value: true, params: params, raw: p.s[start:p.i]}
+		}
+		member.key = key
+		members = append(members, member)
+
+		p.skipSpaces()
+		if p.i == len(p.s) {
+			break
+		}
+		if !p.consume(',') {
+			return nil, fmt.Errorf("unexpected %q at %d", p.s[p.i], p.i)
+		}
+		p.skipSpaces()
+		if p.i == len(p.s) {
+			return nil, errors.New("trailing comma")
+		}
+	}
+	return members, nil
+}
+
+func (p *sfParser) parseItemOrInnerList() (sfItem, error) {
+	start := p.i
+	var value any
+	if p.consume('(') {
+		var items []sfItem
+		for {
+			p.skipSpaces()
+			if p.consume(')') {
+				break
+			}
+			item, err := p.parseItem()
+			if err != nil {
+				return sfItem{}, err
+			}
+			items = append(items, item)
+			if p.i >= len(p.s) || (p.s[p.i] != ' ' && p.s[p.i] != ')') {
+				return sfItem{}, fmt.Errorf("unterminated inner list at %d", p.i)
+			}
+		}
+		value = items
+	} else {
+		v, err := p.parseBareItem()
+		if err != nil {
+			return sfItem{}, err
+		}
+		value = v
+	}
+	params, err := p.parseParameters()
+	if err != nil {
+		return sfItem{}, err
+	}
+	return sfItem{value: value, params: params, raw: p.s[start:p.i]}, nil
+}
+
+func (p *sfParser) parseItem() (sfItem, error) {
+	start := p.i
+	value, err := p.parseBareItem()
+	if err != nil {
+		return sfItem{}, err
+	}
+	params, err := p.parseParameters()
+	if err != nil {
+		return sfItem{}, err
+	}
+	return sfItem{value: value, params: params, raw: p.s[start:p.i]}, nil
+}
+
+func (p *sfParser) parseParameters() (map[string]any, error) {
+	params := map[string]any{}
+	for p.consume(';') {
+		p.skipSpaces()
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		var value any = true
+		if p.consume('=') {
+			if value, err = p.parseBareItem(); err != nil {
+				return nil, err
+			}
+		}
+		params[key] = value
+	}
+	return params, nil
+}
+
+func (p *sfParser) parseKey() (string, error) {
+	start := p.i
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.' || c == '*') {
+			break
+		}
+		p.i++
+	}
+	if p.i == start || !(p.s[start] >= 'a' && p.s[start] <= 'z' || p.s[start] == '*') {
+		return "", fmt.Errorf("invalid key at %d", start)
+	}
+	return p.s[start:p.i], nil
+}
+
+// parseBareItem returns strings and tokens as string, integers as int64, byte sequences as []byte and booleans as bool
+func (p *sfParser) parseBareItem() (any, error) {
+	if p.i >= len(p.s) {
+		return nil, errors.New("unexpected end of the field")
+	}
+	start := p.i
+	switch c := p.s[p.i]; {
+	case c == '"':
+		p.i++
+		var sb strings.Builder
+		for p.i < len(p.s) {
+			c := p.s[p.i]
+			p.i++
+			switch {
+			case c == '\\' && p.i < len(p.s) && (p.s[p.i] == '"' || p.s[p.i] == '\\'):
+				sb.WriteByte(p.s[p.i])
+				p.i++
+			case c == '"':
+				return sb.String(), nil
+			case c < 0x20 || c > 0x7e || c == '\\':
+				return nil, fmt.Errorf("invalid string at %d", start)
+			default:
+				sb.WriteByte(c)
+			}
+		}
+		return nil, fmt.Errorf("unterminated string at %d", start)
+	case c == ':':
+		end := strings.IndexByte(p.s[p.i+1:], ':')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated byte sequence at %d", start)
+		}
+		p.i += end + 2
+		return base64.StdEncoding.DecodeString(p.s[start+1 : p.i-1])
+	case c == '?':
+		p.i++
+		if p.consume('1') {
+			return true, nil
+		} else if p.consume('0') {
+			return false, nil
+		}
+		return nil, fmt.Errorf("invalid boolean at %d", start)
+	case c == '-' || c >= '0' && c <= '9':
+		p.i++
+		for p.i < len(p.s) && p.s[p.i] >= '0' && p.s[p.i] <= '9' {
+			p.i++
+		}
+		return strconv.ParseInt(p.s[start:p.i], 10, 64)
+	case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '*':
+		p.i++
+		for p.i < len(p.s) && isTokenChar(p.s[p.i]) {
+			p.i++
+		}
+		return p.s[start:p.i], nil
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", p.s[p.i], p.i)
+}
+
+func isTokenChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("!#$%&'*+-.^_`|~:/", c) >= 0
+}
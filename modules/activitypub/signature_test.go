@@ -0,0 +1,278 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package activitypub
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDate(t *testing.T) {
+	header := http.Header{}
+	require.Error(t, CheckDate(header, time.Minute))
+
+	header.Set("Date", time.Now().Add(-30*time.Second).UTC().Format(http.TimeFormat))
+	require.NoError(t, CheckDate(header, time.Minute))
+
+	header.Set("Date", time.Now().Add(-2*time.Minute).UTC().Format(http.TimeFormat))
+	require.Error(t, CheckDate(header, time.Minute))
+
+	header.Set("Date", time.Now().Add(2*time.Minute).UTC().Format(http.TimeFormat))
+	require.Error(t, CheckDate(header, time.Minute))
+
+	header.Set("Date", "yesterday")
+	require.Error(t, CheckDate(header, time.Minute))
+}
+
+func TestCheckDigest(t *testing.T) {
+	body := []byte(`{"hello": "world"}`)
+	sha256Sum := sha256.Sum256(body)
+	sha512Sum := sha512.Sum512(body)
+
+	header := http.Header{}
+	require.Error(t, CheckDigest(header, body))
+
+	header.Set("Digest", "SHA-256="+base64.StdEncoding.EncodeToString(sha256Sum[:]))
+	require.NoError(t, CheckDigest(header, body))
+	require.Error(t, CheckDigest(header, []byte(`{"hello": "moon"}`)))
+
+	header.Set("Digest", "MD5=Sd/dVLAcvNLSq16eXua5uQ==")
+	require.Error(t, CheckDigest(header, body), "digests of unsupported algorithms aren't sufficient")
+
+	header = http.Header{}
+	header.Set("Content-Digest", "sha-512=:"+base64.StdEncoding.EncodeToString(sha512Sum[:])+":")
+	require.NoError(t, CheckDigest(header, body))
+	require.Error(t, CheckDigest(header, []byte(`{"hello": "moon"}`)))
+
+	header.Set("Content-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sha256Sum[:])+":, sha-512=:"+base64.StdEncoding.EncodeToString(sha256Sum[:])+":")
+	require.Error(t, CheckDigest(header, body), "all supported digests have to match")
+}
+
+func TestCavageSignedHeaders(t *testing.T) {
+	header := http.Header{}
+	_, err := CavageSignedHeaders(header)
+	require.Error(t, err)
+
+	header.Set("Signature", `keyId="https://example.com/actor#main-key",algorithm="rsa-sha256",headers="(request-target) Host Date Digest",signature="c2ln"`)
+	headers, err := CavageSignedHeaders(header)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"(request-target)", "host", "date", "digest"}, headers)
+
+	header = http.Header{}
+	header.Set("Authorization", `Signature keyId="https://example.com/actor#main-key",signature="c2ln"`)
+	headers, err = CavageSignedHeaders(header)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"date"}, headers)
+}
+
+func TestComponentValue(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "https://forge.example.com/api/v1/activitypub/user-id/1", nil)
+	r.Header["Accept"] = []string{" application/activity+json ", "application/json "}
+
+	v, err := componentValue(r, "accept")
+	require.NoError(t, err)
+	assert.Equal(t, "application/activity+json, application/json", v)
+	// the header of the request isn't trimmed in place
+	assert.Equal(t, []string{" application/activity+json ", "application/json "}, r.Header["Accept"])
+
+	v, err = componentValue(r, "host")
+	require.NoError(t, err)
+	assert.Equal(t, "forge.example.com", v)
+
+	_, err = componentValue(r, "digest")
+	require.Error(t, err)
+}
+
+func TestParseDictionary(t *testing.T) {
+	members, err := parseDictionary(`sig1=("@method" "@target-uri" "content-digest");created=1618884473;keyid="test-key";alg="rsa-pss-sha512", sig2=:c2ln:, flag`)
+	require.NoError(t, err)
+	require.Len(t, members, 3)
+
+	assert.Equal(t, "sig1", members[0].key)
+	assert.Equal(t, `("@method" "@target-uri" "content-digest");created=1618884473;keyid="test-key";alg="rsa-pss-sha512"`, members[0].raw)
+	assert.Equal(t, map[string]any{"created": int64(1618884473), "keyid": "test-key", "alg": "rsa-pss-sha512"}, members[0].params)
+	items := members[0].value.([]sfItem)
+	require.Len(t, items, 3)
+	assert.Equal(t, "@target-uri", items[1].value)
+	assert.Equal(t, `"@target-uri"`, items[1].raw)
+
+	assert.Equal(t, []byte("sig"), members[1].value)
+	assert.Equal(t, true, members[2].value)
+
+	for _, invalid := range []string{`sig1=("@method"`, `sig1=:c2ln`, `Sig1=:c2ln:`, `sig1=:c2ln:,`, `sig1="unterminated`, `sig1=("@method""@path")`} {
+		_, err := parseDictionary(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+// TestMessageSignatureRFCExample verifies the ed25519 example of section B.2.6 of RFC 9421
+func TestMessageSignatureRFCExample(t *testing.T) {
+	block, _ := pem.Decode([]byte("-----BEGIN PUBLIC KEY-----\nMCowBQYDK2VwAyEAJrQLj5P/89iXES9+vFgrIy29clF9CC/oPPsw3c5D0bs=\n-----END PUBLIC KEY-----\n"))
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "http://example.com/foo?param=Value&Pet=dog", strings.NewReader(`{"hello": "world"}`))
+	r.Header.Set("Date", "Tue, 20 Apr 2021 02:07:55 GMT")
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Content-Length", "18")
+	r.Header.Set(SignatureInputHeader, `sig-b26=("date" "@method" "@path" "@authority" "content-type" "content-length");created=1618884473;keyid="test-key-ed25519"`)
+	r.Header.Set("Signature", `sig-b26=:wqcAqbmYJ2ji2glfAMaRy4gruYYnx2nEFN2HN6jrnDnQCK1u02Gb04v9EDgwUPiu4A0w6vuQv5lIp5WPpBKRCw==:`)
+
+	s, err := ParseMessageSignature(r.Header)
+	require.NoError(t, err)
+	assert.Equal(t, "sig-b26", s.Label)
+	assert.Equal(t, "test-key-ed25519", s.KeyID)
+	assert.Equal(t, time.Unix(1618884473, 0), s.Created)
+	require.NoError(t, s.Verify(r, pub))
+
+	r.Header.Set("Content-Type", "text/plain")
+	require.Error(t, s.Verify(r, pub))
+}
+
+// signMessage signs the request with an RFC 9421 signature covering the components
+func signMessage(t *testing.T, r *http.Request, components []string, params string, sign func(base []byte) []byte) {
+	t.Helper()
+	identifiers := make([]string, len(components))
+	for i, c := range components {
+		identifiers[i] = fmt.Sprintf("%q", c)
+	}
+	r.Header.Set(SignatureInputHeader, "sig1=("+strings.Join(identifiers, " ")+")"+params)
+	r.Header.Set("Signature", "sig1=:c2ln:")
+	s, err := ParseMessageSignature(r.Header)
+	require.NoError(t, err)
+	base, err := s.signatureBase(r)
+	require.NoError(t, err)
+	r.Header.Set("Signature", "sig1=:"+base64.StdEncoding.EncodeToString(sign(base))+":")
+}
+
+func TestMessageSignature(t *testing.T) {
+	defer test.MockVariableValue(&setting.AppURL, "https://forge.example.com/")()
+
+	body := []byte(`{"type":"Follow"}`)
+	digest := sha256.Sum256(body)
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "https://forge.example.com/api/v1/activitypub/user-id/1/inbox", strings.NewReader(string(body)))
+		r.Header.Set("Content-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(digest[:])+":")
+		return r
+	}
+	components := []string{"@method", "@target-uri", "content-digest"}
+	created := time.Now().Unix()
+	keyParams := func(alg string) string {
+		params := fmt.Sprintf(`;created=%d;keyid="https://remote.example.com/actor#main-key"`, created)
+		if alg != "" {
+			params += fmt.Sprintf(";alg=%q", alg)
+		}
+		return params
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ed25519Pub, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rsaV15 := func(base []byte) []byte {
+		h := sha256.Sum256(base)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, h[:])
+		require.NoError(t, err)
+		return sig
+	}
+
+	for _, c := range []struct {
+		name string
+		alg  string
+		pub  crypto.PublicKey
+		sign func(base []byte) []byte
+	}{
+		{name: "rsa-v1_5-sha256", alg: "rsa-v1_5-sha256", pub: &rsaKey.PublicKey, sign: rsaV15},
+		{name: "rsa without alg", pub: &rsaKey.PublicKey, sign: rsaV15},
+		{name: "rsa-pss-sha512", alg: "rsa-pss-sha512", pub: &rsaKey.PublicKey, sign: func(base []byte) []byte {
+			h := sha512.Sum512(base)
+			sig, err := rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA512, h[:], &rsa.PSSOptions{SaltLength: sha512.Size})
+			require.NoError(t, err)
+			return sig
+		}},
+		{name: "ecdsa-p256-sha256", alg: "ecdsa-p256-sha256", pub: &ecdsaKey.PublicKey, sign: func(base []byte) []byte {
+			h := sha256.Sum256(base)
+			r, s, err := ecdsa.Sign(rand.Reader, ecdsaKey, h[:])
+			require.NoError(t, err)
+			return append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}},
+		{name: "ed25519", alg: "ed25519", pub: ed25519Pub, sign: func(base []byte) []byte {
+			return ed25519.Sign(ed25519Key, base)
+		}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := newRequest()
+			signMessage(t, r, components, keyParams(c.alg), c.sign)
+			s, err := ParseMessageSignature(r.Header)
+			require.NoError(t, err)
+			assert.Equal(t, "https://remote.example.com/actor#main-key", s.KeyID)
+			require.NoError(t, s.Validate(r.Method, time.Minute))
+			require.NoError(t, s.Verify(r, c.pub))
+
+			// the signature covers the target
+			r.URL.Path = "/api/v1/activitypub/user-id/2/inbox"
+			require.Error(t, s.Verify(r, c.pub))
+		})
+	}
+
+	t.Run("WrongKey", func(t *testing.T) {
+		r := newRequest()
+		signMessage(t, r, components, keyParams(""), rsaV15)
+		s, err := ParseMessageSignature(r.Header)
+		require.NoError(t, err)
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		require.Error(t, s.Verify(r, &otherKey.PublicKey))
+		require.Error(t, s.Verify(r, ed25519Pub))
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		r := newRequest()
+		signMessage(t, r, []string{"@method", "@target-uri"}, keyParams(""), rsaV15)
+		s, err := ParseMessageSignature(r.Header)
+		require.NoError(t, err)
+		require.Error(t, s.Validate(http.MethodPost, time.Minute), "POST requests have to cover the digest")
+		require.NoError(t, s.Validate(http.MethodGet, time.Minute))
+
+		signMessage(t, r, components, fmt.Sprintf(`;created=%d;keyid="key"`, created-3600), rsaV15)
+		s, err = ParseMessageSignature(r.Header)
+		require.NoError(t, err)
+		require.Error(t, s.Validate(http.MethodPost, time.Minute), "signatures have to be fresh")
+
+		signMessage(t, r, components, fmt.Sprintf(`;created=%d;expires=%d;keyid="key"`, created-4, created-10), rsaV15)
+		s, err = ParseMessageSignature(r.Header)
+		require.NoError(t, err)
+		require.NoError(t, s.Validate(http.MethodPost, time.Minute), "expiry is tolerated within the clock skew")
+		require.Error(t, s.Validate(http.MethodPost, 5*time.Second))
+
+		signMessage(t, r, components, `;keyid="key"`, rsaV15)
+		s, err = ParseMessageSignature(r.Header)
+		require.NoError(t, err)
+		require.Error(t, s.Validate(http.MethodPost, time.Minute), "signatures have to be created")
+	})
+}
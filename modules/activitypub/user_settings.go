@@ -19,16 +19,7 @@ func GetKeyPair(ctx context.Context, user *user_model.User) (pub, priv string, e
 	if err != nil {
 		return pub, priv, err
 	} else if len(settings) == 0 {
-		if priv, pub, err = util.GenerateKeyPair(rsaBits); err != nil {
-			return pub, priv, err
-		}
-		if err = user_model.SetUserSetting(ctx, user.ID, user_model.UserActivityPubPrivPem, priv); err != nil {
-			return pub, priv, err
-		}
-		if err = user_model.SetUserSetting(ctx, user.ID, user_model.UserActivityPubPubPem, pub); err != nil {
-			return pub, priv, err
-		}
-		return pub, priv, err
+		return generateKeyPair(ctx, user)
 	}
 	priv = settings[user_model.UserActivityPubPrivPem].SettingValue
 	pub = settings[user_model.UserActivityPubPubPem].SettingValue
@@ -46,3 +37,22 @@ func GetPrivateKey(ctx context.Context, user *user_model.User) (priv string, err
 	_, priv, err = GetKeyPair(ctx, user)
 	return priv, err
 }
+
+// RotateKeyPair function replaces a user's key pair with a new one and returns the new public key
+func RotateKeyPair(ctx context.Context, user *user_model.User) (pub string, err error) {
+	pub, _, err = generateKeyPair(ctx, user)
+	return pub, err
+}
+
+func generateKeyPair(ctx context.Context, user *user_model.User) (pub, priv string, err error) {
+	if priv, pub, err = util.GenerateKeyPair(rsaBits); err != nil {
+		return pub, priv, err
+	}
+	if err = user_model.SetUserSetting(ctx, user.ID, user_model.UserActivityPubPrivPem, priv); err != nil {
+		return pub, priv, err
+	}
+	if err = user_model.SetUserSetting(ctx, user.ID, user_model.UserActivityPubPubPem, pub); err != nil {
+		return pub, priv, err
+	}
+	return pub, priv, err
+}
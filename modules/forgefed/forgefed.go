@@ -10,12 +10,31 @@ import (
 
 const ForgeFedNamespaceURI = "https://forgefed.org/ns"
 
+func init() {
+	// let the activitypub package decode the ForgeFed objects embedded in activities
+	ap.ItemTyperFunc = GetItemByType
+	ap.JSONItemUnmarshal = JSONUnmarshalerFn
+	ap.IsNotEmpty = NotEmpty
+}
+
 // GetItemByType instantiates a new ForgeFed object if the type matches
 // otherwise it defaults to existing activitypub package typer function.
 func GetItemByType(typ ap.ActivityVocabularyType) (ap.Item, error) {
 	switch typ {
 	case RepositoryType:
 		return RepositoryNew(""), nil
+	case TicketType:
+		return TicketNew(""), nil
+	case CommitType:
+		return CommitNew(""), nil
+	case PushType:
+		a := ap.ActivityNew("", PushType, nil)
+		a.Type = PushType
+		return a, nil
+	case BranchType:
+		o := ap.ObjectNew(BranchType)
+		o.Type = BranchType
+		return o, nil
 	default:
 		return ap.GetItemByType(typ)
 	}
@@ -29,6 +48,22 @@ func JSONUnmarshalerFn(typ ap.ActivityVocabularyType, val *fastjson.Value, i ap.
 		return OnRepository(i, func(r *Repository) error {
 			return JSONLoadRepository(val, r)
 		})
+	case TicketType:
+		return OnTicket(i, func(t *Ticket) error {
+			return JSONLoadTicket(val, t)
+		})
+	case CommitType:
+		return OnCommit(i, func(c *Commit) error {
+			return JSONLoadCommit(val, c)
+		})
+	case PushType:
+		return ap.OnActivity(i, func(a *ap.Activity) error {
+			return ap.JSONLoadActivity(val, a)
+		})
+	case BranchType:
+		return ap.OnObject(i, func(o *ap.Object) error {
+			return ap.JSONLoadObject(val, o)
+		})
 	default:
 		return nil
 	}
@@ -46,6 +81,18 @@ func NotEmpty(i ap.Item) bool {
 			return false
 		}
 		return ap.NotEmpty(r.Actor)
+	case TicketType:
+		t, err := ToTicket(i)
+		if err != nil {
+			return false
+		}
+		return ap.NotEmpty(&t.Object)
+	case CommitType:
+		c, err := ToCommit(i)
+		if err != nil {
+			return false
+		}
+		return ap.NotEmpty(&c.Object)
 	default:
 		return ap.NotEmpty(i)
 	}
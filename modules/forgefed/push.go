@@ -0,0 +1,104 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgefed
+
+import (
+	"time"
+
+	ap "github.com/go-ap/activitypub"
+	"github.com/valyala/fastjson"
+)
+
+const (
+	// PushType is the type of the activity announcing commits pushed to a branch of a repository,
+	// its object is an OrderedCollection of the commits, its context the repository and its target the branch
+	PushType ap.ActivityVocabularyType = "Push"
+	// BranchType is the type of the target of a Push activity
+	BranchType ap.ActivityVocabularyType = "Branch"
+	CommitType ap.ActivityVocabularyType = "Commit"
+)
+
+// Commit is a commit of a repository, its first message line is the summary and the full message the content
+type Commit struct {
+	ap.Object
+	// Hash is the SHA of the commit
+	Hash string `jsonld:"hash,omitempty"`
+	// Created is the time the commit was authored
+	Created time.Time `jsonld:"created,omitempty"`
+}
+
+// CommitNew initializes a Commit type object
+func CommitNew(id ap.ID) *Commit {
+	o := ap.ObjectNew(CommitType)
+	o.Type = CommitType
+	o.ID = id
+	return &Commit{Object: *o}
+}
+
+func (c Commit) MarshalJSON() ([]byte, error) {
+	b, err := c.Object.MarshalJSON()
+	if len(b) == 0 || err != nil {
+		return nil, err
+	}
+
+	b = b[:len(b)-1]
+	if c.Hash != "" {
+		ap.JSONWriteStringProp(&b, "hash", c.Hash)
+	}
+	if !c.Created.IsZero() {
+		ap.JSONWriteTimeProp(&b, "created", c.Created)
+	}
+	ap.JSONWrite(&b, '}')
+	return b, nil
+}
+
+func JSONLoadCommit(val *fastjson.Value, c *Commit) error {
+	if err := ap.OnObject(&c.Object, func(o *ap.Object) error {
+		return ap.JSONLoadObject(val, o)
+	}); err != nil {
+		return err
+	}
+
+	c.Hash = ap.JSONGetString(val, "hash")
+	c.Created = ap.JSONGetTime(val, "created")
+	return nil
+}
+
+func (c *Commit) UnmarshalJSON(data []byte) error {
+	p := fastjson.Parser{}
+	val, err := p.ParseBytes(data)
+	if err != nil {
+		return err
+	}
+	return JSONLoadCommit(val, c)
+}
+
+// ToCommit tries to convert the it Item to a Commit.
+func ToCommit(it ap.Item) (*Commit, error) {
+	switch i := it.(type) {
+	case *Commit:
+		return i, nil
+	case Commit:
+		return &i, nil
+	case *ap.Object:
+		return &Commit{Object: *i}, nil
+	case ap.Object:
+		return &Commit{Object: i}, nil
+	}
+	return nil, ap.ErrorInvalidType[ap.Object](it)
+}
+
+type withCommitFn func(*Commit) error
+
+// OnCommit calls function fn on it Item if it can be asserted to type *Commit
+func OnCommit(it ap.Item, fn withCommitFn) error {
+	if it == nil {
+		return nil
+	}
+	ob, err := ToCommit(it)
+	if err != nil {
+		return err
+	}
+	return fn(ob)
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgefed
+
+import (
+	"testing"
+	"time"
+
+	ap "github.com/go-ap/activitypub"
+	"github.com/go-ap/jsonld"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CommitMarshalJSON(t *testing.T) {
+	tests := map[string]struct {
+		item Commit
+		want string
+	}{
+		"with ID": {
+			item: Commit{Object: ap.Object{ID: "https://example.com/1", Type: CommitType}},
+			want: `{"id":"https://example.com/1","type":"Commit"}`,
+		},
+		"with hash": {
+			item: Commit{
+				Object:  ap.Object{ID: "https://example.com/1", Type: CommitType},
+				Hash:    "65f1bf27bc3bf70f64657658635e66094edbcb4d",
+				Created: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			},
+			want: `{"id":"https://example.com/1","type":"Commit","hash":"65f1bf27bc3bf70f64657658635e66094edbcb4d","created":"2024-05-01T12:00:00Z"}`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := tt.item.MarshalJSON()
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
+
+func Test_CommitUnmarshalJSON(t *testing.T) {
+	got := new(Commit)
+	require.NoError(t, got.UnmarshalJSON([]byte(`{"id":"https://example.com/1","type":"Commit","summary":"Fix","hash":"65f1bf27","created":"2024-05-01T12:00:00Z"}`)))
+	assert.Equal(t, ap.IRI("https://example.com/1"), got.ID)
+	assert.Equal(t, CommitType, got.Type)
+	assert.Equal(t, "Fix", got.Summary.String())
+	assert.Equal(t, "65f1bf27", got.Hash)
+	assert.Equal(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), got.Created.UTC())
+}
+
+func Test_PushWithCommits(t *testing.T) {
+	commit := CommitNew("https://example.com/user1/repo1/commit/65f1bf27")
+	commit.Hash = "65f1bf27"
+	commit.Summary = ap.DefaultNaturalLanguageValue("Fix")
+	commits := ap.OrderedCollectionNew("")
+	commits.OrderedItems = ap.ItemCollection{commit}
+	commits.TotalItems = 1
+
+	push := ap.ActivityNew("https://example.com/api/v1/activitypub/user-id/1/outbox/1", PushType, commits)
+	push.Type = PushType
+	push.Actor = ap.IRI("https://example.com/api/v1/activitypub/user-id/1")
+	push.Context = ap.IRI("https://example.com/api/v1/activitypub/repository-id/1")
+	branch := ap.ObjectNew(BranchType)
+	branch.Type = BranchType
+	branch.Name = ap.DefaultNaturalLanguageValue("main")
+	push.Target = branch
+
+	b, err := jsonld.WithContext(jsonld.IRI(ap.ActivityBaseURI)).Marshal(push)
+	require.NoError(t, err)
+
+	item, err := ap.UnmarshalJSON(b)
+	require.NoError(t, err)
+	got, err := ap.ToActivity(item)
+	require.NoError(t, err)
+	assert.Equal(t, PushType, got.Type)
+	assert.Equal(t, "https://example.com/api/v1/activitypub/repository-id/1", got.Context.GetID().String())
+	target, err := ap.ToObject(got.Target)
+	require.NoError(t, err)
+	assert.Equal(t, BranchType, target.Type)
+	assert.Equal(t, "main", target.Name.String())
+
+	collection, err := ap.ToOrderedCollection(got.Object)
+	require.NoError(t, err)
+	require.Len(t, collection.OrderedItems, 1)
+	gotCommit, err := ToCommit(collection.OrderedItems[0])
+	require.NoError(t, err)
+	assert.Equal(t, CommitType, gotCommit.Type)
+	assert.Equal(t, "65f1bf27", gotCommit.Hash)
+	assert.Equal(t, "Fix", gotCommit.Summary.String())
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgefed
+
+import (
+	"testing"
+
+	ap "github.com/go-ap/activitypub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_TicketMarshalJSON(t *testing.T) {
+	tests := map[string]struct {
+		item Ticket
+		want string
+	}{
+		"with ID": {
+			item: Ticket{Object: ap.Object{ID: "https://example.com/1", Type: TicketType}},
+			want: `{"id":"https://example.com/1","type":"Ticket"}`,
+		},
+		"resolved": {
+			item: Ticket{Object: ap.Object{ID: "https://example.com/1", Type: TicketType}, IsResolved: true},
+			want: `{"id":"https://example.com/1","type":"Ticket","isResolved":true}`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := tt.item.MarshalJSON()
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
+
+func Test_TicketUnmarshalJSON(t *testing.T) {
+	got := new(Ticket)
+	require.NoError(t, got.UnmarshalJSON([]byte(`{"id":"https://example.com/1","type":"Ticket","summary":"Title","isResolved":true}`)))
+	assert.Equal(t, ap.IRI("https://example.com/1"), got.ID)
+	assert.Equal(t, TicketType, got.Type)
+	assert.Equal(t, "Title", got.Summary.String())
+	assert.True(t, got.IsResolved)
+}
+
+func Test_OfferWithTicketUnmarshalJSON(t *testing.T) {
+	item, err := ap.UnmarshalJSON([]byte(`{"type":"Offer","actor":"https://example.com/api/v1/activitypub/user-id/1",` +
+		`"object":{"type":"Ticket","attributedTo":"https://example.com/api/v1/activitypub/user-id/1","summary":"Title","content":"Description"},` +
+		`"target":"https://example.org/api/v1/activitypub/repository-id/1"}`))
+	require.NoError(t, err)
+	offer, err := ap.ToActivity(item)
+	require.NoError(t, err)
+	assert.Equal(t, ap.OfferType, offer.Type)
+
+	ticket, err := ToTicket(offer.Object)
+	require.NoError(t, err)
+	assert.Equal(t, TicketType, ticket.Type)
+	assert.Equal(t, "Title", ticket.Summary.String())
+	assert.Equal(t, "Description", ticket.Content.String())
+	assert.Equal(t, "https://example.com/api/v1/activitypub/user-id/1", ticket.AttributedTo.GetID().String())
+}
+
+func Test_TicketNew(t *testing.T) {
+	ticket := TicketNew("https://example.com/1")
+	assert.Equal(t, TicketType, ticket.Type)
+	assert.Equal(t, ap.IRI("https://example.com/1"), ticket.ID)
+}
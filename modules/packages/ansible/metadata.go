@@ -0,0 +1,191 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package ansible
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/validation"
+
+	"github.com/hashicorp/go-version"
+)
+
+var (
+	ErrInvalidArchive      = util.NewInvalidArgumentErrorf("collection archive is invalid")
+	ErrMissingManifestFile = util.NewInvalidArgumentErrorf("MANIFEST.json file is missing")
+	ErrInvalidName         = util.NewInvalidArgumentErrorf("collection namespace or name is invalid")
+	ErrInvalidVersion      = util.NewInvalidArgumentErrorf("collection version is invalid")
+	ErrInvalidFilename     = util.NewInvalidArgumentErrorf("collection filename is invalid")
+)
+
+const (
+	manifestFile  = "MANIFEST.json"
+	maxReadmeSize = 1 << 20
+)
+
+// https://docs.ansible.com/ansible/latest/dev_guide/collections_galaxy_meta.html
+var namePattern = regexp.MustCompile(`\A[a-z][0-9a-z_]{1,63}\z`)
+
+// Package represents an Ansible collection
+type Package struct {
+	Namespace string
+	Name      string
+	Version   string
+	Metadata  *Metadata
+}
+
+// Metadata represents the metadata of an Ansible collection
+type Metadata struct {
+	Description      string            `json:"description,omitempty"`
+	Readme           string            `json:"readme,omitempty"`
+	Authors          []string          `json:"authors,omitempty"`
+	License          []string          `json:"license,omitempty"`
+	Tags             []string          `json:"tags,omitempty"`
+	Dependencies     map[string]string `json:"dependencies,omitempty"`
+	RepositoryURL    string            `json:"repository_url,omitempty"`
+	DocumentationURL string            `json:"documentation_url,omitempty"`
+	HomepageURL      string            `json:"homepage_url,omitempty"`
+	IssuesURL        string            `json:"issues_url,omitempty"`
+}
+
+type manifest struct {
+	CollectionInfo struct {
+		Namespace     string            `json:"namespace"`
+		Name          string            `json:"name"`
+		Version       string            `json:"version"`
+		Authors       []string          `json:"authors"`
+		Readme        string            `json:"readme"`
+		Tags          []string          `json:"tags"`
+		Description   string            `json:"description"`
+		License       []string          `json:"license"`
+		Dependencies  map[string]string `json:"dependencies"`
+		Repository    string            `json:"repository"`
+		Documentation string            `json:"documentation"`
+		Homepage      string            `json:"homepage"`
+		Issues        string            `json:"issues"`
+	} `json:"collection_info"`
+}
+
+// IsValidName checks if the name is a valid collection namespace or name
+func IsValidName(name string) bool {
+	return namePattern.MatchString(name) && !strings.Contains(name, "__")
+}
+
+// IsValidVersion checks if the version is a valid semantic version
+func IsValidVersion(v string) bool {
+	_, err := version.NewSemver(v)
+	return err == nil && strings.Count(v, ".") >= 2
+}
+
+// PackageName returns the fully qualified collection name which is used as package name
+func PackageName(namespace, name string) string {
+	return namespace + "." + name
+}
+
+// Filename returns the filename of the collection artifact
+func Filename(namespace, name, version string) string {
+	return namespace + "-" + name + "-" + version + ".tar.gz"
+}
+
+// ParseFilename extracts the namespace, name and version from the filename of a collection artifact
+func ParseFilename(filename string) (string, string, string, error) {
+	base, ok := strings.CutSuffix(filename, ".tar.gz")
+	if !ok {
+		return "", "", "", ErrInvalidFilename
+	}
+
+	parts := strings.SplitN(base, "-", 3)
+	if len(parts) != 3 || !IsValidName(parts[0]) || !IsValidName(parts[1]) || !IsValidVersion(parts[2]) {
+		return "", "", "", ErrInvalidFilename
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// ParsePackage parses the collection artifact created by "ansible-galaxy collection build"
+func ParsePackage(r io.Reader) (*Package, error) {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, ErrInvalidArchive
+	}
+	defer gzr.Close()
+
+	var m *manifest
+	files := make(map[string]string)
+
+	tr := tar.NewReader(gzr)
+	for {
+		hd, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidArchive
+		}
+
+		if hd.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(strings.TrimPrefix(hd.Name, "./"))
+		if name == manifestFile {
+			if err := json.NewDecoder(io.LimitReader(tr, maxReadmeSize)).Decode(&m); err != nil {
+				return nil, ErrInvalidArchive
+			}
+		} else if strings.HasSuffix(strings.ToLower(name), ".md") && !strings.Contains(name, "/") && hd.Size <= maxReadmeSize {
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			files[name] = string(data)
+		}
+	}
+
+	if m == nil {
+		return nil, ErrMissingManifestFile
+	}
+
+	ci := m.CollectionInfo
+	if !IsValidName(ci.Namespace) || !IsValidName(ci.Name) {
+		return nil, ErrInvalidName
+	}
+	if !IsValidVersion(ci.Version) {
+		return nil, ErrInvalidVersion
+	}
+
+	metadata := &Metadata{
+		Description:      ci.Description,
+		Authors:          ci.Authors,
+		License:          ci.License,
+		Tags:             ci.Tags,
+		Dependencies:     ci.Dependencies,
+		RepositoryURL:    validURL(ci.Repository),
+		DocumentationURL: validURL(ci.Documentation),
+		HomepageURL:      validURL(ci.Homepage),
+		IssuesURL:        validURL(ci.Issues),
+	}
+	if ci.Readme != "" {
+		metadata.Readme = files[path.Clean(ci.Readme)]
+	}
+
+	return &Package{
+		Namespace: ci.Namespace,
+		Name:      ci.Name,
+		Version:   ci.Version,
+		Metadata:  metadata,
+	}, nil
+}
+
+func validURL(s string) string {
+	if validation.IsValidURL(s) {
+		return s
+	}
+	return ""
+}
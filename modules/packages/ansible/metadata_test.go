@@ -0,0 +1,104 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package ansible
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	collectionNamespace = "my_namespace"
+	collectionName      = "my_collection"
+	collectionVersion   = "1.0.0"
+	description         = "Test collection"
+	readme              = "# My collection"
+)
+
+func createArchive(files map[string]string) *bytes.Buffer {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for name, content := range files {
+		hdr := &tar.Header{
+			Name: name,
+			Mode: 0o600,
+			Size: int64(len(content)),
+		}
+		tw.WriteHeader(hdr)
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	zw.Close()
+	return &buf
+}
+
+func createManifest(namespace, name, version string) string {
+	return `{"collection_info":{"namespace":"` + namespace + `","name":"` + name + `","version":"` + version + `","authors":["Forgejo"],"readme":"README.md","description":"` + description + `","license":["MIT"],"dependencies":{"community.general":">=1.0.0"},"repository":"https://example.com/repo","issues":"invalid"},"format":1}`
+}
+
+func TestParsePackage(t *testing.T) {
+	t.Run("InvalidArchive", func(t *testing.T) {
+		p, err := ParsePackage(strings.NewReader("invalid"))
+		assert.Nil(t, p)
+		assert.ErrorIs(t, err, ErrInvalidArchive)
+	})
+
+	t.Run("MissingManifestFile", func(t *testing.T) {
+		p, err := ParsePackage(createArchive(map[string]string{"README.md": readme}))
+		assert.Nil(t, p)
+		assert.ErrorIs(t, err, ErrMissingManifestFile)
+	})
+
+	t.Run("InvalidName", func(t *testing.T) {
+		p, err := ParsePackage(createArchive(map[string]string{"MANIFEST.json": createManifest("My-Namespace", collectionName, collectionVersion)}))
+		assert.Nil(t, p)
+		assert.ErrorIs(t, err, ErrInvalidName)
+	})
+
+	t.Run("InvalidVersion", func(t *testing.T) {
+		p, err := ParsePackage(createArchive(map[string]string{"MANIFEST.json": createManifest(collectionNamespace, collectionName, "1.0")}))
+		assert.Nil(t, p)
+		assert.ErrorIs(t, err, ErrInvalidVersion)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		p, err := ParsePackage(createArchive(map[string]string{
+			"MANIFEST.json": createManifest(collectionNamespace, collectionName, collectionVersion),
+			"README.md":     readme,
+		}))
+		require.NoError(t, err)
+		require.NotNil(t, p)
+
+		assert.Equal(t, collectionNamespace, p.Namespace)
+		assert.Equal(t, collectionName, p.Name)
+		assert.Equal(t, collectionVersion, p.Version)
+		assert.Equal(t, description, p.Metadata.Description)
+		assert.Equal(t, readme, p.Metadata.Readme)
+		assert.Equal(t, []string{"Forgejo"}, p.Metadata.Authors)
+		assert.Equal(t, []string{"MIT"}, p.Metadata.License)
+		assert.Equal(t, map[string]string{"community.general": ">=1.0.0"}, p.Metadata.Dependencies)
+		assert.Equal(t, "https://example.com/repo", p.Metadata.RepositoryURL)
+		assert.Empty(t, p.Metadata.IssuesURL)
+	})
+}
+
+func TestParseFilename(t *testing.T) {
+	ns, n, v, err := ParseFilename(Filename(collectionNamespace, collectionName, "1.0.0-beta.1"))
+	require.NoError(t, err)
+	assert.Equal(t, collectionNamespace, ns)
+	assert.Equal(t, collectionName, n)
+	assert.Equal(t, "1.0.0-beta.1", v)
+
+	for _, filename := range []string{"", "my_namespace-my_collection-1.0.0.zip", "my_namespace-1.0.0.tar.gz", "My-Collection-1.0.0.tar.gz"} {
+		_, _, _, err := ParseFilename(filename)
+		assert.ErrorIs(t, err, ErrInvalidFilename, filename)
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package attestation
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"strings"
+
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/util"
+)
+
+var (
+	ErrInvalidEnvelope  = util.NewInvalidArgumentErrorf("attestation envelope is invalid")
+	ErrInvalidStatement = util.NewInvalidArgumentErrorf("attestation statement is invalid")
+	ErrInvalidKey       = util.NewInvalidArgumentErrorf("public key is invalid")
+)
+
+const (
+	// PayloadType is the DSSE payload type of in-toto statements
+	PayloadType = "application/vnd.in-toto+json"
+
+	StatementTypeV1  = "https://in-toto.io/Statement/v1"
+	StatementTypeV01 = "https://in-toto.io/Statement/v0.1"
+
+	PredicateTypeSLSAProvenanceV1 = "https://slsa.dev/provenance/v1"
+
+	SettingKeyPrivate = "attestation.key.private"
+	SettingKeyPublic  = "attestation.key.public"
+
+	maxEnvelopeSize = 4 << 20
+)
+
+// Envelope is a DSSE envelope
+// https://github.com/secure-systems-lab/dsse/blob/master/envelope.md
+type Envelope struct {
+	PayloadType string       `json:"payloadType"`
+	Payload     string       `json:"payload"`
+	Signatures  []*Signature `json:"signatures"`
+}
+
+// Signature is a signature of a DSSE envelope
+type Signature struct {
+	KeyID string `json:"keyid,omitempty"`
+	Sig   string `json:"sig"`
+}
+
+// Subject is an artifact an in-toto statement is about
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Statement is an in-toto attestation statement
+// https://github.com/in-toto/attestation/blob/main/spec/v1/statement.md
+type Statement struct {
+	Type          string     `json:"_type"`
+	Subject       []*Subject `json:"subject"`
+	PredicateType string     `json:"predicateType"`
+	Predicate     any        `json:"predicate,omitempty"`
+}
+
+// HasSubjectDigest checks if the statement is about an artifact with the SHA256 digest
+func (s *Statement) HasSubjectDigest(sha256Digest string) bool {
+	for _, subject := range s.Subject {
+		if strings.EqualFold(subject.Digest["sha256"], sha256Digest) {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	if data, err := base64.StdEncoding.DecodeString(s); err == nil {
+		return data, nil
+	}
+	return base64.URLEncoding.DecodeString(s)
+}
+
+// ParseEnvelope parses a DSSE envelope containing an in-toto statement
+func ParseEnvelope(r io.Reader) (*Envelope, *Statement, error) {
+	var e Envelope
+	if err := json.NewDecoder(io.LimitReader(r, maxEnvelopeSize)).Decode(&e); err != nil {
+		return nil, nil, ErrInvalidEnvelope
+	}
+	if e.PayloadType != PayloadType || len(e.Signatures) == 0 {
+		return nil, nil, ErrInvalidEnvelope
+	}
+
+	payload, err := decodeBase64(e.Payload)
+	if err != nil {
+		return nil, nil, ErrInvalidEnvelope
+	}
+
+	var s Statement
+	if err := json.Unmarshal(payload, &s); err != nil {
+		return nil, nil, ErrInvalidStatement
+	}
+	if (s.Type != StatementTypeV1 && s.Type != StatementTypeV01) || len(s.Subject) == 0 || s.PredicateType == "" {
+		return nil, nil, ErrInvalidStatement
+	}
+
+	return &e, &s, nil
+}
+
+// PAE computes the pre-authentication encoding which is signed
+func PAE(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// ParsePublicKey parses a PEM encoded PKIX public key
+func ParsePublicKey(content string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(content)))
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, ErrInvalidKey
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	switch pub.(type) {
+	case *ecdsa.PublicKey, ed25519.PublicKey, *rsa.PublicKey:
+		return pub, nil
+	default:
+		return nil, ErrInvalidKey
+	}
+}
+
+// KeyFingerprint returns the hex encoded SHA256 hash of the DER encoded public key
+func KeyFingerprint(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(der)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// Verify checks if any signature of the envelope was created with the key
+func (e *Envelope) Verify(pub crypto.PublicKey) bool {
+	payload, err := decodeBase64(e.Payload)
+	if err != nil {
+		return false
+	}
+	message := PAE(e.PayloadType, payload)
+	digest := sha256.Sum256(message)
+
+	for _, s := range e.Signatures {
+		sig, err := decodeBase64(s.Sig)
+		if err != nil {
+			continue
+		}
+
+		switch k := pub.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, digest[:], sig) {
+				return true
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(k, message, sig) {
+				return true
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPSS(k, crypto.SHA256, digest[:], sig, nil) == nil || rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Sign creates an envelope for the statement signed by the ECDSA key
+func Sign(s *Statement, key *ecdsa.PrivateKey) (*Envelope, error) {
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(PAE(PayloadType, payload))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	keyID, err := KeyFingerprint(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Envelope{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []*Signature{
+			{
+				KeyID: keyID,
+				Sig:   base64.StdEncoding.EncodeToString(sig),
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package attestation
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/modules/json"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const digest = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+func createStatement() *Statement {
+	return &Statement{
+		Type: StatementTypeV1,
+		Subject: []*Subject{
+			{
+				Name:   "package.tgz",
+				Digest: map[string]string{"sha256": digest},
+			},
+		},
+		PredicateType: PredicateTypeSLSAProvenanceV1,
+		Predicate:     map[string]any{},
+	}
+}
+
+func marshalPublicKey(t *testing.T, pub any) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestSignAndVerify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	e, err := Sign(createStatement(), key)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, json.NewEncoder(&buf).Encode(e))
+
+	parsed, s, err := ParseEnvelope(&buf)
+	require.NoError(t, err)
+	assert.True(t, s.HasSubjectDigest(strings.ToUpper(digest)))
+	assert.False(t, s.HasSubjectDigest("abc"))
+
+	pub, err := ParsePublicKey(marshalPublicKey(t, &key.PublicKey))
+	require.NoError(t, err)
+	assert.True(t, parsed.Verify(pub))
+
+	pub, err = ParsePublicKey(marshalPublicKey(t, &other.PublicKey))
+	require.NoError(t, err)
+	assert.False(t, parsed.Verify(pub))
+}
+
+func TestVerifyEd25519(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	payload, _ := json.Marshal(createStatement())
+	e := &Envelope{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []*Signature{
+			{Sig: base64.StdEncoding.EncodeToString(ed25519.Sign(priv, PAE(PayloadType, payload)))},
+		},
+	}
+
+	parsed, err := ParsePublicKey(marshalPublicKey(t, pub))
+	require.NoError(t, err)
+	assert.True(t, e.Verify(parsed))
+}
+
+func TestParseEnvelope(t *testing.T) {
+	encode := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+
+	cases := []struct {
+		Content string
+		Err     error
+	}{
+		{`invalid`, ErrInvalidEnvelope},
+		{`{"payloadType":"text/plain","payload":"` + encode(`{}`) + `","signatures":[{"sig":"YQ=="}]}`, ErrInvalidEnvelope},
+		{`{"payloadType":"application/vnd.in-toto+json","payload":"` + encode(`{}`) + `","signatures":[]}`, ErrInvalidEnvelope},
+		{`{"payloadType":"application/vnd.in-toto+json","payload":"` + encode(`{}`) + `","signatures":[{"sig":"YQ=="}]}`, ErrInvalidStatement},
+		{`{"payloadType":"application/vnd.in-toto+json","payload":"` + encode(`{"_type":"https://in-toto.io/Statement/v1","subject":[],"predicateType":"x"}`) + `","signatures":[{"sig":"YQ=="}]}`, ErrInvalidStatement},
+	}
+
+	for _, c := range cases {
+		_, _, err := ParseEnvelope(strings.NewReader(c.Content))
+		assert.ErrorIs(t, err, c.Err, c.Content)
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	_, err := ParsePublicKey("invalid")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = ParsePublicKey("-----BEGIN PUBLIC KEY-----\nYQ==\n-----END PUBLIC KEY-----")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
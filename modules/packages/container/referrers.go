@@ -0,0 +1,31 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package container
+
+import (
+	"strings"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+// ReferrersTag returns the tag clients use to store the referrers of a digest
+// if the registry does not support the referrers API
+// https://github.com/opencontainers/distribution-spec/blob/main/spec.md#referrers-tag-schema
+func ReferrersTag(d digest.Digest) string {
+	alg := d.Algorithm().String()
+	if len(alg) > 32 {
+		alg = alg[:32]
+	}
+	ref := d.Encoded()
+	if len(ref) > 64 {
+		ref = ref[:64]
+	}
+	return alg + "-" + ref
+}
+
+// IsReferrersTag checks if the tag follows the referrers tag schema
+func IsReferrersTag(tag string) bool {
+	alg, ref, ok := strings.Cut(tag, "-")
+	return ok && digest.Digest(alg+":"+ref).Validate() == nil
+}
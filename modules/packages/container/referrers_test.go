@@ -0,0 +1,26 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package container
+
+import (
+	"strings"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferrersTag(t *testing.T) {
+	assert.Equal(t, "sha256-4f10484d1c1bb13e3956b4de1cd42db8e0f14a75be1617b60f2de3cd59c803c6", ReferrersTag("sha256:4f10484d1c1bb13e3956b4de1cd42db8e0f14a75be1617b60f2de3cd59c803c6"))
+
+	long := digest.Digest(strings.Repeat("a", 40) + ":" + strings.Repeat("b", 128))
+	assert.Equal(t, strings.Repeat("a", 32)+"-"+strings.Repeat("b", 64), ReferrersTag(long))
+}
+
+func TestIsReferrersTag(t *testing.T) {
+	assert.True(t, IsReferrersTag("sha256-4f10484d1c1bb13e3956b4de1cd42db8e0f14a75be1617b60f2de3cd59c803c6"))
+	assert.False(t, IsReferrersTag("sha256-invalid"))
+	assert.False(t, IsReferrersTag("v1.0.0"))
+	assert.False(t, IsReferrersTag("latest"))
+}
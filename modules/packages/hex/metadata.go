@@ -0,0 +1,331 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package hex
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/validation"
+)
+
+var (
+	ErrInvalidName     = util.NewInvalidArgumentErrorf("package name is invalid")
+	ErrInvalidVersion  = util.NewInvalidArgumentErrorf("package version is invalid")
+	ErrInvalidTarball  = util.NewInvalidArgumentErrorf("package tarball is invalid")
+	ErrInvalidChecksum = util.NewInvalidArgumentErrorf("package checksum is invalid")
+	ErrInvalidMetadata = util.NewInvalidArgumentErrorf("package metadata is invalid")
+)
+
+const (
+	PropertyInnerChecksum = "hex.checksum.inner"
+
+	SettingKeyPrivate = "hex.key.private"
+	SettingKeyPublic  = "hex.key.public"
+
+	// tarballVersion is the only supported version of the package tarball format
+	tarballVersion = "3"
+
+	maxMetadataSize = 128 * 1024
+)
+
+var (
+	namePattern = regexp.MustCompile(`\A[a-z][a-z0-9_]*\z`)
+	// https://semver.org/#is-there-a-suggested-regular-expression-regex-to-check-a-semver-string
+	versionPattern = regexp.MustCompile(`\A(?:0|[1-9]\d*)\.(?:0|[1-9]\d*)\.(?:0|[1-9]\d*)(?:-(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*)?(?:\+[0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*)?\z`)
+)
+
+// Package represents a Hex package
+type Package struct {
+	Name          string
+	Version       string
+	InnerChecksum string
+	Metadata      *Metadata
+}
+
+// Metadata represents the metadata of a Hex package
+type Metadata struct {
+	App          string            `json:"app,omitempty"`
+	Description  string            `json:"description,omitempty"`
+	Licenses     []string          `json:"licenses,omitempty"`
+	Links        map[string]string `json:"links,omitempty"`
+	BuildTools   []string          `json:"build_tools,omitempty"`
+	Elixir       string            `json:"elixir,omitempty"`
+	Requirements []*Requirement    `json:"requirements,omitempty"`
+}
+
+// Requirement represents a dependency of a Hex package
+type Requirement struct {
+	Name        string `json:"name"`
+	App         string `json:"app,omitempty"`
+	Requirement string `json:"requirement"`
+	Optional    bool   `json:"optional,omitempty"`
+	Repository  string `json:"repository,omitempty"`
+}
+
+// IsValidName checks if the name is a valid package name
+func IsValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// IsValidVersion checks if the version is a valid semantic version
+func IsValidVersion(v string) bool {
+	return versionPattern.MatchString(v)
+}
+
+// TarballFilename returns the filename of the package tarball
+func TarballFilename(name, version string) string {
+	return name + "-" + version + ".tar"
+}
+
+// ParsePackage parses the package tarball created by "mix hex.build"
+// https://github.com/hexpm/specifications/blob/main/package_tarball.md
+func ParsePackage(r io.ReadSeeker) (*Package, error) {
+	files := map[string][]byte{
+		"VERSION":         nil,
+		"CHECKSUM":        nil,
+		"metadata.config": nil,
+	}
+
+	hasContents := false
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err := walkTarball(r, func(name string, tr io.Reader) error {
+		if _, ok := files[name]; ok {
+			data, err := io.ReadAll(io.LimitReader(tr, maxMetadataSize+1))
+			if err != nil {
+				return err
+			}
+			if len(data) > maxMetadataSize {
+				return ErrInvalidTarball
+			}
+			files[name] = data
+		} else if name == "contents.tar.gz" {
+			hasContents = true
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(string(files["VERSION"])) != tarballVersion || files["metadata.config"] == nil || !hasContents {
+		return nil, ErrInvalidTarball
+	}
+
+	// The inner checksum covers the content of VERSION, metadata.config and contents.tar.gz
+	h := sha256.New()
+	h.Write(files["VERSION"])
+	h.Write(files["metadata.config"])
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err := walkTarball(r, func(name string, tr io.Reader) error {
+		if name == "contents.tar.gz" {
+			_, err := io.Copy(h, tr)
+			return err
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	innerChecksum := hex.EncodeToString(h.Sum(nil))
+
+	if checksum := strings.TrimSpace(string(files["CHECKSUM"])); checksum != "" && !strings.EqualFold(checksum, innerChecksum) {
+		return nil, ErrInvalidChecksum
+	}
+
+	p, err := parseMetadataConfig(files["metadata.config"])
+	if err != nil {
+		return nil, err
+	}
+	p.InnerChecksum = innerChecksum
+	return p, nil
+}
+
+func walkTarball(r io.Reader, fn func(name string, r io.Reader) error) error {
+	tr := tar.NewReader(r)
+	for {
+		hd, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return ErrInvalidTarball
+		}
+
+		if hd.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if err := fn(hd.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func parseMetadataConfig(data []byte) (*Package, error) {
+	terms, err := parseTerms(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+	}
+
+	p := &Package{
+		Metadata: &Metadata{},
+	}
+
+	for _, term := range terms {
+		kv, ok := term.(Tuple)
+		if !ok || len(kv) != 2 {
+			return nil, ErrInvalidMetadata
+		}
+		key, ok := kv[0].(string)
+		if !ok {
+			return nil, ErrInvalidMetadata
+		}
+
+		switch key {
+		case "name":
+			p.Name, _ = kv[1].(string)
+		case "version":
+			p.Version, _ = kv[1].(string)
+		case "app":
+			p.Metadata.App, _ = kv[1].(string)
+		case "description":
+			p.Metadata.Description, _ = kv[1].(string)
+		case "elixir":
+			p.Metadata.Elixir, _ = kv[1].(string)
+		case "licenses":
+			p.Metadata.Licenses = toStrings(kv[1])
+		case "build_tools":
+			p.Metadata.BuildTools = toStrings(kv[1])
+		case "links":
+			p.Metadata.Links = parseLinks(kv[1])
+		case "requirements":
+			p.Metadata.Requirements, err = parseRequirements(kv[1])
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if !IsValidName(p.Name) {
+		return nil, ErrInvalidName
+	}
+	if !IsValidVersion(p.Version) {
+		return nil, ErrInvalidVersion
+	}
+	if p.Metadata.App == "" {
+		p.Metadata.App = p.Name
+	}
+
+	return p, nil
+}
+
+func toStrings(v any) []string {
+	list, _ := v.([]any)
+
+	var result []string
+	for _, e := range list {
+		if s, ok := e.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// proplist converts a list of {Key, Value} tuples into a map
+func proplist(v any) (map[string]any, bool) {
+	list, ok := v.([]any)
+	if !ok {
+		return nil, false
+	}
+
+	m := make(map[string]any, len(list))
+	for _, e := range list {
+		kv, ok := e.(Tuple)
+		if !ok || len(kv) != 2 {
+			return nil, false
+		}
+		k, ok := kv[0].(string)
+		if !ok {
+			return nil, false
+		}
+		m[k] = kv[1]
+	}
+	return m, true
+}
+
+func parseLinks(v any) map[string]string {
+	m, ok := proplist(v)
+	if !ok {
+		return nil
+	}
+
+	links := make(map[string]string, len(m))
+	for name, link := range m {
+		if s, ok := link.(string); ok && validation.IsValidURL(s) {
+			links[name] = s
+		}
+	}
+	return links
+}
+
+// parseRequirements supports the list of requirement proplists and the older format keyed by the package name
+func parseRequirements(v any) ([]*Requirement, error) {
+	list, ok := v.([]any)
+	if !ok {
+		return nil, ErrInvalidMetadata
+	}
+
+	requirements := make([]*Requirement, 0, len(list))
+	for _, e := range list {
+		var name string
+		var props map[string]any
+
+		if kv, ok := e.(Tuple); ok && len(kv) == 2 {
+			name, _ = kv[0].(string)
+			props, ok = proplist(kv[1])
+			if !ok {
+				return nil, ErrInvalidMetadata
+			}
+		} else {
+			props, ok = proplist(e)
+			if !ok {
+				return nil, ErrInvalidMetadata
+			}
+			name, _ = props["name"].(string)
+		}
+
+		if !IsValidName(name) {
+			return nil, ErrInvalidMetadata
+		}
+
+		r := &Requirement{
+			Name: name,
+		}
+		r.App, _ = props["app"].(string)
+		r.Requirement, _ = props["requirement"].(string)
+		r.Optional = props["optional"] == Atom("true")
+		r.Repository, _ = props["repository"].(string)
+		if r.App == "" {
+			r.App = name
+		}
+
+		requirements = append(requirements, r)
+	}
+
+	sort.Slice(requirements, func(i, j int) bool {
+		return requirements[i].Name < requirements[j].Name
+	})
+
+	return requirements, nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package hex
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	packageName    = "my_lib"
+	packageVersion = "1.2.3"
+	metadataConfig = `{<<"name">>,<<"my_lib">>}.
+{<<"version">>,<<"1.2.3">>}.
+{<<"description">>,<<"A library with ümlauts"/utf8>>}.
+{<<"licenses">>,[<<"MIT">>]}.
+{<<"links">>,[{<<"GitHub">>,<<"https://example.com/my_lib">>},{<<"Invalid">>,<<"invalid">>}]}.
+{<<"build_tools">>,[<<"mix">>]}.
+{<<"elixir">>,<<"~> 1.15">>}.
+{<<"files">>,[<<"lib">>,<<"mix.exs">>]}.
+{<<"requirements">>,
+ [[{<<"name">>,<<"jason">>},
+   {<<"app">>,<<"jason">>},
+   {<<"optional">>,false},
+   {<<"requirement">>,<<"~> 1.4">>},
+   {<<"repository">>,<<"hexpm">>}],
+  [{<<"name">>,<<"decimal">>},
+   {<<"app">>,<<"decimal">>},
+   {<<"optional">>,true},
+   {<<"requirement">>,<<"~> 2.0">>},
+   {<<"repository">>,<<"hexpm">>}]]}.
+`
+)
+
+func createTarball(metadata, checksum string) *bytes.Reader {
+	contents := "contents"
+
+	if checksum == "" {
+		sum := sha256.Sum256([]byte(tarballVersion + metadata + contents))
+		checksum = strings.ToUpper(hex.EncodeToString(sum[:]))
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range []struct{ Name, Content string }{
+		{"VERSION", tarballVersion},
+		{"CHECKSUM", checksum},
+		{"metadata.config", metadata},
+		{"contents.tar.gz", contents},
+	} {
+		tw.WriteHeader(&tar.Header{
+			Name: f.Name,
+			Mode: 0o600,
+			Size: int64(len(f.Content)),
+		})
+		tw.Write([]byte(f.Content))
+	}
+	tw.Close()
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestParsePackage(t *testing.T) {
+	t.Run("InvalidTarball", func(t *testing.T) {
+		p, err := ParsePackage(strings.NewReader("invalid"))
+		assert.Nil(t, p)
+		assert.ErrorIs(t, err, ErrInvalidTarball)
+	})
+
+	t.Run("InvalidChecksum", func(t *testing.T) {
+		p, err := ParsePackage(createTarball(metadataConfig, "ABC"))
+		assert.Nil(t, p)
+		assert.ErrorIs(t, err, ErrInvalidChecksum)
+	})
+
+	t.Run("InvalidName", func(t *testing.T) {
+		p, err := ParsePackage(createTarball(`{<<"name">>,<<"My-Lib">>}.
+{<<"version">>,<<"1.2.3">>}.`, ""))
+		assert.Nil(t, p)
+		assert.ErrorIs(t, err, ErrInvalidName)
+	})
+
+	t.Run("InvalidVersion", func(t *testing.T) {
+		p, err := ParsePackage(createTarball(`{<<"name">>,<<"my_lib">>}.
+{<<"version">>,<<"1.2">>}.`, ""))
+		assert.Nil(t, p)
+		assert.ErrorIs(t, err, ErrInvalidVersion)
+	})
+
+	t.Run("InvalidMetadata", func(t *testing.T) {
+		p, err := ParsePackage(createTarball(`{<<"name">>,<<"my_lib">>`, ""))
+		assert.Nil(t, p)
+		assert.ErrorIs(t, err, ErrInvalidMetadata)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		p, err := ParsePackage(createTarball(metadataConfig, ""))
+		require.NoError(t, err)
+		require.NotNil(t, p)
+
+		sum := sha256.Sum256([]byte(tarballVersion + metadataConfig + "contents"))
+
+		assert.Equal(t, packageName, p.Name)
+		assert.Equal(t, packageVersion, p.Version)
+		assert.Equal(t, hex.EncodeToString(sum[:]), p.InnerChecksum)
+		assert.Equal(t, packageName, p.Metadata.App)
+		assert.Equal(t, "A library with ümlauts", p.Metadata.Description)
+		assert.Equal(t, []string{"MIT"}, p.Metadata.Licenses)
+		assert.Equal(t, map[string]string{"GitHub": "https://example.com/my_lib"}, p.Metadata.Links)
+		assert.Equal(t, []string{"mix"}, p.Metadata.BuildTools)
+		assert.Equal(t, "~> 1.15", p.Metadata.Elixir)
+		assert.Equal(t, []*Requirement{
+			{Name: "decimal", App: "decimal", Requirement: "~> 2.0", Optional: true, Repository: "hexpm"},
+			{Name: "jason", App: "jason", Requirement: "~> 1.4", Repository: "hexpm"},
+		}, p.Metadata.Requirements)
+	})
+
+	t.Run("LegacyRequirements", func(t *testing.T) {
+		p, err := ParsePackage(createTarball(`{<<"name">>,<<"my_lib">>}.
+{<<"version">>,<<"1.2.3">>}.
+{<<"requirements">>,[{<<"jason">>,[{<<"app">>,<<"json">>},{<<"optional">>,false},{<<"requirement">>,<<"~> 1.4">>}]}]}.`, ""))
+		require.NoError(t, err)
+		assert.Equal(t, []*Requirement{
+			{Name: "jason", App: "json", Requirement: "~> 1.4"},
+		}, p.Metadata.Requirements)
+	})
+}
+
+func TestParseTerms(t *testing.T) {
+	terms, err := parseTerms([]byte(`% comment
+{'quoted atom', [1, -2, "str\"ing"], <<>>, ok}.`))
+	require.NoError(t, err)
+	assert.Equal(t, []any{
+		Tuple{Atom("quoted atom"), []any{int64(1), int64(-2), `str"ing`}, "", Atom("ok")},
+	}, terms)
+
+	_, err = parseTerms([]byte(`{a, b}`))
+	assert.Error(t, err)
+}
+
+func TestMarshalTerm(t *testing.T) {
+	b, err := MarshalTerm(map[string]any{
+		"a": "b",
+		"c": []any{1, true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{
+		131,
+		116, 0, 0, 0, 2,
+		109, 0, 0, 0, 1, 'a', 109, 0, 0, 0, 1, 'b',
+		109, 0, 0, 0, 1, 'c', 108, 0, 0, 0, 2, 97, 1, 119, 4, 't', 'r', 'u', 'e', 106,
+	}, b)
+
+	_, err = MarshalTerm(1.5)
+	assert.Error(t, err)
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package hex
+
+import (
+	"bytes"
+	"compress/gzip"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The registry resources are protocol buffer messages wrapped in a signed message and gzipped.
+// https://github.com/hexpm/specifications/blob/main/registry-v2.md
+
+// NamesPackage is an entry of the /names resource
+type NamesPackage struct {
+	Name      string
+	UpdatedAt time.Time
+}
+
+// VersionsPackage is an entry of the /versions resource
+type VersionsPackage struct {
+	Name     string
+	Versions []string
+}
+
+// Release is a version entry of the /packages/{name} resource
+type Release struct {
+	Version       string
+	InnerChecksum []byte
+	OuterChecksum []byte
+	Dependencies  []*Requirement
+}
+
+// EncodeNames encodes the Names message
+func EncodeNames(repository string, packages []*NamesPackage) []byte {
+	var b []byte
+	for _, p := range packages {
+		var pb []byte
+		pb = protowire.AppendTag(pb, 1, protowire.BytesType)
+		pb = protowire.AppendString(pb, p.Name)
+
+		var ts []byte
+		ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(p.UpdatedAt.Unix()))
+		ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(p.UpdatedAt.Nanosecond()))
+		pb = protowire.AppendTag(pb, 2, protowire.BytesType)
+		pb = protowire.AppendBytes(pb, ts)
+
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, pb)
+	}
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	return protowire.AppendString(b, repository)
+}
+
+// EncodeVersions encodes the Versions message
+func EncodeVersions(repository string, packages []*VersionsPackage) []byte {
+	var b []byte
+	for _, p := range packages {
+		var pb []byte
+		pb = protowire.AppendTag(pb, 1, protowire.BytesType)
+		pb = protowire.AppendString(pb, p.Name)
+		for _, v := range p.Versions {
+			pb = protowire.AppendTag(pb, 2, protowire.BytesType)
+			pb = protowire.AppendString(pb, v)
+		}
+
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, pb)
+	}
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	return protowire.AppendString(b, repository)
+}
+
+// EncodePackage encodes the Package message with all releases of a package
+func EncodePackage(repository, name string, releases []*Release) []byte {
+	var b []byte
+	for _, r := range releases {
+		var rb []byte
+		rb = protowire.AppendTag(rb, 1, protowire.BytesType)
+		rb = protowire.AppendString(rb, r.Version)
+		rb = protowire.AppendTag(rb, 2, protowire.BytesType)
+		rb = protowire.AppendBytes(rb, r.InnerChecksum)
+		for _, d := range r.Dependencies {
+			var db []byte
+			db = protowire.AppendTag(db, 1, protowire.BytesType)
+			db = protowire.AppendString(db, d.Name)
+			db = protowire.AppendTag(db, 2, protowire.BytesType)
+			db = protowire.AppendString(db, d.Requirement)
+			if d.Optional {
+				db = protowire.AppendTag(db, 3, protowire.VarintType)
+				db = protowire.AppendVarint(db, protowire.EncodeBool(true))
+			}
+			if d.App != "" && d.App != d.Name {
+				db = protowire.AppendTag(db, 4, protowire.BytesType)
+				db = protowire.AppendString(db, d.App)
+			}
+			if d.Repository != "" {
+				db = protowire.AppendTag(db, 5, protowire.BytesType)
+				db = protowire.AppendString(db, d.Repository)
+			}
+
+			rb = protowire.AppendTag(rb, 3, protowire.BytesType)
+			rb = protowire.AppendBytes(rb, db)
+		}
+		if len(r.OuterChecksum) > 0 {
+			rb = protowire.AppendTag(rb, 5, protowire.BytesType)
+			rb = protowire.AppendBytes(rb, r.OuterChecksum)
+		}
+
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, rb)
+	}
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendString(b, name)
+	b = protowire.AppendTag(b, 3, protowire.BytesType)
+	return protowire.AppendString(b, repository)
+}
+
+// EncodeSigned wraps the payload and its signature in the Signed message and gzips it
+func EncodeSigned(payload, signature []byte) ([]byte, error) {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendBytes(b, payload)
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendBytes(b, signature)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,339 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package hex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var errInvalidTerm = errors.New("invalid term")
+
+// Atom is an Erlang atom like true or false
+type Atom string
+
+// Tuple is an Erlang tuple
+type Tuple []any
+
+// parseTerms parses the "consult" format used by metadata.config which is a sequence of terms each followed by a dot.
+// Binaries and strings are returned as string, lists as []any and integers as int64.
+func parseTerms(data []byte) ([]any, error) {
+	p := &termParser{data: data}
+
+	var terms []any
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return terms, nil
+		}
+
+		t, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+
+		p.skipSpace()
+		if !p.consume('.') {
+			return nil, errInvalidTerm
+		}
+
+		terms = append(terms, t)
+	}
+}
+
+type termParser struct {
+	data []byte
+	pos  int
+}
+
+func (p *termParser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *termParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.data[p.pos]
+}
+
+func (p *termParser) consume(c byte) bool {
+	if !p.eof() && p.data[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *termParser) skipSpace() {
+	for !p.eof() {
+		switch c := p.data[p.pos]; {
+		case c == '%':
+			for !p.eof() && p.data[p.pos] != '\n' {
+				p.pos++
+			}
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *termParser) parseTerm() (any, error) {
+	p.skipSpace()
+
+	switch c := p.peek(); {
+	case c == '{':
+		p.pos++
+		elems, err := p.parseSequence('}')
+		if err != nil {
+			return nil, err
+		}
+		return Tuple(elems), nil
+	case c == '[':
+		p.pos++
+		return p.parseSequence(']')
+	case c == '<':
+		return p.parseBinary()
+	case c == '"':
+		return p.parseString('"')
+	case c == '\'':
+		s, err := p.parseString('\'')
+		if err != nil {
+			return nil, err
+		}
+		return Atom(s), nil
+	case c == '-' || ('0' <= c && c <= '9'):
+		return p.parseInteger()
+	case 'a' <= c && c <= 'z':
+		start := p.pos
+		for !p.eof() && isAtomChar(p.data[p.pos]) {
+			p.pos++
+		}
+		return Atom(p.data[start:p.pos]), nil
+	}
+	return nil, errInvalidTerm
+}
+
+func isAtomChar(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || c == '_' || c == '@'
+}
+
+func (p *termParser) parseSequence(end byte) ([]any, error) {
+	elems := []any{}
+
+	p.skipSpace()
+	if p.consume(end) {
+		return elems, nil
+	}
+
+	for {
+		t, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, t)
+
+		p.skipSpace()
+		if p.consume(end) {
+			return elems, nil
+		}
+		if !p.consume(',') {
+			return nil, errInvalidTerm
+		}
+	}
+}
+
+// parseBinary parses binaries like <<"text">> or <<"text"/utf8>>
+func (p *termParser) parseBinary() (any, error) {
+	if !p.consume('<') || !p.consume('<') {
+		return nil, errInvalidTerm
+	}
+
+	p.skipSpace()
+	if p.consume('>') {
+		if !p.consume('>') {
+			return nil, errInvalidTerm
+		}
+		return "", nil
+	}
+
+	s, err := p.parseString('"')
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if p.consume('/') {
+		if !bytes.HasPrefix(p.data[p.pos:], []byte("utf8")) {
+			return nil, errInvalidTerm
+		}
+		p.pos += len("utf8")
+		p.skipSpace()
+	}
+
+	if !p.consume('>') || !p.consume('>') {
+		return nil, errInvalidTerm
+	}
+	return s, nil
+}
+
+func (p *termParser) parseString(quote byte) (string, error) {
+	if !p.consume(quote) {
+		return "", errInvalidTerm
+	}
+
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", errInvalidTerm
+		}
+
+		c := p.data[p.pos]
+		p.pos++
+
+		switch c {
+		case quote:
+			return sb.String(), nil
+		case '\\':
+			if p.eof() {
+				return "", errInvalidTerm
+			}
+			e := p.data[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			default:
+				sb.WriteByte(e)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+}
+
+func (p *termParser) parseInteger() (any, error) {
+	start := p.pos
+	p.consume('-')
+	for !p.eof() && '0' <= p.data[p.pos] && p.data[p.pos] <= '9' {
+		p.pos++
+	}
+
+	i, err := strconv.ParseInt(string(p.data[start:p.pos]), 10, 64)
+	if err != nil {
+		return nil, errInvalidTerm
+	}
+	return i, nil
+}
+
+// External Term Format tags
+// https://www.erlang.org/doc/apps/erts/erl_ext_dist.html
+const (
+	etfVersion      = 131
+	etfSmallInteger = 97
+	etfInteger      = 98
+	etfSmallAtom    = 119
+	etfNil          = 106
+	etfList         = 108
+	etfBinary       = 109
+	etfMap          = 116
+)
+
+// MarshalTerm encodes the value in the Erlang External Term Format which is used by the Hex API.
+// Supported are strings (encoded as binaries), integers, booleans, nil, Atom, []any and map[string]any.
+func MarshalTerm(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(etfVersion)
+	if err := writeTerm(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeTerm(buf *bytes.Buffer, v any) error {
+	switch t := v.(type) {
+	case nil:
+		return writeAtom(buf, "nil")
+	case bool:
+		return writeAtom(buf, strconv.FormatBool(t))
+	case Atom:
+		return writeAtom(buf, string(t))
+	case int:
+		return writeInteger(buf, int64(t))
+	case int64:
+		return writeInteger(buf, t)
+	case string:
+		buf.WriteByte(etfBinary)
+		_ = binary.Write(buf, binary.BigEndian, uint32(len(t)))
+		buf.WriteString(t)
+	case []any:
+		if len(t) == 0 {
+			buf.WriteByte(etfNil)
+			return nil
+		}
+		buf.WriteByte(etfList)
+		_ = binary.Write(buf, binary.BigEndian, uint32(len(t)))
+		for _, e := range t {
+			if err := writeTerm(buf, e); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(etfNil)
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		buf.WriteByte(etfMap)
+		_ = binary.Write(buf, binary.BigEndian, uint32(len(t)))
+		for _, k := range keys {
+			if err := writeTerm(buf, k); err != nil {
+				return err
+			}
+			if err := writeTerm(buf, t[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported term type %T", v)
+	}
+	return nil
+}
+
+func writeAtom(buf *bytes.Buffer, name string) error {
+	if len(name) > 255 {
+		return errors.New("atom is too long")
+	}
+	buf.WriteByte(etfSmallAtom)
+	buf.WriteByte(byte(len(name)))
+	buf.WriteString(name)
+	return nil
+}
+
+func writeInteger(buf *bytes.Buffer, i int64) error {
+	if 0 <= i && i <= 255 {
+		buf.WriteByte(etfSmallInteger)
+		buf.WriteByte(byte(i))
+		return nil
+	}
+	if i < -1<<31 || i > 1<<31-1 {
+		return errors.New("integer is out of range")
+	}
+	buf.WriteByte(etfInteger)
+	_ = binary.Write(buf, binary.BigEndian, int32(i))
+	return nil
+}
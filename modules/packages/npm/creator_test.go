@@ -300,3 +300,33 @@ func TestParsePackage(t *testing.T) {
 		assert.Equal(t, repository.URL, p.Metadata.Repository.URL)
 	})
 }
+
+func TestParsePackageVersion(t *testing.T) {
+	data, _ := base64.StdEncoding.DecodeString("H4sIAAAAAAAA/ytITM5OTE/VL4DQelnF+XkMVAYGBgZmJiYK2MRBwNDcSIHB2NTMwNDQzMwAqA7IMDUxA9LUdgg2UFpcklgEdAql5kD8ogCnhwio5lJQUMpLzE1VslJQcihOzi9I1S9JLS7RhSYIJR2QgrLUouLM/DyQGkM9Az1D3YIiqExKanFyUWZBCVQ2BKhVwQVJDKwosbQkI78IJO/tZ+LsbRykxFXLNdA+HwWjYBSMgpENACgAbtAACAAA")
+
+	meta := func(integrity string) *PackageMetadataVersion {
+		return &PackageMetadataVersion{
+			Name:    "@scope/test-package",
+			Version: "1.0.1-pre",
+			Dist: PackageDistribution{
+				Integrity: integrity,
+			},
+		}
+	}
+
+	t.Run("InvalidIntegrity", func(t *testing.T) {
+		p, err := ParsePackageVersion(meta("sha512-invalid"), data)
+		assert.Nil(t, p)
+		require.ErrorIs(t, err, ErrInvalidIntegrity)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		p, err := ParsePackageVersion(meta("sha512-yA4FJsVhetynGfOC1jFf79BuS+jrHbm0fhh+aHzCQkOaOBXKf9oBnC4a6DnLLnEsHQDRLYd00cwj8sCXpC+wIg=="), data)
+		require.NoError(t, err)
+		assert.Equal(t, "@scope/test-package", p.Name)
+		assert.Equal(t, "1.0.1-pre", p.Version)
+		assert.Equal(t, "test-package-1.0.1-pre.tgz", p.Filename)
+		assert.Equal(t, "@scope", p.Metadata.Scope)
+		assert.Equal(t, data, p.Data)
+	})
+}
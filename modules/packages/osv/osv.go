@@ -0,0 +1,239 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package osv
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/hashicorp/go-version"
+)
+
+var ErrInvalidVulnerability = util.NewInvalidArgumentErrorf("vulnerability is invalid")
+
+const (
+	SeverityUnknown  = ""
+	SeverityLow      = "low"
+	SeverityModerate = "moderate"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+
+	RangeTypeSemver    = "SEMVER"
+	RangeTypeEcosystem = "ECOSYSTEM"
+
+	maxVulnerabilitySize = 8 << 20
+)
+
+// Ecosystems maps the supported OSV ecosystems to package types
+var Ecosystems = map[string]string{
+	"crates.io": "cargo",
+	"Go":        "go",
+	"Maven":     "maven",
+	"npm":       "npm",
+	"PyPI":      "pypi",
+}
+
+// Vulnerability is an entry of an OSV advisory database
+// https://ossf.github.io/osv-schema/
+type Vulnerability struct {
+	ID               string         `json:"id"`
+	Modified         time.Time      `json:"modified"`
+	Withdrawn        *time.Time     `json:"withdrawn,omitempty"`
+	Aliases          []string       `json:"aliases,omitempty"`
+	Summary          string         `json:"summary,omitempty"`
+	Details          string         `json:"details,omitempty"`
+	Severity         []*Severity    `json:"severity,omitempty"`
+	Affected         []*Affected    `json:"affected,omitempty"`
+	DatabaseSpecific map[string]any `json:"database_specific,omitempty"`
+}
+
+// Severity is a quantitative severity score
+type Severity struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+// Package identifies an affected package
+type Package struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+}
+
+// Event marks the start or end of an affected version range
+type Event struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+	Limit        string `json:"limit,omitempty"`
+}
+
+// Range is a range of affected versions
+type Range struct {
+	Type   string   `json:"type"`
+	Events []*Event `json:"events"`
+}
+
+// Affected describes the affected versions of a package
+type Affected struct {
+	Package          Package        `json:"package"`
+	Ranges           []*Range       `json:"ranges,omitempty"`
+	Versions         []string       `json:"versions,omitempty"`
+	DatabaseSpecific map[string]any `json:"database_specific,omitempty"`
+}
+
+// ParseVulnerability parses an OSV vulnerability entry
+func ParseVulnerability(r io.Reader) (*Vulnerability, error) {
+	var v Vulnerability
+	if err := json.NewDecoder(io.LimitReader(r, maxVulnerabilitySize)).Decode(&v); err != nil {
+		return nil, ErrInvalidVulnerability
+	}
+	if v.ID == "" {
+		return nil, ErrInvalidVulnerability
+	}
+	return &v, nil
+}
+
+// NormalizeName converts the package name of the ecosystem to the lower name used by the package registry
+func NormalizeName(ecosystem, name string) string {
+	switch ecosystem {
+	case "Maven":
+		// groupId:artifactId is stored as groupId-artifactId
+		name = strings.Replace(name, ":", "-", 1)
+	case "PyPI":
+		name = strings.NewReplacer(".", "-", "_", "-").Replace(name)
+	}
+	return strings.ToLower(name)
+}
+
+// GetSeverity returns the normalized severity of the vulnerability.
+// The severity provided by the database is preferred over the CVSS score.
+func (v *Vulnerability) GetSeverity() string {
+	if s, ok := v.DatabaseSpecific["severity"].(string); ok {
+		switch strings.ToLower(s) {
+		case "low":
+			return SeverityLow
+		case "moderate", "medium":
+			return SeverityModerate
+		case "high":
+			return SeverityHigh
+		case "critical":
+			return SeverityCritical
+		}
+	}
+
+	for _, s := range v.Severity {
+		if s.Type != "CVSS_V3" {
+			continue
+		}
+		if score, ok := CVSS3BaseScore(s.Score); ok {
+			return SeverityFromScore(score)
+		}
+	}
+	return SeverityUnknown
+}
+
+// SeverityFromScore maps a CVSS score to a severity
+func SeverityFromScore(score float64) string {
+	switch {
+	case score >= 9:
+		return SeverityCritical
+	case score >= 7:
+		return SeverityHigh
+	case score >= 4:
+		return SeverityModerate
+	case score > 0:
+		return SeverityLow
+	default:
+		return SeverityUnknown
+	}
+}
+
+// IsAffected checks if the version is affected.
+// Ranges which are not SEMVER or ECOSYSTEM ranges and versions which can't be compared are ignored.
+func (a *Affected) IsAffected(v string) bool {
+	for _, av := range a.Versions {
+		if av == v {
+			return true
+		}
+	}
+
+	pv, err := version.NewVersion(v)
+	if err != nil {
+		return false
+	}
+
+	for _, r := range a.Ranges {
+		if r.Type != RangeTypeSemver && r.Type != RangeTypeEcosystem {
+			continue
+		}
+		if r.contains(pv) {
+			return true
+		}
+	}
+	return false
+}
+
+type rangeEvent struct {
+	version *version.Version // nil is the lowest possible version
+	event   *Event
+}
+
+// contains evaluates the events of the range in version order as described in
+// https://ossf.github.io/osv-schema/#evaluation
+func (r *Range) contains(v *version.Version) bool {
+	events := make([]*rangeEvent, 0, len(r.Events))
+	for _, e := range r.Events {
+		s := e.Introduced + e.Fixed + e.LastAffected + e.Limit
+		if s == "0" {
+			events = append(events, &rangeEvent{event: e})
+			continue
+		}
+		ev, err := version.NewVersion(s)
+		if err != nil {
+			return false
+		}
+		events = append(events, &rangeEvent{version: ev, event: e})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].version == nil {
+			return events[j].version != nil
+		}
+		if events[j].version == nil {
+			return false
+		}
+		return events[i].version.LessThan(events[j].version)
+	})
+
+	affected := false
+	for _, e := range events {
+		if e.version == nil && e.event.Introduced == "" {
+			continue
+		}
+		switch {
+		case e.event.Introduced != "":
+			if e.version == nil || !v.LessThan(e.version) {
+				affected = true
+			}
+		case e.event.Fixed != "":
+			if !v.LessThan(e.version) {
+				affected = false
+			}
+		case e.event.LastAffected != "":
+			if v.GreaterThan(e.version) {
+				affected = false
+			}
+		case e.event.Limit != "":
+			if !v.LessThan(e.version) {
+				affected = false
+			}
+		}
+	}
+	return affected
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package osv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const content = `{
+  "id": "GHSA-xxxx-yyyy-zzzz",
+  "modified": "2024-03-01T10:00:00Z",
+  "aliases": ["CVE-2024-0001"],
+  "summary": "Prototype pollution",
+  "severity": [{"type": "CVSS_V3", "score": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}],
+  "affected": [
+    {
+      "package": {"ecosystem": "npm", "name": "left-pad"},
+      "ranges": [
+        {"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "1.3.1"}]},
+        {"type": "SEMVER", "events": [{"introduced": "2.0.0"}, {"last_affected": "2.1.0"}]}
+      ],
+      "versions": ["0.9.0-beta"]
+    }
+  ]
+}`
+
+func TestParseVulnerability(t *testing.T) {
+	_, err := ParseVulnerability(strings.NewReader("{}"))
+	require.ErrorIs(t, err, ErrInvalidVulnerability)
+
+	v, err := ParseVulnerability(strings.NewReader(content))
+	require.NoError(t, err)
+
+	assert.Equal(t, "GHSA-xxxx-yyyy-zzzz", v.ID)
+	assert.Equal(t, []string{"CVE-2024-0001"}, v.Aliases)
+	assert.Equal(t, SeverityCritical, v.GetSeverity())
+	require.Len(t, v.Affected, 1)
+
+	a := v.Affected[0]
+	assert.Equal(t, "npm", a.Package.Ecosystem)
+
+	cases := map[string]bool{
+		"0.9.0-beta": true,
+		"1.0.0":      true,
+		"1.3.0":      true,
+		"1.3.1":      false,
+		"1.4.0":      false,
+		"2.0.0":      true,
+		"2.1.0":      true,
+		"2.1.1":      false,
+		"invalid":    false,
+	}
+	for version, expected := range cases {
+		assert.Equal(t, expected, a.IsAffected(version), version)
+	}
+}
+
+func TestGetSeverity(t *testing.T) {
+	v := &Vulnerability{DatabaseSpecific: map[string]any{"severity": "MODERATE"}}
+	assert.Equal(t, SeverityModerate, v.GetSeverity())
+
+	v = &Vulnerability{}
+	assert.Equal(t, SeverityUnknown, v.GetSeverity())
+}
+
+func TestCVSS3BaseScore(t *testing.T) {
+	cases := map[string]float64{
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H": 9.8,
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H": 10,
+		"CVSS:3.0/AV:N/AC:L/PR:L/UI:N/S:U/C:L/I:N/A:N": 4.3,
+		"CVSS:3.1/AV:L/AC:H/PR:H/UI:R/S:U/C:L/I:N/A:N": 1.8,
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N": 6.1,
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N": 0,
+	}
+	for vector, expected := range cases {
+		score, ok := CVSS3BaseScore(vector)
+		assert.True(t, ok, vector)
+		assert.InDelta(t, expected, score, 0.001, vector)
+	}
+
+	_, ok := CVSS3BaseScore("CVSS:2.0/AV:N")
+	assert.False(t, ok)
+	_, ok = CVSS3BaseScore("CVSS:3.1/AV:X/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H")
+	assert.False(t, ok)
+}
+
+func TestNormalizeName(t *testing.T) {
+	assert.Equal(t, "org.example-lib", NormalizeName("Maven", "org.example:lib"))
+	assert.Equal(t, "zope-interface", NormalizeName("PyPI", "Zope.Interface"))
+	assert.Equal(t, "@scope/pkg", NormalizeName("npm", "@Scope/pkg"))
+}
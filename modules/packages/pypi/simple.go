@@ -0,0 +1,148 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package pypi
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	"code.gitea.io/gitea/modules/json"
+
+	"golang.org/x/net/html"
+)
+
+// ContentTypeSimpleJSON is the content type of the JSON based simple repository API
+// https://peps.python.org/pep-0691/
+const ContentTypeSimpleJSON = "application/vnd.pypi.simple.v1+json"
+
+var normalizer = strings.NewReplacer(".", "-", "_", "-")
+
+// SimpleFile represents a file listed in the simple repository API of a package index
+type SimpleFile struct {
+	Filename       string
+	URL            string
+	SHA256         string
+	RequiresPython string
+}
+
+// ParseSimpleIndex parses the project page of the simple repository API.
+// Both the JSON (PEP 691) and the HTML (PEP 503) format are supported.
+// Relative file urls are resolved against the url of the page.
+func ParseSimpleIndex(r io.Reader, contentType string, pageURL *url.URL) ([]*SimpleFile, error) {
+	var files []*SimpleFile
+	var err error
+	if strings.Contains(contentType, "json") {
+		files, err = parseSimpleIndexJSON(r)
+	} else {
+		files, err = parseSimpleIndexHTML(r)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*SimpleFile, 0, len(files))
+	for _, f := range files {
+		u, err := pageURL.Parse(f.URL)
+		if err != nil || f.Filename == "" {
+			continue
+		}
+		if f.SHA256 == "" && strings.HasPrefix(u.Fragment, "sha256=") {
+			f.SHA256 = strings.TrimPrefix(u.Fragment, "sha256=")
+		}
+		u.Fragment = ""
+		f.URL = u.String()
+		result = append(result, f)
+	}
+	return result, nil
+}
+
+func parseSimpleIndexJSON(r io.Reader) ([]*SimpleFile, error) {
+	var page struct {
+		Files []struct {
+			Filename       string            `json:"filename"`
+			URL            string            `json:"url"`
+			Hashes         map[string]string `json:"hashes"`
+			RequiresPython string            `json:"requires-python"`
+		} `json:"files"`
+	}
+	if err := json.NewDecoder(r).Decode(&page); err != nil {
+		return nil, err
+	}
+
+	files := make([]*SimpleFile, 0, len(page.Files))
+	for _, f := range page.Files {
+		files = append(files, &SimpleFile{
+			Filename:       f.Filename,
+			URL:            f.URL,
+			SHA256:         f.Hashes["sha256"],
+			RequiresPython: f.RequiresPython,
+		})
+	}
+	return files, nil
+}
+
+func parseSimpleIndexHTML(r io.Reader) ([]*SimpleFile, error) {
+	files := make([]*SimpleFile, 0, 10)
+
+	var current *SimpleFile
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return files, nil
+			}
+			return nil, z.Err()
+		case html.StartTagToken:
+			t := z.Token()
+			if t.Data != "a" {
+				continue
+			}
+			current = &SimpleFile{}
+			for _, attr := range t.Attr {
+				switch attr.Key {
+				case "href":
+					current.URL = attr.Val
+				case "data-requires-python":
+					current.RequiresPython = attr.Val
+				}
+			}
+		case html.TextToken:
+			if current != nil {
+				current.Filename += strings.TrimSpace(string(z.Text()))
+			}
+		case html.EndTagToken:
+			if current != nil && z.Token().Data == "a" {
+				files = append(files, current)
+				current = nil
+			}
+		}
+	}
+}
+
+// VersionFromFilename extracts the version of the package from the name of a wheel or source distribution file
+// https://packaging.python.org/en/latest/specifications/binary-distribution-format/#file-name-convention
+// https://packaging.python.org/en/latest/specifications/source-distribution-format/#source-distribution-file-name
+func VersionFromFilename(packageName, filename string) string {
+	if base, ok := strings.CutSuffix(filename, ".whl"); ok {
+		parts := strings.Split(base, "-")
+		if len(parts) < 5 {
+			return ""
+		}
+		return parts[1]
+	}
+
+	for _, ext := range []string{".tar.gz", ".tar.bz2", ".tar.xz", ".tgz", ".zip"} {
+		if base, ok := strings.CutSuffix(filename, ext); ok {
+			prefix := strings.ToLower(normalizer.Replace(packageName)) + "-"
+			if !strings.HasPrefix(strings.ToLower(normalizer.Replace(base)), prefix) {
+				return ""
+			}
+			return base[len(prefix):]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package pypi
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSimpleIndex(t *testing.T) {
+	pageURL, _ := url.Parse("https://example.com/simple/test-package/")
+
+	t.Run("JSON", func(t *testing.T) {
+		content := `{"meta":{"api-version":"1.1"},"name":"test-package","files":[{"filename":"test_package-1.0.0-py3-none-any.whl","url":"https://files.example.com/test_package-1.0.0-py3-none-any.whl","hashes":{"sha256":"abc"},"requires-python":">=3.8"}]}`
+
+		files, err := ParseSimpleIndex(strings.NewReader(content), ContentTypeSimpleJSON, pageURL)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		assert.Equal(t, "test_package-1.0.0-py3-none-any.whl", files[0].Filename)
+		assert.Equal(t, "https://files.example.com/test_package-1.0.0-py3-none-any.whl", files[0].URL)
+		assert.Equal(t, "abc", files[0].SHA256)
+		assert.Equal(t, ">=3.8", files[0].RequiresPython)
+	})
+
+	t.Run("HTML", func(t *testing.T) {
+		content := `<!DOCTYPE html><html><body>
+<a href="../../files/test-package-1.0.0.tar.gz#sha256=def" data-requires-python="&gt;=3.8">test-package-1.0.0.tar.gz</a><br>
+</body></html>`
+
+		files, err := ParseSimpleIndex(strings.NewReader(content), "text/html", pageURL)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		assert.Equal(t, "test-package-1.0.0.tar.gz", files[0].Filename)
+		assert.Equal(t, "https://example.com/files/test-package-1.0.0.tar.gz", files[0].URL)
+		assert.Equal(t, "def", files[0].SHA256)
+		assert.Equal(t, ">=3.8", files[0].RequiresPython)
+	})
+}
+
+func TestVersionFromFilename(t *testing.T) {
+	cases := []struct {
+		Filename string
+		Version  string
+	}{
+		{"test_package-1.0.0-py3-none-any.whl", "1.0.0"},
+		{"test_package-1.0.0-1-cp312-cp312-manylinux_2_17_x86_64.whl", "1.0.0"},
+		{"test-package-1.0.0.tar.gz", "1.0.0"},
+		{"test_package-2.0rc1.zip", "2.0rc1"},
+		{"other-package-1.0.0.tar.gz", ""},
+		{"test-package-1.0.0.exe", ""},
+		{"invalid.whl", ""},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.Version, VersionFromFilename("Test.Package", c.Filename), c.Filename)
+	}
+}
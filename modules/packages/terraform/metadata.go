@@ -0,0 +1,223 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package terraform
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/hashicorp/go-version"
+)
+
+var (
+	ErrInvalidName     = util.NewInvalidArgumentErrorf("package name is invalid")
+	ErrInvalidVersion  = util.NewInvalidArgumentErrorf("package version is invalid")
+	ErrInvalidFilename = util.NewInvalidArgumentErrorf("package filename is invalid")
+	ErrInvalidModule   = util.NewInvalidArgumentErrorf("module archive is invalid")
+	ErrInvalidProvider = util.NewInvalidArgumentErrorf("provider archive is invalid")
+	ErrInvalidManifest = util.NewInvalidArgumentErrorf("provider manifest is invalid")
+)
+
+const (
+	KindModule   = "module"
+	KindProvider = "provider"
+
+	PropertyOS   = "terraform.os"
+	PropertyArch = "terraform.arch"
+
+	SettingKeyPrivate = "terraform.key.private"
+	SettingKeyPublic  = "terraform.key.public"
+
+	// DefaultProtocol is used if no provider manifest was uploaded
+	DefaultProtocol = "5.0"
+
+	maxReadmeSize = 1 << 20
+)
+
+var (
+	namePattern         = regexp.MustCompile(`\A[0-9A-Za-z](?:[0-9A-Za-z-_]{0,62}[0-9A-Za-z])?\z`)
+	providerTypePattern = regexp.MustCompile(`\A[0-9a-z](?:[0-9a-z-]{0,62}[0-9a-z])?\z`)
+	platformPattern     = regexp.MustCompile(`\A[0-9a-z]+\z`)
+)
+
+// Metadata represents the metadata of a Terraform module or provider
+type Metadata struct {
+	Kind      string   `json:"kind"`
+	Readme    string   `json:"readme,omitempty"`
+	Protocols []string `json:"protocols,omitempty"`
+}
+
+// Platform is a os/arch combination a provider is built for
+type Platform struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+}
+
+// IsValidName checks if the name is a valid module name, module system or namespace
+func IsValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// IsValidProviderType checks if the name is a valid provider type
+func IsValidProviderType(name string) bool {
+	return providerTypePattern.MatchString(name)
+}
+
+// IsValidVersion checks if the version is a valid semantic version
+func IsValidVersion(v string) bool {
+	_, err := version.NewSemver(v)
+	return err == nil
+}
+
+// ModulePackageName returns the package name used to store a module
+func ModulePackageName(name, system string) string {
+	return name + "/" + system
+}
+
+// ModuleFilename returns the filename of the module archive
+func ModuleFilename(name, system, version string) string {
+	return strings.ToLower(name + "-" + system + "-" + version + ".tar.gz")
+}
+
+// ParseModule validates the gzipped tar archive of a module and extracts the metadata
+func ParseModule(r io.Reader) (*Metadata, error) {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, ErrInvalidModule
+	}
+	defer gzr.Close()
+
+	m := &Metadata{
+		Kind: KindModule,
+	}
+
+	hasConfiguration := false
+
+	tr := tar.NewReader(gzr)
+	for {
+		hd, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidModule
+		}
+
+		if hd.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(strings.TrimPrefix(hd.Name, "./"))
+		if strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json") {
+			hasConfiguration = true
+		}
+		if strings.EqualFold(name, "README.md") {
+			data, err := io.ReadAll(io.LimitReader(tr, maxReadmeSize))
+			if err != nil {
+				return nil, err
+			}
+			m.Readme = string(data)
+		}
+	}
+
+	if !hasConfiguration {
+		return nil, ErrInvalidModule
+	}
+
+	return m, nil
+}
+
+// ProviderFile contains the information encoded in the filename of a provider file
+type ProviderFile struct {
+	Type       string
+	Version    string
+	IsManifest bool
+	Platform
+}
+
+// ParseProviderFilename parses filenames like terraform-provider-{type}_{version}_{os}_{arch}.zip
+// and terraform-provider-{type}_{version}_manifest.json
+func ParseProviderFilename(filename string) (*ProviderFile, error) {
+	base, ok := strings.CutPrefix(filename, "terraform-provider-")
+	if !ok {
+		return nil, ErrInvalidFilename
+	}
+
+	pf := &ProviderFile{}
+
+	if name, ok := strings.CutSuffix(base, "_manifest.json"); ok {
+		parts := strings.Split(name, "_")
+		if len(parts) != 2 {
+			return nil, ErrInvalidFilename
+		}
+		pf.Type, pf.Version, pf.IsManifest = parts[0], parts[1], true
+	} else if name, ok := strings.CutSuffix(base, ".zip"); ok {
+		parts := strings.Split(name, "_")
+		if len(parts) != 4 {
+			return nil, ErrInvalidFilename
+		}
+		pf.Type, pf.Version, pf.OS, pf.Arch = parts[0], parts[1], parts[2], parts[3]
+		if !platformPattern.MatchString(pf.OS) || !platformPattern.MatchString(pf.Arch) {
+			return nil, ErrInvalidFilename
+		}
+	} else {
+		return nil, ErrInvalidFilename
+	}
+
+	if !IsValidProviderType(pf.Type) {
+		return nil, ErrInvalidName
+	}
+	if !IsValidVersion(pf.Version) {
+		return nil, ErrInvalidVersion
+	}
+
+	return pf, nil
+}
+
+// ValidateProviderArchive checks if the zip archive contains the provider executable
+func ValidateProviderArchive(r io.ReaderAt, size int64, providerType string) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return ErrInvalidProvider
+	}
+
+	for _, f := range zr.File {
+		if strings.HasPrefix(path.Base(f.Name), "terraform-provider-"+providerType) {
+			return nil
+		}
+	}
+	return ErrInvalidProvider
+}
+
+// ParseProviderManifest parses the manifest file to retrieve the supported plugin protocol versions
+// https://developer.hashicorp.com/terraform/registry/providers/publishing#terraform-registry-manifest-file
+func ParseProviderManifest(r io.Reader) ([]string, error) {
+	var manifest struct {
+		Version  int `json:"version"`
+		Metadata struct {
+			ProtocolVersions []string `json:"protocol_versions"`
+		} `json:"metadata"`
+	}
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, errors.Join(ErrInvalidManifest, err)
+	}
+	if manifest.Version != 1 || len(manifest.Metadata.ProtocolVersions) == 0 {
+		return nil, ErrInvalidManifest
+	}
+	for _, p := range manifest.Metadata.ProtocolVersions {
+		if _, err := version.NewVersion(p); err != nil {
+			return nil, ErrInvalidManifest
+		}
+	}
+	return manifest.Metadata.ProtocolVersions, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package terraform
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createModuleArchive(files map[string]string) *bytes.Buffer {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for name, content := range files {
+		hdr := &tar.Header{
+			Name: name,
+			Mode: 0o600,
+			Size: int64(len(content)),
+		}
+		tw.WriteHeader(hdr)
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	zw.Close()
+	return &buf
+}
+
+func TestParseModule(t *testing.T) {
+	t.Run("InvalidArchive", func(t *testing.T) {
+		m, err := ParseModule(strings.NewReader("invalid"))
+		assert.Nil(t, m)
+		assert.ErrorIs(t, err, ErrInvalidModule)
+	})
+
+	t.Run("MissingConfiguration", func(t *testing.T) {
+		m, err := ParseModule(createModuleArchive(map[string]string{"README.md": "readme"}))
+		assert.Nil(t, m)
+		assert.ErrorIs(t, err, ErrInvalidModule)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		m, err := ParseModule(createModuleArchive(map[string]string{
+			"./main.tf":   `variable "test" {}`,
+			"./README.md": "# Test module",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, KindModule, m.Kind)
+		assert.Equal(t, "# Test module", m.Readme)
+	})
+}
+
+func TestParseProviderFilename(t *testing.T) {
+	pf, err := ParseProviderFilename("terraform-provider-test_1.0.0_linux_amd64.zip")
+	require.NoError(t, err)
+	assert.Equal(t, "test", pf.Type)
+	assert.Equal(t, "1.0.0", pf.Version)
+	assert.Equal(t, "linux", pf.OS)
+	assert.Equal(t, "amd64", pf.Arch)
+	assert.False(t, pf.IsManifest)
+
+	pf, err = ParseProviderFilename("terraform-provider-test-beta_1.0.0-rc1_manifest.json")
+	require.NoError(t, err)
+	assert.Equal(t, "test-beta", pf.Type)
+	assert.Equal(t, "1.0.0-rc1", pf.Version)
+	assert.True(t, pf.IsManifest)
+
+	for _, filename := range []string{
+		"test_1.0.0_linux_amd64.zip",
+		"terraform-provider-test_1.0.0_linux.zip",
+		"terraform-provider-test_1.0.0_linux_amd64.tar.gz",
+		"terraform-provider-Test_1.0.0_linux_amd64.zip",
+		"terraform-provider-test_invalid_linux_amd64.zip",
+	} {
+		_, err := ParseProviderFilename(filename)
+		assert.Error(t, err, filename)
+	}
+}
+
+func TestValidateProviderArchive(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("terraform-provider-test_v1.0.0")
+	w.Write([]byte("binary"))
+	zw.Close()
+
+	r := bytes.NewReader(buf.Bytes())
+	assert.NoError(t, ValidateProviderArchive(r, r.Size(), "test"))
+	assert.ErrorIs(t, ValidateProviderArchive(r, r.Size(), "other"), ErrInvalidProvider)
+
+	r = bytes.NewReader([]byte("invalid"))
+	assert.ErrorIs(t, ValidateProviderArchive(r, r.Size(), "test"), ErrInvalidProvider)
+}
+
+func TestParseProviderManifest(t *testing.T) {
+	protocols, err := ParseProviderManifest(strings.NewReader(`{"version":1,"metadata":{"protocol_versions":["6.0"]}}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"6.0"}, protocols)
+
+	_, err = ParseProviderManifest(strings.NewReader(`{"version":2,"metadata":{"protocol_versions":["6.0"]}}`))
+	assert.ErrorIs(t, err, ErrInvalidManifest)
+
+	_, err = ParseProviderManifest(strings.NewReader(`{"version":1,"metadata":{}}`))
+	assert.ErrorIs(t, err, ErrInvalidManifest)
+}
@@ -45,13 +45,14 @@ type ServCommandResults struct {
 	RepoID      int64
 }
 
-// ServCommand preps for a serv call
-func ServCommand(ctx context.Context, keyID int64, ownerName, repoName string, mode perm.AccessMode, verbs ...string) (*ServCommandResults, ResponseExtra) {
-	reqURL := setting.LocalURL + fmt.Sprintf("api/internal/serv/command/%d/%s/%s?mode=%d",
+// ServCommand preps for a serv call, remoteIP is the address the SSH client connected from
+func ServCommand(ctx context.Context, keyID int64, ownerName, repoName string, mode perm.AccessMode, remoteIP string, verbs ...string) (*ServCommandResults, ResponseExtra) {
+	reqURL := setting.LocalURL + fmt.Sprintf("api/internal/serv/command/%d/%s/%s?mode=%d&remote_ip=%s",
 		keyID,
 		url.PathEscape(ownerName),
 		url.PathEscape(repoName),
 		mode,
+		url.QueryEscape(remoteIP),
 	)
 	for _, verb := range verbs {
 		if verb != "" {
@@ -27,6 +27,8 @@ type PushCommit struct {
 	CommitterEmail string
 	CommitterName  string
 	Timestamp      time.Time
+	// URL links to the commit if it isn't a commit of the repository of the action, e.g. one of a federated push
+	URL string `json:",omitempty"`
 }
 
 // PushCommits represents list of commits in a push operation.
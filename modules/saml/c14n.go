@@ -0,0 +1,135 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package saml
+
+import (
+	"slices"
+	"strings"
+)
+
+// canonicalizer implements Exclusive XML Canonicalization 1.0 without comments
+// (https://www.w3.org/TR/xml-exc-c14n/) for an element and its descendants.
+type canonicalizer struct {
+	// inclusivePrefixes are treated like in inclusive canonicalization
+	inclusivePrefixes []string
+	// exclude is omitted with its descendants, used by the enveloped signature transform
+	exclude *Element
+	sb      strings.Builder
+}
+
+// Canonicalize returns the exclusive canonical form of the element
+func Canonicalize(e *Element, inclusivePrefixes []string) []byte {
+	c := &canonicalizer{inclusivePrefixes: inclusivePrefixes}
+	c.element(e, map[string]string{})
+	return []byte(c.sb.String())
+}
+
+func canonicalizeEnveloped(e, signature *Element, inclusivePrefixes []string) []byte {
+	c := &canonicalizer{inclusivePrefixes: inclusivePrefixes, exclude: signature}
+	c.element(e, map[string]string{})
+	return []byte(c.sb.String())
+}
+
+func (c *canonicalizer) element(e *Element, rendered map[string]string) {
+	// namespaces which are visibly utilized by the element or its attributes
+	prefixes := []string{e.Prefix}
+	for _, a := range e.Attrs {
+		if a.Prefix != "" && a.Prefix != "xml" {
+			prefixes = append(prefixes, a.Prefix)
+		}
+	}
+	for _, p := range c.inclusivePrefixes {
+		if p == "#default" {
+			p = ""
+		}
+		if _, inScope := e.LookupNamespace(p); inScope {
+			prefixes = append(prefixes, p)
+		}
+	}
+	slices.Sort(prefixes)
+	prefixes = slices.Compact(prefixes)
+
+	var nsDecls []Attr
+	current := rendered
+	for _, p := range prefixes {
+		uri, _ := e.LookupNamespace(p)
+		previous, ok := rendered[p]
+		if ok && previous == uri || !ok && p == "" && uri == "" {
+			continue
+		}
+		if len(nsDecls) == 0 {
+			current = make(map[string]string, len(rendered)+len(prefixes))
+			for k, v := range rendered {
+				current[k] = v
+			}
+		}
+		current[p] = uri
+		nsDecls = append(nsDecls, Attr{Local: p, Value: uri})
+	}
+
+	attrs := slices.Clone(e.Attrs)
+	slices.SortFunc(attrs, func(a, b Attr) int {
+		if a.Space != b.Space {
+			return strings.Compare(a.Space, b.Space)
+		}
+		return strings.Compare(a.Local, b.Local)
+	})
+
+	c.sb.WriteByte('<')
+	c.sb.WriteString(qualifiedName(e.Prefix, e.Local))
+	for _, ns := range nsDecls {
+		if ns.Local == "" {
+			c.sb.WriteString(` xmlns="`)
+		} else {
+			c.sb.WriteString(` xmlns:`)
+			c.sb.WriteString(ns.Local)
+			c.sb.WriteString(`="`)
+		}
+		c.sb.WriteString(escapeAttr(ns.Value))
+		c.sb.WriteByte('"')
+	}
+	for _, a := range attrs {
+		c.sb.WriteByte(' ')
+		c.sb.WriteString(qualifiedName(a.Prefix, a.Local))
+		c.sb.WriteString(`="`)
+		c.sb.WriteString(escapeAttr(a.Value))
+		c.sb.WriteByte('"')
+	}
+	c.sb.WriteByte('>')
+
+	for _, child := range e.Children {
+		switch n := child.(type) {
+		case *Element:
+			if n != c.exclude {
+				c.element(n, current)
+			}
+		case string:
+			c.sb.WriteString(escapeText(n))
+		}
+	}
+
+	c.sb.WriteString("</")
+	c.sb.WriteString(qualifiedName(e.Prefix, e.Local))
+	c.sb.WriteByte('>')
+}
+
+func qualifiedName(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func escapeAttr(s string) string {
+	return attrEscaper.Replace(s)
+}
@@ -0,0 +1,292 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package saml
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	// register the hash functions used by signatures
+	_ "crypto/sha1" //nolint:gosec
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+const (
+	nsDS = "http://www.w3.org/2000/09/xmldsig#"
+
+	algExcC14N            = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	algEnvelopedSignature = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+
+	algDigestSHA1   = "http://www.w3.org/2000/09/xmldsig#sha1"
+	algDigestSHA256 = "http://www.w3.org/2001/04/xmlenc#sha256"
+	algDigestSHA512 = "http://www.w3.org/2001/04/xmlenc#sha512"
+
+	algRSASHA1     = "http://www.w3.org/2000/09/xmldsig#rsa-sha1"
+	algRSASHA256   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	algRSASHA512   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha512"
+	algECDSASHA256 = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256"
+	algECDSASHA512 = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha512"
+)
+
+var (
+	// ErrSignatureMissing is returned if an element which has to be signed has no signature
+	ErrSignatureMissing = errors.New("signature is missing")
+	// ErrSHA1NotAllowed is returned for SHA-1 signatures and digests unless the service provider allows them
+	ErrSHA1NotAllowed = errors.New("SHA-1 is not allowed")
+
+	digestAlgorithms = map[string]crypto.Hash{
+		algDigestSHA1:   crypto.SHA1,
+		algDigestSHA256: crypto.SHA256,
+		algDigestSHA512: crypto.SHA512,
+	}
+
+	signatureAlgorithms = map[string]crypto.Hash{
+		algRSASHA1:     crypto.SHA1,
+		algRSASHA256:   crypto.SHA256,
+		algRSASHA512:   crypto.SHA512,
+		algECDSASHA256: crypto.SHA256,
+		algECDSASHA512: crypto.SHA512,
+	}
+)
+
+// VerifySignature verifies the enveloped signature of the element with one of the certificates.
+// The signature must reference the element itself, so the verified content is exactly the element.
+// Signatures and digests using SHA-1 are only accepted if allowSHA1 is set.
+func VerifySignature(e *Element, certs []*x509.Certificate, allowSHA1 bool) error {
+	signatures := e.ChildElements(nsDS, "Signature")
+	if len(signatures) == 0 {
+		return ErrSignatureMissing
+	} else if len(signatures) > 1 {
+		return errors.New("multiple signatures")
+	}
+	signature := signatures[0]
+
+	id := e.Attr("ID")
+	if id == "" {
+		return errors.New("signed element has no ID")
+	}
+	if err := checkUniqueID(e.root(), id); err != nil {
+		return err
+	}
+
+	signedInfo := signature.ChildElement(nsDS, "SignedInfo")
+	if signedInfo == nil {
+		return errors.New("signature has no SignedInfo")
+	}
+	canonicalizationMethod := signedInfo.ChildElement(nsDS, "CanonicalizationMethod")
+	if canonicalizationMethod == nil || !isExcC14N(canonicalizationMethod.Attr("Algorithm")) {
+		return errors.New("unsupported canonicalization method")
+	}
+	signatureMethod := signedInfo.ChildElement(nsDS, "SignatureMethod")
+	if signatureMethod == nil {
+		return errors.New("signature has no SignatureMethod")
+	}
+	hash, ok := signatureAlgorithms[signatureMethod.Attr("Algorithm")]
+	if !ok {
+		return fmt.Errorf("unsupported signature method: %s", signatureMethod.Attr("Algorithm"))
+	} else if hash == crypto.SHA1 && !allowSHA1 {
+		return fmt.Errorf("signature method %s: %w", signatureMethod.Attr("Algorithm"), ErrSHA1NotAllowed)
+	}
+
+	references := signedInfo.ChildElements(nsDS, "Reference")
+	if len(references) != 1 {
+		return errors.New("signature must have exactly one reference")
+	}
+	if err := verifyReference(e, signature, references[0], allowSHA1); err != nil {
+		return err
+	}
+
+	signatureValue := signature.ChildElement(nsDS, "SignatureValue")
+	if signatureValue == nil {
+		return errors.New("signature has no SignatureValue")
+	}
+	sig, err := decodeBase64(signatureValue.Text())
+	if err != nil {
+		return fmt.Errorf("invalid SignatureValue: %w", err)
+	}
+
+	h := hash.New()
+	h.Write(Canonicalize(signedInfo, inclusivePrefixes(canonicalizationMethod)))
+	hashed := h.Sum(nil)
+
+	for _, cert := range certs {
+		if verifyHash(cert.PublicKey, hash, hashed, sig) == nil {
+			return nil
+		}
+	}
+	return errors.New("signature does not match any trusted certificate")
+}
+
+func verifyReference(e, signature, reference *Element, allowSHA1 bool) error {
+	if reference.Attr("URI") != "#"+e.Attr("ID") {
+		return errors.New("signature does not reference the signed element")
+	}
+
+	var prefixes []string
+	transforms := reference.ChildElement(nsDS, "Transforms")
+	enveloped := false
+	if transforms != nil {
+		for _, transform := range transforms.ChildElements(nsDS, "Transform") {
+			switch algorithm := transform.Attr("Algorithm"); {
+			case algorithm == algEnvelopedSignature:
+				enveloped = true
+			case isExcC14N(algorithm):
+				prefixes = inclusivePrefixes(transform)
+			default:
+				return fmt.Errorf("unsupported transform: %s", algorithm)
+			}
+		}
+	}
+	if !enveloped {
+		return errors.New("signature must be enveloped")
+	}
+
+	digestMethod := reference.ChildElement(nsDS, "DigestMethod")
+	if digestMethod == nil {
+		return errors.New("reference has no DigestMethod")
+	}
+	hash, ok := digestAlgorithms[digestMethod.Attr("Algorithm")]
+	if !ok {
+		return fmt.Errorf("unsupported digest method: %s", digestMethod.Attr("Algorithm"))
+	} else if hash == crypto.SHA1 && !allowSHA1 {
+		return fmt.Errorf("digest method %s: %w", digestMethod.Attr("Algorithm"), ErrSHA1NotAllowed)
+	}
+	digestValue := reference.ChildElement(nsDS, "DigestValue")
+	if digestValue == nil {
+		return errors.New("reference has no DigestValue")
+	}
+	expected, err := decodeBase64(digestValue.Text())
+	if err != nil {
+		return fmt.Errorf("invalid DigestValue: %w", err)
+	}
+
+	h := hash.New()
+	h.Write(canonicalizeEnveloped(e, signature, prefixes))
+	if subtle.ConstantTimeCompare(h.Sum(nil), expected) != 1 {
+		return errors.New("digest of the signed element does not match")
+	}
+	return nil
+}
+
+// checkUniqueID rejects documents with duplicate IDs which are used for signature wrapping attacks
+func checkUniqueID(root *Element, id string) error {
+	count := 0
+	root.walk(func(el *Element) {
+		if el.Attr("ID") == id {
+			count++
+		}
+	})
+	if count != 1 {
+		return errors.New("signed element ID is not unique")
+	}
+	return nil
+}
+
+// isExcC14N returns true for exclusive canonicalization without comments, the variant with comments
+// is rejected as comments are dropped when parsing, so the canonical form wouldn't match the signed one
+func isExcC14N(algorithm string) bool {
+	return algorithm == algExcC14N
+}
+
+func inclusivePrefixes(method *Element) []string {
+	for _, c := range method.Children {
+		if el, ok := c.(*Element); ok && el.Local == "InclusiveNamespaces" && el.Space == algExcC14N {
+			return strings.Fields(el.Attr("PrefixList"))
+		}
+	}
+	return nil
+}
+
+func verifyHash(publicKey any, hash crypto.Hash, hashed, sig []byte) error {
+	switch pub := publicKey.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pub, hash, hashed, sig)
+	case *ecdsa.PublicKey:
+		// XML signatures use the concatenation of r and s
+		if len(sig)%2 != 0 {
+			return errors.New("invalid ECDSA signature")
+		}
+		r := new(big.Int).SetBytes(sig[:len(sig)/2])
+		s := new(big.Int).SetBytes(sig[len(sig)/2:])
+		if !ecdsa.Verify(pub, hashed, r, s) {
+			return errors.New("invalid ECDSA signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	// base64 values in XML may be wrapped over multiple lines
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+}
+
+// SignEnveloped adds an enveloped RSA-SHA256 signature to the element, which must have an ID attribute.
+// The signature is inserted after the Issuer element if present, as required by SAML.
+func SignEnveloped(e *Element, key *rsa.PrivateKey, cert *x509.Certificate) error {
+	return signEnveloped(e, key, cert, algRSASHA256, algDigestSHA256)
+}
+
+func signEnveloped(e *Element, key *rsa.PrivateKey, cert *x509.Certificate, signatureMethod, digestMethod string) error {
+	id := e.Attr("ID")
+	if id == "" {
+		return errors.New("element has no ID")
+	}
+
+	signature := &Element{Prefix: "ds", Local: "Signature", Space: nsDS, NSDecls: []Attr{{Local: "ds", Value: nsDS}}, Parent: e}
+	newChild := func(parent *Element, local string, attrs ...Attr) *Element {
+		el := &Element{Prefix: "ds", Local: local, Space: nsDS, Attrs: attrs, Parent: parent}
+		parent.Children = append(parent.Children, el)
+		return el
+	}
+
+	h := digestAlgorithms[digestMethod].New()
+	h.Write(canonicalizeEnveloped(e, nil, nil))
+	digest := h.Sum(nil)
+
+	signedInfo := newChild(signature, "SignedInfo")
+	newChild(signedInfo, "CanonicalizationMethod", Attr{Local: "Algorithm", Value: algExcC14N})
+	newChild(signedInfo, "SignatureMethod", Attr{Local: "Algorithm", Value: signatureMethod})
+	reference := newChild(signedInfo, "Reference", Attr{Local: "URI", Value: "#" + id})
+	transforms := newChild(reference, "Transforms")
+	newChild(transforms, "Transform", Attr{Local: "Algorithm", Value: algEnvelopedSignature})
+	newChild(transforms, "Transform", Attr{Local: "Algorithm", Value: algExcC14N})
+	newChild(reference, "DigestMethod", Attr{Local: "Algorithm", Value: digestMethod})
+	newChild(reference, "DigestValue").Children = []any{base64.StdEncoding.EncodeToString(digest)}
+
+	hash := signatureAlgorithms[signatureMethod]
+	h = hash.New()
+	h.Write(Canonicalize(signedInfo, nil))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, hash, h.Sum(nil))
+	if err != nil {
+		return err
+	}
+	newChild(signature, "SignatureValue").Children = []any{base64.StdEncoding.EncodeToString(sig)}
+	if cert != nil {
+		keyInfo := newChild(signature, "KeyInfo")
+		x509Data := newChild(keyInfo, "X509Data")
+		newChild(x509Data, "X509Certificate").Children = []any{base64.StdEncoding.EncodeToString(cert.Raw)}
+	}
+
+	position := 0
+	for i, c := range e.Children {
+		if el, ok := c.(*Element); ok && el.Is(nsSAML, "Issuer") {
+			position = i + 1
+			break
+		}
+	}
+	e.Children = append(e.Children[:position], append([]any{signature}, e.Children[position:]...)...)
+	return nil
+}
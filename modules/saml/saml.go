@@ -0,0 +1,617 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package saml implements the service provider side of SAML 2.0 Web Browser SSO:
+// signed authentication requests with the HTTP-Redirect binding, validation of signed
+// responses with the HTTP-POST binding and IdP-initiated single logout.
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	nsSAMLP = "urn:oasis:names:tc:SAML:2.0:protocol"
+	nsSAML  = "urn:oasis:names:tc:SAML:2.0:assertion"
+	nsMD    = "urn:oasis:names:tc:SAML:2.0:metadata"
+
+	bindingHTTPRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	bindingHTTPPost     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+
+	statusSuccess = "urn:oasis:names:tc:SAML:2.0:status:Success"
+
+	confirmationMethodBearer = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+
+	// NameIDFormatUnspecified lets the identity provider choose the format of the subject identifier
+	NameIDFormatUnspecified = "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"
+	// NameIDFormatPersistent requests an opaque identifier which stays the same across logins
+	NameIDFormatPersistent = "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"
+
+	// maxClockSkew is the tolerated difference between the clocks of the identity provider and this instance
+	maxClockSkew = 3 * time.Minute
+	// maxMessageSize limits inflated messages of the HTTP-Redirect binding
+	maxMessageSize = 1 << 20
+)
+
+var timeNow = time.Now
+
+// ServiceProvider is the configuration of this instance as SAML service provider and of the trusted identity provider
+type ServiceProvider struct {
+	EntityID                    string
+	AssertionConsumerServiceURL string
+	SingleLogoutServiceURL      string
+	NameIDFormat                string
+	// Key and Certificate are used to sign requests, the certificate is published in the metadata
+	Key          *rsa.PrivateKey
+	Certificate  *x509.Certificate
+	SignRequests bool
+
+	IdPEntityID string
+	// IdPSSOURL receives authentication requests with the HTTP-Redirect binding
+	IdPSSOURL string
+	// IdPSLOURL receives logout responses with the HTTP-Redirect binding
+	IdPSLOURL       string
+	IdPCertificates []*x509.Certificate
+	// AllowSHA1 accepts signatures and digests using SHA-1 for identity providers which don't support SHA-256
+	AllowSHA1 bool
+}
+
+// Assertion contains the validated information about the authenticated subject
+type Assertion struct {
+	NameID       string
+	NameIDFormat string
+	SessionIndex string
+	// Attributes maps the attribute names, and friendly names if present, to their values
+	Attributes map[string][]string
+}
+
+// Attribute returns the first value of the attribute
+func (a *Assertion) Attribute(name string) string {
+	if values := a.Attributes[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// LogoutRequest is a validated logout request of the identity provider
+type LogoutRequest struct {
+	ID           string
+	NameID       string
+	SessionIndex []string
+}
+
+// ParseCertificates parses all PEM encoded certificates
+func ParseCertificates(data string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+	return certs, nil
+}
+
+func newID() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	// IDs must not start with a digit
+	return "_" + hex.EncodeToString(b), nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+type xmlIssuer struct {
+	XMLName xml.Name `xml:"saml:Issuer"`
+	Value   string   `xml:",chardata"`
+}
+
+type xmlAuthnRequest struct {
+	XMLName                     xml.Name  `xml:"samlp:AuthnRequest"`
+	XMLNSSAMLP                  string    `xml:"xmlns:samlp,attr"`
+	XMLNSSAML                   string    `xml:"xmlns:saml,attr"`
+	ID                          string    `xml:"ID,attr"`
+	Version                     string    `xml:"Version,attr"`
+	IssueInstant                string    `xml:"IssueInstant,attr"`
+	Destination                 string    `xml:"Destination,attr"`
+	ProtocolBinding             string    `xml:"ProtocolBinding,attr"`
+	AssertionConsumerServiceURL string    `xml:"AssertionConsumerServiceURL,attr"`
+	Issuer                      xmlIssuer `xml:"saml:Issuer"`
+	NameIDPolicy                struct {
+		Format      string `xml:"Format,attr"`
+		AllowCreate bool   `xml:"AllowCreate,attr"`
+	} `xml:"samlp:NameIDPolicy"`
+}
+
+// AuthnRequestURL returns the URL which sends the user to the identity provider
+// with an authentication request, and the ID of the request to validate the response
+func (sp *ServiceProvider) AuthnRequestURL(relayState string) (string, string, error) {
+	id, err := newID()
+	if err != nil {
+		return "", "", err
+	}
+
+	req := xmlAuthnRequest{
+		XMLNSSAMLP:                  nsSAMLP,
+		XMLNSSAML:                   nsSAML,
+		ID:                          id,
+		Version:                     "2.0",
+		IssueInstant:                formatTime(timeNow()),
+		Destination:                 sp.IdPSSOURL,
+		ProtocolBinding:             bindingHTTPPost,
+		AssertionConsumerServiceURL: sp.AssertionConsumerServiceURL,
+		Issuer:                      xmlIssuer{Value: sp.EntityID},
+	}
+	req.NameIDPolicy.Format = sp.nameIDFormat()
+	req.NameIDPolicy.AllowCreate = true
+
+	redirectURL, err := sp.redirectURL(sp.IdPSSOURL, "SAMLRequest", req, relayState)
+	return redirectURL, id, err
+}
+
+type xmlLogoutResponse struct {
+	XMLName      xml.Name  `xml:"samlp:LogoutResponse"`
+	XMLNSSAMLP   string    `xml:"xmlns:samlp,attr"`
+	XMLNSSAML    string    `xml:"xmlns:saml,attr"`
+	ID           string    `xml:"ID,attr"`
+	Version      string    `xml:"Version,attr"`
+	IssueInstant string    `xml:"IssueInstant,attr"`
+	Destination  string    `xml:"Destination,attr"`
+	InResponseTo string    `xml:"InResponseTo,attr"`
+	Issuer       xmlIssuer `xml:"saml:Issuer"`
+	Status       struct {
+		StatusCode struct {
+			Value string `xml:"Value,attr"`
+		} `xml:"samlp:StatusCode"`
+	} `xml:"samlp:Status"`
+}
+
+// LogoutResponseURL returns the URL which sends the user back to the identity provider after the logout
+func (sp *ServiceProvider) LogoutResponseURL(req *LogoutRequest, relayState string) (string, error) {
+	if sp.IdPSLOURL == "" {
+		return "", errors.New("the identity provider has no single logout URL")
+	}
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+
+	resp := xmlLogoutResponse{
+		XMLNSSAMLP:   nsSAMLP,
+		XMLNSSAML:    nsSAML,
+		ID:           id,
+		Version:      "2.0",
+		IssueInstant: formatTime(timeNow()),
+		Destination:  sp.IdPSLOURL,
+		InResponseTo: req.ID,
+		Issuer:       xmlIssuer{Value: sp.EntityID},
+	}
+	resp.Status.StatusCode.Value = statusSuccess
+
+	return sp.redirectURL(sp.IdPSLOURL, "SAMLResponse", resp, relayState)
+}
+
+// redirectURL encodes the message for the HTTP-Redirect binding and signs the query if requests are signed
+func (sp *ServiceProvider) redirectURL(destination, parameter string, message any, relayState string) (string, error) {
+	data, err := xml.Marshal(message)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(data); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	// the signature covers the parameters in this exact order
+	query := parameter + "=" + url.QueryEscape(base64.StdEncoding.EncodeToString(buf.Bytes()))
+	if relayState != "" {
+		query += "&RelayState=" + url.QueryEscape(relayState)
+	}
+	if sp.SignRequests {
+		if sp.Key == nil {
+			return "", errors.New("signing requests requires a private key")
+		}
+		query += "&SigAlg=" + url.QueryEscape(algRSASHA256)
+		hashed := crypto.SHA256.New()
+		hashed.Write([]byte(query))
+		sig, err := rsa.SignPKCS1v15(rand.Reader, sp.Key, crypto.SHA256, hashed.Sum(nil))
+		if err != nil {
+			return "", err
+		}
+		query += "&Signature=" + url.QueryEscape(base64.StdEncoding.EncodeToString(sig))
+	}
+
+	u, err := url.Parse(destination)
+	if err != nil {
+		return "", err
+	}
+	if u.RawQuery != "" {
+		u.RawQuery += "&" + query
+	} else {
+		u.RawQuery = query
+	}
+	return u.String(), nil
+}
+
+func (sp *ServiceProvider) nameIDFormat() string {
+	if sp.NameIDFormat == "" {
+		return NameIDFormatUnspecified
+	}
+	return sp.NameIDFormat
+}
+
+type xmlMetadata struct {
+	XMLName         xml.Name `xml:"md:EntityDescriptor"`
+	XMLNSMD         string   `xml:"xmlns:md,attr"`
+	XMLNSDS         string   `xml:"xmlns:ds,attr"`
+	EntityID        string   `xml:"entityID,attr"`
+	SPSSODescriptor struct {
+		AuthnRequestsSigned        bool              `xml:"AuthnRequestsSigned,attr"`
+		WantAssertionsSigned       bool              `xml:"WantAssertionsSigned,attr"`
+		ProtocolSupportEnumeration string            `xml:"protocolSupportEnumeration,attr"`
+		KeyDescriptor              *xmlKeyDescriptor `xml:"md:KeyDescriptor,omitempty"`
+		SingleLogoutService        *xmlEndpoint      `xml:"md:SingleLogoutService,omitempty"`
+		NameIDFormat               string            `xml:"md:NameIDFormat"`
+		AssertionConsumer          struct {
+			xmlEndpoint
+			Index     int  `xml:"index,attr"`
+			IsDefault bool `xml:"isDefault,attr"`
+		} `xml:"md:AssertionConsumerService"`
+	} `xml:"md:SPSSODescriptor"`
+}
+
+type xmlKeyDescriptor struct {
+	Use     string `xml:"use,attr"`
+	KeyInfo struct {
+		X509Data struct {
+			X509Certificate string `xml:"ds:X509Certificate"`
+		} `xml:"ds:X509Data"`
+	} `xml:"ds:KeyInfo"`
+}
+
+type xmlEndpoint struct {
+	Binding  string `xml:"Binding,attr"`
+	Location string `xml:"Location,attr"`
+}
+
+// Metadata returns the service provider metadata which is imported into the identity provider
+func (sp *ServiceProvider) Metadata() ([]byte, error) {
+	md := xmlMetadata{
+		XMLNSMD:  nsMD,
+		XMLNSDS:  nsDS,
+		EntityID: sp.EntityID,
+	}
+	md.SPSSODescriptor.AuthnRequestsSigned = sp.SignRequests
+	md.SPSSODescriptor.WantAssertionsSigned = true
+	md.SPSSODescriptor.ProtocolSupportEnumeration = nsSAMLP
+	if sp.Certificate != nil {
+		md.SPSSODescriptor.KeyDescriptor = &xmlKeyDescriptor{Use: "signing"}
+		md.SPSSODescriptor.KeyDescriptor.KeyInfo.X509Data.X509Certificate = base64.StdEncoding.EncodeToString(sp.Certificate.Raw)
+	}
+	if sp.SingleLogoutServiceURL != "" {
+		md.SPSSODescriptor.SingleLogoutService = &xmlEndpoint{Binding: bindingHTTPRedirect, Location: sp.SingleLogoutServiceURL}
+	}
+	md.SPSSODescriptor.NameIDFormat = sp.nameIDFormat()
+	md.SPSSODescriptor.AssertionConsumer.Binding = bindingHTTPPost
+	md.SPSSODescriptor.AssertionConsumer.Location = sp.AssertionConsumerServiceURL
+	md.SPSSODescriptor.AssertionConsumer.IsDefault = true
+
+	data, err := xml.MarshalIndent(md, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// ParseResponse validates a base64 encoded response of the HTTP-POST binding and returns its assertion.
+// The response must answer a request of this service provider, consumeRequestID reports
+// whether the request ID is known and must invalidate it.
+func (sp *ServiceProvider) ParseResponse(encoded string, consumeRequestID func(id string) bool) (*Assertion, error) {
+	data, err := decodeBase64(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response encoding: %w", err)
+	}
+	response, err := ParseXML(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	if !response.Is(nsSAMLP, "Response") || response.Attr("Version") != "2.0" {
+		return nil, errors.New("not a SAML 2.0 response")
+	}
+	if destination := response.Attr("Destination"); destination != "" && destination != sp.AssertionConsumerServiceURL {
+		return nil, fmt.Errorf("response is addressed to %s", destination)
+	}
+	if err := sp.checkIssuer(response, false); err != nil {
+		return nil, err
+	}
+
+	status := response.ChildElement(nsSAMLP, "Status")
+	if status == nil {
+		return nil, errors.New("response has no status")
+	}
+	if statusCode := status.ChildElement(nsSAMLP, "StatusCode"); statusCode == nil || statusCode.Attr("Value") != statusSuccess {
+		value := ""
+		if statusCode != nil {
+			value = statusCode.Attr("Value")
+			if nested := statusCode.ChildElement(nsSAMLP, "StatusCode"); nested != nil {
+				value += " " + nested.Attr("Value")
+			}
+		}
+		return nil, fmt.Errorf("authentication failed with status %s", strings.TrimSpace(value))
+	}
+
+	if len(response.ChildElements(nsSAML, "EncryptedAssertion")) > 0 {
+		return nil, errors.New("encrypted assertions are not supported")
+	}
+	assertions := response.ChildElements(nsSAML, "Assertion")
+	if len(assertions) != 1 {
+		return nil, errors.New("response must contain exactly one assertion")
+	}
+	assertion := assertions[0]
+
+	// either the whole response or the assertion must be signed by the identity provider
+	responseErr := VerifySignature(response, sp.IdPCertificates, sp.AllowSHA1)
+	if responseErr != nil && !errors.Is(responseErr, ErrSignatureMissing) {
+		return nil, fmt.Errorf("invalid response signature: %w", responseErr)
+	}
+	assertionErr := VerifySignature(assertion, sp.IdPCertificates, sp.AllowSHA1)
+	if assertionErr != nil && !errors.Is(assertionErr, ErrSignatureMissing) {
+		return nil, fmt.Errorf("invalid assertion signature: %w", assertionErr)
+	}
+	if responseErr != nil && assertionErr != nil {
+		return nil, errors.New("neither the response nor the assertion is signed")
+	}
+
+	inResponseTo := response.Attr("InResponseTo")
+	if err := sp.checkIssuer(assertion, true); err != nil {
+		return nil, err
+	}
+
+	now := timeNow()
+	result := &Assertion{Attributes: make(map[string][]string)}
+
+	subject := assertion.ChildElement(nsSAML, "Subject")
+	if subject == nil {
+		return nil, errors.New("assertion has no subject")
+	}
+	nameID := subject.ChildElement(nsSAML, "NameID")
+	if nameID == nil || nameID.Text() == "" {
+		return nil, errors.New("assertion has no NameID")
+	}
+	result.NameID = nameID.Text()
+	result.NameIDFormat = nameID.Attr("Format")
+
+	confirmed := false
+	for _, confirmation := range subject.ChildElements(nsSAML, "SubjectConfirmation") {
+		if confirmation.Attr("Method") != confirmationMethodBearer {
+			continue
+		}
+		data := confirmation.ChildElement(nsSAML, "SubjectConfirmationData")
+		if data == nil {
+			continue
+		}
+		if recipient := data.Attr("Recipient"); recipient != sp.AssertionConsumerServiceURL {
+			continue
+		}
+		if notOnOrAfter, err := parseTime(data.Attr("NotOnOrAfter")); err != nil || !now.Before(notOnOrAfter.Add(maxClockSkew)) {
+			continue
+		}
+		if id := data.Attr("InResponseTo"); id != "" {
+			if inResponseTo != "" && inResponseTo != id {
+				continue
+			}
+			inResponseTo = id
+		}
+		confirmed = true
+		break
+	}
+	if !confirmed {
+		return nil, errors.New("assertion has no valid bearer subject confirmation")
+	}
+
+	// only responses to requests of this service provider are accepted, which prevents replays
+	if inResponseTo == "" || !consumeRequestID(inResponseTo) {
+		return nil, errors.New("response does not answer a pending request")
+	}
+
+	if conditions := assertion.ChildElement(nsSAML, "Conditions"); conditions != nil {
+		if v := conditions.Attr("NotBefore"); v != "" {
+			notBefore, err := parseTime(v)
+			if err != nil || now.Add(maxClockSkew).Before(notBefore) {
+				return nil, errors.New("assertion is not yet valid")
+			}
+		}
+		if v := conditions.Attr("NotOnOrAfter"); v != "" {
+			notOnOrAfter, err := parseTime(v)
+			if err != nil || !now.Before(notOnOrAfter.Add(maxClockSkew)) {
+				return nil, errors.New("assertion has expired")
+			}
+		}
+		for _, restriction := range conditions.ChildElements(nsSAML, "AudienceRestriction") {
+			audiences := make([]string, 0, 1)
+			for _, audience := range restriction.ChildElements(nsSAML, "Audience") {
+				audiences = append(audiences, audience.Text())
+			}
+			if !slices.Contains(audiences, sp.EntityID) {
+				return nil, errors.New("assertion is intended for another audience")
+			}
+		}
+	}
+
+	if authnStatement := assertion.ChildElement(nsSAML, "AuthnStatement"); authnStatement != nil {
+		result.SessionIndex = authnStatement.Attr("SessionIndex")
+	}
+
+	for _, statement := range assertion.ChildElements(nsSAML, "AttributeStatement") {
+		for _, attribute := range statement.ChildElements(nsSAML, "Attribute") {
+			var values []string
+			for _, value := range attribute.ChildElements(nsSAML, "AttributeValue") {
+				values = append(values, value.Text())
+			}
+			for _, name := range []string{attribute.Attr("Name"), attribute.Attr("FriendlyName")} {
+				if name != "" {
+					result.Attributes[name] = append(result.Attributes[name], values...)
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// checkIssuer validates the issuer of the element, the issuer of responses is optional
+func (sp *ServiceProvider) checkIssuer(e *Element, required bool) error {
+	issuer := e.ChildElement(nsSAML, "Issuer")
+	if issuer == nil {
+		if required {
+			return errors.New("issuer is missing")
+		}
+		return nil
+	}
+	if issuer.Text() != sp.IdPEntityID {
+		return fmt.Errorf("unexpected issuer %s", issuer.Text())
+	}
+	return nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// ParseLogoutRequest validates a logout request of the identity provider received with the HTTP-Redirect binding.
+// The raw query is needed because the signature covers the parameters as they were encoded by the identity provider.
+func (sp *ServiceProvider) ParseLogoutRequest(rawQuery string) (*LogoutRequest, error) {
+	raw := make(map[string]string, 4)
+	for _, part := range strings.Split(rawQuery, "&") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "SAMLRequest", "RelayState", "SigAlg", "Signature":
+			if _, ok := raw[key]; ok {
+				return nil, fmt.Errorf("duplicate parameter %s", key)
+			}
+			raw[key] = value
+		}
+	}
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		unescaped, err := url.QueryUnescape(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter %s: %w", key, err)
+		}
+		values[key] = unescaped
+	}
+	if values["SAMLRequest"] == "" {
+		return nil, errors.New("request is missing")
+	}
+
+	// logout requests must be signed, otherwise anybody could end the sessions of users
+	hash, ok := signatureAlgorithms[values["SigAlg"]]
+	if !ok {
+		return nil, fmt.Errorf("unsupported signature algorithm: %s", values["SigAlg"])
+	} else if hash == crypto.SHA1 && !sp.AllowSHA1 {
+		return nil, fmt.Errorf("signature algorithm %s: %w", values["SigAlg"], ErrSHA1NotAllowed)
+	}
+	sig, err := base64.StdEncoding.DecodeString(values["Signature"])
+	if err != nil || len(sig) == 0 {
+		return nil, errors.New("request signature is missing")
+	}
+	signed := "SAMLRequest=" + raw["SAMLRequest"]
+	if _, ok := raw["RelayState"]; ok {
+		signed += "&RelayState=" + raw["RelayState"]
+	}
+	signed += "&SigAlg=" + raw["SigAlg"]
+	h := hash.New()
+	h.Write([]byte(signed))
+	hashed := h.Sum(nil)
+	verified := false
+	for _, cert := range sp.IdPCertificates {
+		if verifyHash(cert.PublicKey, hash, hashed, sig) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("request signature does not match any trusted certificate")
+	}
+
+	compressed, err := base64.StdEncoding.DecodeString(values["SAMLRequest"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid request encoding: %w", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(compressed)), maxMessageSize))
+	if err != nil {
+		return nil, fmt.Errorf("invalid request encoding: %w", err)
+	}
+	request, err := ParseXML(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if !request.Is(nsSAMLP, "LogoutRequest") || request.Attr("Version") != "2.0" {
+		return nil, errors.New("not a SAML 2.0 logout request")
+	}
+	if destination := request.Attr("Destination"); destination != "" && destination != sp.SingleLogoutServiceURL {
+		return nil, fmt.Errorf("request is addressed to %s", destination)
+	}
+	if err := sp.checkIssuer(request, true); err != nil {
+		return nil, err
+	}
+	if v := request.Attr("NotOnOrAfter"); v != "" {
+		notOnOrAfter, err := parseTime(v)
+		if err != nil || !timeNow().Before(notOnOrAfter.Add(maxClockSkew)) {
+			return nil, errors.New("request has expired")
+		}
+	}
+
+	result := &LogoutRequest{ID: request.Attr("ID")}
+	if nameID := request.ChildElement(nsSAML, "NameID"); nameID != nil {
+		result.NameID = nameID.Text()
+	}
+	if result.ID == "" || result.NameID == "" {
+		return nil, errors.New("request has no ID or NameID")
+	}
+	for _, sessionIndex := range request.ChildElements(nsSAMLP, "SessionIndex") {
+		result.SessionIndex = append(result.SessionIndex, sessionIndex.Text())
+	}
+	return result, nil
+}
@@ -0,0 +1,385 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKeyPair(t *testing.T) (*rsa.PrivateKey, *x509.Certificate) {
+	keyPEM, certPEM, err := GenerateKeyPair("test")
+	require.NoError(t, err)
+	key, err := ParsePrivateKey(keyPEM)
+	require.NoError(t, err)
+	certs, err := ParseCertificates(certPEM)
+	require.NoError(t, err)
+	return key, certs[0]
+}
+
+func newTestServiceProvider(t *testing.T) (*ServiceProvider, *rsa.PrivateKey) {
+	spKey, spCert := newTestKeyPair(t)
+	idpKey, idpCert := newTestKeyPair(t)
+	return &ServiceProvider{
+		EntityID:                    "https://forge.example.com/user/saml/corp/metadata",
+		AssertionConsumerServiceURL: "https://forge.example.com/user/saml/corp/acs",
+		SingleLogoutServiceURL:      "https://forge.example.com/user/saml/corp/slo",
+		Key:                         spKey,
+		Certificate:                 spCert,
+		SignRequests:                true,
+		IdPEntityID:                 "https://idp.example.com",
+		IdPSSOURL:                   "https://idp.example.com/sso",
+		IdPSLOURL:                   "https://idp.example.com/slo",
+		IdPCertificates:             []*x509.Certificate{idpCert},
+	}, idpKey
+}
+
+const testResponse = `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" ID="_response" Version="2.0" IssueInstant="%[1]s" Destination="https://forge.example.com/user/saml/corp/acs" InResponseTo="_request">
+  <saml:Issuer xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion">https://idp.example.com</saml:Issuer>
+  <samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status>
+  <saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" xmlns:xs="http://www.w3.org/2001/XMLSchema" ID="_assertion" Version="2.0" IssueInstant="%[1]s">
+    <saml:Issuer>https://idp.example.com</saml:Issuer>
+    <saml:Subject>
+      <saml:NameID Format="urn:oasis:names:tc:SAML:2.0:nameid-format:persistent">jdoe</saml:NameID>
+      <saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">
+        <saml:SubjectConfirmationData NotOnOrAfter="%[2]s" Recipient="https://forge.example.com/user/saml/corp/acs" InResponseTo="_request"/>
+      </saml:SubjectConfirmation>
+    </saml:Subject>
+    <saml:Conditions NotBefore="%[1]s" NotOnOrAfter="%[2]s">
+      <saml:AudienceRestriction><saml:Audience>%[3]s</saml:Audience></saml:AudienceRestriction>
+    </saml:Conditions>
+    <saml:AuthnStatement AuthnInstant="%[1]s" SessionIndex="_session"/>
+    <saml:AttributeStatement>
+      <saml:Attribute Name="urn:oid:0.9.2342.19200300.100.1.3" FriendlyName="mail"><saml:AttributeValue>jdoe@example.com</saml:AttributeValue></saml:Attribute>
+      <saml:Attribute Name="groups"><saml:AttributeValue>developers</saml:AttributeValue><saml:AttributeValue>admins</saml:AttributeValue></saml:Attribute>
+    </saml:AttributeStatement>
+  </saml:Assertion>
+</samlp:Response>`
+
+func buildTestResponse(t *testing.T, audience string, sign func(response, assertion *Element)) string {
+	now := time.Now()
+	doc, err := ParseXML([]byte(fmt.Sprintf(testResponse, formatTime(now), formatTime(now.Add(5*time.Minute)), audience)))
+	require.NoError(t, err)
+	sign(doc, doc.ChildElement(nsSAML, "Assertion"))
+	return base64.StdEncoding.EncodeToString(Canonicalize(doc, nil))
+}
+
+func TestParseResponse(t *testing.T) {
+	sp, idpKey := newTestServiceProvider(t)
+	consume := func(id string) bool { return id == "_request" }
+
+	t.Run("SignedAssertion", func(t *testing.T) {
+		encoded := buildTestResponse(t, sp.EntityID, func(_, assertion *Element) {
+			require.NoError(t, SignEnveloped(assertion, idpKey, nil))
+		})
+		assertion, err := sp.ParseResponse(encoded, consume)
+		require.NoError(t, err)
+		assert.Equal(t, "jdoe", assertion.NameID)
+		assert.Equal(t, NameIDFormatPersistent, assertion.NameIDFormat)
+		assert.Equal(t, "_session", assertion.SessionIndex)
+		assert.Equal(t, "jdoe@example.com", assertion.Attribute("mail"))
+		assert.Equal(t, "jdoe@example.com", assertion.Attribute("urn:oid:0.9.2342.19200300.100.1.3"))
+		assert.Equal(t, []string{"developers", "admins"}, assertion.Attributes["groups"])
+	})
+
+	t.Run("SignedResponse", func(t *testing.T) {
+		encoded := buildTestResponse(t, sp.EntityID, func(response, _ *Element) {
+			require.NoError(t, SignEnveloped(response, idpKey, nil))
+		})
+		_, err := sp.ParseResponse(encoded, consume)
+		require.NoError(t, err)
+	})
+
+	t.Run("Unsigned", func(t *testing.T) {
+		encoded := buildTestResponse(t, sp.EntityID, func(_, _ *Element) {})
+		_, err := sp.ParseResponse(encoded, consume)
+		assert.ErrorContains(t, err, "neither the response nor the assertion is signed")
+	})
+
+	t.Run("UntrustedKey", func(t *testing.T) {
+		otherKey, _ := newTestKeyPair(t)
+		encoded := buildTestResponse(t, sp.EntityID, func(_, assertion *Element) {
+			require.NoError(t, SignEnveloped(assertion, otherKey, nil))
+		})
+		_, err := sp.ParseResponse(encoded, consume)
+		assert.ErrorContains(t, err, "does not match any trusted certificate")
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		encoded := buildTestResponse(t, sp.EntityID, func(_, assertion *Element) {
+			require.NoError(t, SignEnveloped(assertion, idpKey, nil))
+			nameID := assertion.ChildElement(nsSAML, "Subject").ChildElement(nsSAML, "NameID")
+			nameID.Children = []any{"admin"}
+		})
+		_, err := sp.ParseResponse(encoded, consume)
+		assert.ErrorContains(t, err, "digest of the signed element does not match")
+	})
+
+	t.Run("Wrapped", func(t *testing.T) {
+		encoded := buildTestResponse(t, sp.EntityID, func(response, assertion *Element) {
+			require.NoError(t, SignEnveloped(assertion, idpKey, nil))
+			// hide a copy of the signed assertion and replace the original
+			copied := *assertion
+			response.Children = append(response.Children, &Element{Prefix: "samlp", Local: "Extensions", Space: nsSAMLP, Children: []any{&copied}, Parent: response})
+			assertion.ChildElement(nsSAML, "Subject").ChildElement(nsSAML, "NameID").Children = []any{"admin"}
+		})
+		_, err := sp.ParseResponse(encoded, consume)
+		assert.Error(t, err)
+	})
+
+	t.Run("DuplicatedAssertion", func(t *testing.T) {
+		encoded := buildTestResponse(t, sp.EntityID, func(response, assertion *Element) {
+			require.NoError(t, SignEnveloped(assertion, idpKey, nil))
+			// add an unsigned assertion next to the signed one
+			copied := *assertion
+			copied.Attrs = []Attr{{Local: "ID", Value: "_evil"}, {Local: "Version", Value: "2.0"}}
+			copied.Children = slices.DeleteFunc(slices.Clone(assertion.Children), func(c any) bool {
+				el, ok := c.(*Element)
+				return ok && el.Is(nsDS, "Signature")
+			})
+			response.Children = append(response.Children, &copied)
+		})
+		_, err := sp.ParseResponse(encoded, consume)
+		assert.ErrorContains(t, err, "exactly one assertion")
+	})
+
+	t.Run("DuplicatedID", func(t *testing.T) {
+		encoded := buildTestResponse(t, sp.EntityID, func(response, assertion *Element) {
+			require.NoError(t, SignEnveloped(assertion, idpKey, nil))
+			// an element elsewhere in the document with the ID of the signed assertion
+			response.ChildElement(nsSAMLP, "Status").Attrs = []Attr{{Local: "ID", Value: "_assertion"}}
+		})
+		_, err := sp.ParseResponse(encoded, consume)
+		assert.ErrorContains(t, err, "signed element ID is not unique")
+	})
+
+	t.Run("SecondSignature", func(t *testing.T) {
+		encoded := buildTestResponse(t, sp.EntityID, func(_, assertion *Element) {
+			require.NoError(t, SignEnveloped(assertion, idpKey, nil))
+			signature := assertion.ChildElement(nsDS, "Signature")
+			copied := *signature
+			assertion.Children = append(assertion.Children, &copied)
+		})
+		_, err := sp.ParseResponse(encoded, consume)
+		assert.ErrorContains(t, err, "multiple signatures")
+	})
+
+	t.Run("ReferenceToOtherElement", func(t *testing.T) {
+		encoded := buildTestResponse(t, sp.EntityID, func(response, assertion *Element) {
+			// move the valid signature of the response into the assertion
+			require.NoError(t, SignEnveloped(response, idpKey, nil))
+			signature := response.ChildElement(nsDS, "Signature")
+			response.Children = slices.DeleteFunc(response.Children, func(c any) bool { return c == signature })
+			signature.Parent = assertion
+			assertion.Children = append([]any{signature}, assertion.Children...)
+		})
+		_, err := sp.ParseResponse(encoded, consume)
+		assert.ErrorContains(t, err, "signature does not reference the signed element")
+	})
+
+	t.Run("CommentInNameID", func(t *testing.T) {
+		encoded := buildTestResponse(t, sp.EntityID, func(_, assertion *Element) {
+			require.NoError(t, SignEnveloped(assertion, idpKey, nil))
+		})
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		require.NoError(t, err)
+		// comments aren't signed, they must not truncate the NameID
+		data = bytes.Replace(data, []byte(">jdoe<"), []byte(">jd<!-- injected -->oe<"), 1)
+		assertion, err := sp.ParseResponse(base64.StdEncoding.EncodeToString(data), consume)
+		require.NoError(t, err)
+		assert.Equal(t, "jdoe", assertion.NameID)
+	})
+
+	t.Run("CanonicalizationWithComments", func(t *testing.T) {
+		encoded := buildTestResponse(t, sp.EntityID, func(_, assertion *Element) {
+			require.NoError(t, SignEnveloped(assertion, idpKey, nil))
+			method := assertion.ChildElement(nsDS, "Signature").ChildElement(nsDS, "SignedInfo").ChildElement(nsDS, "CanonicalizationMethod")
+			method.Attrs = []Attr{{Local: "Algorithm", Value: algExcC14N + "WithComments"}}
+		})
+		_, err := sp.ParseResponse(encoded, consume)
+		assert.ErrorContains(t, err, "unsupported canonicalization method")
+	})
+
+	t.Run("WrongAudience", func(t *testing.T) {
+		encoded := buildTestResponse(t, "https://other.example.com", func(_, assertion *Element) {
+			require.NoError(t, SignEnveloped(assertion, idpKey, nil))
+		})
+		_, err := sp.ParseResponse(encoded, consume)
+		assert.ErrorContains(t, err, "another audience")
+	})
+
+	t.Run("UnknownRequest", func(t *testing.T) {
+		encoded := buildTestResponse(t, sp.EntityID, func(_, assertion *Element) {
+			require.NoError(t, SignEnveloped(assertion, idpKey, nil))
+		})
+		_, err := sp.ParseResponse(encoded, func(string) bool { return false })
+		assert.ErrorContains(t, err, "does not answer a pending request")
+	})
+
+	t.Run("SHA1", func(t *testing.T) {
+		encoded := buildTestResponse(t, sp.EntityID, func(_, assertion *Element) {
+			require.NoError(t, signEnveloped(assertion, idpKey, nil, algRSASHA1, algDigestSHA1))
+		})
+		_, err := sp.ParseResponse(encoded, consume)
+		assert.ErrorIs(t, err, ErrSHA1NotAllowed)
+
+		// a SHA-256 signature with a SHA-1 digest is refused as well
+		encoded = buildTestResponse(t, sp.EntityID, func(_, assertion *Element) {
+			require.NoError(t, signEnveloped(assertion, idpKey, nil, algRSASHA256, algDigestSHA1))
+		})
+		_, err = sp.ParseResponse(encoded, consume)
+		assert.ErrorIs(t, err, ErrSHA1NotAllowed)
+
+		sp.AllowSHA1 = true
+		defer func() { sp.AllowSHA1 = false }()
+		_, err = sp.ParseResponse(encoded, consume)
+		require.NoError(t, err)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		encoded := buildTestResponse(t, sp.EntityID, func(_, assertion *Element) {
+			require.NoError(t, SignEnveloped(assertion, idpKey, nil))
+		})
+		defer func() { timeNow = time.Now }()
+		timeNow = func() time.Time { return time.Now().Add(time.Hour) }
+		_, err := sp.ParseResponse(encoded, consume)
+		assert.Error(t, err)
+	})
+}
+
+func TestCanonicalize(t *testing.T) {
+	doc, err := ParseXML([]byte(`<a:root xmlns:a="urn:a" xmlns:b="urn:b" xmlns:unused="urn:unused"><b:child z="1" b:y="2" a="&quot;"/><!-- comment --><a:text>x &amp; y</a:text></a:root>`))
+	require.NoError(t, err)
+	assert.Equal(t,
+		`<a:root xmlns:a="urn:a"><b:child xmlns:b="urn:b" a="&quot;" z="1" b:y="2"></b:child><a:text>x &amp; y</a:text></a:root>`,
+		string(Canonicalize(doc, nil)))
+
+	child := doc.ChildElement("urn:b", "child")
+	assert.Equal(t, `<b:child xmlns:a="urn:a" xmlns:b="urn:b" a="&quot;" z="1" b:y="2"></b:child>`, string(Canonicalize(child, []string{"a"})))
+
+	_, err = ParseXML([]byte(`<!DOCTYPE root [<!ENTITY e "x">]><root>&e;</root>`))
+	assert.Error(t, err)
+}
+
+func inflate(t *testing.T, encoded string) *Element {
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	require.NoError(t, err)
+	data, err := io.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+	require.NoError(t, err)
+	e, err := ParseXML(data)
+	require.NoError(t, err)
+	return e
+}
+
+func TestAuthnRequestURL(t *testing.T) {
+	sp, _ := newTestServiceProvider(t)
+
+	redirect, id, err := sp.AuthnRequestURL("state")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(redirect, sp.IdPSSOURL+"?SAMLRequest="))
+
+	u, err := url.Parse(redirect)
+	require.NoError(t, err)
+	query := u.Query()
+	assert.Equal(t, "state", query.Get("RelayState"))
+	assert.Equal(t, algRSASHA256, query.Get("SigAlg"))
+
+	req := inflate(t, query.Get("SAMLRequest"))
+	assert.True(t, req.Is(nsSAMLP, "AuthnRequest"))
+	assert.Equal(t, id, req.Attr("ID"))
+	assert.Equal(t, sp.AssertionConsumerServiceURL, req.Attr("AssertionConsumerServiceURL"))
+	assert.Equal(t, sp.EntityID, req.ChildElement(nsSAML, "Issuer").Text())
+
+	signed, _, _ := strings.Cut(u.RawQuery, "&Signature=")
+	sig, err := base64.StdEncoding.DecodeString(query.Get("Signature"))
+	require.NoError(t, err)
+	hashed := crypto.SHA256.New()
+	hashed.Write([]byte(signed))
+	assert.NoError(t, rsa.VerifyPKCS1v15(&sp.Key.PublicKey, crypto.SHA256, hashed.Sum(nil), sig))
+}
+
+func TestParseLogoutRequest(t *testing.T) {
+	sp, idpKey := newTestServiceProvider(t)
+
+	buildQuery := func(key *rsa.PrivateKey, sigAlg string) string {
+		logoutRequest := `<samlp:LogoutRequest xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_logout" Version="2.0" IssueInstant="` + formatTime(time.Now()) + `" Destination="` + sp.SingleLogoutServiceURL + `"><saml:Issuer>https://idp.example.com</saml:Issuer><saml:NameID>jdoe</saml:NameID><samlp:SessionIndex>_session</samlp:SessionIndex></samlp:LogoutRequest>`
+		var buf bytes.Buffer
+		w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+		_, _ = w.Write([]byte(logoutRequest))
+		_ = w.Close()
+
+		query := "SAMLRequest=" + url.QueryEscape(base64.StdEncoding.EncodeToString(buf.Bytes())) + "&SigAlg=" + url.QueryEscape(sigAlg)
+		hash := signatureAlgorithms[sigAlg]
+		hashed := hash.New()
+		hashed.Write([]byte(query))
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, hash, hashed.Sum(nil))
+		require.NoError(t, err)
+		return query + "&Signature=" + url.QueryEscape(base64.StdEncoding.EncodeToString(sig))
+	}
+
+	req, err := sp.ParseLogoutRequest(buildQuery(idpKey, algRSASHA256))
+	require.NoError(t, err)
+	assert.Equal(t, "_logout", req.ID)
+	assert.Equal(t, "jdoe", req.NameID)
+	assert.Equal(t, []string{"_session"}, req.SessionIndex)
+
+	redirect, err := sp.LogoutResponseURL(req, "")
+	require.NoError(t, err)
+	u, err := url.Parse(redirect)
+	require.NoError(t, err)
+	resp := inflate(t, u.Query().Get("SAMLResponse"))
+	assert.True(t, resp.Is(nsSAMLP, "LogoutResponse"))
+	assert.Equal(t, "_logout", resp.Attr("InResponseTo"))
+
+	otherKey, _ := newTestKeyPair(t)
+	_, err = sp.ParseLogoutRequest(buildQuery(otherKey, algRSASHA256))
+	assert.Error(t, err)
+
+	query := buildQuery(idpKey, algRSASHA256)
+	_, err = sp.ParseLogoutRequest(query[:strings.Index(query, "&Signature=")])
+	assert.Error(t, err)
+
+	_, err = sp.ParseLogoutRequest(buildQuery(idpKey, algRSASHA1))
+	assert.ErrorIs(t, err, ErrSHA1NotAllowed)
+}
+
+func TestMetadata(t *testing.T) {
+	sp, _ := newTestServiceProvider(t)
+
+	data, err := sp.Metadata()
+	require.NoError(t, err)
+	md, err := ParseXML(data)
+	require.NoError(t, err)
+	assert.True(t, md.Is(nsMD, "EntityDescriptor"))
+	assert.Equal(t, sp.EntityID, md.Attr("entityID"))
+
+	descriptor := md.ChildElement(nsMD, "SPSSODescriptor")
+	require.NotNil(t, descriptor)
+	assert.Equal(t, "true", descriptor.Attr("AuthnRequestsSigned"))
+	acs := descriptor.ChildElement(nsMD, "AssertionConsumerService")
+	require.NotNil(t, acs)
+	assert.Equal(t, sp.AssertionConsumerServiceURL, acs.Attr("Location"))
+	assert.Equal(t, bindingHTTPPost, acs.Attr("Binding"))
+	slo := descriptor.ChildElement(nsMD, "SingleLogoutService")
+	require.NotNil(t, slo)
+	assert.Equal(t, sp.SingleLogoutServiceURL, slo.Attr("Location"))
+
+	certificate := descriptor.ChildElement(nsMD, "KeyDescriptor").ChildElement(nsDS, "KeyInfo").ChildElement(nsDS, "X509Data").ChildElement(nsDS, "X509Certificate")
+	assert.Equal(t, base64.StdEncoding.EncodeToString(sp.Certificate.Raw), certificate.Text())
+}
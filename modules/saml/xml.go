@@ -0,0 +1,196 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package saml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const nsXML = "http://www.w3.org/XML/1998/namespace"
+
+// Attr is an attribute of an element
+type Attr struct {
+	Prefix string
+	Local  string
+	// Space is the resolved namespace of the attribute, attributes without prefix have no namespace
+	Space string
+	Value string
+}
+
+// Element is a node of a parsed XML document which keeps the namespace prefixes as written,
+// so it can be canonicalized for signature verification.
+type Element struct {
+	Prefix string
+	Local  string
+	// Space is the resolved namespace of the element
+	Space string
+	Attrs []Attr
+	// NSDecls are the namespace declarations of the element, Local is the declared prefix and empty for the default namespace
+	NSDecls  []Attr
+	Children []any // *Element or string
+	Parent   *Element
+}
+
+// ParseXML parses a document into its root element.
+// Document type declarations are rejected, comments and processing instructions are dropped.
+func ParseXML(data []byte) (*Element, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+
+	var root, current *Element
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if current == nil && root != nil {
+				return nil, errors.New("multiple root elements")
+			}
+			el := &Element{
+				Prefix: t.Name.Space,
+				Local:  t.Name.Local,
+				Parent: current,
+			}
+			for _, a := range t.Attr {
+				switch {
+				case a.Name.Space == "xmlns":
+					el.NSDecls = append(el.NSDecls, Attr{Local: a.Name.Local, Value: a.Value})
+				case a.Name.Space == "" && a.Name.Local == "xmlns":
+					el.NSDecls = append(el.NSDecls, Attr{Value: a.Value})
+				default:
+					el.Attrs = append(el.Attrs, Attr{Prefix: a.Name.Space, Local: a.Name.Local, Value: a.Value})
+				}
+			}
+			var ok bool
+			if el.Space, ok = el.LookupNamespace(el.Prefix); !ok {
+				return nil, fmt.Errorf("undeclared namespace prefix: %s", el.Prefix)
+			}
+			for i := range el.Attrs {
+				if el.Attrs[i].Prefix == "" {
+					continue
+				}
+				if el.Attrs[i].Space, ok = el.LookupNamespace(el.Attrs[i].Prefix); !ok {
+					return nil, fmt.Errorf("undeclared namespace prefix: %s", el.Attrs[i].Prefix)
+				}
+			}
+
+			if current == nil {
+				root = el
+			} else {
+				current.Children = append(current.Children, el)
+			}
+			current = el
+		case xml.EndElement:
+			if current == nil || current.Prefix != t.Name.Space || current.Local != t.Name.Local {
+				return nil, fmt.Errorf("unexpected end element: %s", t.Name.Local)
+			}
+			current = current.Parent
+		case xml.CharData:
+			if current != nil {
+				current.Children = append(current.Children, string(t))
+			} else if len(bytes.TrimSpace(t)) > 0 {
+				return nil, errors.New("character data outside of the root element")
+			}
+		case xml.Directive:
+			return nil, errors.New("document type declarations are not allowed")
+		}
+	}
+	if root == nil {
+		return nil, errors.New("empty document")
+	}
+	if current != nil {
+		return nil, errors.New("unexpected end of document")
+	}
+	return root, nil
+}
+
+// LookupNamespace returns the namespace bound to the prefix in the scope of the element
+func (e *Element) LookupNamespace(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return nsXML, true
+	}
+	for el := e; el != nil; el = el.Parent {
+		for _, ns := range el.NSDecls {
+			if ns.Local == prefix {
+				return ns.Value, true
+			}
+		}
+	}
+	// the default namespace is empty unless declared
+	return "", prefix == ""
+}
+
+// Is returns true if the element has the namespace and local name
+func (e *Element) Is(space, local string) bool {
+	return e.Space == space && e.Local == local
+}
+
+// Attr returns the value of the attribute without namespace
+func (e *Element) Attr(local string) string {
+	for _, a := range e.Attrs {
+		if a.Space == "" && a.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// ChildElements returns the child elements with the namespace and local name
+func (e *Element) ChildElements(space, local string) []*Element {
+	var elements []*Element
+	for _, c := range e.Children {
+		if el, ok := c.(*Element); ok && el.Is(space, local) {
+			elements = append(elements, el)
+		}
+	}
+	return elements
+}
+
+// ChildElement returns the first child element with the namespace and local name
+func (e *Element) ChildElement(space, local string) *Element {
+	for _, c := range e.Children {
+		if el, ok := c.(*Element); ok && el.Is(space, local) {
+			return el
+		}
+	}
+	return nil
+}
+
+// Text returns the trimmed character data of the element
+func (e *Element) Text() string {
+	var sb strings.Builder
+	for _, c := range e.Children {
+		if s, ok := c.(string); ok {
+			sb.WriteString(s)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// walk calls fn for the element and all its descendants
+func (e *Element) walk(fn func(*Element)) {
+	fn(e)
+	for _, c := range e.Children {
+		if el, ok := c.(*Element); ok {
+			el.walk(fn)
+		}
+	}
+}
+
+// root returns the root element of the document
+func (e *Element) root() *Element {
+	for e.Parent != nil {
+		e = e.Parent
+	}
+	return e
+}
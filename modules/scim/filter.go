@@ -0,0 +1,112 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package scim
+
+import (
+	"strconv"
+	"strings"
+
+	"code.gitea.io/gitea/modules/json"
+)
+
+// Filter is an equality filter on an attribute, the only kind of filter identity providers use for provisioning.
+// Attribute names are case-insensitive and therefore stored in lower case.
+type Filter struct {
+	Attribute string
+	Value     string
+}
+
+// ParseFilter parses a filter expression of the form `attribute eq "value"`
+func ParseFilter(s string) (*Filter, error) {
+	attribute, rest, _ := strings.Cut(strings.TrimSpace(s), " ")
+	op, rest, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	rest = strings.TrimSpace(rest)
+	if attribute == "" || rest == "" {
+		return nil, newBadRequestError(ErrorTypeInvalidFilter, "invalid filter %q", s)
+	}
+	if !strings.EqualFold(op, "eq") {
+		return nil, newBadRequestError(ErrorTypeInvalidFilter, "unsupported filter operator %q", op)
+	}
+
+	var value string
+	if strings.HasPrefix(rest, `"`) {
+		if err := json.Unmarshal([]byte(rest), &value); err != nil {
+			return nil, newBadRequestError(ErrorTypeInvalidFilter, "invalid filter value %s", rest)
+		}
+	} else if rest == "true" || rest == "false" {
+		value = rest
+	} else if _, err := strconv.ParseFloat(rest, 64); err == nil {
+		value = rest
+	} else {
+		return nil, newBadRequestError(ErrorTypeInvalidFilter, "invalid filter value %s", rest)
+	}
+
+	return &Filter{
+		Attribute: stripSchema(strings.ToLower(attribute)),
+		Value:     value,
+	}, nil
+}
+
+// Path is the target of a patch operation of the form `attribute[filter].subAttribute`.
+// Attribute names are stored in lower case.
+type Path struct {
+	Attribute    string
+	Filter       *Filter
+	SubAttribute string
+}
+
+// ParsePath parses the path of a patch operation
+func ParsePath(s string) (*Path, error) {
+	p := &Path{}
+	rest := stripSchema(strings.TrimSpace(s))
+
+	if before, after, ok := strings.Cut(rest, "["); ok {
+		expr, after, ok := strings.Cut(after, "]")
+		if !ok {
+			return nil, newBadRequestError(ErrorTypeInvalidPath, "invalid path %q", s)
+		}
+		filter, err := ParseFilter(expr)
+		if err != nil {
+			return nil, newBadRequestError(ErrorTypeInvalidPath, "invalid path %q", s)
+		}
+		p.Filter = filter
+		p.Attribute = before
+		rest = after
+		if rest != "" && !strings.HasPrefix(rest, ".") {
+			return nil, newBadRequestError(ErrorTypeInvalidPath, "invalid path %q", s)
+		}
+		p.SubAttribute = strings.TrimPrefix(rest, ".")
+	} else {
+		p.Attribute, p.SubAttribute, _ = strings.Cut(rest, ".")
+	}
+
+	p.Attribute = strings.ToLower(p.Attribute)
+	p.SubAttribute = strings.ToLower(p.SubAttribute)
+	if p.Attribute == "" || strings.ContainsAny(p.Attribute+p.SubAttribute, " .[]\"") {
+		return nil, newBadRequestError(ErrorTypeInvalidPath, "invalid path %q", s)
+	}
+	return p, nil
+}
+
+// String returns the attribute and the sub attribute of the path joined by a dot
+func (p *Path) String() string {
+	if p.SubAttribute == "" {
+		return p.Attribute
+	}
+	return p.Attribute + "." + p.SubAttribute
+}
+
+// stripSchema removes the schema URN of a fully qualified attribute name like
+// `urn:ietf:params:scim:schemas:core:2.0:User:name.givenName`
+func stripSchema(s string) string {
+	if !strings.HasPrefix(strings.ToLower(s), "urn:") {
+		return s
+	}
+	// the attribute path may contain a filter with colons in its value
+	end := strings.IndexByte(s, '[')
+	if end < 0 {
+		end = len(s)
+	}
+	return s[strings.LastIndexByte(s[:end], ':')+1:]
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package scim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilter(t *testing.T) {
+	cases := []struct {
+		filter   string
+		expected *Filter
+	}{
+		{`userName eq "alice@example.com"`, &Filter{Attribute: "username", Value: "alice@example.com"}},
+		{`displayName EQ "Dev Team"`, &Filter{Attribute: "displayname", Value: "Dev Team"}},
+		{`emails.value eq "a\"b"`, &Filter{Attribute: "emails.value", Value: `a"b`}},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "bob"`, &Filter{Attribute: "username", Value: "bob"}},
+		{`active eq true`, &Filter{Attribute: "active", Value: "true"}},
+	}
+	for _, c := range cases {
+		t.Run(c.filter, func(t *testing.T) {
+			f, err := ParseFilter(c.filter)
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, f)
+		})
+	}
+
+	for _, filter := range []string{
+		``,
+		`userName`,
+		`userName eq`,
+		`userName sw "a"`,
+		`userName eq "a" and active eq true`,
+		`userName eq alice`,
+	} {
+		t.Run(filter, func(t *testing.T) {
+			_, err := ParseFilter(filter)
+			var badRequest *BadRequestError
+			require.ErrorAs(t, err, &badRequest)
+			assert.Equal(t, ErrorTypeInvalidFilter, badRequest.ScimType)
+		})
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	cases := []struct {
+		path     string
+		expected *Path
+	}{
+		{`active`, &Path{Attribute: "active"}},
+		{`name.givenName`, &Path{Attribute: "name", SubAttribute: "givenname"}},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:name.formatted`, &Path{Attribute: "name", SubAttribute: "formatted"}},
+		{`members[value eq "42"]`, &Path{Attribute: "members", Filter: &Filter{Attribute: "value", Value: "42"}}},
+		{`emails[type eq "work"].value`, &Path{Attribute: "emails", Filter: &Filter{Attribute: "type", Value: "work"}, SubAttribute: "value"}},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			p, err := ParsePath(c.path)
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, p)
+		})
+	}
+
+	for _, path := range []string{
+		``,
+		`members[value eq "42"`,
+		`members[value]`,
+		`emails[type eq "work"]value`,
+		`name.a.b`,
+	} {
+		t.Run(path, func(t *testing.T) {
+			_, err := ParsePath(path)
+			var badRequest *BadRequestError
+			require.ErrorAs(t, err, &badRequest)
+			assert.Equal(t, ErrorTypeInvalidPath, badRequest.ScimType)
+		})
+	}
+}
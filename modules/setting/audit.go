@@ -0,0 +1,31 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package setting
+
+// Audit settings
+var Audit = struct {
+	Enabled bool
+
+	SyslogEnabled bool
+	SyslogNetwork string
+	SyslogAddress string
+	SyslogTag     string
+
+	WebhookURL           string
+	WebhookAuthorization string
+}{
+	Enabled:   true,
+	SyslogTag: "forgejo-audit",
+}
+
+func loadAuditFrom(rootCfg ConfigProvider) {
+	sec := rootCfg.Section("audit")
+	Audit.Enabled = sec.Key("ENABLED").MustBool(true)
+	Audit.SyslogEnabled = sec.Key("SYSLOG_ENABLED").MustBool(false)
+	Audit.SyslogNetwork = sec.Key("SYSLOG_NETWORK").MustString("")
+	Audit.SyslogAddress = sec.Key("SYSLOG_ADDRESS").MustString("")
+	Audit.SyslogTag = sec.Key("SYSLOG_TAG").MustString("forgejo-audit")
+	Audit.WebhookURL = sec.Key("WEBHOOK_URL").MustString("")
+	Audit.WebhookAuthorization = sec.Key("WEBHOOK_AUTHORIZATION_HEADER").MustString("")
+}
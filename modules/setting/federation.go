@@ -4,6 +4,8 @@
 package setting
 
 import (
+	"time"
+
 	"code.gitea.io/gitea/modules/log"
 
 	"github.com/go-fed/httpsig"
@@ -15,6 +17,11 @@ var (
 		Enabled             bool
 		ShareUserStatistics bool
 		MaxSize             int64
+		AllowlistMode       bool
+		InboxRateLimit      int
+		MaxClockSkew        time.Duration
+		PublicKeyCacheTTL   time.Duration
+		SyncMirrorsOnPush   bool
 		Algorithms          []string
 		DigestAlgorithm     string
 		GetHeaders          []string
@@ -23,6 +30,11 @@ var (
 		Enabled:             false,
 		ShareUserStatistics: true,
 		MaxSize:             4,
+		AllowlistMode:       false,
+		InboxRateLimit:      300,
+		MaxClockSkew:        5 * time.Minute,
+		PublicKeyCacheTTL:   24 * time.Hour,
+		SyncMirrorsOnPush:   true,
 		Algorithms:          []string{"rsa-sha256", "rsa-sha512", "ed25519"},
 		DigestAlgorithm:     "SHA-256",
 		GetHeaders:          []string{"(request-target)", "Date", "Host"},
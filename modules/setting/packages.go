@@ -24,6 +24,7 @@ var (
 		LimitTotalOwnerCount  int64
 		LimitTotalOwnerSize   int64
 		LimitSizeAlpine       int64
+		LimitSizeAnsible      int64
 		LimitSizeArch         int64
 		LimitSizeCargo        int64
 		LimitSizeChef         int64
@@ -36,6 +37,7 @@ var (
 		LimitSizeGeneric      int64
 		LimitSizeGo           int64
 		LimitSizeHelm         int64
+		LimitSizeHex          int64
 		LimitSizeMaven        int64
 		LimitSizeNpm          int64
 		LimitSizeNuGet        int64
@@ -44,8 +46,12 @@ var (
 		LimitSizeRpm          int64
 		LimitSizeRubyGems     int64
 		LimitSizeSwift        int64
+		LimitSizeTerraform    int64
 		LimitSizeVagrant      int64
 		DefaultRPMSignEnabled bool
+		RemoteAllowedHostList string
+
+		BlockCriticalVulnerabilities bool
 	}{
 		Enabled:              true,
 		LimitTotalOwnerCount: -1,
@@ -84,6 +90,7 @@ func loadPackagesFrom(rootCfg ConfigProvider) (err error) {
 
 	Packages.LimitTotalOwnerSize = mustBytes(sec, "LIMIT_TOTAL_OWNER_SIZE")
 	Packages.LimitSizeAlpine = mustBytes(sec, "LIMIT_SIZE_ALPINE")
+	Packages.LimitSizeAnsible = mustBytes(sec, "LIMIT_SIZE_ANSIBLE")
 	Packages.LimitSizeArch = mustBytes(sec, "LIMIT_SIZE_ARCH")
 	Packages.LimitSizeCargo = mustBytes(sec, "LIMIT_SIZE_CARGO")
 	Packages.LimitSizeChef = mustBytes(sec, "LIMIT_SIZE_CHEF")
@@ -96,6 +103,7 @@ func loadPackagesFrom(rootCfg ConfigProvider) (err error) {
 	Packages.LimitSizeGeneric = mustBytes(sec, "LIMIT_SIZE_GENERIC")
 	Packages.LimitSizeGo = mustBytes(sec, "LIMIT_SIZE_GO")
 	Packages.LimitSizeHelm = mustBytes(sec, "LIMIT_SIZE_HELM")
+	Packages.LimitSizeHex = mustBytes(sec, "LIMIT_SIZE_HEX")
 	Packages.LimitSizeMaven = mustBytes(sec, "LIMIT_SIZE_MAVEN")
 	Packages.LimitSizeNpm = mustBytes(sec, "LIMIT_SIZE_NPM")
 	Packages.LimitSizeNuGet = mustBytes(sec, "LIMIT_SIZE_NUGET")
@@ -104,8 +112,11 @@ func loadPackagesFrom(rootCfg ConfigProvider) (err error) {
 	Packages.LimitSizeRpm = mustBytes(sec, "LIMIT_SIZE_RPM")
 	Packages.LimitSizeRubyGems = mustBytes(sec, "LIMIT_SIZE_RUBYGEMS")
 	Packages.LimitSizeSwift = mustBytes(sec, "LIMIT_SIZE_SWIFT")
+	Packages.LimitSizeTerraform = mustBytes(sec, "LIMIT_SIZE_TERRAFORM")
 	Packages.LimitSizeVagrant = mustBytes(sec, "LIMIT_SIZE_VAGRANT")
 	Packages.DefaultRPMSignEnabled = sec.Key("DEFAULT_RPM_SIGN_ENABLED").MustBool(false)
+	Packages.RemoteAllowedHostList = sec.Key("REMOTE_ALLOWED_HOST_LIST").MustString("")
+	Packages.BlockCriticalVulnerabilities = sec.Key("BLOCK_CRITICAL_VULNERABILITIES").MustBool(false)
 	return nil
 }
 
@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	"code.gitea.io/gitea/modules/auth/password/hash"
 	"code.gitea.io/gitea/modules/generate"
@@ -36,6 +37,10 @@ var (
 	PasswordCheckPwn                   bool
 	SuccessfulTokensCacheSize          int
 	DisableQueryAuthToken              bool
+	AccessTokenRequireExpiry           bool
+	AccessTokenMaxExpiry               time.Duration
+	AccessTokenExpiryWarning           time.Duration
+	OrgMembersRequireWebAuthn          bool
 	CSRFCookieName                     = "_csrf"
 	CSRFCookieHTTPOnly                 = true
 )
@@ -141,6 +146,10 @@ func loadSecurityFrom(rootCfg ConfigProvider) {
 	CSRFCookieHTTPOnly = sec.Key("CSRF_COOKIE_HTTP_ONLY").MustBool(true)
 	PasswordCheckPwn = sec.Key("PASSWORD_CHECK_PWN").MustBool(false)
 	SuccessfulTokensCacheSize = sec.Key("SUCCESSFUL_TOKENS_CACHE_SIZE").MustInt(20)
+	AccessTokenRequireExpiry = sec.Key("ACCESS_TOKEN_REQUIRE_EXPIRY").MustBool(false)
+	AccessTokenMaxExpiry = sec.Key("ACCESS_TOKEN_MAX_EXPIRY").MustDuration(0)
+	AccessTokenExpiryWarning = sec.Key("ACCESS_TOKEN_EXPIRY_WARNING").MustDuration(7 * 24 * time.Hour)
+	OrgMembersRequireWebAuthn = sec.Key("ORG_MEMBERS_REQUIRE_WEBAUTHN").MustBool(false)
 
 	InternalToken = loadSecret(sec, "INTERNAL_TOKEN_URI", "INTERNAL_TOKEN")
 	if InstallLock && InternalToken == "" {
@@ -48,6 +48,9 @@ var SSH = struct {
 	TrustedUserCAKeys                     []string           `ini:"SSH_TRUSTED_USER_CA_KEYS"`
 	TrustedUserCAKeysFile                 string             `ini:"SSH_TRUSTED_USER_CA_KEYS_FILENAME"`
 	TrustedUserCAKeysParsed               []gossh.PublicKey  `ini:"-"`
+	UserCAEnabled                         bool               `ini:"SSH_USER_CA_ENABLED"`
+	UserCAKey                             string             `ini:"SSH_USER_CA_KEY"`
+	UserCertificateMaxValidity            time.Duration      `ini:"SSH_USER_CERTIFICATE_MAX_VALIDITY"`
 	PerWriteTimeout                       time.Duration      `ini:"SSH_PER_WRITE_TIMEOUT"`
 	PerWritePerKbTimeout                  time.Duration      `ini:"SSH_PER_WRITE_PER_KB_TIMEOUT"`
 }{
@@ -62,6 +65,8 @@ var SSH = struct {
 	MinimumKeySizeCheck:           true,
 	MinimumKeySizes:               map[string]int{"ed25519": 256, "ed25519-sk": 256, "ecdsa": 256, "ecdsa-sk": 256, "rsa": 3071},
 	ServerHostKeys:                []string{"ssh/gitea.rsa", "ssh/gogs.rsa"},
+	UserCAKey:                     "ssh/forgejo-user-ca",
+	UserCertificateMaxValidity:    16 * time.Hour,
 	AuthorizedKeysCommandTemplate: "{{.AppPath}} --config={{.CustomConf}} serv key-{{.Key.ID}}",
 	PerWriteTimeout:               PerWriteTimeout,
 	PerWritePerKbTimeout:          PerWritePerKbTimeout,
@@ -134,6 +139,10 @@ func loadSSHFrom(rootCfg ConfigProvider) {
 		}
 	}
 
+	if !filepath.IsAbs(SSH.UserCAKey) {
+		SSH.UserCAKey = filepath.Join(AppDataPath, SSH.UserCAKey)
+	}
+
 	SSH.KeygenPath = sec.Key("SSH_KEYGEN_PATH").String()
 	SSH.Port = sec.Key("SSH_PORT").MustInt(22)
 	SSH.ListenPort = sec.Key("SSH_LISTEN_PORT").MustInt(SSH.Port)
@@ -154,7 +163,7 @@ func loadSSHFrom(rootCfg ConfigProvider) {
 
 		SSH.TrustedUserCAKeysParsed = append(SSH.TrustedUserCAKeysParsed, pubKey)
 	}
-	if len(SSH.TrustedUserCAKeys) > 0 {
+	if len(SSH.TrustedUserCAKeys) > 0 || SSH.UserCAEnabled {
 		// Set the default as email,username otherwise we can leave it empty
 		sec.Key("SSH_AUTHORIZED_PRINCIPALS_ALLOW").MustString("username,email")
 	} else {
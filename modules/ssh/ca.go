@@ -0,0 +1,116 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package ssh
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// certificateClockSkew is subtracted from the start of the validity of certificates
+const certificateClockSkew = 5 * time.Minute
+
+var (
+	userCAOnce   sync.Once
+	userCASigner gossh.Signer
+	userCAErr    error
+)
+
+// UserCA returns the certificate authority of the instance which signs user certificates.
+// The key is generated on first use if it doesn't exist.
+func UserCA() (gossh.Signer, error) {
+	userCAOnce.Do(func() {
+		userCASigner, userCAErr = loadOrGenerateCAKey(setting.SSH.UserCAKey)
+	})
+	return userCASigner, userCAErr
+}
+
+// IsUserCA checks if the key is the certificate authority of the instance
+func IsUserCA(key gossh.PublicKey) bool {
+	if !setting.SSH.UserCAEnabled {
+		return false
+	}
+	signer, err := UserCA()
+	if err != nil {
+		log.Error("Unable to load the SSH certificate authority: %v", err)
+		return false
+	}
+	return bytes.Equal(key.Marshal(), signer.PublicKey().Marshal())
+}
+
+func loadOrGenerateCAKey(keyPath string) (gossh.Signer, error) {
+	data, err := os.ReadFile(keyPath)
+	if err == nil {
+		return gossh.ParsePrivateKey(data)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	block, err := gossh.MarshalPrivateKey(privateKey, "forgejo user ca")
+	if err != nil {
+		return nil, err
+	}
+	signer, err := gossh.NewSignerFromKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(keyPath+".pub", gossh.MarshalAuthorizedKey(signer.PublicKey()), 0o644); err != nil {
+		return nil, err
+	}
+	log.Info("New SSH certificate authority key is generated: %s", keyPath)
+
+	return signer, nil
+}
+
+// SignUserCertificate signs a user certificate for the public key with the certificate authority of the instance
+func SignUserCertificate(key gossh.PublicKey, keyID, principal string, validity time.Duration) (*gossh.Certificate, error) {
+	signer, err := UserCA()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the SSH certificate authority: %w", err)
+	}
+
+	var serial [8]byte
+	if _, err := rand.Read(serial[:]); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	cert := &gossh.Certificate{
+		Key:             key,
+		Serial:          binary.BigEndian.Uint64(serial[:]),
+		CertType:        gossh.UserCert,
+		KeyId:           keyID,
+		ValidPrincipals: []string{principal},
+		ValidAfter:      uint64(now.Add(-certificateClockSkew).Unix()),
+		ValidBefore:     uint64(now.Add(validity).Unix()),
+	}
+	if err := cert.SignCert(rand.Reader, signer); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
@@ -8,11 +8,14 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/setting"
+
+	gossh "golang.org/x/crypto/ssh"
 )
 
 func Init() error {
@@ -21,6 +24,15 @@ func Init() error {
 		return nil
 	}
 
+	trustedUserCAKeys := slices.Clone(setting.SSH.TrustedUserCAKeys)
+	if setting.SSH.UserCAEnabled {
+		signer, err := UserCA()
+		if err != nil {
+			return fmt.Errorf("failed to load the ssh certificate authority %q: %w", setting.SSH.UserCAKey, err)
+		}
+		trustedUserCAKeys = append(trustedUserCAKeys, strings.TrimSpace(string(gossh.MarshalAuthorizedKey(signer.PublicKey()))))
+	}
+
 	if setting.SSH.StartBuiltinServer {
 		Listen(setting.SSH.ListenHost, setting.SSH.ListenPort, setting.SSH.ServerCiphers, setting.SSH.ServerKeyExchanges, setting.SSH.ServerMACs)
 		log.Info("SSH server started on %s. Cipher list (%v), key exchange algorithms (%v), MACs (%v)",
@@ -37,7 +49,7 @@ func Init() error {
 		return fmt.Errorf("failed to create directory %q for ssh key test: %w", setting.SSH.KeyTestPath, err)
 	}
 
-	if len(setting.SSH.TrustedUserCAKeys) > 0 && setting.SSH.AuthorizedPrincipalsEnabled {
+	if len(trustedUserCAKeys) > 0 && setting.SSH.AuthorizedPrincipalsEnabled {
 		caKeysFileName := setting.SSH.TrustedUserCAKeysFile
 		caKeysFileDir := filepath.Dir(caKeysFileName)
 
@@ -46,7 +58,7 @@ func Init() error {
 			return fmt.Errorf("failed to create directory %q for ssh trusted ca keys: %w", caKeysFileDir, err)
 		}
 
-		if err := os.WriteFile(caKeysFileName, []byte(strings.Join(setting.SSH.TrustedUserCAKeys, "\n")), 0o600); err != nil {
+		if err := os.WriteFile(caKeysFileName, []byte(strings.Join(trustedUserCAKeys, "\n")), 0o600); err != nil {
 			return fmt.Errorf("failed to write ssh trusted ca keys to %q: %w", caKeysFileName, err)
 		}
 	}
@@ -61,6 +61,14 @@ func getExitStatusFromError(err error) int {
 	return waitStatus.ExitStatus()
 }
 
+// sshConnection returns the addresses of the session like OpenSSH provides them in SSH_CONNECTION:
+// "<client ip> <client port> <server ip> <server port>"
+func sshConnection(session ssh.Session) string {
+	clientIP, clientPort, _ := net.SplitHostPort(session.RemoteAddr().String())
+	serverIP, serverPort, _ := net.SplitHostPort(session.LocalAddr().String())
+	return strings.Join([]string{clientIP, clientPort, serverIP, serverPort}, " ")
+}
+
 func sessionHandler(session ssh.Session) {
 	keyID := fmt.Sprintf("%d", session.Context().Value(giteaKeyID).(int64))
 
@@ -88,6 +96,7 @@ func sessionHandler(session ssh.Session) {
 		"SSH_ORIGINAL_COMMAND="+command,
 		"SKIP_MINWINSVC=1",
 		"GIT_PROTOCOL="+gitProtocol,
+		"SSH_CONNECTION="+sshConnection(session),
 	)
 
 	stdout, err := cmd.StdoutPipe()
@@ -180,7 +189,7 @@ func publicKeyHandler(ctx ssh.Context, key ssh.PublicKey) bool {
 			log.Debug("Handle Certificate: %s Fingerprint: %s is a certificate", ctx.RemoteAddr(), gossh.FingerprintSHA256(key))
 		}
 
-		if len(setting.SSH.TrustedUserCAKeys) == 0 {
+		if len(setting.SSH.TrustedUserCAKeys) == 0 && !setting.SSH.UserCAEnabled {
 			log.Warn("Certificate Rejected: No trusted certificate authorities for this server")
 			log.Warn("Failed authentication attempt from %s", ctx.RemoteAddr())
 			return false
@@ -214,7 +223,8 @@ func publicKeyHandler(ctx ssh.Context, key ssh.PublicKey) bool {
 						}
 					}
 
-					return false
+					// certificates of the instance are only valid for the principal of their owner
+					return IsUserCA(auth) && pkey.Type == asymkey_model.KeyTypePrincipal
 				},
 			}
 
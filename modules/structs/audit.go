@@ -0,0 +1,32 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package structs
+
+import "time"
+
+// AuditEvent represents a security-relevant event of the audit log
+type AuditEvent struct {
+	ID int64 `json:"id"`
+	// the kind of event, e.g. "user.admin.grant" or "repo.visibility"
+	Action string `json:"action"`
+	// the user who caused the event, 0 if it wasn't caused by a signed-in user
+	ActorID   int64  `json:"actor_id"`
+	ActorName string `json:"actor_name"`
+	IPAddress string `json:"ip_address"`
+	// the user or organization whose audit log contains the event
+	OwnerID    int64  `json:"owner_id"`
+	TargetType string `json:"target_type"`
+	TargetID   int64  `json:"target_id"`
+	TargetName string `json:"target_name"`
+	// the changed fields of the target by field name
+	Diff map[string]AuditChange `json:"diff,omitempty"`
+	// swagger:strfmt date-time
+	Created time.Time `json:"created"`
+}
+
+// AuditChange is the old and the new value of a field changed by an audit event
+type AuditChange struct {
+	Old any `json:"old,omitempty"`
+	New any `json:"new,omitempty"`
+}
@@ -0,0 +1,42 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package structs
+
+import "time"
+
+// FederationHost represents a host which contacted this instance over ActivityPub
+type FederationHost struct {
+	ID       int64  `json:"id"`
+	Host     string `json:"host"`
+	Software string `json:"software"`
+	// how the activities of the host are treated according to the federation host rules
+	// enum: none,allow,silence,block
+	Action string `json:"action"`
+	// swagger:strfmt date-time
+	LatestActivity time.Time `json:"latest_activity"`
+	// swagger:strfmt date-time
+	Created time.Time `json:"created"`
+}
+
+// FederationHostRule moderates the federation with a host or with the subdomains of a domain
+type FederationHostRule struct {
+	ID int64 `json:"id"`
+	// a host name, or a wildcard domain like "*.example.com" matching all its subdomains
+	Pattern string `json:"pattern"`
+	// enum: allow,silence,block
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+	// swagger:strfmt date-time
+	Created time.Time `json:"created"`
+	// swagger:strfmt date-time
+	Updated time.Time `json:"updated"`
+}
+
+// SetFederationHostRuleOption options for creating or updating a federation host rule
+type SetFederationHostRuleOption struct {
+	// required: true
+	// enum: allow,silence,block
+	Action string `json:"action" binding:"Required;In(allow,silence,block)"`
+	Reason string `json:"reason" binding:"MaxSize(1024)"`
+}
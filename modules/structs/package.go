@@ -31,3 +31,51 @@ type PackageFile struct {
 	HashSHA256 string `json:"sha256"`
 	HashSHA512 string `json:"sha512"`
 }
+
+// PackageAttestation represents a provenance attestation of a package version
+type PackageAttestation struct {
+	ID            int64  `json:"id"`
+	Creator       *User  `json:"creator"`
+	PredicateType string `json:"predicate_type"`
+	// whether the attestation is signed by a trusted key of the owner
+	Verified bool `json:"verified"`
+	// fingerprint of the key which verified the attestation
+	KeyID string `json:"key_id"`
+	// the DSSE envelope containing the in-toto statement
+	Envelope string `json:"envelope"`
+	// swagger:strfmt date-time
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PackageVulnerability represents a known vulnerability affecting a package version
+type PackageVulnerability struct {
+	// id of the advisory in the imported database
+	ID      string   `json:"id"`
+	Aliases []string `json:"aliases"`
+	Summary string   `json:"summary"`
+	// enum: low,moderate,high,critical
+	Severity string `json:"severity"`
+	// swagger:strfmt date-time
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// PreviewPackageCleanupRuleOption describes a cleanup rule whose effect should be previewed
+type PreviewPackageCleanupRuleOption struct {
+	// required: true
+	// enum: alpine,ansible,arch,cargo,chef,composer,conan,conda,container,cran,debian,generic,go,helm,hex,maven,npm,nuget,pub,pypi,rpm,rubygems,swift,terraform,vagrant
+	Type string `json:"type" binding:"Required"`
+	// number of most recent versions per package to keep
+	KeepCount int `json:"keep_count"`
+	// versions matching this pattern are kept
+	KeepPattern string `json:"keep_pattern"`
+	// number of highest patch releases per minor version to keep, versions which are no semantic versions aren't kept by it
+	KeepSemverPatches int `json:"keep_semver_patches"`
+	// only versions older than this number of days are removed
+	RemoveDays int `json:"remove_days"`
+	// only versions not downloaded in this number of days are removed
+	RemoveUnusedDays int `json:"remove_unused_days"`
+	// only versions matching this pattern are removed
+	RemovePattern string `json:"remove_pattern"`
+	// apply the patterns to the full package name instead of the version
+	MatchFullName bool `json:"match_full_name"`
+}
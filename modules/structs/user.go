@@ -113,6 +113,14 @@ type RenameUserOption struct {
 	NewName string `json:"new_username" binding:"Required"`
 }
 
+// FollowFederatedUserOption options when following a user of another instance
+type FollowFederatedUserOption struct {
+	// ID of the ActivityPub actor of the user, e.g. https://example.com/api/v1/activitypub/user-id/1
+	//
+	// required: true
+	ActorID string `json:"actor_id" binding:"Required"`
+}
+
 // UpdateUserAvatarUserOption options when updating the user avatar
 type UpdateUserAvatarOption struct {
 	// image must be base64 encoded
@@ -16,6 +16,14 @@ type AccessToken struct {
 	Token          string   `json:"sha1"`
 	TokenLastEight string   `json:"token_last_eight"`
 	Scopes         []string `json:"scopes"`
+	// Organization is set if the fine-grained token is restricted to the repositories of an organization
+	Organization string `json:"organization,omitempty"`
+	// Repositories are the full names of the repositories the fine-grained token is restricted to
+	Repositories []string `json:"repositories,omitempty"`
+	// Permissions of the fine-grained token, mapping contents, issues, pulls, packages and actions to read or write
+	Permissions map[string]string `json:"permissions,omitempty"`
+	// swagger:strfmt date-time
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 // AccessTokenList represents a list of API access token.
@@ -27,6 +35,16 @@ type CreateAccessTokenOption struct {
 	// required: true
 	Name   string   `json:"name" binding:"Required"`
 	Scopes []string `json:"scopes"`
+	// point in time the token expires, it never expires if unset
+	// swagger:strfmt date-time
+	ExpiresAt *time.Time `json:"expires_at"`
+	// restrict the token to all repositories of this organization, requires permissions
+	Organization string `json:"organization"`
+	// restrict the token to these repositories given as owner/name, requires permissions
+	Repositories []string `json:"repositories"`
+	// permissions of a fine-grained token, mapping contents, issues, pulls, packages and actions to read or write.
+	// The scopes are derived from the permissions.
+	Permissions map[string]string `json:"permissions"`
 }
 
 // CreateOAuth2ApplicationOptions holds options to create an oauth2 application
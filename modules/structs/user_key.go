@@ -20,3 +20,26 @@ type PublicKey struct {
 	ReadOnly bool      `json:"read_only,omitempty"`
 	KeyType  string    `json:"key_type,omitempty"`
 }
+
+// CreateSSHCertificateOption options when requesting an SSH certificate
+type CreateSSHCertificateOption struct {
+	// The SSH public key to sign
+	//
+	// required: true
+	Key string `json:"key" binding:"Required"`
+	// Validity of the certificate in seconds, limited by the instance. Defaults to the maximum validity
+	Validity int64 `json:"validity"`
+}
+
+// SSHCertificate represents an SSH user certificate signed by the instance
+type SSHCertificate struct {
+	// The certificate in authorized_keys format
+	Certificate string   `json:"certificate"`
+	KeyID       string   `json:"key_id"`
+	Serial      uint64   `json:"serial"`
+	Principals  []string `json:"principals"`
+	// swagger:strfmt date-time
+	ValidAfter time.Time `json:"valid_after"`
+	// swagger:strfmt date-time
+	ValidBefore time.Time `json:"valid_before"`
+}
@@ -94,6 +94,8 @@ func ActionIcon(opType activities_model.ActionType) string {
 		return "comment-discussion"
 	case activities_model.ActionMirrorSyncPush, activities_model.ActionMirrorSyncCreate, activities_model.ActionMirrorSyncDelete:
 		return "mirror"
+	case activities_model.ActionFederatedPush:
+		return "repo-push"
 	case activities_model.ActionApprovePullRequest:
 		return "check"
 	case activities_model.ActionRejectPullRequest:
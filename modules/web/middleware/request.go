@@ -4,6 +4,7 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"strings"
 )
@@ -12,3 +13,14 @@ import (
 func IsAPIPath(req *http.Request) bool {
 	return strings.HasPrefix(req.URL.Path, "/api/")
 }
+
+type remoteAddrContextKeyType struct{}
+
+// RemoteAddrContextKey is the key of the remote address of the request in the request context
+var RemoteAddrContextKey any = &remoteAddrContextKeyType{}
+
+// RemoteAddr returns the remote address of the request the context belongs to, it's empty outside of requests
+func RemoteAddr(ctx context.Context) string {
+	addr, _ := ctx.Value(RemoteAddrContextKey).(string)
+	return addr
+}
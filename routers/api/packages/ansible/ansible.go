@@ -0,0 +1,453 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package ansible
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	ansible_module "code.gitea.io/gitea/modules/packages/ansible"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/routers/api/packages/helper"
+	"code.gitea.io/gitea/services/context"
+	packages_service "code.gitea.io/gitea/services/packages"
+
+	"github.com/hashicorp/go-version"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+func apiError(ctx *context.Context, status int, obj any) {
+	helper.LogAndProcessError(ctx, status, obj, func(message string) {
+		type Error struct {
+			Status string `json:"status"`
+			Code   string `json:"code"`
+			Title  string `json:"title"`
+		}
+		ctx.JSON(status, struct {
+			Errors []Error `json:"errors"`
+		}{
+			Errors: []Error{
+				{Status: fmt.Sprint(status), Code: strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_"), Title: message},
+			},
+		})
+	})
+}
+
+// apiPath returns the server relative path of the Galaxy API which is used for the links in the responses
+func apiPath(ctx *context.Context) string {
+	return fmt.Sprintf("%s/api/packages/%s/ansible/api/v3", setting.AppSubURL, url.PathEscape(ctx.Package.Owner.Name))
+}
+
+func collectionPath(ctx *context.Context, namespace, name string) string {
+	return fmt.Sprintf("%s/collections/%s/%s", apiPath(ctx), url.PathEscape(namespace), url.PathEscape(name))
+}
+
+func collectionParams(ctx *context.Context) (string, string, bool) {
+	namespace := ctx.Params("namespace")
+	name := ctx.Params("name")
+	return namespace, name, ansible_module.IsValidName(namespace) && ansible_module.IsValidName(name)
+}
+
+// getCollectionVersions returns the versions of the collection sorted from newest to oldest
+func getCollectionVersions(ctx *context.Context, namespace, name string) ([]*packages_model.PackageVersion, error) {
+	pvs, err := packages_model.GetVersionsByPackageName(ctx, ctx.Package.Owner.ID, packages_model.TypeAnsible, ansible_module.PackageName(namespace, name))
+	if err == nil {
+		pvs, err = packages_service.FilterVersionsByRepositoryScope(ctx, pvs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(pvs) == 0 {
+		return nil, packages_model.ErrPackageNotExist
+	}
+
+	sort.SliceStable(pvs, func(i, j int) bool {
+		vi, erri := version.NewSemver(pvs[i].Version)
+		vj, errj := version.NewSemver(pvs[j].Version)
+		if erri != nil || errj != nil {
+			return pvs[i].Version > pvs[j].Version
+		}
+		return vi.GreaterThan(vj)
+	})
+	return pvs, nil
+}
+
+// AvailableVersions tells the client which API versions are supported
+// The client requests this resource to discover the API.
+func AvailableVersions(ctx *context.Context) {
+	ctx.JSON(http.StatusOK, map[string]any{
+		"description": "Forgejo Ansible Galaxy API",
+		"available_versions": map[string]string{
+			"v3": "v3/",
+		},
+	})
+}
+
+type versionReference struct {
+	Href      string    `json:"href"`
+	Version   string    `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newVersionReference(ctx *context.Context, namespace, name string, pv *packages_model.PackageVersion) *versionReference {
+	return &versionReference{
+		Href:      fmt.Sprintf("%s/versions/%s/", collectionPath(ctx, namespace, name), url.PathEscape(pv.Version)),
+		Version:   pv.Version,
+		CreatedAt: pv.CreatedUnix.AsLocalTime(),
+		UpdatedAt: pv.CreatedUnix.AsLocalTime(),
+	}
+}
+
+// CollectionInfo returns the information about a collection
+func CollectionInfo(ctx *context.Context) {
+	namespace, name, ok := collectionParams(ctx)
+	if !ok {
+		apiError(ctx, http.StatusNotFound, ansible_module.ErrInvalidName)
+		return
+	}
+
+	pvs, err := getCollectionVersions(ctx, namespace, name)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	latest := newVersionReference(ctx, namespace, name, pvs[0])
+
+	ctx.JSON(http.StatusOK, map[string]any{
+		"href":            collectionPath(ctx, namespace, name) + "/",
+		"namespace":       namespace,
+		"name":            name,
+		"deprecated":      false,
+		"versions_url":    collectionPath(ctx, namespace, name) + "/versions/",
+		"highest_version": latest,
+		"created_at":      pvs[len(pvs)-1].CreatedUnix.AsLocalTime(),
+		"updated_at":      latest.CreatedAt,
+	})
+}
+
+// EnumerateCollectionVersions lists the versions of a collection
+func EnumerateCollectionVersions(ctx *context.Context) {
+	namespace, name, ok := collectionParams(ctx)
+	if !ok {
+		apiError(ctx, http.StatusNotFound, ansible_module.ErrInvalidName)
+		return
+	}
+
+	pvs, err := getCollectionVersions(ctx, namespace, name)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	limit := ctx.FormInt("limit")
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	limit = min(limit, maxPageSize)
+	offset := max(ctx.FormInt("offset"), 0)
+
+	pageLink := func(offset int) string {
+		return fmt.Sprintf("%s/versions/?limit=%d&offset=%d", collectionPath(ctx, namespace, name), limit, offset)
+	}
+
+	links := map[string]any{
+		"first":    pageLink(0),
+		"previous": nil,
+		"next":     nil,
+		"last":     pageLink(max((len(pvs)-1)/limit*limit, 0)),
+	}
+	if offset > 0 {
+		links["previous"] = pageLink(max(offset-limit, 0))
+	}
+	if offset+limit < len(pvs) {
+		links["next"] = pageLink(offset + limit)
+	}
+
+	data := make([]*versionReference, 0, limit)
+	for i := offset; i < len(pvs) && i < offset+limit; i++ {
+		data = append(data, newVersionReference(ctx, namespace, name, pvs[i]))
+	}
+
+	ctx.JSON(http.StatusOK, map[string]any{
+		"meta": map[string]any{
+			"count": len(pvs),
+		},
+		"links": links,
+		"data":  data,
+	})
+}
+
+// CollectionVersionInfo returns the information about a collection version which is needed to install it
+func CollectionVersionInfo(ctx *context.Context) {
+	namespace, name, ok := collectionParams(ctx)
+	if !ok {
+		apiError(ctx, http.StatusNotFound, ansible_module.ErrInvalidName)
+		return
+	}
+
+	pv, err := packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypeAnsible, ansible_module.PackageName(namespace, name), ctx.Params("version"))
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	pd, err := packages_model.GetPackageDescriptor(ctx, pv)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if len(pd.Files) == 0 {
+		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageFileNotExist)
+		return
+	}
+
+	metadata := pd.Metadata.(*ansible_module.Metadata)
+	dependencies := metadata.Dependencies
+	if dependencies == nil {
+		dependencies = map[string]string{}
+	}
+
+	pf := pd.Files[0]
+	ref := newVersionReference(ctx, namespace, name, pv)
+
+	ctx.JSON(http.StatusOK, map[string]any{
+		"href":       ref.Href,
+		"version":    pv.Version,
+		"created_at": ref.CreatedAt,
+		"updated_at": ref.UpdatedAt,
+		"namespace": map[string]any{
+			"name": namespace,
+		},
+		"collection": map[string]any{
+			"href": collectionPath(ctx, namespace, name) + "/",
+			"name": name,
+		},
+		"download_url": fmt.Sprintf("%sapi/packages/%s/ansible/download/%s", setting.AppURL, url.PathEscape(ctx.Package.Owner.Name), url.PathEscape(pf.File.Name)),
+		"artifact": map[string]any{
+			"filename": pf.File.Name,
+			"sha256":   pf.Blob.HashSHA256,
+			"size":     pf.Blob.Size,
+		},
+		"metadata": map[string]any{
+			"description":   metadata.Description,
+			"authors":       metadata.Authors,
+			"license":       metadata.License,
+			"tags":          metadata.Tags,
+			"dependencies":  dependencies,
+			"repository":    metadata.RepositoryURL,
+			"documentation": metadata.DocumentationURL,
+			"homepage":      metadata.HomepageURL,
+			"issues":        metadata.IssuesURL,
+		},
+		"signatures": []any{},
+	})
+}
+
+// DownloadCollectionFile serves the artifact of a collection version
+func DownloadCollectionFile(ctx *context.Context) {
+	filename := ctx.Params("filename")
+
+	namespace, name, collectionVersion, err := ansible_module.ParseFilename(filename)
+	if err != nil {
+		apiError(ctx, http.StatusNotFound, err)
+		return
+	}
+
+	s, u, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(
+		ctx,
+		&packages_service.PackageInfo{
+			Owner:       ctx.Package.Owner,
+			PackageType: packages_model.TypeAnsible,
+			Name:        ansible_module.PackageName(namespace, name),
+			Version:     collectionVersion,
+		},
+		&packages_service.PackageFileInfo{
+			Filename: filename,
+		},
+	)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		if errors.Is(err, packages_service.ErrVersionBlocked) {
+			apiError(ctx, http.StatusForbidden, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	helper.ServePackageFile(ctx, s, u, pf)
+}
+
+// UploadCollection publishes a collection artifact created by "ansible-galaxy collection build"
+// The collection is imported immediately, the returned task only reports the result.
+func UploadCollection(ctx *context.Context) {
+	file, _, err := ctx.Req.FormFile("file")
+	if err != nil {
+		apiError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	defer file.Close()
+
+	buf, err := packages_module.CreateHashedBufferFromReader(file)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	defer buf.Close()
+
+	if checksum := ctx.Req.FormValue("sha256"); checksum != "" {
+		_, _, hashSHA256, _ := buf.Sums()
+		if !strings.EqualFold(checksum, hex.EncodeToString(hashSHA256)) {
+			apiError(ctx, http.StatusBadRequest, "hash mismatch")
+			return
+		}
+	}
+
+	pck, err := ansible_module.ParsePackage(buf)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			apiError(ctx, http.StatusBadRequest, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if _, err := buf.Seek(0, io.SeekStart); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	pv, _, err := packages_service.CreatePackageAndAddFile(
+		ctx,
+		&packages_service.PackageCreationInfo{
+			PackageInfo: packages_service.PackageInfo{
+				Owner:       ctx.Package.Owner,
+				PackageType: packages_model.TypeAnsible,
+				Name:        ansible_module.PackageName(pck.Namespace, pck.Name),
+				Version:     pck.Version,
+			},
+			SemverCompatible: true,
+			Creator:          ctx.Doer,
+			Metadata:         pck.Metadata,
+		},
+		&packages_service.PackageFileCreationInfo{
+			PackageFileInfo: packages_service.PackageFileInfo{
+				Filename: ansible_module.Filename(pck.Namespace, pck.Name, pck.Version),
+			},
+			Creator: ctx.Doer,
+			Data:    buf,
+			IsLead:  true,
+		},
+	)
+	if err != nil {
+		switch err {
+		case packages_model.ErrDuplicatePackageVersion:
+			apiError(ctx, http.StatusConflict, err)
+		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize, packages_service.ErrPackageAccessDenied:
+			apiError(ctx, http.StatusForbidden, err)
+		default:
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, map[string]string{
+		"task": fmt.Sprintf("%s/imports/collections/%d/", apiPath(ctx), pv.ID),
+	})
+}
+
+// ImportTaskStatus reports the state of an import task
+func ImportTaskStatus(ctx *context.Context) {
+	pv, err := packages_model.GetVersionByID(ctx, ctx.ParamsInt64("id"))
+	if err == nil {
+		var p *packages_model.Package
+		p, err = packages_model.GetPackageByID(ctx, pv.PackageID)
+		if err == nil && (p.OwnerID != ctx.Package.Owner.ID || p.Type != packages_model.TypeAnsible) {
+			err = packages_model.ErrPackageNotExist
+		}
+	}
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, map[string]any{
+		"id":          fmt.Sprint(pv.ID),
+		"state":       "completed",
+		"created_at":  pv.CreatedUnix.AsLocalTime(),
+		"finished_at": pv.CreatedUnix.AsLocalTime(),
+		"messages":    []any{},
+		"error":       nil,
+	})
+}
+
+// DeleteCollectionVersion deletes a collection version
+func DeleteCollectionVersion(ctx *context.Context) {
+	namespace, name, ok := collectionParams(ctx)
+	if !ok {
+		apiError(ctx, http.StatusNotFound, ansible_module.ErrInvalidName)
+		return
+	}
+
+	err := packages_service.RemovePackageVersionByNameAndVersion(
+		ctx,
+		ctx.Doer,
+		&packages_service.PackageInfo{
+			Owner:       ctx.Package.Owner,
+			PackageType: packages_model.TypeAnsible,
+			Name:        ansible_module.PackageName(namespace, name),
+			Version:     ctx.Params("version"),
+		},
+	)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		if errors.Is(err, util.ErrPermissionDenied) {
+			apiError(ctx, http.StatusForbidden, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -15,6 +15,7 @@ import (
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/web"
 	"code.gitea.io/gitea/routers/api/packages/alpine"
+	"code.gitea.io/gitea/routers/api/packages/ansible"
 	"code.gitea.io/gitea/routers/api/packages/arch"
 	"code.gitea.io/gitea/routers/api/packages/cargo"
 	"code.gitea.io/gitea/routers/api/packages/chef"
@@ -27,6 +28,7 @@ import (
 	"code.gitea.io/gitea/routers/api/packages/generic"
 	"code.gitea.io/gitea/routers/api/packages/goproxy"
 	"code.gitea.io/gitea/routers/api/packages/helm"
+	"code.gitea.io/gitea/routers/api/packages/hex"
 	"code.gitea.io/gitea/routers/api/packages/maven"
 	"code.gitea.io/gitea/routers/api/packages/npm"
 	"code.gitea.io/gitea/routers/api/packages/nuget"
@@ -35,9 +37,11 @@ import (
 	"code.gitea.io/gitea/routers/api/packages/rpm"
 	"code.gitea.io/gitea/routers/api/packages/rubygems"
 	"code.gitea.io/gitea/routers/api/packages/swift"
+	"code.gitea.io/gitea/routers/api/packages/terraform"
 	"code.gitea.io/gitea/routers/api/packages/vagrant"
 	"code.gitea.io/gitea/services/auth"
 	"code.gitea.io/gitea/services/context"
+	packages_service "code.gitea.io/gitea/services/packages"
 )
 
 func reqPackageAccess(accessMode perm.AccessMode) func(ctx *context.Context) {
@@ -83,9 +87,23 @@ func reqPackageAccess(accessMode perm.AccessMode) func(ctx *context.Context) {
 		}
 
 		if ctx.Package.AccessMode < accessMode && !ctx.IsUserSiteAdmin() {
-			ctx.Resp.Header().Set("WWW-Authenticate", `Basic realm="Gitea Package API"`)
-			ctx.Error(http.StatusUnauthorized, "reqPackageAccess", "user should have specific permission or be a site admin")
-			return
+			// the doer may still access the packages which inherit the permissions of a linked repository,
+			// but fine-grained access tokens are limited to the packages permission of the token
+			hasAccess := false
+			if context.FineGrainedAccessToken(ctx.Data) == nil {
+				var err error
+				hasAccess, err = packages_service.HasRepositoryAccess(ctx, ctx.Doer, ctx.Package.Owner, accessMode)
+				if err != nil {
+					ctx.Error(http.StatusInternalServerError, "HasRepositoryAccess", err.Error())
+					return
+				}
+			}
+			if !hasAccess {
+				ctx.Resp.Header().Set("WWW-Authenticate", `Basic realm="Gitea Package API"`)
+				ctx.Error(http.StatusUnauthorized, "reqPackageAccess", "user should have specific permission or be a site admin")
+				return
+			}
+			packages_service.SetRepositoryScope(ctx, ctx.Doer)
 		}
 	}
 }
@@ -136,6 +154,21 @@ func CommonRoutes() *web.Route {
 		&nuget.Auth{},
 		&conan.Auth{},
 		&chef.Auth{},
+		&hex.Auth{},
+		&ansible.Auth{},
+	})
+
+	// Terraform resolves the registry endpoints through service discovery, so the owner is part of the path
+	// https://developer.hashicorp.com/terraform/internals/remote-service-discovery
+	r.Group("/-/terraform", func() {
+		r.Group("/modules/v1/{username}/{name}/{system}", func() {
+			r.Get("/versions", terraform.EnumerateModuleVersions)
+			r.Get("/{version}/download", terraform.DownloadModule)
+		}, context.UserAssignmentWeb(), context.PackageAssignment(), reqPackageAccess(perm.AccessModeRead))
+		r.Group("/providers/v1/{username}/{type}", func() {
+			r.Get("/versions", terraform.EnumerateProviderVersions)
+			r.Get("/{version}/download/{os}/{arch}", terraform.DownloadProvider)
+		}, context.UserAssignmentWeb(), context.PackageAssignment(), reqPackageAccess(perm.AccessModeRead))
 	})
 
 	r.Group("/{username}", func() {
@@ -152,6 +185,24 @@ func CommonRoutes() *web.Route {
 				})
 			})
 		}, reqPackageAccess(perm.AccessModeRead))
+		r.Group("/ansible", func() {
+			r.Group("/api", func() {
+				r.Get("", ansible.AvailableVersions)
+				r.Group("/v3", func() {
+					r.Group("/collections/{namespace}/{name}", func() {
+						r.Get("", ansible.CollectionInfo)
+						r.Group("/versions", func() {
+							r.Get("", ansible.EnumerateCollectionVersions)
+							r.Get("/{version}", ansible.CollectionVersionInfo)
+							r.Delete("/{version}", reqPackageAccess(perm.AccessModeWrite), ansible.DeleteCollectionVersion)
+						})
+					})
+					r.Post("/artifacts/collections", reqPackageAccess(perm.AccessModeWrite), enforcePackagesQuota(), ansible.UploadCollection)
+					r.Get("/imports/collections/{id}", ansible.ImportTaskStatus)
+				})
+			})
+			r.Get("/download/{filename}", ansible.DownloadCollectionFile)
+		}, reqPackageAccess(perm.AccessModeRead))
 		r.Group("/arch", func() {
 			r.Group("/repository.key", func() {
 				r.Head("", arch.GetRepositoryKey)
@@ -498,6 +549,20 @@ func CommonRoutes() *web.Route {
 			r.Get("/{filename}", helm.DownloadPackageFile)
 			r.Post("/api/charts", reqPackageAccess(perm.AccessModeWrite), enforcePackagesQuota(), helm.UploadPackage)
 		}, reqPackageAccess(perm.AccessModeRead))
+		r.Group("/hex", func() {
+			r.Get("/names", hex.EnumeratePackages)
+			r.Get("/versions", hex.EnumeratePackageVersions)
+			r.Get("/packages/{name}", hex.PackageReleases)
+			r.Get("/tarballs/{filename}", hex.DownloadPackageFile)
+			r.Get("/public_key", hex.RepositoryPublicKey)
+			r.Group("/api", func() {
+				r.Post("/publish", reqPackageAccess(perm.AccessModeWrite), enforcePackagesQuota(), hex.UploadPackage)
+				r.Group("/packages/{name}", func() {
+					r.Get("", hex.PackageInfo)
+					r.Delete("/releases/{version}", reqPackageAccess(perm.AccessModeWrite), hex.DeletePackageVersion)
+				})
+			})
+		}, reqPackageAccess(perm.AccessModeRead))
 		r.Group("/maven", func() {
 			r.Put("/*", reqPackageAccess(perm.AccessModeWrite), enforcePackagesQuota(), maven.UploadPackageFile)
 			r.Get("/*", maven.DownloadPackageFile)
@@ -727,6 +792,20 @@ func CommonRoutes() *web.Route {
 			})
 			r.Get("/identifiers", swift.CheckAcceptMediaType(swift.AcceptJSON), swift.LookupPackageIdentifiers)
 		}, reqPackageAccess(perm.AccessModeRead))
+		r.Group("/terraform", func() {
+			r.Group("/modules/{name}/{system}/{version}", func() {
+				r.Put("", reqPackageAccess(perm.AccessModeWrite), enforcePackagesQuota(), terraform.UploadModule)
+				r.Get("/{filename}", terraform.DownloadModuleFile)
+			})
+			r.Group("/providers/{type}/{version}", func() {
+				r.Get("/SHA256SUMS", terraform.DownloadProviderSHA256Sums)
+				r.Get("/SHA256SUMS.sig", terraform.DownloadProviderSHA256SumsSignature)
+				r.Group("/{filename}", func() {
+					r.Get("", terraform.DownloadProviderFile)
+					r.Put("", reqPackageAccess(perm.AccessModeWrite), enforcePackagesQuota(), terraform.UploadProviderFile)
+				})
+			})
+		}, reqPackageAccess(perm.AccessModeRead))
 		r.Group("/vagrant", func() {
 			r.Group("/authenticate", func() {
 				r.Get("", vagrant.CheckAuthenticate)
@@ -774,25 +853,27 @@ func ContainerRoutes() *web.Route {
 					r.Put("", container.EndUploadBlob)
 					r.Delete("", container.CancelUploadBlob)
 				})
-			}, reqPackageAccess(perm.AccessModeWrite))
+			}, reqPackageAccess(perm.AccessModeWrite), container.VerifyRepositoryScope)
 			r.Group("/blobs/{digest}", func() {
 				r.Head("", container.HeadBlob)
 				r.Get("", container.GetBlob)
-				r.Delete("", reqPackageAccess(perm.AccessModeWrite), container.DeleteBlob)
+				r.Delete("", reqPackageAccess(perm.AccessModeWrite), container.VerifyRepositoryScope, container.DeleteBlob)
 			})
 			r.Group("/manifests/{reference}", func() {
-				r.Put("", reqPackageAccess(perm.AccessModeWrite), container.UploadManifest)
+				r.Put("", reqPackageAccess(perm.AccessModeWrite), container.VerifyRepositoryScope, container.UploadManifest)
 				r.Head("", container.HeadManifest)
 				r.Get("", container.GetManifest)
-				r.Delete("", reqPackageAccess(perm.AccessModeWrite), container.DeleteManifest)
+				r.Delete("", reqPackageAccess(perm.AccessModeWrite), container.VerifyRepositoryScope, container.DeleteManifest)
 			})
 			r.Get("/tags/list", container.GetTagList)
-		}, container.VerifyImageName)
+			r.Get("/referrers/{digest}", container.GetReferrers)
+		}, container.VerifyImageName, container.VerifyRepositoryScope)
 
 		var (
 			blobsUploadsPattern = regexp.MustCompile(`\A(.+)/blobs/uploads/([a-zA-Z0-9-_.=]+)\z`)
 			blobsPattern        = regexp.MustCompile(`\A(.+)/blobs/([^/]+)\z`)
 			manifestsPattern    = regexp.MustCompile(`\A(.+)/manifests/([^/]+)\z`)
+			referrersPattern    = regexp.MustCompile(`\A(.+)/referrers/([^/]+)\z`)
 		)
 
 		// Manual mapping of routes because {image} can contain slashes which chi does not support
@@ -816,6 +897,10 @@ func ContainerRoutes() *web.Route {
 				if ctx.Written() {
 					return
 				}
+				container.VerifyRepositoryScope(ctx)
+				if ctx.Written() {
+					return
+				}
 
 				container.InitiateUploadBlob(ctx)
 				return
@@ -826,6 +911,10 @@ func ContainerRoutes() *web.Route {
 				if ctx.Written() {
 					return
 				}
+				container.VerifyRepositoryScope(ctx)
+				if ctx.Written() {
+					return
+				}
 
 				container.GetTagList(ctx)
 				return
@@ -843,6 +932,10 @@ func ContainerRoutes() *web.Route {
 				if ctx.Written() {
 					return
 				}
+				container.VerifyRepositoryScope(ctx)
+				if ctx.Written() {
+					return
+				}
 
 				ctx.SetParams("uuid", m[2])
 
@@ -864,6 +957,10 @@ func ContainerRoutes() *web.Route {
 				if ctx.Written() {
 					return
 				}
+				container.VerifyRepositoryScope(ctx)
+				if ctx.Written() {
+					return
+				}
 
 				ctx.SetParams("digest", m[2])
 
@@ -876,6 +973,10 @@ func ContainerRoutes() *web.Route {
 					if ctx.Written() {
 						return
 					}
+					container.VerifyRepositoryScope(ctx)
+					if ctx.Written() {
+						return
+					}
 					container.DeleteBlob(ctx)
 				}
 				return
@@ -887,6 +988,10 @@ func ContainerRoutes() *web.Route {
 				if ctx.Written() {
 					return
 				}
+				container.VerifyRepositoryScope(ctx)
+				if ctx.Written() {
+					return
+				}
 
 				ctx.SetParams("reference", m[2])
 
@@ -899,6 +1004,10 @@ func ContainerRoutes() *web.Route {
 					if ctx.Written() {
 						return
 					}
+					container.VerifyRepositoryScope(ctx)
+					if ctx.Written() {
+						return
+					}
 					if isPut {
 						container.UploadManifest(ctx)
 					} else {
@@ -907,6 +1016,23 @@ func ContainerRoutes() *web.Route {
 				}
 				return
 			}
+			m = referrersPattern.FindStringSubmatch(path)
+			if len(m) == 3 && isGet {
+				ctx.SetParams("image", m[1])
+				container.VerifyImageName(ctx)
+				if ctx.Written() {
+					return
+				}
+				container.VerifyRepositoryScope(ctx)
+				if ctx.Written() {
+					return
+				}
+
+				ctx.SetParams("digest", m[2])
+
+				container.GetReferrers(ctx)
+				return
+			}
 
 			ctx.Status(http.StatusNotFound)
 		})
@@ -147,7 +147,7 @@ func PushPackage(ctx *context.Context) {
 		switch {
 		case errors.Is(err, packages_model.ErrDuplicatePackageVersion), errors.Is(err, packages_model.ErrDuplicatePackageFile):
 			apiError(ctx, http.StatusConflict, err)
-		case errors.Is(err, packages_service.ErrQuotaTotalCount), errors.Is(err, packages_service.ErrQuotaTypeSize), errors.Is(err, packages_service.ErrQuotaTotalSize):
+		case errors.Is(err, packages_service.ErrQuotaTotalCount), errors.Is(err, packages_service.ErrQuotaTypeSize), errors.Is(err, packages_service.ErrQuotaTotalSize), errors.Is(err, packages_service.ErrPackageAccessDenied):
 			apiError(ctx, http.StatusForbidden, err)
 		default:
 			apiError(ctx, http.StatusInternalServerError, err)
@@ -254,9 +254,13 @@ func RemovePackage(ctx *context.Context) {
 		if file.CompositeKey == group &&
 			strings.HasSuffix(file.LowerName, extName) {
 			deleted = true
-			err := packages_service.RemovePackageFileAndVersionIfUnreferenced(ctx, ctx.ContextUser, file)
+			err := packages_service.RemovePackageFileAndVersionIfUnreferenced(ctx, ctx.Doer, file)
 			if err != nil {
-				apiError(ctx, http.StatusInternalServerError, err)
+				if errors.Is(err, util.ErrPermissionDenied) {
+					apiError(ctx, http.StatusForbidden, err)
+				} else {
+					apiError(ctx, http.StatusInternalServerError, err)
+				}
 				return
 			}
 		}
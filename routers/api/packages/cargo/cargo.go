@@ -12,6 +12,7 @@ import (
 
 	"code.gitea.io/gitea/models/db"
 	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/perm"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/optional"
 	packages_module "code.gitea.io/gitea/modules/packages"
@@ -105,16 +106,19 @@ func SearchPackages(ctx *context.Context) {
 		PageSize: convert.ToCorrectPageSize(perPage),
 	}
 
-	pvs, total, err := packages_model.SearchLatestVersions(
-		ctx,
-		&packages_model.PackageSearchOptions{
-			OwnerID:    ctx.Package.Owner.ID,
-			Type:       packages_model.TypeCargo,
-			Name:       packages_model.SearchValue{Value: ctx.FormTrim("q")},
-			IsInternal: optional.Some(false),
-			Paginator:  &paginator,
-		},
-	)
+	opts := &packages_model.PackageSearchOptions{
+		OwnerID:    ctx.Package.Owner.ID,
+		Type:       packages_model.TypeCargo,
+		Name:       packages_model.SearchValue{Value: ctx.FormTrim("q")},
+		IsInternal: optional.Some(false),
+		Paginator:  &paginator,
+	}
+	if err := packages_service.LimitSearchByRepositoryScope(ctx, opts); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	pvs, total, err := packages_model.SearchLatestVersions(ctx, opts)
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -185,6 +189,10 @@ func DownloadPackageFile(ctx *context.Context) {
 			apiError(ctx, http.StatusNotFound, err)
 			return
 		}
+		if errors.Is(err, packages_service.ErrVersionBlocked) {
+			apiError(ctx, http.StatusForbidden, err)
+			return
+		}
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
@@ -243,7 +251,7 @@ func UploadPackage(ctx *context.Context) {
 		switch err {
 		case packages_model.ErrDuplicatePackageVersion:
 			apiError(ctx, http.StatusConflict, err)
-		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize:
+		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize, packages_service.ErrPackageAccessDenied:
 			apiError(ctx, http.StatusForbidden, err)
 		default:
 			apiError(ctx, http.StatusInternalServerError, err)
@@ -284,6 +292,15 @@ func yankPackage(ctx *context.Context, yank bool) {
 		return
 	}
 
+	if err := packages_service.CheckRepositoryScopeByPackageID(ctx, pv.PackageID, perm.AccessModeWrite); err != nil {
+		if errors.Is(err, util.ErrPermissionDenied) {
+			apiError(ctx, http.StatusForbidden, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
 	pps, err := packages_model.GetPropertiesByName(ctx, packages_model.PropertyTypeVersion, pv.ID, cargo_module.PropertyYanked)
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
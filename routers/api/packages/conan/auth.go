@@ -6,6 +6,7 @@ package conan
 import (
 	"net/http"
 
+	auth_model "code.gitea.io/gitea/models/auth"
 	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/services/auth"
@@ -22,7 +23,7 @@ func (a *Auth) Name() string {
 
 // Verify extracts the user from the Bearer token
 func (a *Auth) Verify(req *http.Request, w http.ResponseWriter, store auth.DataStore, sess auth.SessionStore) (*user_model.User, error) {
-	uid, scope, err := packages.ParseAuthorizationToken(req)
+	uid, scope, accessTokenID, err := packages.ParseAuthorizationToken(req)
 	if err != nil {
 		log.Trace("ParseAuthorizationToken: %v", err)
 		return nil, err
@@ -32,8 +33,17 @@ func (a *Auth) Verify(req *http.Request, w http.ResponseWriter, store auth.DataS
 		return nil, nil
 	}
 
-	// Propagate scope of the authorization token.
-	if scope != "" {
+	// Propagate the personal access token or the scope of the authorization token.
+	if accessTokenID > 0 {
+		t, err := auth_model.GetAccessTokenByID(req.Context(), accessTokenID)
+		if err != nil {
+			log.Trace("GetAccessTokenByID: %v", err)
+			return nil, err
+		}
+		if ok, err := auth.PropagateAccessToken(req.Context(), store, t); err != nil || !ok {
+			return nil, err
+		}
+	} else if scope != "" {
 		store.GetData()["IsApiToken"] = true
 		store.GetData()["ApiTokenScope"] = scope
 	}
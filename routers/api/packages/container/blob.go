@@ -104,6 +104,9 @@ func getOrCreateUploadVersion(ctx context.Context, pi *packages_service.PackageI
 			Name:      strings.ToLower(pi.Name),
 			LowerName: strings.ToLower(pi.Name),
 		}
+		if err := packages_service.LinkActionsTaskRepository(ctx, p); err != nil {
+			return err
+		}
 		var err error
 		if p, err = packages_model.TryInsertPackage(ctx, p); err != nil {
 			if err == packages_model.ErrDuplicatePackage {
@@ -17,7 +17,9 @@ import (
 	auth_model "code.gitea.io/gitea/models/auth"
 	packages_model "code.gitea.io/gitea/models/packages"
 	container_model "code.gitea.io/gitea/models/packages/container"
+	"code.gitea.io/gitea/models/perm"
 	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/container"
 	"code.gitea.io/gitea/modules/json"
 	"code.gitea.io/gitea/modules/log"
 	packages_module "code.gitea.io/gitea/modules/packages"
@@ -28,8 +30,11 @@ import (
 	"code.gitea.io/gitea/services/context"
 	packages_service "code.gitea.io/gitea/services/packages"
 	container_service "code.gitea.io/gitea/services/packages/container"
+	remote_service "code.gitea.io/gitea/services/packages/remote"
 
 	digest "github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	oci "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
 // maximum size of a container manifest
@@ -134,6 +139,22 @@ func VerifyImageName(ctx *context.Context) {
 	}
 }
 
+// VerifyRepositoryScope is a middleware which checks if a repository scoped request may access the image
+func VerifyRepositoryScope(ctx *context.Context) {
+	accessMode := perm.AccessModeWrite
+	if ctx.Req.Method == http.MethodGet || ctx.Req.Method == http.MethodHead {
+		accessMode = perm.AccessModeRead
+	}
+
+	if err := packages_service.CheckRepositoryScopeByName(ctx, ctx.Package.Owner.ID, packages_model.TypeContainer, ctx.Params("image"), accessMode); err != nil {
+		if errors.Is(err, packages_service.ErrPackageAccessDenied) {
+			apiErrorDefined(ctx, errDenied)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+	}
+}
+
 // DetermineSupport is used to test if the registry supports OCI
 // https://github.com/opencontainers/distribution-spec/blob/main/spec.md#determining-support
 func DetermineSupport(ctx *context.Context) {
@@ -157,8 +178,12 @@ func Authenticate(ctx *context.Context) {
 
 	// If there's an API scope, ensure it propagates.
 	scope, _ := ctx.Data["ApiTokenScope"].(auth_model.AccessTokenScope)
+	var accessTokenID int64
+	if t, ok := ctx.Data["ApiToken"].(*auth_model.AccessToken); ok {
+		accessTokenID = t.ID
+	}
 
-	token, err := packages_service.CreateAuthorizationToken(u, scope)
+	token, err := packages_service.CreateAuthorizationToken(u, scope, accessTokenID)
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -274,7 +299,7 @@ func InitiateUploadBlob(ctx *context.Context) {
 			},
 		); err != nil {
 			switch err {
-			case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize:
+			case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize, packages_service.ErrPackageAccessDenied:
 				apiError(ctx, http.StatusForbidden, err)
 			default:
 				apiError(ctx, http.StatusInternalServerError, err)
@@ -419,7 +444,7 @@ func EndUploadBlob(ctx *context.Context) {
 		},
 	); err != nil {
 		switch err {
-		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize:
+		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize, packages_service.ErrPackageAccessDenied:
 			apiError(ctx, http.StatusForbidden, err)
 		default:
 			apiError(ctx, http.StatusInternalServerError, err)
@@ -476,11 +501,29 @@ func getBlobFromContext(ctx *context.Context) (*packages_model.PackageFileDescri
 		return nil, container_model.ErrContainerBlobNotExist
 	}
 
-	return workaroundGetContainerBlob(ctx, &container_model.BlobSearchOptions{
+	opts := &container_model.BlobSearchOptions{
 		OwnerID: ctx.Package.Owner.ID,
 		Image:   ctx.Params("image"),
 		Digest:  d,
-	})
+	}
+
+	pfd, err := workaroundGetContainerBlob(ctx, opts)
+	if err != container_model.ErrContainerBlobNotExist {
+		return pfd, err
+	}
+
+	client, err := remote_service.GetClientForPackage(ctx, ctx.Package.Owner.ID, packages_model.TypeContainer, opts.Image)
+	if err == nil {
+		err = fetchRemoteBlob(ctx, client, opts.Image, digest.Digest(d))
+	}
+	if err != nil {
+		if isRemoteNotExistError(err) {
+			return nil, container_model.ErrContainerBlobNotExist
+		}
+		return nil, err
+	}
+
+	return workaroundGetContainerBlob(ctx, opts)
 }
 
 // https://github.com/opencontainers/distribution-spec/blob/main/spec.md#checking-if-content-exists-in-the-registry
@@ -576,7 +619,7 @@ func UploadManifest(ctx *context.Context) {
 			apiErrorDefined(ctx, errBlobUnknown)
 		} else {
 			switch err {
-			case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize:
+			case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize, packages_service.ErrPackageAccessDenied:
 				apiError(ctx, http.StatusForbidden, err)
 			default:
 				apiError(ctx, http.StatusInternalServerError, err)
@@ -585,6 +628,12 @@ func UploadManifest(ctx *context.Context) {
 		return
 	}
 
+	if mci.Subject != "" {
+		// Signal clients that the subject is processed and no referrers tag needs to be maintained
+		// https://github.com/opencontainers/distribution-spec/blob/main/spec.md#pushing-manifests-with-subject
+		ctx.Resp.Header().Set("OCI-Subject", mci.Subject)
+	}
+
 	setResponseHeaders(ctx.Resp, &containerHeaders{
 		Location:      fmt.Sprintf("/v2/%s/%s/manifests/%s", ctx.Package.Owner.LowerName, mci.Image, reference),
 		ContentDigest: digest,
@@ -618,7 +667,30 @@ func getManifestFromContext(ctx *context.Context) (*packages_model.PackageFileDe
 		return nil, err
 	}
 
-	return workaroundGetContainerBlob(ctx, opts)
+	pfd, err := workaroundGetContainerBlob(ctx, opts)
+	if err != nil && err != container_model.ErrContainerBlobNotExist {
+		return nil, err
+	}
+
+	fetched, remoteErr := syncRemoteManifest(ctx, opts, pfd)
+	if remoteErr != nil {
+		if errors.Is(remoteErr, packages_model.ErrPackageRemoteNotExist) {
+			return pfd, err
+		}
+		if pfd != nil {
+			// Serve the cached manifest if the upstream registry is not available
+			log.Warn("Unable to check manifest %s of image %s in upstream registry: %v", opts.Tag, opts.Image, remoteErr)
+			return pfd, nil
+		}
+		if isRemoteNotExistError(remoteErr) {
+			return nil, container_model.ErrContainerBlobNotExist
+		}
+		return nil, remoteErr
+	}
+	if fetched {
+		return workaroundGetContainerBlob(ctx, opts)
+	}
+	return pfd, err
 }
 
 // https://github.com/opencontainers/distribution-spec/blob/main/spec.md#checking-if-content-exists-in-the-registry
@@ -676,6 +748,28 @@ func DeleteManifest(ctx *context.Context) {
 		return
 	}
 
+	manifestDigest := opts.Digest
+	if manifestDigest == "" {
+		manifest, err := container_model.GetContainerBlob(ctx, opts)
+		if err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		manifestDigest = manifest.Properties.GetByName(container_module.PropertyDigest)
+	}
+
+	// Check all tags first to not delete only some of them
+	for _, pv := range pvs {
+		if err := packages_service.CheckVersionProtection(ctx, ctx.Doer, ctx.Package.Owner.ID, packages_model.TypeContainer, pv.LowerVersion); err != nil {
+			if errors.Is(err, packages_service.ErrVersionProtected) {
+				apiErrorDefined(ctx, errDenied.WithMessage("Tag is protected"))
+			} else {
+				apiError(ctx, http.StatusInternalServerError, err)
+			}
+			return
+		}
+	}
+
 	for _, pv := range pvs {
 		if err := packages_service.RemovePackageVersion(ctx, ctx.Doer, pv); err != nil {
 			apiError(ctx, http.StatusInternalServerError, err)
@@ -683,6 +777,11 @@ func DeleteManifest(ctx *context.Context) {
 		}
 	}
 
+	if err := container_service.RemoveDanglingReferrers(ctx, ctx.Doer, ctx.Package.Owner.ID, opts.Image, manifestDigest); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
 	setResponseHeaders(ctx.Resp, &containerHeaders{
 		Status: http.StatusAccepted,
 	})
@@ -764,6 +863,129 @@ func GetTagList(ctx *context.Context) {
 	})
 }
 
+// https://github.com/opencontainers/distribution-spec/blob/main/spec.md#listing-referrers
+func GetReferrers(ctx *context.Context) {
+	d := digest.Digest(ctx.Params("digest"))
+	if d.Validate() != nil {
+		apiErrorDefined(ctx, errDigestInvalid)
+		return
+	}
+
+	image := ctx.Params("image")
+	artifactType := ctx.FormTrim("artifactType")
+
+	pvs, err := container_model.GetReferrerVersions(ctx, ctx.Package.Owner.ID, image, string(d))
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	descriptors := make([]oci.Descriptor, 0, len(pvs))
+	known := make(container.Set[digest.Digest], len(pvs))
+	for _, pv := range pvs {
+		pf, err := packages_model.GetFileForVersionByName(ctx, pv.ID, container_model.ManifestFilename, packages_model.EmptyFileKey)
+		if err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		pfd, err := packages_model.GetPackageFileDescriptor(ctx, pf)
+		if err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+		var metadata container_module.Metadata
+		if err := json.Unmarshal([]byte(pv.MetadataJSON), &metadata); err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+		descriptor := oci.Descriptor{
+			MediaType:    pfd.Properties.GetByName(container_module.PropertyMediaType),
+			Digest:       digest.Digest(pfd.Properties.GetByName(container_module.PropertyDigest)),
+			Size:         pfd.Blob.Size,
+			ArtifactType: metadata.ArtifactType,
+			Annotations:  metadata.Annotations,
+		}
+		if known.Add(descriptor.Digest) {
+			descriptors = append(descriptors, descriptor)
+		}
+	}
+
+	// Include the referrers pushed by clients which used the referrers tag schema
+	fallback, err := getReferrersTagIndex(ctx, image, d)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if fallback != nil {
+		for _, descriptor := range fallback.Manifests {
+			if known.Add(descriptor.Digest) {
+				descriptors = append(descriptors, descriptor)
+			}
+		}
+	}
+
+	if artifactType != "" {
+		filtered := make([]oci.Descriptor, 0, len(descriptors))
+		for _, descriptor := range descriptors {
+			if descriptor.ArtifactType == artifactType {
+				filtered = append(filtered, descriptor)
+			}
+		}
+		descriptors = filtered
+
+		ctx.Resp.Header().Set("OCI-Filters-Applied", "artifactType")
+	}
+
+	setResponseHeaders(ctx.Resp, &containerHeaders{
+		Status:      http.StatusOK,
+		ContentType: oci.MediaTypeImageIndex,
+	})
+	if err := json.NewEncoder(ctx.Resp).Encode(oci.Index{
+		Versioned: specs.Versioned{
+			SchemaVersion: 2,
+		},
+		MediaType: oci.MediaTypeImageIndex,
+		Manifests: descriptors,
+	}); err != nil {
+		log.Error("JSON encode: %v", err)
+	}
+}
+
+// getReferrersTagIndex gets the index stored with the referrers tag of the digest
+func getReferrersTagIndex(ctx *context.Context, image string, d digest.Digest) (*oci.Index, error) {
+	pfd, err := container_model.GetContainerBlob(ctx, &container_model.BlobSearchOptions{
+		OwnerID:    ctx.Package.Owner.ID,
+		Image:      image,
+		Tag:        container_module.ReferrersTag(d),
+		IsManifest: true,
+	})
+	if err != nil {
+		if err == container_model.ErrContainerBlobNotExist {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	s, err := packages_module.NewContentStore().Get(packages_module.BlobHash256Key(pfd.Blob.HashSHA256))
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) || errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer s.Close()
+
+	var index oci.Index
+	if err := json.NewDecoder(s).Decode(&index); err != nil {
+		// A tag with this name may also be an unrelated image
+		log.Debug("Referrers tag of %s is not an index: %v", d, err)
+		return nil, nil
+	}
+	return &index, nil
+}
+
 // FIXME: Workaround to be removed in v1.20
 // https://github.com/go-gitea/gitea/issues/19586
 func workaroundGetContainerBlob(ctx *context.Context, opts *container_model.BlobSearchOptions) (*packages_model.PackageFileDescriptor, error) {
@@ -39,6 +39,12 @@ func isImageIndexMediaType(mt string) bool {
 	return strings.EqualFold(mt, oci.MediaTypeImageIndex) || strings.EqualFold(mt, "application/vnd.docker.distribution.manifest.list.v2+json")
 }
 
+// isArtifactManifest checks if the manifest describes an artifact (signature, SBOM, ...) instead of a runnable image
+// https://github.com/opencontainers/image-spec/blob/main/manifest.md#guidelines-for-artifact-usage
+func isArtifactManifest(manifest *oci.Manifest) bool {
+	return manifest.ArtifactType != "" || strings.EqualFold(manifest.Config.MediaType, oci.MediaTypeEmptyJSON)
+}
+
 // manifestCreationInfo describes a manifest to create
 type manifestCreationInfo struct {
 	MediaType  string
@@ -47,6 +53,8 @@ type manifestCreationInfo struct {
 	Image      string
 	Reference  string
 	IsTagged   bool
+	Digest     string
+	Subject    string
 	Properties map[string]string
 }
 
@@ -60,10 +68,19 @@ func processManifest(ctx context.Context, mci *manifestCreationInfo, buf *packag
 		return "", errUnsupported.WithMessage("Schema version is not supported")
 	}
 
+	if index.Subject != nil {
+		if index.Subject.Digest.Validate() != nil {
+			return "", errManifestInvalid.WithMessage("Subject digest is invalid")
+		}
+		mci.Subject = string(index.Subject.Digest)
+	}
+
 	if _, err := buf.Seek(0, io.SeekStart); err != nil {
 		return "", err
 	}
 
+	mci.Digest = digestFromHashSummer(buf)
+
 	if !isValidMediaType(mci.MediaType) {
 		mci.MediaType = index.MediaType
 		if !isValidMediaType(mci.MediaType) {
@@ -107,16 +124,34 @@ func processImageManifest(ctx context.Context, mci *manifestCreationInfo, buf *p
 			return err
 		}
 
-		configReader, err := packages_module.NewContentStore().Get(packages_module.BlobHash256Key(configDescriptor.Blob.HashSHA256))
-		if err != nil {
-			return err
+		var metadata *container_module.Metadata
+		if isArtifactManifest(&manifest) {
+			// The config of an artifact is not an image config and contains no useful metadata
+			metadata = &container_module.Metadata{
+				Type: container_module.TypeOCI,
+			}
+		} else {
+			configReader, err := packages_module.NewContentStore().Get(packages_module.BlobHash256Key(configDescriptor.Blob.HashSHA256))
+			if err != nil {
+				return err
+			}
+			defer configReader.Close()
+
+			metadata, err = container_module.ParseImageConfig(manifest.Config.MediaType, configReader)
+			if err != nil {
+				return err
+			}
 		}
-		defer configReader.Close()
 
-		metadata, err := container_module.ParseImageConfig(manifest.Config.MediaType, configReader)
-		if err != nil {
-			return err
+		metadata.ArtifactType = manifest.ArtifactType
+		if mci.Subject != "" {
+			metadata.Subject = mci.Subject
+			if metadata.ArtifactType == "" {
+				// https://github.com/opencontainers/distribution-spec/blob/main/spec.md#listing-referrers
+				metadata.ArtifactType = manifest.Config.MediaType
+			}
 		}
+		metadata.Annotations = manifest.Annotations
 
 		blobReferences := make([]*blobReference, 0, 1+len(manifest.Layers))
 
@@ -216,8 +251,11 @@ func processImageManifestIndex(ctx context.Context, mci *manifestCreationInfo, b
 		defer committer.Close()
 
 		metadata := &container_module.Metadata{
-			Type:      container_module.TypeOCI,
-			Manifests: make([]*container_module.Manifest, 0, len(index.Manifests)),
+			Type:         container_module.TypeOCI,
+			Manifests:    make([]*container_module.Manifest, 0, len(index.Manifests)),
+			ArtifactType: index.ArtifactType,
+			Subject:      mci.Subject,
+			Annotations:  index.Annotations,
 		}
 
 		for _, manifest := range index.Manifests {
@@ -311,6 +349,35 @@ func notifyPackageCreate(ctx context.Context, doer *user_model.User, pv *package
 	return nil
 }
 
+// checkTagProtection checks if the doer is allowed to overwrite the existing tag.
+// Pushing the same manifest again is always allowed.
+func checkTagProtection(ctx context.Context, mci *manifestCreationInfo, pv *packages_model.PackageVersion) error {
+	if !mci.IsTagged {
+		return nil
+	}
+
+	pfd, err := container_model.GetContainerBlob(ctx, &container_model.BlobSearchOptions{
+		OwnerID:    mci.Owner.ID,
+		Image:      mci.Image,
+		Tag:        pv.LowerVersion,
+		IsManifest: true,
+	})
+	if err != nil && err != container_model.ErrContainerBlobNotExist {
+		return err
+	}
+	if pfd != nil && pfd.Properties.GetByName(container_module.PropertyDigest) == mci.Digest {
+		return nil
+	}
+
+	if err := packages_service.CheckVersionProtection(ctx, mci.Creator, mci.Owner.ID, packages_model.TypeContainer, pv.LowerVersion); err != nil {
+		if errors.Is(err, packages_service.ErrVersionProtected) {
+			return errDenied.WithMessage("Tag is protected")
+		}
+		return err
+	}
+	return nil
+}
+
 func createPackageAndVersion(ctx context.Context, mci *manifestCreationInfo, metadata *container_module.Metadata) (*packages_model.PackageVersion, error) {
 	created := true
 	p := &packages_model.Package{
@@ -319,6 +386,9 @@ func createPackageAndVersion(ctx context.Context, mci *manifestCreationInfo, met
 		Name:      strings.ToLower(mci.Image),
 		LowerName: strings.ToLower(mci.Image),
 	}
+	if err := packages_service.LinkActionsTaskRepository(ctx, p); err != nil {
+		return nil, err
+	}
 	var err error
 	if p, err = packages_model.TryInsertPackage(ctx, p); err != nil {
 		if err == packages_model.ErrDuplicatePackage {
@@ -353,6 +423,10 @@ func createPackageAndVersion(ctx context.Context, mci *manifestCreationInfo, met
 	var pv *packages_model.PackageVersion
 	if pv, err = packages_model.GetOrInsertVersion(ctx, _pv); err != nil {
 		if err == packages_model.ErrDuplicatePackageVersion {
+			if err := checkTagProtection(ctx, mci, pv); err != nil {
+				return nil, err
+			}
+
 			if err := packages_service.DeletePackageVersionAndReferences(ctx, pv); err != nil {
 				return nil, err
 			}
@@ -374,6 +448,12 @@ func createPackageAndVersion(ctx context.Context, mci *manifestCreationInfo, met
 		return nil, err
 	}
 
+	for name, value := range mci.Properties {
+		if _, err := packages_model.InsertProperty(ctx, packages_model.PropertyTypeVersion, pv.ID, name, value); err != nil {
+			log.Error("Error setting package version property: %v", err)
+			return nil, err
+		}
+	}
 	if mci.IsTagged {
 		if _, err := packages_model.InsertProperty(ctx, packages_model.PropertyTypeVersion, pv.ID, container_module.PropertyManifestTagged, ""); err != nil {
 			log.Error("Error setting package version property: %v", err)
@@ -386,6 +466,12 @@ func createPackageAndVersion(ctx context.Context, mci *manifestCreationInfo, met
 			return nil, err
 		}
 	}
+	if metadata.Subject != "" {
+		if _, err := packages_model.InsertProperty(ctx, packages_model.PropertyTypeVersion, pv.ID, container_module.PropertyManifestSubject, metadata.Subject); err != nil {
+			log.Error("Error setting package version property: %v", err)
+			return nil, err
+		}
+	}
 
 	return pv, nil
 }
@@ -0,0 +1,208 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package container
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	container_model "code.gitea.io/gitea/models/packages/container"
+	"code.gitea.io/gitea/modules/json"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	container_module "code.gitea.io/gitea/modules/packages/container"
+	"code.gitea.io/gitea/services/context"
+	packages_service "code.gitea.io/gitea/services/packages"
+	remote_service "code.gitea.io/gitea/services/packages/remote"
+
+	digest "github.com/opencontainers/go-digest"
+	oci "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+var remoteManifestAccept = http.Header{
+	"Accept": []string{
+		oci.MediaTypeImageIndex,
+		oci.MediaTypeImageManifest,
+		"application/vnd.docker.distribution.manifest.list.v2+json",
+		"application/vnd.docker.distribution.manifest.v2+json",
+	},
+}
+
+// remoteImagePath returns the path of the image in the upstream registry.
+// Docker Hub stores official images in the "library" namespace.
+func remoteImagePath(client *remote_service.Client, image string) string {
+	if !strings.Contains(image, "/") && (client.Host() == "registry-1.docker.io" || client.Host() == "docker.io") {
+		image = "library/" + image
+	}
+	return "v2/" + image
+}
+
+// isRemoteNotExistError returns true if there is no remote or the upstream registry does not know the content
+func isRemoteNotExistError(err error) bool {
+	return errors.Is(err, packages_model.ErrPackageRemoteNotExist) || errors.Is(err, remote_service.ErrUpstreamNotExist)
+}
+
+// syncRemoteManifest fetches the manifest from the upstream registry if it does not exist locally.
+// Tags are mutable, so a cached tag gets refreshed if it points to a different manifest upstream.
+// Returns true if the manifest was fetched.
+func syncRemoteManifest(ctx *context.Context, opts *container_model.BlobSearchOptions, local *packages_model.PackageFileDescriptor) (bool, error) {
+	client, err := remote_service.GetClientForPackage(ctx, ctx.Package.Owner.ID, packages_model.TypeContainer, opts.Image)
+	if err != nil {
+		return false, err
+	}
+
+	reference := opts.Digest
+	if reference == "" {
+		reference = opts.Tag
+	}
+
+	if local != nil {
+		if opts.Tag == "" {
+			// manifests referenced by digest never change
+			return false, nil
+		}
+
+		resp, err := client.Head(ctx, remoteImagePath(client, opts.Image)+"/manifests/"+reference, remoteManifestAccept)
+		if err != nil {
+			return false, err
+		}
+		if resp.Header.Get("Docker-Content-Digest") == local.Properties.GetByName(container_module.PropertyDigest) {
+			return false, nil
+		}
+	}
+
+	return true, fetchRemoteManifest(ctx, client, opts.Image, reference)
+}
+
+// fetchRemoteManifest fetches the manifest and all referenced manifests and blobs from the upstream registry and stores them
+func fetchRemoteManifest(ctx *context.Context, client *remote_service.Client, image, reference string) error {
+	p := remoteImagePath(client, image) + "/manifests/" + reference
+
+	resp, err := client.Get(ctx, p, remoteManifestAccept)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	maxSize := maxManifestSize + 1
+	buf, err := packages_module.CreateHashedBufferFromReaderWithSize(&io.LimitedReader{R: resp.Body, N: int64(maxSize)}, maxSize)
+	if err != nil {
+		return err
+	}
+	defer buf.Close()
+
+	if buf.Size() > maxManifestSize {
+		return errManifestInvalid.WithMessage("Manifest exceeds maximum size")
+	}
+
+	isTagged := digest.Digest(reference).Validate() != nil
+	if !isTagged && digestFromHashSummer(buf) != reference {
+		return errManifestInvalid.WithMessage("Manifest digest does not match")
+	}
+
+	var manifest struct {
+		MediaType string           `json:"mediaType"`
+		Config    oci.Descriptor   `json:"config"`
+		Layers    []oci.Descriptor `json:"layers"`
+		Manifests []oci.Descriptor `json:"manifests"`
+	}
+	if err := json.NewDecoder(buf).Decode(&manifest); err != nil {
+		return err
+	}
+	if _, err := buf.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	mediaType, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+	if !isValidMediaType(mediaType) {
+		mediaType = manifest.MediaType
+	}
+
+	if isImageIndexMediaType(mediaType) {
+		for _, m := range manifest.Manifests {
+			if _, err := container_model.GetContainerBlob(ctx, &container_model.BlobSearchOptions{
+				OwnerID:    ctx.Package.Owner.ID,
+				Image:      image,
+				Digest:     string(m.Digest),
+				IsManifest: true,
+			}); err == nil {
+				continue
+			} else if err != container_model.ErrContainerBlobNotExist {
+				return err
+			}
+
+			if !isImageManifestMediaType(m.MediaType) {
+				return errManifestInvalid
+			}
+			if err := fetchRemoteManifest(ctx, client, image, string(m.Digest)); err != nil {
+				return err
+			}
+		}
+	} else if isImageManifestMediaType(mediaType) {
+		for _, d := range append([]oci.Descriptor{manifest.Config}, manifest.Layers...) {
+			if _, err := container_model.GetContainerBlob(ctx, &container_model.BlobSearchOptions{
+				OwnerID: ctx.Package.Owner.ID,
+				Image:   image,
+				Digest:  string(d.Digest),
+			}); err == nil {
+				continue
+			} else if err != container_model.ErrContainerBlobNotExist {
+				return err
+			}
+
+			if err := fetchRemoteBlob(ctx, client, image, d.Digest); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = processManifest(ctx, &manifestCreationInfo{
+		MediaType:  mediaType,
+		Owner:      ctx.Package.Owner,
+		Creator:    ctx.Package.Owner,
+		Image:      image,
+		Reference:  reference,
+		IsTagged:   isTagged,
+		Properties: remote_service.VersionProperties(client.URL(p)),
+	}, buf)
+	return err
+}
+
+// fetchRemoteBlob fetches the blob from the upstream registry and stores it in the upload version of the image
+func fetchRemoteBlob(ctx *context.Context, client *remote_service.Client, image string, d digest.Digest) error {
+	if d.Validate() != nil || d.Algorithm() != digest.SHA256 {
+		return errDigestInvalid
+	}
+
+	resp, err := client.Get(ctx, fmt.Sprintf("%s/blobs/%s", remoteImagePath(client, image), d), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	buf, err := packages_module.CreateHashedBufferFromReader(resp.Body)
+	if err != nil {
+		return err
+	}
+	defer buf.Close()
+
+	if digestFromHashSummer(buf) != string(d) {
+		return errDigestInvalid.WithMessage("Blob digest does not match")
+	}
+
+	_, err = saveAsPackageBlob(ctx,
+		buf,
+		&packages_service.PackageCreationInfo{
+			PackageInfo: packages_service.PackageInfo{
+				Owner: ctx.Package.Owner,
+				Name:  image,
+			},
+			Creator: ctx.Package.Owner,
+		},
+	)
+	return err
+}
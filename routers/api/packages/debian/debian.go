@@ -13,6 +13,7 @@ import (
 
 	"code.gitea.io/gitea/models/db"
 	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/perm"
 	packages_module "code.gitea.io/gitea/modules/packages"
 	debian_module "code.gitea.io/gitea/modules/packages/debian"
 	"code.gitea.io/gitea/modules/util"
@@ -190,7 +191,7 @@ func UploadPackageFile(ctx *context.Context) {
 		switch err {
 		case packages_model.ErrDuplicatePackageVersion, packages_model.ErrDuplicatePackageFile:
 			apiError(ctx, http.StatusConflict, err)
-		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize:
+		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize, packages_service.ErrPackageAccessDenied:
 			apiError(ctx, http.StatusForbidden, err)
 		default:
 			apiError(ctx, http.StatusInternalServerError, err)
@@ -247,6 +248,7 @@ func DeletePackageFile(ctx *context.Context) {
 	architecture := ctx.Params("architecture")
 
 	owner := ctx.Package.Owner
+	doer := ctx.Doer
 
 	var pd *packages_model.PackageDescriptor
 
@@ -256,6 +258,13 @@ func DeletePackageFile(ctx *context.Context) {
 			return err
 		}
 
+		if err := packages_service.CheckRepositoryScopeByPackageID(ctx, pv.PackageID, perm.AccessModeWrite); err != nil {
+			return err
+		}
+		if err := packages_service.CheckVersionProtection(ctx, doer, owner.ID, packages_model.TypeDebian, pv.Version); err != nil {
+			return err
+		}
+
 		pf, err := packages_model.GetFileForVersionByName(
 			ctx,
 			pv.ID,
@@ -290,6 +299,8 @@ func DeletePackageFile(ctx *context.Context) {
 	if err != nil {
 		if errors.Is(err, util.ErrNotExist) {
 			apiError(ctx, http.StatusNotFound, err)
+		} else if errors.Is(err, util.ErrPermissionDenied) {
+			apiError(ctx, http.StatusForbidden, err)
 		} else {
 			apiError(ctx, http.StatusInternalServerError, err)
 		}
@@ -28,7 +28,14 @@ func apiError(ctx *context.Context, status int, obj any) {
 }
 
 func EnumeratePackageVersions(ctx *context.Context) {
-	pvs, err := packages_model.GetVersionsByPackageName(ctx, ctx.Package.Owner.ID, packages_model.TypeGo, ctx.Params("name"))
+	if serveRemoteOrFallback(ctx, ctx.Params("name"), "@v/list") {
+		return
+	}
+
+	pvs, err := packages_model.GetVersionsByPackageName(ctx, ctx.Package.Owner.ID, packages_model.TypeGo, unescapePath(ctx.Params("name")))
+	if err == nil {
+		pvs, err = packages_service.FilterVersionsByRepositoryScope(ctx, pvs)
+	}
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -50,9 +57,17 @@ func EnumeratePackageVersions(ctx *context.Context) {
 }
 
 func PackageVersionMetadata(ctx *context.Context) {
+	if ctx.Params("version") == "latest" && serveRemoteOrFallback(ctx, ctx.Params("name"), "@latest") {
+		return
+	}
+
 	pv, err := resolvePackage(ctx, ctx.Package.Owner.ID, ctx.Params("name"), ctx.Params("version"))
 	if err != nil {
 		if errors.Is(err, util.ErrNotExist) {
+			if ctx.Params("version") != "latest" && serveRemoteOrFallback(ctx, ctx.Params("name"), "@v/"+ctx.Params("version")+".info") {
+				return
+			}
+
 			apiError(ctx, http.StatusNotFound, err)
 		} else {
 			apiError(ctx, http.StatusInternalServerError, err)
@@ -73,6 +88,10 @@ func PackageVersionGoModContent(ctx *context.Context) {
 	pv, err := resolvePackage(ctx, ctx.Package.Owner.ID, ctx.Params("name"), ctx.Params("version"))
 	if err != nil {
 		if errors.Is(err, util.ErrNotExist) {
+			if serveRemoteOrFallback(ctx, ctx.Params("name"), "@v/"+ctx.Params("version")+".mod") {
+				return
+			}
+
 			apiError(ctx, http.StatusNotFound, err)
 		} else {
 			apiError(ctx, http.StatusInternalServerError, err)
@@ -91,6 +110,11 @@ func PackageVersionGoModContent(ctx *context.Context) {
 
 func DownloadPackageFile(ctx *context.Context) {
 	pv, err := resolvePackage(ctx, ctx.Package.Owner.ID, ctx.Params("name"), ctx.Params("version"))
+	if errors.Is(err, util.ErrNotExist) {
+		if err = fetchRemotePackageVersion(ctx, ctx.Params("name"), ctx.Params("version")); err == nil {
+			pv, err = resolvePackage(ctx, ctx.Package.Owner.ID, ctx.Params("name"), ctx.Params("version"))
+		}
+	}
 	if err != nil {
 		if errors.Is(err, util.ErrNotExist) {
 			apiError(ctx, http.StatusNotFound, err)
@@ -110,6 +134,8 @@ func DownloadPackageFile(ctx *context.Context) {
 	if err != nil {
 		if errors.Is(err, util.ErrNotExist) {
 			apiError(ctx, http.StatusNotFound, err)
+		} else if errors.Is(err, packages_service.ErrVersionBlocked) {
+			apiError(ctx, http.StatusForbidden, err)
 		} else {
 			apiError(ctx, http.StatusInternalServerError, err)
 		}
@@ -122,6 +148,9 @@ func DownloadPackageFile(ctx *context.Context) {
 func resolvePackage(ctx *context.Context, ownerID int64, name, version string) (*packages_model.PackageVersion, error) {
 	var pv *packages_model.PackageVersion
 
+	name = unescapePath(name)
+	version = unescapePath(version)
+
 	if version == "latest" {
 		pvs, _, err := packages_model.SearchLatestVersions(ctx, &packages_model.PackageSearchOptions{
 			OwnerID: ownerID,
@@ -212,7 +241,7 @@ func UploadPackage(ctx *context.Context) {
 		switch err {
 		case packages_model.ErrDuplicatePackageVersion:
 			apiError(ctx, http.StatusConflict, err)
-		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize:
+		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize, packages_service.ErrPackageAccessDenied:
 			apiError(ctx, http.StatusForbidden, err)
 		default:
 			apiError(ctx, http.StatusInternalServerError, err)
@@ -0,0 +1,140 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package goproxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/modules/log"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	goproxy_module "code.gitea.io/gitea/modules/packages/goproxy"
+	"code.gitea.io/gitea/services/context"
+	packages_service "code.gitea.io/gitea/services/packages"
+	remote_service "code.gitea.io/gitea/services/packages/remote"
+)
+
+// unescapePath reverts the case encoding of module paths and versions
+// https://go.dev/ref/mod#goproxy-protocol
+func unescapePath(s string) string {
+	if !strings.Contains(s, "!") {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '!' && i+1 < len(s) && 'a' <= s[i+1] && s[i+1] <= 'z' {
+			i++
+			sb.WriteByte(s[i] - 'a' + 'A')
+			continue
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// serveRemote passes the response of the upstream proxy for the path through.
+// The path must use the escaped module path as sent by the client.
+func serveRemote(ctx *context.Context, name, p string) error {
+	client, err := remote_service.GetClientForPackage(ctx, ctx.Package.Owner.ID, packages_model.TypeGo, unescapePath(name))
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Get(ctx, name+"/"+p, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		ctx.Resp.Header().Set("Content-Type", contentType)
+	}
+	ctx.Resp.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(ctx.Resp, resp.Body); err != nil {
+		log.Error("Error whilst copying content to response: %v", err)
+	}
+	return nil
+}
+
+// serveRemoteOrFallback passes the response of the upstream proxy through if the package is served from there.
+// It returns false if the request should be answered from the cached versions.
+func serveRemoteOrFallback(ctx *context.Context, name, p string) bool {
+	err := serveRemote(ctx, name, p)
+	if err == nil {
+		return true
+	}
+	if !errors.Is(err, packages_model.ErrPackageRemoteNotExist) {
+		log.Warn("Unable to serve %s/%s from upstream proxy: %v", name, p, err)
+	}
+	return false
+}
+
+// fetchRemotePackageVersion fetches the module zip from the upstream proxy and stores it
+func fetchRemotePackageVersion(ctx *context.Context, name, version string) error {
+	client, err := remote_service.GetClientForPackage(ctx, ctx.Package.Owner.ID, packages_model.TypeGo, unescapePath(name))
+	if err != nil {
+		return err
+	}
+
+	p := fmt.Sprintf("%s/@v/%s.zip", name, version)
+
+	resp, err := client.Get(ctx, p, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	buf, err := packages_module.CreateHashedBufferFromReader(resp.Body)
+	if err != nil {
+		return err
+	}
+	defer buf.Close()
+
+	pck, err := goproxy_module.ParsePackage(buf, buf.Size())
+	if err != nil {
+		return err
+	}
+	if pck.Name != unescapePath(name) || pck.Version != unescapePath(version) {
+		return goproxy_module.ErrInvalidStructure
+	}
+
+	if _, err := buf.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	versionProperties := remote_service.VersionProperties(client.URL(p))
+	versionProperties[goproxy_module.PropertyGoMod] = pck.GoMod
+
+	_, _, err = packages_service.CreatePackageAndAddFile(
+		ctx,
+		&packages_service.PackageCreationInfo{
+			PackageInfo: packages_service.PackageInfo{
+				Owner:       ctx.Package.Owner,
+				PackageType: packages_model.TypeGo,
+				Name:        pck.Name,
+				Version:     pck.Version,
+			},
+			Creator:           ctx.Package.Owner,
+			VersionProperties: versionProperties,
+		},
+		&packages_service.PackageFileCreationInfo{
+			PackageFileInfo: packages_service.PackageFileInfo{
+				Filename: fmt.Sprintf("%v.zip", pck.Version),
+			},
+			Creator: ctx.Package.Owner,
+			Data:    buf,
+			IsLead:  true,
+		},
+	)
+	if err != nil && err != packages_model.ErrDuplicatePackageVersion {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package helper
+
+import (
+	"errors"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/perm"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/services/context"
+)
+
+// ResolveVirtualOwner changes the package owner of the request to the member of the virtual registry which provides the package.
+// The members are searched in order and the first member which contains the package and is readable by the doer wins.
+// If no member contains the package, the default owner of the virtual registry is used.
+// Nothing changes if the owner has no enabled virtual registry for the package type.
+// URLs returned to the client should be built from ctx.ContextUser, which stays the requested owner.
+func ResolveVirtualOwner(ctx *context.Context, packageType packages_model.Type, packageName string) error {
+	pvr, err := packages_model.GetEnabledVirtualRegistryByOwnerAndType(ctx, ctx.ContextUser.ID, packageType)
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageVirtualRegistryNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	for _, memberID := range pvr.MemberIDs {
+		if _, err := packages_model.GetPackageByName(ctx, memberID, packageType, packageName); err != nil {
+			if err == packages_model.ErrPackageNotExist {
+				continue
+			}
+			return err
+		}
+
+		if ok, err := setPackageOwner(ctx, memberID, perm.AccessModeRead); err != nil || ok {
+			return err
+		}
+	}
+
+	_, err = setPackageOwner(ctx, pvr.DefaultOwnerID, perm.AccessModeRead)
+	return err
+}
+
+// ResolveVirtualUploadOwner changes the package owner of the request to the default owner of the virtual registry.
+// It returns util.ErrPermissionDenied if the doer can't write to the default owner.
+// Nothing changes if the owner has no enabled virtual registry for the package type.
+func ResolveVirtualUploadOwner(ctx *context.Context, packageType packages_model.Type) error {
+	pvr, err := packages_model.GetEnabledVirtualRegistryByOwnerAndType(ctx, ctx.ContextUser.ID, packageType)
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageVirtualRegistryNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	ok, err := setPackageOwner(ctx, pvr.DefaultOwnerID, perm.AccessModeWrite)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return util.ErrPermissionDenied
+	}
+	return nil
+}
+
+// setPackageOwner changes the package owner of the request if the doer has at least the required access mode.
+// It returns false if the access is insufficient or the owner does not exist anymore.
+func setPackageOwner(ctx *context.Context, ownerID int64, required perm.AccessMode) (bool, error) {
+	owner, err := user_model.GetUserByID(ctx, ownerID)
+	if err != nil {
+		if user_model.IsErrUserNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	accessMode, err := context.DeterminePackageAccessMode(ctx.Base, owner, ctx.Doer)
+	if err != nil {
+		return false, err
+	}
+	accessMode, err = context.LimitPackageAccessModeByToken(ctx.Base, owner, accessMode)
+	if err != nil {
+		return false, err
+	}
+	if accessMode < required && !ctx.IsUserSiteAdmin() {
+		return false, nil
+	}
+
+	ctx.Package.Owner = owner
+	ctx.Package.AccessMode = accessMode
+	return true, nil
+}
@@ -0,0 +1,34 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package hex
+
+import (
+	"net/http"
+	"strings"
+
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/services/auth"
+)
+
+var _ auth.Method = &Auth{}
+
+type Auth struct{}
+
+func (a *Auth) Name() string {
+	return "hex"
+}
+
+// Verify extracts the user from the API key which the Hex client sends without an authentication scheme
+// https://hexdocs.pm/hex/Mix.Tasks.Hex.Repo.html#module-config-overrides
+func (a *Auth) Verify(req *http.Request, w http.ResponseWriter, store auth.DataStore, sess auth.SessionStore) (*user_model.User, error) {
+	key := req.Header.Get("Authorization")
+	if key == "" || strings.ContainsRune(key, ' ') {
+		return nil, nil
+	}
+
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+key)
+
+	return (&auth.OAuth2{}).Verify(r, w, store, sess)
+}
@@ -0,0 +1,313 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package hex
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	hex_module "code.gitea.io/gitea/modules/packages/hex"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/routers/api/packages/helper"
+	"code.gitea.io/gitea/services/context"
+	packages_service "code.gitea.io/gitea/services/packages"
+	hex_service "code.gitea.io/gitea/services/packages/hex"
+)
+
+const termContentType = "application/vnd.hex+erlang"
+
+func apiError(ctx *context.Context, status int, obj any) {
+	helper.LogAndProcessError(ctx, status, obj, func(message string) {
+		serveTerm(ctx, status, map[string]any{
+			"status":  status,
+			"message": message,
+		})
+	})
+}
+
+// serveTerm writes the value in the Erlang External Term Format which the Hex client expects from the API
+func serveTerm(ctx *context.Context, status int, v map[string]any) {
+	data, err := hex_module.MarshalTerm(v)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "MarshalTerm", err.Error())
+		return
+	}
+
+	ctx.Resp.Header().Set("Content-Type", termContentType)
+	ctx.Resp.WriteHeader(status)
+	_, _ = ctx.Resp.Write(data)
+}
+
+func serveRegistryResource(ctx *context.Context, name string, data []byte) {
+	ctx.ServeContent(bytes.NewReader(data), &context.ServeHeaderOptions{
+		ContentType:  "application/octet-stream",
+		Filename:     name,
+		LastModified: time.Now(),
+	})
+}
+
+func apiURL(ctx *context.Context) string {
+	return fmt.Sprintf("%sapi/packages/%s/hex/api", setting.AppURL, url.PathEscape(ctx.Package.Owner.Name))
+}
+
+// https://github.com/hexpm/specifications/blob/main/endpoints.md#repository
+
+// EnumeratePackages serves the /names resource
+func EnumeratePackages(ctx *context.Context) {
+	data, err := hex_service.BuildNames(ctx, ctx.Package.Owner)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	serveRegistryResource(ctx, "names", data)
+}
+
+// EnumeratePackageVersions serves the /versions resource
+func EnumeratePackageVersions(ctx *context.Context) {
+	data, err := hex_service.BuildVersions(ctx, ctx.Package.Owner)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	serveRegistryResource(ctx, "versions", data)
+}
+
+// PackageReleases serves the /packages/{name} resource
+func PackageReleases(ctx *context.Context) {
+	name := ctx.Params("name")
+
+	data, err := hex_service.BuildPackage(ctx, ctx.Package.Owner, name)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	serveRegistryResource(ctx, name, data)
+}
+
+// RepositoryPublicKey serves the public key used to verify the registry resources
+func RepositoryPublicKey(ctx *context.Context) {
+	_, pub, err := hex_service.GetOrCreateKeyPair(ctx, ctx.Package.Owner.ID)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.ServeContent(strings.NewReader(pub), &context.ServeHeaderOptions{
+		ContentType: "application/x-pem-file",
+		Filename:    "public_key",
+	})
+}
+
+// DownloadPackageFile serves the tarball of a package version
+func DownloadPackageFile(ctx *context.Context) {
+	filename := ctx.Params("filename")
+
+	name, version, ok := strings.Cut(strings.TrimSuffix(filename, ".tar"), "-")
+	if !ok || !strings.HasSuffix(filename, ".tar") {
+		apiError(ctx, http.StatusNotFound, nil)
+		return
+	}
+
+	s, u, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(
+		ctx,
+		&packages_service.PackageInfo{
+			Owner:       ctx.Package.Owner,
+			PackageType: packages_model.TypeHex,
+			Name:        name,
+			Version:     version,
+		},
+		&packages_service.PackageFileInfo{
+			Filename: filename,
+		},
+	)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		if errors.Is(err, packages_service.ErrVersionBlocked) {
+			apiError(ctx, http.StatusForbidden, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	helper.ServePackageFile(ctx, s, u, pf)
+}
+
+// https://github.com/hexpm/specifications/blob/main/endpoints.md#http-api
+
+// UploadPackage publishes a package tarball created by "mix hex.build"
+func UploadPackage(ctx *context.Context) {
+	upload, needToClose, err := ctx.UploadStream()
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if needToClose {
+		defer upload.Close()
+	}
+
+	buf, err := packages_module.CreateHashedBufferFromReader(upload)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	defer buf.Close()
+
+	pck, err := hex_module.ParsePackage(buf)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			apiError(ctx, http.StatusUnprocessableEntity, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if _, err := buf.Seek(0, io.SeekStart); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	pv, _, err := packages_service.CreatePackageAndAddFile(
+		ctx,
+		&packages_service.PackageCreationInfo{
+			PackageInfo: packages_service.PackageInfo{
+				Owner:       ctx.Package.Owner,
+				PackageType: packages_model.TypeHex,
+				Name:        pck.Name,
+				Version:     pck.Version,
+			},
+			SemverCompatible: true,
+			Creator:          ctx.Doer,
+			Metadata:         pck.Metadata,
+		},
+		&packages_service.PackageFileCreationInfo{
+			PackageFileInfo: packages_service.PackageFileInfo{
+				Filename: hex_module.TarballFilename(pck.Name, pck.Version),
+			},
+			Creator: ctx.Doer,
+			Data:    buf,
+			IsLead:  true,
+			Properties: map[string]string{
+				hex_module.PropertyInnerChecksum: pck.InnerChecksum,
+			},
+		},
+	)
+	if err != nil {
+		switch err {
+		case packages_model.ErrDuplicatePackageVersion:
+			apiError(ctx, http.StatusConflict, err)
+		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize, packages_service.ErrPackageAccessDenied:
+			apiError(ctx, http.StatusForbidden, err)
+		default:
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	pd, err := packages_model.GetPackageDescriptor(ctx, pv)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	serveTerm(ctx, http.StatusCreated, map[string]any{
+		"version":        pd.Version.Version,
+		"html_url":       pd.VersionHTMLURL(),
+		"url":            fmt.Sprintf("%s/packages/%s/releases/%s", apiURL(ctx), url.PathEscape(pd.Package.Name), url.PathEscape(pd.Version.Version)),
+		"inner_checksum": pck.InnerChecksum,
+		"outer_checksum": pd.Files[0].Blob.HashSHA256,
+	})
+}
+
+// PackageInfo returns the information about a package and its releases
+func PackageInfo(ctx *context.Context) {
+	name := ctx.Params("name")
+
+	pvs, err := packages_model.GetVersionsByPackageName(ctx, ctx.Package.Owner.ID, packages_model.TypeHex, name)
+	if err == nil {
+		pvs, err = packages_service.FilterVersionsByRepositoryScope(ctx, pvs)
+	}
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if len(pvs) == 0 {
+		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageNotExist)
+		return
+	}
+
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	releases := make([]any, 0, len(pds))
+	for _, pd := range pds {
+		releases = append(releases, map[string]any{
+			"version":     pd.Version.Version,
+			"url":         fmt.Sprintf("%s/packages/%s/releases/%s", apiURL(ctx), url.PathEscape(pd.Package.Name), url.PathEscape(pd.Version.Version)),
+			"inserted_at": pd.Version.CreatedUnix.AsTime().UTC().Format(time.RFC3339),
+		})
+	}
+
+	serveTerm(ctx, http.StatusOK, map[string]any{
+		"name":       pds[0].Package.Name,
+		"repository": hex_service.RepositoryName(ctx.Package.Owner),
+		"html_url":   pds[0].PackageHTMLURL(),
+		"url":        fmt.Sprintf("%s/packages/%s", apiURL(ctx), url.PathEscape(pds[0].Package.Name)),
+		"meta": map[string]any{
+			"description": pds[0].Metadata.(*hex_module.Metadata).Description,
+		},
+		"releases": releases,
+	})
+}
+
+// DeletePackageVersion reverts a published release
+func DeletePackageVersion(ctx *context.Context) {
+	err := packages_service.RemovePackageVersionByNameAndVersion(
+		ctx,
+		ctx.Doer,
+		&packages_service.PackageInfo{
+			Owner:       ctx.Package.Owner,
+			PackageType: packages_model.TypeHex,
+			Name:        ctx.Params("name"),
+			Version:     ctx.Params("version"),
+		},
+	)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		if errors.Is(err, util.ErrPermissionDenied) {
+			apiError(ctx, http.StatusForbidden, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -11,6 +11,7 @@ import (
 	"encoding/hex"
 	"encoding/xml"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"path/filepath"
@@ -24,9 +25,11 @@ import (
 	"code.gitea.io/gitea/modules/log"
 	packages_module "code.gitea.io/gitea/modules/packages"
 	maven_module "code.gitea.io/gitea/modules/packages/maven"
+	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/routers/api/packages/helper"
 	"code.gitea.io/gitea/services/context"
 	packages_service "code.gitea.io/gitea/services/packages"
+	remote_service "code.gitea.io/gitea/services/packages/remote"
 )
 
 const (
@@ -77,6 +80,11 @@ func handlePackageFile(ctx *context.Context, serveContent bool) {
 		return
 	}
 
+	if err := helper.ResolveVirtualOwner(ctx, packages_model.TypeMaven, params.GroupID+"-"+params.ArtifactID); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
 	if params.IsMeta && params.Version == "" {
 		serveMavenMetadata(ctx, params)
 	} else {
@@ -88,7 +96,24 @@ func serveMavenMetadata(ctx *context.Context, params parameters) {
 	// /com/foo/project/maven-metadata.xml[.md5/.sha1/.sha256/.sha512]
 
 	packageName := params.GroupID + "-" + params.ArtifactID
+
+	client, err := remote_service.GetClientForPackage(ctx, ctx.Package.Owner.ID, packages_model.TypeMaven, packageName)
+	if err == nil {
+		err = serveRemoteMavenMetadata(ctx, client, params)
+		if err == nil {
+			return
+		}
+		// Fall back to the cached versions
+		log.Warn("Unable to serve metadata of Maven package %s from upstream repository: %v", packageName, err)
+	} else if !errors.Is(err, packages_model.ErrPackageRemoteNotExist) {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
 	pvs, err := packages_model.GetVersionsByPackageName(ctx, ctx.Package.Owner.ID, packages_model.TypeMaven, packageName)
+	if err == nil {
+		pvs, err = packages_service.FilterVersionsByRepositoryScope(ctx, pvs)
+	}
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -151,16 +176,6 @@ func serveMavenMetadata(ctx *context.Context, params parameters) {
 func servePackageFile(ctx *context.Context, params parameters, serveContent bool) {
 	packageName := params.GroupID + "-" + params.ArtifactID
 
-	pv, err := packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypeMaven, packageName, params.Version)
-	if err != nil {
-		if err == packages_model.ErrPackageNotExist {
-			apiError(ctx, http.StatusNotFound, err)
-		} else {
-			apiError(ctx, http.StatusInternalServerError, err)
-		}
-		return
-	}
-
 	filename := params.Filename
 
 	ext := strings.ToLower(filepath.Ext(filename))
@@ -168,9 +183,22 @@ func servePackageFile(ctx *context.Context, params parameters, serveContent bool
 		filename = filename[:len(filename)-len(ext)]
 	}
 
-	pf, err := packages_model.GetFileForVersionByName(ctx, pv.ID, filename, packages_model.EmptyFileKey)
+	getPackageFile := func() (*packages_model.PackageFile, error) {
+		pv, err := packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypeMaven, packageName, params.Version)
+		if err != nil {
+			return nil, err
+		}
+		return packages_model.GetFileForVersionByName(ctx, pv.ID, filename, packages_model.EmptyFileKey)
+	}
+
+	pf, err := getPackageFile()
+	if err == packages_model.ErrPackageNotExist || err == packages_model.ErrPackageFileNotExist {
+		if err = fetchRemotePackageFile(ctx, params); err == nil {
+			pf, err = getPackageFile()
+		}
+	}
 	if err != nil {
-		if err == packages_model.ErrPackageFileNotExist {
+		if isNotExistError(err) {
 			apiError(ctx, http.StatusNotFound, err)
 		} else {
 			apiError(ctx, http.StatusInternalServerError, err)
@@ -219,6 +247,10 @@ func servePackageFile(ctx *context.Context, params parameters, serveContent bool
 
 	s, u, _, err := packages_service.GetPackageBlobStream(ctx, pf, pb)
 	if err != nil {
+		if errors.Is(err, packages_service.ErrVersionBlocked) {
+			apiError(ctx, http.StatusForbidden, err)
+			return
+		}
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
@@ -244,6 +276,15 @@ func UploadPackageFile(ctx *context.Context) {
 		return
 	}
 
+	if err := helper.ResolveVirtualUploadOwner(ctx, packages_model.TypeMaven); err != nil {
+		if errors.Is(err, util.ErrPermissionDenied) {
+			apiError(ctx, http.StatusForbidden, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
 	packageName := params.GroupID + "-" + params.ArtifactID
 
 	buf, err := packages_module.CreateHashedBufferFromReader(ctx.Req.Body)
@@ -324,35 +365,12 @@ func UploadPackageFile(ctx *context.Context) {
 	if ext == extensionPom {
 		pfci.IsLead = true
 
-		var err error
-		pvci.Metadata, err = maven_module.ParsePackageMetaData(buf)
-		if err != nil {
-			apiError(ctx, http.StatusBadRequest, err)
-			return
-		}
-
-		if pvci.Metadata != nil {
-			pv, err := packages_model.GetVersionByNameAndVersion(ctx, pvci.Owner.ID, pvci.PackageType, pvci.Name, pvci.Version)
-			if err != nil && err != packages_model.ErrPackageNotExist {
+		if err := setMetadataFromPom(ctx, pvci, buf); err != nil {
+			if errors.Is(err, errInvalidPom) {
+				apiError(ctx, http.StatusBadRequest, err)
+			} else {
 				apiError(ctx, http.StatusInternalServerError, err)
-				return
 			}
-			if pv != nil {
-				raw, err := json.Marshal(pvci.Metadata)
-				if err != nil {
-					apiError(ctx, http.StatusInternalServerError, err)
-					return
-				}
-				pv.MetadataJSON = string(raw)
-				if err := packages_model.UpdateVersion(ctx, pv); err != nil {
-					apiError(ctx, http.StatusInternalServerError, err)
-					return
-				}
-			}
-		}
-
-		if _, err := buf.Seek(0, io.SeekStart); err != nil {
-			apiError(ctx, http.StatusInternalServerError, err)
 			return
 		}
 	}
@@ -366,7 +384,7 @@ func UploadPackageFile(ctx *context.Context) {
 		switch err {
 		case packages_model.ErrDuplicatePackageFile:
 			apiError(ctx, http.StatusConflict, err)
-		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize:
+		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize, packages_service.ErrPackageAccessDenied:
 			apiError(ctx, http.StatusForbidden, err)
 		default:
 			apiError(ctx, http.StatusInternalServerError, err)
@@ -377,6 +395,37 @@ func UploadPackageFile(ctx *context.Context) {
 	ctx.Status(http.StatusCreated)
 }
 
+var errInvalidPom = errors.New("pom file is invalid")
+
+// setMetadataFromPom extracts the metadata from the pom file and updates the metadata of an existing package version
+func setMetadataFromPom(ctx *context.Context, pvci *packages_service.PackageCreationInfo, buf *packages_module.HashedBuffer) error {
+	var err error
+	pvci.Metadata, err = maven_module.ParsePackageMetaData(buf)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidPom, err)
+	}
+
+	if pvci.Metadata != nil {
+		pv, err := packages_model.GetVersionByNameAndVersion(ctx, pvci.Owner.ID, pvci.PackageType, pvci.Name, pvci.Version)
+		if err != nil && err != packages_model.ErrPackageNotExist {
+			return err
+		}
+		if pv != nil {
+			raw, err := json.Marshal(pvci.Metadata)
+			if err != nil {
+				return err
+			}
+			pv.MetadataJSON = string(raw)
+			if err := packages_model.UpdateVersion(ctx, pv); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = buf.Seek(0, io.SeekStart)
+	return err
+}
+
 func isChecksumExtension(ext string) bool {
 	return ext == extensionMD5 || ext == extensionSHA1 || ext == extensionSHA256 || ext == extensionSHA512
 }
@@ -0,0 +1,127 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package maven
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/modules/log"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	"code.gitea.io/gitea/services/context"
+	packages_service "code.gitea.io/gitea/services/packages"
+	remote_service "code.gitea.io/gitea/services/packages/remote"
+)
+
+func remotePath(params parameters, filename string) string {
+	p := strings.ReplaceAll(params.GroupID, ".", "/") + "/" + params.ArtifactID
+	if params.Version != "" {
+		p += "/" + params.Version
+	}
+	return p + "/" + filename
+}
+
+// serveRemoteMavenMetadata passes the package index of the upstream repository through
+func serveRemoteMavenMetadata(ctx *context.Context, client *remote_service.Client, params parameters) error {
+	resp, err := client.Get(ctx, remotePath(params, params.Filename), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if isChecksumExtension(strings.ToLower(filepath.Ext(params.Filename))) {
+		ctx.Resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		ctx.Resp.Header().Set("Content-Type", contentTypeXML)
+	}
+	if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" {
+		ctx.Resp.Header().Set("Last-Modified", lastModified)
+	}
+	ctx.Resp.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(ctx.Resp, resp.Body); err != nil {
+		log.Error("Error whilst copying content to response: %v", err)
+	}
+	return nil
+}
+
+// fetchRemotePackageFile fetches the package file from the upstream repository and stores it
+func fetchRemotePackageFile(ctx *context.Context, params parameters) error {
+	packageName := params.GroupID + "-" + params.ArtifactID
+
+	client, err := remote_service.GetClientForPackage(ctx, ctx.Package.Owner.ID, packages_model.TypeMaven, packageName)
+	if err != nil {
+		return err
+	}
+
+	// Checksums are calculated from the stored file
+	filename := params.Filename
+	ext := strings.ToLower(filepath.Ext(filename))
+	if isChecksumExtension(ext) {
+		filename = filename[:len(filename)-len(ext)]
+		ext = strings.ToLower(filepath.Ext(filename))
+	}
+
+	p := remotePath(params, filename)
+
+	resp, err := client.Get(ctx, p, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	buf, err := packages_module.CreateHashedBufferFromReader(resp.Body)
+	if err != nil {
+		return err
+	}
+	defer buf.Close()
+
+	pvci := &packages_service.PackageCreationInfo{
+		PackageInfo: packages_service.PackageInfo{
+			Owner:       ctx.Package.Owner,
+			PackageType: packages_model.TypeMaven,
+			Name:        packageName,
+			Version:     params.Version,
+		},
+		SemverCompatible:  false,
+		Creator:           ctx.Package.Owner,
+		VersionProperties: remote_service.VersionProperties(client.URL(remotePath(params, ""))),
+	}
+
+	pfci := &packages_service.PackageFileCreationInfo{
+		PackageFileInfo: packages_service.PackageFileInfo{
+			Filename: filename,
+		},
+		Creator:           ctx.Package.Owner,
+		Data:              buf,
+		IsLead:            false,
+		OverwriteExisting: params.IsMeta,
+	}
+
+	if ext == extensionPom {
+		pfci.IsLead = true
+
+		if err := setMetadataFromPom(ctx, pvci, buf); err != nil {
+			return err
+		}
+	}
+
+	_, _, err = packages_service.CreatePackageOrAddFileToExisting(ctx, pvci, pfci)
+	if err != nil && err != packages_model.ErrDuplicatePackageFile {
+		return err
+	}
+	return nil
+}
+
+// isNotExistError returns true if the content exists neither locally nor in the upstream repository
+func isNotExistError(err error) bool {
+	return err == packages_model.ErrPackageNotExist ||
+		err == packages_model.ErrPackageFileNotExist ||
+		errors.Is(err, packages_model.ErrPackageRemoteNotExist) ||
+		errors.Is(err, remote_service.ErrUpstreamNotExist)
+}
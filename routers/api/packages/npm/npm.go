@@ -14,9 +14,11 @@ import (
 
 	"code.gitea.io/gitea/models/db"
 	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/perm"
 	access_model "code.gitea.io/gitea/models/perm/access"
 	repo_model "code.gitea.io/gitea/models/repo"
 	"code.gitea.io/gitea/models/unit"
+	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/optional"
 	packages_module "code.gitea.io/gitea/modules/packages"
 	npm_module "code.gitea.io/gitea/modules/packages/npm"
@@ -25,6 +27,7 @@ import (
 	"code.gitea.io/gitea/routers/api/packages/helper"
 	"code.gitea.io/gitea/services/context"
 	packages_service "code.gitea.io/gitea/services/packages"
+	remote_service "code.gitea.io/gitea/services/packages/remote"
 
 	"github.com/hashicorp/go-version"
 )
@@ -55,7 +58,28 @@ func packageNameFromParams(ctx *context.Context) string {
 func PackageMetadata(ctx *context.Context) {
 	packageName := packageNameFromParams(ctx)
 
+	if err := helper.ResolveVirtualOwner(ctx, packages_model.TypeNpm, packageName); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	client, err := remote_service.GetClientForPackage(ctx, ctx.Package.Owner.ID, packages_model.TypeNpm, packageName)
+	if err == nil {
+		err = serveRemotePackageMetadata(ctx, client, packageName)
+		if err == nil {
+			return
+		}
+		// Fall back to the cached versions
+		log.Warn("Unable to serve metadata of npm package %s from upstream registry: %v", packageName, err)
+	} else if !errors.Is(err, packages_model.ErrPackageRemoteNotExist) {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
 	pvs, err := packages_model.GetVersionsByPackageName(ctx, ctx.Package.Owner.ID, packages_model.TypeNpm, packageName)
+	if err == nil {
+		pvs, err = packages_service.FilterVersionsByRepositoryScope(ctx, pvs)
+	}
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -72,7 +96,7 @@ func PackageMetadata(ctx *context.Context) {
 	}
 
 	resp := createPackageMetadataResponse(
-		setting.AppURL+"api/packages/"+ctx.Package.Owner.Name+"/npm",
+		setting.AppURL+"api/packages/"+ctx.ContextUser.Name+"/npm",
 		pds,
 	)
 
@@ -85,23 +109,36 @@ func DownloadPackageFile(ctx *context.Context) {
 	packageVersion := ctx.Params("version")
 	filename := ctx.Params("filename")
 
-	s, u, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(
-		ctx,
-		&packages_service.PackageInfo{
-			Owner:       ctx.Package.Owner,
-			PackageType: packages_model.TypeNpm,
-			Name:        packageName,
-			Version:     packageVersion,
-		},
-		&packages_service.PackageFileInfo{
-			Filename: filename,
-		},
-	)
+	if err := helper.ResolveVirtualOwner(ctx, packages_model.TypeNpm, packageName); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	pvi := &packages_service.PackageInfo{
+		Owner:       ctx.Package.Owner,
+		PackageType: packages_model.TypeNpm,
+		Name:        packageName,
+		Version:     packageVersion,
+	}
+	pfi := &packages_service.PackageFileInfo{
+		Filename: filename,
+	}
+
+	s, u, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(ctx, pvi, pfi)
+	if err == packages_model.ErrPackageNotExist {
+		if err = fetchRemotePackageVersion(ctx, packageName, packageVersion); err == nil {
+			s, u, pf, err = packages_service.GetFileStreamByPackageNameAndVersion(ctx, pvi, pfi)
+		}
+	}
 	if err != nil {
-		if err == packages_model.ErrPackageNotExist || err == packages_model.ErrPackageFileNotExist {
+		if isNotExistError(err) {
 			apiError(ctx, http.StatusNotFound, err)
 			return
 		}
+		if errors.Is(err, packages_service.ErrVersionBlocked) || errors.Is(err, packages_service.ErrQuotaTypeSize) {
+			apiError(ctx, http.StatusForbidden, err)
+			return
+		}
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
@@ -112,18 +149,41 @@ func DownloadPackageFile(ctx *context.Context) {
 // DownloadPackageFileByName finds the version and serves the contents of a package
 func DownloadPackageFileByName(ctx *context.Context) {
 	filename := ctx.Params("filename")
+	packageName := packageNameFromParams(ctx)
+
+	if err := helper.ResolveVirtualOwner(ctx, packages_model.TypeNpm, packageName); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
 
-	pvs, _, err := packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
+	opts := &packages_model.PackageSearchOptions{
 		OwnerID: ctx.Package.Owner.ID,
 		Type:    packages_model.TypeNpm,
 		Name: packages_model.SearchValue{
 			ExactMatch: true,
-			Value:      packageNameFromParams(ctx),
+			Value:      packageName,
 		},
 		HasFileWithName: filename,
 		IsInternal:      optional.Some(false),
-	})
+	}
+
+	pvs, _, err := packages_model.SearchVersions(ctx, opts)
+	if err == nil && len(pvs) == 0 {
+		if packageVersion := versionFromFilename(packageName, filename); packageVersion != "" {
+			if err = fetchRemotePackageVersion(ctx, packageName, packageVersion); err == nil {
+				pvs, _, err = packages_model.SearchVersions(ctx, opts)
+			}
+		}
+	}
 	if err != nil {
+		if isNotExistError(err) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		if errors.Is(err, packages_service.ErrVersionBlocked) || errors.Is(err, packages_service.ErrQuotaTypeSize) {
+			apiError(ctx, http.StatusForbidden, err)
+			return
+		}
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
@@ -144,6 +204,10 @@ func DownloadPackageFileByName(ctx *context.Context) {
 			apiError(ctx, http.StatusNotFound, err)
 			return
 		}
+		if errors.Is(err, packages_service.ErrVersionBlocked) {
+			apiError(ctx, http.StatusForbidden, err)
+			return
+		}
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
@@ -153,6 +217,15 @@ func DownloadPackageFileByName(ctx *context.Context) {
 
 // UploadPackage creates a new package
 func UploadPackage(ctx *context.Context) {
+	if err := helper.ResolveVirtualUploadOwner(ctx, packages_model.TypeNpm); err != nil {
+		if errors.Is(err, util.ErrPermissionDenied) {
+			apiError(ctx, http.StatusForbidden, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
 	npmPackage, err := npm_module.ParsePackage(ctx.Req.Body)
 	if err != nil {
 		if errors.Is(err, util.ErrInvalidArgument) {
@@ -216,7 +289,7 @@ func UploadPackage(ctx *context.Context) {
 		switch err {
 		case packages_model.ErrDuplicatePackageVersion:
 			apiError(ctx, http.StatusConflict, err)
-		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize:
+		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize, packages_service.ErrPackageAccessDenied:
 			apiError(ctx, http.StatusForbidden, err)
 		default:
 			apiError(ctx, http.StatusInternalServerError, err)
@@ -271,6 +344,10 @@ func DeletePackageVersion(ctx *context.Context) {
 			apiError(ctx, http.StatusNotFound, err)
 			return
 		}
+		if errors.Is(err, util.ErrPermissionDenied) {
+			apiError(ctx, http.StatusForbidden, err)
+			return
+		}
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
@@ -295,7 +372,11 @@ func DeletePackage(ctx *context.Context) {
 
 	for _, pv := range pvs {
 		if err := packages_service.RemovePackageVersion(ctx, ctx.Doer, pv); err != nil {
-			apiError(ctx, http.StatusInternalServerError, err)
+			if errors.Is(err, util.ErrPermissionDenied) {
+				apiError(ctx, http.StatusForbidden, err)
+			} else {
+				apiError(ctx, http.StatusInternalServerError, err)
+			}
 			return
 		}
 	}
@@ -307,7 +388,15 @@ func DeletePackage(ctx *context.Context) {
 func ListPackageTags(ctx *context.Context) {
 	packageName := packageNameFromParams(ctx)
 
+	if err := helper.ResolveVirtualOwner(ctx, packages_model.TypeNpm, packageName); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
 	pvs, err := packages_model.GetVersionsByPackageName(ctx, ctx.Package.Owner.ID, packages_model.TypeNpm, packageName)
+	if err == nil {
+		pvs, err = packages_service.FilterVersionsByRepositoryScope(ctx, pvs)
+	}
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -349,6 +438,15 @@ func AddPackageTag(ctx *context.Context) {
 		return
 	}
 
+	if err := packages_service.CheckRepositoryScopeByPackageID(ctx, pv.PackageID, perm.AccessModeWrite); err != nil {
+		if errors.Is(err, util.ErrPermissionDenied) {
+			apiError(ctx, http.StatusForbidden, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
 	if err := setPackageTag(ctx, ctx.Params("tag"), pv, false); err != nil {
 		if err == errInvalidTagName {
 			apiError(ctx, http.StatusBadRequest, err)
@@ -370,6 +468,15 @@ func DeletePackageTag(ctx *context.Context) {
 	}
 
 	if len(pvs) != 0 {
+		if err := packages_service.CheckRepositoryScopeByPackageID(ctx, pvs[0].PackageID, perm.AccessModeWrite); err != nil {
+			if errors.Is(err, util.ErrPermissionDenied) {
+				apiError(ctx, http.StatusForbidden, err)
+				return
+			}
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
 		if err := setPackageTag(ctx, ctx.Params("tag"), pvs[0], true); err != nil {
 			if err == errInvalidTagName {
 				apiError(ctx, http.StatusBadRequest, err)
@@ -429,7 +536,7 @@ func setPackageTag(ctx std_ctx.Context, tag string, pv *packages_model.PackageVe
 }
 
 func PackageSearch(ctx *context.Context) {
-	pvs, total, err := packages_model.SearchLatestVersions(ctx, &packages_model.PackageSearchOptions{
+	opts := &packages_model.PackageSearchOptions{
 		OwnerID:    ctx.Package.Owner.ID,
 		Type:       packages_model.TypeNpm,
 		IsInternal: optional.Some(false),
@@ -441,7 +548,13 @@ func PackageSearch(ctx *context.Context) {
 			ctx.FormInt("from"),
 			ctx.FormInt("size"),
 		),
-	})
+	}
+	if err := packages_service.LimitSearchByRepositoryScope(ctx, opts); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	pvs, total, err := packages_model.SearchLatestVersions(ctx, opts)
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
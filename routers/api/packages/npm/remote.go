@@ -0,0 +1,200 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package npm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/modules/json"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	npm_module "code.gitea.io/gitea/modules/packages/npm"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/services/context"
+	packages_service "code.gitea.io/gitea/services/packages"
+	remote_service "code.gitea.io/gitea/services/packages/remote"
+)
+
+// serveRemotePackageMetadata serves the package metadata of the upstream registry.
+// The tarball urls are rewritten to point to this registry which fetches and caches the tarballs on demand.
+func serveRemotePackageMetadata(ctx *context.Context, client *remote_service.Client, packageName string) error {
+	resp, err := client.Get(ctx, url.PathEscape(packageName), http.Header{
+		"Accept": []string{"application/vnd.npm.install-v1+json; q=1.0, application/json; q=0.8"},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Decode into a generic structure to keep all fields the npm client relies on (os, cpu, engines, ...)
+	var metadata map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return err
+	}
+
+	registryURL := setting.AppURL + "api/packages/" + ctx.ContextUser.Name + "/npm"
+
+	versions, _ := metadata["versions"].(map[string]any)
+	for v, version := range versions {
+		version, ok := version.(map[string]any)
+		if !ok {
+			continue
+		}
+		dist, ok := version["dist"].(map[string]any)
+		if !ok {
+			continue
+		}
+		tarball, _ := dist["tarball"].(string)
+		if tarball == "" {
+			continue
+		}
+		dist["tarball"] = fmt.Sprintf("%s/%s/-/%s/%s", registryURL, url.QueryEscape(packageName), url.PathEscape(v), url.PathEscape(strings.ToLower(path.Base(tarball))))
+	}
+
+	ctx.JSON(http.StatusOK, metadata)
+	return nil
+}
+
+// fetchRemotePackageVersion fetches the package version from the upstream registry and stores it
+func fetchRemotePackageVersion(ctx *context.Context, packageName, packageVersion string) error {
+	client, err := remote_service.GetClientForPackage(ctx, ctx.Package.Owner.ID, packages_model.TypeNpm, packageName)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Get(ctx, url.PathEscape(packageName), http.Header{
+		"Accept": []string{"application/json"},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var metadata npm_module.PackageMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return err
+	}
+
+	meta, ok := metadata.Versions[packageVersion]
+	if !ok || meta.Name != packageName {
+		return remote_service.ErrUpstreamNotExist
+	}
+
+	if meta.Dist.Integrity == "" && meta.Dist.Shasum != "" {
+		// Old packages only provide a sha1 checksum
+		hash, err := hex.DecodeString(meta.Dist.Shasum)
+		if err != nil {
+			return npm_module.ErrInvalidIntegrity
+		}
+		meta.Dist.Integrity = "sha1-" + base64.StdEncoding.EncodeToString(hash)
+	}
+
+	tarballURL := meta.Dist.Tarball
+	if tarballURL == "" {
+		return remote_service.ErrUpstreamNotExist
+	}
+
+	tarball, err := client.GetURL(ctx, tarballURL, nil)
+	if err != nil {
+		return err
+	}
+	defer tarball.Body.Close()
+
+	// don't read more than the package type size limit into memory
+	var r io.Reader = tarball.Body
+	if setting.Packages.LimitSizeNpm > -1 {
+		r = &io.LimitedReader{R: tarball.Body, N: setting.Packages.LimitSizeNpm + 1}
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if setting.Packages.LimitSizeNpm > -1 && int64(len(data)) > setting.Packages.LimitSizeNpm {
+		return packages_service.ErrQuotaTypeSize
+	}
+
+	npmPackage, err := npm_module.ParsePackageVersion(meta, data)
+	if err != nil {
+		return err
+	}
+
+	buf, err := packages_module.CreateHashedBufferFromReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer buf.Close()
+
+	pv, _, err := packages_service.CreatePackageAndAddFile(
+		ctx,
+		&packages_service.PackageCreationInfo{
+			PackageInfo: packages_service.PackageInfo{
+				Owner:       ctx.Package.Owner,
+				PackageType: packages_model.TypeNpm,
+				Name:        npmPackage.Name,
+				Version:     npmPackage.Version,
+			},
+			SemverCompatible:  true,
+			Creator:           ctx.Package.Owner,
+			Metadata:          npmPackage.Metadata,
+			VersionProperties: remote_service.VersionProperties(tarballURL),
+		},
+		&packages_service.PackageFileCreationInfo{
+			PackageFileInfo: packages_service.PackageFileInfo{
+				Filename: npmPackage.Filename,
+			},
+			Creator: ctx.Package.Owner,
+			Data:    buf,
+			IsLead:  true,
+		},
+	)
+	if err != nil {
+		if err == packages_model.ErrDuplicatePackageVersion {
+			// fetched by a concurrent request
+			return nil
+		}
+		return err
+	}
+
+	for tag, version := range metadata.DistTags {
+		if version != pv.Version {
+			continue
+		}
+		if err := setPackageTag(ctx, tag, pv, false); err != nil && err != errInvalidTagName {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isNotExistError returns true if the content exists neither locally nor in the upstream registry
+func isNotExistError(err error) bool {
+	return err == packages_model.ErrPackageNotExist ||
+		err == packages_model.ErrPackageFileNotExist ||
+		errors.Is(err, packages_model.ErrPackageRemoteNotExist) ||
+		errors.Is(err, remote_service.ErrUpstreamNotExist)
+}
+
+// versionFromFilename extracts the version from a tarball name like "name-1.0.0.tgz"
+func versionFromFilename(packageName, filename string) string {
+	name := packageName
+	if _, after, ok := strings.Cut(packageName, "/"); ok {
+		name = after
+	}
+
+	version, ok := strings.CutPrefix(strings.ToLower(filename), strings.ToLower(name)+"-")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSuffix(version, ".tgz")
+}
@@ -0,0 +1,135 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package pypi
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	pypi_module "code.gitea.io/gitea/modules/packages/pypi"
+	"code.gitea.io/gitea/services/context"
+	packages_service "code.gitea.io/gitea/services/packages"
+	remote_service "code.gitea.io/gitea/services/packages/remote"
+)
+
+// remoteFile is a file of the upstream package index
+type remoteFile struct {
+	*pypi_module.SimpleFile
+	Version string
+}
+
+// getRemoteFiles lists the files of the package in the upstream package index
+func getRemoteFiles(ctx *context.Context, client *remote_service.Client, packageName string) ([]*remoteFile, error) {
+	resp, err := client.Get(ctx, url.PathEscape(strings.ToLower(packageName))+"/", http.Header{
+		"Accept": []string{pypi_module.ContentTypeSimpleJSON + ", text/html;q=0.1"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	files, err := pypi_module.ParseSimpleIndex(resp.Body, resp.Header.Get("Content-Type"), resp.Request.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*remoteFile, 0, len(files))
+	for _, f := range files {
+		version := pypi_module.VersionFromFilename(packageName, f.Filename)
+		if !isValidNameAndVersion(packageName, version) {
+			continue
+		}
+		result = append(result, &remoteFile{
+			SimpleFile: f,
+			Version:    version,
+		})
+	}
+	return result, nil
+}
+
+// fetchRemotePackageFile fetches the package file from the upstream package index and stores it
+func fetchRemotePackageFile(ctx *context.Context, packageName, packageVersion, filename string) error {
+	client, err := remote_service.GetClientForPackage(ctx, ctx.Package.Owner.ID, packages_model.TypePyPI, packageName)
+	if err != nil {
+		return err
+	}
+
+	files, err := getRemoteFiles(ctx, client, packageName)
+	if err != nil {
+		return err
+	}
+
+	var file *remoteFile
+	for _, f := range files {
+		if strings.EqualFold(f.Filename, filename) && f.Version == packageVersion {
+			file = f
+			break
+		}
+	}
+	if file == nil {
+		return remote_service.ErrUpstreamNotExist
+	}
+
+	resp, err := client.GetURL(ctx, file.URL, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	buf, err := packages_module.CreateHashedBufferFromReader(resp.Body)
+	if err != nil {
+		return err
+	}
+	defer buf.Close()
+
+	if file.SHA256 != "" {
+		_, _, hashSHA256, _ := buf.Sums()
+		if !strings.EqualFold(file.SHA256, hex.EncodeToString(hashSHA256)) {
+			return errors.New("hash mismatch of upstream package file")
+		}
+	}
+
+	_, _, err = packages_service.CreatePackageOrAddFileToExisting(
+		ctx,
+		&packages_service.PackageCreationInfo{
+			PackageInfo: packages_service.PackageInfo{
+				Owner:       ctx.Package.Owner,
+				PackageType: packages_model.TypePyPI,
+				Name:        packageName,
+				Version:     packageVersion,
+			},
+			SemverCompatible: false,
+			Creator:          ctx.Package.Owner,
+			Metadata: &pypi_module.Metadata{
+				RequiresPython: file.RequiresPython,
+			},
+			VersionProperties: remote_service.VersionProperties(file.URL),
+		},
+		&packages_service.PackageFileCreationInfo{
+			PackageFileInfo: packages_service.PackageFileInfo{
+				Filename: file.Filename,
+			},
+			Creator: ctx.Package.Owner,
+			Data:    buf,
+			IsLead:  true,
+		},
+	)
+	if err != nil && err != packages_model.ErrDuplicatePackageFile {
+		return err
+	}
+	return nil
+}
+
+// isNotExistError returns true if the content exists neither locally nor in the upstream package index
+func isNotExistError(err error) bool {
+	return err == packages_model.ErrPackageNotExist ||
+		err == packages_model.ErrPackageFileNotExist ||
+		errors.Is(err, packages_model.ErrPackageRemoteNotExist) ||
+		errors.Is(err, remote_service.ErrUpstreamNotExist)
+}
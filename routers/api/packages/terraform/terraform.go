@@ -0,0 +1,595 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package terraform
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/modules/json"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	terraform_module "code.gitea.io/gitea/modules/packages/terraform"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/routers/api/packages/helper"
+	"code.gitea.io/gitea/services/context"
+	packages_service "code.gitea.io/gitea/services/packages"
+	terraform_service "code.gitea.io/gitea/services/packages/terraform"
+)
+
+func apiError(ctx *context.Context, status int, obj any) {
+	helper.LogAndProcessError(ctx, status, obj, func(message string) {
+		ctx.JSON(status, struct {
+			Errors []string `json:"errors"`
+		}{
+			Errors: []string{
+				message,
+			},
+		})
+	})
+}
+
+// ServiceDiscovery tells Terraform and OpenTofu where the module and provider registries are located
+// https://developer.hashicorp.com/terraform/internals/remote-service-discovery
+func ServiceDiscovery(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(resp).Encode(map[string]string{
+		"modules.v1":   setting.AppSubURL + "/api/packages/-/terraform/modules/v1/",
+		"providers.v1": setting.AppSubURL + "/api/packages/-/terraform/providers/v1/",
+	})
+}
+
+func baseURL(ctx *context.Context) string {
+	return fmt.Sprintf("%sapi/packages/%s/terraform", setting.AppURL, url.PathEscape(ctx.Package.Owner.Name))
+}
+
+func moduleParams(ctx *context.Context) (string, string, bool) {
+	name := ctx.Params("name")
+	system := ctx.Params("system")
+	return name, system, terraform_module.IsValidName(name) && terraform_module.IsValidName(system)
+}
+
+type moduleVersion struct {
+	Version string `json:"version"`
+}
+
+type moduleVersions struct {
+	Modules []struct {
+		Versions []*moduleVersion `json:"versions"`
+	} `json:"modules"`
+}
+
+// EnumerateModuleVersions lists all versions of a module
+// https://developer.hashicorp.com/terraform/internals/module-registry-protocol#list-available-versions-for-a-specific-module
+func EnumerateModuleVersions(ctx *context.Context) {
+	name, system, ok := moduleParams(ctx)
+	if !ok {
+		apiError(ctx, http.StatusNotFound, terraform_module.ErrInvalidName)
+		return
+	}
+
+	pvs, err := packages_model.GetVersionsByPackageName(ctx, ctx.Package.Owner.ID, packages_model.TypeTerraform, terraform_module.ModulePackageName(name, system))
+	if err == nil {
+		pvs, err = packages_service.FilterVersionsByRepositoryScope(ctx, pvs)
+	}
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if len(pvs) == 0 {
+		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageNotExist)
+		return
+	}
+
+	versions := make([]*moduleVersion, 0, len(pvs))
+	for _, pv := range pvs {
+		versions = append(versions, &moduleVersion{Version: pv.Version})
+	}
+
+	resp := &moduleVersions{}
+	resp.Modules = append(resp.Modules, struct {
+		Versions []*moduleVersion `json:"versions"`
+	}{Versions: versions})
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// DownloadModule points the client to the archive of the module version
+// https://developer.hashicorp.com/terraform/internals/module-registry-protocol#download-source-code-for-a-specific-module-version
+func DownloadModule(ctx *context.Context) {
+	name, system, ok := moduleParams(ctx)
+	if !ok {
+		apiError(ctx, http.StatusNotFound, terraform_module.ErrInvalidName)
+		return
+	}
+	moduleVersion := ctx.Params("version")
+
+	_, err := packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypeTerraform, terraform_module.ModulePackageName(name, system), moduleVersion)
+	if err != nil {
+		if err == packages_model.ErrPackageNotExist {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Resp.Header().Set("X-Terraform-Get", fmt.Sprintf(
+		"%s/modules/%s/%s/%s/%s",
+		baseURL(ctx),
+		url.PathEscape(name),
+		url.PathEscape(system),
+		url.PathEscape(moduleVersion),
+		url.PathEscape(terraform_module.ModuleFilename(name, system, moduleVersion)),
+	))
+	ctx.Status(http.StatusNoContent)
+}
+
+// DownloadModuleFile serves the archive of the module version
+func DownloadModuleFile(ctx *context.Context) {
+	name, system, ok := moduleParams(ctx)
+	if !ok {
+		apiError(ctx, http.StatusNotFound, terraform_module.ErrInvalidName)
+		return
+	}
+
+	s, u, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(
+		ctx,
+		&packages_service.PackageInfo{
+			Owner:       ctx.Package.Owner,
+			PackageType: packages_model.TypeTerraform,
+			Name:        terraform_module.ModulePackageName(name, system),
+			Version:     ctx.Params("version"),
+		},
+		&packages_service.PackageFileInfo{
+			Filename: ctx.Params("filename"),
+		},
+	)
+	if err != nil {
+		if err == packages_model.ErrPackageNotExist || err == packages_model.ErrPackageFileNotExist {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	helper.ServePackageFile(ctx, s, u, pf)
+}
+
+// UploadModule creates a new module version from a gzipped tar archive
+func UploadModule(ctx *context.Context) {
+	name, system, ok := moduleParams(ctx)
+	if !ok {
+		apiError(ctx, http.StatusBadRequest, terraform_module.ErrInvalidName)
+		return
+	}
+	moduleVersion := ctx.Params("version")
+	if !terraform_module.IsValidVersion(moduleVersion) {
+		apiError(ctx, http.StatusBadRequest, terraform_module.ErrInvalidVersion)
+		return
+	}
+
+	upload, needsClose, err := ctx.UploadStream()
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if needsClose {
+		defer upload.Close()
+	}
+
+	buf, err := packages_module.CreateHashedBufferFromReader(upload)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	defer buf.Close()
+
+	metadata, err := terraform_module.ParseModule(buf)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			apiError(ctx, http.StatusBadRequest, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if _, err := buf.Seek(0, io.SeekStart); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	_, _, err = packages_service.CreatePackageAndAddFile(
+		ctx,
+		&packages_service.PackageCreationInfo{
+			PackageInfo: packages_service.PackageInfo{
+				Owner:       ctx.Package.Owner,
+				PackageType: packages_model.TypeTerraform,
+				Name:        terraform_module.ModulePackageName(name, system),
+				Version:     moduleVersion,
+			},
+			SemverCompatible: true,
+			Creator:          ctx.Doer,
+			Metadata:         metadata,
+		},
+		&packages_service.PackageFileCreationInfo{
+			PackageFileInfo: packages_service.PackageFileInfo{
+				Filename: terraform_module.ModuleFilename(name, system, moduleVersion),
+			},
+			Creator: ctx.Doer,
+			Data:    buf,
+			IsLead:  true,
+		},
+	)
+	if err != nil {
+		switch err {
+		case packages_model.ErrDuplicatePackageVersion:
+			apiError(ctx, http.StatusConflict, err)
+		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize, packages_service.ErrPackageAccessDenied:
+			apiError(ctx, http.StatusForbidden, err)
+		default:
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusCreated)
+}
+
+type providerVersion struct {
+	Version   string                       `json:"version"`
+	Protocols []string                     `json:"protocols"`
+	Platforms []*terraform_module.Platform `json:"platforms"`
+}
+
+func protocolsOf(pd *packages_model.PackageDescriptor) []string {
+	if m, ok := pd.Metadata.(*terraform_module.Metadata); ok && len(m.Protocols) > 0 {
+		return m.Protocols
+	}
+	return []string{terraform_module.DefaultProtocol}
+}
+
+func getProviderDescriptors(ctx *context.Context) ([]*packages_model.PackageDescriptor, error) {
+	providerType := ctx.Params("type")
+	if !terraform_module.IsValidProviderType(providerType) {
+		return nil, packages_model.ErrPackageNotExist
+	}
+
+	pvs, err := packages_model.GetVersionsByPackageName(ctx, ctx.Package.Owner.ID, packages_model.TypeTerraform, providerType)
+	if err == nil {
+		pvs, err = packages_service.FilterVersionsByRepositoryScope(ctx, pvs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(pvs) == 0 {
+		return nil, packages_model.ErrPackageNotExist
+	}
+
+	return packages_model.GetPackageDescriptors(ctx, pvs)
+}
+
+func getProviderDescriptor(ctx *context.Context) (*packages_model.PackageDescriptor, error) {
+	providerType := ctx.Params("type")
+	if !terraform_module.IsValidProviderType(providerType) {
+		return nil, packages_model.ErrPackageNotExist
+	}
+
+	pv, err := packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypeTerraform, providerType, ctx.Params("version"))
+	if err != nil {
+		return nil, err
+	}
+
+	return packages_model.GetPackageDescriptor(ctx, pv)
+}
+
+// EnumerateProviderVersions lists all versions of a provider and the platforms they are available for
+// https://developer.hashicorp.com/terraform/internals/provider-registry-protocol#list-available-versions
+func EnumerateProviderVersions(ctx *context.Context) {
+	pds, err := getProviderDescriptors(ctx)
+	if err != nil {
+		if err == packages_model.ErrPackageNotExist {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	sort.Slice(pds, func(i, j int) bool {
+		return pds[i].SemVer.LessThan(pds[j].SemVer)
+	})
+
+	versions := make([]*providerVersion, 0, len(pds))
+	for _, pd := range pds {
+		platforms := make([]*terraform_module.Platform, 0, len(pd.Files))
+		for _, pfd := range pd.Files {
+			if os := pfd.Properties.GetByName(terraform_module.PropertyOS); os != "" {
+				platforms = append(platforms, &terraform_module.Platform{
+					OS:   os,
+					Arch: pfd.Properties.GetByName(terraform_module.PropertyArch),
+				})
+			}
+		}
+		if len(platforms) == 0 {
+			continue
+		}
+
+		versions = append(versions, &providerVersion{
+			Version:   pd.Version.Version,
+			Protocols: protocolsOf(pd),
+			Platforms: platforms,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, map[string]any{
+		"versions": versions,
+	})
+}
+
+type gpgPublicKey struct {
+	KeyID      string `json:"key_id"`
+	ASCIIArmor string `json:"ascii_armor"`
+}
+
+type providerPackage struct {
+	Protocols           []string `json:"protocols"`
+	OS                  string   `json:"os"`
+	Arch                string   `json:"arch"`
+	Filename            string   `json:"filename"`
+	DownloadURL         string   `json:"download_url"`
+	SHASumsURL          string   `json:"shasums_url"`
+	SHASumsSignatureURL string   `json:"shasums_signature_url"`
+	SHASum              string   `json:"shasum"`
+	SigningKeys         struct {
+		GPGPublicKeys []*gpgPublicKey `json:"gpg_public_keys"`
+	} `json:"signing_keys"`
+}
+
+// DownloadProvider describes the provider archive for the platform
+// https://developer.hashicorp.com/terraform/internals/provider-registry-protocol#find-a-provider-package
+func DownloadProvider(ctx *context.Context) {
+	pd, err := getProviderDescriptor(ctx)
+	if err != nil {
+		if err == packages_model.ErrPackageNotExist {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	os := ctx.Params("os")
+	arch := ctx.Params("arch")
+
+	var pfd *packages_model.PackageFileDescriptor
+	for _, f := range pd.Files {
+		if f.Properties.GetByName(terraform_module.PropertyOS) == os && f.Properties.GetByName(terraform_module.PropertyArch) == arch {
+			pfd = f
+			break
+		}
+	}
+	if pfd == nil {
+		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageFileNotExist)
+		return
+	}
+
+	_, pub, err := terraform_service.GetOrCreateKeyPair(ctx, ctx.Package.Owner.ID)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	keyID, err := terraform_service.GetPublicKeyID(pub)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	versionURL := fmt.Sprintf("%s/providers/%s/%s", baseURL(ctx), url.PathEscape(pd.Package.Name), url.PathEscape(pd.Version.Version))
+
+	resp := &providerPackage{
+		Protocols:           protocolsOf(pd),
+		OS:                  os,
+		Arch:                arch,
+		Filename:            pfd.File.Name,
+		DownloadURL:         versionURL + "/" + url.PathEscape(pfd.File.Name),
+		SHASumsURL:          versionURL + "/SHA256SUMS",
+		SHASumsSignatureURL: versionURL + "/SHA256SUMS.sig",
+		SHASum:              pfd.Blob.HashSHA256,
+	}
+	resp.SigningKeys.GPGPublicKeys = []*gpgPublicKey{
+		{
+			KeyID:      keyID,
+			ASCIIArmor: pub,
+		},
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// DownloadProviderFile serves a provider archive or manifest
+func DownloadProviderFile(ctx *context.Context) {
+	s, u, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(
+		ctx,
+		&packages_service.PackageInfo{
+			Owner:       ctx.Package.Owner,
+			PackageType: packages_model.TypeTerraform,
+			Name:        ctx.Params("type"),
+			Version:     ctx.Params("version"),
+		},
+		&packages_service.PackageFileInfo{
+			Filename: ctx.Params("filename"),
+		},
+	)
+	if err != nil {
+		if err == packages_model.ErrPackageNotExist || err == packages_model.ErrPackageFileNotExist {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	helper.ServePackageFile(ctx, s, u, pf)
+}
+
+// DownloadProviderSHA256Sums serves the checksums of all provider archives of the version
+func DownloadProviderSHA256Sums(ctx *context.Context) {
+	pd, err := getProviderDescriptor(ctx)
+	if err != nil {
+		if err == packages_model.ErrPackageNotExist {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.ServeContent(bytes.NewReader(terraform_service.BuildSHA256Sums(pd)), &context.ServeHeaderOptions{
+		Filename:     fmt.Sprintf("terraform-provider-%s_%s_SHA256SUMS", pd.Package.Name, pd.Version.Version),
+		ContentType:  "text/plain; charset=utf-8",
+		LastModified: time.Unix(int64(pd.Version.CreatedUnix), 0),
+	})
+}
+
+// DownloadProviderSHA256SumsSignature serves the detached signature of the checksums file
+func DownloadProviderSHA256SumsSignature(ctx *context.Context) {
+	pd, err := getProviderDescriptor(ctx)
+	if err != nil {
+		if err == packages_model.ErrPackageNotExist {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	sig, err := terraform_service.SignSHA256Sums(ctx, ctx.Package.Owner.ID, terraform_service.BuildSHA256Sums(pd))
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.ServeContent(bytes.NewReader(sig), &context.ServeHeaderOptions{
+		Filename:    fmt.Sprintf("terraform-provider-%s_%s_SHA256SUMS.sig", pd.Package.Name, pd.Version.Version),
+		ContentType: "application/octet-stream",
+	})
+}
+
+// UploadProviderFile adds a provider archive or the provider manifest to the provider version
+func UploadProviderFile(ctx *context.Context) {
+	providerType := ctx.Params("type")
+	version := ctx.Params("version")
+	filename := ctx.Params("filename")
+
+	pf, err := terraform_module.ParseProviderFilename(filename)
+	if err != nil {
+		apiError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if pf.Type != providerType || pf.Version != version {
+		apiError(ctx, http.StatusBadRequest, terraform_module.ErrInvalidFilename)
+		return
+	}
+
+	upload, needsClose, err := ctx.UploadStream()
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if needsClose {
+		defer upload.Close()
+	}
+
+	buf, err := packages_module.CreateHashedBufferFromReader(upload)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	defer buf.Close()
+
+	metadata := &terraform_module.Metadata{
+		Kind: terraform_module.KindProvider,
+	}
+	var properties map[string]string
+
+	if pf.IsManifest {
+		metadata.Protocols, err = terraform_module.ParseProviderManifest(buf)
+	} else {
+		err = terraform_module.ValidateProviderArchive(buf, buf.Size(), providerType)
+		properties = map[string]string{
+			terraform_module.PropertyOS:   pf.OS,
+			terraform_module.PropertyArch: pf.Arch,
+		}
+	}
+	if err != nil {
+		apiError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, err := buf.Seek(0, io.SeekStart); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	pv, _, err := packages_service.CreatePackageOrAddFileToExisting(
+		ctx,
+		&packages_service.PackageCreationInfo{
+			PackageInfo: packages_service.PackageInfo{
+				Owner:       ctx.Package.Owner,
+				PackageType: packages_model.TypeTerraform,
+				Name:        providerType,
+				Version:     version,
+			},
+			SemverCompatible: true,
+			Creator:          ctx.Doer,
+			Metadata:         metadata,
+		},
+		&packages_service.PackageFileCreationInfo{
+			PackageFileInfo: packages_service.PackageFileInfo{
+				Filename: filename,
+			},
+			Creator:    ctx.Doer,
+			Data:       buf,
+			IsLead:     !pf.IsManifest,
+			Properties: properties,
+		},
+	)
+	if err != nil {
+		switch err {
+		case packages_model.ErrDuplicatePackageFile:
+			apiError(ctx, http.StatusConflict, err)
+		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize, packages_service.ErrPackageAccessDenied:
+			apiError(ctx, http.StatusForbidden, err)
+		default:
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if pf.IsManifest {
+		// The version may have been created by the upload of an archive
+		metadataJSON, err := json.Marshal(metadata)
+		if err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		pv.MetadataJSON = string(metadataJSON)
+		if err := packages_model.UpdateVersion(ctx, pv); err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	ctx.Status(http.StatusCreated)
+}
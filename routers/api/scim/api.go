@@ -0,0 +1,180 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package scim implements the SCIM 2.0 provisioning endpoint of an authentication source.
+// The identity provider authenticates with the bearer token generated for the source and
+// manages the users of the source and their memberships in the teams linked to SCIM groups.
+package scim
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/scim"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/web"
+	web_types "code.gitea.io/gitea/modules/web/types"
+	"code.gitea.io/gitea/services/context"
+)
+
+type scimContextKeyType struct{}
+
+var scimContextKey = scimContextKeyType{}
+
+// Context is the context of a SCIM request authenticated for an authentication source
+type Context struct {
+	*context.Base
+
+	Source *auth_model.Source
+}
+
+func init() {
+	web.RegisterResponseStatusProvider[*Context](func(req *http.Request) web_types.ResponseStatusProvider {
+		return req.Context().Value(scimContextKey).(*Context)
+	})
+}
+
+// BaseURL returns the URL of the SCIM endpoint
+func BaseURL() string {
+	return setting.AppURL + "api/scim/v2"
+}
+
+// Contexter authenticates the identity provider by the SCIM token of the authentication source
+func Contexter() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			base, baseCleanUp := context.NewBaseContext(resp, req)
+			defer baseCleanUp()
+
+			ctx := &Context{Base: base}
+			ctx.AppendContextValue(scimContextKey, ctx)
+
+			authHeader := req.Header.Get("Authorization")
+			if len(authHeader) <= 7 || !strings.EqualFold(authHeader[:7], "Bearer ") {
+				ctx.error(http.StatusUnauthorized, "", "missing bearer token")
+				return
+			}
+
+			token, err := auth_model.GetSCIMTokenByToken(ctx, strings.TrimSpace(authHeader[7:]))
+			if err != nil {
+				if !auth_model.IsErrSCIMTokenNotExist(err) {
+					log.Error("GetSCIMTokenByToken: %v", err)
+				}
+				ctx.error(http.StatusUnauthorized, "", "invalid bearer token")
+				return
+			}
+
+			source, err := auth_model.GetSourceByID(ctx, token.SourceID)
+			if err != nil {
+				ctx.serverError("GetSourceByID", err)
+				return
+			}
+			if !source.IsActive || !source.IsSAML() {
+				ctx.error(http.StatusForbidden, "", "the authentication source is not active")
+				return
+			}
+			ctx.Source = source
+
+			next.ServeHTTP(ctx.Resp, ctx.Req)
+		})
+	}
+}
+
+// Routes provides the SCIM 2.0 endpoint mounted on `/api/scim/v2`
+func Routes() *web.Route {
+	m := web.NewRoute()
+	m.Use(Contexter())
+
+	m.Get("/ServiceProviderConfig", ServiceProviderConfig)
+	m.Get("/ResourceTypes", ResourceTypes)
+	m.Get("/Schemas", Schemas)
+
+	m.Group("/Users", func() {
+		m.Combo("").Get(ListUsers).Post(CreateUser)
+		m.Combo("/{id}").Get(GetUser).Put(ReplaceUser).Patch(PatchUser).Delete(DeleteUser)
+	})
+	m.Group("/Groups", func() {
+		m.Combo("").Get(ListGroups).Post(CreateGroup)
+		m.Combo("/{id}").Get(GetGroup).Put(ReplaceGroup).Patch(PatchGroup).Delete(DeleteGroup)
+	})
+
+	m.NotFound(func(w http.ResponseWriter, req *http.Request) {
+		respond(w, http.StatusNotFound, scim.NewError(http.StatusNotFound, "", "resource type not found"))
+	})
+
+	return m
+}
+
+func respond(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error("Failed to encode SCIM response: %v", err)
+	}
+}
+
+// respond writes a resource or a list response
+func (ctx *Context) respond(status int, v any) {
+	respond(ctx.Resp, status, v)
+}
+
+// error writes an error response
+func (ctx *Context) error(status int, scimType, detail string) {
+	ctx.respond(status, scim.NewError(status, scimType, detail))
+}
+
+// serverError logs the error and writes an internal server error response
+func (ctx *Context) serverError(title string, err error) {
+	log.ErrorWithSkip(1, "%s: %v", title, err)
+	ctx.error(http.StatusInternalServerError, "", title)
+}
+
+// badRequest writes the response of an invalid request, or an internal server error response for other errors
+func (ctx *Context) badRequest(title string, err error) {
+	var badRequestError *scim.BadRequestError
+	if errors.As(err, &badRequestError) {
+		ctx.respond(http.StatusBadRequest, badRequestError.Response())
+		return
+	}
+	ctx.serverError(title, err)
+}
+
+// decode reads the JSON body of the request
+func (ctx *Context) decode(v any) bool {
+	if err := json.NewDecoder(ctx.Req.Body).Decode(v); err != nil {
+		ctx.error(http.StatusBadRequest, scim.ErrorTypeInvalidSyntax, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// pagination returns the 1-based start index and the page size of a list request
+func (ctx *Context) pagination() (int, int) {
+	startIndex := ctx.FormInt("startIndex")
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	count := setting.API.DefaultPagingNum
+	if ctx.FormString("count") != "" {
+		count = max(ctx.FormInt("count"), 0)
+	}
+	return startIndex, min(count, setting.API.MaxResponseItems)
+}
+
+// filter returns the filter of a list request
+func (ctx *Context) filter() (*scim.Filter, bool) {
+	s := ctx.FormString("filter")
+	if s == "" {
+		return nil, true
+	}
+	filter, err := scim.ParseFilter(s)
+	if err != nil {
+		ctx.badRequest("ParseFilter", err)
+		return nil, false
+	}
+	return filter, true
+}
@@ -0,0 +1,161 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package scim
+
+import (
+	"net/http"
+
+	"code.gitea.io/gitea/modules/scim"
+	"code.gitea.io/gitea/modules/setting"
+)
+
+type supported struct {
+	Supported bool `json:"supported"`
+}
+
+type authenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+type resourceType struct {
+	Schemas  []string   `json:"schemas"`
+	ID       string     `json:"id"`
+	Name     string     `json:"name"`
+	Endpoint string     `json:"endpoint"`
+	Schema   string     `json:"schema"`
+	Meta     *scim.Meta `json:"meta"`
+}
+
+type schemaAttribute struct {
+	Name          string            `json:"name"`
+	Type          string            `json:"type"`
+	MultiValued   bool              `json:"multiValued"`
+	Required      bool              `json:"required"`
+	CaseExact     bool              `json:"caseExact"`
+	Mutability    string            `json:"mutability"`
+	Returned      string            `json:"returned"`
+	Uniqueness    string            `json:"uniqueness"`
+	SubAttributes []schemaAttribute `json:"subAttributes,omitempty"`
+}
+
+type schema struct {
+	Schemas     []string          `json:"schemas"`
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Attributes  []schemaAttribute `json:"attributes"`
+	Meta        *scim.Meta        `json:"meta"`
+}
+
+func attribute(name, typ, mutability string, subAttributes ...schemaAttribute) schemaAttribute {
+	return schemaAttribute{
+		Name:          name,
+		Type:          typ,
+		Mutability:    mutability,
+		Returned:      "default",
+		Uniqueness:    "none",
+		SubAttributes: subAttributes,
+	}
+}
+
+// unique marks an attribute as required and unique
+func unique(a schemaAttribute) schemaAttribute {
+	a.Required = true
+	a.Uniqueness = "server"
+	return a
+}
+
+func multiValued(a schemaAttribute) schemaAttribute {
+	a.MultiValued = true
+	return a
+}
+
+// ServiceProviderConfig describes the supported features of the endpoint
+func ServiceProviderConfig(ctx *Context) {
+	ctx.respond(http.StatusOK, map[string]any{
+		"schemas":        []string{scim.SchemaServiceProviderConfig},
+		"patch":          supported{true},
+		"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]any{"supported": true, "maxResults": setting.API.MaxResponseItems},
+		"changePassword": supported{false},
+		"sort":           supported{false},
+		"etag":           supported{false},
+		"authenticationSchemes": []authenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "Bearer token",
+			Description: "The SCIM token generated for the authentication source",
+			Primary:     true,
+		}},
+		"meta": &scim.Meta{ResourceType: "ServiceProviderConfig", Location: BaseURL() + "/ServiceProviderConfig"},
+	})
+}
+
+func resourceTypes() []any {
+	return []any{
+		&resourceType{
+			Schemas:  []string{scim.SchemaResourceType},
+			ID:       "User",
+			Name:     "User",
+			Endpoint: "/Users",
+			Schema:   scim.SchemaUser,
+			Meta:     &scim.Meta{ResourceType: "ResourceType", Location: BaseURL() + "/ResourceTypes/User"},
+		},
+		&resourceType{
+			Schemas:  []string{scim.SchemaResourceType},
+			ID:       "Group",
+			Name:     "Group",
+			Endpoint: "/Groups",
+			Schema:   scim.SchemaGroup,
+			Meta:     &scim.Meta{ResourceType: "ResourceType", Location: BaseURL() + "/ResourceTypes/Group"},
+		},
+	}
+}
+
+// ResourceTypes lists the resource types of the endpoint
+func ResourceTypes(ctx *Context) {
+	resources := resourceTypes()
+	ctx.respond(http.StatusOK, scim.NewListResponse(int64(len(resources)), 1, resources))
+}
+
+// Schemas lists the attributes of the resource types which are supported by the endpoint
+func Schemas(ctx *Context) {
+	resources := []any{
+		&schema{
+			Schemas:     []string{scim.SchemaSchema},
+			ID:          scim.SchemaUser,
+			Name:        "User",
+			Description: "User account of the authentication source",
+			Attributes: []schemaAttribute{
+				unique(attribute("userName", "string", "readWrite")),
+				attribute("name", "complex", "readWrite",
+					attribute("formatted", "string", "readWrite"),
+				),
+				attribute("displayName", "string", "readWrite"),
+				multiValued(attribute("emails", "complex", "readWrite",
+					attribute("value", "string", "readWrite"),
+					attribute("primary", "boolean", "readWrite"),
+				)),
+				attribute("active", "boolean", "readWrite"),
+			},
+			Meta: &scim.Meta{ResourceType: "Schema", Location: BaseURL() + "/Schemas/" + scim.SchemaUser},
+		},
+		&schema{
+			Schemas:     []string{scim.SchemaSchema},
+			ID:          scim.SchemaGroup,
+			Name:        "Group",
+			Description: "Team of an organization, named organization/team",
+			Attributes: []schemaAttribute{
+				unique(attribute("displayName", "string", "immutable")),
+				multiValued(attribute("members", "complex", "readWrite",
+					attribute("value", "string", "immutable"),
+				)),
+			},
+			Meta: &scim.Meta{ResourceType: "Schema", Location: BaseURL() + "/Schemas/" + scim.SchemaGroup},
+		},
+	}
+	ctx.respond(http.StatusOK, scim.NewListResponse(int64(len(resources)), 1, resources))
+}
@@ -0,0 +1,501 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"code.gitea.io/gitea/models"
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/organization"
+	"code.gitea.io/gitea/models/perm"
+	"code.gitea.io/gitea/models/unit"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/container"
+	"code.gitea.io/gitea/modules/optional"
+	"code.gitea.io/gitea/modules/scim"
+	"code.gitea.io/gitea/modules/util"
+	org_service "code.gitea.io/gitea/services/org"
+)
+
+// group is a team linked to a SCIM group of the authentication source
+type group struct {
+	*auth_model.SCIMGroup
+
+	Org  *organization.Organization
+	Team *organization.Team
+}
+
+// DisplayName returns the name of the group in the form organization/team
+func (g *group) DisplayName() string {
+	return g.Org.Name + "/" + g.Team.Name
+}
+
+// loadGroup loads the team and the organization of a SCIM group
+func loadGroup(ctx context.Context, g *auth_model.SCIMGroup) (*group, error) {
+	team, err := organization.GetTeamByID(ctx, g.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	org, err := organization.GetOrgByID(ctx, team.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	return &group{SCIMGroup: g, Org: org, Team: team}, nil
+}
+
+// sourceMembers returns the members of the team which belong to the authentication source
+func sourceMembers(ctx context.Context, source *auth_model.Source, teamID int64) ([]*user_model.User, error) {
+	users := make([]*user_model.User, 0, 10)
+	return users, db.GetEngine(ctx).
+		Join("INNER", "team_user", "team_user.uid = `user`.id").
+		Where(sourceUsersCond(source)).
+		And("team_user.team_id = ?", teamID).
+		OrderBy("`user`.id").
+		Find(&users)
+}
+
+func toGroup(ctx *Context, g *group, withMembers bool) (*scim.Group, error) {
+	id := strconv.FormatInt(g.TeamID, 10)
+	result := &scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ID:          id,
+		ExternalID:  g.ExternalID,
+		DisplayName: g.DisplayName(),
+		Meta: &scim.Meta{
+			ResourceType: "Group",
+			Location:     BaseURL() + "/Groups/" + id,
+		},
+	}
+	if !withMembers {
+		return result, nil
+	}
+
+	members, err := sourceMembers(ctx, ctx.Source, g.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	result.Members = make([]scim.Reference, 0, len(members))
+	for _, u := range members {
+		result.Members = append(result.Members, scim.Reference{
+			Value:   strconv.FormatInt(u.ID, 10),
+			Display: u.LoginName,
+			Ref:     BaseURL() + "/Users/" + strconv.FormatInt(u.ID, 10),
+		})
+	}
+	return result, nil
+}
+
+// withMembers checks if the members of groups are not excluded from the response
+func (ctx *Context) withMembers() bool {
+	for _, attribute := range strings.Split(ctx.FormString("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(attribute), "members") {
+			return false
+		}
+	}
+	return true
+}
+
+// getGroup returns the group of the id parameter, or writes a not found response
+func getGroup(ctx *Context) *group {
+	teamID, _ := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	g, err := auth_model.GetSCIMGroup(ctx, ctx.Source.ID, teamID)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.error(http.StatusNotFound, "", fmt.Sprintf("group %q not found", ctx.Params("id")))
+		} else {
+			ctx.serverError("GetSCIMGroup", err)
+		}
+		return nil
+	}
+	loaded, err := loadGroup(ctx, g)
+	if err != nil {
+		ctx.serverError("loadGroup", err)
+		return nil
+	}
+	return loaded
+}
+
+// resolveMembers returns the ids of the member references, which must be users of the authentication source
+func resolveMembers(ctx *Context, refs []scim.Reference) (container.Set[int64], error) {
+	ids := make(container.Set[int64], len(refs))
+	for _, ref := range refs {
+		u, err := getSourceUser(ctx, ref.Value)
+		if err != nil {
+			return nil, err
+		}
+		if u == nil {
+			return nil, invalidValue("member %q is not a user of the authentication source", ref.Value)
+		}
+		ids.Add(u.ID)
+	}
+	return ids, nil
+}
+
+func addMembers(ctx context.Context, team *organization.Team, ids container.Set[int64]) error {
+	for _, id := range ids.Values() {
+		if err := org_service.AddTeamMember(ctx, team, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeMembers(ctx context.Context, team *organization.Team, ids container.Set[int64]) error {
+	for _, id := range ids.Values() {
+		if err := org_service.RemoveTeamMember(ctx, team, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setMembers makes the users the only members of the team which belong to the authentication source
+func setMembers(ctx context.Context, source *auth_model.Source, team *organization.Team, ids container.Set[int64]) error {
+	members, err := sourceMembers(ctx, source, team.ID)
+	if err != nil {
+		return err
+	}
+	removed := make(container.Set[int64])
+	for _, u := range members {
+		if !ids.Contains(u.ID) {
+			removed.Add(u.ID)
+		}
+	}
+	if err := removeMembers(ctx, team, removed); err != nil {
+		return err
+	}
+	return addMembers(ctx, team, ids)
+}
+
+// ListGroups lists the teams linked to SCIM groups of the authentication source
+func ListGroups(ctx *Context) {
+	filter, ok := ctx.filter()
+	if !ok {
+		return
+	}
+	if filter != nil && filter.Attribute != "id" && filter.Attribute != "displayname" && filter.Attribute != "externalid" {
+		ctx.error(http.StatusBadRequest, scim.ErrorTypeInvalidFilter, fmt.Sprintf("filtering by %q is not supported", filter.Attribute))
+		return
+	}
+	startIndex, count := ctx.pagination()
+
+	scimGroups, err := auth_model.FindSCIMGroups(ctx, ctx.Source.ID)
+	if err != nil {
+		ctx.serverError("FindSCIMGroups", err)
+		return
+	}
+
+	groups := make([]*group, 0, len(scimGroups))
+	for _, g := range scimGroups {
+		loaded, err := loadGroup(ctx, g)
+		if err != nil {
+			ctx.serverError("loadGroup", err)
+			return
+		}
+		if filter != nil {
+			switch filter.Attribute {
+			case "id":
+				if strconv.FormatInt(g.TeamID, 10) != filter.Value {
+					continue
+				}
+			case "displayname":
+				if !strings.EqualFold(loaded.DisplayName(), filter.Value) {
+					continue
+				}
+			case "externalid":
+				if g.ExternalID != filter.Value {
+					continue
+				}
+			}
+		}
+		groups = append(groups, loaded)
+	}
+
+	resources := make([]any, 0, count)
+	for i := startIndex - 1; i < len(groups) && len(resources) < count; i++ {
+		result, err := toGroup(ctx, groups[i], ctx.withMembers())
+		if err != nil {
+			ctx.serverError("toGroup", err)
+			return
+		}
+		resources = append(resources, result)
+	}
+	ctx.respond(http.StatusOK, scim.NewListResponse(int64(len(groups)), startIndex, resources))
+}
+
+// GetGroup returns a team linked to a SCIM group of the authentication source
+func GetGroup(ctx *Context) {
+	g := getGroup(ctx)
+	if g == nil {
+		return
+	}
+	result, err := toGroup(ctx, g, ctx.withMembers())
+	if err != nil {
+		ctx.serverError("toGroup", err)
+		return
+	}
+	ctx.respond(http.StatusOK, result)
+}
+
+// CreateGroup links a team to a SCIM group of the authentication source.
+// The displayName of the group is the name of the team in the form organization/team,
+// the team is created with read access if it doesn't exist.
+func CreateGroup(ctx *Context) {
+	var form scim.Group
+	if !ctx.decode(&form) {
+		return
+	}
+	orgName, teamName, ok := strings.Cut(strings.TrimSpace(form.DisplayName), "/")
+	if !ok || orgName == "" || teamName == "" {
+		ctx.error(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "displayName must be in the form organization/team")
+		return
+	}
+
+	org, err := organization.GetOrgByName(ctx, orgName)
+	if err != nil {
+		if organization.IsErrOrgNotExist(err) {
+			ctx.error(http.StatusBadRequest, scim.ErrorTypeInvalidValue, fmt.Sprintf("organization %q does not exist", orgName))
+		} else {
+			ctx.serverError("GetOrgByName", err)
+		}
+		return
+	}
+	team, err := organization.GetTeam(ctx, org.ID, teamName)
+	if err != nil && !organization.IsErrTeamNotExist(err) {
+		ctx.serverError("GetTeam", err)
+		return
+	}
+	if team != nil {
+		if team.IsOwnerTeam() {
+			ctx.error(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "the owners team can't be provisioned")
+			return
+		}
+		if _, err := auth_model.GetSCIMGroup(ctx, ctx.Source.ID, team.ID); err == nil {
+			ctx.error(http.StatusConflict, scim.ErrorTypeUniqueness, fmt.Sprintf("group %q already exists", form.DisplayName))
+			return
+		} else if !errors.Is(err, util.ErrNotExist) {
+			ctx.serverError("GetSCIMGroup", err)
+			return
+		}
+	}
+
+	members, err := resolveMembers(ctx, form.Members)
+	if err != nil {
+		ctx.badRequest("resolveMembers", err)
+		return
+	}
+
+	g := &group{
+		SCIMGroup: &auth_model.SCIMGroup{SourceID: ctx.Source.ID, ExternalID: form.ExternalID},
+		Org:       org,
+		Team:      team,
+	}
+	err = db.WithTx(ctx, func(ctx context.Context) error {
+		if g.Team == nil {
+			g.Team = &organization.Team{
+				OrgID:      org.ID,
+				Name:       teamName,
+				LowerName:  strings.ToLower(teamName),
+				AccessMode: perm.AccessModeRead,
+			}
+			for _, tp := range unit.DefaultRepoUnits {
+				g.Team.Units = append(g.Team.Units, &organization.TeamUnit{
+					OrgID:      org.ID,
+					Type:       tp,
+					AccessMode: perm.AccessModeRead,
+				})
+			}
+			if err := models.NewTeam(ctx, g.Team); err != nil {
+				return err
+			}
+		}
+		g.TeamID = g.Team.ID
+		if err := auth_model.CreateSCIMGroup(ctx, g.SCIMGroup); err != nil {
+			return err
+		}
+		return addMembers(ctx, g.Team, members)
+	})
+	if err != nil {
+		if organization.IsErrTeamAlreadyExist(err) || db.IsErrNameReserved(err) || db.IsErrNamePatternNotAllowed(err) {
+			ctx.error(http.StatusBadRequest, scim.ErrorTypeInvalidValue, err.Error())
+		} else {
+			ctx.serverError("CreateGroup", err)
+		}
+		return
+	}
+
+	result, err := toGroup(ctx, g, true)
+	if err != nil {
+		ctx.serverError("toGroup", err)
+		return
+	}
+	ctx.Resp.Header().Set("Location", result.Meta.Location)
+	ctx.respond(http.StatusCreated, result)
+}
+
+// checkDisplayName rejects renaming a group, as the name refers to the organization and the team
+func checkDisplayName(g *group, displayName string) error {
+	if !strings.EqualFold(strings.TrimSpace(displayName), g.DisplayName()) {
+		return &scim.BadRequestError{ScimType: scim.ErrorTypeMutability, Detail: "the displayName of a group can't be changed"}
+	}
+	return nil
+}
+
+// ReplaceGroup replaces the members of the team which belong to the authentication source
+func ReplaceGroup(ctx *Context) {
+	g := getGroup(ctx)
+	if g == nil {
+		return
+	}
+	var form scim.Group
+	if !ctx.decode(&form) {
+		return
+	}
+	if err := checkDisplayName(g, form.DisplayName); err != nil {
+		ctx.badRequest("checkDisplayName", err)
+		return
+	}
+	members, err := resolveMembers(ctx, form.Members)
+	if err != nil {
+		ctx.badRequest("resolveMembers", err)
+		return
+	}
+
+	source := ctx.Source
+	err = db.WithTx(ctx, func(ctx context.Context) error {
+		g.ExternalID = form.ExternalID
+		if err := auth_model.UpdateSCIMGroupExternalID(ctx, g.SCIMGroup); err != nil {
+			return err
+		}
+		return setMembers(ctx, source, g.Team, members)
+	})
+	if err != nil {
+		ctx.serverError("ReplaceGroup", err)
+		return
+	}
+
+	result, err := toGroup(ctx, g, true)
+	if err != nil {
+		ctx.serverError("toGroup", err)
+		return
+	}
+	ctx.respond(http.StatusOK, result)
+}
+
+// PatchGroup modifies the members of the team which belong to the authentication source
+func PatchGroup(ctx *Context) {
+	g := getGroup(ctx)
+	if g == nil {
+		return
+	}
+	var form scim.PatchRequest
+	if !ctx.decode(&form) {
+		return
+	}
+
+	// the operations are validated before they are applied in a single transaction
+	var steps []func(ctx context.Context) error
+	err := forEachPatchValue(form.Operations, func(op string, path *scim.Path, value any) error {
+		switch path.Attribute {
+		case "displayname":
+			if op == "remove" {
+				return checkDisplayName(g, "")
+			}
+			displayName, _ := value.(string)
+			return checkDisplayName(g, displayName)
+		case "externalid":
+			var externalID optional.Option[string]
+			if err := patchString(&externalID, op, value); err != nil {
+				return err
+			}
+			steps = append(steps, func(ctx context.Context) error {
+				g.ExternalID = externalID.Value()
+				return auth_model.UpdateSCIMGroupExternalID(ctx, g.SCIMGroup)
+			})
+		case "members":
+			var refs []scim.Reference
+			if path.Filter != nil {
+				if path.Filter.Attribute != "value" || op != "remove" {
+					return &scim.BadRequestError{ScimType: scim.ErrorTypeInvalidPath, Detail: "members can only be filtered by value to remove them"}
+				}
+				refs = []scim.Reference{{Value: path.Filter.Value}}
+			} else if value != nil {
+				if err := convert(value, &refs); err != nil {
+					return err
+				}
+			}
+			ids, err := resolveMembers(ctx, refs)
+			if err != nil {
+				return err
+			}
+			source := ctx.Source
+			steps = append(steps, func(ctx context.Context) error {
+				switch {
+				case op == "add":
+					return addMembers(ctx, g.Team, ids)
+				case op == "replace":
+					return setMembers(ctx, source, g.Team, ids)
+				case path.Filter == nil && value == nil:
+					// removing the attribute removes all members
+					return setMembers(ctx, source, g.Team, nil)
+				default:
+					return removeMembers(ctx, g.Team, ids)
+				}
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.badRequest("patch", err)
+		return
+	}
+
+	err = db.WithTx(ctx, func(ctx context.Context) error {
+		for _, step := range steps {
+			if err := step(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.serverError("PatchGroup", err)
+		return
+	}
+
+	result, err := toGroup(ctx, g, true)
+	if err != nil {
+		ctx.serverError("toGroup", err)
+		return
+	}
+	ctx.respond(http.StatusOK, result)
+}
+
+// DeleteGroup unlinks the team from the SCIM group and removes the members which belong to the authentication source.
+// The team itself is kept.
+func DeleteGroup(ctx *Context) {
+	g := getGroup(ctx)
+	if g == nil {
+		return
+	}
+	source := ctx.Source
+	err := db.WithTx(ctx, func(ctx context.Context) error {
+		if err := setMembers(ctx, source, g.Team, nil); err != nil {
+			return err
+		}
+		return auth_model.DeleteSCIMGroup(ctx, g.SCIMGroup)
+	})
+	if err != nil {
+		ctx.serverError("DeleteGroup", err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
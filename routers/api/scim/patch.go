@@ -0,0 +1,99 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package scim
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/optional"
+	"code.gitea.io/gitea/modules/scim"
+)
+
+func invalidValue(format string, args ...any) error {
+	return &scim.BadRequestError{ScimType: scim.ErrorTypeInvalidValue, Detail: fmt.Sprintf(format, args...)}
+}
+
+// forEachPatchValue calls fn for every attribute modified by the operations.
+// Operations without a path modify the attributes of their value object.
+func forEachPatchValue(ops []scim.PatchOperation, fn func(op string, path *scim.Path, value any) error) error {
+	for _, operation := range ops {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			return &scim.BadRequestError{ScimType: scim.ErrorTypeInvalidSyntax, Detail: fmt.Sprintf("unsupported operation %q", operation.Op)}
+		}
+
+		if operation.Path != "" {
+			path, err := scim.ParsePath(operation.Path)
+			if err != nil {
+				return err
+			}
+			if err := fn(op, path, operation.Value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if op == "remove" {
+			return &scim.BadRequestError{ScimType: scim.ErrorTypeNoTarget, Detail: "remove operations require a path"}
+		}
+		values, ok := operation.Value.(map[string]any)
+		if !ok {
+			return invalidValue("operations without a path require an object value")
+		}
+		for attribute, value := range values {
+			path, err := scim.ParsePath(attribute)
+			if err != nil {
+				return err
+			}
+			if err := fn(op, path, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseBool returns the boolean value, some identity providers send booleans as strings like "False"
+func parseBool(value any) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, invalidValue("invalid boolean %q", v)
+		}
+		return b, nil
+	}
+	return false, invalidValue("invalid boolean %v", value)
+}
+
+// patchString sets the string value of the operation, or clears it for remove operations
+func patchString(o *optional.Option[string], op string, value any) error {
+	if op == "remove" {
+		*o = optional.Some("")
+		return nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return invalidValue("invalid string %v", value)
+	}
+	*o = optional.Some(s)
+	return nil
+}
+
+// convert decodes a complex value into v
+func convert(value, v any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return invalidValue("invalid value %s", data)
+	}
+	return nil
+}
@@ -0,0 +1,407 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.gitea.io/gitea/models"
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/optional"
+	"code.gitea.io/gitea/modules/scim"
+	"code.gitea.io/gitea/modules/validation"
+	user_service "code.gitea.io/gitea/services/user"
+
+	"xorm.io/builder"
+)
+
+var errLoginNameUsed = errors.New("userName is already used")
+
+func toUser(u *user_model.User) *scim.User {
+	id := strconv.FormatInt(u.ID, 10)
+	active := u.IsActive
+	user := &scim.User{
+		Schemas:     []string{scim.SchemaUser},
+		ID:          id,
+		UserName:    u.LoginName,
+		DisplayName: u.FullName,
+		Emails:      []scim.Email{{Value: u.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta: &scim.Meta{
+			ResourceType: "User",
+			Created:      u.CreatedUnix.AsTime().Format(time.RFC3339),
+			LastModified: u.UpdatedUnix.AsTime().Format(time.RFC3339),
+			Location:     BaseURL() + "/Users/" + id,
+		},
+	}
+	if u.FullName != "" {
+		user.Name = &scim.Name{Formatted: u.FullName}
+	}
+	return user
+}
+
+// sourceUsersCond returns the condition of the users of the authentication source
+func sourceUsersCond(source *auth_model.Source) builder.Cond {
+	return builder.Eq{
+		"login_type":   source.Type,
+		"login_source": source.ID,
+		"type":         user_model.UserTypeIndividual,
+	}
+}
+
+// getSourceUser returns the user of the authentication source with the id, or nil if the user doesn't exist
+func getSourceUser(ctx *Context, id string) (*user_model.User, error) {
+	userID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+	u := &user_model.User{}
+	has, err := db.GetEngine(ctx).Where(sourceUsersCond(ctx.Source)).And("id = ?", userID).Get(u)
+	if err != nil || !has {
+		return nil, err
+	}
+	return u, nil
+}
+
+// getUser returns the user of the id parameter, or writes a not found response
+func getUser(ctx *Context) *user_model.User {
+	u, err := getSourceUser(ctx, ctx.Params("id"))
+	if err != nil {
+		ctx.serverError("getSourceUser", err)
+		return nil
+	}
+	if u == nil {
+		ctx.error(http.StatusNotFound, "", fmt.Sprintf("user %q not found", ctx.Params("id")))
+		return nil
+	}
+	return u
+}
+
+// isLoginNameUsed checks if another user of the authentication source has the case-insensitive userName
+func isLoginNameUsed(ctx *Context, userID int64, loginName string) (bool, error) {
+	return db.GetEngine(ctx).
+		Where(sourceUsersCond(ctx.Source)).
+		And("id <> ?", userID).
+		And("LOWER(login_name) = ?", strings.ToLower(loginName)).
+		Exist(new(user_model.User))
+}
+
+// userError writes the response of an error of a user modification
+func (ctx *Context) userError(title string, err error) {
+	switch {
+	case errors.Is(err, errLoginNameUsed),
+		user_model.IsErrUserAlreadyExist(err),
+		user_model.IsErrEmailAlreadyUsed(err):
+		ctx.error(http.StatusConflict, scim.ErrorTypeUniqueness, err.Error())
+	case db.IsErrNameReserved(err),
+		db.IsErrNamePatternNotAllowed(err),
+		db.IsErrNameCharsNotAllowed(err),
+		validation.IsErrEmailInvalid(err),
+		validation.IsErrEmailCharIsNotSupported(err):
+		ctx.error(http.StatusBadRequest, scim.ErrorTypeInvalidValue, err.Error())
+	default:
+		ctx.badRequest(title, err)
+	}
+}
+
+// ListUsers lists the users of the authentication source
+func ListUsers(ctx *Context) {
+	filter, ok := ctx.filter()
+	if !ok {
+		return
+	}
+	startIndex, count := ctx.pagination()
+
+	cond := sourceUsersCond(ctx.Source)
+	if filter != nil {
+		switch filter.Attribute {
+		case "id":
+			id, _ := strconv.ParseInt(filter.Value, 10, 64)
+			cond = cond.And(builder.Eq{"id": id})
+		case "username":
+			cond = cond.And(builder.Expr("LOWER(login_name) = ?", strings.ToLower(filter.Value)))
+		case "emails", "emails.value":
+			cond = cond.And(builder.Expr("LOWER(email) = ?", strings.ToLower(filter.Value)))
+		case "displayname":
+			cond = cond.And(builder.Eq{"full_name": filter.Value})
+		case "active":
+			cond = cond.And(builder.Eq{"is_active": filter.Value == "true"})
+		default:
+			ctx.error(http.StatusBadRequest, scim.ErrorTypeInvalidFilter, fmt.Sprintf("filtering by %q is not supported", filter.Attribute))
+			return
+		}
+	}
+
+	users := make([]*user_model.User, 0, count)
+	var total int64
+	var err error
+	if count > 0 {
+		total, err = db.GetEngine(ctx).Where(cond).OrderBy("id").Limit(count, startIndex-1).FindAndCount(&users)
+	} else {
+		total, err = db.GetEngine(ctx).Where(cond).Count(new(user_model.User))
+	}
+	if err != nil {
+		ctx.serverError("FindUsers", err)
+		return
+	}
+
+	resources := make([]any, 0, len(users))
+	for _, u := range users {
+		resources = append(resources, toUser(u))
+	}
+	ctx.respond(http.StatusOK, scim.NewListResponse(total, startIndex, resources))
+}
+
+// GetUser returns a user of the authentication source
+func GetUser(ctx *Context) {
+	u := getUser(ctx)
+	if u == nil {
+		return
+	}
+	ctx.respond(http.StatusOK, toUser(u))
+}
+
+// CreateUser creates a user of the authentication source.
+// The username is derived from the local part of the userName.
+func CreateUser(ctx *Context) {
+	var form scim.User
+	if !ctx.decode(&form) {
+		return
+	}
+	loginName := strings.TrimSpace(form.UserName)
+	if loginName == "" {
+		ctx.error(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "userName is required")
+		return
+	}
+
+	used, err := isLoginNameUsed(ctx, 0, loginName)
+	if err != nil {
+		ctx.serverError("isLoginNameUsed", err)
+		return
+	} else if used {
+		ctx.userError("isLoginNameUsed", errLoginNameUsed)
+		return
+	}
+
+	localPart, _, _ := strings.Cut(loginName, "@")
+	name, err := user_model.NormalizeUserName(localPart)
+	if err != nil {
+		ctx.error(http.StatusBadRequest, scim.ErrorTypeInvalidValue, err.Error())
+		return
+	}
+	email := form.PrimaryEmail()
+	if email == "" {
+		email = fmt.Sprintf("%s@localhost.local", name)
+	}
+	fullName := form.DisplayName
+	if fullName == "" {
+		fullName = form.Name.String()
+	}
+
+	u := &user_model.User{
+		LowerName:   strings.ToLower(name),
+		Name:        name,
+		FullName:    fullName,
+		Email:       email,
+		LoginType:   ctx.Source.Type,
+		LoginSource: ctx.Source.ID,
+		LoginName:   loginName,
+	}
+	overwriteDefault := &user_model.CreateUserOverwriteOptions{
+		IsActive: optional.Some(form.Active == nil || *form.Active),
+	}
+	if err := user_model.CreateUser(ctx, u, overwriteDefault); err != nil {
+		ctx.userError("CreateUser", err)
+		return
+	}
+
+	user := toUser(u)
+	ctx.Resp.Header().Set("Location", user.Meta.Location)
+	ctx.respond(http.StatusCreated, user)
+}
+
+// userChanges contains the attributes of a user to modify
+type userChanges struct {
+	LoginName optional.Option[string]
+	FullName  optional.Option[string]
+	Email     optional.Option[string]
+	IsActive  optional.Option[bool]
+}
+
+func (c *userChanges) apply(ctx *Context, u *user_model.User) error {
+	if c.LoginName.Has() && c.LoginName.Value() != u.LoginName {
+		used, err := isLoginNameUsed(ctx, u.ID, c.LoginName.Value())
+		if err != nil {
+			return err
+		} else if used {
+			return errLoginNameUsed
+		}
+	}
+
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if c.LoginName.Has() && c.LoginName.Value() != u.LoginName {
+			if err := user_service.UpdateAuth(ctx, u, &user_service.UpdateAuthOptions{LoginName: c.LoginName}); err != nil {
+				return err
+			}
+		}
+		if c.Email.Has() && c.Email.Value() != "" {
+			if err := user_service.ReplacePrimaryEmailAddress(ctx, u, c.Email.Value()); err != nil {
+				return err
+			}
+		}
+		return user_service.UpdateUser(ctx, u, &user_service.UpdateOptions{
+			FullName: c.FullName,
+			IsActive: c.IsActive,
+		})
+	})
+}
+
+// ReplaceUser replaces the attributes of a user of the authentication source
+func ReplaceUser(ctx *Context) {
+	u := getUser(ctx)
+	if u == nil {
+		return
+	}
+	var form scim.User
+	if !ctx.decode(&form) {
+		return
+	}
+	if strings.TrimSpace(form.UserName) == "" {
+		ctx.error(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "userName is required")
+		return
+	}
+
+	fullName := form.DisplayName
+	if fullName == "" {
+		fullName = form.Name.String()
+	}
+	changes := &userChanges{
+		LoginName: optional.Some(strings.TrimSpace(form.UserName)),
+		FullName:  optional.Some(fullName),
+		Email:     optional.Some(form.PrimaryEmail()),
+	}
+	if form.Active != nil {
+		changes.IsActive = optional.Some(*form.Active)
+	}
+	if err := changes.apply(ctx, u); err != nil {
+		ctx.userError("apply", err)
+		return
+	}
+	ctx.respond(http.StatusOK, toUser(u))
+}
+
+// PatchUser modifies attributes of a user of the authentication source.
+// Attributes which are not stored, like the parts of the name, are ignored.
+func PatchUser(ctx *Context) {
+	u := getUser(ctx)
+	if u == nil {
+		return
+	}
+	var form scim.PatchRequest
+	if !ctx.decode(&form) {
+		return
+	}
+
+	changes := &userChanges{}
+	err := forEachPatchValue(form.Operations, func(op string, path *scim.Path, value any) error {
+		return changes.patch(op, path, value)
+	})
+	if err != nil {
+		ctx.badRequest("patch", err)
+		return
+	}
+	if err := changes.apply(ctx, u); err != nil {
+		ctx.userError("apply", err)
+		return
+	}
+	ctx.respond(http.StatusOK, toUser(u))
+}
+
+// patch records the modification of an attribute by a patch operation
+func (c *userChanges) patch(op string, path *scim.Path, value any) error {
+	switch path.Attribute {
+	case "active":
+		if op == "remove" {
+			return nil
+		}
+		active, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		c.IsActive = optional.Some(active)
+	case "username":
+		var loginName optional.Option[string]
+		if err := patchString(&loginName, op, value); err != nil {
+			return err
+		}
+		if strings.TrimSpace(loginName.Value()) == "" {
+			return &scim.BadRequestError{ScimType: scim.ErrorTypeInvalidValue, Detail: "userName is required"}
+		}
+		c.LoginName = optional.Some(strings.TrimSpace(loginName.Value()))
+	case "displayname":
+		return patchString(&c.FullName, op, value)
+	case "name":
+		if path.SubAttribute == "formatted" {
+			return patchString(&c.FullName, op, value)
+		}
+		if path.SubAttribute == "" {
+			if op == "remove" {
+				c.FullName = optional.Some("")
+				return nil
+			}
+			var name scim.Name
+			if err := convert(value, &name); err != nil {
+				return err
+			}
+			if !c.FullName.Has() {
+				c.FullName = optional.Some(name.String())
+			}
+		}
+	case "emails":
+		if op == "remove" {
+			// the user must always have an email address
+			return nil
+		}
+		if path.SubAttribute == "value" {
+			return patchString(&c.Email, op, value)
+		}
+		if path.SubAttribute == "" {
+			var emails []scim.Email
+			if err := convert(value, &emails); err != nil {
+				return err
+			}
+			c.Email = optional.Some((&scim.User{Emails: emails}).PrimaryEmail())
+		}
+	}
+	return nil
+}
+
+// DeleteUser deletes a user of the authentication source.
+// Users who still own repositories, organizations or packages can only be deactivated.
+func DeleteUser(ctx *Context) {
+	u := getUser(ctx)
+	if u == nil {
+		return
+	}
+	if err := user_service.DeleteUser(ctx, u, false); err != nil {
+		if models.IsErrUserOwnRepos(err) ||
+			models.IsErrUserHasOrgs(err) ||
+			models.IsErrUserOwnPackages(err) ||
+			models.IsErrDeleteLastAdminUser(err) {
+			ctx.error(http.StatusConflict, scim.ErrorTypeMutability, err.Error())
+		} else {
+			ctx.serverError("DeleteUser", err)
+		}
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
@@ -5,13 +5,18 @@ package activitypub
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/forgefed"
 	"code.gitea.io/gitea/modules/activitypub"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/routers/api/v1/utils"
 	"code.gitea.io/gitea/services/context"
+	"code.gitea.io/gitea/services/federation"
 
 	ap "github.com/go-ap/activitypub"
 	"github.com/go-ap/jsonld"
@@ -34,45 +39,12 @@ func Person(ctx *context.APIContext) {
 	//   "200":
 	//     "$ref": "#/responses/ActivityPub"
 
-	// TODO: the setting.AppURL during the test doesn't follow the definition: "It always has a '/' suffix"
-	link := fmt.Sprintf("%s/api/v1/activitypub/user-id/%d", strings.TrimSuffix(setting.AppURL, "/"), ctx.ContextUser.ID)
-	person := ap.PersonNew(ap.IRI(link))
-
-	person.Name = ap.NaturalLanguageValuesNew()
-	err := person.Name.Set("en", ap.Content(ctx.ContextUser.FullName))
-	if err != nil {
-		ctx.ServerError("Set Name", err)
-		return
-	}
-
-	person.PreferredUsername = ap.NaturalLanguageValuesNew()
-	err = person.PreferredUsername.Set("en", ap.Content(ctx.ContextUser.Name))
+	person, err := federation.Person(ctx, ctx.ContextUser)
 	if err != nil {
-		ctx.ServerError("Set PreferredUsername", err)
+		ctx.ServerError("Person", err)
 		return
 	}
 
-	person.URL = ap.IRI(ctx.ContextUser.HTMLURL())
-
-	person.Icon = ap.Image{
-		Type:      ap.ImageType,
-		MediaType: "image/png",
-		URL:       ap.IRI(ctx.ContextUser.AvatarLink(ctx)),
-	}
-
-	person.Inbox = ap.IRI(link + "/inbox")
-	person.Outbox = ap.IRI(link + "/outbox")
-
-	person.PublicKey.ID = ap.IRI(link + "#main-key")
-	person.PublicKey.Owner = ap.IRI(link)
-
-	publicKeyPem, err := activitypub.GetPublicKey(ctx, ctx.ContextUser)
-	if err != nil {
-		ctx.ServerError("GetPublicKey", err)
-		return
-	}
-	person.PublicKey.PublicKeyPem = publicKeyPem
-
 	binary, err := jsonld.WithContext(jsonld.IRI(ap.ActivityBaseURI), jsonld.IRI(ap.SecurityContextURI)).Marshal(person)
 	if err != nil {
 		ctx.ServerError("MarshalJSON", err)
@@ -101,6 +73,82 @@ func PersonInbox(ctx *context.APIContext) {
 	// responses:
 	//   "204":
 	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "429":
+	//     "$ref": "#/responses/error"
 
+	body, err := io.ReadAll(io.LimitReader(ctx.Req.Body, setting.Federation.MaxSize))
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "ReadAll", err)
+		return
+	}
+	signer, _ := ctx.Data["ActivityPubSigner"].(string)
+	if httpStatus, title, err := federation.ProcessPersonInbox(ctx, ctx.ContextUser, signer, body); err != nil {
+		ctx.Error(httpStatus, title, err)
+		return
+	}
 	ctx.Status(http.StatusNoContent)
 }
+
+// PersonOutbox function returns the collection of the activities published by a user,
+// it lists the public activities and the ones addressed to the actor signing the request
+func PersonOutbox(ctx *context.APIContext) {
+	// swagger:operation GET /activitypub/user-id/{user-id}/outbox activitypub activitypubPersonOutbox
+	// ---
+	// summary: Returns the outbox of a user
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: user-id
+	//   in: path
+	//   description: user ID of the user
+	//   type: integer
+	//   required: true
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ActivityPub"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	if !ctx.ContextUser.Visibility.IsPublic() {
+		ctx.NotFound()
+		return
+	}
+
+	recipients := []string{ap.PublicNS.String()}
+	if signer, _ := ctx.Data["ActivityPubSigner"].(string); signer != "" {
+		recipients = append(recipients, signer)
+	}
+
+	link := fmt.Sprintf("%s/api/v1/activitypub/user-id/%d/outbox", strings.TrimSuffix(setting.AppURL, "/"), ctx.ContextUser.ID)
+	activities, count, err := db.FindAndCount[forgefed.OutboxActivity](ctx, forgefed.FindOutboxActivitiesOptions{
+		ListOptions: utils.GetListOptions(ctx),
+		UserID:      ctx.ContextUser.ID,
+		Recipients:  recipients,
+	})
+	if err != nil {
+		ctx.ServerError("FindOutboxActivities", err)
+		return
+	}
+
+	outbox := ap.OrderedCollectionNew(ap.IRI(link))
+	outbox.TotalItems = uint(count)
+	for _, a := range activities {
+		item, err := ap.UnmarshalJSON([]byte(a.Content))
+		if err != nil {
+			ctx.ServerError("UnmarshalJSON", err)
+			return
+		}
+		outbox.OrderedItems = append(outbox.OrderedItems, item)
+	}
+	response(ctx, outbox)
+}
@@ -6,8 +6,12 @@ package activitypub
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 
+	issues_model "code.gitea.io/gitea/models/issues"
+	access_model "code.gitea.io/gitea/models/perm/access"
+	"code.gitea.io/gitea/models/unit"
 	"code.gitea.io/gitea/modules/forgefed"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/setting"
@@ -47,7 +51,7 @@ func Repository(ctx *context.APIContext) {
 	response(ctx, repo)
 }
 
-// PersonInbox function handles the incoming data for a repository inbox
+// RepositoryInbox function handles the incoming data for a repository inbox
 func RepositoryInbox(ctx *context.APIContext) {
 	// swagger:operation POST /activitypub/repository-id/{repository-id}/inbox activitypub activitypubRepositoryInbox
 	// ---
@@ -67,14 +71,126 @@ func RepositoryInbox(ctx *context.APIContext) {
 	// responses:
 	//   "204":
 	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "429":
+	//     "$ref": "#/responses/error"
 
 	repository := ctx.Repo.Repository
 	log.Info("RepositoryInbox: repo: %v", repository)
 
 	form := web.GetForm(ctx)
-	httpStatus, title, err := federation.ProcessLikeActivity(ctx, form, repository.ID)
+	activity := form.(*forgefed.ForgeLike)
+	switch activity.Type {
+	case ap.OfferType, ap.CreateType, ap.FollowType, ap.UndoType, forgefed.PushType:
+		// issues, comments, follows and pushes are only accepted from the actor who signed them
+		if !reqHTTPSignature(ctx) {
+			return
+		}
+	default:
+		// likes aren't signed yet, the host of their actor is moderated instead
+		if activity.Actor != nil {
+			if actorIRI, err := url.Parse(activity.Actor.GetID().String()); err == nil && !checkFederationHost(ctx, actorIRI.Hostname()) {
+				return
+			}
+		}
+	}
+	if silenced, _ := ctx.Data["ActivityPubSilenced"].(bool); silenced {
+		log.Info("RepositoryInbox: dropping the %s activity of a silenced host", activity.Type)
+		ctx.Status(http.StatusNoContent)
+		return
+	}
+
+	var httpStatus int
+	var title string
+	var err error
+	signer, _ := ctx.Data["ActivityPubSigner"].(string)
+	switch activity.Type {
+	case ap.OfferType:
+		httpStatus, title, err = federation.ProcessTicketOffer(ctx, repository, signer, &activity.Activity)
+	case ap.CreateType:
+		httpStatus, title, err = federation.ProcessNoteCreation(ctx, repository, signer, &activity.Activity)
+	case ap.FollowType:
+		httpStatus, title, err = federation.ProcessRepositoryFollow(ctx, repository, signer, &activity.Activity)
+	case ap.UndoType:
+		httpStatus, title, err = federation.ProcessRepositoryUndo(ctx, repository, signer, &activity.Activity)
+	case forgefed.PushType:
+		httpStatus, title, err = federation.ProcessPush(ctx, repository, signer, &activity.Activity)
+	default:
+		httpStatus, title, err = federation.ProcessLikeActivity(ctx, form, repository.ID)
+	}
 	if err != nil {
 		ctx.Error(httpStatus, title, err)
+		return
 	}
 	ctx.Status(http.StatusNoContent)
 }
+
+// RepositoryTicket function returns an issue of a repository as a ForgeFed Ticket
+func RepositoryTicket(ctx *context.APIContext) {
+	// swagger:operation GET /activitypub/repository-id/{repository-id}/issues/{index} activitypub activitypubRepositoryTicket
+	// ---
+	// summary: Returns an issue of a repository as a Ticket
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: repository-id
+	//   in: path
+	//   description: repository ID of the repo
+	//   type: integer
+	//   required: true
+	// - name: index
+	//   in: path
+	//   description: index of the issue
+	//   type: integer
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ActivityPub"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	repository := ctx.Repo.Repository
+	perm, err := access_model.GetUserRepoPermission(ctx, repository, nil)
+	if err != nil {
+		ctx.ServerError("GetUserRepoPermission", err)
+		return
+	}
+	if !perm.CanRead(unit.TypeIssues) {
+		ctx.NotFound()
+		return
+	}
+	issue, err := issues_model.GetIssueByIndex(ctx, repository.ID, ctx.ParamsInt64(":index"))
+	if err != nil {
+		if issues_model.IsErrIssueNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.ServerError("GetIssueByIndex", err)
+		}
+		return
+	}
+	if issue.IsPull {
+		ctx.NotFound()
+		return
+	}
+	issue.Repo = repository
+	if err := issue.LoadPoster(ctx); err != nil {
+		ctx.ServerError("LoadPoster", err)
+		return
+	}
+
+	ticket := forgefed.TicketNew(ap.IRI(issue.APObjectID()))
+	if issue.Poster.IsRemote() {
+		ticket.AttributedTo = ap.IRI(issue.Poster.NormalizedFederatedURI)
+	} else {
+		ticket.AttributedTo = ap.IRI(issue.Poster.APActorID())
+	}
+	ticket.Context = ap.IRI(repository.APActorID())
+	ticket.Summary = ap.DefaultNaturalLanguageValue(issue.Title)
+	ticket.Content = ap.DefaultNaturalLanguageValue(issue.Content)
+	ticket.Source = ap.Source{Content: ap.DefaultNaturalLanguageValue(issue.Content), MediaType: "text/markdown"}
+	ticket.URL = ap.IRI(issue.HTMLURL())
+	ticket.Published = issue.CreatedUnix.AsTime()
+	ticket.IsResolved = issue.IsClosed
+	response(ctx, ticket)
+}
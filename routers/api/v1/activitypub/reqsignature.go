@@ -4,96 +4,202 @@
 package activitypub
 
 import (
+	"bytes"
 	"crypto"
-	"crypto/x509"
-	"encoding/pem"
-	"fmt"
+	"errors"
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 
 	"code.gitea.io/gitea/modules/activitypub"
-	"code.gitea.io/gitea/modules/httplib"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/setting"
 	gitea_context "code.gitea.io/gitea/services/context"
+	"code.gitea.io/gitea/services/federation"
 
-	ap "github.com/go-ap/activitypub"
 	"github.com/go-fed/httpsig"
 )
 
-func getPublicKeyFromResponse(b []byte, keyID *url.URL) (p crypto.PublicKey, err error) {
-	person := ap.PersonNew(ap.IRI(keyID.String()))
-	err = person.UnmarshalJSON(b)
-	if err != nil {
-		return nil, fmt.Errorf("ActivityStreams type cannot be converted to one known to have publicKey property: %w", err)
-	}
-	pubKey := person.PublicKey
-	if pubKey.ID.String() != keyID.String() {
-		return nil, fmt.Errorf("cannot find publicKey with id: %s in %s", keyID, string(b))
+// readBody returns the body of the request and leaves it to be read again by the handler
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
 	}
-	pubKeyPem := pubKey.PublicKeyPem
-	block, _ := pem.Decode([]byte(pubKeyPem))
-	if block == nil || block.Type != "PUBLIC KEY" {
-		return nil, fmt.Errorf("could not decode publicKeyPem to PUBLIC KEY pem block type")
+	body, err := io.ReadAll(io.LimitReader(r.Body, setting.Federation.MaxSize))
+	if err != nil {
+		return nil, err
 	}
-	p, err = x509.ParsePKIXPublicKey(block.Bytes)
-	return p, err
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
 }
 
-func fetch(iri *url.URL) (b []byte, err error) {
-	req := httplib.NewRequest(iri.String(), http.MethodGet)
-	req.Header("Accept", activitypub.ActivityStreamsContentType)
-	req.Header("User-Agent", "Gitea/"+setting.AppVer)
-	resp, err := req.Response()
-	if err != nil {
-		return nil, err
+// parseHTTPSignature returns the key ID of the RFC 9421 or draft-cavage signature of the request and a function
+// verifying the signature with a public key, signatures covering neither the date nor the digest of the body
+// or signed at a time too far off the local time are rejected
+func parseHTTPSignature(r *http.Request) (keyID string, verify func(crypto.PublicKey) bool, err error) {
+	skew := setting.Federation.MaxClockSkew
+	hasBody := r.Method != http.MethodGet && r.Method != http.MethodHead
+
+	if r.Header.Get(activitypub.SignatureInputHeader) != "" {
+		s, err := activitypub.ParseMessageSignature(r.Header)
+		if err != nil {
+			return "", nil, err
+		}
+		if err := s.Validate(r.Method, skew); err != nil {
+			return "", nil, err
+		}
+		if s.Covers("date") {
+			if err := activitypub.CheckDate(r.Header, skew); err != nil {
+				return "", nil, err
+			}
+		}
+		return s.KeyID, func(pub crypto.PublicKey) bool {
+			return s.Verify(r, pub) == nil
+		}, nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("url IRI fetch [%s] failed with status (%d): %s", iri, resp.StatusCode, resp.Status)
+	v, err := httpsig.NewVerifier(r)
+	if err != nil {
+		return "", nil, err
+	}
+	headers, err := activitypub.CavageSignedHeaders(r.Header)
+	if err != nil {
+		return "", nil, err
+	}
+	if !slices.Contains(headers, httpsig.RequestTarget) {
+		return "", nil, errors.New("the signature doesn't cover the request target")
+	}
+	if !slices.Contains(headers, "date") {
+		return "", nil, errors.New("the signature doesn't cover the Date header")
 	}
-	b, err = io.ReadAll(io.LimitReader(resp.Body, setting.Federation.MaxSize))
-	return b, err
+	if hasBody && !slices.Contains(headers, "digest") && !slices.Contains(headers, "content-digest") {
+		return "", nil, errors.New("the signature doesn't cover the Digest header")
+	}
+	if err := activitypub.CheckDate(r.Header, skew); err != nil {
+		return "", nil, err
+	}
+	return v.KeyId(), func(pub crypto.PublicKey) bool {
+		for _, algo := range setting.HttpsigAlgs {
+			if v.Verify(pub, algo) == nil {
+				return true
+			}
+		}
+		return false
+	}, nil
 }
 
 func verifyHTTPSignatures(ctx *gitea_context.APIContext) (authenticated bool, err error) {
 	r := ctx.Req
 
 	// 1. Figure out what key we need to verify
-	v, err := httpsig.NewVerifier(r)
+	keyID, verify, err := parseHTTPSignature(r)
 	if err != nil {
 		return false, err
 	}
-	ID := v.KeyId()
-	idIRI, err := url.Parse(ID)
+	idIRI, err := url.Parse(keyID)
 	if err != nil {
 		return false, err
 	}
-	// 2. Fetch the public key of the other actor
-	b, err := fetch(idIRI)
+	// 2. Reject blocked hosts before fetching anything from them, the rate limit is only counted once the
+	// signature is verified because the key ID could be forged
+	silenced, err := federation.CheckInboxHost(ctx, idIRI.Hostname())
 	if err != nil {
 		return false, err
 	}
-	pubKey, err := getPublicKeyFromResponse(b, idIRI)
+	ctx.Data["ActivityPubSilenced"] = silenced
+	// 3. Check that the body is the one which was signed
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		body, err := readBody(r)
+		if err != nil {
+			return false, err
+		}
+		if err := activitypub.CheckDigest(r.Header, body); err != nil {
+			return false, err
+		}
+	}
+	// 4. Get the public key of the other actor, cached keys are fetched again if they don't match the
+	// signature because the actor might have rotated its key
+	pubKey, cached, err := federation.RemotePublicKey(ctx, idIRI, false)
 	if err != nil {
 		return false, err
 	}
-	// 3. Verify the other actor's key
-	algo := httpsig.Algorithm(setting.Federation.Algorithms[0])
-	authenticated = v.Verify(pubKey, algo) == nil
-	return authenticated, err
+	authenticated = verify(pubKey)
+	if !authenticated && cached {
+		if pubKey, _, err = federation.RemotePublicKey(ctx, idIRI, true); err != nil {
+			return false, err
+		}
+		authenticated = verify(pubKey)
+	}
+	if authenticated {
+		// the key ID is the IRI of the actor with the fragment of the key
+		signer := *idIRI
+		signer.Fragment = ""
+		ctx.Data["ActivityPubSigner"] = signer.String()
+		if err := federation.LimitInboxHost(idIRI.Hostname()); err != nil {
+			return false, err
+		}
+	}
+	return authenticated, nil
+}
+
+// reqHTTPSignature verifies the signature of the request and responds with an error if it isn't valid
+func reqHTTPSignature(ctx *gitea_context.APIContext) bool {
+	authenticated, err := verifyHTTPSignatures(ctx)
+	switch {
+	case errors.Is(err, federation.ErrHostBlocked), errors.Is(err, federation.ErrHostRateLimited):
+		rejectFederationHost(ctx, err)
+	case err != nil:
+		log.Warn("verifyHttpSignatures failed: %v", err)
+		ctx.Error(http.StatusBadRequest, "reqSignature", "request signature verification failed")
+	case !authenticated:
+		ctx.Error(http.StatusForbidden, "reqSignature", "request signature verification failed")
+	default:
+		return true
+	}
+	return false
+}
+
+// checkFederationHost responds with an error if the host is blocked or exceeded the inbox rate limit,
+// requests of silenced hosts are marked with ctx.Data["ActivityPubSilenced"]. The host of unsigned requests
+// isn't verified, so their rate limit is counted per host and remote address.
+func checkFederationHost(ctx *gitea_context.APIContext, host string) bool {
+	silenced, err := federation.CheckInboxHost(ctx, host)
+	if err == nil {
+		err = federation.LimitInboxHost(host + " " + ctx.RemoteAddr())
+	}
+	if err != nil {
+		rejectFederationHost(ctx, err)
+		return false
+	}
+	ctx.Data["ActivityPubSilenced"] = silenced
+	return true
+}
+
+func rejectFederationHost(ctx *gitea_context.APIContext, err error) {
+	switch {
+	case errors.Is(err, federation.ErrHostBlocked):
+		ctx.Error(http.StatusForbidden, "checkFederationHost", err)
+	case errors.Is(err, federation.ErrHostRateLimited):
+		ctx.Resp.Header().Set("Retry-After", "60")
+		ctx.Error(http.StatusTooManyRequests, "checkFederationHost", err)
+	default:
+		ctx.Error(http.StatusInternalServerError, "CheckInboxHost", err)
+	}
 }
 
 // ReqHTTPSignature function
 func ReqHTTPSignature() func(ctx *gitea_context.APIContext) {
 	return func(ctx *gitea_context.APIContext) {
-		if authenticated, err := verifyHTTPSignatures(ctx); err != nil {
-			log.Warn("verifyHttpSignatures failed: %v", err)
-			ctx.Error(http.StatusBadRequest, "reqSignature", "request signature verification failed")
-		} else if !authenticated {
-			ctx.Error(http.StatusForbidden, "reqSignature", "request signature verification failed")
+		reqHTTPSignature(ctx)
+	}
+}
+
+// OptHTTPSignature verifies the signature of signed requests, unsigned requests are handled as anonymous ones
+func OptHTTPSignature() func(ctx *gitea_context.APIContext) {
+	return func(ctx *gitea_context.APIContext) {
+		if ctx.Req.Header.Get("Signature") != "" {
+			reqHTTPSignature(ctx)
 		}
 	}
 }
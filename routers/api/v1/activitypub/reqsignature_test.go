@@ -0,0 +1,39 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package activitypub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCavageSignature(t *testing.T) {
+	defer test.MockVariableValue(&setting.Federation.MaxClockSkew, time.Minute)()
+
+	newRequest := func(headers string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "https://forge.example.com/api/v1/activitypub/user-id/1/outbox", nil)
+		r.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+		r.Header.Set("Signature", `keyId="https://remote.example.com/actor#main-key",algorithm="rsa-sha256",headers="`+headers+`",signature="c2ln"`)
+		return r
+	}
+
+	keyID, _, err := parseHTTPSignature(newRequest("(request-target) host date"))
+	require.NoError(t, err)
+	assert.Equal(t, "https://remote.example.com/actor#main-key", keyID)
+
+	// a signature without the request target could be replayed for other endpoints
+	_, _, err = parseHTTPSignature(newRequest("host date"))
+	require.ErrorContains(t, err, "request target")
+
+	_, _, err = parseHTTPSignature(newRequest("(request-target) host"))
+	require.ErrorContains(t, err, "Date header")
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package admin
+
+import (
+	"code.gitea.io/gitea/routers/api/v1/shared"
+	"code.gitea.io/gitea/services/context"
+)
+
+// ListAuditEvents lists the audit events of the instance
+func ListAuditEvents(ctx *context.APIContext) {
+	// swagger:operation GET /admin/audit admin adminListAuditEvents
+	// ---
+	// summary: List the audit events of the instance
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: action
+	//   in: query
+	//   description: only show events of this action, e.g. "user.admin.grant"
+	//   type: string
+	// - name: actor
+	//   in: query
+	//   description: only show events caused by the user with this name
+	//   type: string
+	// - name: target_type
+	//   in: query
+	//   description: only show events on targets of this type, e.g. "repository"
+	//   type: string
+	// - name: since
+	//   in: query
+	//   description: only show events created after the given time (RFC 3339 format)
+	//   type: string
+	//   format: date-time
+	// - name: before
+	//   in: query
+	//   description: only show events created before the given time (RFC 3339 format)
+	//   type: string
+	//   format: date-time
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/AuditEventList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	shared.ListAuditEvents(ctx, 0)
+}
+
+// ExportAuditEvents exports the audit events of the instance as JSON lines
+func ExportAuditEvents(ctx *context.APIContext) {
+	// swagger:operation GET /admin/audit/export admin adminExportAuditEvents
+	// ---
+	// summary: Export the audit events of the instance as JSON lines
+	// produces:
+	// - application/jsonl
+	// parameters:
+	// - name: action
+	//   in: query
+	//   description: only show events of this action, e.g. "user.admin.grant"
+	//   type: string
+	// - name: actor
+	//   in: query
+	//   description: only show events caused by the user with this name
+	//   type: string
+	// - name: target_type
+	//   in: query
+	//   description: only show events on targets of this type, e.g. "repository"
+	//   type: string
+	// - name: since
+	//   in: query
+	//   description: only show events created after the given time (RFC 3339 format)
+	//   type: string
+	//   format: date-time
+	// - name: before
+	//   in: query
+	//   description: only show events created before the given time (RFC 3339 format)
+	//   type: string
+	//   format: date-time
+	// responses:
+	//   "200":
+	//     description: one AuditEvent per line
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	shared.ExportAuditEvents(ctx, 0)
+}
@@ -0,0 +1,170 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	"code.gitea.io/gitea/models/forgefed"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/web"
+	"code.gitea.io/gitea/routers/api/v1/utils"
+	"code.gitea.io/gitea/services/context"
+	"code.gitea.io/gitea/services/convert"
+	"code.gitea.io/gitea/services/federation"
+	user_service "code.gitea.io/gitea/services/user"
+)
+
+// ListFederationHosts lists the hosts which contacted this instance
+func ListFederationHosts(ctx *context.APIContext) {
+	// swagger:operation GET /admin/federation/hosts admin adminListFederationHosts
+	// ---
+	// summary: List the federation hosts which contacted this instance
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/FederationHostList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+
+	hosts, count, err := forgefed.FindFederationHosts(ctx, utils.GetListOptions(ctx))
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "FindFederationHosts", err)
+		return
+	}
+	rules, err := forgefed.FindFederationHostRules(ctx)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "FindFederationHostRules", err)
+		return
+	}
+
+	apiHosts := make([]*api.FederationHost, len(hosts))
+	for i, host := range hosts {
+		apiHosts[i] = convert.ToFederationHost(host, federation.MatchHostAction(rules, host.HostFqdn))
+	}
+	ctx.SetTotalCountHeader(count)
+	ctx.JSON(http.StatusOK, apiHosts)
+}
+
+// ListFederationHostRules lists the federation host rules
+func ListFederationHostRules(ctx *context.APIContext) {
+	// swagger:operation GET /admin/federation/rules admin adminListFederationHostRules
+	// ---
+	// summary: List the rules blocking, silencing or allowing federation hosts
+	// produces:
+	// - application/json
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/FederationHostRuleList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+
+	rules, err := forgefed.FindFederationHostRules(ctx)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "FindFederationHostRules", err)
+		return
+	}
+
+	apiRules := make([]*api.FederationHostRule, len(rules))
+	for i, rule := range rules {
+		apiRules[i] = convert.ToFederationHostRule(rule)
+	}
+	ctx.JSON(http.StatusOK, apiRules)
+}
+
+// SetFederationHostRule creates or updates the rule of a federation host
+func SetFederationHostRule(ctx *context.APIContext) {
+	// swagger:operation PUT /admin/federation/rules/{pattern} admin adminSetFederationHostRule
+	// ---
+	// summary: Block, silence or allow a federation host, blocking a host purges its users
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: pattern
+	//   in: path
+	//   description: host name, or wildcard domain like "*.example.com" matching all its subdomains
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/SetFederationHostRuleOption"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/FederationHostRule"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	form := web.GetForm(ctx).(*api.SetFederationHostRuleOption)
+	action, _ := forgefed.ParseFederationHostAction(form.Action)
+	rule := &forgefed.FederationHostRule{
+		Pattern: ctx.Params(":pattern"),
+		Action:  action,
+		Reason:  form.Reason,
+	}
+	if err := federation.SetHostRule(ctx, ctx.Doer, rule); err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			ctx.Error(http.StatusUnprocessableEntity, "SetHostRule", err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "SetHostRule", err)
+		}
+		return
+	}
+	if rule.Action == forgefed.FederationHostActionBlock {
+		if err := user_service.PurgeFederationHostUsers(ctx, rule); err != nil {
+			ctx.Error(http.StatusInternalServerError, "PurgeFederationHostUsers", err)
+			return
+		}
+	}
+	ctx.JSON(http.StatusOK, convert.ToFederationHostRule(rule))
+}
+
+// DeleteFederationHostRule deletes the rule of a federation host
+func DeleteFederationHostRule(ctx *context.APIContext) {
+	// swagger:operation DELETE /admin/federation/rules/{pattern} admin adminDeleteFederationHostRule
+	// ---
+	// summary: Delete the rule of a federation host, purged users are not restored
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: pattern
+	//   in: path
+	//   description: host name or wildcard domain of the rule
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	if err := federation.DeleteHostRule(ctx, ctx.Doer, ctx.Params(":pattern")); err != nil {
+		if errors.Is(err, util.ErrNotExist) || errors.Is(err, util.ErrInvalidArgument) {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "DeleteHostRule", err)
+		}
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
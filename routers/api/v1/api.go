@@ -223,6 +223,10 @@ func repoAssignment() func(ctx *context.APIContext) {
 				ctx.Error(http.StatusInternalServerError, "GetUserRepoPermission", err)
 				return
 			}
+			if err := context.LimitRepoPermissionByToken(ctx, ctx.Data, repo, &ctx.Repo.Permission); err != nil {
+				ctx.Error(http.StatusInternalServerError, "LimitRepoPermissionByToken", err)
+				return
+			}
 		}
 
 		if !ctx.Repo.HasAccess() {
@@ -378,6 +382,16 @@ func tokenRequiresScopes(requiredScopeCategories ...auth_model.AccessTokenScopeC
 	}
 }
 
+// reqNotFineGrainedToken rejects fine-grained personal access tokens on endpoints which aren't limited
+// to the repositories of the token, e.g. the creation of repositories
+func reqNotFineGrainedToken() func(ctx *context.APIContext) {
+	return func(ctx *context.APIContext) {
+		if context.FineGrainedAccessToken(ctx.Data) != nil {
+			ctx.Error(http.StatusForbidden, "reqNotFineGrainedToken", "fine-grained access tokens are limited to their repositories")
+		}
+	}
+}
+
 // Contexter middleware already checks token for user sign in process.
 func reqToken() func(ctx *context.APIContext) {
 	return func(ctx *context.APIContext) {
@@ -837,6 +851,7 @@ func Routes() *web.Route {
 				m.Group("/user-id/{user-id}", func() {
 					m.Get("", activitypub.Person)
 					m.Post("/inbox", activitypub.ReqHTTPSignature(), activitypub.PersonInbox)
+					m.Get("/outbox", activitypub.OptHTTPSignature(), activitypub.PersonOutbox)
 				}, context.UserIDAssignmentAPI(), checkTokenPublicOnly())
 				m.Group("/actor", func() {
 					m.Get("", activitypub.Actor)
@@ -848,6 +863,7 @@ func Routes() *web.Route {
 						bind(forgefed.ForgeLike{}),
 						// TODO: activitypub.ReqHTTPSignature(),
 						activitypub.RepositoryInbox)
+					m.Get("/issues/{index}", activitypub.RepositoryTicket)
 				}, context.RepositoryIDAssignmentAPI())
 			}, tokenRequiresScopes(auth_model.AccessTokenScopeCategoryActivityPub))
 		}
@@ -943,6 +959,14 @@ func Routes() *web.Route {
 				m.Get("", user.GetUserSettings)
 				m.Patch("", bind(api.UserSettingsOptions{}), user.UpdateUserSettings)
 			}, reqToken())
+			m.Group("/sessions", func() {
+				m.Combo("").Get(user.ListSessions).
+					Delete(user.DeleteAllSessions)
+				m.Delete("/{id}", user.DeleteSession)
+			}, reqToken())
+			if setting.Federation.Enabled {
+				m.Post("/activitypub/rotate-key", reqToken(), user.RotateActivityPubKey)
+			}
 			m.Combo("/emails").
 				Get(user.ListEmails).
 				Post(bind(api.CreateEmailOption{}), user.AddEmail).
@@ -973,6 +997,9 @@ func Routes() *web.Route {
 			m.Get("/followers", user.ListMyFollowers)
 			m.Group("/following", func() {
 				m.Get("", user.ListMyFollowing)
+				if setting.Federation.Enabled {
+					m.Post("/federated", bind(api.FollowFederatedUserOption{}), user.FollowFederated)
+				}
 				m.Group("/{username}", func() {
 					m.Get("", user.CheckMyFollowing)
 					m.Put("", user.Follow)
@@ -987,6 +1014,7 @@ func Routes() *web.Route {
 				m.Combo("/{id}").Get(user.GetPublicKey).
 					Delete(user.DeletePublicKey)
 			})
+			m.Post("/ssh_certificates", bind(api.CreateSSHCertificateOption{}), user.CreateSSHCertificate)
 
 			// (admin:application scope)
 			m.Group("/applications", func() {
@@ -1011,7 +1039,7 @@ func Routes() *web.Route {
 
 			// (repo scope)
 			m.Combo("/repos", tokenRequiresScopes(auth_model.AccessTokenScopeCategoryRepository)).Get(user.ListMyRepos).
-				Post(bind(api.CreateRepoOption{}), context.EnforceQuotaAPI(quota_model.LimitSubjectSizeReposAll, context.QuotaTargetUser), repo.Create)
+				Post(reqNotFineGrainedToken(), bind(api.CreateRepoOption{}), context.EnforceQuotaAPI(quota_model.LimitSubjectSizeReposAll, context.QuotaTargetUser), repo.Create)
 
 			// (repo scope)
 			if !setting.Repository.DisableStars {
@@ -1055,6 +1083,7 @@ func Routes() *web.Route {
 			// FIXME: we need org in context
 			tokenRequiresScopes(auth_model.AccessTokenScopeCategoryOrganization, auth_model.AccessTokenScopeCategoryRepository),
 			reqToken(),
+			reqNotFineGrainedToken(),
 			bind(api.CreateRepoOption{}),
 			repo.CreateOrgRepoDeprecated)
 
@@ -1067,7 +1096,7 @@ func Routes() *web.Route {
 			m.Get("/search", repo.Search)
 
 			// (repo scope)
-			m.Post("/migrate", reqToken(), bind(api.MigrateRepoOptions{}), repo.Migrate)
+			m.Post("/migrate", reqToken(), reqNotFineGrainedToken(), bind(api.MigrateRepoOptions{}), repo.Migrate)
 
 			m.Group("/{username}/{reponame}", func() {
 				m.Get("/compare/*", reqRepoReader(unit.TypeCode), repo.CompareDiff)
@@ -1075,7 +1104,7 @@ func Routes() *web.Route {
 				m.Combo("").Get(reqAnyRepoReader(), repo.Get).
 					Delete(reqToken(), reqOwner(), repo.Delete).
 					Patch(reqToken(), reqAdmin(), bind(api.EditRepoOption{}), repo.Edit)
-				m.Post("/generate", reqToken(), reqRepoReader(unit.TypeCode), bind(api.GenerateRepoOption{}), repo.Generate)
+				m.Post("/generate", reqToken(), reqNotFineGrainedToken(), reqRepoReader(unit.TypeCode), bind(api.GenerateRepoOption{}), repo.Generate)
 				m.Group("/transfer", func() {
 					m.Post("", reqOwner(), bind(api.TransferRepoOption{}), repo.Transfer)
 					m.Post("/accept", repo.AcceptTransfer)
@@ -1138,7 +1167,7 @@ func Routes() *web.Route {
 				m.Get("/archive/*", reqRepoReader(unit.TypeCode), repo.GetArchive)
 				if !setting.Repository.DisableForks {
 					m.Combo("/forks").Get(repo.ListForks).
-						Post(reqToken(), reqRepoReader(unit.TypeCode), bind(api.CreateForkOption{}), repo.CreateFork)
+						Post(reqToken(), reqNotFineGrainedToken(), reqRepoReader(unit.TypeCode), bind(api.CreateForkOption{}), repo.CreateFork)
 				}
 				m.Group("/branches", func() {
 					m.Get("", repo.ListBranches)
@@ -1472,8 +1501,13 @@ func Routes() *web.Route {
 				m.Get("", reqToken(), packages.GetPackage)
 				m.Delete("", reqToken(), reqPackageAccess(perm.AccessModeWrite), packages.DeletePackage)
 				m.Get("/files", reqToken(), packages.ListPackageFiles)
+				m.Combo("/attestations").
+					Get(reqToken(), packages.ListPackageAttestations).
+					Post(reqToken(), reqPackageAccess(perm.AccessModeWrite), packages.UploadPackageAttestation)
+				m.Get("/vulnerabilities", reqToken(), packages.ListPackageVulnerabilities)
 			})
 			m.Get("/", reqToken(), packages.ListPackages)
+			m.Post("/cleanup-rules/preview", reqToken(), reqPackageAccess(perm.AccessModeWrite), bind(api.PreviewPackageCleanupRuleOption{}), packages.PreviewCleanupRule)
 		}, tokenRequiresScopes(auth_model.AccessTokenScopeCategoryPackage), context.UserAssignmentAPI(), context.PackageAssignmentAPI(), reqPackageAccess(perm.AccessModeRead), checkTokenPublicOnly())
 
 		// Organizations
@@ -1489,7 +1523,7 @@ func Routes() *web.Route {
 				Patch(reqToken(), reqOrgOwnership(), bind(api.EditOrgOption{}), org.Edit).
 				Delete(reqToken(), reqOrgOwnership(), org.Delete)
 			m.Combo("/repos").Get(user.ListOrgRepos).
-				Post(reqToken(), bind(api.CreateRepoOption{}), context.EnforceQuotaAPI(quota_model.LimitSubjectSizeReposAll, context.QuotaTargetOrg), repo.CreateOrgRepo)
+				Post(reqToken(), reqNotFineGrainedToken(), bind(api.CreateRepoOption{}), context.EnforceQuotaAPI(quota_model.LimitSubjectSizeReposAll, context.QuotaTargetOrg), repo.CreateOrgRepo)
 			m.Group("/members", func() {
 				m.Get("", reqToken(), org.ListMembers)
 				m.Combo("/{username}").Get(reqToken(), org.IsMember).
@@ -1529,6 +1563,10 @@ func Routes() *web.Route {
 				m.Post("", bind(api.UpdateUserAvatarOption{}), org.UpdateAvatar)
 				m.Delete("", org.DeleteAvatar)
 			}, reqToken(), reqOrgOwnership())
+			m.Group("/audit", func() {
+				m.Get("", org.ListAuditEvents)
+				m.Get("/export", org.ExportAuditEvents)
+			}, reqToken(), reqOrgOwnership())
 			m.Get("/activities/feeds", org.ListOrgActivityFeeds)
 
 			if setting.Quota.Enabled {
@@ -1576,6 +1614,10 @@ func Routes() *web.Route {
 				m.Post("/{task}", admin.PostCronTask)
 			})
 			m.Get("/orgs", admin.GetAllOrgs)
+			m.Group("/audit", func() {
+				m.Get("", admin.ListAuditEvents)
+				m.Get("/export", admin.ExportAuditEvents)
+			})
 			m.Group("/users", func() {
 				m.Get("", admin.SearchUsers)
 				m.Post("", bind(api.CreateUserOption{}), admin.CreateUser)
@@ -1617,6 +1659,16 @@ func Routes() *web.Route {
 			m.Group("/runners", func() {
 				m.Get("/registration-token", admin.GetRegistrationToken)
 			})
+			if setting.Federation.Enabled {
+				m.Group("/federation", func() {
+					m.Get("/hosts", admin.ListFederationHosts)
+					m.Group("/rules", func() {
+						m.Get("", admin.ListFederationHostRules)
+						m.Combo("/{pattern}").Put(bind(api.SetFederationHostRuleOption{}), admin.SetFederationHostRule).
+							Delete(admin.DeleteFederationHostRule)
+					})
+				})
+			}
 			if setting.Quota.Enabled {
 				m.Group("/quota", func() {
 					m.Group("/rules", func() {
@@ -4,15 +4,21 @@
 package packages
 
 import (
+	"errors"
 	"net/http"
+	"slices"
 
 	"code.gitea.io/gitea/models/packages"
 	"code.gitea.io/gitea/modules/optional"
 	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/web"
 	"code.gitea.io/gitea/routers/api/v1/utils"
 	"code.gitea.io/gitea/services/context"
 	"code.gitea.io/gitea/services/convert"
 	packages_service "code.gitea.io/gitea/services/packages"
+	attestation_service "code.gitea.io/gitea/services/packages/attestation"
+	cleanup_service "code.gitea.io/gitea/services/packages/cleanup"
 )
 
 // ListPackages gets all packages of an owner
@@ -40,7 +46,7 @@ func ListPackages(ctx *context.APIContext) {
 	//   in: query
 	//   description: package type filter
 	//   type: string
-	//   enum: [alpine, cargo, chef, composer, conan, conda, container, cran, debian, generic, go, helm, maven, npm, nuget, pub, pypi, rpm, rubygems, swift, vagrant]
+	//   enum: [alpine, ansible, cargo, chef, composer, conan, conda, container, cran, debian, generic, go, helm, hex, maven, npm, nuget, pub, pypi, rpm, rubygems, swift, terraform, vagrant]
 	// - name: q
 	//   in: query
 	//   description: name filter
@@ -161,11 +167,17 @@ func DeletePackage(ctx *context.APIContext) {
 	// responses:
 	//   "204":
 	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
 	//   "404":
 	//     "$ref": "#/responses/notFound"
 
 	err := packages_service.RemovePackageVersion(ctx, ctx.Doer, ctx.Package.Descriptor.Version)
 	if err != nil {
+		if errors.Is(err, packages_service.ErrVersionProtected) {
+			ctx.Error(http.StatusForbidden, "", err)
+			return
+		}
 		ctx.Error(http.StatusInternalServerError, "RemovePackageVersion", err)
 		return
 	}
@@ -213,3 +225,238 @@ func ListPackageFiles(ctx *context.APIContext) {
 
 	ctx.JSON(http.StatusOK, apiPackageFiles)
 }
+
+// ListPackageAttestations gets all attestations of a package version
+func ListPackageAttestations(ctx *context.APIContext) {
+	// swagger:operation GET /packages/{owner}/{type}/{name}/{version}/attestations package listPackageAttestations
+	// ---
+	// summary: Gets all provenance attestations of a package
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: version
+	//   in: path
+	//   description: version of the package
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PackageAttestationList"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	pas, err := packages.GetAttestationsByVersionID(ctx, ctx.Package.Descriptor.Version.ID)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "GetAttestationsByVersionID", err)
+		return
+	}
+
+	apiAttestations := make([]*api.PackageAttestation, 0, len(pas))
+	for _, pa := range pas {
+		apiAttestation, err := convert.ToPackageAttestation(ctx, pa, ctx.Doer)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, "Error converting package attestation for api", err)
+			return
+		}
+		apiAttestations = append(apiAttestations, apiAttestation)
+	}
+
+	ctx.JSON(http.StatusOK, apiAttestations)
+}
+
+// UploadPackageAttestation attaches a provenance attestation to a package version
+func UploadPackageAttestation(ctx *context.APIContext) {
+	// swagger:operation POST /packages/{owner}/{type}/{name}/{version}/attestations package uploadPackageAttestation
+	// ---
+	// summary: Attach a provenance attestation to a package
+	// description: The body must be a DSSE envelope containing an in-toto statement about a file of the package.
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: version
+	//   in: path
+	//   description: version of the package
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   description: DSSE envelope
+	//   required: true
+	//   schema:
+	//     type: object
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/PackageAttestation"
+	//   "400":
+	//     "$ref": "#/responses/error"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	pa, err := attestation_service.UploadAttestation(ctx, ctx.Doer, ctx.Package.Descriptor, ctx.Req.Body)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			ctx.Error(http.StatusBadRequest, "", err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "UploadAttestation", err)
+		}
+		return
+	}
+
+	apiAttestation, err := convert.ToPackageAttestation(ctx, pa, ctx.Doer)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "Error converting package attestation for api", err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, apiAttestation)
+}
+
+// ListPackageVulnerabilities gets the known vulnerabilities affecting a package version
+func ListPackageVulnerabilities(ctx *context.APIContext) {
+	// swagger:operation GET /packages/{owner}/{type}/{name}/{version}/vulnerabilities package listPackageVulnerabilities
+	// ---
+	// summary: Gets the known vulnerabilities affecting a package
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: version
+	//   in: path
+	//   description: version of the package
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PackageVulnerabilityList"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	pas, err := packages.GetAdvisoriesByVersionID(ctx, ctx.Package.Descriptor.Version.ID)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "GetAdvisoriesByVersionID", err)
+		return
+	}
+
+	apiVulnerabilities := make([]*api.PackageVulnerability, 0, len(pas))
+	for _, pa := range pas {
+		apiVulnerabilities = append(apiVulnerabilities, convert.ToPackageVulnerability(pa))
+	}
+
+	ctx.JSON(http.StatusOK, apiVulnerabilities)
+}
+
+// PreviewCleanupRule lists the package versions a cleanup rule would remove
+func PreviewCleanupRule(ctx *context.APIContext) {
+	// swagger:operation POST /packages/{owner}/cleanup-rules/preview package previewPackageCleanupRule
+	// ---
+	// summary: Lists the package versions a cleanup rule would remove, without removing them
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the packages
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/PreviewPackageCleanupRuleOption"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PackageList"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	form := web.GetForm(ctx).(*api.PreviewPackageCleanupRuleOption)
+
+	packageType := packages.Type(form.Type)
+	if !slices.Contains(packages.TypeList, packageType) {
+		ctx.Error(http.StatusUnprocessableEntity, "", "invalid package type")
+		return
+	}
+
+	pcr := &packages.PackageCleanupRule{
+		OwnerID:           ctx.Package.Owner.ID,
+		Type:              packageType,
+		KeepCount:         form.KeepCount,
+		KeepPattern:       form.KeepPattern,
+		KeepSemverPatches: form.KeepSemverPatches,
+		RemoveDays:        form.RemoveDays,
+		RemoveUnusedDays:  form.RemoveUnusedDays,
+		RemovePattern:     form.RemovePattern,
+		MatchFullName:     form.MatchFullName,
+	}
+	if err := pcr.CompiledPattern(); err != nil {
+		ctx.Error(http.StatusUnprocessableEntity, "CompiledPattern", err)
+		return
+	}
+
+	pds, err := cleanup_service.PreviewCleanupRule(ctx, pcr)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "PreviewCleanupRule", err)
+		return
+	}
+
+	apiPackages := make([]*api.Package, 0, len(pds))
+	for _, pd := range pds {
+		apiPackage, err := convert.ToPackage(ctx, pd, ctx.Doer)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, "Error converting package for api", err)
+			return
+		}
+		apiPackages = append(apiPackages, apiPackage)
+	}
+
+	ctx.JSON(http.StatusOK, apiPackages)
+}
@@ -201,6 +201,11 @@ func SearchIssues(ctx *context.APIContext) {
 			allPublic = true
 			opts.AllPublic = false // set it false to avoid returning too many repos, we could filter by indexer
 		}
+		context.LimitRepoSearchByToken(ctx.Data, opts)
+		if err := context.LimitRepoSearchByIPAllowlist(ctx, ctx.Doer, opts); err != nil {
+			ctx.Error(http.StatusInternalServerError, "LimitRepoSearchByIPAllowlist", err)
+			return
+		}
 		repoIDs, _, err = repo_model.SearchRepositoryIDs(ctx, opts)
 		if err != nil {
 			ctx.Error(http.StatusInternalServerError, "SearchRepositoryIDs", err)
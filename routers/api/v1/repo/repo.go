@@ -203,6 +203,15 @@ func Search(ctx *context.APIContext) {
 		}
 	}
 
+	context.LimitRepoSearchByToken(ctx.Data, opts)
+	if err := context.LimitRepoSearchByIPAllowlist(ctx, ctx.Doer, opts); err != nil {
+		ctx.JSON(http.StatusInternalServerError, api.SearchError{
+			OK:    false,
+			Error: err.Error(),
+		})
+		return
+	}
+
 	var err error
 	repos, count, err := repo_model.SearchRepository(ctx, opts)
 	if err != nil {
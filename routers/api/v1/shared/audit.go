@@ -0,0 +1,81 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package shared
+
+import (
+	go_context "context"
+	"net/http"
+
+	audit_model "code.gitea.io/gitea/models/audit"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/log"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/routers/api/v1/utils"
+	audit_service "code.gitea.io/gitea/services/audit"
+	"code.gitea.io/gitea/services/context"
+)
+
+// auditFindOptions returns the filters of the audit events given by the query of the request
+func auditFindOptions(ctx *context.APIContext, ownerID int64) (audit_model.FindEventsOptions, bool) {
+	before, since, err := context.GetQueryBeforeSince(ctx.Base)
+	if err != nil {
+		ctx.Error(http.StatusUnprocessableEntity, "GetQueryBeforeSince", err)
+		return audit_model.FindEventsOptions{}, false
+	}
+	return audit_model.FindEventsOptions{
+		Action:     audit_model.Action(ctx.FormTrim("action")),
+		ActorName:  ctx.FormTrim("actor"),
+		OwnerID:    ownerID,
+		TargetType: ctx.FormTrim("target_type"),
+		Since:      timeutil.TimeStamp(since),
+		Until:      timeutil.TimeStamp(before),
+	}, true
+}
+
+// ListAuditEvents responds with a page of the audit events of the owner which match the filters of the request,
+// an owner ID of 0 lists the events of the whole instance
+func ListAuditEvents(ctx *context.APIContext, ownerID int64) {
+	opts, ok := auditFindOptions(ctx, ownerID)
+	if !ok {
+		return
+	}
+	opts.ListOptions = utils.GetListOptions(ctx)
+
+	events, total, err := db.FindAndCount[audit_model.Event](ctx, opts)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "FindAuditEvents", err)
+		return
+	}
+
+	apiEvents := make([]*api.AuditEvent, len(events))
+	for i, e := range events {
+		apiEvents[i] = audit_service.ToAPIEvent(e)
+	}
+
+	ctx.SetLinkHeader(int(total), opts.PageSize)
+	ctx.SetTotalCountHeader(total)
+	ctx.JSON(http.StatusOK, apiEvents)
+}
+
+// ExportAuditEvents responds with all audit events of the owner which match the filters of the request as JSON lines,
+// an owner ID of 0 exports the events of the whole instance
+func ExportAuditEvents(ctx *context.APIContext, ownerID int64) {
+	opts, ok := auditFindOptions(ctx, ownerID)
+	if !ok {
+		return
+	}
+
+	ctx.Resp.Header().Set("Content-Type", "application/jsonl")
+	ctx.Resp.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(ctx.Resp)
+	if err := db.Iterate(ctx, opts.ToConds(), func(_ go_context.Context, e *audit_model.Event) error {
+		return enc.Encode(audit_service.ToAPIEvent(e))
+	}); err != nil {
+		// the status is already written, the export can only be truncated
+		log.Error("Unable to export the audit events: %v", err)
+	}
+}
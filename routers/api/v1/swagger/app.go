@@ -20,3 +20,10 @@ type swaggerResponseAccessToken struct {
 	// in:body
 	Body api.AccessToken `json:"body"`
 }
+
+// UserSessionList represents a list of signed-in web sessions
+// swagger:response UserSessionList
+type swaggerResponseUserSessionList struct {
+	// in:body
+	Body []api.UserSession `json:"body"`
+}
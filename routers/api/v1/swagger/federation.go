@@ -0,0 +1,29 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package swagger
+
+import (
+	api "code.gitea.io/gitea/modules/structs"
+)
+
+// FederationHostList
+// swagger:response FederationHostList
+type swaggerResponseFederationHostList struct {
+	// in:body
+	Body []api.FederationHost `json:"body"`
+}
+
+// FederationHostRule
+// swagger:response FederationHostRule
+type swaggerResponseFederationHostRule struct {
+	// in:body
+	Body api.FederationHostRule `json:"body"`
+}
+
+// FederationHostRuleList
+// swagger:response FederationHostRuleList
+type swaggerResponseFederationHostRuleList struct {
+	// in:body
+	Body []api.FederationHostRule `json:"body"`
+}
@@ -61,6 +61,9 @@ type swaggerParameterBodies struct {
 	// in:body
 	CreateKeyOption api.CreateKeyOption
 
+	// in:body
+	CreateSSHCertificateOption api.CreateSSHCertificateOption
+
 	// in:body
 	RenameUserOption api.RenameUserOption
 
@@ -205,6 +208,12 @@ type swaggerParameterBodies struct {
 	// in:body
 	UpdateUserAvatarOptions api.UpdateUserAvatarOption
 
+	// in:body
+	FollowFederatedUserOption api.FollowFederatedUserOption
+
+	// in:body
+	SetFederationHostRuleOption api.SetFederationHostRuleOption
+
 	// in:body
 	UpdateRepoAvatarOptions api.UpdateRepoAvatarOption
 
@@ -231,4 +240,7 @@ type swaggerParameterBodies struct {
 
 	// in:body
 	SetUserQuotaGroupsOptions api.SetUserQuotaGroupsOptions
+
+	// in:body
+	PreviewPackageCleanupRuleOption api.PreviewPackageCleanupRuleOption
 }
@@ -0,0 +1,31 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package user
+
+import (
+	"net/http"
+
+	"code.gitea.io/gitea/services/context"
+	"code.gitea.io/gitea/services/federation"
+)
+
+// RotateActivityPubKey replaces the key the authenticated user signs its ActivityPub activities with
+func RotateActivityPubKey(ctx *context.APIContext) {
+	// swagger:operation POST /user/activitypub/rotate-key user userRotateActivityPubKey
+	// ---
+	// summary: Replace the key the authenticated user signs its ActivityPub activities with
+	// description: The new public key is announced to the users of other instances following the user or followed by it.
+	// produces:
+	// - application/json
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	if err := federation.RotateKey(ctx, ctx.Doer); err != nil {
+		ctx.InternalServerError(err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
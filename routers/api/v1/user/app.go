@@ -14,8 +14,10 @@ import (
 	auth_model "code.gitea.io/gitea/models/auth"
 	"code.gitea.io/gitea/models/db"
 	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/modules/web"
 	"code.gitea.io/gitea/routers/api/v1/utils"
+	auth_service "code.gitea.io/gitea/services/auth"
 	"code.gitea.io/gitea/services/context"
 	"code.gitea.io/gitea/services/convert"
 )
@@ -57,11 +59,9 @@ func ListAccessTokens(ctx *context.APIContext) {
 
 	apiTokens := make([]*api.AccessToken, len(tokens))
 	for i := range tokens {
-		apiTokens[i] = &api.AccessToken{
-			ID:             tokens[i].ID,
-			Name:           tokens[i].Name,
-			TokenLastEight: tokens[i].TokenLastEight,
-			Scopes:         tokens[i].Scope.StringSlice(),
+		if apiTokens[i], err = convert.ToAccessToken(ctx, tokens[i]); err != nil {
+			ctx.InternalServerError(err)
+			return
 		}
 	}
 
@@ -113,28 +113,45 @@ func CreateAccessToken(ctx *context.APIContext) {
 		return
 	}
 
-	scope, err := auth_model.AccessTokenScope(strings.Join(form.Scopes, ",")).Normalize()
-	if err != nil {
-		ctx.Error(http.StatusBadRequest, "AccessTokenScope.Normalize", fmt.Errorf("invalid access token scope provided: %w", err))
-		return
+	opts := auth_service.CreateAccessTokenOptions{
+		Name:         form.Name,
+		Organization: form.Organization,
+		Repositories: form.Repositories,
+	}
+	if len(form.Permissions) > 0 {
+		opts.Permissions, err = auth_model.ParseAccessTokenPermissions(form.Permissions)
+		if err != nil {
+			ctx.Error(http.StatusBadRequest, "ParseAccessTokenPermissions", err)
+			return
+		}
+	} else {
+		opts.Scope, err = auth_model.AccessTokenScope(strings.Join(form.Scopes, ",")).Normalize()
+		if err != nil {
+			ctx.Error(http.StatusBadRequest, "AccessTokenScope.Normalize", fmt.Errorf("invalid access token scope provided: %w", err))
+			return
+		}
+	}
+	if form.ExpiresAt != nil {
+		opts.Expires = *form.ExpiresAt
 	}
-	if scope == "" {
-		ctx.Error(http.StatusBadRequest, "AccessTokenScope", "access token must have a scope")
+
+	t, err = auth_service.CreateAccessToken(ctx, ctx.ContextUser, opts)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			ctx.Error(http.StatusBadRequest, "CreateAccessToken", err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "CreateAccessToken", err)
+		}
 		return
 	}
-	t.Scope = scope
 
-	if err := auth_model.NewAccessToken(ctx, t); err != nil {
-		ctx.Error(http.StatusInternalServerError, "NewAccessToken", err)
+	apiToken, err := convert.ToAccessToken(ctx, t)
+	if err != nil {
+		ctx.InternalServerError(err)
 		return
 	}
-	ctx.JSON(http.StatusCreated, &api.AccessToken{
-		Name:           t.Name,
-		Token:          t.Token,
-		ID:             t.ID,
-		TokenLastEight: t.TokenLastEight,
-		Scopes:         t.Scope.StringSlice(),
-	})
+	apiToken.Token = t.Token
+	ctx.JSON(http.StatusCreated, apiToken)
 }
 
 // DeleteAccessToken delete access tokens
@@ -194,11 +211,11 @@ func DeleteAccessToken(ctx *context.APIContext) {
 		return
 	}
 
-	if err := auth_model.DeleteAccessTokenByID(ctx, tokenID, ctx.ContextUser.ID); err != nil {
+	if err := auth_service.DeleteAccessToken(ctx, ctx.ContextUser, tokenID); err != nil {
 		if auth_model.IsErrAccessTokenNotExist(err) {
 			ctx.NotFound()
 		} else {
-			ctx.Error(http.StatusInternalServerError, "DeleteAccessTokenByID", err)
+			ctx.Error(http.StatusInternalServerError, "DeleteAccessToken", err)
 		}
 		return
 	}
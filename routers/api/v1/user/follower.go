@@ -9,10 +9,13 @@ import (
 	"net/http"
 
 	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/setting"
 	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/web"
 	"code.gitea.io/gitea/routers/api/v1/utils"
 	"code.gitea.io/gitea/services/context"
 	"code.gitea.io/gitea/services/convert"
+	"code.gitea.io/gitea/services/federation"
 )
 
 func responseAPIUsers(ctx *context.APIContext, users []*user_model.User) {
@@ -227,7 +230,13 @@ func Follow(ctx *context.APIContext) {
 	//   "403":
 	//     "$ref": "#/responses/forbidden"
 
-	if err := user_model.FollowUser(ctx, ctx.Doer.ID, ctx.ContextUser.ID); err != nil {
+	var err error
+	if ctx.ContextUser.IsRemote() && setting.Federation.Enabled {
+		err = federation.FollowRemoteUser(ctx, ctx.Doer, ctx.ContextUser)
+	} else {
+		err = user_model.FollowUser(ctx, ctx.Doer.ID, ctx.ContextUser.ID)
+	}
+	if err != nil {
 		if errors.Is(err, user_model.ErrBlockedByUser) {
 			ctx.Error(http.StatusForbidden, "BlockedByUser", err)
 			return
@@ -255,9 +264,51 @@ func Unfollow(ctx *context.APIContext) {
 	//   "404":
 	//     "$ref": "#/responses/notFound"
 
-	if err := user_model.UnfollowUser(ctx, ctx.Doer.ID, ctx.ContextUser.ID); err != nil {
+	var err error
+	if ctx.ContextUser.IsRemote() && setting.Federation.Enabled {
+		err = federation.UnfollowRemoteUser(ctx, ctx.Doer, ctx.ContextUser)
+	} else {
+		err = user_model.UnfollowUser(ctx, ctx.Doer.ID, ctx.ContextUser.ID)
+	}
+	if err != nil {
 		ctx.Error(http.StatusInternalServerError, "UnfollowUser", err)
 		return
 	}
 	ctx.Status(http.StatusNoContent)
 }
+
+// FollowFederated follow a user of another instance
+func FollowFederated(ctx *context.APIContext) {
+	// swagger:operation POST /user/following/federated user userCurrentFollowFederated
+	// ---
+	// summary: Follow a user of another instance by the ID of its ActivityPub actor
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: body
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/FollowFederatedUserOption"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/User"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	form := web.GetForm(ctx).(*api.FollowFederatedUserOption)
+	remoteUser, err := federation.FollowActor(ctx, ctx.Doer, form.ActorID)
+	if err != nil {
+		if errors.Is(err, user_model.ErrBlockedByUser) {
+			ctx.Error(http.StatusForbidden, "BlockedByUser", err)
+			return
+		}
+		ctx.Error(http.StatusUnprocessableEntity, "FollowActor", err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, convert.ToUser(ctx, remoteUser, ctx.Doer))
+}
@@ -210,7 +210,7 @@ func CreateUserPublicKey(ctx *context.APIContext, form api.CreateKeyOption, uid
 		return
 	}
 
-	key, err := asymkey_model.AddPublicKey(ctx, uid, form.Title, content, 0)
+	key, err := asymkey_service.AddPublicKey(ctx, uid, form.Title, content)
 	if err != nil {
 		repo.HandleAddKeyError(ctx, err)
 		return
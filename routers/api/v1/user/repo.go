@@ -19,12 +19,19 @@ import (
 func listUserRepos(ctx *context.APIContext, u *user_model.User, private bool) {
 	opts := utils.GetListOptions(ctx)
 
-	repos, count, err := repo_model.GetUserRepositories(ctx, &repo_model.SearchRepoOptions{
+	searchOpts := &repo_model.SearchRepoOptions{
 		Actor:       u,
 		Private:     private,
 		ListOptions: opts,
 		OrderBy:     "id ASC",
-	})
+	}
+	context.LimitRepoSearchByToken(ctx.Data, searchOpts)
+	if err := context.LimitRepoSearchByIPAllowlist(ctx, ctx.Doer, searchOpts); err != nil {
+		ctx.Error(http.StatusInternalServerError, "LimitRepoSearchByIPAllowlist", err)
+		return
+	}
+
+	repos, count, err := repo_model.GetUserRepositories(ctx, searchOpts)
 	if err != nil {
 		ctx.Error(http.StatusInternalServerError, "GetUserRepositories", err)
 		return
@@ -129,6 +136,11 @@ func ListMyRepos(ctx *context.APIContext) {
 		ctx.Error(http.StatusUnprocessableEntity, "", "invalid order_by")
 		return
 	}
+	context.LimitRepoSearchByToken(ctx.Data, opts)
+	if err := context.LimitRepoSearchByIPAllowlist(ctx, ctx.Doer, opts); err != nil {
+		ctx.Error(http.StatusInternalServerError, "LimitRepoSearchByIPAllowlist", err)
+		return
+	}
 
 	var err error
 	repos, count, err := repo_model.SearchRepository(ctx, opts)
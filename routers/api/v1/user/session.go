@@ -0,0 +1,123 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package user
+
+import (
+	"errors"
+	"net/http"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/routers/api/v1/utils"
+	"code.gitea.io/gitea/services/context"
+	"code.gitea.io/gitea/services/convert"
+)
+
+// ListSessions lists the signed-in web sessions of the authenticated user
+func ListSessions(ctx *context.APIContext) {
+	// swagger:operation GET /user/sessions user userListSessions
+	// ---
+	// summary: List the authenticated user's signed-in web sessions
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/UserSessionList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+
+	opts := auth_model.FindUserSessionsOptions{UID: ctx.Doer.ID, ListOptions: utils.GetListOptions(ctx)}
+
+	sessions, count, err := db.FindAndCount[auth_model.UserSession](ctx, opts)
+	if err != nil {
+		ctx.InternalServerError(err)
+		return
+	}
+
+	apiSessions := make([]*api.UserSession, len(sessions))
+	for i := range sessions {
+		apiSessions[i] = convert.ToUserSession(sessions[i])
+	}
+
+	ctx.SetLinkHeader(int(count), opts.PageSize)
+	ctx.SetTotalCountHeader(count)
+	ctx.JSON(http.StatusOK, &apiSessions)
+}
+
+// DeleteSession signs out a web session of the authenticated user
+func DeleteSession(ctx *context.APIContext) {
+	// swagger:operation DELETE /user/sessions/{id} user userDeleteSession
+	// ---
+	// summary: Sign out a web session of the authenticated user
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of the session to sign out
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	s, err := auth_model.GetUserSessionByID(ctx, ctx.ParamsInt64(":id"))
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.NotFound()
+		} else {
+			ctx.InternalServerError(err)
+		}
+		return
+	}
+	if s.UID != ctx.Doer.ID || s.IsExpired() {
+		ctx.NotFound()
+		return
+	}
+
+	if err := auth_model.RevokeUserSession(ctx, s); err != nil {
+		ctx.InternalServerError(err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// DeleteAllSessions signs out all web sessions of the authenticated user
+func DeleteAllSessions(ctx *context.APIContext) {
+	// swagger:operation DELETE /user/sessions user userDeleteAllSessions
+	// ---
+	// summary: Sign out all web sessions of the authenticated user
+	// description: The long-term authentication tokens ("remember me") of the user are revoked as well, access tokens are kept.
+	// produces:
+	// - application/json
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+
+	if err := auth_model.RevokeUserSessionsByUID(ctx, ctx.Doer.ID); err != nil {
+		ctx.InternalServerError(err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package user
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/web"
+	asymkey_service "code.gitea.io/gitea/services/asymkey"
+	"code.gitea.io/gitea/services/context"
+	"code.gitea.io/gitea/services/convert"
+)
+
+// CreateSSHCertificate signs a short-lived SSH certificate for a public key of the authenticated user
+func CreateSSHCertificate(ctx *context.APIContext) {
+	// swagger:operation POST /user/ssh_certificates user userCreateSSHCertificate
+	// ---
+	// summary: Sign a short-lived SSH certificate for a public key
+	// description: The certificate is issued by the SSH certificate authority of the instance and has the username as principal.
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/CreateSSHCertificateOption"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/SSHCertificate"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "409":
+	//     "$ref": "#/responses/error"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	form := web.GetForm(ctx).(*api.CreateSSHCertificateOption)
+	if form.Validity < 0 {
+		ctx.Error(http.StatusUnprocessableEntity, "", "validity must not be negative")
+		return
+	}
+
+	cert, err := asymkey_service.SignUserCertificate(ctx, ctx.Doer, form.Key, time.Duration(form.Validity)*time.Second)
+	if err != nil {
+		switch {
+		case errors.Is(err, util.ErrNotExist):
+			ctx.NotFound()
+		case errors.Is(err, util.ErrAlreadyExist):
+			ctx.Error(http.StatusConflict, "", err)
+		case errors.Is(err, util.ErrInvalidArgument):
+			ctx.Error(http.StatusUnprocessableEntity, "", err)
+		default:
+			ctx.Error(http.StatusInternalServerError, "SignUserCertificate", err)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, convert.ToSSHCertificate(cert))
+}
@@ -29,15 +29,19 @@ import (
 	actions_router "code.gitea.io/gitea/routers/api/actions"
 	forgejo "code.gitea.io/gitea/routers/api/forgejo/v1"
 	packages_router "code.gitea.io/gitea/routers/api/packages"
+	"code.gitea.io/gitea/routers/api/packages/terraform"
+	scim_router "code.gitea.io/gitea/routers/api/scim"
 	apiv1 "code.gitea.io/gitea/routers/api/v1"
 	"code.gitea.io/gitea/routers/common"
 	"code.gitea.io/gitea/routers/private"
 	web_routers "code.gitea.io/gitea/routers/web"
 	actions_service "code.gitea.io/gitea/services/actions"
+	audit_service "code.gitea.io/gitea/services/audit"
 	"code.gitea.io/gitea/services/auth"
 	"code.gitea.io/gitea/services/auth/source/oauth2"
 	"code.gitea.io/gitea/services/automerge"
 	"code.gitea.io/gitea/services/cron"
+	"code.gitea.io/gitea/services/federation"
 	feed_service "code.gitea.io/gitea/services/feed"
 	indexer_service "code.gitea.io/gitea/services/indexer"
 	"code.gitea.io/gitea/services/mailer"
@@ -123,6 +127,7 @@ func InitWebInstalled(ctx context.Context) {
 	mustInit(cache.Init)
 	mustInit(feed_service.Init)
 	mustInit(uinotification.Init)
+	mustInit(audit_service.Init)
 	mustInitCtx(ctx, archiver.Init)
 
 	highlight.NewContext()
@@ -151,6 +156,7 @@ func InitWebInstalled(ctx context.Context) {
 
 	mirror_service.InitSyncMirrors()
 	mustInit(webhook.Init)
+	mustInit(federation.Init)
 	mustInit(pull_service.Init)
 	mustInit(automerge.Init)
 	mustInit(task.Init)
@@ -181,6 +187,7 @@ func NormalRoutes() *web.Route {
 	r.Mount("/api/v1", apiv1.Routes())
 	r.Mount("/api/forgejo/v1", forgejo.Routes())
 	r.Mount("/api/internal", private.Routes())
+	r.Mount("/api/scim/v2", scim_router.Routes())
 
 	r.Post("/-/fetch-redirect", common.FetchRedirectDelegate)
 
@@ -189,6 +196,8 @@ func NormalRoutes() *web.Route {
 		r.Mount("/api/packages", packages_router.CommonRoutes())
 		// This implements the OCI API (Note this is not preceded by /api but is instead /v2)
 		r.Mount("/v2", packages_router.ContainerRoutes())
+		// Terraform and OpenTofu locate the registry through service discovery on the host
+		r.Get("/.well-known/terraform.json", terraform.ServiceDiscovery)
 	}
 
 	if setting.Actions.Enabled {
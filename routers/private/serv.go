@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	asymkey_model "code.gitea.io/gitea/models/asymkey"
+	"code.gitea.io/gitea/models/organization"
 	"code.gitea.io/gitea/models/perm"
 	access_model "code.gitea.io/gitea/models/perm/access"
 	repo_model "code.gitea.io/gitea/models/repo"
@@ -301,6 +302,25 @@ func ServCommand(ctx *context.PrivateContext) {
 				mode = perm.AccessModeRead
 			}
 
+			// Organizations may only allow access from some networks, deploy keys aren't restricted by them
+			if owner.IsOrganization() && !user.IsAdmin {
+				allowed, err := organization.IsIPAllowed(ctx, owner.ID, ctx.FormString("remote_ip"))
+				if err != nil {
+					log.Error("Unable to check the IP allowlist of %-v Error: %v", owner, err)
+					ctx.JSON(http.StatusInternalServerError, private.Response{
+						Err: fmt.Sprintf("Unable to check the IP allowlist of %s Error: %v", results.OwnerName, err),
+					})
+					return
+				}
+				if !allowed {
+					log.Warn("Failed authentication attempt for %s with key %s (IP address %q not allowed by %s) from %s", user.Name, key.Name, ctx.FormString("remote_ip"), ownerName, ctx.RemoteAddr())
+					ctx.JSON(http.StatusForbidden, private.Response{
+						UserMsg: fmt.Sprintf("The IP allowlist of %s does not allow access from your IP address.", ownerName),
+					})
+					return
+				}
+			}
+
 			perm, err := access_model.GetUserRepoPermission(ctx, repo, user)
 			if err != nil {
 				log.Error("Unable to get permissions for %-v with key %d in %-v Error: %v", user, key.ID, repo, err)
@@ -0,0 +1,32 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package admin
+
+import (
+	"net/http"
+
+	"code.gitea.io/gitea/modules/base"
+	shared "code.gitea.io/gitea/routers/web/shared/audit"
+	"code.gitea.io/gitea/services/context"
+)
+
+const tplAudit base.TplName = "admin/audit"
+
+// Audit shows the audit log of the instance
+func Audit(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("admin.audit")
+	ctx.Data["PageIsAdminAudit"] = true
+
+	shared.SetEventsContext(ctx, 0)
+	if ctx.Written() {
+		return
+	}
+
+	ctx.HTML(http.StatusOK, tplAudit)
+}
+
+// AuditExport exports the audit log of the instance as JSON lines
+func AuditExport(ctx *context.Context) {
+	shared.ExportEvents(ctx, 0)
+}
@@ -17,6 +17,7 @@ import (
 	"code.gitea.io/gitea/modules/auth/pam"
 	"code.gitea.io/gitea/modules/base"
 	"code.gitea.io/gitea/modules/log"
+	saml_module "code.gitea.io/gitea/modules/saml"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/modules/web"
@@ -24,6 +25,7 @@ import (
 	"code.gitea.io/gitea/services/auth/source/ldap"
 	"code.gitea.io/gitea/services/auth/source/oauth2"
 	pam_service "code.gitea.io/gitea/services/auth/source/pam"
+	"code.gitea.io/gitea/services/auth/source/saml"
 	"code.gitea.io/gitea/services/auth/source/smtp"
 	"code.gitea.io/gitea/services/auth/source/sspi"
 	"code.gitea.io/gitea/services/context"
@@ -71,6 +73,7 @@ var (
 			{auth.SMTP.String(), auth.SMTP},
 			{auth.OAuth2.String(), auth.OAuth2},
 			{auth.SSPI.String(), auth.SSPI},
+			{auth.SAML.String(), auth.SAML},
 		}
 		if pam.Supported {
 			items = append(items, dropdownItem{auth.Names[auth.PAM], auth.PAM})
@@ -232,6 +235,73 @@ func parseSSPIConfig(ctx *context.Context, form forms.AuthenticationForm) (*sspi
 	}, nil
 }
 
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// parseSAMLConfig validates the SAML settings of the form, the existing service provider key is kept
+// unless a new one is provided and a key is generated if there is none
+func parseSAMLConfig(ctx *context.Context, form forms.AuthenticationForm, existing *saml.Source) (*saml.Source, error) {
+	if !isHTTPURL(form.SAMLIdpSsoURL) {
+		ctx.Data["Err_SAMLIdpSsoURL"] = true
+		return nil, errors.New(ctx.Locale.TrString("admin.auths.saml_invalid_url"))
+	}
+	if form.SAMLIdpSloURL != "" && !isHTTPURL(form.SAMLIdpSloURL) {
+		ctx.Data["Err_SAMLIdpSloURL"] = true
+		return nil, errors.New(ctx.Locale.TrString("admin.auths.saml_invalid_url"))
+	}
+	if strings.TrimSpace(form.SAMLIdpEntityID) == "" {
+		ctx.Data["Err_SAMLIdpEntityID"] = true
+		return nil, errors.New(ctx.Locale.TrString("admin.auths.saml_idp_entity_id") + ctx.Locale.TrString("form.require_error"))
+	}
+	if _, err := saml_module.ParseCertificates(form.SAMLIdpCertificate); err != nil {
+		ctx.Data["Err_SAMLIdpCertificate"] = true
+		return nil, errors.New(ctx.Locale.TrString("admin.auths.saml_invalid_certificate"))
+	}
+
+	source := &saml.Source{
+		IdPEntityID:         strings.TrimSpace(form.SAMLIdpEntityID),
+		IdPSSOURL:           form.SAMLIdpSsoURL,
+		IdPSLOURL:           form.SAMLIdpSloURL,
+		IdPCertificate:      form.SAMLIdpCertificate,
+		SignRequests:        form.SAMLSignRequests,
+		AllowSHA1:           form.SAMLAllowSHA1,
+		NameIDFormat:        form.SAMLNameIDFormat,
+		AttributeUsername:   form.SAMLAttributeUsername,
+		AttributeEmail:      form.SAMLAttributeEmail,
+		AttributeFullName:   form.SAMLAttributeFullName,
+		AttributeGroups:     form.SAMLAttributeGroups,
+		GroupTeamMap:        form.SAMLGroupTeamMap,
+		GroupTeamMapRemoval: form.SAMLGroupTeamMapRemoval,
+		SkipLocalTwoFA:      form.SkipLocalTwoFA,
+		IconURL:             form.SAMLIconURL,
+	}
+
+	switch {
+	case form.SAMLSpPrivateKey != "":
+		if _, err := saml_module.ParsePrivateKey(form.SAMLSpPrivateKey); err != nil {
+			ctx.Data["Err_SAMLSpPrivateKey"] = true
+			return nil, errors.New(ctx.Locale.TrString("admin.auths.saml_invalid_private_key"))
+		}
+		if _, err := saml_module.ParseCertificates(form.SAMLSpCertificate); err != nil {
+			ctx.Data["Err_SAMLSpCertificate"] = true
+			return nil, errors.New(ctx.Locale.TrString("admin.auths.saml_invalid_certificate"))
+		}
+		source.SPPrivateKey = form.SAMLSpPrivateKey
+		source.SPCertificate = form.SAMLSpCertificate
+	case existing != nil && existing.SPPrivateKey != "":
+		source.SPPrivateKey = existing.SPPrivateKey
+		source.SPCertificate = existing.SPCertificate
+	default:
+		var err error
+		if source.SPPrivateKey, source.SPCertificate, err = saml_module.GenerateKeyPair(setting.Domain); err != nil {
+			return nil, err
+		}
+	}
+	return source, nil
+}
+
 // NewAuthSourcePost response for adding an auth source
 func NewAuthSourcePost(ctx *context.Context) {
 	form := *web.GetForm(ctx).(*forms.AuthenticationForm)
@@ -291,6 +361,13 @@ func NewAuthSourcePost(ctx *context.Context) {
 			ctx.RenderWithErr(ctx.Tr("admin.auths.login_source_of_type_exist"), tplAuthNew, form)
 			return
 		}
+	case auth.SAML:
+		var err error
+		config, err = parseSAMLConfig(ctx, form, nil)
+		if err != nil {
+			ctx.RenderWithErr(err.Error(), tplAuthNew, form)
+			return
+		}
 	default:
 		ctx.Error(http.StatusBadRequest)
 		return
@@ -346,6 +423,15 @@ func EditAuthSource(ctx *context.Context) {
 	ctx.Data["Source"] = source
 	ctx.Data["HasTLS"] = source.HasTLS()
 
+	if source.IsSAML() {
+		token, err := auth.GetSCIMToken(ctx, source.ID)
+		if err != nil && !auth.IsErrSCIMTokenNotExist(err) {
+			ctx.ServerError("auth.GetSCIMToken", err)
+			return
+		}
+		ctx.Data["SCIMToken"] = token
+	}
+
 	if source.IsOAuth2() {
 		type Named interface {
 			Name() string
@@ -413,6 +499,13 @@ func EditAuthSourcePost(ctx *context.Context) {
 			ctx.RenderWithErr(err.Error(), tplAuthEdit, form)
 			return
 		}
+	case auth.SAML:
+		existing, _ := source.Cfg.(*saml.Source)
+		config, err = parseSAMLConfig(ctx, form, existing)
+		if err != nil {
+			ctx.RenderWithErr(err.Error(), tplAuthEdit, form)
+			return
+		}
 	default:
 		ctx.Error(http.StatusBadRequest)
 		return
@@ -463,3 +556,45 @@ func DeleteAuthSource(ctx *context.Context) {
 	ctx.Flash.Success(ctx.Tr("admin.auths.deletion_success"))
 	ctx.JSONRedirect(setting.AppSubURL + "/admin/auths")
 }
+
+// GenerateSCIMToken creates the token of the SCIM provisioning endpoint of a SAML source, replacing an existing one
+func GenerateSCIMToken(ctx *context.Context) {
+	source, err := auth.GetSourceByID(ctx, ctx.ParamsInt64(":authid"))
+	if err != nil {
+		ctx.ServerError("auth.GetSourceByID", err)
+		return
+	}
+	if !source.IsSAML() {
+		ctx.NotFound("GenerateSCIMToken", nil)
+		return
+	}
+
+	token, err := auth.GenerateSCIMToken(ctx, source.ID)
+	if err != nil {
+		ctx.ServerError("auth.GenerateSCIMToken", err)
+		return
+	}
+	log.Trace("SCIM token of authentication %d generated by admin(%s)", source.ID, ctx.Doer.Name)
+
+	ctx.Flash.Success(ctx.Tr("admin.auths.scim_token_generated"))
+	ctx.Flash.Info(token)
+	ctx.Redirect(setting.AppSubURL + "/admin/auths/" + strconv.FormatInt(source.ID, 10))
+}
+
+// DeleteSCIMToken revokes the token of the SCIM provisioning endpoint of a source
+func DeleteSCIMToken(ctx *context.Context) {
+	source, err := auth.GetSourceByID(ctx, ctx.ParamsInt64(":authid"))
+	if err != nil {
+		ctx.ServerError("auth.GetSourceByID", err)
+		return
+	}
+
+	if err := auth.DeleteSCIMToken(ctx, source.ID); err != nil {
+		ctx.ServerError("auth.DeleteSCIMToken", err)
+		return
+	}
+	log.Trace("SCIM token of authentication %d revoked by admin(%s)", source.ID, ctx.Doer.Name)
+
+	ctx.Flash.Success(ctx.Tr("admin.auths.scim_token_revoked"))
+	ctx.Redirect(setting.AppSubURL + "/admin/auths/" + strconv.FormatInt(source.ID, 10))
+}
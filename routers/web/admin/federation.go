@@ -0,0 +1,112 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/forgefed"
+	"code.gitea.io/gitea/modules/base"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/web"
+	"code.gitea.io/gitea/services/context"
+	"code.gitea.io/gitea/services/federation"
+	"code.gitea.io/gitea/services/forms"
+	user_service "code.gitea.io/gitea/services/user"
+)
+
+const tplFederation base.TplName = "admin/federation"
+
+// federationHost is a known federation host with the action of the rules applying to it
+type federationHost struct {
+	*forgefed.FederationHost
+	Action forgefed.FederationHostAction
+}
+
+// Federation shows the federation host rules and the known federation hosts
+func Federation(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("admin.federation")
+	ctx.Data["PageIsAdminFederation"] = true
+	ctx.Data["AllowlistMode"] = setting.Federation.AllowlistMode
+
+	rules, err := forgefed.FindFederationHostRules(ctx)
+	if err != nil {
+		ctx.ServerError("FindFederationHostRules", err)
+		return
+	}
+	ctx.Data["Rules"] = rules
+
+	page := ctx.FormInt("page")
+	if page <= 1 {
+		page = 1
+	}
+	hosts, count, err := forgefed.FindFederationHosts(ctx, db.ListOptions{Page: page, PageSize: setting.UI.Admin.UserPagingNum})
+	if err != nil {
+		ctx.ServerError("FindFederationHosts", err)
+		return
+	}
+	federationHosts := make([]federationHost, len(hosts))
+	for i, host := range hosts {
+		federationHosts[i] = federationHost{FederationHost: host, Action: federation.MatchHostAction(rules, host.HostFqdn)}
+	}
+	ctx.Data["Hosts"] = federationHosts
+	ctx.Data["Total"] = count
+	ctx.Data["Page"] = context.NewPagination(int(count), setting.UI.Admin.UserPagingNum, page, 5)
+
+	ctx.HTML(http.StatusOK, tplFederation)
+}
+
+// FederationHostRulePost blocks, silences or allows a federation host
+func FederationHostRulePost(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.AdminFederationHostRuleForm)
+	if ctx.HasError() {
+		ctx.Flash.Error(ctx.GetErrMsg())
+		ctx.Redirect(setting.AppSubURL + "/admin/federation")
+		return
+	}
+
+	action, _ := forgefed.ParseFederationHostAction(form.Action)
+	rule := &forgefed.FederationHostRule{
+		Pattern: form.Pattern,
+		Action:  action,
+		Reason:  form.Reason,
+	}
+	if err := federation.SetHostRule(ctx, ctx.Doer, rule); err != nil {
+		if errors.As(err, &forgefed.ErrInvalidFederationHostPattern{}) {
+			ctx.Flash.Error(ctx.Tr("admin.federation.invalid_pattern", form.Pattern))
+			ctx.Redirect(setting.AppSubURL + "/admin/federation")
+			return
+		}
+		ctx.ServerError("SetHostRule", err)
+		return
+	}
+	if rule.Action == forgefed.FederationHostActionBlock {
+		if err := user_service.PurgeFederationHostUsers(ctx, rule); err != nil {
+			ctx.ServerError("PurgeFederationHostUsers", err)
+			return
+		}
+	}
+
+	ctx.Flash.Success(ctx.Tr("admin.federation.rule_saved", rule.Pattern))
+	ctx.Redirect(setting.AppSubURL + "/admin/federation")
+}
+
+// DeleteFederationHostRule deletes the rule of a federation host
+func DeleteFederationHostRule(ctx *context.Context) {
+	pattern := ctx.FormString("pattern")
+	if err := federation.DeleteHostRule(ctx, ctx.Doer, pattern); err != nil {
+		if errors.Is(err, util.ErrNotExist) || errors.Is(err, util.ErrInvalidArgument) {
+			ctx.NotFound("DeleteHostRule", err)
+			return
+		}
+		ctx.ServerError("DeleteHostRule", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("admin.federation.rule_deleted", pattern))
+	ctx.Redirect(setting.AppSubURL + "/admin/federation")
+}
@@ -12,6 +12,7 @@ import (
 	"code.gitea.io/gitea/modules/base"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/web"
+	auth_service "code.gitea.io/gitea/services/auth"
 	"code.gitea.io/gitea/services/context"
 	"code.gitea.io/gitea/services/externalaccount"
 	"code.gitea.io/gitea/services/forms"
@@ -52,6 +53,30 @@ func TwoFactorPost(ctx *context.Context) {
 	}
 
 	id := idSess.(int64)
+	u, err := user_model.GetUserByID(ctx, id)
+	if err != nil {
+		ctx.ServerError("UserSignIn", err)
+		return
+	}
+
+	// TOTP codes can be phished, users who are required to use a security key have to sign in with it
+	webAuthnRequired, err := auth_service.IsWebAuthnRequired(ctx, u)
+	if err != nil {
+		ctx.ServerError("IsWebAuthnRequired", err)
+		return
+	}
+	if webAuthnRequired {
+		hasWebAuthn, err := auth.HasWebAuthnRegistrationsByUID(ctx, u.ID)
+		if err != nil {
+			ctx.ServerError("HasWebAuthnRegistrationsByUID", err)
+			return
+		}
+		if hasWebAuthn {
+			ctx.RenderWithErr(ctx.Tr("auth.twofa_totp_not_allowed"), tplTwofa, forms.TwoFactorAuthForm{})
+			return
+		}
+	}
+
 	twofa, err := auth.GetTwoFactorByUID(ctx, id)
 	if err != nil {
 		ctx.ServerError("UserSignIn", err)
@@ -67,11 +92,6 @@ func TwoFactorPost(ctx *context.Context) {
 
 	if ok && twofa.LastUsedPasscode != form.Passcode {
 		remember := ctx.Session.Get("twofaRemember").(bool)
-		u, err := user_model.GetUserByID(ctx, id)
-		if err != nil {
-			ctx.ServerError("UserSignIn", err)
-			return
-		}
 
 		if ctx.Session.Get("linkAccount") != nil {
 			err = externalaccount.LinkAccountFromStore(ctx, ctx.Session, u)
@@ -100,10 +100,7 @@ func autoSignIn(ctx *context.Context) (bool, error) {
 
 	isSucceed = true
 
-	if err := updateSession(ctx, nil, map[string]any{
-		// Set session IDs
-		"uid": u.ID,
-	}); err != nil {
+	if err := updateSignedInSession(ctx, nil, u); err != nil {
 		return false, fmt.Errorf("unable to updateSession: %w", err)
 	}
 
@@ -188,6 +185,15 @@ func SignIn(ctx *context.Context) {
 		return
 	}
 	ctx.Data["OAuth2Providers"] = oauth2Providers
+	samlSources, err := db.Find[auth.Source](ctx, auth.FindSourcesOptions{
+		IsActive:  optional.Some(true),
+		LoginType: auth.SAML,
+	})
+	if err != nil {
+		ctx.ServerError("UserSignIn", err)
+		return
+	}
+	ctx.Data["SAMLSources"] = samlSources
 	ctx.Data["Title"] = ctx.Tr("sign_in")
 	ctx.Data["SignInLink"] = setting.AppSubURL + "/user/login"
 	ctx.Data["PageIsSignIn"] = true
@@ -211,6 +217,15 @@ func SignInPost(ctx *context.Context) {
 		return
 	}
 	ctx.Data["OAuth2Providers"] = oauth2Providers
+	samlSources, err := db.Find[auth.Source](ctx, auth.FindSourcesOptions{
+		IsActive:  optional.Some(true),
+		LoginType: auth.SAML,
+	})
+	if err != nil {
+		ctx.ServerError("UserSignIn", err)
+		return
+	}
+	ctx.Data["SAMLSources"] = samlSources
 	ctx.Data["Title"] = ctx.Tr("sign_in")
 	ctx.Data["SignInLink"] = setting.AppSubURL + "/user/login"
 	ctx.Data["PageIsSignIn"] = true
@@ -330,7 +345,7 @@ func handleSignInFull(ctx *context.Context, u *user_model.User, remember, obeyRe
 		}
 	}
 
-	if err := updateSession(ctx, []string{
+	if err := updateSignedInSession(ctx, []string{
 		// Delete the openid, 2fa and linkaccount data
 		"openid_verified_uri",
 		"openid_signin_remember",
@@ -339,9 +354,7 @@ func handleSignInFull(ctx *context.Context, u *user_model.User, remember, obeyRe
 		"twofaUid",
 		"twofaRemember",
 		"linkAccount",
-	}, map[string]any{
-		"uid": u.ID,
-	}); err != nil {
+	}, u); err != nil {
 		ctx.ServerError("RegenerateSession", err)
 		return setting.AppSubURL + "/"
 	}
@@ -399,6 +412,9 @@ func getUserName(gothUser *goth.User) (string, error) {
 
 // HandleSignOut resets the session and sets the cookies
 func HandleSignOut(ctx *context.Context) {
+	if err := auth_service.RevokeCurrentUserSession(ctx, ctx.Session); err != nil {
+		log.Error("RevokeCurrentUserSession: %v", err)
+	}
 	_ = ctx.Session.Flush()
 	_ = ctx.Session.Destroy(ctx.Resp, ctx.Req)
 	ctx.DeleteSiteCookie(setting.CookieRememberName)
@@ -802,9 +818,7 @@ func handleAccountActivation(ctx *context.Context, user *user_model.User) {
 
 	log.Trace("User activated: %s", user.Name)
 
-	if err := updateSession(ctx, nil, map[string]any{
-		"uid": user.ID,
-	}); err != nil {
+	if err := updateSignedInSession(ctx, nil, user); err != nil {
 		log.Error("Unable to regenerate session for user: %-v with email: %s: %v", user, user.Email, err)
 		ctx.ServerError("ActivateUserEmail", err)
 		return
@@ -880,3 +894,15 @@ func updateSession(ctx *context.Context, deletes []string, updates map[string]an
 	}
 	return nil
 }
+
+// updateSignedInSession regenerates the session and signs the user in, the session is tracked as a new user session
+func updateSignedInSession(ctx *context.Context, deletes []string, u *user_model.User) error {
+	userSessionID, err := auth_service.NewUserSession(ctx.Req, u)
+	if err != nil {
+		return fmt.Errorf("track user session: %w", err)
+	}
+	return updateSession(ctx, deletes, map[string]any{
+		"uid":                       u.ID,
+		auth_service.UserSessionKey: userSessionID,
+	})
+}
@@ -695,39 +695,48 @@ func OIDCKeys(ctx *context.Context) {
 	}
 }
 
+// parseClientCredentials fills the client credentials by the Authorization header if there is no ClientID or ClientSecret in the request body,
+// and ensures the provided fields match the Authorization header
+func parseClientCredentials(ctx *context.Context, clientID, clientSecret *string) *AccessTokenError {
+	if *clientID != "" && *clientSecret != "" {
+		return nil
+	}
+	authHeader := ctx.Req.Header.Get("Authorization")
+	authType, authData, ok := strings.Cut(authHeader, " ")
+	if !ok || !strings.EqualFold(authType, "Basic") {
+		return nil
+	}
+	headerClientID, headerClientSecret, err := base.BasicAuthDecode(authData)
+	if err != nil {
+		return &AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidRequest,
+			ErrorDescription: "cannot parse basic auth header",
+		}
+	}
+	// validate that any fields present in the form match the Basic auth header
+	if *clientID != "" && *clientID != headerClientID {
+		return &AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidRequest,
+			ErrorDescription: "client_id in request body inconsistent with Authorization header",
+		}
+	}
+	*clientID = headerClientID
+	if *clientSecret != "" && *clientSecret != headerClientSecret {
+		return &AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidRequest,
+			ErrorDescription: "client_secret in request body inconsistent with Authorization header",
+		}
+	}
+	*clientSecret = headerClientSecret
+	return nil
+}
+
 // AccessTokenOAuth manages all access token requests by the client
 func AccessTokenOAuth(ctx *context.Context) {
 	form := *web.GetForm(ctx).(*forms.AccessTokenForm)
-	// if there is no ClientID or ClientSecret in the request body, fill these fields by the Authorization header and ensure the provided field matches the Authorization header
-	if form.ClientID == "" || form.ClientSecret == "" {
-		authHeader := ctx.Req.Header.Get("Authorization")
-		if authType, authData, ok := strings.Cut(authHeader, " "); ok && strings.EqualFold(authType, "Basic") {
-			clientID, clientSecret, err := base.BasicAuthDecode(authData)
-			if err != nil {
-				handleAccessTokenError(ctx, AccessTokenError{
-					ErrorCode:        AccessTokenErrorCodeInvalidRequest,
-					ErrorDescription: "cannot parse basic auth header",
-				})
-				return
-			}
-			// validate that any fields present in the form match the Basic auth header
-			if form.ClientID != "" && form.ClientID != clientID {
-				handleAccessTokenError(ctx, AccessTokenError{
-					ErrorCode:        AccessTokenErrorCodeInvalidRequest,
-					ErrorDescription: "client_id in request body inconsistent with Authorization header",
-				})
-				return
-			}
-			form.ClientID = clientID
-			if form.ClientSecret != "" && form.ClientSecret != clientSecret {
-				handleAccessTokenError(ctx, AccessTokenError{
-					ErrorCode:        AccessTokenErrorCodeInvalidRequest,
-					ErrorDescription: "client_secret in request body inconsistent with Authorization header",
-				})
-				return
-			}
-			form.ClientSecret = clientSecret
-		}
+	if acErr := parseClientCredentials(ctx, &form.ClientID, &form.ClientSecret); acErr != nil {
+		handleAccessTokenError(ctx, *acErr)
+		return
 	}
 
 	serverKey := oauth2.DefaultSigningKey
@@ -749,10 +758,12 @@ func AccessTokenOAuth(ctx *context.Context) {
 		handleRefreshToken(ctx, form, serverKey, clientKey)
 	case "authorization_code":
 		handleAuthorizationCode(ctx, form, serverKey, clientKey)
+	case GrantTypeDeviceCode:
+		handleDeviceCode(ctx, form, serverKey, clientKey)
 	default:
 		handleAccessTokenError(ctx, AccessTokenError{
 			ErrorCode:        AccessTokenErrorCodeUnsupportedGrantType,
-			ErrorDescription: "Only refresh_token or authorization_code grant type is supported",
+			ErrorDescription: "Only refresh_token, authorization_code or device_code grant type is supported",
 		})
 	}
 }
@@ -1236,9 +1247,7 @@ func handleOAuth2SignIn(ctx *context.Context, source *auth.Source, u *user_model
 			return
 		}
 
-		if err := updateSession(ctx, nil, map[string]any{
-			"uid": u.ID,
-		}); err != nil {
+		if err := updateSignedInSession(ctx, nil, u); err != nil {
 			ctx.ServerError("updateSession", err)
 			return
 		}
@@ -0,0 +1,284 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"code.gitea.io/gitea/models/auth"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/base"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/web"
+	"code.gitea.io/gitea/services/auth/source/oauth2"
+	"code.gitea.io/gitea/services/context"
+	"code.gitea.io/gitea/services/forms"
+)
+
+const tplDeviceAuthorize base.TplName = "user/auth/device"
+
+// GrantTypeDeviceCode is the grant type of the device authorization grant specified in RFC 8628
+// https://datatracker.ietf.org/doc/html/rfc8628#section-3.4
+const GrantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+
+const (
+	// AccessTokenErrorCodeAuthorizationPending represents an error code specified in RFC 8628
+	AccessTokenErrorCodeAuthorizationPending AccessTokenErrorCode = "authorization_pending"
+	// AccessTokenErrorCodeSlowDown represents an error code specified in RFC 8628
+	AccessTokenErrorCodeSlowDown AccessTokenErrorCode = "slow_down"
+	// AccessTokenErrorCodeAccessDenied represents an error code specified in RFC 8628
+	AccessTokenErrorCodeAccessDenied AccessTokenErrorCode = "access_denied"
+	// AccessTokenErrorCodeExpiredToken represents an error code specified in RFC 8628
+	AccessTokenErrorCodeExpiredToken AccessTokenErrorCode = "expired_token"
+)
+
+// DeviceAuthorizationResponse represents a successful device authorization response
+// https://datatracker.ietf.org/doc/html/rfc8628#section-3.2
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+// loadOAuth2ClientApplication loads the application of the client and authenticates confidential clients
+func loadOAuth2ClientApplication(ctx *context.Context, clientID, clientSecret string) (*auth.OAuth2Application, *AccessTokenError) {
+	app, err := auth.GetOAuth2ApplicationByClientID(ctx, clientID)
+	if err != nil {
+		return nil, &AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidClient,
+			ErrorDescription: fmt.Sprintf("cannot load client with client id: %q", clientID),
+		}
+	}
+	if app.ConfidentialClient && !app.ValidateClientSecret([]byte(clientSecret)) {
+		errorDescription := "invalid client secret"
+		if clientSecret == "" {
+			errorDescription = "invalid empty client secret"
+		}
+		return nil, &AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidClient,
+			ErrorDescription: errorDescription,
+		}
+	}
+	return app, nil
+}
+
+// DeviceAuthorizationOAuth issues a device code and a user code to a client which can't open a browser (RFC 8628)
+func DeviceAuthorizationOAuth(ctx *context.Context) {
+	form := *web.GetForm(ctx).(*forms.DeviceAuthorizationForm)
+	if acErr := parseClientCredentials(ctx, &form.ClientID, &form.ClientSecret); acErr != nil {
+		handleAccessTokenError(ctx, *acErr)
+		return
+	}
+	app, acErr := loadOAuth2ClientApplication(ctx, form.ClientID, form.ClientSecret)
+	if acErr != nil {
+		handleAccessTokenError(ctx, *acErr)
+		return
+	}
+
+	code, err := app.CreateDeviceCode(ctx, form.Scope)
+	if err != nil {
+		ctx.ServerError("CreateDeviceCode", err)
+		return
+	}
+
+	verificationURI := setting.AppURL + "login/oauth/device"
+	ctx.JSON(http.StatusOK, &DeviceAuthorizationResponse{
+		DeviceCode:              code.DeviceCode,
+		UserCode:                code.FormattedUserCode(),
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?user_code=" + url.QueryEscape(code.FormattedUserCode()),
+		ExpiresIn:               setting.OAuth2.DeviceCodeExpirationTime,
+		Interval:                code.Interval,
+	})
+}
+
+// loadPendingDeviceCode loads the device code which the user entered, it renders the verification page with an error if the code can't be used
+func loadPendingDeviceCode(ctx *context.Context, userCode string) *auth.OAuth2DeviceCode {
+	code, err := auth.GetOAuth2DeviceCodeByUserCode(ctx, userCode)
+	if err != nil {
+		if auth.IsErrOAuth2DeviceCodeNotExist(err) {
+			ctx.Data["Err_UserCode"] = true
+			ctx.RenderWithErr(ctx.Tr("auth.device_code_invalid"), tplDeviceAuthorize, nil)
+			return nil
+		}
+		ctx.ServerError("GetOAuth2DeviceCodeByUserCode", err)
+		return nil
+	}
+	if !code.IsPending() {
+		ctx.Data["Err_UserCode"] = true
+		ctx.RenderWithErr(ctx.Tr("auth.device_code_used"), tplDeviceAuthorize, nil)
+		return nil
+	}
+	if err := code.LoadApplication(ctx); err != nil {
+		ctx.ServerError("LoadApplication", err)
+		return nil
+	}
+	return code
+}
+
+// DeviceVerificationOAuth shows the page where users enter the user code of a device and authorize it
+func DeviceVerificationOAuth(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("auth.device_title")
+	userCode := ctx.FormString("user_code")
+	ctx.Data["user_code"] = userCode
+	if userCode == "" {
+		ctx.HTML(http.StatusOK, tplDeviceAuthorize)
+		return
+	}
+
+	code := loadPendingDeviceCode(ctx, userCode)
+	if code == nil {
+		return
+	}
+
+	ctx.Data["DeviceCode"] = code
+	ctx.Data["Application"] = code.Application
+	if code.Application.UID != 0 {
+		user, err := user_model.GetUserByID(ctx, code.Application.UID)
+		if err != nil {
+			ctx.ServerError("GetUserByID", err)
+			return
+		}
+		ctx.Data["ApplicationCreatorLinkHTML"] = template.HTML(fmt.Sprintf(`<a href="%s">@%s</a>`, html.EscapeString(user.HomeLink()), html.EscapeString(user.Name)))
+	} else {
+		ctx.Data["ApplicationCreatorLinkHTML"] = template.HTML(fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(setting.AppSubURL+"/"), html.EscapeString(setting.AppName)))
+	}
+	ctx.HTML(http.StatusOK, tplDeviceAuthorize)
+}
+
+// GrantDeviceOAuth manages the post request submitted when a user authorizes or denies a device
+func GrantDeviceOAuth(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.GrantDeviceForm)
+	ctx.Data["Title"] = ctx.Tr("auth.device_title")
+	if ctx.HasError() {
+		ctx.HTML(http.StatusOK, tplDeviceAuthorize)
+		return
+	}
+
+	code := loadPendingDeviceCode(ctx, form.UserCode)
+	if code == nil {
+		return
+	}
+
+	if !form.Granted {
+		if err := code.Deny(ctx); err != nil {
+			ctx.ServerError("Deny", err)
+			return
+		}
+		ctx.Data["DeviceDone"] = true
+		ctx.Flash.Info(ctx.Tr("auth.device_denied"), true)
+		ctx.HTML(http.StatusOK, tplDeviceAuthorize)
+		return
+	}
+
+	grant, err := code.Application.GetGrantByUserID(ctx, ctx.Doer.ID)
+	if err != nil {
+		ctx.ServerError("GetGrantByUserID", err)
+		return
+	}
+	if grant == nil {
+		grant, err = code.Application.CreateGrant(ctx, ctx.Doer.ID, code.Scope)
+		if err != nil {
+			ctx.ServerError("CreateGrant", err)
+			return
+		}
+	} else if grant.Scope != code.Scope {
+		ctx.RenderWithErr(ctx.Tr("auth.device_grant_scope_mismatch"), tplDeviceAuthorize, nil)
+		return
+	}
+
+	if err := code.Approve(ctx, grant.ID); err != nil {
+		ctx.ServerError("Approve", err)
+		return
+	}
+	ctx.Data["DeviceDone"] = true
+	ctx.Flash.Success(ctx.Tr("auth.device_authorized"), true)
+	ctx.HTML(http.StatusOK, tplDeviceAuthorize)
+}
+
+func handleDeviceCode(ctx *context.Context, form forms.AccessTokenForm, serverKey, clientKey oauth2.JWTSigningKey) {
+	app, acErr := loadOAuth2ClientApplication(ctx, form.ClientID, form.ClientSecret)
+	if acErr != nil {
+		handleAccessTokenError(ctx, *acErr)
+		return
+	}
+
+	code, err := auth.GetOAuth2DeviceCodeByDeviceCode(ctx, form.DeviceCode)
+	if err != nil || code.ApplicationID != app.ID {
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidGrant,
+			ErrorDescription: "invalid device code",
+		})
+		return
+	}
+
+	switch {
+	case code.IsExpired():
+		if err := code.Invalidate(ctx); err != nil {
+			ctx.ServerError("Invalidate", err)
+			return
+		}
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeExpiredToken,
+			ErrorDescription: "the device code has expired",
+		})
+		return
+	case code.Denied:
+		if err := code.Invalidate(ctx); err != nil {
+			ctx.ServerError("Invalidate", err)
+			return
+		}
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeAccessDenied,
+			ErrorDescription: "the authorization request was denied",
+		})
+		return
+	case code.IsPending():
+		slowDown, err := code.Poll(ctx)
+		if err != nil {
+			ctx.ServerError("Poll", err)
+			return
+		}
+		if slowDown {
+			handleAccessTokenError(ctx, AccessTokenError{
+				ErrorCode:        AccessTokenErrorCodeSlowDown,
+				ErrorDescription: fmt.Sprintf("polling too fast, the interval is increased to %d seconds", code.Interval),
+			})
+			return
+		}
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeAuthorizationPending,
+			ErrorDescription: "the authorization request is still pending",
+		})
+		return
+	}
+
+	// remove device code from database to deny duplicate usage
+	if err := code.Invalidate(ctx); err != nil {
+		ctx.ServerError("Invalidate", err)
+		return
+	}
+	grant, err := auth.GetOAuth2GrantByID(ctx, code.GrantID)
+	if err != nil || grant == nil {
+		// the grant was revoked before the device obtained its token
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeAccessDenied,
+			ErrorDescription: "the grant does not exist",
+		})
+		return
+	}
+	resp, tokenErr := newAccessTokenResponse(ctx, grant, serverKey, clientKey)
+	if tokenErr != nil {
+		handleAccessTokenError(ctx, *tokenErr)
+		return
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -18,6 +18,7 @@ import (
 	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/web"
 	"code.gitea.io/gitea/modules/web/middleware"
+	auth_service "code.gitea.io/gitea/services/auth"
 	"code.gitea.io/gitea/services/context"
 	"code.gitea.io/gitea/services/forms"
 	"code.gitea.io/gitea/services/mailer"
@@ -305,6 +306,12 @@ func MustChangePasswordPost(ctx *context.Context) {
 		return
 	}
 
+	// Keep the current session signed in, the other ones were revoked
+	if err := auth_service.StartUserSession(ctx.Req, ctx.Session, ctx.Doer); err != nil {
+		ctx.ServerError("StartUserSession", err)
+		return
+	}
+
 	ctx.Flash.Success(ctx.Tr("settings.change_password_success"))
 
 	log.Trace("User updated password: %s", ctx.Doer.Name)
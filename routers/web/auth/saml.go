@@ -0,0 +1,230 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"fmt"
+	"net/http"
+
+	"code.gitea.io/gitea/models/auth"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/eventsource"
+	"code.gitea.io/gitea/modules/httplib"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/web/middleware"
+	"code.gitea.io/gitea/services/auth/source/saml"
+	"code.gitea.io/gitea/services/context"
+	user_service "code.gitea.io/gitea/services/user"
+)
+
+// getActiveSAMLSource returns the active SAML source of the provider parameter, or writes a not found response
+func getActiveSAMLSource(ctx *context.Context) (*auth.Source, *saml.Source) {
+	authSource, err := auth.GetSourceByName(ctx, ctx.Params(":provider"))
+	if err != nil {
+		if auth.IsErrSourceNotExist(err) {
+			ctx.NotFound("GetSourceByName", err)
+		} else {
+			ctx.ServerError("GetSourceByName", err)
+		}
+		return nil, nil
+	}
+	if !authSource.IsActive || !authSource.IsSAML() {
+		ctx.NotFound("GetSourceByName", fmt.Errorf("saml source not found, name: %q", authSource.Name))
+		return nil, nil
+	}
+	return authSource, authSource.Cfg.(*saml.Source)
+}
+
+// SignInSAML redirects the user to the identity provider with an authentication request
+func SignInSAML(ctx *context.Context) {
+	_, source := getActiveSAMLSource(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	sp, err := source.ServiceProvider()
+	if err != nil {
+		ctx.ServerError("ServiceProvider", err)
+		return
+	}
+	redirectURL, requestID, err := sp.AuthnRequestURL("")
+	if err != nil {
+		ctx.ServerError("AuthnRequestURL", err)
+		return
+	}
+
+	redirectTo := ctx.FormString("redirect_to")
+	if redirectTo == "" || httplib.IsRiskyRedirectURL(redirectTo) {
+		redirectTo = ctx.GetSiteCookie("redirect_to")
+	}
+	if err := source.StorePendingRequest(requestID, redirectTo); err != nil {
+		ctx.ServerError("StorePendingRequest", err)
+		return
+	}
+
+	ctx.Redirect(redirectURL)
+}
+
+// SAMLAssertionConsumerService handles the response of the identity provider and signs the user in
+func SAMLAssertionConsumerService(ctx *context.Context) {
+	authSource, source := getActiveSAMLSource(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	sp, err := source.ServiceProvider()
+	if err != nil {
+		ctx.ServerError("ServiceProvider", err)
+		return
+	}
+
+	var redirectTo string
+	assertion, err := sp.ParseResponse(ctx.Req.PostFormValue("SAMLResponse"), func(requestID string) bool {
+		var ok bool
+		redirectTo, ok = source.ConsumePendingRequest(requestID)
+		return ok
+	})
+	if err != nil {
+		log.Warn("Failed SAML sign in via %s from %s: %v", authSource.Name, ctx.RemoteAddr(), err)
+		ctx.Flash.Error(ctx.Tr("auth.saml.signin.error"))
+		ctx.Redirect(setting.AppSubURL + "/user/login")
+		return
+	}
+
+	u, err := source.Authenticate(ctx, assertion)
+	if err != nil {
+		switch {
+		case user_model.IsErrUserProhibitLogin(err), user_model.IsErrUserInactive(err):
+			log.Info("Failed authentication attempt for %s from %s: %v", assertion.NameID, ctx.RemoteAddr(), err)
+			ctx.Data["Title"] = ctx.Tr("auth.prohibit_login")
+			ctx.HTML(http.StatusOK, "user/auth/prohibit_login")
+		case user_model.IsErrUserAlreadyExist(err), user_model.IsErrEmailAlreadyUsed(err):
+			log.Warn("SAML sign in of %s via %s conflicts with an existing account: %v", assertion.NameID, authSource.Name, err)
+			ctx.Flash.Error(ctx.Tr("auth.saml.signin.account_conflict"))
+			ctx.Redirect(setting.AppSubURL + "/user/login")
+		default:
+			ctx.ServerError("Authenticate", err)
+		}
+		return
+	}
+
+	needs2FA := false
+	if !source.SkipLocalTwoFA {
+		_, err := auth.GetTwoFactorByUID(ctx, u.ID)
+		if err != nil && !auth.IsErrTwoFactorNotEnrolled(err) {
+			ctx.ServerError("UserSignIn", err)
+			return
+		}
+		needs2FA = err == nil
+	}
+
+	if needs2FA {
+		if err := updateSession(ctx, nil, map[string]any{
+			// User needs to use 2FA, save data and redirect to 2FA page.
+			"twofaUid":      u.ID,
+			"twofaRemember": false,
+		}); err != nil {
+			ctx.ServerError("updateSession", err)
+			return
+		}
+		if redirectTo != "" {
+			middleware.SetRedirectToCookie(ctx.Resp, redirectTo)
+		}
+
+		// If WebAuthn is enrolled -> Redirect to WebAuthn instead
+		regs, err := auth.GetWebAuthnCredentialsByUID(ctx, u.ID)
+		if err == nil && len(regs) > 0 {
+			ctx.Redirect(setting.AppSubURL + "/user/webauthn")
+			return
+		}
+		ctx.Redirect(setting.AppSubURL + "/user/two_factor")
+		return
+	}
+
+	if err := user_service.UpdateUser(ctx, u, &user_service.UpdateOptions{SetLastLogin: true}); err != nil {
+		ctx.ServerError("UpdateUser", err)
+		return
+	}
+
+	if err := updateSignedInSession(ctx, nil, u); err != nil {
+		ctx.ServerError("updateSession", err)
+		return
+	}
+
+	// Clear whatever CSRF cookie has right now, force to generate a new one
+	ctx.Csrf.DeleteCookie(ctx)
+
+	if err := resetLocale(ctx, u); err != nil {
+		ctx.ServerError("resetLocale", err)
+		return
+	}
+
+	middleware.DeleteRedirectToCookie(ctx.Resp)
+	ctx.RedirectToFirst(redirectTo)
+}
+
+// SAMLMetadata returns the service provider metadata to configure the identity provider
+func SAMLMetadata(ctx *context.Context) {
+	_, source := getActiveSAMLSource(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	sp, err := source.ServiceProvider()
+	if err != nil {
+		ctx.ServerError("ServiceProvider", err)
+		return
+	}
+	metadata, err := sp.Metadata()
+	if err != nil {
+		ctx.ServerError("Metadata", err)
+		return
+	}
+
+	ctx.Resp.Header().Set("Content-Type", "application/samlmetadata+xml")
+	ctx.Resp.WriteHeader(http.StatusOK)
+	_, _ = ctx.Resp.Write(metadata)
+}
+
+// SAMLSingleLogout handles logout requests of the identity provider.
+// The session is ended if it belongs to the user of the request, then the user is sent back to the identity provider.
+func SAMLSingleLogout(ctx *context.Context) {
+	authSource, source := getActiveSAMLSource(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	sp, err := source.ServiceProvider()
+	if err != nil {
+		ctx.ServerError("ServiceProvider", err)
+		return
+	}
+
+	logoutRequest, err := sp.ParseLogoutRequest(ctx.Req.URL.RawQuery)
+	if err != nil {
+		log.Warn("Invalid SAML logout request via %s from %s: %v", authSource.Name, ctx.RemoteAddr(), err)
+		ctx.Error(http.StatusBadRequest, "invalid logout request")
+		return
+	}
+
+	if ctx.Doer != nil && ctx.Doer.LoginType == auth.SAML && ctx.Doer.LoginSource == authSource.ID && ctx.Doer.LoginName == logoutRequest.NameID {
+		eventsource.GetManager().SendMessage(ctx.Doer.ID, &eventsource.Event{
+			Name: "logout",
+			Data: ctx.Session.ID(),
+		})
+		HandleSignOut(ctx)
+	}
+
+	if source.IdPSLOURL == "" {
+		ctx.Redirect(setting.AppSubURL + "/")
+		return
+	}
+	redirectURL, err := sp.LogoutResponseURL(logoutRequest, ctx.FormString("RelayState"))
+	if err != nil {
+		ctx.ServerError("LogoutResponseURL", err)
+		return
+	}
+	ctx.Redirect(redirectURL)
+}
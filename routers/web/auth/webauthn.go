@@ -4,7 +4,9 @@
 package auth
 
 import (
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"net/http"
 
 	"code.gitea.io/gitea/models/auth"
@@ -175,3 +177,92 @@ func WebAuthnLoginAssertionPost(ctx *context.Context) {
 
 	ctx.JSONRedirect(redirect)
 }
+
+// WebAuthnPasskeyAssertion submits a WebAuthn challenge for a passkey sign-in to the browser,
+// the browser lets the user choose one of the discoverable credentials stored for this instance
+func WebAuthnPasskeyAssertion(ctx *context.Context) {
+	assertion, sessionData, err := wa.WebAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		ctx.ServerError("webauthn.BeginDiscoverableLogin", err)
+		return
+	}
+
+	if err := ctx.Session.Set("webauthnPasskeyAssertion", sessionData); err != nil {
+		ctx.ServerError("Session.Set", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, assertion)
+}
+
+// WebAuthnPasskeyAssertionPost validates the signature of a passkey and logs its owner in without a password
+func WebAuthnPasskeyAssertionPost(ctx *context.Context) {
+	sessionData, ok := ctx.Session.Get("webauthnPasskeyAssertion").(*webauthn.SessionData)
+	if !ok || sessionData == nil {
+		ctx.ServerError("UserSignIn", errors.New("not in WebAuthn session"))
+		return
+	}
+	defer func() {
+		_ = ctx.Session.Delete("webauthnPasskeyAssertion")
+	}()
+
+	parsedResponse, err := protocol.ParseCredentialRequestResponse(ctx.Req)
+	if err != nil {
+		log.Info("Failed passkey authentication attempt from %s: %v", ctx.RemoteAddr(), err)
+		ctx.Status(http.StatusForbidden)
+		return
+	}
+
+	var user *user_model.User
+	var dbCred *auth.WebAuthnCredential
+	// The user handle is the WebAuthn ID of the user which was stored on the authenticator during the registration
+	cred, err := wa.WebAuthn.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		uid, n := binary.Varint(userHandle)
+		if n <= 0 {
+			return nil, errors.New("invalid user handle")
+		}
+		if user, err = user_model.GetUserByID(ctx, uid); err != nil {
+			return nil, err
+		}
+		if dbCred, err = auth.GetWebAuthnCredentialByCredID(ctx, user.ID, rawID); err != nil {
+			return nil, err
+		}
+		if !dbCred.Discoverable {
+			return nil, fmt.Errorf("credential %d is not a passkey", dbCred.ID)
+		}
+		return (*wa.User)(user), nil
+	}, *sessionData, parsedResponse)
+	if err != nil {
+		log.Info("Failed passkey authentication attempt from %s: %v", ctx.RemoteAddr(), err)
+		ctx.Status(http.StatusForbidden)
+		return
+	}
+
+	// Ensure that the credential wasn't cloned by checking if CloneWarning is set.
+	// (This is set if the sign counter is less than the one we have stored.)
+	if cred.Authenticator.CloneWarning {
+		log.Info("Failed authentication attempt for %s from %s: cloned credential", user.Name, ctx.RemoteAddr())
+		ctx.Status(http.StatusForbidden)
+		return
+	}
+
+	// The same restrictions as for a sign-in with a password apply
+	if user.ProhibitLogin || user.Type != user_model.UserTypeIndividual {
+		log.Info("Failed authentication attempt for %s from %s: login is prohibited", user.Name, ctx.RemoteAddr())
+		ctx.Status(http.StatusForbidden)
+		return
+	}
+
+	dbCred.SignCount = cred.Authenticator.SignCount
+	if err := dbCred.UpdateSignCount(ctx); err != nil {
+		ctx.ServerError("UpdateSignCount", err)
+		return
+	}
+
+	// The passkey verified the user, so it satisfies two-factor authentication on its own
+	redirect := handleSignInFull(ctx, user, ctx.FormBool("remember"), false)
+	if redirect == "" {
+		redirect = setting.AppSubURL + "/"
+	}
+
+	ctx.JSONRedirect(redirect)
+}
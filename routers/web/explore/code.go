@@ -76,6 +76,11 @@ func Code(ctx *context.Context) {
 			ctx.ServerError("FindUserCodeAccessibleRepoIDs", err)
 			return
 		}
+		repoIDs, err = context.LimitCodeSearchByIPAllowlist(ctx, ctx.Doer, repoIDs)
+		if err != nil {
+			ctx.ServerError("LimitCodeSearchByIPAllowlist", err)
+			return
+		}
 	}
 
 	var (
@@ -18,6 +18,7 @@ import (
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/markup"
 	"code.gitea.io/gitea/modules/markup/markdown"
+	"code.gitea.io/gitea/modules/repository"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/templates"
 	"code.gitea.io/gitea/modules/util"
@@ -47,6 +48,18 @@ func toSrcLink(ctx *context.Context, act *activities_model.Action) string {
 	return act.GetRepoAbsoluteLink(ctx) + "/src/" + util.PathEscapeSegments(act.GetBranch())
 }
 
+// toCommitLink returns the link to the pushed commit, it's empty for the commits of federated pushes
+// which didn't link to the repository they were pushed to
+func toCommitLink(ctx *context.Context, act *activities_model.Action, commit *repository.PushCommit) string {
+	if commit.URL != "" {
+		return commit.URL
+	}
+	if act.OpType == activities_model.ActionFederatedPush {
+		return ""
+	}
+	return act.GetRepoAbsoluteLink(ctx) + "/commit/" + commit.Sha1
+}
+
 func toReleaseLink(ctx *context.Context, act *activities_model.Action) string {
 	return act.GetRepoAbsoluteLink(ctx) + "/releases/tag/" + util.PathEscapeSegments(act.GetBranch())
 }
@@ -177,6 +190,9 @@ func feedActionsToFeedItems(ctx *context.Context, actions activities_model.Actio
 		case activities_model.ActionMirrorSyncDelete:
 			link.Href = act.GetRepoAbsoluteLink(ctx)
 			titleExtra = ctx.Locale.Tr("action.mirror_sync_delete", act.GetRepoAbsoluteLink(ctx), act.GetBranch(), act.ShortRepoPath(ctx))
+		case activities_model.ActionFederatedPush:
+			link.Href = act.GetRepoAbsoluteLink(ctx)
+			titleExtra = ctx.Locale.Tr("action.federated_push", act.GetRepoAbsoluteLink(ctx), html.EscapeString(act.GetBranch()), act.ShortRepoPath(ctx))
 		case activities_model.ActionApprovePullRequest:
 			pullLink := toPullLink(ctx, act)
 			titleExtra = ctx.Locale.Tr("action.approve_pull_request", pullLink, act.GetIssueInfos()[0], act.ShortRepoPath(ctx))
@@ -208,24 +224,30 @@ func feedActionsToFeedItems(ctx *context.Context, actions activities_model.Actio
 		// description & content
 		{
 			switch act.OpType {
-			case activities_model.ActionCommitRepo, activities_model.ActionMirrorSyncPush:
+			case activities_model.ActionCommitRepo, activities_model.ActionMirrorSyncPush, activities_model.ActionFederatedPush:
 				push := templates.ActionContent2Commits(act)
 
 				for _, commit := range push.Commits {
 					if len(desc) != 0 {
 						desc += "\n\n"
 					}
-					desc += fmt.Sprintf("<a href=\"%s\">%s</a>\n%s",
-						html.EscapeString(fmt.Sprintf("%s/commit/%s", act.GetRepoAbsoluteLink(ctx), commit.Sha1)),
-						commit.Sha1,
-						templates.RenderCommitMessage(ctx, commit.Message, nil),
-					)
+					if commitLink := toCommitLink(ctx, act, commit); commitLink != "" {
+						desc += fmt.Sprintf("<a href=\"%s\">%s</a>\n%s",
+							html.EscapeString(commitLink),
+							commit.Sha1,
+							templates.RenderCommitMessage(ctx, commit.Message, nil),
+						)
+					} else {
+						desc += fmt.Sprintf("%s\n%s", commit.Sha1, templates.RenderCommitMessage(ctx, commit.Message, nil))
+					}
 				}
 
-				if push.Len > 1 {
+				if push.Len > 1 && push.CompareURL != "" {
 					link = &feeds.Link{Href: fmt.Sprintf("%s/%s", setting.AppSubURL, push.CompareURL)}
 				} else if push.Len == 1 {
-					link = &feeds.Link{Href: fmt.Sprintf("%s/commit/%s", act.GetRepoAbsoluteLink(ctx), push.Commits[0].Sha1)}
+					if commitLink := toCommitLink(ctx, act, push.Commits[0]); commitLink != "" {
+						link = &feeds.Link{Href: commitLink}
+					}
 				}
 
 			case activities_model.ActionCreateIssue, activities_model.ActionCreatePullRequest:
@@ -0,0 +1,38 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package setting
+
+import (
+	"net/http"
+
+	"code.gitea.io/gitea/modules/base"
+	shared "code.gitea.io/gitea/routers/web/shared/audit"
+	shared_user "code.gitea.io/gitea/routers/web/shared/user"
+	"code.gitea.io/gitea/services/context"
+)
+
+const tplAudit base.TplName = "org/settings/audit"
+
+// Audit shows the audit log of an organization
+func Audit(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("org.settings.audit")
+	ctx.Data["PageIsSettingsAudit"] = true
+
+	if err := shared_user.LoadHeaderCount(ctx); err != nil {
+		ctx.ServerError("LoadHeaderCount", err)
+		return
+	}
+
+	shared.SetEventsContext(ctx, ctx.Org.Organization.ID)
+	if ctx.Written() {
+		return
+	}
+
+	ctx.HTML(http.StatusOK, tplAudit)
+}
+
+// AuditExport exports the audit log of an organization as JSON lines
+func AuditExport(ctx *context.Context) {
+	shared.ExportEvents(ctx, ctx.Org.Organization.ID)
+}
@@ -0,0 +1,177 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package setting
+
+import (
+	"errors"
+	"net/http"
+
+	audit_model "code.gitea.io/gitea/models/audit"
+	org_model "code.gitea.io/gitea/models/organization"
+	"code.gitea.io/gitea/modules/base"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/web"
+	shared_user "code.gitea.io/gitea/routers/web/shared/user"
+	audit_service "code.gitea.io/gitea/services/audit"
+	"code.gitea.io/gitea/services/context"
+	"code.gitea.io/gitea/services/forms"
+)
+
+const tplSecurity base.TplName = "org/settings/security"
+
+func loadSecurityData(ctx *context.Context) {
+	policy, err := org_model.GetTwoFactorPolicy(ctx, ctx.Org.Organization.ID)
+	if err != nil {
+		ctx.ServerError("GetTwoFactorPolicy", err)
+		return
+	}
+	ctx.Data["TwoFactorPolicy"] = policy
+
+	allowlist, err := org_model.GetIPAllowlist(ctx, ctx.Org.Organization.ID)
+	if err != nil {
+		ctx.ServerError("GetIPAllowlist", err)
+		return
+	}
+	ctx.Data["IPAllowlist"] = allowlist
+	ctx.Data["RemoteIP"] = context.RemoteIP(ctx.Req)
+
+	members, err := org_model.GetMembersWithoutTwoFactor(ctx, ctx.Org.Organization.ID)
+	if err != nil {
+		ctx.ServerError("GetMembersWithoutTwoFactor", err)
+		return
+	}
+	ctx.Data["MembersWithoutTwoFactor"] = members
+
+	if err := shared_user.LoadHeaderCount(ctx); err != nil {
+		ctx.ServerError("LoadHeaderCount", err)
+		return
+	}
+}
+
+// Security renders the security settings of an organization
+func Security(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("org.settings.security")
+	ctx.Data["PageIsSettingsSecurity"] = true
+
+	loadSecurityData(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	ctx.HTML(http.StatusOK, tplSecurity)
+}
+
+// SecurityPost updates the two-factor authentication policy of an organization
+func SecurityPost(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.OrgTwoFactorPolicyForm)
+	ctx.Data["Title"] = ctx.Tr("org.settings.security")
+	ctx.Data["PageIsSettingsSecurity"] = true
+
+	if ctx.HasError() {
+		loadSecurityData(ctx)
+		if ctx.Written() {
+			return
+		}
+		ctx.HTML(http.StatusOK, tplSecurity)
+		return
+	}
+
+	if !form.RequireTwoFactor {
+		if err := org_model.DeleteTwoFactorPolicy(ctx, ctx.Org.Organization.ID); err != nil {
+			ctx.ServerError("DeleteTwoFactorPolicy", err)
+			return
+		}
+		audit_service.Record(ctx, audit_model.ActionOrgTwoFactorPolicy, ctx.Doer, ctx.Org.Organization.ID, audit_service.UserTarget(ctx.Org.Organization.AsUser()), audit_model.Diff{
+			"require_two_factor": {New: false},
+		})
+		ctx.Flash.Success(ctx.Tr("org.settings.two_factor_policy_updated"))
+		ctx.Redirect(ctx.Org.OrgLink + "/settings/security")
+		return
+	}
+
+	// Prevent owners from locking themselves out of the repositories
+	if !ctx.Doer.IsAdmin {
+		enrolled, err := org_model.HasTwoFactorEnrolled(ctx, ctx.Doer.ID)
+		if err != nil {
+			ctx.ServerError("HasTwoFactorEnrolled", err)
+			return
+		}
+		if !enrolled {
+			ctx.Flash.Error(ctx.Tr("org.settings.two_factor_policy_enroll_first"))
+			ctx.Redirect(ctx.Org.OrgLink + "/settings/security")
+			return
+		}
+	}
+
+	var removeAfter timeutil.TimeStamp
+	if form.RemoveMembers {
+		removeAfter = timeutil.TimeStampNow().Add(form.GracePeriod * 24 * 60 * 60)
+	}
+	if err := org_model.SetTwoFactorPolicy(ctx, ctx.Org.Organization.ID, removeAfter); err != nil {
+		ctx.ServerError("SetTwoFactorPolicy", err)
+		return
+	}
+	audit_service.Record(ctx, audit_model.ActionOrgTwoFactorPolicy, ctx.Doer, ctx.Org.Organization.ID, audit_service.UserTarget(ctx.Org.Organization.AsUser()), audit_model.Diff{
+		"require_two_factor": {New: true},
+		"remove_after_unix":  {New: int64(removeAfter)},
+	})
+
+	ctx.Flash.Success(ctx.Tr("org.settings.two_factor_policy_updated"))
+	ctx.Redirect(ctx.Org.OrgLink + "/settings/security")
+}
+
+// SecurityIPAllowlistPost updates the IP allowlist of an organization
+func SecurityIPAllowlistPost(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.OrgIPAllowlistForm)
+	ctx.Data["Title"] = ctx.Tr("org.settings.security")
+	ctx.Data["PageIsSettingsSecurity"] = true
+
+	if ctx.HasError() {
+		loadSecurityData(ctx)
+		if ctx.Written() {
+			return
+		}
+		ctx.HTML(http.StatusOK, tplSecurity)
+		return
+	}
+
+	list, err := org_model.ParseIPAllowlist(form.Allowlist)
+	if err != nil {
+		var invalidErr org_model.ErrInvalidIPAllowlistEntry
+		if errors.As(err, &invalidErr) {
+			ctx.Flash.Error(ctx.Tr("org.settings.ip_allowlist_invalid", invalidErr.Entry))
+			ctx.Redirect(ctx.Org.OrgLink + "/settings/security")
+			return
+		}
+		ctx.ServerError("ParseIPAllowlist", err)
+		return
+	}
+
+	// Prevent owners from locking themselves out of the repositories
+	if list != "" && !ctx.Doer.IsAdmin && !(&org_model.IPAllowlist{Allowlist: list}).AllowsIP(context.RemoteIP(ctx.Req)) {
+		ctx.Flash.Error(ctx.Tr("org.settings.ip_allowlist_lockout", context.RemoteIP(ctx.Req)))
+		ctx.Redirect(ctx.Org.OrgLink + "/settings/security")
+		return
+	}
+
+	old, err := org_model.GetIPAllowlist(ctx, ctx.Org.Organization.ID)
+	if err != nil {
+		ctx.ServerError("GetIPAllowlist", err)
+		return
+	}
+	if err := org_model.SetIPAllowlist(ctx, ctx.Org.Organization.ID, list); err != nil {
+		ctx.ServerError("SetIPAllowlist", err)
+		return
+	}
+	change := audit_model.Change{New: list}
+	if old != nil {
+		change.Old = old.Allowlist
+	}
+	audit_service.Record(ctx, audit_model.ActionOrgIPAllowlist, ctx.Doer, ctx.Org.Organization.ID, audit_service.UserTarget(ctx.Org.Organization.AsUser()), audit_model.Diff{
+		"allowlist": change,
+	})
+
+	ctx.Flash.Success(ctx.Tr("org.settings.ip_allowlist_updated"))
+	ctx.Redirect(ctx.Org.OrgLink + "/settings/security")
+}
@@ -218,6 +218,10 @@ func httpBase(ctx *context.Context) *serviceHandler {
 					ctx.ServerError("GetUserRepoPermission", err)
 					return nil
 				}
+				if err := context.LimitRepoPermissionByToken(ctx, ctx.Data, repo, &p); err != nil {
+					ctx.ServerError("LimitRepoPermissionByToken", err)
+					return nil
+				}
 
 				if !p.CanAccess(accessMode, unitType) {
 					ctx.PlainText(http.StatusNotFound, "Repository not found")
@@ -253,6 +257,11 @@ func httpBase(ctx *context.Context) *serviceHandler {
 			return nil
 		}
 
+		if context.FineGrainedAccessToken(ctx.Data) != nil {
+			ctx.PlainText(http.StatusForbidden, "Fine-grained access tokens cannot create repositories.")
+			return nil
+		}
+
 		if owner.IsOrganization() && !setting.Repository.EnablePushCreateOrg {
 			ctx.PlainText(http.StatusForbidden, "Push to create is not enabled for organizations.")
 			return nil
@@ -2673,6 +2673,11 @@ func SearchIssues(ctx *context.Context) {
 			allPublic = true
 			opts.AllPublic = false // set it false to avoid returning too many repos, we could filter by indexer
 		}
+		if err := context.LimitRepoSearchByIPAllowlist(ctx, ctx.Doer, opts); err != nil {
+			log.Error("LimitRepoSearchByIPAllowlist: %v", err)
+			ctx.Error(http.StatusInternalServerError)
+			return
+		}
 		repoIDs, _, err = repo_model.SearchRepositoryIDs(ctx, opts)
 		if err != nil {
 			log.Error("SearchRepositoryIDs: %v", err)
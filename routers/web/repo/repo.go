@@ -656,6 +656,12 @@ func SearchRepo(ctx *context.Context) {
 		}
 	}
 
+	if err := context.LimitRepoSearchByIPAllowlist(ctx, ctx.Doer, opts); err != nil {
+		log.Error("LimitRepoSearchByIPAllowlist: %v", err)
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
 	// To improve performance when only the count is requested
 	if ctx.FormBool("count_only") {
 		if count, err := repo_model.CountRepository(ctx, opts); err != nil {
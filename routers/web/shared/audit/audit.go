@@ -0,0 +1,102 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package audit
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	audit_model "code.gitea.io/gitea/models/audit"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
+	audit_service "code.gitea.io/gitea/services/audit"
+	context_module "code.gitea.io/gitea/services/context"
+)
+
+// findOptions returns the filters of the audit events given by the query of the request
+func findOptions(ctx *context_module.Context, ownerID int64) audit_model.FindEventsOptions {
+	opts := audit_model.FindEventsOptions{
+		Action:     audit_model.Action(ctx.FormTrim("action")),
+		ActorName:  ctx.FormTrim("actor"),
+		OwnerID:    ownerID,
+		TargetType: ctx.FormTrim("target_type"),
+	}
+	if since, err := time.ParseInLocation("2006-01-02", ctx.FormTrim("since"), setting.DefaultUILocation); err == nil {
+		opts.Since = timeutil.TimeStamp(since.Unix())
+	}
+	if until, err := time.ParseInLocation("2006-01-02", ctx.FormTrim("until"), setting.DefaultUILocation); err == nil {
+		// the given day is included
+		opts.Until = timeutil.TimeStamp(until.AddDate(0, 0, 1).Unix())
+	}
+	return opts
+}
+
+// SetEventsContext loads a page of the audit events of the owner which match the filters of the request,
+// an owner ID of 0 loads the events of the whole instance
+func SetEventsContext(ctx *context_module.Context, ownerID int64) {
+	opts := findOptions(ctx, ownerID)
+	page := ctx.FormInt("page")
+	if page <= 1 {
+		page = 1
+	}
+	opts.ListOptions = db.ListOptions{Page: page, PageSize: setting.UI.Admin.NoticePagingNum}
+
+	events, total, err := db.FindAndCount[audit_model.Event](ctx, opts)
+	if err != nil {
+		ctx.ServerError("FindAuditEvents", err)
+		return
+	}
+	ctx.Data["AuditEvents"] = events
+	ctx.Data["Total"] = total
+	ctx.Data["AuditActions"] = audit_model.Actions()
+	ctx.Data["AuditTargetTypes"] = []string{
+		audit_model.TargetTypeUser,
+		audit_model.TargetTypeOrganization,
+		audit_model.TargetTypeRepository,
+		audit_model.TargetTypeAccessToken,
+		audit_model.TargetTypePublicKey,
+		audit_model.TargetTypeGPGKey,
+		audit_model.TargetTypeProtectedBranch,
+		audit_model.TargetTypeTeam,
+	}
+
+	filters := map[string]string{
+		"action":      string(opts.Action),
+		"actor":       opts.ActorName,
+		"target_type": opts.TargetType,
+		"since":       ctx.FormTrim("since"),
+		"until":       ctx.FormTrim("until"),
+	}
+	ctx.Data["AuditFilters"] = filters
+
+	pager := context_module.NewPagination(int(total), opts.PageSize, page, 5)
+	for key, value := range filters {
+		if value != "" {
+			pager.AddParamString(key, value)
+		}
+	}
+	ctx.Data["Page"] = pager
+}
+
+// ExportEvents writes the audit events of the owner which match the filters of the request as JSON lines,
+// an owner ID of 0 exports the events of the whole instance
+func ExportEvents(ctx *context_module.Context, ownerID int64) {
+	opts := findOptions(ctx, ownerID)
+
+	ctx.Resp.Header().Set("Content-Type", "application/jsonl")
+	ctx.Resp.Header().Set("Content-Disposition", `attachment; filename="audit-events.jsonl"`)
+	ctx.Resp.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(ctx.Resp)
+	if err := db.Iterate(ctx, opts.ToConds(), func(_ context.Context, e *audit_model.Event) error {
+		return enc.Encode(audit_service.ToAPIEvent(e))
+	}); err != nil {
+		// the status is already written, the export can only be truncated
+		log.Error("Unable to export the audit events: %v", err)
+	}
+}
@@ -7,21 +7,24 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"time"
+	"slices"
+	"strings"
 
-	"code.gitea.io/gitea/models/db"
+	org_model "code.gitea.io/gitea/models/organization"
 	packages_model "code.gitea.io/gitea/models/packages"
 	repo_model "code.gitea.io/gitea/models/repo"
 	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/base"
 	"code.gitea.io/gitea/modules/log"
-	"code.gitea.io/gitea/modules/optional"
+	attestation_module "code.gitea.io/gitea/modules/packages/attestation"
 	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/modules/web"
 	"code.gitea.io/gitea/services/context"
 	"code.gitea.io/gitea/services/forms"
+	attestation_service "code.gitea.io/gitea/services/packages/attestation"
 	cargo_service "code.gitea.io/gitea/services/packages/cargo"
-	container_service "code.gitea.io/gitea/services/packages/container"
+	cleanup_service "code.gitea.io/gitea/services/packages/cleanup"
+	remote_service "code.gitea.io/gitea/services/packages/remote"
 )
 
 func SetPackagesContext(ctx *context.Context, owner *user_model.User) {
@@ -33,6 +36,64 @@ func SetPackagesContext(ctx *context.Context, owner *user_model.User) {
 
 	ctx.Data["CleanupRules"] = pcrs
 
+	prs, err := packages_model.GetRemotesByOwner(ctx, owner.ID)
+	if err != nil {
+		ctx.ServerError("GetRemotesByOwner", err)
+		return
+	}
+
+	ctx.Data["Remotes"] = prs
+
+	pvrs, err := packages_model.GetVirtualRegistriesByOwner(ctx, owner.ID)
+	if err != nil {
+		ctx.ServerError("GetVirtualRegistriesByOwner", err)
+		return
+	}
+
+	virtualRegistries := make([]*virtualRegistry, 0, len(pvrs))
+	for _, pvr := range pvrs {
+		vr, err := loadVirtualRegistry(ctx, pvr)
+		if err != nil {
+			ctx.ServerError("loadVirtualRegistry", err)
+			return
+		}
+		virtualRegistries = append(virtualRegistries, vr)
+	}
+
+	ctx.Data["VirtualRegistries"] = virtualRegistries
+
+	pprs, err := packages_model.GetProtectionRulesByOwner(ctx, owner.ID)
+	if err != nil {
+		ctx.ServerError("GetProtectionRulesByOwner", err)
+		return
+	}
+
+	protectionRules := make([]*protectionRule, 0, len(pprs))
+	for _, ppr := range pprs {
+		pr, err := loadProtectionRule(ctx, ppr)
+		if err != nil {
+			ctx.ServerError("loadProtectionRule", err)
+			return
+		}
+		protectionRules = append(protectionRules, pr)
+	}
+
+	ctx.Data["ProtectionRules"] = protectionRules
+
+	ptks, err := packages_model.GetTrustedKeysByOwner(ctx, owner.ID)
+	if err != nil {
+		ctx.ServerError("GetTrustedKeysByOwner", err)
+		return
+	}
+
+	ctx.Data["TrustedKeys"] = ptks
+
+	_, ctx.Data["AttestationSigningKey"], err = attestation_service.GetOrCreateKeyPair(ctx, owner.ID)
+	if err != nil {
+		ctx.ServerError("GetOrCreateKeyPair", err)
+		return
+	}
+
 	ctx.Data["CargoIndexExists"], err = repo_model.IsRepositoryModelExist(ctx, owner, cargo_service.IndexRepositoryName)
 	if err != nil {
 		ctx.ServerError("IsRepositoryModelExist", err)
@@ -101,7 +162,9 @@ func performRuleEditPost(ctx *context.Context, owner *user_model.User, pcr *pack
 	pcr.OwnerID = owner.ID
 	pcr.KeepCount = form.KeepCount
 	pcr.KeepPattern = form.KeepPattern
+	pcr.KeepSemverPatches = form.KeepSemverPatches
 	pcr.RemoveDays = form.RemoveDays
+	pcr.RemoveUnusedDays = form.RemoveUnusedDays
 	pcr.RemovePattern = form.RemovePattern
 	pcr.MatchFullName = form.MatchFullName
 
@@ -148,89 +211,700 @@ func SetRulePreviewContext(ctx *context.Context, owner *user_model.User) {
 		return
 	}
 
-	if err := pcr.CompiledPattern(); err != nil {
-		ctx.ServerError("CompiledPattern", err)
+	versionsToRemove, err := cleanup_service.PreviewCleanupRule(ctx, pcr)
+	if err != nil {
+		ctx.ServerError("PreviewCleanupRule", err)
 		return
 	}
 
-	olderThan := time.Now().AddDate(0, 0, -pcr.RemoveDays)
+	ctx.Data["CleanupRule"] = pcr
+	ctx.Data["VersionsToRemove"] = versionsToRemove
+}
 
-	packages, err := packages_model.GetPackagesByType(ctx, pcr.OwnerID, pcr.Type)
+func getCleanupRuleByContext(ctx *context.Context, owner *user_model.User) *packages_model.PackageCleanupRule {
+	id := ctx.FormInt64("id")
+	if id == 0 {
+		id = ctx.ParamsInt64("id")
+	}
+
+	pcr, err := packages_model.GetCleanupRuleByID(ctx, id)
 	if err != nil {
-		ctx.ServerError("GetPackagesByType", err)
+		if err == packages_model.ErrPackageCleanupRuleNotExist {
+			ctx.NotFound("", err)
+		} else {
+			ctx.ServerError("GetCleanupRuleByID", err)
+		}
+		return nil
+	}
+
+	if pcr != nil && pcr.OwnerID == owner.ID {
+		return pcr
+	}
+
+	ctx.NotFound("", fmt.Errorf("PackageCleanupRule[%v] not associated to owner %v", id, owner))
+
+	return nil
+}
+
+func SetRemoteAddContext(ctx *context.Context) {
+	setRemoteEditContext(ctx, nil)
+}
+
+func SetRemoteEditContext(ctx *context.Context, owner *user_model.User) {
+	pr := getRemoteByContext(ctx, owner)
+	if pr == nil {
 		return
 	}
 
-	versionsToRemove := make([]*packages_model.PackageDescriptor, 0, 10)
+	setRemoteEditContext(ctx, pr)
+}
 
-	for _, p := range packages {
-		pvs, _, err := packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
-			PackageID:  p.ID,
-			IsInternal: optional.Some(false),
-			Sort:       packages_model.SortCreatedDesc,
-			Paginator:  db.NewAbsoluteListOptions(pcr.KeepCount, 200),
-		})
-		if err != nil {
-			ctx.ServerError("SearchVersions", err)
+func setRemoteEditContext(ctx *context.Context, pr *packages_model.PackageRemote) {
+	ctx.Data["IsEditRemote"] = pr != nil
+
+	if pr == nil {
+		pr = &packages_model.PackageRemote{}
+	}
+	ctx.Data["Remote"] = pr
+	ctx.Data["AvailableTypes"] = packages_model.RemoteSupportedTypes
+}
+
+func PerformRemoteAddPost(ctx *context.Context, owner *user_model.User, redirectURL string, template base.TplName) {
+	performRemoteEditPost(ctx, owner, nil, redirectURL, template)
+}
+
+func PerformRemoteEditPost(ctx *context.Context, owner *user_model.User, redirectURL string, template base.TplName) {
+	pr := getRemoteByContext(ctx, owner)
+	if pr == nil {
+		return
+	}
+
+	form := web.GetForm(ctx).(*forms.PackageRemoteForm)
+
+	if form.Action == "remove" {
+		if err := packages_model.DeleteRemoteByID(ctx, pr.ID); err != nil {
+			ctx.ServerError("DeleteRemoteByID", err)
 			return
 		}
-		for _, pv := range pvs {
-			if skip, err := container_service.ShouldBeSkipped(ctx, pcr, p, pv); err != nil {
-				ctx.ServerError("ShouldBeSkipped", err)
-				return
-			} else if skip {
-				continue
-			}
 
-			toMatch := pv.LowerVersion
-			if pcr.MatchFullName {
-				toMatch = p.LowerName + "/" + pv.LowerVersion
-			}
+		ctx.Flash.Success(ctx.Tr("packages.owner.settings.remotes.success.delete"))
+		ctx.Redirect(redirectURL)
+	} else {
+		performRemoteEditPost(ctx, owner, pr, redirectURL, template)
+	}
+}
 
-			if pcr.KeepPatternMatcher != nil && pcr.KeepPatternMatcher.MatchString(toMatch) {
-				continue
-			}
-			if pv.CreatedUnix.AsLocalTime().After(olderThan) {
-				continue
-			}
-			if pcr.RemovePatternMatcher != nil && !pcr.RemovePatternMatcher.MatchString(toMatch) {
+func performRemoteEditPost(ctx *context.Context, owner *user_model.User, pr *packages_model.PackageRemote, redirectURL string, template base.TplName) {
+	isEditRemote := pr != nil
+
+	if pr == nil {
+		pr = &packages_model.PackageRemote{}
+	}
+
+	form := web.GetForm(ctx).(*forms.PackageRemoteForm)
+
+	pr.Enabled = form.Enabled
+	pr.OwnerID = owner.ID
+	pr.URL = form.URL
+	pr.Username = form.Username
+
+	ctx.Data["IsEditRemote"] = isEditRemote
+	ctx.Data["Remote"] = pr
+	ctx.Data["AvailableTypes"] = packages_model.RemoteSupportedTypes
+
+	if ctx.HasError() {
+		ctx.HTML(http.StatusOK, template)
+		return
+	}
+
+	u, err := remote_service.ParseRemoteURL(form.URL)
+	if err != nil {
+		ctx.Data["Err_URL"] = true
+		ctx.RenderWithErr(ctx.Tr("packages.owner.settings.remotes.url.invalid"), template, form)
+		return
+	}
+	pr.URL = u.String()
+
+	pr.AuthHost, err = remote_service.ParseAuthHost(form.AuthHost)
+	if err != nil {
+		ctx.Data["Err_AuthHost"] = true
+		ctx.RenderWithErr(ctx.Tr("packages.owner.settings.remotes.auth_host.invalid"), template, form)
+		return
+	}
+
+	// An empty password keeps the stored one
+	if form.Password != "" || pr.Username == "" {
+		if err := pr.SetPassword(form.Password); err != nil {
+			ctx.ServerError("SetPassword", err)
+			return
+		}
+	}
+
+	if isEditRemote {
+		if err := packages_model.UpdateRemote(ctx, pr); err != nil {
+			ctx.ServerError("UpdateRemote", err)
+			return
+		}
+	} else {
+		pr.Type = packages_model.Type(form.Type)
+
+		if has, err := packages_model.HasOwnerRemoteForPackageType(ctx, owner.ID, pr.Type); err != nil {
+			ctx.ServerError("HasOwnerRemoteForPackageType", err)
+			return
+		} else if has {
+			ctx.Data["Err_Type"] = true
+			ctx.RenderWithErr(ctx.Tr("packages.owner.settings.remotes.type.exists"), template, form)
+			return
+		}
+
+		if pr, err = packages_model.InsertRemote(ctx, pr); err != nil {
+			ctx.ServerError("InsertRemote", err)
+			return
+		}
+	}
+
+	ctx.Flash.Success(ctx.Tr("packages.owner.settings.remotes.success.update"))
+	ctx.Redirect(fmt.Sprintf("%s/remotes/%d", redirectURL, pr.ID))
+}
+
+func getRemoteByContext(ctx *context.Context, owner *user_model.User) *packages_model.PackageRemote {
+	id := ctx.FormInt64("id")
+	if id == 0 {
+		id = ctx.ParamsInt64("id")
+	}
+
+	pr, err := packages_model.GetRemoteByID(ctx, id)
+	if err != nil {
+		if err == packages_model.ErrPackageRemoteNotExist {
+			ctx.NotFound("", err)
+		} else {
+			ctx.ServerError("GetRemoteByID", err)
+		}
+		return nil
+	}
+
+	if pr != nil && pr.OwnerID == owner.ID {
+		return pr
+	}
+
+	ctx.NotFound("", fmt.Errorf("PackageRemote[%v] not associated to owner %v", id, owner))
+
+	return nil
+}
+
+func SetTrustedKeyAddContext(ctx *context.Context) {
+	setTrustedKeyEditContext(ctx, nil)
+}
+
+func SetTrustedKeyEditContext(ctx *context.Context, owner *user_model.User) {
+	ptk := getTrustedKeyByContext(ctx, owner)
+	if ptk == nil {
+		return
+	}
+
+	setTrustedKeyEditContext(ctx, ptk)
+}
+
+func setTrustedKeyEditContext(ctx *context.Context, ptk *packages_model.PackageTrustedKey) {
+	ctx.Data["IsEditTrustedKey"] = ptk != nil
+
+	if ptk == nil {
+		ptk = &packages_model.PackageTrustedKey{}
+	}
+	ctx.Data["TrustedKey"] = ptk
+}
+
+func PerformTrustedKeyAddPost(ctx *context.Context, owner *user_model.User, redirectURL string, template base.TplName) {
+	performTrustedKeyEditPost(ctx, owner, nil, redirectURL, template)
+}
+
+func PerformTrustedKeyEditPost(ctx *context.Context, owner *user_model.User, redirectURL string, template base.TplName) {
+	ptk := getTrustedKeyByContext(ctx, owner)
+	if ptk == nil {
+		return
+	}
+
+	form := web.GetForm(ctx).(*forms.PackageTrustedKeyForm)
+
+	if form.Action == "remove" {
+		if err := packages_model.DeleteTrustedKeyByID(ctx, ptk.ID); err != nil {
+			ctx.ServerError("DeleteTrustedKeyByID", err)
+			return
+		}
+
+		ctx.Flash.Success(ctx.Tr("packages.owner.settings.trusted_keys.success.delete"))
+		ctx.Redirect(redirectURL)
+	} else {
+		performTrustedKeyEditPost(ctx, owner, ptk, redirectURL, template)
+	}
+}
+
+func performTrustedKeyEditPost(ctx *context.Context, owner *user_model.User, ptk *packages_model.PackageTrustedKey, redirectURL string, template base.TplName) {
+	isEditTrustedKey := ptk != nil
+
+	if ptk == nil {
+		ptk = &packages_model.PackageTrustedKey{}
+	}
+
+	form := web.GetForm(ctx).(*forms.PackageTrustedKeyForm)
+
+	oldFingerprint := ptk.Fingerprint
+
+	ptk.OwnerID = owner.ID
+	ptk.Name = form.Name
+	ptk.Content = strings.TrimSpace(form.Content)
+
+	ctx.Data["IsEditTrustedKey"] = isEditTrustedKey
+	ctx.Data["TrustedKey"] = ptk
+
+	if ctx.HasError() {
+		ctx.HTML(http.StatusOK, template)
+		return
+	}
+
+	pub, err := attestation_module.ParsePublicKey(ptk.Content)
+	if err != nil {
+		ctx.Data["Err_Content"] = true
+		ctx.RenderWithErr(ctx.Tr("packages.owner.settings.trusted_keys.content.invalid"), template, form)
+		return
+	}
+	ptk.Fingerprint, err = attestation_module.KeyFingerprint(pub)
+	if err != nil {
+		ctx.ServerError("KeyFingerprint", err)
+		return
+	}
+
+	if ptk.Fingerprint != oldFingerprint {
+		if has, err := packages_model.HasOwnerTrustedKey(ctx, owner.ID, ptk.Fingerprint); err != nil {
+			ctx.ServerError("HasOwnerTrustedKey", err)
+			return
+		} else if has {
+			ctx.Data["Err_Content"] = true
+			ctx.RenderWithErr(ctx.Tr("packages.owner.settings.trusted_keys.content.exists"), template, form)
+			return
+		}
+	}
+
+	if isEditTrustedKey {
+		if err := packages_model.UpdateTrustedKey(ctx, ptk); err != nil {
+			ctx.ServerError("UpdateTrustedKey", err)
+			return
+		}
+	} else {
+		if ptk, err = packages_model.InsertTrustedKey(ctx, ptk); err != nil {
+			ctx.ServerError("InsertTrustedKey", err)
+			return
+		}
+	}
+
+	ctx.Flash.Success(ctx.Tr("packages.owner.settings.trusted_keys.success.update"))
+	ctx.Redirect(fmt.Sprintf("%s/trusted_keys/%d", redirectURL, ptk.ID))
+}
+
+func getTrustedKeyByContext(ctx *context.Context, owner *user_model.User) *packages_model.PackageTrustedKey {
+	id := ctx.FormInt64("id")
+	if id == 0 {
+		id = ctx.ParamsInt64("id")
+	}
+
+	ptk, err := packages_model.GetTrustedKeyByID(ctx, id)
+	if err != nil {
+		if err == packages_model.ErrPackageTrustedKeyNotExist {
+			ctx.NotFound("", err)
+		} else {
+			ctx.ServerError("GetTrustedKeyByID", err)
+		}
+		return nil
+	}
+
+	if ptk != nil && ptk.OwnerID == owner.ID {
+		return ptk
+	}
+
+	ctx.NotFound("", fmt.Errorf("PackageTrustedKey[%v] not associated to owner %v", id, owner))
+
+	return nil
+}
+
+// protectionRule is a protection rule with the resolved names of its allowed teams
+type protectionRule struct {
+	*packages_model.PackageProtectionRule
+	TeamNames []string
+}
+
+func loadProtectionRule(ctx *context.Context, ppr *packages_model.PackageProtectionRule) (*protectionRule, error) {
+	pr := &protectionRule{
+		PackageProtectionRule: ppr,
+		TeamNames:             make([]string, 0, len(ppr.AllowedTeamIDs)),
+	}
+	for _, id := range ppr.AllowedTeamIDs {
+		t, err := org_model.GetTeamByID(ctx, id)
+		if err != nil {
+			if org_model.IsErrTeamNotExist(err) {
 				continue
 			}
+			return nil, err
+		}
+		pr.TeamNames = append(pr.TeamNames, t.Name)
+	}
+	return pr, nil
+}
+
+func SetProtectionRuleAddContext(ctx *context.Context, owner *user_model.User) {
+	setProtectionRuleEditContext(ctx, owner, nil)
+}
+
+func SetProtectionRuleEditContext(ctx *context.Context, owner *user_model.User) {
+	ppr := getProtectionRuleByContext(ctx, owner)
+	if ppr == nil {
+		return
+	}
+
+	setProtectionRuleEditContext(ctx, owner, ppr)
+}
+
+func setProtectionRuleEditContext(ctx *context.Context, owner *user_model.User, ppr *packages_model.PackageProtectionRule) {
+	ctx.Data["IsEditProtectionRule"] = ppr != nil
+
+	if ppr == nil {
+		ppr = &packages_model.PackageProtectionRule{}
+	}
+
+	pr, err := loadProtectionRule(ctx, ppr)
+	if err != nil {
+		ctx.ServerError("loadProtectionRule", err)
+		return
+	}
+
+	ctx.Data["ProtectionRule"] = pr
+	ctx.Data["AvailableTypes"] = packages_model.TypeList
+	ctx.Data["IsOrganization"] = owner.IsOrganization()
+}
+
+func PerformProtectionRuleAddPost(ctx *context.Context, owner *user_model.User, redirectURL string, template base.TplName) {
+	performProtectionRuleEditPost(ctx, owner, nil, redirectURL, template)
+}
+
+func PerformProtectionRuleEditPost(ctx *context.Context, owner *user_model.User, redirectURL string, template base.TplName) {
+	ppr := getProtectionRuleByContext(ctx, owner)
+	if ppr == nil {
+		return
+	}
+
+	form := web.GetForm(ctx).(*forms.PackageProtectionRuleForm)
+
+	if form.Action == "remove" {
+		if err := packages_model.DeleteProtectionRuleByID(ctx, ppr.ID); err != nil {
+			ctx.ServerError("DeleteProtectionRuleByID", err)
+			return
+		}
+
+		ctx.Flash.Success(ctx.Tr("packages.owner.settings.protection.success.delete"))
+		ctx.Redirect(redirectURL)
+	} else {
+		performProtectionRuleEditPost(ctx, owner, ppr, redirectURL, template)
+	}
+}
+
+func performProtectionRuleEditPost(ctx *context.Context, owner *user_model.User, ppr *packages_model.PackageProtectionRule, redirectURL string, template base.TplName) {
+	isEditProtectionRule := ppr != nil
+
+	if ppr == nil {
+		ppr = &packages_model.PackageProtectionRule{}
+	}
+
+	form := web.GetForm(ctx).(*forms.PackageProtectionRuleForm)
+
+	oldPattern := ppr.VersionPattern
 
-			pd, err := packages_model.GetPackageDescriptor(ctx, pv)
+	ppr.OwnerID = owner.ID
+	ppr.VersionPattern = strings.TrimSpace(form.VersionPattern)
+
+	pr := &protectionRule{
+		PackageProtectionRule: ppr,
+		TeamNames:             strings.Fields(strings.ReplaceAll(form.AllowedTeams, ",", " ")),
+	}
+
+	ctx.Data["IsEditProtectionRule"] = isEditProtectionRule
+	ctx.Data["ProtectionRule"] = pr
+	ctx.Data["AvailableTypes"] = packages_model.TypeList
+	ctx.Data["IsOrganization"] = owner.IsOrganization()
+
+	if ctx.HasError() {
+		ctx.HTML(http.StatusOK, template)
+		return
+	}
+
+	if !isEditProtectionRule {
+		ppr.Type = packages_model.Type(form.Type)
+	}
+
+	if _, err := packages_model.CompilePattern(ppr.VersionPattern); err != nil {
+		ctx.Data["Err_VersionPattern"] = true
+		ctx.RenderWithErr(ctx.Tr("packages.owner.settings.protection.pattern.invalid"), template, form)
+		return
+	}
+
+	ppr.AllowedTeamIDs = make([]int64, 0, len(pr.TeamNames))
+	if owner.IsOrganization() {
+		for _, name := range pr.TeamNames {
+			t, err := org_model.GetTeam(ctx, owner.ID, name)
 			if err != nil {
-				ctx.ServerError("GetPackageDescriptor", err)
+				if org_model.IsErrTeamNotExist(err) {
+					ctx.Data["Err_AllowedTeams"] = true
+					ctx.RenderWithErr(ctx.Tr("packages.owner.settings.protection.teams.not_exist", name), template, form)
+				} else {
+					ctx.ServerError("GetTeam", err)
+				}
 				return
 			}
-			versionsToRemove = append(versionsToRemove, pd)
+			if !slices.Contains(ppr.AllowedTeamIDs, t.ID) {
+				ppr.AllowedTeamIDs = append(ppr.AllowedTeamIDs, t.ID)
+			}
 		}
 	}
 
-	ctx.Data["CleanupRule"] = pcr
-	ctx.Data["VersionsToRemove"] = versionsToRemove
+	if !isEditProtectionRule || ppr.VersionPattern != oldPattern {
+		if has, err := packages_model.HasOwnerProtectionRule(ctx, owner.ID, ppr.Type, ppr.VersionPattern); err != nil {
+			ctx.ServerError("HasOwnerProtectionRule", err)
+			return
+		} else if has {
+			ctx.Data["Err_VersionPattern"] = true
+			ctx.RenderWithErr(ctx.Tr("packages.owner.settings.protection.pattern.exists"), template, form)
+			return
+		}
+	}
+
+	if isEditProtectionRule {
+		if err := packages_model.UpdateProtectionRule(ctx, ppr); err != nil {
+			ctx.ServerError("UpdateProtectionRule", err)
+			return
+		}
+	} else {
+		var err error
+		if ppr, err = packages_model.InsertProtectionRule(ctx, ppr); err != nil {
+			ctx.ServerError("InsertProtectionRule", err)
+			return
+		}
+	}
+
+	ctx.Flash.Success(ctx.Tr("packages.owner.settings.protection.success.update"))
+	ctx.Redirect(fmt.Sprintf("%s/protection/%d", redirectURL, ppr.ID))
 }
 
-func getCleanupRuleByContext(ctx *context.Context, owner *user_model.User) *packages_model.PackageCleanupRule {
+func getProtectionRuleByContext(ctx *context.Context, owner *user_model.User) *packages_model.PackageProtectionRule {
 	id := ctx.FormInt64("id")
 	if id == 0 {
 		id = ctx.ParamsInt64("id")
 	}
 
-	pcr, err := packages_model.GetCleanupRuleByID(ctx, id)
+	ppr, err := packages_model.GetProtectionRuleByID(ctx, id)
 	if err != nil {
-		if err == packages_model.ErrPackageCleanupRuleNotExist {
+		if err == packages_model.ErrPackageProtectionRuleNotExist {
 			ctx.NotFound("", err)
 		} else {
-			ctx.ServerError("GetCleanupRuleByID", err)
+			ctx.ServerError("GetProtectionRuleByID", err)
 		}
 		return nil
 	}
 
-	if pcr != nil && pcr.OwnerID == owner.ID {
-		return pcr
+	if ppr != nil && ppr.OwnerID == owner.ID {
+		return ppr
 	}
 
-	ctx.NotFound("", fmt.Errorf("PackageCleanupRule[%v] not associated to owner %v", id, owner))
+	ctx.NotFound("", fmt.Errorf("PackageProtectionRule[%v] not associated to owner %v", id, owner))
+
+	return nil
+}
+
+// virtualRegistry is a virtual registry with the resolved names of its owners
+type virtualRegistry struct {
+	*packages_model.PackageVirtualRegistry
+	MemberNames      []string
+	DefaultOwnerName string
+}
+
+func loadVirtualRegistry(ctx *context.Context, pvr *packages_model.PackageVirtualRegistry) (*virtualRegistry, error) {
+	users, err := user_model.GetUsersByIDs(ctx, append([]int64{pvr.DefaultOwnerID}, pvr.MemberIDs...))
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int64]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.Name
+	}
+
+	vr := &virtualRegistry{
+		PackageVirtualRegistry: pvr,
+		MemberNames:            make([]string, 0, len(pvr.MemberIDs)),
+		DefaultOwnerName:       names[pvr.DefaultOwnerID],
+	}
+	for _, id := range pvr.MemberIDs {
+		if name, ok := names[id]; ok {
+			vr.MemberNames = append(vr.MemberNames, name)
+		}
+	}
+	return vr, nil
+}
+
+func SetVirtualRegistryAddContext(ctx *context.Context) {
+	setVirtualRegistryEditContext(ctx, nil)
+}
+
+func SetVirtualRegistryEditContext(ctx *context.Context, owner *user_model.User) {
+	pvr := getVirtualRegistryByContext(ctx, owner)
+	if pvr == nil {
+		return
+	}
+
+	setVirtualRegistryEditContext(ctx, pvr)
+}
+
+func setVirtualRegistryEditContext(ctx *context.Context, pvr *packages_model.PackageVirtualRegistry) {
+	ctx.Data["IsEditVirtualRegistry"] = pvr != nil
+
+	if pvr == nil {
+		pvr = &packages_model.PackageVirtualRegistry{}
+	}
+
+	vr, err := loadVirtualRegistry(ctx, pvr)
+	if err != nil {
+		ctx.ServerError("loadVirtualRegistry", err)
+		return
+	}
+
+	ctx.Data["VirtualRegistry"] = vr
+	ctx.Data["AvailableTypes"] = packages_model.VirtualRegistrySupportedTypes
+}
+
+func PerformVirtualRegistryAddPost(ctx *context.Context, owner *user_model.User, redirectURL string, template base.TplName) {
+	performVirtualRegistryEditPost(ctx, owner, nil, redirectURL, template)
+}
+
+func PerformVirtualRegistryEditPost(ctx *context.Context, owner *user_model.User, redirectURL string, template base.TplName) {
+	pvr := getVirtualRegistryByContext(ctx, owner)
+	if pvr == nil {
+		return
+	}
+
+	form := web.GetForm(ctx).(*forms.PackageVirtualRegistryForm)
+
+	if form.Action == "remove" {
+		if err := packages_model.DeleteVirtualRegistryByID(ctx, pvr.ID); err != nil {
+			ctx.ServerError("DeleteVirtualRegistryByID", err)
+			return
+		}
+
+		ctx.Flash.Success(ctx.Tr("packages.owner.settings.virtual.success.delete"))
+		ctx.Redirect(redirectURL)
+	} else {
+		performVirtualRegistryEditPost(ctx, owner, pvr, redirectURL, template)
+	}
+}
+
+func performVirtualRegistryEditPost(ctx *context.Context, owner *user_model.User, pvr *packages_model.PackageVirtualRegistry, redirectURL string, template base.TplName) {
+	isEditVirtualRegistry := pvr != nil
+
+	if pvr == nil {
+		pvr = &packages_model.PackageVirtualRegistry{}
+	}
+
+	form := web.GetForm(ctx).(*forms.PackageVirtualRegistryForm)
+
+	pvr.Enabled = form.Enabled
+	pvr.OwnerID = owner.ID
+
+	vr := &virtualRegistry{
+		PackageVirtualRegistry: pvr,
+		MemberNames:            strings.Fields(strings.ReplaceAll(form.Members, ",", " ")),
+		DefaultOwnerName:       strings.TrimSpace(form.DefaultOwner),
+	}
+
+	ctx.Data["IsEditVirtualRegistry"] = isEditVirtualRegistry
+	ctx.Data["VirtualRegistry"] = vr
+	ctx.Data["AvailableTypes"] = packages_model.VirtualRegistrySupportedTypes
+
+	if ctx.HasError() {
+		ctx.HTML(http.StatusOK, template)
+		return
+	}
+
+	pvr.MemberIDs = make([]int64, 0, len(vr.MemberNames))
+	pvr.DefaultOwnerID = 0
+	for _, name := range vr.MemberNames {
+		u, err := user_model.GetUserByName(ctx, name)
+		if err != nil {
+			if user_model.IsErrUserNotExist(err) {
+				ctx.Data["Err_Members"] = true
+				ctx.RenderWithErr(ctx.Tr("packages.owner.settings.virtual.members.not_exist", name), template, form)
+			} else {
+				ctx.ServerError("GetUserByName", err)
+			}
+			return
+		}
+		if slices.Contains(pvr.MemberIDs, u.ID) {
+			continue
+		}
+		pvr.MemberIDs = append(pvr.MemberIDs, u.ID)
+		if strings.EqualFold(u.Name, vr.DefaultOwnerName) {
+			pvr.DefaultOwnerID = u.ID
+		}
+	}
+	if pvr.DefaultOwnerID == 0 {
+		ctx.Data["Err_DefaultOwner"] = true
+		ctx.RenderWithErr(ctx.Tr("packages.owner.settings.virtual.default_owner.invalid"), template, form)
+		return
+	}
+
+	if isEditVirtualRegistry {
+		if err := packages_model.UpdateVirtualRegistry(ctx, pvr); err != nil {
+			ctx.ServerError("UpdateVirtualRegistry", err)
+			return
+		}
+	} else {
+		pvr.Type = packages_model.Type(form.Type)
+
+		if has, err := packages_model.HasOwnerVirtualRegistryForPackageType(ctx, owner.ID, pvr.Type); err != nil {
+			ctx.ServerError("HasOwnerVirtualRegistryForPackageType", err)
+			return
+		} else if has {
+			ctx.Data["Err_Type"] = true
+			ctx.RenderWithErr(ctx.Tr("packages.owner.settings.virtual.type.exists"), template, form)
+			return
+		}
+
+		var err error
+		if pvr, err = packages_model.InsertVirtualRegistry(ctx, pvr); err != nil {
+			ctx.ServerError("InsertVirtualRegistry", err)
+			return
+		}
+	}
+
+	ctx.Flash.Success(ctx.Tr("packages.owner.settings.virtual.success.update"))
+	ctx.Redirect(fmt.Sprintf("%s/virtual/%d", redirectURL, pvr.ID))
+}
+
+func getVirtualRegistryByContext(ctx *context.Context, owner *user_model.User) *packages_model.PackageVirtualRegistry {
+	id := ctx.FormInt64("id")
+	if id == 0 {
+		id = ctx.ParamsInt64("id")
+	}
+
+	pvr, err := packages_model.GetVirtualRegistryByID(ctx, id)
+	if err != nil {
+		if err == packages_model.ErrPackageVirtualRegistryNotExist {
+			ctx.NotFound("", err)
+		} else {
+			ctx.ServerError("GetVirtualRegistryByID", err)
+		}
+		return nil
+	}
+
+	if pvr != nil && pvr.OwnerID == owner.ID {
+		return pvr
+	}
+
+	ctx.NotFound("", fmt.Errorf("PackageVirtualRegistry[%v] not associated to owner %v", id, owner))
 
 	return nil
 }
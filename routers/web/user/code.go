@@ -75,6 +75,11 @@ func CodeSearch(ctx *context.Context) {
 		ctx.ServerError("FindUserCodeAccessibleOwnerRepoIDs", err)
 		return
 	}
+	repoIDs, err = context.LimitCodeSearchByIPAllowlist(ctx, ctx.Doer, repoIDs)
+	if err != nil {
+		ctx.ServerError("LimitCodeSearchByIPAllowlist", err)
+		return
+	}
 
 	var (
 		total                 int
@@ -169,6 +169,10 @@ func Milestones(ctx *context.Context) {
 	if ctxUser.IsOrganization() && ctx.Org.Team != nil {
 		repoOpts.TeamID = ctx.Org.Team.ID
 	}
+	if err := context.LimitRepoSearchByIPAllowlist(ctx, ctx.Doer, &repoOpts); err != nil {
+		ctx.ServerError("LimitRepoSearchByIPAllowlist", err)
+		return
+	}
 
 	var (
 		userRepoCond = repo_model.SearchRepositoryCondition(&repoOpts) // all repo condition user could visit
@@ -489,6 +493,10 @@ func buildIssueOverview(ctx *context.Context, unitType unit.Type) {
 	if team != nil {
 		repoOpts.TeamID = team.ID
 	}
+	if err := context.LimitRepoSearchByIPAllowlist(ctx, ctx.Doer, repoOpts); err != nil {
+		ctx.ServerError("LimitRepoSearchByIPAllowlist", err)
+		return
+	}
 	accessibleRepos := container.Set[int64]{}
 	{
 		ids, _, err := repo_model.SearchRepositoryIDs(ctx, repoOpts)
@@ -4,6 +4,7 @@
 package user
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -21,7 +22,9 @@ import (
 	alpine_module "code.gitea.io/gitea/modules/packages/alpine"
 	arch_model "code.gitea.io/gitea/modules/packages/arch"
 	debian_module "code.gitea.io/gitea/modules/packages/debian"
+	"code.gitea.io/gitea/modules/packages/osv"
 	rpm_module "code.gitea.io/gitea/modules/packages/rpm"
+	terraform_module "code.gitea.io/gitea/modules/packages/terraform"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/modules/web"
@@ -181,6 +184,19 @@ func ViewPackageVersion(ctx *context.Context) {
 	switch pd.Package.Type {
 	case packages_model.TypeContainer:
 		ctx.Data["RegistryHost"] = setting.Packages.RegistryHost
+	case packages_model.TypeTerraform:
+		ctx.Data["RegistryHost"] = setting.Packages.RegistryHost
+
+		platforms := make([]*terraform_module.Platform, 0, len(pd.Files))
+		for _, f := range pd.Files {
+			if os := f.Properties.GetByName(terraform_module.PropertyOS); os != "" {
+				platforms = append(platforms, &terraform_module.Platform{
+					OS:   os,
+					Arch: f.Properties.GetByName(terraform_module.PropertyArch),
+				})
+			}
+		}
+		ctx.Data["Platforms"] = platforms
 	case packages_model.TypeAlpine:
 		branches := make(container.Set[string])
 		repositories := make(container.Set[string])
@@ -281,6 +297,43 @@ func ViewPackageVersion(ctx *context.Context) {
 	ctx.Data["LatestVersions"] = pvs
 	ctx.Data["TotalVersionCount"] = total
 
+	attestations, err := packages_model.GetAttestationsByVersionID(ctx, pd.Version.ID)
+	if err != nil {
+		ctx.ServerError("GetAttestationsByVersionID", err)
+		return
+	}
+	hasVerifiedProvenance := false
+	for _, pa := range attestations {
+		if pa.IsVerified {
+			hasVerifiedProvenance = true
+			break
+		}
+	}
+	ctx.Data["Attestations"] = attestations
+	ctx.Data["HasVerifiedProvenance"] = hasVerifiedProvenance
+
+	advisories, err := packages_model.GetAdvisoriesByVersionID(ctx, pd.Version.ID)
+	if err != nil {
+		ctx.ServerError("GetAdvisoriesByVersionID", err)
+		return
+	}
+	ctx.Data["Advisories"] = advisories
+	ctx.Data["IsDownloadBlocked"] = false
+	if setting.Packages.BlockCriticalVulnerabilities {
+		for _, pa := range advisories {
+			if pa.Severity == osv.SeverityCritical {
+				ctx.Data["IsDownloadBlocked"] = true
+				break
+			}
+		}
+	}
+
+	ctx.Data["IsVersionProtected"], err = packages_service.IsVersionProtected(ctx, pd.Owner.ID, pd.Package.Type, pd.Version.Version)
+	if err != nil {
+		ctx.ServerError("IsVersionProtected", err)
+		return
+	}
+
 	ctx.Data["CanWritePackages"] = ctx.Package.AccessMode >= perm.AccessModeWrite || ctx.IsUserSiteAdmin()
 
 	hasRepositoryAccess := false
@@ -423,8 +476,14 @@ func PackageSettings(ctx *context.Context) {
 	ctx.Data["Repos"] = repos
 	ctx.Data["CanWritePackages"] = ctx.Package.AccessMode >= perm.AccessModeWrite || ctx.IsUserSiteAdmin()
 
-	err := shared_user.LoadHeaderCount(ctx)
+	canLinkRepository, err := canLinkRepository(ctx)
 	if err != nil {
+		ctx.ServerError("canLinkRepository", err)
+		return
+	}
+	ctx.Data["CanLinkRepository"] = canLinkRepository
+
+	if err := shared_user.LoadHeaderCount(ctx); err != nil {
 		ctx.ServerError("LoadHeaderCount", err)
 		return
 	}
@@ -432,6 +491,19 @@ func PackageSettings(ctx *context.Context) {
 	ctx.HTML(http.StatusOK, tplPackagesSettings)
 }
 
+// canLinkRepository checks if the doer may change the repository link of the package.
+// Permissions which are inherited from the currently linked repository are not sufficient.
+func canLinkRepository(ctx *context.Context) (bool, error) {
+	if ctx.IsUserSiteAdmin() {
+		return true, nil
+	}
+	accessMode, err := context.DeterminePackageAccessMode(ctx.Base, ctx.Package.Owner, ctx.Doer)
+	if err != nil {
+		return false, err
+	}
+	return accessMode >= perm.AccessModeWrite, nil
+}
+
 // PackageSettingsPost updates the package settings
 func PackageSettingsPost(ctx *context.Context) {
 	pd := ctx.Package.Descriptor
@@ -439,6 +511,16 @@ func PackageSettingsPost(ctx *context.Context) {
 	form := web.GetForm(ctx).(*forms.PackageSettingForm)
 	switch form.Action {
 	case "link":
+		canLink, err := canLinkRepository(ctx)
+		if err != nil {
+			ctx.ServerError("canLinkRepository", err)
+			return
+		}
+		if !canLink {
+			ctx.NotFound("", nil)
+			return
+		}
+
 		success := func() bool {
 			repoID := int64(0)
 			if form.RepoID != 0 {
@@ -459,6 +541,10 @@ func PackageSettingsPost(ctx *context.Context) {
 				log.Error("Error updating package: %v", err)
 				return false
 			}
+			if err := packages_model.SetRepositoryPermissionInheritance(ctx, pd.Package.ID, repoID != 0 && form.InheritRepoPermissions); err != nil {
+				log.Error("Error updating package: %v", err)
+				return false
+			}
 
 			return true
 		}()
@@ -473,6 +559,11 @@ func PackageSettingsPost(ctx *context.Context) {
 		return
 	case "delete":
 		err := packages_service.RemovePackageVersion(ctx, ctx.Doer, ctx.Package.Descriptor.Version)
+		if errors.Is(err, packages_service.ErrVersionProtected) {
+			ctx.Flash.Error(ctx.Tr("packages.settings.delete.protected"))
+			ctx.Redirect(ctx.Link)
+			return
+		}
 		if err != nil {
 			log.Error("Error deleting package: %v", err)
 			ctx.Flash.Error(ctx.Tr("packages.settings.delete.error"))
@@ -505,6 +596,10 @@ func DownloadPackageFile(ctx *context.Context) {
 
 	s, u, _, err := packages_service.GetPackageFileStream(ctx, pf)
 	if err != nil {
+		if errors.Is(err, packages_service.ErrVersionBlocked) {
+			ctx.Error(http.StatusForbidden, ctx.Locale.TrString("packages.vulnerabilities.blocked"))
+			return
+		}
 		ctx.ServerError("GetPackageFileStream", err)
 		return
 	}
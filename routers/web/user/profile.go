@@ -27,6 +27,7 @@ import (
 	"code.gitea.io/gitea/routers/web/org"
 	shared_user "code.gitea.io/gitea/routers/web/shared/user"
 	"code.gitea.io/gitea/services/context"
+	"code.gitea.io/gitea/services/federation"
 	user_service "code.gitea.io/gitea/services/user"
 )
 
@@ -346,9 +347,17 @@ func Action(ctx *context.Context) {
 
 	switch action {
 	case "follow":
-		err = user_model.FollowUser(ctx, ctx.Doer.ID, ctx.ContextUser.ID)
+		if ctx.ContextUser.IsRemote() && setting.Federation.Enabled {
+			err = federation.FollowRemoteUser(ctx, ctx.Doer, ctx.ContextUser)
+		} else {
+			err = user_model.FollowUser(ctx, ctx.Doer.ID, ctx.ContextUser.ID)
+		}
 	case "unfollow":
-		err = user_model.UnfollowUser(ctx, ctx.Doer.ID, ctx.ContextUser.ID)
+		if ctx.ContextUser.IsRemote() && setting.Federation.Enabled {
+			err = federation.UnfollowRemoteUser(ctx, ctx.Doer, ctx.ContextUser)
+		} else {
+			err = user_model.UnfollowUser(ctx, ctx.Doer.ID, ctx.ContextUser.ID)
+		}
 	case "block":
 		err = user_service.BlockUser(ctx, ctx.Doer.ID, ctx.ContextUser.ID)
 	case "unblock":
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"code.gitea.io/gitea/models"
+	auth_model "code.gitea.io/gitea/models/auth"
 	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/auth/password"
 	"code.gitea.io/gitea/modules/base"
@@ -17,6 +18,7 @@ import (
 	"code.gitea.io/gitea/modules/optional"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/modules/validation"
 	"code.gitea.io/gitea/modules/web"
 	"code.gitea.io/gitea/services/auth"
@@ -81,6 +83,12 @@ func AccountPost(ctx *context.Context) {
 				return
 			}
 		} else {
+			// Keep the current session signed in, the other ones were revoked
+			if err := auth.StartUserSession(ctx.Req, ctx.Session, ctx.Doer); err != nil {
+				ctx.ServerError("StartUserSession", err)
+				return
+			}
+
 			// Re-generate LTA cookie.
 			if len(ctx.GetSiteCookie(setting.CookieRememberName)) != 0 {
 				if err := ctx.SetLTACookie(ctx.Doer); err != nil {
@@ -97,6 +105,48 @@ func AccountPost(ctx *context.Context) {
 	ctx.Redirect(setting.AppSubURL + "/user/settings/account")
 }
 
+// RemovePasswordPost removes the password of a user who signs in with passkeys
+func RemovePasswordPost(ctx *context.Context) {
+	if !ctx.Doer.IsLocal() || !ctx.Doer.IsPasswordSet() {
+		ctx.Error(http.StatusNotFound)
+		return
+	}
+
+	if !ctx.Doer.ValidatePassword(ctx.FormString("old_password")) {
+		ctx.Flash.Error(ctx.Tr("settings.password_incorrect"))
+		ctx.Redirect(setting.AppSubURL + "/user/settings/account")
+		return
+	}
+
+	if err := user.RemovePassword(ctx, ctx.Doer); err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			ctx.Flash.Error(ctx.Tr("settings.remove_password_no_passkey"))
+			ctx.Redirect(setting.AppSubURL + "/user/settings/account")
+			return
+		}
+		ctx.ServerError("RemovePassword", err)
+		return
+	}
+
+	// Keep the current session signed in, the other ones were revoked
+	if err := auth.StartUserSession(ctx.Req, ctx.Session, ctx.Doer); err != nil {
+		ctx.ServerError("StartUserSession", err)
+		return
+	}
+
+	// Re-generate LTA cookie.
+	if len(ctx.GetSiteCookie(setting.CookieRememberName)) != 0 {
+		if err := ctx.SetLTACookie(ctx.Doer); err != nil {
+			ctx.ServerError("SetLTACookie", err)
+			return
+		}
+	}
+
+	log.Trace("User password removed: %s", ctx.Doer.Name)
+	ctx.Flash.Success(ctx.Tr("settings.remove_password_success"))
+	ctx.Redirect(setting.AppSubURL + "/user/settings/account")
+}
+
 // EmailPost response for change user's email
 func EmailPost(ctx *context.Context) {
 	form := web.GetForm(ctx).(*forms.AddEmailForm)
@@ -338,6 +388,13 @@ func loadAccountData(ctx *context.Context) {
 	ctx.Data["CanAddEmails"] = !pendingActivation || !setting.Service.RegisterEmailConfirm
 	ctx.Data["UserDisabledFeatures"] = user_model.DisabledFeaturesWithLoginType(ctx.Doer)
 
+	hasPasskey, err := auth_model.HasDiscoverableWebAuthnCredentialsByUID(ctx, ctx.Doer.ID)
+	if err != nil {
+		ctx.ServerError("HasDiscoverableWebAuthnCredentialsByUID", err)
+		return
+	}
+	ctx.Data["HasPasskey"] = hasPasskey
+
 	if setting.Service.UserDeleteWithCommentsMaxTime != 0 {
 		ctx.Data["UserDeleteWithCommentsMaxTime"] = setting.Service.UserDeleteWithCommentsMaxTime.String()
 		ctx.Data["UserDeleteWithComments"] = ctx.Doer.CreatedUnix.AsTime().Add(setting.Service.UserDeleteWithCommentsMaxTime).After(time.Now())
@@ -5,13 +5,20 @@
 package setting
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	auth_model "code.gitea.io/gitea/models/auth"
 	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/organization"
+	repo_model "code.gitea.io/gitea/models/repo"
+	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/base"
 	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/modules/web"
+	auth_service "code.gitea.io/gitea/services/auth"
 	"code.gitea.io/gitea/services/context"
 	"code.gitea.io/gitea/services/forms"
 )
@@ -43,15 +50,9 @@ func ApplicationsPost(ctx *context.Context) {
 		return
 	}
 
-	scope, err := form.GetScope()
-	if err != nil {
-		ctx.ServerError("GetScope", err)
-		return
-	}
 	t := &auth_model.AccessToken{
-		UID:   ctx.Doer.ID,
-		Name:  form.Name,
-		Scope: scope,
+		UID:  ctx.Doer.ID,
+		Name: form.Name,
 	}
 
 	exist, err := auth_model.AccessTokenByNameExists(ctx, t)
@@ -65,8 +66,43 @@ func ApplicationsPost(ctx *context.Context) {
 		return
 	}
 
-	if err := auth_model.NewAccessToken(ctx, t); err != nil {
-		ctx.ServerError("NewAccessToken", err)
+	opts := auth_service.CreateAccessTokenOptions{
+		Name:         form.Name,
+		Organization: form.Organization,
+		Repositories: form.GetRepositories(),
+	}
+	opts.Permissions, err = form.GetPermissions()
+	if err != nil {
+		ctx.Flash.Error(ctx.Tr("settings.generate_token_invalid", err.Error()))
+		ctx.Redirect(setting.AppSubURL + "/user/settings/applications")
+		return
+	}
+	if len(opts.Permissions) == 0 {
+		opts.Scope, err = form.GetScope()
+		if err != nil {
+			ctx.ServerError("GetScope", err)
+			return
+		}
+	}
+	if form.ExpiresAt != "" {
+		// the token is valid through the whole selected day
+		expires, err := time.ParseInLocation(time.DateOnly, form.ExpiresAt, setting.DefaultUILocation)
+		if err != nil {
+			ctx.Flash.Error(ctx.Tr("settings.generate_token_invalid", err.Error()))
+			ctx.Redirect(setting.AppSubURL + "/user/settings/applications")
+			return
+		}
+		opts.Expires = expires.AddDate(0, 0, 1)
+	}
+
+	t, err = auth_service.CreateAccessToken(ctx, ctx.Doer, opts)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			ctx.Flash.Error(ctx.Tr("settings.generate_token_invalid", err.Error()))
+			ctx.Redirect(setting.AppSubURL + "/user/settings/applications")
+			return
+		}
+		ctx.ServerError("CreateAccessToken", err)
 		return
 	}
 
@@ -78,8 +114,8 @@ func ApplicationsPost(ctx *context.Context) {
 
 // DeleteApplication response for delete user access token
 func DeleteApplication(ctx *context.Context) {
-	if err := auth_model.DeleteAccessTokenByID(ctx, ctx.FormInt64("id"), ctx.Doer.ID); err != nil {
-		ctx.Flash.Error("DeleteAccessTokenByID: " + err.Error())
+	if err := auth_service.DeleteAccessToken(ctx, ctx.Doer, ctx.FormInt64("id")); err != nil {
+		ctx.Flash.Error("DeleteAccessToken: " + err.Error())
 	} else {
 		ctx.Flash.Success(ctx.Tr("settings.delete_token_success"))
 	}
@@ -95,6 +131,57 @@ func loadApplicationsData(ctx *context.Context) {
 		return
 	}
 	ctx.Data["Tokens"] = tokens
+
+	// resolve the restrictions of fine-grained tokens
+	tokenOrganizations := make(map[int64]string)
+	tokenRepositories := make(map[int64][]string)
+	for _, t := range tokens {
+		if !t.IsFineGrained() {
+			continue
+		}
+		if t.OrgID > 0 {
+			org, err := user_model.GetUserByID(ctx, t.OrgID)
+			if err != nil && !user_model.IsErrUserNotExist(err) {
+				ctx.ServerError("GetUserByID", err)
+				return
+			}
+			if org != nil {
+				tokenOrganizations[t.ID] = org.Name
+			}
+			continue
+		}
+		if err := t.LoadRepositoryIDs(ctx); err != nil {
+			ctx.ServerError("LoadRepositoryIDs", err)
+			return
+		}
+		repos, err := repo_model.GetRepositoriesMapByIDs(ctx, t.RepoIDs)
+		if err != nil {
+			ctx.ServerError("GetRepositoriesMapByIDs", err)
+			return
+		}
+		for _, repoID := range t.RepoIDs {
+			if repo, ok := repos[repoID]; ok {
+				tokenRepositories[t.ID] = append(tokenRepositories[t.ID], repo.FullName())
+			}
+		}
+	}
+	ctx.Data["TokenOrganizations"] = tokenOrganizations
+	ctx.Data["TokenRepositories"] = tokenRepositories
+
+	orgs, err := db.Find[organization.Organization](ctx, organization.FindOrgOptions{
+		UserID:         ctx.Doer.ID,
+		IncludePrivate: true,
+	})
+	if err != nil {
+		ctx.ServerError("FindOrgs", err)
+		return
+	}
+	ctx.Data["Orgs"] = orgs
+	ctx.Data["AccessTokenPermissions"] = auth_model.AllAccessTokenPermissions
+	ctx.Data["AccessTokenRequireExpiry"] = setting.AccessTokenRequireExpiry || setting.AccessTokenMaxExpiry > 0
+	if setting.AccessTokenMaxExpiry > 0 {
+		ctx.Data["AccessTokenMaxExpiry"] = time.Now().Add(setting.AccessTokenMaxExpiry).In(setting.DefaultUILocation).Format(time.DateOnly)
+	}
 	ctx.Data["EnableOAuth2"] = setting.OAuth2.Enabled
 	ctx.Data["IsAdmin"] = ctx.Doer.IsAdmin
 	if setting.OAuth2.Enabled {
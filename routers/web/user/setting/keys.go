@@ -5,18 +5,24 @@
 package setting
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	asymkey_model "code.gitea.io/gitea/models/asymkey"
 	"code.gitea.io/gitea/models/db"
 	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/base"
 	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/modules/web"
 	asymkey_service "code.gitea.io/gitea/services/asymkey"
 	"code.gitea.io/gitea/services/context"
 	"code.gitea.io/gitea/services/forms"
+
+	gossh "golang.org/x/crypto/ssh"
 )
 
 const (
@@ -30,6 +36,7 @@ func Keys(ctx *context.Context) {
 	ctx.Data["DisableSSH"] = setting.SSH.Disabled
 	ctx.Data["BuiltinSSH"] = setting.SSH.StartBuiltinServer
 	ctx.Data["AllowPrincipals"] = setting.SSH.AuthorizedPrincipalsEnabled
+	ctx.Data["UserCAEnabled"] = setting.SSH.UserCAEnabled
 
 	loadKeysData(ctx)
 
@@ -44,6 +51,7 @@ func KeysPost(ctx *context.Context) {
 	ctx.Data["DisableSSH"] = setting.SSH.Disabled
 	ctx.Data["BuiltinSSH"] = setting.SSH.StartBuiltinServer
 	ctx.Data["AllowPrincipals"] = setting.SSH.AuthorizedPrincipalsEnabled
+	ctx.Data["UserCAEnabled"] = setting.SSH.UserCAEnabled
 
 	if ctx.HasError() {
 		loadKeysData(ctx)
@@ -87,9 +95,9 @@ func KeysPost(ctx *context.Context) {
 		token := asymkey_model.VerificationToken(ctx.Doer, 1)
 		lastToken := asymkey_model.VerificationToken(ctx.Doer, 0)
 
-		keys, err := asymkey_model.AddGPGKey(ctx, ctx.Doer.ID, form.Content, token, form.Signature)
+		keys, err := asymkey_service.AddGPGKey(ctx, ctx.Doer.ID, form.Content, token, form.Signature)
 		if err != nil && asymkey_model.IsErrGPGInvalidTokenSignature(err) {
-			keys, err = asymkey_model.AddGPGKey(ctx, ctx.Doer.ID, form.Content, lastToken, form.Signature)
+			keys, err = asymkey_service.AddGPGKey(ctx, ctx.Doer.ID, form.Content, lastToken, form.Signature)
 		}
 		if err != nil {
 			ctx.Data["HasGPGError"] = true
@@ -180,7 +188,7 @@ func KeysPost(ctx *context.Context) {
 			return
 		}
 
-		if _, err = asymkey_model.AddPublicKey(ctx, ctx.Doer.ID, form.Title, content, 0); err != nil {
+		if _, err = asymkey_service.AddPublicKey(ctx, ctx.Doer.ID, form.Title, content); err != nil {
 			ctx.Data["HasSSHError"] = true
 			switch {
 			case asymkey_model.IsErrKeyAlreadyExist(err):
@@ -203,6 +211,31 @@ func KeysPost(ctx *context.Context) {
 		}
 		ctx.Flash.Success(ctx.Tr("settings.add_key_success", form.Title))
 		ctx.Redirect(setting.AppSubURL + "/user/settings/keys")
+	case "ssh_certificate":
+		cert, err := asymkey_service.SignUserCertificate(ctx, ctx.Doer, form.Content, 0)
+		if err != nil {
+			switch {
+			case errors.Is(err, asymkey_service.ErrUserCADisabled):
+				ctx.NotFound("SignUserCertificate", err)
+			case errors.Is(err, util.ErrInvalidArgument):
+				ctx.Flash.Error(ctx.Tr("form.invalid_ssh_key", err.Error()))
+				ctx.Redirect(setting.AppSubURL + "/user/settings/keys")
+			case errors.Is(err, util.ErrAlreadyExist):
+				ctx.Flash.Error(ctx.Tr("settings.ssh_certificate_principal_used", ctx.Doer.Name))
+				ctx.Redirect(setting.AppSubURL + "/user/settings/keys")
+			default:
+				ctx.ServerError("SignUserCertificate", err)
+			}
+			return
+		}
+		loadKeysData(ctx)
+		if ctx.Written() {
+			return
+		}
+
+		ctx.Data["SSHCertificate"] = strings.TrimSpace(string(gossh.MarshalAuthorizedKey(cert)))
+		ctx.Data["SSHCertificateValidBefore"] = time.Unix(int64(cert.ValidBefore), 0)
+		ctx.HTML(http.StatusOK, tplSettingsKeys)
 	case "verify_ssh":
 		if user_model.IsFeatureDisabledWithLoginType(ctx.Doer, setting.UserFeatureManageSSHKeys) {
 			ctx.NotFound("Not Found", fmt.Errorf("ssh keys setting is not allowed to be visited"))
@@ -245,7 +278,7 @@ func DeleteKey(ctx *context.Context) {
 			ctx.NotFound("Not Found", fmt.Errorf("gpg keys setting is not allowed to be visited"))
 			return
 		}
-		if err := asymkey_model.DeleteGPGKey(ctx, ctx.Doer, ctx.FormInt64("id")); err != nil {
+		if err := asymkey_service.DeleteGPGKey(ctx, ctx.Doer, ctx.FormInt64("id")); err != nil {
 			ctx.Flash.Error("DeleteGPGKey: " + err.Error())
 		} else {
 			ctx.Flash.Success(ctx.Tr("settings.gpg_key_deletion_success"))
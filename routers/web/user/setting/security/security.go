@@ -14,6 +14,7 @@ import (
 	"code.gitea.io/gitea/modules/base"
 	"code.gitea.io/gitea/modules/optional"
 	"code.gitea.io/gitea/modules/setting"
+	auth_service "code.gitea.io/gitea/services/auth"
 	"code.gitea.io/gitea/services/auth/source/oauth2"
 	"code.gitea.io/gitea/services/context"
 )
@@ -69,6 +70,21 @@ func loadSecurityData(ctx *context.Context) {
 	}
 	ctx.Data["WebAuthnCredentials"] = credentials
 
+	webAuthnRequired, err := auth_service.IsWebAuthnRequired(ctx, ctx.Doer)
+	if err != nil {
+		ctx.ServerError("IsWebAuthnRequired", err)
+		return
+	}
+	ctx.Data["WebAuthnRequired"] = webAuthnRequired
+
+	sessions, err := db.Find[auth_model.UserSession](ctx, auth_model.FindUserSessionsOptions{UID: ctx.Doer.ID})
+	if err != nil {
+		ctx.ServerError("FindUserSessions", err)
+		return
+	}
+	ctx.Data["UserSessions"] = sessions
+	ctx.Data["CurrentUserSessionID"] = auth_service.CurrentUserSessionID(ctx.Session)
+
 	tokens, err := db.Find[auth_model.AccessToken](ctx, auth_model.ListAccessTokensOptions{UserID: ctx.Doer.ID})
 	if err != nil {
 		ctx.ServerError("ListAccessTokens", err)
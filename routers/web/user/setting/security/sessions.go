@@ -0,0 +1,44 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package security
+
+import (
+	"errors"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/services/context"
+)
+
+// RevokeSession signs out a single session of the user
+func RevokeSession(ctx *context.Context) {
+	s, err := auth_model.GetUserSessionByID(ctx, ctx.FormInt64("id"))
+	if err != nil && !errors.Is(err, util.ErrNotExist) {
+		ctx.ServerError("GetUserSessionByID", err)
+		return
+	}
+	if s == nil || s.UID != ctx.Doer.ID {
+		ctx.JSONRedirect(setting.AppSubURL + "/user/settings/security")
+		return
+	}
+
+	if err := auth_model.RevokeUserSession(ctx, s); err != nil {
+		ctx.ServerError("RevokeUserSession", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("settings.revoke_session_success"))
+	ctx.JSONRedirect(setting.AppSubURL + "/user/settings/security")
+}
+
+// RevokeAllSessions signs out all sessions of the user, including the current one
+func RevokeAllSessions(ctx *context.Context) {
+	if err := auth_model.RevokeUserSessionsByUID(ctx, ctx.Doer.ID); err != nil {
+		ctx.ServerError("RevokeUserSessionsByUID", err)
+		return
+	}
+
+	ctx.JSONRedirect(setting.AppSubURL + "/user/login")
+}
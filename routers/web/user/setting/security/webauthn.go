@@ -45,8 +45,23 @@ func WebAuthnRegister(ctx *context.Context) {
 		ctx.ServerError("Unable to set session key for webauthnName", err)
 		return
 	}
+	if err := ctx.Session.Set("webauthnPasskey", form.Passkey); err != nil {
+		ctx.ServerError("Unable to set session key for webauthnPasskey", err)
+		return
+	}
+
+	var opts []webauthn.RegistrationOption
+	if form.Passkey {
+		// A passkey is stored on the authenticator together with the user handle
+		// and has to verify the user, so that it can replace the password.
+		opts = append(opts, webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			UserVerification:   protocol.VerificationRequired,
+		}))
+	}
 
-	credentialOptions, sessionData, err := wa.WebAuthn.BeginRegistration((*wa.User)(ctx.Doer))
+	credentialOptions, sessionData, err := wa.WebAuthn.BeginRegistration((*wa.User)(ctx.Doer), opts...)
 	if err != nil {
 		ctx.ServerError("Unable to BeginRegistration", err)
 		return
@@ -100,12 +115,14 @@ func WebauthnRegisterPost(ctx *context.Context) {
 	}
 
 	// Create the credential
-	_, err = auth.CreateCredential(ctx, ctx.Doer.ID, name, cred)
+	passkey, _ := ctx.Session.Get("webauthnPasskey").(bool)
+	_, err = auth.CreateCredential(ctx, ctx.Doer.ID, name, cred, passkey && cred.Flags.UserVerified)
 	if err != nil {
 		ctx.ServerError("CreateCredential", err)
 		return
 	}
 	_ = ctx.Session.Delete("webauthnName")
+	_ = ctx.Session.Delete("webauthnPasskey")
 
 	ctx.JSON(http.StatusCreated, cred)
 }
@@ -123,6 +140,20 @@ func WebauthnDelete(ctx *context.Context) {
 		return
 	}
 
+	// Users without a password sign in with their passkeys, the last one can't be removed
+	if cred.Discoverable && !ctx.Doer.IsPasswordSet() {
+		count, err := auth.CountDiscoverableWebAuthnCredentialsByUID(ctx, ctx.Doer.ID)
+		if err != nil {
+			ctx.ServerError("CountDiscoverableWebAuthnCredentialsByUID", err)
+			return
+		}
+		if count <= 1 {
+			ctx.Flash.Error(ctx.Tr("settings.webauthn_delete_last_passkey"))
+			ctx.JSONRedirect(setting.AppSubURL + "/user/settings/security")
+			return
+		}
+	}
+
 	if _, err := auth.DeleteCredential(ctx, form.ID, ctx.Doer.ID); err != nil {
 		ctx.ServerError("GetWebAuthnCredentialByID", err)
 		return
@@ -137,6 +137,11 @@ func webAuth(authMethod auth_service.Method) func(*context.Context) {
 	}
 }
 
+// isWebAuthnEnrollmentPath returns true for the pages a user needs to register a security key
+func isWebAuthnEnrollmentPath(path string) bool {
+	return strings.HasPrefix(path, "/user/settings/security") || path == "/user/logout" || path == "/user/events"
+}
+
 // verifyAuthWithOptions checks authentication according to options
 func verifyAuthWithOptions(options *common.VerifyOptions) func(ctx *context.Context) {
 	return func(ctx *context.Context) {
@@ -173,6 +178,26 @@ func verifyAuthWithOptions(options *common.VerifyOptions) func(ctx *context.Cont
 				ctx.Redirect(setting.AppSubURL + "/")
 				return
 			}
+
+			// Users who are required to use a security key or a passkey have to register one first,
+			// only access tokens can't be phished and keep working without it
+			isTokenAuth := ctx.IsBasicAuth && ctx.Data["IsApiToken"] == true
+			if !isTokenAuth && !isWebAuthnEnrollmentPath(ctx.Req.URL.Path) {
+				missing, err := auth_service.IsMissingRequiredWebAuthn(ctx, ctx.Doer)
+				if err != nil {
+					ctx.ServerError("IsMissingRequiredWebAuthn", err)
+					return
+				}
+				if missing {
+					if strings.HasPrefix(ctx.Req.UserAgent(), "git") {
+						ctx.Error(http.StatusUnauthorized, ctx.Locale.TrString("auth.webauthn_required"))
+						return
+					}
+					ctx.Flash.Warning(ctx.Tr("auth.webauthn_required"))
+					ctx.Redirect(setting.AppSubURL + "/user/settings/security")
+					return
+				}
+			}
 		}
 
 		// Redirect to dashboard (or alternate location) if user tries to visit any non-login page.
@@ -510,6 +535,8 @@ func registerRoutes(m *web.Route) {
 			m.Get("", auth.WebAuthn)
 			m.Get("/assertion", auth.WebAuthnLoginAssertion)
 			m.Post("/assertion", auth.WebAuthnLoginAssertionPost)
+			m.Get("/passkey", auth.WebAuthnPasskeyAssertion)
+			m.Post("/passkey", auth.WebAuthnPasskeyAssertionPost)
 		})
 	}, reqSignOut)
 
@@ -521,8 +548,12 @@ func registerRoutes(m *web.Route) {
 		// TODO manage redirection
 		m.Post("/authorize", web.Bind(forms.AuthorizationForm{}), auth.AuthorizeOAuth)
 	}, ignSignInAndCsrf, reqSignIn)
+	m.Combo("/login/oauth/device", reqSignIn).
+		Get(auth.DeviceVerificationOAuth).
+		Post(web.Bind(forms.GrantDeviceForm{}), auth.GrantDeviceOAuth)
 
 	m.Methods("GET, OPTIONS", "/login/oauth/userinfo", optionsCorsHandler(), ignSignInAndCsrf, auth.InfoOAuth)
+	m.Methods("POST, OPTIONS", "/login/oauth/device_authorization", optionsCorsHandler(), web.Bind(forms.DeviceAuthorizationForm{}), ignSignInAndCsrf, auth.DeviceAuthorizationOAuth)
 	m.Methods("POST, OPTIONS", "/login/oauth/access_token", optionsCorsHandler(), web.Bind(forms.AccessTokenForm{}), ignSignInAndCsrf, auth.AccessTokenOAuth)
 	m.Methods("GET, OPTIONS", "/login/oauth/keys", optionsCorsHandler(), ignSignInAndCsrf, auth.OIDCKeys)
 	m.Methods("POST, OPTIONS", "/login/oauth/introspect", optionsCorsHandler(), web.Bind(forms.IntrospectTokenForm{}), ignSignInAndCsrf, auth.IntrospectOAuth)
@@ -538,6 +569,7 @@ func registerRoutes(m *web.Route) {
 			m.Combo("").Get(user_setting.Account).Post(web.Bind(forms.ChangePasswordForm{}), user_setting.AccountPost)
 			m.Post("/email", web.Bind(forms.AddEmailForm{}), user_setting.EmailPost)
 			m.Post("/email/delete", user_setting.DeleteEmail)
+			m.Post("/remove_password", user_setting.RemovePasswordPost)
 			m.Post("/delete", user_setting.DeleteAccount)
 		})
 		m.Group("/appearance", func() {
@@ -565,6 +597,10 @@ func registerRoutes(m *web.Route) {
 				m.Post("/delete", security.DeleteOpenID)
 				m.Post("/toggle_visibility", security.ToggleOpenIDVisibility)
 			}, openIDSignInEnabled)
+			m.Group("/sessions", func() {
+				m.Post("/revoke", security.RevokeSession)
+				m.Post("/revoke_all", security.RevokeAllSessions)
+			})
 			m.Post("/account_link", linkAccountEnabled, security.DeleteAccountLink)
 		})
 		m.Group("/applications/oauth2", func() {
@@ -594,6 +630,46 @@ func registerRoutes(m *web.Route) {
 					m.Get("/preview", user_setting.PackagesRulePreview)
 				})
 			})
+			m.Group("/remotes", func() {
+				m.Group("/add", func() {
+					m.Get("", user_setting.PackagesRemoteAdd)
+					m.Post("", web.Bind(forms.PackageRemoteForm{}), user_setting.PackagesRemoteAddPost)
+				})
+				m.Group("/{id}", func() {
+					m.Get("", user_setting.PackagesRemoteEdit)
+					m.Post("", web.Bind(forms.PackageRemoteForm{}), user_setting.PackagesRemoteEditPost)
+				})
+			})
+			m.Group("/virtual", func() {
+				m.Group("/add", func() {
+					m.Get("", user_setting.PackagesVirtualRegistryAdd)
+					m.Post("", web.Bind(forms.PackageVirtualRegistryForm{}), user_setting.PackagesVirtualRegistryAddPost)
+				})
+				m.Group("/{id}", func() {
+					m.Get("", user_setting.PackagesVirtualRegistryEdit)
+					m.Post("", web.Bind(forms.PackageVirtualRegistryForm{}), user_setting.PackagesVirtualRegistryEditPost)
+				})
+			})
+			m.Group("/trusted_keys", func() {
+				m.Group("/add", func() {
+					m.Get("", user_setting.PackagesTrustedKeyAdd)
+					m.Post("", web.Bind(forms.PackageTrustedKeyForm{}), user_setting.PackagesTrustedKeyAddPost)
+				})
+				m.Group("/{id}", func() {
+					m.Get("", user_setting.PackagesTrustedKeyEdit)
+					m.Post("", web.Bind(forms.PackageTrustedKeyForm{}), user_setting.PackagesTrustedKeyEditPost)
+				})
+			})
+			m.Group("/protection", func() {
+				m.Group("/add", func() {
+					m.Get("", user_setting.PackagesProtectionRuleAdd)
+					m.Post("", web.Bind(forms.PackageProtectionRuleForm{}), user_setting.PackagesProtectionRuleAddPost)
+				})
+				m.Group("/{id}", func() {
+					m.Get("", user_setting.PackagesProtectionRuleEdit)
+					m.Post("", web.Bind(forms.PackageProtectionRuleForm{}), user_setting.PackagesProtectionRuleEditPost)
+				})
+			})
 			m.Group("/cargo", func() {
 				m.Post("/initialize", user_setting.InitializeCargoIndex)
 				m.Post("/rebuild", user_setting.RebuildCargoIndex)
@@ -647,6 +723,12 @@ func registerRoutes(m *web.Route) {
 			m.Get("/{provider}", auth.SignInOAuth)
 			m.Get("/{provider}/callback", auth.SignInOAuthCallback)
 		})
+		m.Group("/saml/{provider}", func() {
+			m.Get("", auth.SignInSAML)
+			m.Post("/acs", ignSignInAndCsrf, auth.SAMLAssertionConsumerService)
+			m.Get("/metadata", auth.SAMLMetadata)
+			m.Get("/slo", auth.SAMLSingleLogout)
+		})
 	})
 	// ***** END: User *****
 
@@ -739,6 +821,8 @@ func registerRoutes(m *web.Route) {
 			m.Combo("/{authid}").Get(admin.EditAuthSource).
 				Post(web.Bind(forms.AuthenticationForm{}), admin.EditAuthSourcePost)
 			m.Post("/{authid}/delete", admin.DeleteAuthSource)
+			m.Post("/{authid}/scim_token", admin.GenerateSCIMToken)
+			m.Post("/{authid}/scim_token/delete", admin.DeleteSCIMToken)
 		})
 
 		m.Group("/notices", func() {
@@ -747,6 +831,19 @@ func registerRoutes(m *web.Route) {
 			m.Post("/empty", admin.EmptyNotices)
 		})
 
+		m.Group("/audit", func() {
+			m.Get("", admin.Audit)
+			m.Get("/export", admin.AuditExport)
+		})
+
+		if setting.Federation.Enabled {
+			m.Group("/federation", func() {
+				m.Get("", admin.Federation)
+				m.Post("/rules", web.Bind(forms.AdminFederationHostRuleForm{}), admin.FederationHostRulePost)
+				m.Post("/rules/delete", admin.DeleteFederationHostRule)
+			})
+		}
+
 		m.Group("/applications", func() {
 			m.Get("", admin.Applications)
 			m.Post("/oauth2", web.Bind(forms.EditOAuth2ApplicationForm{}), admin.ApplicationsPost)
@@ -767,7 +864,7 @@ func registerRoutes(m *web.Route) {
 			addSettingsRunnersRoutes()
 			addSettingsVariablesRoutes()
 		})
-	}, adminReq, ctxDataSet("EnableOAuth2", setting.OAuth2.Enabled, "EnablePackages", setting.Packages.Enabled))
+	}, adminReq, ctxDataSet("EnableOAuth2", setting.OAuth2.Enabled, "EnablePackages", setting.Packages.Enabled, "EnableFederation", setting.Federation.Enabled))
 	// ***** END: Admin *****
 
 	m.Group("", func() {
@@ -919,6 +1016,15 @@ func registerRoutes(m *web.Route) {
 
 				m.Methods("GET,POST", "/delete", org.SettingsDelete)
 
+				m.Combo("/security").Get(org_setting.Security).
+					Post(web.Bind(forms.OrgTwoFactorPolicyForm{}), org_setting.SecurityPost)
+				m.Post("/security/ip_allowlist", web.Bind(forms.OrgIPAllowlistForm{}), org_setting.SecurityIPAllowlistPost)
+
+				m.Group("/audit", func() {
+					m.Get("", org_setting.Audit)
+					m.Get("/export", org_setting.AuditExport)
+				})
+
 				m.Group("/blocked_users", func() {
 					m.Get("", org_setting.BlockedUsers)
 					m.Post("/block", org_setting.BlockedUsersBlock)
@@ -938,6 +1044,46 @@ func registerRoutes(m *web.Route) {
 							m.Get("/preview", org.PackagesRulePreview)
 						})
 					})
+					m.Group("/remotes", func() {
+						m.Group("/add", func() {
+							m.Get("", org.PackagesRemoteAdd)
+							m.Post("", web.Bind(forms.PackageRemoteForm{}), org.PackagesRemoteAddPost)
+						})
+						m.Group("/{id}", func() {
+							m.Get("", org.PackagesRemoteEdit)
+							m.Post("", web.Bind(forms.PackageRemoteForm{}), org.PackagesRemoteEditPost)
+						})
+					})
+					m.Group("/virtual", func() {
+						m.Group("/add", func() {
+							m.Get("", org.PackagesVirtualRegistryAdd)
+							m.Post("", web.Bind(forms.PackageVirtualRegistryForm{}), org.PackagesVirtualRegistryAddPost)
+						})
+						m.Group("/{id}", func() {
+							m.Get("", org.PackagesVirtualRegistryEdit)
+							m.Post("", web.Bind(forms.PackageVirtualRegistryForm{}), org.PackagesVirtualRegistryEditPost)
+						})
+					})
+					m.Group("/trusted_keys", func() {
+						m.Group("/add", func() {
+							m.Get("", org.PackagesTrustedKeyAdd)
+							m.Post("", web.Bind(forms.PackageTrustedKeyForm{}), org.PackagesTrustedKeyAddPost)
+						})
+						m.Group("/{id}", func() {
+							m.Get("", org.PackagesTrustedKeyEdit)
+							m.Post("", web.Bind(forms.PackageTrustedKeyForm{}), org.PackagesTrustedKeyEditPost)
+						})
+					})
+					m.Group("/protection", func() {
+						m.Group("/add", func() {
+							m.Get("", org.PackagesProtectionRuleAdd)
+							m.Post("", web.Bind(forms.PackageProtectionRuleForm{}), org.PackagesProtectionRuleAddPost)
+						})
+						m.Group("/{id}", func() {
+							m.Get("", org.PackagesProtectionRuleEdit)
+							m.Post("", web.Bind(forms.PackageProtectionRuleForm{}), org.PackagesProtectionRuleEditPost)
+						})
+					})
 					m.Group("/cargo", func() {
 						m.Post("/initialize", org.InitializeCargoIndex)
 						m.Post("/rebuild", org.RebuildCargoIndex)
@@ -0,0 +1,41 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package asymkey
+
+import (
+	"context"
+
+	asymkey_model "code.gitea.io/gitea/models/asymkey"
+	audit_model "code.gitea.io/gitea/models/audit"
+	user_model "code.gitea.io/gitea/models/user"
+	audit_service "code.gitea.io/gitea/services/audit"
+)
+
+// AddGPGKey adds the armored GPG key to the user and records the added keys in the audit log
+func AddGPGKey(ctx context.Context, ownerID int64, content, token, signature string) ([]*asymkey_model.GPGKey, error) {
+	keys, err := asymkey_model.AddGPGKey(ctx, ownerID, content, token, signature)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		audit_service.Record(ctx, audit_model.ActionGPGKeyAdd, nil, ownerID, audit_service.GPGKeyTarget(key), nil)
+	}
+	return keys, nil
+}
+
+// DeleteGPGKey deletes a GPG key of the user and records it in the audit log
+func DeleteGPGKey(ctx context.Context, doer *user_model.User, id int64) error {
+	key, err := asymkey_model.GetGPGKeyForUserByID(ctx, doer.ID, id)
+	if err != nil {
+		if asymkey_model.IsErrGPGKeyNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := asymkey_model.DeleteGPGKey(ctx, doer, id); err != nil {
+		return err
+	}
+	audit_service.Record(ctx, audit_model.ActionGPGKeyDelete, doer, doer.ID, audit_service.GPGKeyTarget(key), nil)
+	return nil
+}
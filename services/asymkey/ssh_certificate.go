@@ -0,0 +1,71 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package asymkey
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	asymkey_model "code.gitea.io/gitea/models/asymkey"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/ssh"
+	"code.gitea.io/gitea/modules/util"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// ErrUserCADisabled is returned if certificates are requested while the certificate authority is disabled
+var ErrUserCADisabled = util.NewNotExistErrorf("the ssh certificate authority is disabled")
+
+// SignUserCertificate signs a short-lived certificate for a public key of the user with the username as principal.
+// The validity is limited to the maximum validity of the settings, a validity of 0 selects the maximum.
+// Deleting the principal of the user revokes all of their certificates.
+func SignUserCertificate(ctx context.Context, user *user_model.User, content string, validity time.Duration) (*gossh.Certificate, error) {
+	if !setting.SSH.UserCAEnabled || setting.SSH.Disabled {
+		return nil, ErrUserCADisabled
+	}
+	if validity <= 0 || validity > setting.SSH.UserCertificateMaxValidity {
+		validity = setting.SSH.UserCertificateMaxValidity
+	}
+
+	content, err := asymkey_model.CheckPublicKeyString(content)
+	if err != nil {
+		return nil, util.NewInvalidArgumentErrorf("invalid public key: %v", err)
+	}
+	key, _, _, _, err := gossh.ParseAuthorizedKey([]byte(content))
+	if err != nil {
+		return nil, util.NewInvalidArgumentErrorf("invalid public key: %v", err)
+	}
+	if _, ok := key.(*gossh.Certificate); ok {
+		return nil, util.NewInvalidArgumentErrorf("certificates can't be signed")
+	}
+
+	if err := ensureCertificatePrincipal(ctx, user); err != nil {
+		return nil, err
+	}
+
+	keyID := fmt.Sprintf("%s@%s", user.Name, setting.Domain)
+	return ssh.SignUserCertificate(key, keyID, user.Name, validity)
+}
+
+// ensureCertificatePrincipal adds the username as principal of the user, certificates are authenticated by this principal
+func ensureCertificatePrincipal(ctx context.Context, user *user_model.User) error {
+	key, err := asymkey_model.SearchPublicKeyByContentExact(ctx, user.Name)
+	if err == nil {
+		if key.Type != asymkey_model.KeyTypePrincipal || key.OwnerID != user.ID {
+			return util.NewAlreadyExistErrorf("the principal %q is used by another account", user.Name)
+		}
+		return nil
+	} else if !asymkey_model.IsErrKeyNotExist(err) {
+		return err
+	}
+
+	_, err = asymkey_model.AddPrincipalKey(ctx, user.ID, user.Name, 0)
+	if asymkey_model.IsErrKeyAlreadyExist(err) {
+		return util.NewAlreadyExistErrorf("the principal %q is used by another account", user.Name)
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package asymkey
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	asymkey_model "code.gitea.io/gitea/models/asymkey"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/ssh"
+	"code.gitea.io/gitea/modules/test"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func TestSignUserCertificate(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sshKey, err := gossh.NewPublicKey(publicKey)
+	require.NoError(t, err)
+	content := string(gossh.MarshalAuthorizedKey(sshKey))
+
+	_, err = SignUserCertificate(db.DefaultContext, user, content, 0)
+	require.ErrorIs(t, err, ErrUserCADisabled)
+
+	defer test.MockVariableValue(&setting.SSH.UserCAEnabled, true)()
+	defer test.MockVariableValue(&setting.SSH.UserCAKey, filepath.Join(t.TempDir(), "user-ca"))()
+	defer test.MockVariableValue(&setting.SSH.UserCertificateMaxValidity, time.Hour)()
+
+	cert, err := SignUserCertificate(db.DefaultContext, user, content, 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user2"}, cert.ValidPrincipals)
+	assert.Equal(t, sshKey.Marshal(), cert.Key.Marshal())
+	assert.LessOrEqual(t, int64(cert.ValidBefore), time.Now().Add(time.Hour).Unix())
+	assert.True(t, ssh.IsUserCA(cert.SignatureKey))
+
+	// the principal of the certificate authenticates the user
+	principal, err := asymkey_model.SearchPublicKeyByContentExact(db.DefaultContext, "user2")
+	require.NoError(t, err)
+	assert.EqualValues(t, asymkey_model.KeyTypePrincipal, principal.Type)
+	assert.Equal(t, user.ID, principal.OwnerID)
+
+	// certificates can't be signed again
+	certContent := string(gossh.MarshalAuthorizedKey(cert))
+	_, err = SignUserCertificate(db.DefaultContext, user, certContent, 0)
+	require.ErrorIs(t, err, util.ErrInvalidArgument)
+
+	// the principal can't be claimed by another account
+	_, err = SignUserCertificate(db.DefaultContext, &user_model.User{ID: 4, Name: "user2"}, content, 0)
+	require.ErrorIs(t, err, util.ErrAlreadyExist)
+}
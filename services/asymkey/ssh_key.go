@@ -7,10 +7,22 @@ import (
 	"context"
 
 	asymkey_model "code.gitea.io/gitea/models/asymkey"
+	audit_model "code.gitea.io/gitea/models/audit"
 	"code.gitea.io/gitea/models/db"
 	user_model "code.gitea.io/gitea/models/user"
+	audit_service "code.gitea.io/gitea/services/audit"
 )
 
+// AddPublicKey adds an SSH key to the user and records it in the audit log
+func AddPublicKey(ctx context.Context, ownerID int64, name, content string) (*asymkey_model.PublicKey, error) {
+	key, err := asymkey_model.AddPublicKey(ctx, ownerID, name, content, 0)
+	if err != nil {
+		return nil, err
+	}
+	audit_service.Record(ctx, audit_model.ActionPublicKeyAdd, nil, ownerID, audit_service.PublicKeyTarget(key), nil)
+	return key, nil
+}
+
 // DeletePublicKey deletes SSH key information both in database and authorized_keys file.
 func DeletePublicKey(ctx context.Context, doer *user_model.User, id int64) (err error) {
 	key, err := asymkey_model.GetPublicKeyByID(ctx, id)
@@ -42,6 +54,8 @@ func DeletePublicKey(ctx context.Context, doer *user_model.User, id int64) (err
 	}
 	committer.Close()
 
+	audit_service.Record(ctx, audit_model.ActionPublicKeyDelete, doer, key.OwnerID, audit_service.PublicKeyTarget(key), nil)
+
 	if key.Type == asymkey_model.KeyTypePrincipal {
 		return asymkey_model.RewriteAllPrincipalKeys(ctx)
 	}
@@ -0,0 +1,142 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package audit
+
+import (
+	"context"
+	"net"
+
+	asymkey_model "code.gitea.io/gitea/models/asymkey"
+	audit_model "code.gitea.io/gitea/models/audit"
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/forgefed"
+	git_model "code.gitea.io/gitea/models/git"
+	"code.gitea.io/gitea/models/organization"
+	repo_model "code.gitea.io/gitea/models/repo"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/web/middleware"
+)
+
+// Target is the object an audit event acts on
+type Target struct {
+	Type string
+	ID   int64
+	Name string
+}
+
+// UserTarget returns the target of an event on a user or an organization
+func UserTarget(u *user_model.User) Target {
+	if u.IsOrganization() {
+		return Target{Type: audit_model.TargetTypeOrganization, ID: u.ID, Name: u.Name}
+	}
+	return Target{Type: audit_model.TargetTypeUser, ID: u.ID, Name: u.Name}
+}
+
+// RepositoryTarget returns the target of an event on a repository
+func RepositoryTarget(repo *repo_model.Repository) Target {
+	return Target{Type: audit_model.TargetTypeRepository, ID: repo.ID, Name: repo.FullName()}
+}
+
+// AccessTokenTarget returns the target of an event on an access token
+func AccessTokenTarget(t *auth_model.AccessToken) Target {
+	return Target{Type: audit_model.TargetTypeAccessToken, ID: t.ID, Name: t.Name}
+}
+
+// PublicKeyTarget returns the target of an event on an SSH key
+func PublicKeyTarget(key *asymkey_model.PublicKey) Target {
+	return Target{Type: audit_model.TargetTypePublicKey, ID: key.ID, Name: key.Fingerprint}
+}
+
+// GPGKeyTarget returns the target of an event on a GPG key
+func GPGKeyTarget(key *asymkey_model.GPGKey) Target {
+	return Target{Type: audit_model.TargetTypeGPGKey, ID: key.ID, Name: key.KeyID}
+}
+
+// ProtectedBranchTarget returns the target of an event on a branch protection rule
+func ProtectedBranchTarget(repo *repo_model.Repository, pb *git_model.ProtectedBranch) Target {
+	return Target{Type: audit_model.TargetTypeProtectedBranch, ID: pb.ID, Name: repo.FullName() + ":" + pb.RuleName}
+}
+
+// TeamTarget returns the target of an event on a team
+func TeamTarget(team *organization.Team) Target {
+	return Target{Type: audit_model.TargetTypeTeam, ID: team.ID, Name: team.Name}
+}
+
+// FederationHostRuleTarget returns the target of an event on a federation host rule
+func FederationHostRuleTarget(rule *forgefed.FederationHostRule) Target {
+	return Target{Type: audit_model.TargetTypeFederationHostRule, ID: rule.ID, Name: rule.Pattern}
+}
+
+// Record persists a security-relevant event in the audit log and streams it to the configured
+// syslog server and webhook. Inside a transaction the event is only streamed once it is committed.
+// If doer is nil, the signed-in user of the request of the context is the actor.
+// ownerID is the user or organization whose audit log contains the event, 0 limits it to site administrators.
+// Failures are logged, they never fail the recorded action.
+func Record(ctx context.Context, action audit_model.Action, doer *user_model.User, ownerID int64, target Target, diff audit_model.Diff) {
+	if !setting.Audit.Enabled {
+		return
+	}
+
+	if doer == nil {
+		doer, _ = middleware.GetContextData(ctx)[middleware.ContextDataKeySignedUser].(*user_model.User)
+	}
+
+	e := &audit_model.Event{
+		Action:     action,
+		IPAddress:  remoteIP(ctx),
+		OwnerID:    ownerID,
+		TargetType: target.Type,
+		TargetID:   target.ID,
+		TargetName: target.Name,
+		Diff:       diff,
+	}
+	if doer != nil {
+		e.ActorID = doer.ID
+		e.ActorName = doer.Name
+	}
+
+	if err := audit_model.InsertEvent(ctx, e); err != nil {
+		log.Error("Unable to record audit event %s on %s %d: %v", action, target.Type, target.ID, err)
+		return
+	}
+	db.AfterTx(ctx, func() {
+		stream(e)
+	})
+}
+
+// remoteIP returns the IP address of the client of the request of the context
+func remoteIP(ctx context.Context) string {
+	addr := middleware.RemoteAddr(ctx)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// ToAPIEvent converts an audit event to its API format
+func ToAPIEvent(e *audit_model.Event) *api.AuditEvent {
+	ae := &api.AuditEvent{
+		ID:         e.ID,
+		Action:     string(e.Action),
+		ActorID:    e.ActorID,
+		ActorName:  e.ActorName,
+		IPAddress:  e.IPAddress,
+		OwnerID:    e.OwnerID,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		TargetName: e.TargetName,
+		Created:    e.CreatedUnix.AsTime(),
+	}
+	if len(e.Diff) > 0 {
+		ae.Diff = make(map[string]api.AuditChange, len(e.Diff))
+		for field, change := range e.Diff {
+			ae.Diff[field] = api.AuditChange{Old: change.Old, New: change.New}
+		}
+	}
+	return ae
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package audit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	_ "code.gitea.io/gitea/models"
+	audit_model "code.gitea.io/gitea/models/audit"
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/setting"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/test"
+	"code.gitea.io/gitea/modules/web/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	unittest.MainTest(m)
+}
+
+func requestContext(doer *user_model.User, remoteAddr string) context.Context {
+	ctx := middleware.WithContextData(db.DefaultContext)
+	middleware.GetContextData(ctx)[middleware.ContextDataKeySignedUser] = doer
+	return context.WithValue(ctx, middleware.RemoteAddrContextKey, remoteAddr)
+}
+
+func TestRecord(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	admin := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1})
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 3})
+
+	t.Run("ActorFromRequest", func(t *testing.T) {
+		Record(requestContext(admin, "192.0.2.1:4321"), audit_model.ActionUserAdminGrant, nil, 0, UserTarget(user), audit_model.Diff{
+			"is_admin": {Old: false, New: true},
+		})
+
+		e := unittest.AssertExistsAndLoadBean(t, &audit_model.Event{Action: audit_model.ActionUserAdminGrant})
+		assert.EqualValues(t, 1, e.ActorID)
+		assert.Equal(t, "user1", e.ActorName)
+		assert.Equal(t, "192.0.2.1", e.IPAddress)
+		assert.EqualValues(t, 0, e.OwnerID)
+		assert.Equal(t, audit_model.TargetTypeUser, e.TargetType)
+		assert.EqualValues(t, 2, e.TargetID)
+		assert.Equal(t, "user2", e.TargetName)
+		assert.Equal(t, audit_model.Diff{"is_admin": {Old: false, New: true}}, e.Diff)
+	})
+
+	t.Run("ExplicitActor", func(t *testing.T) {
+		Record(requestContext(admin, "[2001:db8::1]:4321"), audit_model.ActionRepoVisibility, user, repo.OwnerID, RepositoryTarget(repo), nil)
+
+		e := unittest.AssertExistsAndLoadBean(t, &audit_model.Event{Action: audit_model.ActionRepoVisibility})
+		assert.EqualValues(t, 2, e.ActorID)
+		assert.Equal(t, "2001:db8::1", e.IPAddress)
+		assert.EqualValues(t, 3, e.OwnerID)
+		assert.Equal(t, "org3/repo3", e.TargetName)
+	})
+
+	t.Run("OutsideOfRequest", func(t *testing.T) {
+		Record(db.DefaultContext, audit_model.ActionTeamMemberRemove, nil, 3, Target{Type: audit_model.TargetTypeTeam, ID: 1, Name: "Owners"}, nil)
+
+		e := unittest.AssertExistsAndLoadBean(t, &audit_model.Event{Action: audit_model.ActionTeamMemberRemove})
+		assert.EqualValues(t, 0, e.ActorID)
+		assert.Empty(t, e.ActorName)
+		assert.Empty(t, e.IPAddress)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		defer test.MockVariableValue(&setting.Audit.Enabled, false)()
+
+		Record(db.DefaultContext, audit_model.ActionUserDelete, admin, 0, UserTarget(user), nil)
+		unittest.AssertNotExistsBean(t, &audit_model.Event{Action: audit_model.ActionUserDelete})
+	})
+}
+
+func TestStreamWebhook(t *testing.T) {
+	var received *api.AuditEvent
+	var authorization string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	defer test.MockVariableValue(&setting.Audit.WebhookURL, s.URL)()
+	defer test.MockVariableValue(&setting.Audit.WebhookAuthorization, "Bearer secret")()
+	defer test.MockVariableValue(&webhookClient, s.Client())()
+
+	handler(ToAPIEvent(&audit_model.Event{
+		ID:         7,
+		Action:     audit_model.ActionAccessTokenCreate,
+		ActorID:    2,
+		ActorName:  "user2",
+		OwnerID:    2,
+		TargetType: audit_model.TargetTypeAccessToken,
+		TargetID:   1,
+		TargetName: "Token A",
+		Diff:       audit_model.Diff{"scope": {New: "all"}},
+	}))
+
+	require.NotNil(t, received)
+	assert.Equal(t, "Bearer secret", authorization)
+	assert.EqualValues(t, 7, received.ID)
+	assert.Equal(t, "user.access_token.create", received.Action)
+	assert.Equal(t, "Token A", received.TargetName)
+	assert.Equal(t, map[string]api.AuditChange{"scope": {New: "all"}}, received.Diff)
+}
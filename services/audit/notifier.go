@@ -0,0 +1,48 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package audit
+
+import (
+	"context"
+
+	audit_model "code.gitea.io/gitea/models/audit"
+	repo_model "code.gitea.io/gitea/models/repo"
+	user_model "code.gitea.io/gitea/models/user"
+	notify_service "code.gitea.io/gitea/services/notify"
+)
+
+type auditNotifier struct {
+	notify_service.NullNotifier
+}
+
+var _ notify_service.Notifier = &auditNotifier{}
+
+// NewNotifier creates a new notifier recording the audit events of repositories and users
+func NewNotifier() notify_service.Notifier {
+	return &auditNotifier{}
+}
+
+func (*auditNotifier) NewUserSignUp(ctx context.Context, newUser *user_model.User) {
+	Record(ctx, audit_model.ActionUserSignUp, newUser, 0, UserTarget(newUser), nil)
+}
+
+func (*auditNotifier) CreateRepository(ctx context.Context, doer, u *user_model.User, repo *repo_model.Repository) {
+	Record(ctx, audit_model.ActionRepoCreate, doer, repo.OwnerID, RepositoryTarget(repo), nil)
+}
+
+func (*auditNotifier) DeleteRepository(ctx context.Context, doer *user_model.User, repo *repo_model.Repository) {
+	Record(ctx, audit_model.ActionRepoDelete, doer, repo.OwnerID, RepositoryTarget(repo), nil)
+}
+
+func (*auditNotifier) RenameRepository(ctx context.Context, doer *user_model.User, repo *repo_model.Repository, oldRepoName string) {
+	Record(ctx, audit_model.ActionRepoRename, doer, repo.OwnerID, RepositoryTarget(repo), audit_model.Diff{
+		"name": {Old: oldRepoName, New: repo.Name},
+	})
+}
+
+func (*auditNotifier) TransferRepository(ctx context.Context, doer *user_model.User, repo *repo_model.Repository, oldOwnerName string) {
+	Record(ctx, audit_model.ActionRepoTransfer, doer, repo.OwnerID, RepositoryTarget(repo), audit_model.Diff{
+		"owner": {Old: oldOwnerName, New: repo.OwnerName},
+	})
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	audit_model "code.gitea.io/gitea/models/audit"
+	"code.gitea.io/gitea/modules/graceful"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/proxy"
+	"code.gitea.io/gitea/modules/queue"
+	"code.gitea.io/gitea/modules/setting"
+	api "code.gitea.io/gitea/modules/structs"
+	notify_service "code.gitea.io/gitea/services/notify"
+)
+
+// streamQueue delivers the recorded events to syslog and the webhook,
+// it's nil if streaming isn't configured
+var streamQueue *queue.WorkerPoolQueue[*api.AuditEvent]
+
+var (
+	syslogWriter  io.Writer
+	webhookClient *http.Client
+)
+
+// Init registers the audit notifier and starts streaming the audit events if it's configured
+func Init() error {
+	if !setting.Audit.Enabled {
+		return nil
+	}
+	notify_service.RegisterNotifier(NewNotifier())
+
+	if !setting.Audit.SyslogEnabled && setting.Audit.WebhookURL == "" {
+		return nil
+	}
+	if setting.Audit.SyslogEnabled {
+		w, err := newSyslogWriter(setting.Audit.SyslogNetwork, setting.Audit.SyslogAddress, setting.Audit.SyslogTag)
+		if err != nil {
+			return fmt.Errorf("unable to connect to the audit syslog: %w", err)
+		}
+		syslogWriter = w
+	}
+	if setting.Audit.WebhookURL != "" {
+		webhookClient = &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				Proxy: proxy.Proxy(),
+			},
+		}
+	}
+
+	streamQueue = queue.CreateSimpleQueue(graceful.GetManager().ShutdownContext(), "audit_stream", handler)
+	if streamQueue == nil {
+		return fmt.Errorf("unable to create audit_stream queue")
+	}
+	go graceful.GetManager().RunWithCancel(streamQueue)
+	return nil
+}
+
+func stream(e *audit_model.Event) {
+	if streamQueue == nil {
+		return
+	}
+	if err := streamQueue.Push(ToAPIEvent(e)); err != nil {
+		log.Error("Unable to push audit event %d to the stream queue: %v", e.ID, err)
+	}
+}
+
+func handler(items ...*api.AuditEvent) []*api.AuditEvent {
+	for _, e := range items {
+		payload, err := json.Marshal(e)
+		if err != nil {
+			log.Error("Unable to marshal audit event %d: %v", e.ID, err)
+			continue
+		}
+		if syslogWriter != nil {
+			if _, err := syslogWriter.Write(payload); err != nil {
+				log.Error("Unable to write audit event %d to syslog: %v", e.ID, err)
+			}
+		}
+		if webhookClient != nil {
+			if err := deliverWebhook(graceful.GetManager().ShutdownContext(), payload); err != nil {
+				log.Error("Unable to deliver audit event %d to the webhook: %v", e.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func deliverWebhook(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, setting.Audit.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Forgejo-Audit")
+	if setting.Audit.WebhookAuthorization != "" {
+		req.Header.Set("Authorization", setting.Audit.WebhookAuthorization)
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
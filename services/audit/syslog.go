@@ -0,0 +1,15 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+//go:build !windows
+
+package audit
+
+import (
+	"io"
+	"log/syslog"
+)
+
+func newSyslogWriter(network, address, tag string) (io.Writer, error) {
+	return syslog.Dial(network, address, syslog.LOG_NOTICE|syslog.LOG_AUTH, tag)
+}
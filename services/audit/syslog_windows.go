@@ -0,0 +1,13 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package audit
+
+import (
+	"errors"
+	"io"
+)
+
+func newSyslogWriter(network, address, tag string) (io.Writer, error) {
+	return nil, errors.New("syslog isn't supported on Windows")
+}
@@ -0,0 +1,207 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	audit_model "code.gitea.io/gitea/models/audit"
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/organization"
+	access_model "code.gitea.io/gitea/models/perm/access"
+	repo_model "code.gitea.io/gitea/models/repo"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+	audit_service "code.gitea.io/gitea/services/audit"
+	"code.gitea.io/gitea/services/mailer"
+)
+
+// PropagateAccessToken stores the access token and its scope in the data store.
+// It returns false if the token can't be used anymore.
+func PropagateAccessToken(ctx context.Context, store DataStore, t *auth_model.AccessToken) (bool, error) {
+	if t.IsExpired() {
+		log.Trace("AccessToken[%d] of user[%d] has expired", t.ID, t.UID)
+		return false, nil
+	}
+	if err := t.LoadRepositoryIDs(ctx); err != nil {
+		return false, err
+	}
+
+	store.GetData()["IsApiToken"] = true
+	store.GetData()["ApiTokenScope"] = t.Scope
+	store.GetData()["ApiToken"] = t
+	return true, nil
+}
+
+// CreateAccessTokenOptions holds the options to create a personal access token
+type CreateAccessTokenOptions struct {
+	Name  string
+	Scope auth_model.AccessTokenScope
+	// Expires is the point in time the token expires, the zero time means it never expires
+	Expires time.Time
+	// Organization restricts a fine-grained token to all repositories of the organization
+	Organization string
+	// Repositories restricts a fine-grained token to the repositories given as "owner/name"
+	Repositories []string
+	// Permissions makes the token fine-grained and replaces the scope
+	Permissions auth_model.AccessTokenPermissions
+}
+
+// CreateAccessToken validates the options against the token policy of the instance and creates the access token
+func CreateAccessToken(ctx context.Context, doer *user_model.User, opts CreateAccessTokenOptions) (*auth_model.AccessToken, error) {
+	t := &auth_model.AccessToken{
+		UID:   doer.ID,
+		Name:  opts.Name,
+		Scope: opts.Scope,
+	}
+
+	if opts.Expires.IsZero() {
+		if setting.AccessTokenRequireExpiry || setting.AccessTokenMaxExpiry > 0 {
+			return nil, util.NewInvalidArgumentErrorf("an expiry date is required")
+		}
+	} else {
+		now := time.Now()
+		if !opts.Expires.After(now) {
+			return nil, util.NewInvalidArgumentErrorf("expiry date must be in the future")
+		}
+		if setting.AccessTokenMaxExpiry > 0 && opts.Expires.After(now.Add(setting.AccessTokenMaxExpiry)) {
+			return nil, util.NewInvalidArgumentErrorf("expiry date must not be later than %s", now.Add(setting.AccessTokenMaxExpiry).Format(time.DateOnly))
+		}
+		t.ExpiresUnix = timeutil.TimeStamp(opts.Expires.Unix())
+	}
+
+	if len(opts.Permissions) > 0 {
+		if (opts.Organization == "") == (len(opts.Repositories) == 0) {
+			return nil, util.NewInvalidArgumentErrorf("a fine-grained token must be restricted to either an organization or a list of repositories")
+		}
+
+		if opts.Organization != "" {
+			org, err := organization.GetOrgByName(ctx, opts.Organization)
+			if err != nil {
+				if organization.IsErrOrgNotExist(err) {
+					return nil, util.NewInvalidArgumentErrorf("organization %s does not exist", opts.Organization)
+				}
+				return nil, err
+			}
+			isMember, err := organization.IsOrganizationMember(ctx, org.ID, doer.ID)
+			if err != nil {
+				return nil, err
+			}
+			if !isMember {
+				return nil, util.NewInvalidArgumentErrorf("you are not a member of organization %s", org.Name)
+			}
+			t.OrgID = org.ID
+		} else {
+			t.RepoIDs = make([]int64, 0, len(opts.Repositories))
+			for _, fullName := range opts.Repositories {
+				ownerName, repoName, ok := strings.Cut(fullName, "/")
+				if !ok {
+					return nil, util.NewInvalidArgumentErrorf("invalid repository %s, expected owner/name", fullName)
+				}
+				repo, err := repo_model.GetRepositoryByOwnerAndName(ctx, ownerName, repoName)
+				if err != nil {
+					if repo_model.IsErrRepoNotExist(err) {
+						return nil, util.NewInvalidArgumentErrorf("repository %s does not exist", fullName)
+					}
+					return nil, err
+				}
+				perm, err := access_model.GetUserRepoPermission(ctx, repo, doer)
+				if err != nil {
+					return nil, err
+				}
+				if !perm.HasAccess() {
+					return nil, util.NewInvalidArgumentErrorf("repository %s does not exist", fullName)
+				}
+				t.RepoIDs = append(t.RepoIDs, repo.ID)
+			}
+		}
+
+		scope, err := opts.Permissions.Scope()
+		if err != nil {
+			return nil, err
+		}
+		t.Permissions = opts.Permissions
+		t.Scope = scope
+	} else if opts.Organization != "" || len(opts.Repositories) > 0 {
+		return nil, util.NewInvalidArgumentErrorf("restricting a token to repositories requires permissions")
+	}
+
+	if t.Scope == "" {
+		return nil, util.NewInvalidArgumentErrorf("access token must have a scope")
+	}
+	if _, err := t.Scope.Normalize(); err != nil {
+		return nil, util.NewInvalidArgumentErrorf("invalid access token scope: %v", err)
+	}
+
+	if err := auth_model.NewAccessToken(ctx, t); err != nil {
+		return nil, err
+	}
+	audit_service.Record(ctx, audit_model.ActionAccessTokenCreate, nil, doer.ID, audit_service.AccessTokenTarget(t), audit_model.Diff{
+		"scope": {New: string(t.Scope)},
+	})
+	return t, nil
+}
+
+// DeleteAccessToken deletes an access token of the user
+func DeleteAccessToken(ctx context.Context, owner *user_model.User, id int64) error {
+	t, err := auth_model.GetAccessTokenByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if t.UID != owner.ID {
+		return auth_model.ErrAccessTokenNotExist{}
+	}
+	if err := auth_model.DeleteAccessTokenByID(ctx, id, owner.ID); err != nil {
+		return err
+	}
+	audit_service.Record(ctx, audit_model.ActionAccessTokenDelete, nil, owner.ID, audit_service.AccessTokenTarget(t), nil)
+	return nil
+}
+
+// NotifyExpiringAccessTokens sends an email to the owners of access tokens which expire soon
+func NotifyExpiringAccessTokens(ctx context.Context) error {
+	if setting.AccessTokenExpiryWarning <= 0 {
+		return nil
+	}
+
+	tokens, err := auth_model.FindExpiringAccessTokens(ctx, timeutil.TimeStampNow().AddDuration(setting.AccessTokenExpiryWarning))
+	if err != nil {
+		return err
+	}
+
+	// tokens are ordered by owner
+	for len(tokens) > 0 {
+		n := 1
+		for n < len(tokens) && tokens[n].UID == tokens[0].UID {
+			n++
+		}
+		userTokens := tokens[:n]
+		tokens = tokens[n:]
+
+		ids := make([]int64, 0, len(userTokens))
+		for _, t := range userTokens {
+			ids = append(ids, t.ID)
+		}
+
+		u, err := user_model.GetUserByID(ctx, userTokens[0].UID)
+		if err != nil {
+			if !user_model.IsErrUserNotExist(err) {
+				return err
+			}
+		} else if err := mailer.SendAccessTokenExpiry(ctx, u, userTokens); err != nil {
+			log.Error("SendAccessTokenExpiry: %v", err)
+			continue
+		}
+
+		if err := auth_model.SetAccessTokensExpiryNotified(ctx, ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
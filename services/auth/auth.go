@@ -83,6 +83,9 @@ func handleSignIn(resp http.ResponseWriter, req *http.Request, sess SessionStore
 	if err != nil {
 		log.Error(fmt.Sprintf("Error setting session: %v", err))
 	}
+	if err := StartUserSession(req, sess, user); err != nil {
+		log.Error(fmt.Sprintf("Error tracking user session: %v", err))
+	}
 
 	// Language setting of the user overwrites the one previously set
 	// If the user does not have a locale set, we save the current one.
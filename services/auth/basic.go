@@ -96,13 +96,15 @@ func (b *Basic) Verify(req *http.Request, w http.ResponseWriter, store DataStore
 			return nil, err
 		}
 
+		if ok, err := PropagateAccessToken(req.Context(), store, token); err != nil || !ok {
+			return nil, err
+		}
+
 		token.UpdatedUnix = timeutil.TimeStampNow()
 		if err = auth_model.UpdateAccessToken(req.Context(), token); err != nil {
 			log.Error("UpdateAccessToken:  %v", err)
 		}
 
-		store.GetData()["IsApiToken"] = true
-		store.GetData()["ApiTokenScope"] = token.Scope
 		return u, nil
 	} else if !auth_model.IsErrAccessTokenNotExist(err) && !auth_model.IsErrAccessTokenEmpty(err) {
 		log.Error("GetAccessTokenBySha: %v", err)
@@ -132,6 +134,13 @@ func (b *Basic) Verify(req *http.Request, w http.ResponseWriter, store DataStore
 		return nil, err
 	}
 
+	// passwords and TOTP codes can be phished, users who are required to use a security key have to use a token
+	if required, err := IsWebAuthnRequired(req.Context(), u); err != nil {
+		return nil, err
+	} else if required {
+		return nil, util.NewPermissionDeniedErrorf("password authentication is not allowed, use an access token")
+	}
+
 	if skipper, ok := source.Cfg.(LocalTwoFASkipper); !ok || !skipper.IsSkipLocalTwoFA() {
 		if err := validateTOTP(req, u); err != nil {
 			return nil, err
@@ -130,7 +130,8 @@ func parseToken(req *http.Request) (string, bool) {
 
 // userIDFromToken returns the user id corresponding to the OAuth token.
 // It will set 'IsApiToken' to true if the token is an API token and
-// set 'ApiTokenScope' to the scope of the access token
+// set 'ApiTokenScope' to the scope of the access token.
+// Expired personal access tokens are rejected.
 func (o *OAuth2) userIDFromToken(ctx context.Context, tokenSHA string, store DataStore) int64 {
 	// Let's see if token is valid.
 	if strings.Contains(tokenSHA, ".") {
@@ -164,12 +165,16 @@ func (o *OAuth2) userIDFromToken(ctx context.Context, tokenSHA string, store Dat
 		}
 		return 0
 	}
+	if ok, err := PropagateAccessToken(ctx, store, t); err != nil || !ok {
+		if err != nil {
+			log.Error("PropagateAccessToken: %v", err)
+		}
+		return 0
+	}
 	t.UpdatedUnix = timeutil.TimeStampNow()
 	if err = auth_model.UpdateAccessToken(ctx, t); err != nil {
 		log.Error("UpdateAccessToken: %v", err)
 	}
-	store.GetData()["IsApiToken"] = true
-	store.GetData()["ApiTokenScope"] = t.Scope
 	return t.UID
 }
 
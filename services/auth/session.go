@@ -55,6 +55,13 @@ func (s *Session) Verify(req *http.Request, w http.ResponseWriter, store DataSto
 		return nil, nil
 	}
 
+	if ok, err := verifyUserSession(req, sess, user); err != nil {
+		log.Error("verifyUserSession: %v", err)
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+
 	log.Trace("Session Authorization: Logged in user %-v", user)
 	return user, nil
 }
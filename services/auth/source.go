@@ -37,6 +37,14 @@ func DeleteSource(ctx context.Context, source *auth.Source) error {
 		}
 	}
 
-	_, err = db.GetEngine(ctx).ID(source.ID).Delete(new(auth.Source))
-	return err
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if err := db.DeleteBeans(ctx,
+			&auth.SCIMToken{SourceID: source.ID},
+			&auth.SCIMGroup{SourceID: source.ID},
+		); err != nil {
+			return err
+		}
+		_, err := db.GetEngine(ctx).ID(source.ID).Delete(new(auth.Source))
+		return err
+	})
 }
@@ -0,0 +1,79 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package saml
+
+import (
+	"fmt"
+	"net/url"
+
+	"code.gitea.io/gitea/modules/cache"
+	"code.gitea.io/gitea/modules/saml"
+	"code.gitea.io/gitea/modules/setting"
+)
+
+// pendingRequestTTL is the time in seconds the user has to authenticate at the identity provider
+const pendingRequestTTL = 10 * 60
+
+// URL returns the URL of an endpoint of this source, e.g. "acs", "slo" or "metadata"
+func (source *Source) URL(endpoint string) string {
+	return setting.AppURL + "user/saml/" + url.PathEscape(source.authSource.Name) + "/" + endpoint
+}
+
+// ServiceProvider returns the SAML service provider of this source
+func (source *Source) ServiceProvider() (*saml.ServiceProvider, error) {
+	sp := &saml.ServiceProvider{
+		EntityID:                    source.URL("metadata"),
+		AssertionConsumerServiceURL: source.URL("acs"),
+		SingleLogoutServiceURL:      source.URL("slo"),
+		NameIDFormat:                source.NameIDFormat,
+		SignRequests:                source.SignRequests,
+		IdPEntityID:                 source.IdPEntityID,
+		IdPSSOURL:                   source.IdPSSOURL,
+		IdPSLOURL:                   source.IdPSLOURL,
+		AllowSHA1:                   source.AllowSHA1,
+	}
+
+	var err error
+	if sp.IdPCertificates, err = saml.ParseCertificates(source.IdPCertificate); err != nil {
+		return nil, fmt.Errorf("invalid identity provider certificate: %w", err)
+	}
+	if source.SPPrivateKey != "" {
+		if sp.Key, err = saml.ParsePrivateKey(source.SPPrivateKey); err != nil {
+			return nil, fmt.Errorf("invalid service provider private key: %w", err)
+		}
+	}
+	if source.SPCertificate != "" {
+		certs, err := saml.ParseCertificates(source.SPCertificate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid service provider certificate: %w", err)
+		}
+		sp.Certificate = certs[0]
+	}
+	return sp, nil
+}
+
+func pendingRequestKey(sourceID int64, requestID string) string {
+	return fmt.Sprintf("saml_request_%d_%s", sourceID, requestID)
+}
+
+// StorePendingRequest remembers an authentication request and the URL to redirect to after the sign in.
+// The response is posted cross-site by the identity provider, so it can not be matched using the session.
+func (source *Source) StorePendingRequest(requestID, redirectTo string) error {
+	return cache.GetCache().Put(pendingRequestKey(source.authSource.ID, requestID), redirectTo, pendingRequestTTL)
+}
+
+// ConsumePendingRequest returns the redirect URL of a pending authentication request and forgets the request,
+// so every response can only be used once
+func (source *Source) ConsumePendingRequest(requestID string) (string, bool) {
+	key := pendingRequestKey(source.authSource.ID, requestID)
+	value := cache.GetCache().Get(key)
+	if value == nil {
+		return "", false
+	}
+	if err := cache.GetCache().Delete(key); err != nil {
+		return "", false
+	}
+	redirectTo, ok := value.(string)
+	return redirectTo, ok
+}
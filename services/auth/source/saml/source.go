@@ -0,0 +1,78 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package saml
+
+import (
+	"code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/secret"
+	"code.gitea.io/gitea/modules/setting"
+)
+
+// Source holds configuration for the SAML 2.0 login source.
+type Source struct {
+	IdPEntityID    string
+	IdPSSOURL      string // receives authentication requests with the HTTP-Redirect binding
+	IdPSLOURL      string // receives logout responses with the HTTP-Redirect binding
+	IdPCertificate string // PEM encoded signing certificates of the identity provider
+
+	SPCertificate       string // PEM encoded certificate published in the service provider metadata
+	SPPrivateKey        string `json:"-"`
+	SPPrivateKeyEncrypt string
+	SignRequests        bool
+	NameIDFormat        string
+	// AllowSHA1 accepts SHA-1 signatures of identity providers which don't support SHA-256
+	AllowSHA1 bool `json:",omitempty"`
+
+	AttributeUsername string // falls back to the NameID if empty
+	AttributeEmail    string
+	AttributeFullName string
+	AttributeGroups   string
+	GroupTeamMap      string
+	// GroupTeamMapRemoval removes users from mapped teams of groups they are no longer a member of
+	GroupTeamMapRemoval bool
+	SkipLocalTwoFA      bool `json:",omitempty"`
+	IconURL             string
+
+	// reference to the authSource
+	authSource *auth.Source
+}
+
+// FromDB fills up a SAML source from serialized format.
+func (source *Source) FromDB(bs []byte) error {
+	err := json.UnmarshalHandleDoubleEncode(bs, &source)
+	if err != nil {
+		return err
+	}
+	if source.SPPrivateKeyEncrypt != "" {
+		source.SPPrivateKey, err = secret.DecryptSecret(setting.SecretKey, source.SPPrivateKeyEncrypt)
+		source.SPPrivateKeyEncrypt = ""
+	}
+	return err
+}
+
+// ToDB exports a SAML source to a serialized format.
+func (source *Source) ToDB() ([]byte, error) {
+	encrypted, err := secret.EncryptSecret(setting.SecretKey, source.SPPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	stored := *source
+	stored.SPPrivateKeyEncrypt = encrypted
+	return json.Marshal(stored)
+}
+
+// SetAuthSource sets the related AuthSource
+func (source *Source) SetAuthSource(authSource *auth.Source) {
+	source.authSource = authSource
+}
+
+// IsSkipLocalTwoFA returns if this source should skip local 2fa
+func (source *Source) IsSkipLocalTwoFA() bool {
+	return source.SkipLocalTwoFA
+}
+
+func init() {
+	auth.RegisterTypeConfig(auth.SAML, &Source{})
+}
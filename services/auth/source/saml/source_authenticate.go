@@ -0,0 +1,99 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package saml
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"code.gitea.io/gitea/models/auth"
+	user_model "code.gitea.io/gitea/models/user"
+	auth_module "code.gitea.io/gitea/modules/auth"
+	"code.gitea.io/gitea/modules/container"
+	"code.gitea.io/gitea/modules/optional"
+	"code.gitea.io/gitea/modules/saml"
+	source_service "code.gitea.io/gitea/services/auth/source"
+	user_service "code.gitea.io/gitea/services/user"
+)
+
+// Authenticate returns the user of a validated assertion, and creates a local user on the first sign in.
+// The user profile and team memberships are updated from the mapped attributes.
+func (source *Source) Authenticate(ctx context.Context, assertion *saml.Assertion) (*user_model.User, error) {
+	user := &user_model.User{
+		LoginType:   auth.SAML,
+		LoginSource: source.authSource.ID,
+		LoginName:   assertion.NameID,
+	}
+	has, err := user_model.GetUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	fullName := source.attribute(assertion, source.AttributeFullName)
+	if has {
+		if user.ProhibitLogin {
+			return nil, user_model.ErrUserProhibitLogin{UID: user.ID, Name: user.Name}
+		}
+		opts := &user_service.UpdateOptions{}
+		if !user.IsActive {
+			// users provisioned by SCIM are deactivated by the identity provider and must stay inactive
+			if _, err := auth.GetSCIMToken(ctx, source.authSource.ID); err == nil {
+				return nil, user_model.ErrUserInactive{UID: user.ID, Name: user.Name}
+			} else if !auth.IsErrSCIMTokenNotExist(err) {
+				return nil, err
+			}
+			opts.IsActive = optional.Some(true)
+		}
+		if fullName != "" && fullName != user.FullName {
+			opts.FullName = optional.Some(fullName)
+		}
+		if opts.IsActive.Has() || opts.FullName.Has() {
+			if err := user_service.UpdateUser(ctx, user, opts); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		username := source.attribute(assertion, source.AttributeUsername)
+		if username == "" {
+			username = assertion.NameID
+		}
+		email := source.attribute(assertion, source.AttributeEmail)
+		if email == "" {
+			email = fmt.Sprintf("%s@localhost.local", username)
+		}
+		user = &user_model.User{
+			LowerName:   strings.ToLower(username),
+			Name:        username,
+			FullName:    fullName,
+			Email:       email,
+			LoginType:   auth.SAML,
+			LoginSource: source.authSource.ID,
+			LoginName:   assertion.NameID,
+		}
+		if err := user_model.CreateUser(ctx, user, &user_model.CreateUserOverwriteOptions{IsActive: optional.Some(true)}); err != nil {
+			return nil, err
+		}
+	}
+
+	if source.AttributeGroups != "" && (source.GroupTeamMap != "" || source.GroupTeamMapRemoval) {
+		groupTeamMapping, err := auth_module.UnmarshalGroupTeamMapping(source.GroupTeamMap)
+		if err != nil {
+			return user, err
+		}
+		groups := container.SetOf(assertion.Attributes[source.AttributeGroups]...)
+		if err := source_service.SyncGroupsToTeams(ctx, user, groups, groupTeamMapping, source.GroupTeamMapRemoval); err != nil {
+			return user, err
+		}
+	}
+
+	return user, nil
+}
+
+func (source *Source) attribute(assertion *saml.Assertion, name string) string {
+	if name == "" {
+		return ""
+	}
+	return strings.TrimSpace(assertion.Attribute(name))
+}
@@ -0,0 +1,144 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/web/middleware"
+)
+
+// UserSessionKey is the key of the ID of the tracked user session in the session data,
+// signed-in sessions which aren't tracked are treated as revoked
+const UserSessionKey = "user_session_id"
+
+// userSessionSeenInterval is how often the last seen time of a user session is updated
+const userSessionSeenInterval = time.Minute
+
+// CurrentUserSessionID returns the ID of the user session tracked for the session, 0 if there is none
+func CurrentUserSessionID(sess SessionStore) int64 {
+	if sess == nil {
+		return 0
+	}
+	id, _ := sess.Get(UserSessionKey).(int64)
+	return id
+}
+
+// NewUserSession tracks a new signed-in session of the user, its ID has to be stored under
+// UserSessionKey in the session data together with the ID of the user
+func NewUserSession(req *http.Request, user *user_model.User) (int64, error) {
+	s := &auth_model.UserSession{
+		UID:         user.ID,
+		AuthTokenID: rememberedAuthTokenID(req, user),
+		UserAgent:   req.UserAgent(),
+		IPAddress:   remoteIP(req),
+	}
+	if err := auth_model.CreateUserSession(req.Context(), s); err != nil {
+		return 0, err
+	}
+	return s.ID, nil
+}
+
+// StartUserSession tracks the session as a new signed-in session of the user,
+// e.g. to keep the current session signed in after all sessions of the user were revoked.
+func StartUserSession(req *http.Request, sess SessionStore, user *user_model.User) error {
+	if sess == nil {
+		return nil
+	}
+	id, err := NewUserSession(req, user)
+	if err != nil {
+		return err
+	}
+	return sess.Set(UserSessionKey, id)
+}
+
+// RevokeCurrentUserSession revokes the user session tracked for the session, e.g. when it is signed out
+func RevokeCurrentUserSession(ctx context.Context, sess SessionStore) error {
+	id := CurrentUserSessionID(sess)
+	if id == 0 {
+		return nil
+	}
+	s, err := auth_model.GetUserSessionByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return auth_model.RevokeUserSession(ctx, s)
+}
+
+// verifyUserSession checks that the signed-in session is tracked as a user session which wasn't revoked
+func verifyUserSession(req *http.Request, sess SessionStore, user *user_model.User) (bool, error) {
+	ctx := req.Context()
+	ipAddress := remoteIP(req)
+
+	var s *auth_model.UserSession
+	id := CurrentUserSessionID(sess)
+	if id != 0 {
+		var err error
+		if s, err = auth_model.GetUserSessionByID(ctx, id); err != nil {
+			if !errors.Is(err, util.ErrNotExist) {
+				return false, err
+			}
+			s = nil
+		}
+	}
+	// the user sessions are tracked from the sign in, so sessions without one can't be trusted
+	if s == nil || s.UID != user.ID || s.IsExpired() {
+		log.Trace("Session Authorization: user session %d of user[%d] was revoked", id, user.ID)
+		_ = sess.Delete("uid")
+		_ = sess.Delete(UserSessionKey)
+		return false, nil
+	}
+
+	// the long-term authentication token set along with the sign in is sent with the following requests
+	if s.AuthTokenID == 0 {
+		if authTokenID := rememberedAuthTokenID(req, user); authTokenID != 0 {
+			if err := auth_model.UpdateUserSessionAuthToken(ctx, s, authTokenID); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	if s.LastSeenUnix.Add(int64(userSessionSeenInterval.Seconds())) <= timeutil.TimeStampNow() || s.IPAddress != ipAddress {
+		if err := auth_model.UpdateUserSessionLastSeen(ctx, s, ipAddress); err != nil {
+			log.Error("UpdateUserSessionLastSeen: %v", err)
+		}
+	}
+	return true, nil
+}
+
+// rememberedAuthTokenID returns the ID of the long-term authentication token of the user
+// sent along with the request, 0 if there is none
+func rememberedAuthTokenID(req *http.Request, user *user_model.User) int64 {
+	lookupKey, _, found := strings.Cut(middleware.GetSiteCookie(req, setting.CookieRememberName), ":")
+	if !found {
+		return 0
+	}
+	authToken, err := auth_model.FindAuthToken(req.Context(), lookupKey)
+	if err != nil || authToken.UID != user.ID {
+		return 0
+	}
+	return authToken.ID
+}
+
+func remoteIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
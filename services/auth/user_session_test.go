@@ -0,0 +1,68 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"net/http"
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSessionStore map[any]any
+
+func (s testSessionStore) Get(key any) any {
+	return s[key]
+}
+
+func (s testSessionStore) Set(key, value any) error {
+	s[key] = value
+	return nil
+}
+
+func (s testSessionStore) Delete(key any) error {
+	delete(s, key)
+	return nil
+}
+
+func TestVerifyUserSession(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	req, err := http.NewRequest("GET", "/", nil)
+	require.NoError(t, err)
+	req.RemoteAddr = "192.0.2.1:1234"
+
+	t.Run("Tracked", func(t *testing.T) {
+		sess := testSessionStore{"uid": user.ID}
+		require.NoError(t, StartUserSession(req, sess, user))
+		s := unittest.AssertExistsAndLoadBean(t, &auth_model.UserSession{ID: CurrentUserSessionID(sess)})
+		assert.Equal(t, "192.0.2.1", s.IPAddress)
+
+		ok, err := verifyUserSession(req, sess, user)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		require.NoError(t, auth_model.RevokeUserSession(req.Context(), s))
+		ok, err = verifyUserSession(req, sess, user)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Nil(t, sess.Get("uid"))
+	})
+
+	t.Run("Untracked", func(t *testing.T) {
+		// e.g. a session which was signed in before all sessions of the user were revoked
+		sess := testSessionStore{"uid": user.ID}
+		ok, err := verifyUserSession(req, sess, user)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Nil(t, sess.Get("uid"))
+		unittest.AssertNotExistsBean(t, &auth_model.UserSession{UID: user.ID})
+	})
+}
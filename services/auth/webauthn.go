@@ -0,0 +1,39 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/organization"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/setting"
+)
+
+// IsWebAuthnRequired returns true if the user has to use a phishing-resistant second factor,
+// which is the case for members of organizations if [security].ORG_MEMBERS_REQUIRE_WEBAUTHN is set
+func IsWebAuthnRequired(ctx context.Context, u *user_model.User) (bool, error) {
+	if !setting.OrgMembersRequireWebAuthn || u.Type != user_model.UserTypeIndividual {
+		return false, nil
+	}
+	count, err := organization.GetOrganizationCount(ctx, u)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// IsMissingRequiredWebAuthn returns true if the user has to use a security key or a passkey but hasn't registered one
+func IsMissingRequiredWebAuthn(ctx context.Context, u *user_model.User) (bool, error) {
+	required, err := IsWebAuthnRequired(ctx, u)
+	if err != nil || !required {
+		return false, err
+	}
+	has, err := auth_model.HasWebAuthnRegistrationsByUID(ctx, u.ID)
+	if err != nil {
+		return false, err
+	}
+	return !has, nil
+}
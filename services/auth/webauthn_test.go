@@ -0,0 +1,40 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsMissingRequiredWebAuthn(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	// user2 is a member of organizations and has no security key
+	user2 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	// user32 is no member of an organization
+	user32 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 32})
+
+	missing, err := IsMissingRequiredWebAuthn(db.DefaultContext, user2)
+	require.NoError(t, err)
+	assert.False(t, missing)
+
+	defer test.MockVariableValue(&setting.OrgMembersRequireWebAuthn, true)()
+
+	missing, err = IsMissingRequiredWebAuthn(db.DefaultContext, user2)
+	require.NoError(t, err)
+	assert.True(t, missing)
+
+	required, err := IsWebAuthnRequired(db.DefaultContext, user32)
+	require.NoError(t, err)
+	assert.False(t, required)
+}
@@ -426,9 +426,10 @@ func (ctx *APIContext) NotFoundOrServerError(logMsg string, errCheck func(error)
 	ctx.Error(http.StatusInternalServerError, "NotFoundOrServerError", logMsg)
 }
 
-// IsUserSiteAdmin returns true if current user is a site admin
+// IsUserSiteAdmin returns true if current user is a site admin.
+// Fine-grained access tokens never grant the privileges of a site admin.
 func (ctx *APIContext) IsUserSiteAdmin() bool {
-	return ctx.IsSigned && ctx.Doer.IsAdmin
+	return ctx.IsSigned && ctx.Doer.IsAdmin && FineGrainedAccessToken(ctx.Data) == nil
 }
 
 // IsUserRepoAdmin returns true if current user is admin in current repo
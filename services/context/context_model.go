@@ -7,9 +7,10 @@ import (
 	"code.gitea.io/gitea/models/unit"
 )
 
-// IsUserSiteAdmin returns true if current user is a site admin
+// IsUserSiteAdmin returns true if current user is a site admin.
+// Fine-grained access tokens never grant the privileges of a site admin.
 func (ctx *Context) IsUserSiteAdmin() bool {
-	return ctx.IsSigned && ctx.Doer.IsAdmin
+	return ctx.IsSigned && ctx.Doer.IsAdmin && FineGrainedAccessToken(ctx.Data) == nil
 }
 
 // IsUserRepoAdmin returns true if current user is admin in current repo
@@ -0,0 +1,79 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package context
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"code.gitea.io/gitea/models/organization"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unit"
+	user_model "code.gitea.io/gitea/models/user"
+
+	"xorm.io/builder"
+)
+
+// IsRequestIPAllowed returns false if the owner is an organization whose IP allowlist doesn't allow
+// the address the request was sent from. Anonymous requests, site administrators and the Actions
+// user are never restricted.
+func IsRequestIPAllowed(ctx context.Context, req *http.Request, doer, owner *user_model.User) (bool, error) {
+	if doer == nil || doer.IsAdmin || doer.IsActions() || owner == nil || !owner.IsOrganization() {
+		return true, nil
+	}
+
+	return organization.IsIPAllowed(ctx, owner.ID, RemoteIP(req))
+}
+
+// RemoteIP returns the IP address the request was sent from
+func RemoteIP(req *http.Request) string {
+	ip, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return ip
+}
+
+// deniedOrgsCondition returns a condition excluding the repositories of organizations whose IP allowlist doesn't
+// allow the address of the request sent by the user, it's nil if the user isn't restricted
+func deniedOrgsCondition(ctx context.Context, doer *user_model.User) (builder.Cond, error) {
+	ip := organization.RequestIP(ctx, doer)
+	if ip == "" {
+		return nil, nil
+	}
+	orgIDs, err := organization.FindOrgIDsDenyingIP(ctx, ip)
+	if err != nil || len(orgIDs) == 0 {
+		return nil, err
+	}
+	return builder.NotIn("`repository`.owner_id", orgIDs), nil
+}
+
+// LimitRepoSearchByIPAllowlist limits a repository search of the user sending the request to the repositories
+// anonymous users can see and the repositories of organizations whose IP allowlist allows the address of the request
+func LimitRepoSearchByIPAllowlist(ctx context.Context, doer *user_model.User, opts *repo_model.SearchRepoOptions) error {
+	cond, err := deniedOrgsCondition(ctx, doer)
+	if err != nil || cond == nil {
+		return err
+	}
+
+	if opts.LimitCond != nil {
+		cond = builder.And(opts.LimitCond, cond)
+	}
+	opts.LimitCond = cond
+	return nil
+}
+
+// LimitCodeSearchByIPAllowlist removes the repositories a code search of the user sending the request can't cover
+// because the IP allowlist of the organization owning them doesn't allow the address of the request
+func LimitCodeSearchByIPAllowlist(ctx context.Context, doer *user_model.User, repoIDs []int64) ([]int64, error) {
+	cond, err := deniedOrgsCondition(ctx, doer)
+	if err != nil || cond == nil || len(repoIDs) == 0 {
+		return repoIDs, err
+	}
+
+	return repo_model.SearchRepositoryIDsByCondition(ctx, builder.In("`repository`.id", repoIDs).And(
+		builder.Or(repo_model.AccessibleRepositoryCondition(nil, unit.TypeCode), cond),
+	))
+}
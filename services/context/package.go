@@ -7,14 +7,17 @@ import (
 	"fmt"
 	"net/http"
 
+	auth_model "code.gitea.io/gitea/models/auth"
 	"code.gitea.io/gitea/models/organization"
 	packages_model "code.gitea.io/gitea/models/packages"
 	"code.gitea.io/gitea/models/perm"
+	repo_model "code.gitea.io/gitea/models/repo"
 	"code.gitea.io/gitea/models/unit"
 	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/structs"
 	"code.gitea.io/gitea/modules/templates"
+	packages_service "code.gitea.io/gitea/services/packages"
 )
 
 // Package contains owner, access mode and optional the package descriptor
@@ -87,11 +90,64 @@ func packageAssignment(ctx *packageAssignmentCtx, errCb func(int, string, any))
 			errCb(http.StatusInternalServerError, "GetPackageDescriptor", err)
 			return pkg
 		}
+
+		// the package may inherit additional permissions from its linked repository
+		repoAccessMode, err := packages_service.RepositoryAccessMode(ctx, ctx.Doer, pkg.Descriptor.Package)
+		if err != nil {
+			errCb(http.StatusInternalServerError, "RepositoryAccessMode", err)
+			return pkg
+		}
+		if repoAccessMode > pkg.AccessMode {
+			pkg.AccessMode = repoAccessMode
+		}
+	}
+
+	pkg.AccessMode, err = LimitPackageAccessModeByToken(ctx.Base, pkg.Owner, pkg.AccessMode)
+	if err != nil {
+		errCb(http.StatusInternalServerError, "LimitPackageAccessModeByToken", err)
 	}
 
 	return pkg
 }
 
+// LimitPackageAccessModeByToken limits the access mode for the packages of the owner to the packages permission
+// of the fine-grained personal access token of the request. The token must be restricted to the owner or one of its repositories.
+func LimitPackageAccessModeByToken(ctx *Base, owner *user_model.User, accessMode perm.AccessMode) (perm.AccessMode, error) {
+	token := FineGrainedAccessToken(ctx.Data)
+	if token == nil {
+		return accessMode, nil
+	}
+
+	anonymous, err := determineAccessMode(ctx, &Package{Owner: owner}, nil)
+	if err != nil {
+		return perm.AccessModeNone, err
+	}
+
+	canAccess := token.OrgID > 0 && token.OrgID == owner.ID
+	if token.OrgID == 0 {
+		repos, err := repo_model.GetRepositoriesMapByIDs(ctx, token.RepoIDs)
+		if err != nil {
+			return perm.AccessModeNone, err
+		}
+		for _, repo := range repos {
+			if repo.OwnerID == owner.ID {
+				canAccess = true
+				break
+			}
+		}
+	}
+	if !canAccess {
+		return anonymous, nil
+	}
+
+	return max(min(accessMode, token.Permissions[auth_model.AccessTokenPermissionPackages]), anonymous), nil
+}
+
+// DeterminePackageAccessMode returns the access mode of the doer for the packages of the owner
+func DeterminePackageAccessMode(ctx *Base, owner, doer *user_model.User) (perm.AccessMode, error) {
+	return determineAccessMode(ctx, &Package{Owner: owner}, doer)
+}
+
 func determineAccessMode(ctx *Base, pkg *Package, doer *user_model.User) (perm.AccessMode, error) {
 	if setting.Service.RequireSignInView && (doer == nil || doer.IsGhost()) {
 		return perm.AccessModeNone, nil
@@ -106,9 +162,17 @@ func determineAccessMode(ctx *Base, pkg *Package, doer *user_model.User) (perm.A
 	if pkg.Owner.IsOrganization() {
 		org := organization.OrgFromUser(pkg.Owner)
 
+		// Requests from addresses outside the IP allowlist of the organization get the access of anonymous users
+		allowed, err := IsRequestIPAllowed(ctx, ctx.Req, doer, pkg.Owner)
+		if err != nil {
+			return accessMode, err
+		}
+		if !allowed {
+			doer = nil
+		}
+
 		if doer != nil && !doer.IsGhost() {
 			// 1. If user is logged in, check all team packages permissions
-			var err error
 			accessMode, err = org.GetOrgUserMaxAuthorizeLevel(ctx, doer.ID)
 			if err != nil {
 				return accessMode, err
@@ -4,12 +4,17 @@
 package context
 
 import (
+	"context"
 	"net/http"
 
 	auth_model "code.gitea.io/gitea/models/auth"
+	access_model "code.gitea.io/gitea/models/perm/access"
 	repo_model "code.gitea.io/gitea/models/repo"
 	"code.gitea.io/gitea/models/unit"
 	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/web/middleware"
+
+	"xorm.io/builder"
 )
 
 // RequireRepoAdmin returns a middleware for requiring repository admin permission
@@ -147,3 +152,56 @@ func CheckRepoScopedToken(ctx *Context, repo *repo_model.Repository, level auth_
 		}
 	}
 }
+
+// FineGrainedAccessToken returns the fine-grained personal access token the request is authenticated with or nil
+func FineGrainedAccessToken(data middleware.ContextData) *auth_model.AccessToken {
+	token, ok := data["ApiToken"].(*auth_model.AccessToken)
+	if !ok || !token.IsFineGrained() {
+		return nil
+	}
+	return token
+}
+
+// LimitRepoPermissionByToken limits the permission for the repository to the repositories and permissions
+// granted by the fine-grained personal access token of the request.
+// The token never grants less than anonymous users have.
+func LimitRepoPermissionByToken(ctx context.Context, data middleware.ContextData, repo *repo_model.Repository, p *access_model.Permission) error {
+	token := FineGrainedAccessToken(data)
+	if token == nil {
+		return nil
+	}
+
+	anonymous, err := access_model.GetUserRepoPermission(ctx, repo, nil)
+	if err != nil {
+		return err
+	}
+
+	if !token.CanAccessRepository(repo.ID, repo.OwnerID) {
+		*p = anonymous
+		return nil
+	}
+
+	p.LimitUnitsMode(token.Permissions.UnitAccessMode, anonymous)
+	return nil
+}
+
+// LimitRepoSearchByToken limits a repository search to the repositories anonymous users can see and the repositories
+// of the fine-grained personal access token of the request. Only the token condition is set here, the search adds
+// the public repositories to it as documented for SearchRepoOptions.LimitCond.
+func LimitRepoSearchByToken(data middleware.ContextData, opts *repo_model.SearchRepoOptions) {
+	token := FineGrainedAccessToken(data)
+	if token == nil {
+		return
+	}
+
+	var cond builder.Cond
+	if token.OrgID > 0 {
+		cond = builder.Eq{"`repository`.owner_id": token.OrgID}
+	} else {
+		cond = builder.In("`repository`.id", token.RepoIDs)
+	}
+	if opts.LimitCond != nil {
+		cond = builder.And(opts.LimitCond, cond)
+	}
+	opts.LimitCond = cond
+}
@@ -375,6 +375,10 @@ func repoAssignment(ctx *Context, repo *repo_model.Repository) {
 		ctx.ServerError("GetUserRepoPermission", err)
 		return
 	}
+	if err = LimitRepoPermissionByToken(ctx, ctx.Data, repo, &ctx.Repo.Permission); err != nil {
+		ctx.ServerError("LimitRepoPermissionByToken", err)
+		return
+	}
 
 	// Check access.
 	if !ctx.Repo.Permission.HasAccess() {
@@ -29,6 +29,8 @@ import (
 	api "code.gitea.io/gitea/modules/structs"
 	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/services/gitdiff"
+
+	"golang.org/x/crypto/ssh"
 )
 
 // ToEmail convert models.EmailAddress to api.Email
@@ -254,6 +256,29 @@ func ToPublicKey(apiLink string, key *asymkey_model.PublicKey) *api.PublicKey {
 	}
 }
 
+// ToUserSession converts auth.UserSession to api.UserSession
+func ToUserSession(s *auth.UserSession) *api.UserSession {
+	return &api.UserSession{
+		ID:        s.ID,
+		UserAgent: s.UserAgent,
+		IPAddress: s.IPAddress,
+		Created:   s.CreatedUnix.AsTime(),
+		LastSeen:  s.LastSeenUnix.AsTime(),
+	}
+}
+
+// ToSSHCertificate converts an SSH certificate to API format
+func ToSSHCertificate(cert *ssh.Certificate) *api.SSHCertificate {
+	return &api.SSHCertificate{
+		Certificate: strings.TrimSpace(string(ssh.MarshalAuthorizedKey(cert))),
+		KeyID:       cert.KeyId,
+		Serial:      cert.Serial,
+		Principals:  cert.ValidPrincipals,
+		ValidAfter:  time.Unix(int64(cert.ValidAfter), 0),
+		ValidBefore: time.Unix(int64(cert.ValidBefore), 0),
+	}
+}
+
 // ToGPGKey converts models.GPGKey to api.GPGKey
 func ToGPGKey(key *asymkey_model.GPGKey) *api.GPGKey {
 	subkeys := make([]*api.GPGKey, len(key.SubsKey))
@@ -461,6 +486,47 @@ func ToOAuth2Application(app *auth.OAuth2Application) *api.OAuth2Application {
 	}
 }
 
+// ToAccessToken convert from auth.AccessToken to api.AccessToken, the token itself is never included
+func ToAccessToken(ctx context.Context, t *auth.AccessToken) (*api.AccessToken, error) {
+	apiToken := &api.AccessToken{
+		ID:             t.ID,
+		Name:           t.Name,
+		TokenLastEight: t.TokenLastEight,
+		Scopes:         t.Scope.StringSlice(),
+	}
+	if t.HasExpiry() {
+		apiToken.ExpiresAt = t.ExpiresUnix.AsTimePtr()
+	}
+	if !t.IsFineGrained() {
+		return apiToken, nil
+	}
+
+	apiToken.Permissions = t.Permissions.ToStringMap()
+	if t.OrgID > 0 {
+		org, err := user_model.GetUserByID(ctx, t.OrgID)
+		if err != nil {
+			return nil, err
+		}
+		apiToken.Organization = org.Name
+		return apiToken, nil
+	}
+
+	if err := t.LoadRepositoryIDs(ctx); err != nil {
+		return nil, err
+	}
+	repos, err := repo_model.GetRepositoriesMapByIDs(ctx, t.RepoIDs)
+	if err != nil {
+		return nil, err
+	}
+	apiToken.Repositories = make([]string, 0, len(repos))
+	for _, repoID := range t.RepoIDs {
+		if repo, ok := repos[repoID]; ok {
+			apiToken.Repositories = append(apiToken.Repositories, repo.FullName())
+		}
+	}
+	return apiToken, nil
+}
+
 // ToLFSLock convert a LFSLock to api.LFSLock
 func ToLFSLock(ctx context.Context, l *git_model.LFSLock) *api.LFSLock {
 	u, err := user_model.GetUserByID(ctx, l.OwnerID)
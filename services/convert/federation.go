@@ -0,0 +1,33 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package convert
+
+import (
+	"code.gitea.io/gitea/models/forgefed"
+	api "code.gitea.io/gitea/modules/structs"
+)
+
+// ToFederationHost converts a forgefed.FederationHost to an api.FederationHost
+func ToFederationHost(host *forgefed.FederationHost, action forgefed.FederationHostAction) *api.FederationHost {
+	return &api.FederationHost{
+		ID:             host.ID,
+		Host:           host.HostFqdn,
+		Software:       string(host.NodeInfo.SoftwareName),
+		Action:         action.String(),
+		LatestActivity: host.LatestActivity,
+		Created:        host.Created.AsTime(),
+	}
+}
+
+// ToFederationHostRule converts a forgefed.FederationHostRule to an api.FederationHostRule
+func ToFederationHostRule(rule *forgefed.FederationHostRule) *api.FederationHostRule {
+	return &api.FederationHostRule{
+		ID:      rule.ID,
+		Pattern: rule.Pattern,
+		Action:  rule.Action.String(),
+		Reason:  rule.Reason,
+		Created: rule.CreatedUnix.AsTime(),
+		Updated: rule.UpdatedUnix.AsTime(),
+	}
+}
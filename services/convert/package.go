@@ -51,3 +51,35 @@ func ToPackageFile(pfd *packages.PackageFileDescriptor) *api.PackageFile {
 		HashSHA512: pfd.Blob.HashSHA512,
 	}
 }
+
+// ToPackageAttestation converts packages.PackageAttestation to api.PackageAttestation
+func ToPackageAttestation(ctx context.Context, pa *packages.PackageAttestation, doer *user_model.User) (*api.PackageAttestation, error) {
+	creator, err := user_model.GetPossibleUserByID(ctx, pa.CreatorID)
+	if err != nil {
+		if !user_model.IsErrUserNotExist(err) {
+			return nil, err
+		}
+		creator = user_model.NewGhostUser()
+	}
+
+	return &api.PackageAttestation{
+		ID:            pa.ID,
+		Creator:       ToUser(ctx, creator, doer),
+		PredicateType: pa.PredicateType,
+		Verified:      pa.IsVerified,
+		KeyID:         pa.KeyID,
+		Envelope:      pa.Envelope,
+		CreatedAt:     pa.CreatedUnix.AsTime(),
+	}, nil
+}
+
+// ToPackageVulnerability converts packages.PackageAdvisory to api.PackageVulnerability
+func ToPackageVulnerability(pa *packages.PackageAdvisory) *api.PackageVulnerability {
+	return &api.PackageVulnerability{
+		ID:         pa.AdvisoryID,
+		Aliases:    pa.Aliases,
+		Summary:    pa.Summary,
+		Severity:   pa.Severity,
+		ModifiedAt: pa.ModifiedUnix.AsTime(),
+	}
+}
@@ -8,15 +8,19 @@ import (
 	"time"
 
 	"code.gitea.io/gitea/models"
+	auth_model "code.gitea.io/gitea/models/auth"
 	git_model "code.gitea.io/gitea/models/git"
 	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/models/webhook"
 	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/services/auth"
+	"code.gitea.io/gitea/services/federation"
 	"code.gitea.io/gitea/services/migrations"
 	mirror_service "code.gitea.io/gitea/services/mirror"
+	org_service "code.gitea.io/gitea/services/org"
 	packages_cleanup_service "code.gitea.io/gitea/services/packages/cleanup"
+	packages_vulnerability_service "code.gitea.io/gitea/services/packages/vulnerability"
 	repo_service "code.gitea.io/gitea/services/repository"
 	archiver_service "code.gitea.io/gitea/services/repository/archiver"
 )
@@ -156,6 +160,56 @@ func registerCleanupPackages() {
 	})
 }
 
+func registerScanPackageVulnerabilities() {
+	RegisterTaskFatal("scan_package_vulnerabilities", &BaseConfig{
+		Enabled:    true,
+		RunAtStart: false,
+		Schedule:   "@midnight",
+	}, func(ctx context.Context, _ *user_model.User, _ Config) error {
+		return packages_vulnerability_service.ScanTask(ctx)
+	})
+}
+
+func registerNotifyExpiringAccessTokens() {
+	RegisterTaskFatal("notify_expiring_access_tokens", &BaseConfig{
+		Enabled:    true,
+		RunAtStart: false,
+		Schedule:   "@midnight",
+	}, func(ctx context.Context, _ *user_model.User, _ Config) error {
+		return auth.NotifyExpiringAccessTokens(ctx)
+	})
+}
+
+func registerRemoveOrgMembersWithoutTwoFactor() {
+	RegisterTaskFatal("remove_org_members_without_two_factor", &BaseConfig{
+		Enabled:    true,
+		RunAtStart: false,
+		Schedule:   "@every 1h",
+	}, func(ctx context.Context, _ *user_model.User, _ Config) error {
+		return org_service.RemoveMembersWithoutTwoFactor(ctx)
+	})
+}
+
+func registerDeleteExpiredUserSessions() {
+	RegisterTaskFatal("delete_expired_user_sessions", &BaseConfig{
+		Enabled:    true,
+		RunAtStart: false,
+		Schedule:   "@midnight",
+	}, func(ctx context.Context, _ *user_model.User, _ Config) error {
+		return auth_model.DeleteExpiredUserSessions(ctx)
+	})
+}
+
+func registerRetryFederationDeliveries() {
+	RegisterTaskFatal("retry_federation_deliveries", &BaseConfig{
+		Enabled:    true,
+		RunAtStart: true,
+		Schedule:   "@every 5m",
+	}, func(ctx context.Context, _ *user_model.User, _ Config) error {
+		return federation.RetryDeliveries(ctx)
+	})
+}
+
 func initBasicTasks() {
 	if setting.Mirror.Enabled {
 		registerUpdateMirrorTask()
@@ -169,7 +223,14 @@ func initBasicTasks() {
 		registerUpdateMigrationPosterID()
 	}
 	registerCleanupHookTaskTable()
+	registerNotifyExpiringAccessTokens()
+	registerRemoveOrgMembersWithoutTwoFactor()
+	registerDeleteExpiredUserSessions()
+	if setting.Federation.Enabled {
+		registerRetryFederationDeliveries()
+	}
 	if setting.Packages.Enabled {
 		registerCleanupPackages()
+		registerScanPackageVulnerabilities()
 	}
 }
@@ -0,0 +1,191 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package federation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"code.gitea.io/gitea/models/forgefed"
+	"code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/activitypub"
+	"code.gitea.io/gitea/modules/graceful"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/queue"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+	notify_service "code.gitea.io/gitea/services/notify"
+
+	ap "github.com/go-ap/activitypub"
+	"github.com/go-ap/jsonld"
+)
+
+const (
+	// maxDeliveryAttempts is how often the delivery of an activity is attempted before it is given up
+	maxDeliveryAttempts = 10
+	// deliveryRetryDelay is the delay before the first retry of a failed delivery, it doubles with every attempt
+	deliveryRetryDelay = time.Minute
+)
+
+var deliveryQueue *queue.WorkerPoolQueue[int64]
+
+// Init starts delivering the activities published by local users
+func Init() error {
+	notify_service.RegisterNotifier(NewNotifier())
+
+	deliveryQueue = queue.CreateUniqueQueue(graceful.GetManager().ShutdownContext(), "federation_delivery", deliveryHandler)
+	if deliveryQueue == nil {
+		return fmt.Errorf("unable to create federation_delivery queue")
+	}
+	go graceful.GetManager().RunWithCancel(deliveryQueue)
+	return nil
+}
+
+func deliveryHandler(ids ...int64) []int64 {
+	ctx := graceful.GetManager().ShutdownContext()
+	for _, id := range ids {
+		if err := deliver(ctx, id); err != nil {
+			log.Error("Unable to deliver the activity of delivery %d: %v", id, err)
+		}
+	}
+	return nil
+}
+
+func enqueueDeliveries(deliveries []*forgefed.Delivery) {
+	for _, d := range deliveries {
+		if err := deliveryQueue.Push(d.ID); err != nil {
+			log.Error("Unable to push delivery %d to the federation_delivery queue: %v", d.ID, err)
+		}
+	}
+}
+
+// RetryDeliveries queues the deliveries whose next attempt is due
+func RetryDeliveries(ctx context.Context) error {
+	if !setting.Federation.Enabled {
+		return nil
+	}
+	ids, err := forgefed.FindDueDeliveryIDs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		if err := deliveryQueue.Push(id); err != nil && !errors.Is(err, queue.ErrAlreadyInQueue) {
+			return err
+		}
+	}
+	return nil
+}
+
+// publish stores the activity in the outbox of the user and schedules its delivery to the inboxes,
+// the returned deliveries have to be queued once the transaction of ctx is committed.
+// The actor of the activity is the user unless it is already set.
+func publish(ctx context.Context, doer *user.User, activity *ap.Activity, inboxes ...string) ([]*forgefed.Delivery, error) {
+	if activity.Actor == nil {
+		activity.Actor = ap.IRI(doer.APActorID())
+	}
+	return forgefed.CreateOutboxActivity(ctx, &forgefed.OutboxActivity{UserID: doer.ID, Type: string(activity.Type)}, func(id int64) ([]byte, error) {
+		activity.ID = ap.IRI(fmt.Sprintf("%s/outbox/%d", doer.APActorID(), id))
+		return jsonld.WithContext(jsonld.IRI(ap.ActivityBaseURI)).Marshal(activity)
+	}, recipientsOf(activity), inboxes)
+}
+
+// recipientsOf returns the IRIs of the actors and collections the activity is addressed to,
+// the blind recipients aren't included as the outbox doesn't list the activity for them
+func recipientsOf(activity *ap.Activity) []string {
+	recipients := make([]string, 0, len(activity.To)+len(activity.CC)+len(activity.Audience))
+	for _, items := range []ap.ItemCollection{activity.To, activity.CC, activity.Audience} {
+		for _, item := range items {
+			if item == nil {
+				continue
+			}
+			if iri := item.GetLink().String(); iri != "" && !slices.Contains(recipients, iri) {
+				recipients = append(recipients, iri)
+			}
+		}
+	}
+	return recipients
+}
+
+// deliver posts the activity of the delivery to the inbox, failed deliveries are retried with an
+// exponential back-off until they are given up after maxDeliveryAttempts
+func deliver(ctx context.Context, id int64) error {
+	d, err := forgefed.GetDeliveryByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if d.NextAttemptUnix > timeutil.TimeStampNow() {
+		return nil
+	}
+
+	a, err := forgefed.GetOutboxActivityByID(ctx, d.ActivityID)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			return forgefed.DeleteDelivery(ctx, d)
+		}
+		return err
+	}
+	doer, err := user.GetUserByID(ctx, a.UserID)
+	if err != nil {
+		if user.IsErrUserNotExist(err) {
+			return forgefed.DeleteDelivery(ctx, d)
+		}
+		return err
+	}
+
+	if err := checkHostOfURI(ctx, d.Inbox); err != nil {
+		if errors.Is(err, ErrHostBlocked) {
+			log.Info("Not delivering activity %d to the blocked inbox %s", a.ID, d.Inbox)
+			return forgefed.DeleteDelivery(ctx, d)
+		}
+		return err
+	}
+
+	err = post(ctx, doer, []byte(a.Content), d.Inbox)
+	if err == nil {
+		return forgefed.DeleteDelivery(ctx, d)
+	}
+	d.Attempts++
+	if d.Attempts >= maxDeliveryAttempts {
+		log.Warn("Giving up delivering activity %d to %s after %d attempts: %v", a.ID, d.Inbox, d.Attempts, err)
+		return forgefed.DeleteDelivery(ctx, d)
+	}
+	d.LastError = err.Error()
+	d.NextAttemptUnix = timeutil.TimeStampNow().AddDuration(deliveryRetryDelay << (d.Attempts - 1))
+	return forgefed.UpdateDeliveryAttempt(ctx, d)
+}
+
+// post sends the activity to the inbox signed with the key of the user
+func post(ctx context.Context, doer *user.User, content []byte, inbox string) error {
+	clientFactory, err := activitypub.GetClientFactory(ctx)
+	if err != nil {
+		return err
+	}
+	client, err := clientFactory.WithKeys(ctx, doer, doer.APActorID()+"#main-key")
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(content, inbox)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
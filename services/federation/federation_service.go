@@ -136,6 +136,9 @@ func CreateFederationHostFromAP(ctx context.Context, actorID fm.ActorID) (*forge
 
 func GetFederationHostForURI(ctx context.Context, actorURI string) (*forgefed.FederationHost, error) {
 	log.Info("Input was: %v", actorURI)
+	if err := checkHostOfURI(ctx, actorURI); err != nil {
+		return nil, err
+	}
 	rawActorID, err := fm.NewActorID(actorURI)
 	if err != nil {
 		return nil, err
@@ -222,8 +225,12 @@ func CreateUserFromAP(ctx context.Context, personID fm.PersonID, federationHostI
 	return &newUser, &federatedUser, nil
 }
 
-// Create or update a list of FollowingRepo structs
+// Create or update a list of FollowingRepo structs, the repositories followed or no longer followed are notified
 func StoreFollowingRepoList(ctx context.Context, localRepoID int64, followingRepoList []string) (int, string, error) {
+	localRepo, err := repo.GetRepositoryByID(ctx, localRepoID)
+	if err != nil {
+		return http.StatusNotFound, "Unknown repository", err
+	}
 	followingRepos := make([]*repo.FollowingRepo, 0, len(followingRepoList))
 	for _, uri := range followingRepoList {
 		federationHost, err := GetFederationHostForURI(ctx, uri)
@@ -241,15 +248,30 @@ func StoreFollowingRepoList(ctx context.Context, localRepoID int64, followingRep
 		followingRepos = append(followingRepos, &followingRepo)
 	}
 
-	if err := repo.StoreFollowingRepos(ctx, localRepoID, followingRepos); err != nil {
+	if err := storeFollowingRepos(ctx, localRepo, followingRepos); err != nil {
 		return 0, "", err
 	}
 
 	return 0, "", nil
 }
 
-func DeleteFollowingRepos(ctx context.Context, localRepoID int64) error {
-	return repo.StoreFollowingRepos(ctx, localRepoID, []*repo.FollowingRepo{})
+// DeleteFollowingRepos stops following the repositories of other instances, localRepo may already be deleted
+func DeleteFollowingRepos(ctx context.Context, localRepo *repo.Repository) error {
+	return storeFollowingRepos(ctx, localRepo, []*repo.FollowingRepo{})
+}
+
+func storeFollowingRepos(ctx context.Context, localRepo *repo.Repository, followingRepos []*repo.FollowingRepo) error {
+	before, err := repo.FindFollowingReposByRepoID(ctx, localRepo.ID)
+	if err != nil {
+		return err
+	}
+	if err := repo.StoreFollowingRepos(ctx, localRepo.ID, followingRepos); err != nil {
+		return err
+	}
+	if !setting.Federation.Enabled {
+		return nil
+	}
+	return announceFollowingRepos(ctx, localRepo, before, followingRepos)
 }
 
 func SendLikeActivities(ctx context.Context, doer user.User, repoID int64) error {
@@ -0,0 +1,249 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package federation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/forgefed"
+	"code.gitea.io/gitea/models/user"
+	fm "code.gitea.io/gitea/modules/forgefed"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+
+	ap "github.com/go-ap/activitypub"
+)
+
+// ErrNotRemoteUser is returned when a user of this instance should be followed over ActivityPub
+var ErrNotRemoteUser = errors.New("user is not a user of another instance")
+
+// inboxOf returns the inbox of a user of another instance
+func inboxOf(remoteUser *user.User) string {
+	return remoteUser.NormalizedFederatedURI + "/inbox"
+}
+
+// GetOrCreateFederatedUser returns the local user representing the actor of another instance,
+// it is created on the first encounter of the actor
+func GetOrCreateFederatedUser(ctx context.Context, actorURI string) (*user.User, error) {
+	federationHost, err := GetFederationHostForURI(ctx, actorURI)
+	if err != nil {
+		return nil, err
+	}
+	personID, err := fm.NewPersonID(actorURI, string(federationHost.NodeInfo.SoftwareName))
+	if err != nil {
+		return nil, err
+	}
+	remoteUser, _, err := user.FindFederatedUser(ctx, personID.ID, federationHost.ID)
+	if err != nil || remoteUser != nil {
+		return remoteUser, err
+	}
+	remoteUser, _, err = CreateUserFromAP(ctx, personID, federationHost.ID)
+	return remoteUser, err
+}
+
+// FollowActor follows the actor of another instance, see FollowRemoteUser
+func FollowActor(ctx context.Context, doer *user.User, actorURI string) (*user.User, error) {
+	if strings.HasPrefix(actorURI, setting.AppURL) {
+		return nil, ErrNotRemoteUser
+	}
+	remoteUser, err := GetOrCreateFederatedUser(ctx, actorURI)
+	if err != nil {
+		return nil, err
+	}
+	return remoteUser, FollowRemoteUser(ctx, doer, remoteUser)
+}
+
+// FollowRemoteUser sends a Follow activity to the user of another instance,
+// the doer follows the user once the Follow activity is accepted
+func FollowRemoteUser(ctx context.Context, doer, remoteUser *user.User) error {
+	if !remoteUser.IsRemote() {
+		return ErrNotRemoteUser
+	}
+	if user.IsBlocked(ctx, doer.ID, remoteUser.ID) || user.IsBlocked(ctx, remoteUser.ID, doer.ID) {
+		return user.ErrBlockedByUser
+	}
+
+	var deliveries []*forgefed.Delivery
+	if err := db.WithTx(ctx, func(ctx context.Context) error {
+		f, err := user.GetFederatedFollow(ctx, doer.ID, remoteUser.ID)
+		if err != nil || f != nil {
+			return err
+		}
+
+		follow := ap.FollowNew("", ap.IRI(remoteUser.NormalizedFederatedURI))
+		follow.To = ap.ItemCollection{ap.IRI(remoteUser.NormalizedFederatedURI)}
+		if deliveries, err = publish(ctx, doer, follow, inboxOf(remoteUser)); err != nil {
+			return err
+		}
+		return user.CreateFederatedFollow(ctx, &user.FederatedFollow{
+			UserID:     doer.ID,
+			FollowID:   remoteUser.ID,
+			ActivityID: follow.ID.String(),
+		})
+	}); err != nil {
+		return err
+	}
+	enqueueDeliveries(deliveries)
+	return nil
+}
+
+// UnfollowRemoteUser sends an Undo activity of the Follow activity to the user of another instance
+// and stops following the user
+func UnfollowRemoteUser(ctx context.Context, doer, remoteUser *user.User) error {
+	if !remoteUser.IsRemote() {
+		return ErrNotRemoteUser
+	}
+
+	var deliveries []*forgefed.Delivery
+	if err := db.WithTx(ctx, func(ctx context.Context) error {
+		f, err := user.GetFederatedFollow(ctx, doer.ID, remoteUser.ID)
+		if err != nil || f == nil {
+			return err
+		}
+
+		follow := ap.FollowNew(ap.IRI(f.ActivityID), ap.IRI(remoteUser.NormalizedFederatedURI))
+		follow.Actor = ap.IRI(doer.APActorID())
+		undo := ap.UndoNew("", follow)
+		undo.To = ap.ItemCollection{ap.IRI(remoteUser.NormalizedFederatedURI)}
+		if deliveries, err = publish(ctx, doer, undo, inboxOf(remoteUser)); err != nil {
+			return err
+		}
+		return user.DeleteFederatedFollow(ctx, f)
+	}); err != nil {
+		return err
+	}
+	enqueueDeliveries(deliveries)
+	return nil
+}
+
+// ProcessPersonInbox handles an activity sent to the inbox of a local user by the actor signer.
+// Follow activities are accepted unless the user blocked the actor, Accept and Reject activities
+// answer the Follow activities of the user, Undo activities withdraw Follow activities and Update activities
+// announce the new public key of the actor.
+func ProcessPersonInbox(ctx context.Context, ctxUser *user.User, signer string, body []byte) (int, string, error) {
+	item, err := ap.UnmarshalJSON(body)
+	if err != nil {
+		return http.StatusBadRequest, "Invalid activity", err
+	}
+	activity, err := ap.ToActivity(item)
+	if err != nil {
+		return http.StatusBadRequest, "Invalid activity", err
+	}
+	if activity.Actor == nil || activity.Object == nil {
+		return http.StatusBadRequest, "Invalid activity", fmt.Errorf("activity without actor or object")
+	}
+	actorURI := activity.Actor.GetID().String()
+	if actorURI != signer {
+		return http.StatusForbidden, "Invalid actor", fmt.Errorf("activity of %q signed by %q", actorURI, signer)
+	}
+
+	switch activity.Type {
+	case ap.UpdateType:
+		return processPersonUpdate(ctx, actorURI, activity)
+	case ap.FollowType, ap.AcceptType, ap.RejectType, ap.UndoType:
+	default:
+		log.Debug("ProcessPersonInbox: ignoring %s activity of %s", activity.Type, actorURI)
+		return 0, "", nil
+	}
+
+	remoteUser, err := GetOrCreateFederatedUser(ctx, actorURI)
+	if err != nil {
+		return http.StatusInternalServerError, "Unable to get the actor", err
+	}
+
+	switch activity.Type {
+	case ap.FollowType:
+		if activity.Object.GetID().String() != ctxUser.APActorID() {
+			return http.StatusNotAcceptable, "Invalid object", fmt.Errorf("follow of %q sent to %q", activity.Object.GetID(), ctxUser.APActorID())
+		}
+		err = processFollow(ctx, ctxUser, remoteUser, activity)
+	case ap.AcceptType, ap.RejectType:
+		err = processFollowResponse(ctx, ctxUser, remoteUser, activity)
+	case ap.UndoType:
+		err = processUndoFollow(ctx, ctxUser, remoteUser, activity)
+	}
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Sprintf("Unable to process %s activity", activity.Type), err
+	}
+	return 0, "", nil
+}
+
+// processFollow lets the user of another instance follow the local user and answers the Follow activity
+func processFollow(ctx context.Context, ctxUser, remoteUser *user.User, follow *ap.Activity) error {
+	var deliveries []*forgefed.Delivery
+	if err := db.WithTx(ctx, func(ctx context.Context) error {
+		if user.IsBlocked(ctx, ctxUser.ID, remoteUser.ID) {
+			reject := ap.RejectNew("", follow)
+			reject.To = ap.ItemCollection{ap.IRI(remoteUser.NormalizedFederatedURI)}
+			var err error
+			deliveries, err = publish(ctx, ctxUser, reject, inboxOf(remoteUser))
+			return err
+		}
+
+		f, err := user.GetFederatedFollow(ctx, remoteUser.ID, ctxUser.ID)
+		if err != nil {
+			return err
+		}
+		if f == nil {
+			if err := user.CreateFederatedFollow(ctx, &user.FederatedFollow{
+				UserID:     remoteUser.ID,
+				FollowID:   ctxUser.ID,
+				ActivityID: follow.ID.String(),
+				Accepted:   true,
+			}); err != nil {
+				return err
+			}
+		}
+
+		accept := ap.AcceptNew("", follow)
+		accept.To = ap.ItemCollection{ap.IRI(remoteUser.NormalizedFederatedURI)}
+		deliveries, err = publish(ctx, ctxUser, accept, inboxOf(remoteUser))
+		return err
+	}); err != nil {
+		return err
+	}
+	enqueueDeliveries(deliveries)
+	return nil
+}
+
+// processFollowResponse handles the answer of the user of another instance to a Follow activity of the local user
+func processFollowResponse(ctx context.Context, ctxUser, remoteUser *user.User, response *ap.Activity) error {
+	f, err := user.GetFederatedFollowByActivityID(ctx, response.Object.GetID().String())
+	if err != nil {
+		return err
+	}
+	if f == nil || f.UserID != ctxUser.ID || f.FollowID != remoteUser.ID {
+		log.Debug("ProcessPersonInbox: %s of unknown follow %s", response.Type, response.Object.GetID())
+		return nil
+	}
+
+	if response.Type == ap.AcceptType {
+		return user.AcceptFederatedFollow(ctx, f)
+	}
+	return user.DeleteFederatedFollow(ctx, f)
+}
+
+// processUndoFollow stops the user of another instance from following the local user
+func processUndoFollow(ctx context.Context, ctxUser, remoteUser *user.User, undo *ap.Activity) error {
+	f, err := user.GetFederatedFollowByActivityID(ctx, undo.Object.GetID().String())
+	if err != nil {
+		return err
+	}
+	if f == nil && undo.Object.GetType() == ap.FollowType {
+		// the Follow activity may be embedded with another ID than the one it was sent with
+		if f, err = user.GetFederatedFollow(ctx, remoteUser.ID, ctxUser.ID); err != nil {
+			return err
+		}
+	}
+	if f == nil || f.UserID != remoteUser.ID || f.FollowID != ctxUser.ID {
+		log.Debug("ProcessPersonInbox: undo of unknown activity %s", undo.Object.GetID())
+		return nil
+	}
+	return user.DeleteFederatedFollow(ctx, f)
+}
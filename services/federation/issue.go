@@ -0,0 +1,268 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package federation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	issues_model "code.gitea.io/gitea/models/issues"
+	access_model "code.gitea.io/gitea/models/perm/access"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unit"
+	"code.gitea.io/gitea/models/user"
+	fm "code.gitea.io/gitea/modules/forgefed"
+	"code.gitea.io/gitea/modules/log"
+	issue_service "code.gitea.io/gitea/services/issue"
+
+	ap "github.com/go-ap/activitypub"
+)
+
+// markdownMediaType is the media type of the source of tickets and notes written in markdown
+const markdownMediaType = "text/markdown"
+
+// naturalLanguageValue returns the first value of n, NaturalLanguageValues.String() lists all of them
+func naturalLanguageValue(n ap.NaturalLanguageValues) string {
+	if len(n) == 0 {
+		return ""
+	}
+	return n.First().Value.String()
+}
+
+// objectContent returns the markdown source of a ticket or note, falling back to its content
+func objectContent(o *ap.Object) string {
+	if o.Source.MediaType == markdownMediaType && len(o.Source.Content) > 0 {
+		return naturalLanguageValue(o.Source.Content)
+	}
+	return naturalLanguageValue(o.Content)
+}
+
+// canUseIssues returns whether the user of another instance may open and comment issues of the repository
+func canUseIssues(ctx context.Context, repo *repo_model.Repository, remoteUser *user.User) (bool, error) {
+	if repo.IsArchived {
+		return false, nil
+	}
+	perm, err := access_model.GetUserRepoPermission(ctx, repo, remoteUser)
+	if err != nil {
+		return false, err
+	}
+	return perm.CanRead(unit.TypeIssues), nil
+}
+
+// ProcessTicketOffer opens an issue authored by the user of another instance for the ticket offered to the repository
+// and answers the Offer activity with an Accept activity whose result is the issue. Offers of users blocked by the
+// owner of the repository are answered with a Reject activity.
+func ProcessTicketOffer(ctx context.Context, repo *repo_model.Repository, signer string, offer *ap.Activity) (int, string, error) {
+	if offer.ID == "" || offer.Actor == nil || offer.Object == nil {
+		return http.StatusBadRequest, "Invalid activity", fmt.Errorf("activity without id, actor or object")
+	}
+	actorURI := offer.Actor.GetID().String()
+	if actorURI != signer {
+		return http.StatusForbidden, "Invalid actor", fmt.Errorf("activity of %q signed by %q", actorURI, signer)
+	}
+	if offer.Object.GetType() != fm.TicketType {
+		return http.StatusNotAcceptable, "Invalid object", fmt.Errorf("offer of a %s", offer.Object.GetType())
+	}
+	if offer.Target != nil && offer.Target.GetID().String() != repo.APActorID() {
+		return http.StatusNotAcceptable, "Invalid target", fmt.Errorf("ticket for %q offered to %q", offer.Target.GetID(), repo.APActorID())
+	}
+	ticket, err := fm.ToTicket(offer.Object)
+	if err != nil {
+		return http.StatusNotAcceptable, "Invalid object", err
+	}
+	if ticket.AttributedTo != nil && ticket.AttributedTo.GetID().String() != actorURI {
+		return http.StatusNotAcceptable, "Invalid object", fmt.Errorf("ticket of %q offered by %q", ticket.AttributedTo.GetID(), actorURI)
+	}
+	title := strings.TrimSpace(naturalLanguageValue(ticket.Summary))
+	if title == "" {
+		title = strings.TrimSpace(naturalLanguageValue(ticket.Name))
+	}
+	if title == "" {
+		return http.StatusNotAcceptable, "Invalid object", fmt.Errorf("ticket without summary")
+	}
+
+	if f, err := issues_model.GetFederatedIssueByOfferID(ctx, offer.ID.String()); err != nil {
+		return http.StatusInternalServerError, "GetFederatedIssueByOfferID", err
+	} else if f != nil {
+		log.Debug("ProcessTicketOffer: offer %s was already processed", offer.ID)
+		return 0, "", nil
+	}
+
+	remoteUser, err := GetOrCreateFederatedUser(ctx, actorURI)
+	if err != nil {
+		return http.StatusInternalServerError, "Unable to get the actor", err
+	}
+	if ok, err := canUseIssues(ctx, repo, remoteUser); err != nil {
+		return http.StatusInternalServerError, "GetUserRepoPermission", err
+	} else if !ok {
+		return http.StatusForbidden, "Issues are not available", fmt.Errorf("%s can't open issues in %s", actorURI, repo.FullName())
+	}
+	if err := repo.LoadOwner(ctx); err != nil {
+		return http.StatusInternalServerError, "LoadOwner", err
+	}
+
+	issue := &issues_model.Issue{
+		RepoID:   repo.ID,
+		Repo:     repo,
+		Title:    title,
+		PosterID: remoteUser.ID,
+		Poster:   remoteUser,
+		Content:  objectContent(&ticket.Object),
+	}
+	if err := issue_service.NewIssue(ctx, repo, issue, nil, nil, nil); err != nil {
+		if errors.Is(err, user.ErrBlockedByUser) {
+			// the repository has no key of its own, its owner answers on its behalf
+			reject := ap.RejectNew("", offer.ID)
+			reject.To = ap.ItemCollection{ap.IRI(remoteUser.NormalizedFederatedURI)}
+			deliveries, err := publish(ctx, repo.Owner, reject, inboxOf(remoteUser))
+			if err != nil {
+				return http.StatusInternalServerError, "Unable to reject the offer", err
+			}
+			enqueueDeliveries(deliveries)
+			return 0, "", nil
+		}
+		return http.StatusInternalServerError, "NewIssue", err
+	}
+	if err := issues_model.CreateFederatedIssue(ctx, &issues_model.FederatedIssue{IssueID: issue.ID, OfferID: offer.ID.String()}); err != nil {
+		return http.StatusInternalServerError, "CreateFederatedIssue", err
+	}
+
+	accept := ap.AcceptNew("", offer.ID)
+	accept.Result = ap.IRI(issue.APObjectID())
+	accept.To = ap.ItemCollection{ap.IRI(remoteUser.NormalizedFederatedURI)}
+	deliveries, err := publish(ctx, repo.Owner, accept, inboxOf(remoteUser))
+	if err != nil {
+		return http.StatusInternalServerError, "Unable to accept the offer", err
+	}
+	enqueueDeliveries(deliveries)
+	return 0, "", nil
+}
+
+// ProcessNoteCreation adds a comment authored by the user of another instance to the issue which is the context of the note
+func ProcessNoteCreation(ctx context.Context, repo *repo_model.Repository, signer string, create *ap.Activity) (int, string, error) {
+	if create.Actor == nil || create.Object == nil {
+		return http.StatusBadRequest, "Invalid activity", fmt.Errorf("activity without actor or object")
+	}
+	actorURI := create.Actor.GetID().String()
+	if actorURI != signer {
+		return http.StatusForbidden, "Invalid actor", fmt.Errorf("activity of %q signed by %q", actorURI, signer)
+	}
+	if create.Object.GetType() != ap.NoteType {
+		return http.StatusNotAcceptable, "Invalid object", fmt.Errorf("creation of a %s", create.Object.GetType())
+	}
+	note, err := ap.ToObject(create.Object)
+	if err != nil {
+		return http.StatusNotAcceptable, "Invalid object", err
+	}
+	if note.AttributedTo != nil && note.AttributedTo.GetID().String() != actorURI {
+		return http.StatusNotAcceptable, "Invalid object", fmt.Errorf("note of %q created by %q", note.AttributedTo.GetID(), actorURI)
+	}
+	content := strings.TrimSpace(objectContent(note))
+	if content == "" {
+		return http.StatusNotAcceptable, "Invalid object", fmt.Errorf("note without content")
+	}
+
+	issue, err := issueOfNote(ctx, repo, note)
+	if err != nil {
+		if issues_model.IsErrIssueNotExist(err) {
+			return http.StatusNotAcceptable, "Invalid context", err
+		}
+		return http.StatusInternalServerError, "GetIssueByIndex", err
+	}
+	if note.ID != "" {
+		if f, err := issues_model.GetFederatedCommentByNoteID(ctx, issue.ID, note.ID.String()); err != nil {
+			return http.StatusInternalServerError, "GetFederatedCommentByNoteID", err
+		} else if f != nil {
+			log.Debug("ProcessNoteCreation: note %s was already processed", note.ID)
+			return 0, "", nil
+		}
+	}
+
+	remoteUser, err := GetOrCreateFederatedUser(ctx, actorURI)
+	if err != nil {
+		return http.StatusInternalServerError, "Unable to get the actor", err
+	}
+	if ok, err := canUseIssues(ctx, repo, remoteUser); err != nil {
+		return http.StatusInternalServerError, "GetUserRepoPermission", err
+	} else if !ok || issue.IsLocked {
+		return http.StatusForbidden, "Comments are not available", fmt.Errorf("%s can't comment on %s#%d", actorURI, repo.FullName(), issue.Index)
+	}
+
+	comment, err := issue_service.CreateIssueComment(ctx, remoteUser, repo, issue, content, nil)
+	if err != nil {
+		if errors.Is(err, user.ErrBlockedByUser) {
+			return http.StatusForbidden, "Blocked", err
+		}
+		return http.StatusInternalServerError, "CreateIssueComment", err
+	}
+	if note.ID != "" {
+		if err := issues_model.CreateFederatedComment(ctx, &issues_model.FederatedComment{IssueID: issue.ID, CommentID: comment.ID, NoteID: note.ID.String()}); err != nil {
+			return http.StatusInternalServerError, "CreateFederatedComment", err
+		}
+	}
+	return 0, "", nil
+}
+
+// issueOfNote returns the issue of the repository the note is about, given by its context or the object it replies to
+func issueOfNote(ctx context.Context, repo *repo_model.Repository, note *ap.Object) (*issues_model.Issue, error) {
+	prefix := repo.APActorID() + "/issues/"
+	for _, it := range []ap.Item{note.Context, note.InReplyTo} {
+		if it == nil {
+			continue
+		}
+		if indexStr, ok := strings.CutPrefix(it.GetID().String(), prefix); ok {
+			index, err := strconv.ParseInt(indexStr, 10, 64)
+			if err != nil {
+				break
+			}
+			issue, err := issues_model.GetIssueByIndex(ctx, repo.ID, index)
+			if err != nil {
+				return nil, err
+			}
+			issue.Repo = repo
+			return issue, nil
+		}
+	}
+	return nil, issues_model.ErrIssueNotExist{RepoID: repo.ID}
+}
+
+// sendIssueComment delivers the comment of a local user to the author of the issue if it was opened from another instance
+func sendIssueComment(ctx context.Context, doer *user.User, issue *issues_model.Issue, comment *issues_model.Comment) error {
+	f, err := issues_model.GetFederatedIssue(ctx, issue.ID)
+	if err != nil || f == nil {
+		return err
+	}
+	if err := issue.LoadRepo(ctx); err != nil {
+		return err
+	}
+	if err := issue.LoadPoster(ctx); err != nil {
+		return err
+	}
+	if !issue.Poster.IsRemote() {
+		return nil
+	}
+
+	ticketID := ap.IRI(issue.APObjectID())
+	note := ap.ObjectNew(ap.NoteType)
+	note.ID = ap.IRI(comment.HTMLURL(ctx))
+	note.AttributedTo = ap.IRI(doer.APActorID())
+	note.Context = ticketID
+	note.InReplyTo = ticketID
+	note.Content = ap.DefaultNaturalLanguageValue(comment.Content)
+	note.Source = ap.Source{Content: ap.DefaultNaturalLanguageValue(comment.Content), MediaType: markdownMediaType}
+	note.To = ap.ItemCollection{ap.IRI(issue.Poster.NormalizedFederatedURI)}
+
+	create := ap.CreateNew("", note)
+	create.To = note.To
+	deliveries, err := publish(ctx, doer, create, inboxOf(issue.Poster))
+	if err != nil {
+		return err
+	}
+	enqueueDeliveries(deliveries)
+	return nil
+}
@@ -0,0 +1,227 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package federation
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	audit_model "code.gitea.io/gitea/models/audit"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/forgefed"
+	"code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/activitypub"
+	"code.gitea.io/gitea/modules/httplib"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+	audit_service "code.gitea.io/gitea/services/audit"
+
+	ap "github.com/go-ap/activitypub"
+)
+
+// ErrNotLocalUser is returned when the key of a user of another instance should be rotated
+var ErrNotLocalUser = errors.New("user is not a user of this instance")
+
+// Person returns the Person actor of a local user
+func Person(ctx context.Context, u *user.User) (*ap.Person, error) {
+	// TODO: the setting.AppURL during the test doesn't follow the definition: "It always has a '/' suffix"
+	link := fmt.Sprintf("%s/api/v1/activitypub/user-id/%d", strings.TrimSuffix(setting.AppURL, "/"), u.ID)
+	person := ap.PersonNew(ap.IRI(link))
+
+	person.Name = ap.NaturalLanguageValuesNew()
+	if err := person.Name.Set("en", ap.Content(u.FullName)); err != nil {
+		return nil, err
+	}
+	person.PreferredUsername = ap.NaturalLanguageValuesNew()
+	if err := person.PreferredUsername.Set("en", ap.Content(u.Name)); err != nil {
+		return nil, err
+	}
+	person.URL = ap.IRI(u.HTMLURL())
+	person.Icon = ap.Image{
+		Type:      ap.ImageType,
+		MediaType: "image/png",
+		URL:       ap.IRI(u.AvatarLink(ctx)),
+	}
+	person.Inbox = ap.IRI(link + "/inbox")
+	person.Outbox = ap.IRI(link + "/outbox")
+
+	publicKeyPem, err := activitypub.GetPublicKey(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	person.PublicKey.ID = ap.IRI(link + "#main-key")
+	person.PublicKey.Owner = ap.IRI(link)
+	person.PublicKey.PublicKeyPem = publicKeyPem
+	return person, nil
+}
+
+// RotateKey replaces the key pair the user signs its activities with and announces the new public key
+// with an Update activity to the users of other instances following the user or followed by it.
+// Until they received it, they fetch the new key once the signature of an activity doesn't match their cached key.
+func RotateKey(ctx context.Context, doer *user.User) error {
+	if doer.IsRemote() {
+		return ErrNotLocalUser
+	}
+
+	var deliveries []*forgefed.Delivery
+	if err := db.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := activitypub.RotateKeyPair(ctx, doer); err != nil {
+			return err
+		}
+		peers, err := user.FindFederatedFollowPeers(ctx, doer.ID)
+		if err != nil || len(peers) == 0 {
+			return err
+		}
+		person, err := Person(ctx, doer)
+		if err != nil {
+			return err
+		}
+
+		update := ap.UpdateNew("", person)
+		inboxes := make([]string, 0, len(peers))
+		for _, peer := range peers {
+			update.To = append(update.To, ap.IRI(peer.NormalizedFederatedURI))
+			inboxes = append(inboxes, inboxOf(peer))
+		}
+		deliveries, err = publish(ctx, doer, update, inboxes...)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	audit_service.Record(ctx, audit_model.ActionActivityPubKeyRotate, doer, doer.ID, audit_service.UserTarget(doer), nil)
+	enqueueDeliveries(deliveries)
+	return nil
+}
+
+// RemotePublicKey returns the public key with the ID of an actor of another instance, taken from the cache
+// unless refresh is set or the cached key is older than setting.Federation.PublicKeyCacheTTL.
+// Fetched keys are cached, cached reports whether the key was taken from the cache.
+func RemotePublicKey(ctx context.Context, keyID *url.URL, refresh bool) (pub crypto.PublicKey, cached bool, err error) {
+	if !refresh {
+		k, err := forgefed.GetRemoteKey(ctx, keyID.String())
+		if err != nil {
+			return nil, false, err
+		}
+		if k != nil && !k.IsStale(setting.Federation.PublicKeyCacheTTL) {
+			pub, err := parsePublicKeyPem(k.PublicKeyPem)
+			if err == nil {
+				return pub, true, nil
+			}
+			log.Warn("Invalid cached public key %s: %v", k.KeyID, err)
+		}
+	}
+
+	b, err := fetch(keyID)
+	if err != nil {
+		return nil, false, err
+	}
+	k, err := publicKeyFromResponse(b, keyID)
+	if err != nil {
+		return nil, false, err
+	}
+	if pub, err = parsePublicKeyPem(k.PublicKeyPem); err != nil {
+		return nil, false, err
+	}
+	cacheRemoteKey(ctx, k)
+	return pub, false, nil
+}
+
+// cacheRemoteKey stores the key in the cache, failures only make the key to be fetched again
+func cacheRemoteKey(ctx context.Context, k *forgefed.RemoteKey) {
+	if len(k.KeyID) > 255 {
+		return
+	}
+	if err := forgefed.SetRemoteKey(ctx, k); err != nil {
+		log.Error("Unable to cache the public key %s: %v", k.KeyID, err)
+	}
+}
+
+// processPersonUpdate refreshes the cached public key of an actor of another instance announced by an Update activity
+func processPersonUpdate(ctx context.Context, actorURI string, update *ap.Activity) (int, string, error) {
+	if update.Object.IsLink() {
+		// the key is fetched again once a signature doesn't match the cached key
+		return 0, "", nil
+	}
+	person, err := ap.ToActor(update.Object)
+	if err != nil {
+		return http.StatusNotAcceptable, "Invalid object", err
+	}
+	if person.ID.String() != actorURI {
+		return http.StatusForbidden, "Invalid object", fmt.Errorf("update of %q by %q", person.ID, actorURI)
+	}
+	key := person.PublicKey
+	if key.ID == "" || key.PublicKeyPem == "" {
+		return 0, "", nil
+	}
+	if key.Owner != "" && key.Owner.String() != actorURI {
+		return http.StatusNotAcceptable, "Invalid public key", fmt.Errorf("public key of %q announced by %q", key.Owner, actorURI)
+	}
+	keyID, err := url.Parse(key.ID.String())
+	if err != nil || !strings.EqualFold(keyID.Host, hostOfURI(actorURI)) {
+		return http.StatusNotAcceptable, "Invalid public key", fmt.Errorf("public key %q announced by %q", key.ID, actorURI)
+	}
+	if _, err := parsePublicKeyPem(key.PublicKeyPem); err != nil {
+		return http.StatusNotAcceptable, "Invalid public key", err
+	}
+	cacheRemoteKey(ctx, &forgefed.RemoteKey{KeyID: key.ID.String(), OwnerURI: actorURI, PublicKeyPem: key.PublicKeyPem})
+	return 0, "", nil
+}
+
+func hostOfURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+func publicKeyFromResponse(b []byte, keyID *url.URL) (*forgefed.RemoteKey, error) {
+	person := ap.PersonNew(ap.IRI(keyID.String()))
+	if err := person.UnmarshalJSON(b); err != nil {
+		return nil, fmt.Errorf("ActivityStreams type cannot be converted to one known to have publicKey property: %w", err)
+	}
+	pubKey := person.PublicKey
+	if pubKey.ID.String() != keyID.String() {
+		return nil, fmt.Errorf("cannot find publicKey with id: %s in %s", keyID, string(b))
+	}
+	owner := person.ID.String()
+	if pubKey.Owner != "" {
+		owner = pubKey.Owner.String()
+	}
+	return &forgefed.RemoteKey{KeyID: keyID.String(), OwnerURI: owner, PublicKeyPem: pubKey.PublicKeyPem}, nil
+}
+
+func parsePublicKeyPem(pubKeyPem string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(pubKeyPem))
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("could not decode publicKeyPem to PUBLIC KEY pem block type")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+func fetch(iri *url.URL) (b []byte, err error) {
+	req := httplib.NewRequest(iri.String(), http.MethodGet)
+	req.Header("Accept", activitypub.ActivityStreamsContentType)
+	req.Header("User-Agent", "Gitea/"+setting.AppVer)
+	resp, err := req.Response()
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("url IRI fetch [%s] failed with status (%d): %s", iri, resp.StatusCode, resp.Status)
+	}
+	b, err = io.ReadAll(io.LimitReader(resp.Body, setting.Federation.MaxSize))
+	return b, err
+}
@@ -0,0 +1,156 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package federation
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	audit_model "code.gitea.io/gitea/models/audit"
+	"code.gitea.io/gitea/models/forgefed"
+	"code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+	audit_service "code.gitea.io/gitea/services/audit"
+)
+
+var (
+	// ErrHostBlocked is returned for requests from and to federation hosts blocked by the administrators
+	ErrHostBlocked = errors.New("federation host is blocked")
+	// ErrHostRateLimited is returned for requests of federation hosts exceeding the inbox rate limit
+	ErrHostRateLimited = errors.New("federation host exceeded the inbox rate limit")
+)
+
+// maxRateLimitedHosts caps the number of hosts counted in a window of the inbox rate limit
+const maxRateLimitedHosts = 10000
+
+// hostRateLimiter counts the requests of every host in windows of a minute
+type hostRateLimiter struct {
+	mu     sync.Mutex
+	window int64
+	counts map[string]int
+}
+
+var inboxRateLimiter = &hostRateLimiter{}
+
+// allow counts a request of the host and returns false if the host made more than limit requests in the current window
+func (l *hostRateLimiter) allow(host string, limit int) bool {
+	if limit <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// a new window is started early when too many hosts are counted so the map can't grow unbounded
+	if window := time.Now().Unix() / 60; window != l.window || l.counts == nil || len(l.counts) >= maxRateLimitedHosts {
+		l.window = window
+		l.counts = make(map[string]int)
+	}
+	if l.counts[host] >= limit {
+		return false
+	}
+	l.counts[host]++
+	return true
+}
+
+// HostAction returns how the activities of the host are treated according to the federation host rules,
+// in allowlist mode hosts without a rule are blocked
+func HostAction(ctx context.Context, host string) (forgefed.FederationHostAction, error) {
+	rules, err := forgefed.FindFederationHostRules(ctx)
+	if err != nil {
+		return forgefed.FederationHostActionNone, err
+	}
+	return MatchHostAction(rules, host), nil
+}
+
+// MatchHostAction returns how the activities of the host are treated according to the rules, see HostAction
+func MatchHostAction(rules []*forgefed.FederationHostRule, host string) forgefed.FederationHostAction {
+	if rule := forgefed.MatchFederationHostRule(rules, host); rule != nil {
+		return rule.Action
+	}
+	if setting.Federation.AllowlistMode {
+		return forgefed.FederationHostActionBlock
+	}
+	return forgefed.FederationHostActionNone
+}
+
+// checkHostOfURI returns ErrHostBlocked if the host of the URI is blocked
+func checkHostOfURI(ctx context.Context, uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	action, err := HostAction(ctx, u.Hostname())
+	if err != nil {
+		return err
+	}
+	if action == forgefed.FederationHostActionBlock {
+		return ErrHostBlocked
+	}
+	return nil
+}
+
+// CheckInboxHost returns ErrHostBlocked if the host may not deliver activities to the inboxes
+// and whether the activities of the host are silenced
+func CheckInboxHost(ctx context.Context, host string) (silenced bool, err error) {
+	action, err := HostAction(ctx, host)
+	if err != nil {
+		return false, err
+	}
+	if action == forgefed.FederationHostActionBlock {
+		return false, ErrHostBlocked
+	}
+	return action == forgefed.FederationHostActionSilence, nil
+}
+
+// LimitInboxHost counts an inbox request and returns ErrHostRateLimited if the inbox rate limit is exceeded,
+// the key must not be forgeable by other senders: the host of a verified signature or the host and remote address
+// of unsigned requests, otherwise anyone could exhaust the limit of a legitimate host
+func LimitInboxHost(key string) error {
+	if !inboxRateLimiter.allow(strings.ToLower(key), setting.Federation.InboxRateLimit) {
+		return ErrHostRateLimited
+	}
+	return nil
+}
+
+// SetHostRule creates or updates a federation host rule,
+// the users of the hosts blocked by the rule are purged with user_service.PurgeFederationHostUsers
+func SetHostRule(ctx context.Context, doer *user.User, rule *forgefed.FederationHostRule) error {
+	old, err := forgefed.GetFederationHostRule(ctx, rule.Pattern)
+	if err != nil {
+		return err
+	}
+	if err := forgefed.SetFederationHostRule(ctx, rule); err != nil {
+		return err
+	}
+
+	change := audit_model.Change{New: rule.Action.String()}
+	if old != nil {
+		change.Old = old.Action.String()
+	}
+	audit_service.Record(ctx, audit_model.ActionFederationHostRuleUpdate, doer, 0, audit_service.FederationHostRuleTarget(rule), audit_model.Diff{
+		"action": change,
+	})
+	return nil
+}
+
+// DeleteHostRule deletes a federation host rule, the users purged by it are not restored
+func DeleteHostRule(ctx context.Context, doer *user.User, pattern string) error {
+	rule, err := forgefed.GetFederationHostRule(ctx, pattern)
+	if err != nil {
+		return err
+	} else if rule == nil {
+		return util.NewNotExistErrorf("federation host rule %s does not exist", pattern)
+	}
+	if err := forgefed.DeleteFederationHostRule(ctx, pattern); err != nil {
+		return err
+	}
+	audit_service.Record(ctx, audit_model.ActionFederationHostRuleDelete, doer, 0, audit_service.FederationHostRuleTarget(rule), audit_model.Diff{
+		"action": {Old: rule.Action.String()},
+	})
+	return nil
+}
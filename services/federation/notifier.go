@@ -0,0 +1,52 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package federation
+
+import (
+	"context"
+
+	issues_model "code.gitea.io/gitea/models/issues"
+	repo_model "code.gitea.io/gitea/models/repo"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/repository"
+	"code.gitea.io/gitea/modules/setting"
+	notify_service "code.gitea.io/gitea/services/notify"
+)
+
+type federationNotifier struct {
+	notify_service.NullNotifier
+}
+
+var _ notify_service.Notifier = &federationNotifier{}
+
+// NewNotifier create a new federationNotifier notifier
+func NewNotifier() notify_service.Notifier {
+	return &federationNotifier{}
+}
+
+// CreateIssueComment delivers the comments of local users on issues opened from other instances
+func (n *federationNotifier) CreateIssueComment(ctx context.Context, doer *user_model.User, repo *repo_model.Repository,
+	issue *issues_model.Issue, comment *issues_model.Comment, mentions []*user_model.User,
+) {
+	if !setting.Federation.Enabled || doer.IsRemote() {
+		return
+	}
+	if err := sendIssueComment(ctx, doer, issue, comment); err != nil {
+		log.Error("Unable to deliver comment %d to another instance: %v", comment.ID, err)
+	}
+}
+
+// PushCommits announces the commits pushed to a branch of a public repository to the repositories of other instances following it
+func (n *federationNotifier) PushCommits(ctx context.Context, pusher *user_model.User, repo *repo_model.Repository,
+	opts *repository.PushUpdateOptions, commits *repository.PushCommits,
+) {
+	if !setting.Federation.Enabled || pusher.IsRemote() || repo.IsPrivate ||
+		!opts.RefFullName.IsBranch() || opts.IsDelRef() || len(commits.Commits) == 0 {
+		return
+	}
+	if err := sendPush(ctx, pusher, repo, opts.RefFullName.BranchName(), commits); err != nil {
+		log.Error("Unable to announce the push to %s to other instances: %v", repo.FullName(), err)
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package federation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	activities_model "code.gitea.io/gitea/models/activities"
+	"code.gitea.io/gitea/models/forgefed"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/user"
+	fm "code.gitea.io/gitea/modules/forgefed"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/repository"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+	mirror_service "code.gitea.io/gitea/services/mirror"
+
+	ap "github.com/go-ap/activitypub"
+)
+
+// announceFollowingRepos sends a Follow activity to the repositories of other instances the local repository
+// started to follow and an Undo activity to the ones it stopped to follow. The repository has no key of its own,
+// its owner signs the activities on its behalf.
+func announceFollowingRepos(ctx context.Context, localRepo *repo_model.Repository, before, after []*repo_model.FollowingRepo) error {
+	followed := make(map[string]bool, len(after))
+	for _, f := range after {
+		followed[f.URI] = true
+	}
+	unfollowed := make(map[string]bool, len(before))
+	for _, f := range before {
+		if followed[f.URI] {
+			delete(followed, f.URI)
+		} else {
+			unfollowed[f.URI] = true
+		}
+	}
+	if len(followed) == 0 && len(unfollowed) == 0 {
+		return nil
+	}
+	if err := localRepo.LoadOwner(ctx); err != nil {
+		return err
+	}
+
+	var deliveries []*forgefed.Delivery
+	send := func(activity *ap.Activity, uri string) error {
+		activity.To = ap.ItemCollection{ap.IRI(uri)}
+		d, err := publish(ctx, localRepo.Owner, activity, uri+"/inbox")
+		deliveries = append(deliveries, d...)
+		return err
+	}
+	for _, f := range after {
+		if !followed[f.URI] {
+			continue
+		}
+		follow := ap.FollowNew("", ap.IRI(f.URI))
+		follow.Actor = ap.IRI(localRepo.APActorID())
+		if err := send(follow, f.URI); err != nil {
+			return err
+		}
+	}
+	for _, f := range before {
+		if !unfollowed[f.URI] {
+			continue
+		}
+		follow := ap.FollowNew("", ap.IRI(f.URI))
+		follow.Actor = ap.IRI(localRepo.APActorID())
+		undo := ap.UndoNew("", follow)
+		undo.Actor = follow.Actor
+		if err := send(undo, f.URI); err != nil {
+			return err
+		}
+	}
+	enqueueDeliveries(deliveries)
+	return nil
+}
+
+// checkRepositoryActor verifies that the activity of a repository of another instance was signed by an actor of
+// the same instance, the repository has no key of its own
+func checkRepositoryActor(actorURI, signer string) error {
+	if signer == "" || !strings.EqualFold(hostOfURI(actorURI), hostOfURI(signer)) {
+		return fmt.Errorf("activity of %q signed by %q", actorURI, signer)
+	}
+	return nil
+}
+
+// ProcessRepositoryFollow makes the repository of another instance which sent the Follow activity a follower of
+// the local repository, the commits pushed to the local repository are announced to it
+func ProcessRepositoryFollow(ctx context.Context, localRepo *repo_model.Repository, signer string, follow *ap.Activity) (int, string, error) {
+	if follow.Actor == nil || follow.Object == nil {
+		return http.StatusBadRequest, "Invalid activity", fmt.Errorf("activity without actor or object")
+	}
+	actorURI := follow.Actor.GetID().String()
+	if err := checkRepositoryActor(actorURI, signer); err != nil {
+		return http.StatusForbidden, "Invalid actor", err
+	}
+	if follow.Object.GetID().String() != localRepo.APActorID() {
+		return http.StatusNotAcceptable, "Invalid object", fmt.Errorf("follow of %q sent to %q", follow.Object.GetID(), localRepo.APActorID())
+	}
+	if localRepo.IsPrivate {
+		return http.StatusForbidden, "Repository is private", fmt.Errorf("%s can't follow %s", actorURI, localRepo.FullName())
+	}
+
+	federationHost, err := GetFederationHostForURI(ctx, actorURI)
+	if err != nil {
+		return http.StatusInternalServerError, "Wrong FederationHost", err
+	}
+	if _, err := fm.NewRepositoryID(actorURI, string(federationHost.NodeInfo.SoftwareName)); err != nil {
+		return http.StatusNotAcceptable, "Invalid actor", err
+	}
+	follower, err := repo_model.NewFollowerRepo(localRepo.ID, actorURI, federationHost.ID)
+	if err != nil {
+		return http.StatusNotAcceptable, "Invalid actor", err
+	}
+	if err := repo_model.AddFollowerRepo(ctx, &follower); err != nil {
+		return http.StatusInternalServerError, "AddFollowerRepo", err
+	}
+	return 0, "", nil
+}
+
+// ProcessRepositoryUndo stops announcing the commits pushed to the local repository to the repository of another
+// instance which sent the Undo activity of its Follow activity
+func ProcessRepositoryUndo(ctx context.Context, localRepo *repo_model.Repository, signer string, undo *ap.Activity) (int, string, error) {
+	if undo.Actor == nil || undo.Object == nil {
+		return http.StatusBadRequest, "Invalid activity", fmt.Errorf("activity without actor or object")
+	}
+	actorURI := undo.Actor.GetID().String()
+	if err := checkRepositoryActor(actorURI, signer); err != nil {
+		return http.StatusForbidden, "Invalid actor", err
+	}
+	if undo.Object.IsLink() || undo.Object.GetType() != ap.FollowType {
+		return http.StatusNotAcceptable, "Invalid object", fmt.Errorf("undo of a %s", undo.Object.GetType())
+	}
+	follow, err := ap.ToActivity(undo.Object)
+	if err != nil {
+		return http.StatusNotAcceptable, "Invalid object", err
+	}
+	if follow.Actor == nil || follow.Actor.GetID().String() != actorURI ||
+		follow.Object == nil || follow.Object.GetID().String() != localRepo.APActorID() {
+		return http.StatusNotAcceptable, "Invalid object", fmt.Errorf("undo of a follow of another actor or repository by %q", actorURI)
+	}
+	if err := repo_model.DeleteFollowerRepo(ctx, localRepo.ID, actorURI); err != nil {
+		return http.StatusInternalServerError, "DeleteFollowerRepo", err
+	}
+	return 0, "", nil
+}
+
+// sendPush announces the commits pushed to a branch of the local repository with a Push activity
+// to the repositories of other instances following it
+func sendPush(ctx context.Context, pusher *user.User, localRepo *repo_model.Repository, branch string, commits *repository.PushCommits) error {
+	followers, err := repo_model.FindFollowerReposByRepoID(ctx, localRepo.ID)
+	if err != nil || len(followers) == 0 {
+		return err
+	}
+
+	collection := ap.OrderedCollectionNew("")
+	for _, c := range commits.Commits {
+		commit := fm.CommitNew(ap.IRI(localRepo.HTMLURL() + "/commit/" + c.Sha1))
+		commit.Context = ap.IRI(localRepo.APActorID())
+		commit.Hash = c.Sha1
+		commit.Created = c.Timestamp
+		summary, _, _ := strings.Cut(c.Message, "\n")
+		commit.Summary = ap.DefaultNaturalLanguageValue(summary)
+		commit.Content = ap.DefaultNaturalLanguageValue(c.Message)
+		collection.OrderedItems = append(collection.OrderedItems, commit)
+	}
+	collection.TotalItems = uint(max(commits.Len, len(commits.Commits)))
+
+	target := ap.ObjectNew(fm.BranchType)
+	target.Type = fm.BranchType
+	target.ID = ap.IRI(localRepo.HTMLURL() + "/src/branch/" + util.PathEscapeSegments(branch))
+	target.Name = ap.DefaultNaturalLanguageValue(branch)
+
+	push := ap.ActivityNew("", fm.PushType, collection)
+	push.Type = fm.PushType
+	push.Context = ap.IRI(localRepo.APActorID())
+	push.Target = target
+	inboxes := make([]string, 0, len(followers))
+	for _, f := range followers {
+		push.To = append(push.To, ap.IRI(f.URI))
+		inboxes = append(inboxes, f.URI+"/inbox")
+	}
+	deliveries, err := publish(ctx, pusher, push, inboxes...)
+	if err != nil {
+		return err
+	}
+	enqueueDeliveries(deliveries)
+	return nil
+}
+
+// ProcessPush shows the commits announced by a Push activity of a repository of another instance followed by the
+// local repository in the activity feed of the local repository and syncs the local repository if it is a pull mirror
+func ProcessPush(ctx context.Context, localRepo *repo_model.Repository, signer string, push *ap.Activity) (int, string, error) {
+	if push.Actor == nil || push.Object == nil || push.Context == nil {
+		return http.StatusBadRequest, "Invalid activity", fmt.Errorf("activity without actor, object or context")
+	}
+	actorURI := push.Actor.GetID().String()
+	if actorURI != signer {
+		return http.StatusForbidden, "Invalid actor", fmt.Errorf("activity of %q signed by %q", actorURI, signer)
+	}
+	contextURI := push.Context.GetID().String()
+	if !strings.EqualFold(hostOfURI(contextURI), hostOfURI(actorURI)) {
+		return http.StatusForbidden, "Invalid actor", fmt.Errorf("push to %q by %q", contextURI, actorURI)
+	}
+	followingRepos, err := repo_model.FindFollowingReposByRepoID(ctx, localRepo.ID)
+	if err != nil {
+		return http.StatusInternalServerError, "FindFollowingReposByRepoID", err
+	}
+	if !slices.ContainsFunc(followingRepos, func(f *repo_model.FollowingRepo) bool { return f.URI == contextURI }) {
+		return http.StatusNotAcceptable, "Invalid context", fmt.Errorf("%s doesn't follow %q", localRepo.FullName(), contextURI)
+	}
+
+	var branch string
+	if push.Target != nil {
+		if target, err := ap.ToObject(push.Target); err == nil {
+			branch = naturalLanguageValue(target.Name)
+		}
+	}
+	if branch == "" || !git.IsValidRefPattern(branch) {
+		return http.StatusNotAcceptable, "Invalid target", fmt.Errorf("push to the branch %q", branch)
+	}
+	collection, err := ap.ToOrderedCollection(push.Object)
+	if err != nil {
+		return http.StatusNotAcceptable, "Invalid object", err
+	}
+	commits := repository.NewPushCommits()
+	for _, item := range collection.OrderedItems {
+		if len(commits.Commits) >= setting.UI.FeedMaxCommitNum {
+			break
+		}
+		c, err := fm.ToCommit(item)
+		if err != nil {
+			return http.StatusNotAcceptable, "Invalid object", err
+		}
+		if _, err := git.NewIDFromString(c.Hash); err != nil {
+			return http.StatusNotAcceptable, "Invalid object", fmt.Errorf("commit with the hash %q", c.Hash)
+		}
+		message := naturalLanguageValue(c.Content)
+		if message == "" {
+			message = naturalLanguageValue(c.Summary)
+		}
+		commits.Commits = append(commits.Commits, &repository.PushCommit{Sha1: c.Hash, Message: message, Timestamp: c.Created, URL: remoteCommitURL(c, contextURI)})
+	}
+	if len(commits.Commits) == 0 {
+		return http.StatusNotAcceptable, "Invalid object", fmt.Errorf("push without commits")
+	}
+	commits.HeadCommit = commits.Commits[0]
+	commits.Len = max(int(collection.TotalItems), len(commits.Commits))
+
+	remoteUser, err := GetOrCreateFederatedUser(ctx, actorURI)
+	if err != nil {
+		return http.StatusInternalServerError, "Unable to get the actor", err
+	}
+	data, err := json.Marshal(commits)
+	if err != nil {
+		return http.StatusInternalServerError, "Marshal", err
+	}
+	if err := activities_model.NotifyWatchers(ctx, &activities_model.Action{
+		ActUserID: remoteUser.ID,
+		ActUser:   remoteUser,
+		OpType:    activities_model.ActionFederatedPush,
+		Content:   string(data),
+		RepoID:    localRepo.ID,
+		Repo:      localRepo,
+		RefName:   git.RefNameFromBranch(branch).String(),
+		IsPrivate: localRepo.IsPrivate,
+	}); err != nil {
+		return http.StatusInternalServerError, "NotifyWatchers", err
+	}
+
+	if setting.Federation.SyncMirrorsOnPush && setting.Mirror.Enabled && localRepo.IsMirror && !localRepo.IsArchived {
+		log.Debug("ProcessPush: syncing the mirror %s after a push to %s", localRepo.FullName(), contextURI)
+		mirror_service.AddPullMirrorToQueue(localRepo.ID)
+	}
+	return 0, "", nil
+}
+
+// remoteCommitURL returns the IRI of the commit of a federated push if it is a web link
+// to the instance of the pushed repository, commits without one aren't linked
+func remoteCommitURL(c *fm.Commit, contextURI string) string {
+	if c.ID == "" {
+		return ""
+	}
+	u, err := url.Parse(c.ID.String())
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !strings.EqualFold(u.Host, hostOfURI(contextURI)) {
+		return ""
+	}
+	return u.String()
+}
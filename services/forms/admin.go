@@ -72,3 +72,16 @@ func (f *AdminDashboardForm) Validate(req *http.Request, errs binding.Errors) bi
 	ctx := context.GetValidateContext(req)
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
+
+// AdminFederationHostRuleForm form for blocking, silencing or allowing federation hosts
+type AdminFederationHostRuleForm struct {
+	Pattern string `binding:"Required;MaxSize(255)"`
+	Action  string `binding:"Required;In(allow,silence,block)"`
+	Reason  string `binding:"MaxSize(1024)"`
+}
+
+// Validate validates form fields
+func (f *AdminFederationHostRuleForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
@@ -15,7 +15,7 @@ import (
 // AuthenticationForm form for authentication
 type AuthenticationForm struct {
 	ID                            int64
-	Type                          int    `binding:"Range(2,7)"`
+	Type                          int    `binding:"Range(2,9)"`
 	Name                          string `binding:"Required;MaxSize(30)"`
 	Host                          string
 	Port                          int
@@ -81,6 +81,22 @@ type AuthenticationForm struct {
 	SSPIStripDomainNames          bool
 	SSPISeparatorReplacement      string `binding:"AlphaDashDot;MaxSize(5)"`
 	SSPIDefaultLanguage           string
+	SAMLIdpEntityID               string
+	SAMLIdpSsoURL                 string
+	SAMLIdpSloURL                 string
+	SAMLIdpCertificate            string
+	SAMLSpPrivateKey              string
+	SAMLSpCertificate             string
+	SAMLSignRequests              bool
+	SAMLAllowSHA1                 bool
+	SAMLNameIDFormat              string
+	SAMLAttributeUsername         string
+	SAMLAttributeEmail            string
+	SAMLAttributeFullName         string
+	SAMLAttributeGroups           string
+	SAMLIconURL                   string
+	SAMLGroupTeamMap              string `binding:"ValidGroupTeamMap"`
+	SAMLGroupTeamMapRemoval       bool
 	GroupTeamMap                  string `binding:"ValidGroupTeamMap"`
 	GroupTeamMapRemoval           bool
 }
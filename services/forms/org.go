@@ -53,6 +53,30 @@ func (f *UpdateOrgSettingForm) Validate(req *http.Request, errs binding.Errors)
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
 
+// OrgTwoFactorPolicyForm form for the two-factor authentication policy of an organization
+type OrgTwoFactorPolicyForm struct {
+	RequireTwoFactor bool
+	RemoveMembers    bool
+	GracePeriod      int64 `binding:"Range(0,365)"`
+}
+
+// Validate validates the fields
+func (f *OrgTwoFactorPolicyForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+// OrgIPAllowlistForm form for the IP allowlist of an organization
+type OrgIPAllowlistForm struct {
+	Allowlist string `binding:"MaxSize(4096)"`
+}
+
+// Validate validates the fields
+func (f *OrgIPAllowlistForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
 // ___________
 // \__    ___/___ _____    _____
 //   |    |_/ __ \\__  \  /     \
@@ -13,18 +13,75 @@ import (
 )
 
 type PackageCleanupRuleForm struct {
-	ID            int64
-	Enabled       bool
-	Type          string `binding:"Required;In(alpine,arch,cargo,chef,composer,conan,conda,container,cran,debian,generic,go,helm,maven,npm,nuget,pub,pypi,rpm,rubygems,swift,vagrant)"`
-	KeepCount     int    `binding:"In(0,1,5,10,25,50,100)"`
-	KeepPattern   string `binding:"RegexPattern"`
-	RemoveDays    int    `binding:"In(0,7,14,30,60,90,180)"`
-	RemovePattern string `binding:"RegexPattern"`
-	MatchFullName bool
-	Action        string `binding:"Required;In(save,remove)"`
+	ID                int64
+	Enabled           bool
+	Type              string `binding:"Required;In(alpine,ansible,arch,cargo,chef,composer,conan,conda,container,cran,debian,generic,go,helm,hex,maven,npm,nuget,pub,pypi,rpm,rubygems,swift,terraform,vagrant)"`
+	KeepCount         int    `binding:"In(0,1,5,10,25,50,100)"`
+	KeepPattern       string `binding:"RegexPattern"`
+	KeepSemverPatches int    `binding:"In(0,1,2,3,5,10)"`
+	RemoveDays        int    `binding:"In(0,7,14,30,60,90,180)"`
+	RemoveUnusedDays  int    `binding:"In(0,7,14,30,60,90,180,365)"`
+	RemovePattern     string `binding:"RegexPattern"`
+	MatchFullName     bool
+	Action            string `binding:"Required;In(save,remove)"`
 }
 
 func (f *PackageCleanupRuleForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
 	ctx := context.GetValidateContext(req)
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
+
+type PackageRemoteForm struct {
+	ID       int64
+	Enabled  bool
+	Type     string `binding:"Required;In(container,go,maven,npm,pypi)"`
+	URL      string `binding:"Required;ValidUrl"`
+	Username string
+	Password string
+	AuthHost string
+	Action   string `binding:"Required;In(save,remove)"`
+}
+
+func (f *PackageRemoteForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+type PackageVirtualRegistryForm struct {
+	ID           int64
+	Enabled      bool
+	Type         string `binding:"Required;In(maven,npm,pypi)"`
+	Members      string `binding:"Required"`
+	DefaultOwner string `binding:"Required"`
+	Action       string `binding:"Required;In(save,remove)"`
+}
+
+func (f *PackageVirtualRegistryForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+type PackageTrustedKeyForm struct {
+	ID      int64
+	Name    string `binding:"Required;MaxSize(255)"`
+	Content string `binding:"Required"`
+	Action  string `binding:"Required;In(save,remove)"`
+}
+
+func (f *PackageTrustedKeyForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+type PackageProtectionRuleForm struct {
+	ID             int64
+	Type           string `binding:"Required;In(alpine,arch,cargo,chef,composer,conan,conda,container,cran,debian,generic,go,helm,maven,npm,nuget,pub,pypi,rpm,rubygems,swift,terraform,vagrant)"`
+	VersionPattern string `binding:"Required;MaxSize(255)"`
+	AllowedTeams   string
+	Action         string `binding:"Required;In(save,remove)"`
+}
+
+func (f *PackageProtectionRuleForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
@@ -8,6 +8,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"strings"
+	"unicode"
 
 	auth_model "code.gitea.io/gitea/models/auth"
 	"code.gitea.io/gitea/modules/setting"
@@ -187,6 +188,9 @@ type AccessTokenForm struct {
 
 	// PKCE support
 	CodeVerifier string `json:"code_verifier"`
+
+	// device authorization grant support
+	DeviceCode string `json:"device_code"`
 }
 
 // Validate validates the fields
@@ -195,6 +199,31 @@ func (f *AccessTokenForm) Validate(req *http.Request, errs binding.Errors) bindi
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
 
+// DeviceAuthorizationForm for requesting a device code of the device authorization grant
+type DeviceAuthorizationForm struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	Scope        string `json:"scope"`
+}
+
+// Validate validates the fields
+func (f *DeviceAuthorizationForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+// GrantDeviceForm form for authorizing devices by their user code
+type GrantDeviceForm struct {
+	UserCode string `binding:"Required"`
+	Granted  bool
+}
+
+// Validate validates the fields
+func (f *GrantDeviceForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
 // IntrospectTokenForm for introspecting tokens
 type IntrospectTokenForm struct {
 	Token string `json:"token"`
@@ -371,6 +400,13 @@ func (f *EditVariableForm) Validate(req *http.Request, errs binding.Errors) bind
 type NewAccessTokenForm struct {
 	Name  string `binding:"Required;MaxSize(255)" locale:"settings.token_name"`
 	Scope []string
+	// ExpiresAt is the last day the token is valid on, formatted as 2006-01-02
+	ExpiresAt    string
+	Organization string
+	// Repositories are the full names of the repositories separated by commas or spaces
+	Repositories string
+	// Permissions are given as name:mode
+	Permissions []string
 }
 
 // Validate validates the fields
@@ -385,6 +421,26 @@ func (f *NewAccessTokenForm) GetScope() (auth_model.AccessTokenScope, error) {
 	return s, err
 }
 
+// GetRepositories returns the full names of the repositories the token is restricted to
+func (f *NewAccessTokenForm) GetRepositories() []string {
+	return strings.FieldsFunc(f.Repositories, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// GetPermissions returns the permissions of a fine-grained token
+func (f *NewAccessTokenForm) GetPermissions() (auth_model.AccessTokenPermissions, error) {
+	permissions := make(map[string]string, len(f.Permissions))
+	for _, p := range f.Permissions {
+		if p == "" {
+			continue
+		}
+		name, mode, _ := strings.Cut(p, ":")
+		permissions[name] = mode
+	}
+	return auth_model.ParseAccessTokenPermissions(permissions)
+}
+
 // EditOAuth2ApplicationForm form for editing oauth2 applications
 type EditOAuth2ApplicationForm struct {
 	Name               string `binding:"Required;MaxSize(255)" form:"application_name"`
@@ -422,7 +478,8 @@ func (f *TwoFactorScratchAuthForm) Validate(req *http.Request, errs binding.Erro
 
 // WebauthnRegistrationForm for reserving an WebAuthn name
 type WebauthnRegistrationForm struct {
-	Name string `binding:"Required"`
+	Name    string `binding:"Required"`
+	Passkey bool
 }
 
 // Validate validates the fields
@@ -444,8 +501,9 @@ func (f *WebauthnDeleteForm) Validate(req *http.Request, errs binding.Errors) bi
 
 // PackageSettingForm form for package settings
 type PackageSettingForm struct {
-	Action string
-	RepoID int64 `form:"repo_id"`
+	Action                 string
+	RepoID                 int64 `form:"repo_id"`
+	InheritRepoPermissions bool  `form:"inherit_repo_permissions"`
 }
 
 // Validate validates the fields
@@ -304,6 +304,8 @@ func deleteIssue(ctx context.Context, issue *issues_model.Issue) error {
 		&issues_model.Comment{RefIssueID: issue.ID},
 		&issues_model.IssueDependency{DependencyID: issue.ID},
 		&issues_model.Comment{DependentIssueID: issue.ID},
+		&issues_model.FederatedIssue{IssueID: issue.ID},
+		&issues_model.FederatedComment{IssueID: issue.ID},
 	); err != nil {
 		return err
 	}
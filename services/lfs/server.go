@@ -569,6 +569,10 @@ func authenticate(ctx *context.Context, repository *repo_model.Repository, autho
 		log.Warn("Authentication failure for provided token with Error: %v", err)
 		return false
 	}
+	if allowed, err := context.IsRequestIPAllowed(ctx, ctx.Req, user, repository.Owner); err != nil || !allowed {
+		log.Warn("Authentication failure for %-v in repo %-v: the request address %s is not allowed (err: %v)", user, repository, ctx.Req.RemoteAddr, err)
+		return false
+	}
 	ctx.Doer = user
 	return true
 }
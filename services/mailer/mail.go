@@ -45,6 +45,7 @@ const (
 	mailAuth2faDisabled        base.TplName = "auth/2fa_disabled"
 	mailAuthRemovedSecurityKey base.TplName = "auth/removed_security_key"
 	mailAuthTOTPEnrolled       base.TplName = "auth/totp_enrolled"
+	mailAuthTokenExpiry        base.TplName = "auth/token_expiry"
 
 	mailNotifyCollaborator base.TplName = "notify/collaborator"
 
@@ -730,3 +731,32 @@ func SendTOTPEnrolled(ctx context.Context, u *user_model.User) error {
 	SendAsync(msg)
 	return nil
 }
+
+// SendAccessTokenExpiry informs the user that some of their personal access tokens will expire soon
+func SendAccessTokenExpiry(ctx context.Context, u *user_model.User, tokens []*auth_model.AccessToken) error {
+	if setting.MailService == nil || len(tokens) == 0 {
+		return nil
+	}
+	locale := translation.NewLocale(u.Language)
+
+	data := map[string]any{
+		"locale":      locale,
+		"Tokens":      tokens,
+		"Link":        setting.AppURL + "user/settings/applications",
+		"DisplayName": u.DisplayName(),
+		"Username":    u.Name,
+		"Language":    locale.Language(),
+	}
+
+	var content bytes.Buffer
+
+	if err := bodyTemplates.ExecuteTemplate(&content, string(mailAuthTokenExpiry), data); err != nil {
+		return err
+	}
+
+	msg := NewMessage(u.EmailTo(), locale.TrString("mail.token_expiry.subject"), content.String())
+	msg.Info = fmt.Sprintf("UID: %d, access token expiry notification", u.ID)
+
+	SendAsync(msg)
+	return nil
+}
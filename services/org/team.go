@@ -0,0 +1,46 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package org
+
+import (
+	"context"
+	"strconv"
+
+	"code.gitea.io/gitea/models"
+	audit_model "code.gitea.io/gitea/models/audit"
+	org_model "code.gitea.io/gitea/models/organization"
+	user_model "code.gitea.io/gitea/models/user"
+	audit_service "code.gitea.io/gitea/services/audit"
+)
+
+// AddTeamMember adds the user to the team and records it in the audit log
+func AddTeamMember(ctx context.Context, team *org_model.Team, userID int64) error {
+	if err := models.AddTeamMember(ctx, team, userID); err != nil {
+		return err
+	}
+	audit_service.Record(ctx, audit_model.ActionTeamMemberAdd, nil, team.OrgID, audit_service.TeamTarget(team), audit_model.Diff{
+		"member": {New: memberName(ctx, userID)},
+	})
+	return nil
+}
+
+// RemoveTeamMember removes the user from the team and records it in the audit log
+func RemoveTeamMember(ctx context.Context, team *org_model.Team, userID int64) error {
+	if err := models.RemoveTeamMember(ctx, team, userID); err != nil {
+		return err
+	}
+	audit_service.Record(ctx, audit_model.ActionTeamMemberRemove, nil, team.OrgID, audit_service.TeamTarget(team), audit_model.Diff{
+		"member": {Old: memberName(ctx, userID)},
+	})
+	return nil
+}
+
+// memberName returns the name of the member for the audit log, or its ID if the user doesn't exist anymore
+func memberName(ctx context.Context, userID int64) string {
+	u, err := user_model.GetUserByID(ctx, userID)
+	if err != nil {
+		return strconv.FormatInt(userID, 10)
+	}
+	return u.Name
+}
@@ -0,0 +1,38 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package org
+
+import (
+	"context"
+
+	"code.gitea.io/gitea/models"
+	org_model "code.gitea.io/gitea/models/organization"
+	"code.gitea.io/gitea/modules/log"
+)
+
+// RemoveMembersWithoutTwoFactor removes the members who didn't enroll two-factor authentication
+// from the organizations which require it after the grace period is over
+func RemoveMembersWithoutTwoFactor(ctx context.Context) error {
+	policies, err := org_model.FindExpiredTwoFactorPolicies(ctx)
+	if err != nil {
+		return err
+	}
+	for _, policy := range policies {
+		members, err := org_model.GetMembersWithoutTwoFactor(ctx, policy.OrgID)
+		if err != nil {
+			return err
+		}
+		for _, member := range members {
+			if err := models.RemoveOrgUser(ctx, policy.OrgID, member.ID); err != nil {
+				if org_model.IsErrLastOrgOwner(err) {
+					log.Warn("Unable to remove the last owner %d of organization %d without two-factor authentication", member.ID, policy.OrgID)
+					continue
+				}
+				return err
+			}
+			log.Info("Removed user %d from organization %d because of missing two-factor authentication", member.ID, policy.OrgID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package org
+
+import (
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/organization"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoveMembersWithoutTwoFactor(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	// user2 is the last owner of org3, user4 enrolled TOTP and user28 has no second factor
+	unittest.AssertSuccessfulInsert(t, &auth_model.TwoFactor{UID: 4})
+
+	// the grace period isn't over yet
+	require.NoError(t, organization.SetTwoFactorPolicy(db.DefaultContext, 3, timeutil.TimeStampNow().Add(3600)))
+	require.NoError(t, RemoveMembersWithoutTwoFactor(db.DefaultContext))
+	unittest.AssertExistsAndLoadBean(t, &organization.OrgUser{OrgID: 3, UID: 28})
+
+	require.NoError(t, organization.SetTwoFactorPolicy(db.DefaultContext, 3, timeutil.TimeStampNow().Add(-1)))
+	require.NoError(t, RemoveMembersWithoutTwoFactor(db.DefaultContext))
+	unittest.AssertNotExistsBean(t, &organization.OrgUser{OrgID: 3, UID: 28})
+	unittest.AssertExistsAndLoadBean(t, &organization.OrgUser{OrgID: 3, UID: 4})
+	unittest.AssertExistsAndLoadBean(t, &organization.OrgUser{OrgID: 3, UID: 2})
+}
@@ -0,0 +1,252 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package attestation
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	packages_model "code.gitea.io/gitea/models/packages"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/json"
+	attestation_module "code.gitea.io/gitea/modules/packages/attestation"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+)
+
+// ErrSubjectMismatch is returned if an attestation is not about any file of the package version
+var ErrSubjectMismatch = util.NewInvalidArgumentErrorf("attestation subject does not match any package file")
+
+// GetOrCreateKeyPair gets or creates the ECDSA keys used to sign attestations created by the instance
+func GetOrCreateKeyPair(ctx context.Context, ownerID int64) (string, string, error) {
+	priv, err := user_model.GetSetting(ctx, ownerID, attestation_module.SettingKeyPrivate)
+	if err != nil && !errors.Is(err, util.ErrNotExist) {
+		return "", "", err
+	}
+
+	pub, err := user_model.GetSetting(ctx, ownerID, attestation_module.SettingKeyPublic)
+	if err != nil && !errors.Is(err, util.ErrNotExist) {
+		return "", "", err
+	}
+
+	if priv == "" || pub == "" {
+		priv, pub, err = generateKeypair()
+		if err != nil {
+			return "", "", err
+		}
+
+		if err := user_model.SetUserSetting(ctx, ownerID, attestation_module.SettingKeyPrivate, priv); err != nil {
+			return "", "", err
+		}
+
+		if err := user_model.SetUserSetting(ctx, ownerID, attestation_module.SettingKeyPublic, pub); err != nil {
+			return "", "", err
+		}
+	}
+
+	return priv, pub, nil
+}
+
+func generateKeypair() (string, string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+
+	privBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	pubBytes, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	priv := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privBytes})
+	pub := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes})
+
+	return string(priv), string(pub), nil
+}
+
+func loadPrivateKey(content string) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(content))
+	if block == nil {
+		return nil, errors.New("failed to decode private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an ECDSA key")
+	}
+	return ecKey, nil
+}
+
+// verifyEnvelope checks the signatures of the envelope against the key of the owner and the trusted keys.
+// It returns the fingerprint of the first key which verifies a signature or an empty string.
+func verifyEnvelope(ctx context.Context, ownerID int64, e *attestation_module.Envelope) (string, error) {
+	_, ownerKey, err := GetOrCreateKeyPair(ctx, ownerID)
+	if err != nil {
+		return "", err
+	}
+
+	contents := []string{ownerKey}
+
+	ptks, err := packages_model.GetTrustedKeysByOwner(ctx, ownerID)
+	if err != nil {
+		return "", err
+	}
+	for _, ptk := range ptks {
+		contents = append(contents, ptk.Content)
+	}
+
+	for _, content := range contents {
+		pub, err := attestation_module.ParsePublicKey(content)
+		if err != nil {
+			continue
+		}
+		if e.Verify(pub) {
+			return attestation_module.KeyFingerprint(pub)
+		}
+	}
+	return "", nil
+}
+
+func checkSubjects(pd *packages_model.PackageDescriptor, s *attestation_module.Statement) error {
+	for _, pfd := range pd.Files {
+		if s.HasSubjectDigest(pfd.Blob.HashSHA256) {
+			return nil
+		}
+	}
+	return ErrSubjectMismatch
+}
+
+func insertAttestation(ctx context.Context, doer *user_model.User, pd *packages_model.PackageDescriptor, e *attestation_module.Envelope, predicateType, keyID string) (*packages_model.PackageAttestation, error) {
+	envelope, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return packages_model.InsertAttestation(ctx, &packages_model.PackageAttestation{
+		VersionID:     pd.Version.ID,
+		CreatorID:     doer.ID,
+		PredicateType: predicateType,
+		Envelope:      string(envelope),
+		IsVerified:    keyID != "",
+		KeyID:         keyID,
+	})
+}
+
+// UploadAttestation attaches the attestation to the package version.
+// The attestation must be about a file of the version. It is marked as verified
+// if it is signed by a trusted key of the owner.
+func UploadAttestation(ctx context.Context, doer *user_model.User, pd *packages_model.PackageDescriptor, r io.Reader) (*packages_model.PackageAttestation, error) {
+	e, s, err := attestation_module.ParseEnvelope(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkSubjects(pd, s); err != nil {
+		return nil, err
+	}
+
+	keyID, err := verifyEnvelope(ctx, pd.Owner.ID, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return insertAttestation(ctx, doer, pd, e, s.PredicateType, keyID)
+}
+
+// AttestActionsBuild creates a SLSA provenance attestation for a package version published by an Actions task
+// https://slsa.dev/spec/v1.0/provenance
+func AttestActionsBuild(ctx context.Context, doer *user_model.User, pd *packages_model.PackageDescriptor, taskID int64) (*packages_model.PackageAttestation, error) {
+	task, err := actions_model.GetTaskByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := task.LoadAttributes(ctx); err != nil {
+		return nil, err
+	}
+	run := task.Job.Run
+	if err := run.LoadRepo(ctx); err != nil {
+		return nil, err
+	}
+
+	subjects := make([]*attestation_module.Subject, 0, len(pd.Files))
+	for _, pfd := range pd.Files {
+		subjects = append(subjects, &attestation_module.Subject{
+			Name:   pfd.File.Name,
+			Digest: map[string]string{"sha256": pfd.Blob.HashSHA256},
+		})
+	}
+	if len(subjects) == 0 {
+		return nil, ErrSubjectMismatch
+	}
+
+	repoURL := run.Repo.HTMLURL()
+
+	statement := &attestation_module.Statement{
+		Type:          attestation_module.StatementTypeV1,
+		Subject:       subjects,
+		PredicateType: attestation_module.PredicateTypeSLSAProvenanceV1,
+		Predicate: map[string]any{
+			"buildDefinition": map[string]any{
+				"buildType": "https://forgejo.org/actions/workflow@v1",
+				"externalParameters": map[string]any{
+					"workflow": map[string]any{
+						"repository": repoURL,
+						"path":       ".forgejo/workflows/" + run.WorkflowID,
+						"ref":        run.Ref,
+					},
+				},
+				"internalParameters": map[string]any{
+					"event": string(run.Event),
+					"job":   task.Job.JobID,
+				},
+				"resolvedDependencies": []any{
+					map[string]any{
+						"uri":    fmt.Sprintf("git+%s@%s", repoURL, run.Ref),
+						"digest": map[string]string{"gitCommit": run.CommitSHA},
+					},
+				},
+			},
+			"runDetails": map[string]any{
+				"builder": map[string]any{
+					"id": strings.TrimSuffix(setting.AppURL, "/") + "/actions/runner",
+				},
+				"metadata": map[string]any{
+					"invocationId": fmt.Sprintf("%s/jobs/%d/attempt/%d", run.HTMLURL(), task.Job.ID, task.Attempt),
+				},
+			},
+		},
+	}
+
+	priv, _, err := GetOrCreateKeyPair(ctx, pd.Owner.ID)
+	if err != nil {
+		return nil, err
+	}
+	key, err := loadPrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := attestation_module.Sign(statement, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return insertAttestation(ctx, doer, pd, e, statement.PredicateType, e.Signatures[0].KeyID)
+}
@@ -0,0 +1,43 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package attestation
+
+import (
+	"context"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/web/middleware"
+	notify_service "code.gitea.io/gitea/services/notify"
+)
+
+func init() {
+	notify_service.RegisterNotifier(&attestationNotifier{})
+}
+
+type attestationNotifier struct {
+	notify_service.NullNotifier
+}
+
+var _ notify_service.Notifier = &attestationNotifier{}
+
+// PackageCreate attests packages which are published by an Actions task
+func (a *attestationNotifier) PackageCreate(ctx context.Context, doer *user_model.User, pd *packages_model.PackageDescriptor) {
+	data := middleware.GetContextData(ctx)
+	if data == nil {
+		return
+	}
+	if isActionsToken, ok := data["IsActionsToken"].(bool); !ok || !isActionsToken {
+		return
+	}
+	taskID, ok := data["ActionsTaskID"].(int64)
+	if !ok {
+		return
+	}
+
+	if _, err := AttestActionsBuild(ctx, doer, pd, taskID); err != nil {
+		log.Error("Error creating attestation for package version %d: %v", pd.Version.ID, err)
+	}
+}
@@ -1,29 +1,53 @@
 // Copyright 2022 The Gitea Authors. All rights reserved.
+// Copyright 2024 The Forgejo Authors. All rights reserved.
 // SPDX-License-Identifier: MIT
 
 package packages
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
+	actions_model "code.gitea.io/gitea/models/actions"
 	auth_model "code.gitea.io/gitea/models/auth"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/perm"
+	access_model "code.gitea.io/gitea/models/perm/access"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unit"
 	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/web/middleware"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// ErrPackageAccessDenied is returned if a repository scoped request accesses a package which does not inherit the permissions of a suitable repository
+var ErrPackageAccessDenied = util.NewPermissionDeniedErrorf("access to the package is not granted by the linked repository")
+
+// repositoryScopeKey is the context data key of the repository scope of a request
+const repositoryScopeKey = "PackagesRepositoryScope"
+
+// repositoryScope limits the access of a request to the packages which inherit the permissions of their linked repository
+type repositoryScope struct {
+	Doer *user_model.User
+}
+
 type packageClaims struct {
 	jwt.RegisteredClaims
-	UserID int64
-	Scope  auth_model.AccessTokenScope
+	UserID        int64
+	Scope         auth_model.AccessTokenScope
+	AccessTokenID int64
 }
 
-func CreateAuthorizationToken(u *user_model.User, scope auth_model.AccessTokenScope) (string, error) {
+// CreateAuthorizationToken creates a token for the user which carries the scope and the id of the personal access token the user authenticated with
+func CreateAuthorizationToken(u *user_model.User, scope auth_model.AccessTokenScope, accessTokenID int64) (string, error) {
 	now := time.Now()
 
 	claims := packageClaims{
@@ -31,8 +55,9 @@ func CreateAuthorizationToken(u *user_model.User, scope auth_model.AccessTokenSc
 			ExpiresAt: jwt.NewNumericDate(now.Add(24 * time.Hour)),
 			NotBefore: jwt.NewNumericDate(now),
 		},
-		UserID: u.ID,
-		Scope:  scope,
+		UserID:        u.ID,
+		Scope:         scope,
+		AccessTokenID: accessTokenID,
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
@@ -44,16 +69,17 @@ func CreateAuthorizationToken(u *user_model.User, scope auth_model.AccessTokenSc
 	return tokenString, nil
 }
 
-func ParseAuthorizationToken(req *http.Request) (int64, auth_model.AccessTokenScope, error) {
+// ParseAuthorizationToken returns the user id, the scope and the id of the personal access token of the token in the request
+func ParseAuthorizationToken(req *http.Request) (int64, auth_model.AccessTokenScope, int64, error) {
 	h := req.Header.Get("Authorization")
 	if h == "" {
-		return 0, "", nil
+		return 0, "", 0, nil
 	}
 
 	parts := strings.SplitN(h, " ", 2)
 	if len(parts) != 2 {
 		log.Error("split token failed: %s", h)
-		return 0, "", fmt.Errorf("split token failed")
+		return 0, "", 0, fmt.Errorf("split token failed")
 	}
 
 	token, err := jwt.ParseWithClaims(parts[1], &packageClaims{}, func(t *jwt.Token) (any, error) {
@@ -63,13 +89,261 @@ func ParseAuthorizationToken(req *http.Request) (int64, auth_model.AccessTokenSc
 		return setting.GetGeneralTokenSigningSecret(), nil
 	})
 	if err != nil {
-		return 0, "", err
+		return 0, "", 0, err
 	}
 
 	c, ok := token.Claims.(*packageClaims)
 	if !token.Valid || !ok {
-		return 0, "", fmt.Errorf("invalid token claim")
+		return 0, "", 0, fmt.Errorf("invalid token claim")
+	}
+
+	return c.UserID, c.Scope, c.AccessTokenID, nil
+}
+
+// RepositoryAccessMode returns the access mode the doer inherits for the package from its linked repository
+func RepositoryAccessMode(ctx context.Context, doer *user_model.User, p *packages_model.Package) (perm.AccessMode, error) {
+	if !p.InheritRepoPermissions || p.RepoID == 0 {
+		return perm.AccessModeNone, nil
+	}
+
+	repo, err := repo_model.GetRepositoryByID(ctx, p.RepoID)
+	if err != nil {
+		if repo_model.IsErrRepoNotExist(err) {
+			return perm.AccessModeNone, nil
+		}
+		return perm.AccessModeNone, err
+	}
+	// a transferred repository doesn't grant access to the packages of its previous owner
+	if repo.OwnerID != p.OwnerID {
+		return perm.AccessModeNone, nil
+	}
+
+	return repositoryAccessMode(ctx, doer, repo)
+}
+
+// HasRepositoryAccess checks if the doer has the access mode for any repository whose permissions are inherited by packages of the owner.
+// An Actions task always has access through its own repository if it belongs to the owner.
+func HasRepositoryAccess(ctx context.Context, doer, owner *user_model.User, accessMode perm.AccessMode) (bool, error) {
+	if doer != nil && doer.ID == user_model.ActionsUserID {
+		task, err := actionsTaskFromContext(ctx)
+		if err != nil || task == nil {
+			return false, err
+		}
+		repo, err := repo_model.GetRepositoryByID(ctx, task.RepoID)
+		if err != nil {
+			return false, err
+		}
+		if repo.OwnerID != owner.ID {
+			return false, nil
+		}
+		mode, err := repositoryAccessMode(ctx, doer, repo)
+		return mode >= accessMode, err
+	}
+
+	repoIDs, err := accessibleRepositoryIDs(ctx, doer, owner.ID, accessMode, true)
+	return len(repoIDs) > 0, err
+}
+
+// accessibleRepositoryIDs returns the ids of the repositories whose permissions are inherited by packages of the owner
+// and grant the doer the access mode. If firstOnly is set, the search stops at the first repository.
+func accessibleRepositoryIDs(ctx context.Context, doer *user_model.User, ownerID int64, accessMode perm.AccessMode, firstOnly bool) ([]int64, error) {
+	repoIDs, err := packages_model.GetRepositoryIDsInheritingPermissions(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	accessible := make([]int64, 0, len(repoIDs))
+	for _, repoID := range repoIDs {
+		repo, err := repo_model.GetRepositoryByID(ctx, repoID)
+		if err != nil {
+			if repo_model.IsErrRepoNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		if repo.OwnerID != ownerID {
+			continue
+		}
+		mode, err := repositoryAccessMode(ctx, doer, repo)
+		if err != nil {
+			return nil, err
+		}
+		if mode >= accessMode {
+			accessible = append(accessible, repo.ID)
+			if firstOnly {
+				break
+			}
+		}
+	}
+	return accessible, nil
+}
+
+func repositoryAccessMode(ctx context.Context, doer *user_model.User, repo *repo_model.Repository) (perm.AccessMode, error) {
+	if doer != nil && doer.ID == user_model.ActionsUserID {
+		task, err := actionsTaskFromContext(ctx)
+		if err != nil || task == nil || task.RepoID != repo.ID {
+			return perm.AccessModeNone, err
+		}
+		if task.IsForkPullRequest {
+			return perm.AccessModeRead, nil
+		}
+		return perm.AccessModeWrite, nil
+	}
+
+	permission, err := access_model.GetUserRepoPermission(ctx, repo, doer)
+	if err != nil {
+		return perm.AccessModeNone, err
+	}
+	return permission.UnitAccessMode(unit.TypePackages), nil
+}
+
+func actionsTaskFromContext(ctx context.Context) (*actions_model.ActionTask, error) {
+	data := middleware.GetContextData(ctx)
+	if data == nil {
+		return nil, nil
+	}
+	taskID, ok := data["ActionsTaskID"].(int64)
+	if !ok {
+		return nil, nil
+	}
+	return actions_model.GetTaskByID(ctx, taskID)
+}
+
+// SetRepositoryScope limits the access of the request to the packages which inherit the permissions of a linked repository.
+// Every package the request creates, modifies, deletes or downloads is checked against the permissions of the doer in that repository.
+func SetRepositoryScope(data middleware.ContextDataStore, doer *user_model.User) {
+	data.GetData()[repositoryScopeKey] = &repositoryScope{Doer: doer}
+}
+
+func repositoryScopeFromContext(ctx context.Context) *repositoryScope {
+	data := middleware.GetContextData(ctx)
+	if data == nil {
+		return nil
+	}
+	scope, _ := data[repositoryScopeKey].(*repositoryScope)
+	return scope
+}
+
+// CheckRepositoryScope checks if a repository scoped request has the access mode for the package.
+// Requests which are not repository scoped are not restricted.
+func CheckRepositoryScope(ctx context.Context, p *packages_model.Package, accessMode perm.AccessMode) error {
+	scope := repositoryScopeFromContext(ctx)
+	if scope == nil {
+		return nil
+	}
+
+	mode, err := RepositoryAccessMode(ctx, scope.Doer, p)
+	if err != nil {
+		return err
+	}
+	if mode < accessMode {
+		return ErrPackageAccessDenied
+	}
+	return nil
+}
+
+// CheckRepositoryScopeByPackageID checks if a repository scoped request has the access mode for the package with the id
+func CheckRepositoryScopeByPackageID(ctx context.Context, packageID int64, accessMode perm.AccessMode) error {
+	if repositoryScopeFromContext(ctx) == nil {
+		return nil
+	}
+
+	p, err := packages_model.GetPackageByID(ctx, packageID)
+	if err != nil {
+		return err
+	}
+	return CheckRepositoryScope(ctx, p, accessMode)
+}
+
+// FilterVersionsByRepositoryScope returns the package versions a repository scoped request may read.
+// Requests which are not repository scoped are not restricted.
+func FilterVersionsByRepositoryScope(ctx context.Context, pvs []*packages_model.PackageVersion) ([]*packages_model.PackageVersion, error) {
+	if repositoryScopeFromContext(ctx) == nil {
+		return pvs, nil
+	}
+
+	readable := make(map[int64]bool)
+	filtered := make([]*packages_model.PackageVersion, 0, len(pvs))
+	for _, pv := range pvs {
+		ok, checked := readable[pv.PackageID]
+		if !checked {
+			err := CheckRepositoryScopeByPackageID(ctx, pv.PackageID, perm.AccessModeRead)
+			if err != nil && !errors.Is(err, ErrPackageAccessDenied) {
+				return nil, err
+			}
+			ok = err == nil
+			readable[pv.PackageID] = ok
+		}
+		if ok {
+			filtered = append(filtered, pv)
+		}
+	}
+	return filtered, nil
+}
+
+// LimitSearchByRepositoryScope limits the search of a repository scoped request to the packages of the owner
+// which inherit the permissions of a linked repository the doer can read.
+// Requests which are not repository scoped are not restricted.
+func LimitSearchByRepositoryScope(ctx context.Context, opts *packages_model.PackageSearchOptions) error {
+	scope := repositoryScopeFromContext(ctx)
+	if scope == nil {
+		return nil
+	}
+
+	if scope.Doer != nil && scope.Doer.ID == user_model.ActionsUserID {
+		task, err := actionsTaskFromContext(ctx)
+		if err != nil {
+			return err
+		}
+		opts.InheritRepoIDs = []int64{}
+		if task != nil {
+			opts.InheritRepoIDs = append(opts.InheritRepoIDs, task.RepoID)
+		}
+		return nil
+	}
+
+	repoIDs, err := accessibleRepositoryIDs(ctx, scope.Doer, opts.OwnerID, perm.AccessModeRead, false)
+	if err != nil {
+		return err
+	}
+	opts.InheritRepoIDs = repoIDs
+	return nil
+}
+
+// CheckRepositoryScopeByName checks if a repository scoped request has the access mode for the package with the name.
+// A package which does not exist yet may only be created by an Actions task, because it gets linked to the repository of the task.
+func CheckRepositoryScopeByName(ctx context.Context, ownerID int64, packageType packages_model.Type, name string, accessMode perm.AccessMode) error {
+	scope := repositoryScopeFromContext(ctx)
+	if scope == nil {
+		return nil
+	}
+
+	p, err := packages_model.GetPackageByName(ctx, ownerID, packageType, name)
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageNotExist) {
+			if accessMode == perm.AccessModeRead || (scope.Doer != nil && scope.Doer.ID == user_model.ActionsUserID) {
+				return nil
+			}
+			return ErrPackageAccessDenied
+		}
+		return err
+	}
+	return CheckRepositoryScope(ctx, p, accessMode)
+}
+
+// LinkActionsTaskRepository links a package which is about to be created by a repository scoped Actions task
+// to the repository of the task, so the package inherits the permissions of that repository.
+func LinkActionsTaskRepository(ctx context.Context, p *packages_model.Package) error {
+	scope := repositoryScopeFromContext(ctx)
+	if scope == nil || scope.Doer == nil || scope.Doer.ID != user_model.ActionsUserID {
+		return nil
+	}
+
+	task, err := actionsTaskFromContext(ctx)
+	if err != nil || task == nil {
+		return err
 	}
 
-	return c.UserID, c.Scope, nil
+	p.RepoID = task.RepoID
+	p.InheritRepoPermissions = true
+	return nil
 }
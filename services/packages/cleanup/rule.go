@@ -0,0 +1,175 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package container
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/modules/container"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/optional"
+	packages_service "code.gitea.io/gitea/services/packages"
+	container_service "code.gitea.io/gitea/services/packages/container"
+
+	"github.com/hashicorp/go-version"
+)
+
+// maxVersionsPerRun limits the number of versions of a package which are inspected in one run
+const maxVersionsPerRun = 200
+
+// GetVersionsToRemove returns the versions of the package which are removed by the cleanup rule
+func GetVersionsToRemove(ctx context.Context, pcr *packages_model.PackageCleanupRule, p *packages_model.Package) ([]*packages_model.PackageVersion, error) {
+	if err := pcr.CompiledPattern(); err != nil {
+		return nil, err
+	}
+
+	// all versions are loaded because the kept semver patches are the highest ones of all versions,
+	// not only of the inspected ones
+	pvs, _, err := packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
+		PackageID:  p.ID,
+		IsInternal: optional.Some(false),
+		Sort:       packages_model.SortCreatedDesc,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keepSemver := latestSemverPatches(pvs, pcr.KeepSemverPatches)
+
+	protectionRules, err := packages_service.GetProtectionRules(ctx, p.OwnerID, p.Type)
+	if err != nil {
+		return nil, fmt.Errorf("GetProtectionRules failed: %w", err)
+	}
+
+	if pcr.KeepCount >= len(pvs) {
+		return nil, nil
+	}
+	pvs = pvs[pcr.KeepCount:]
+	if len(pvs) > maxVersionsPerRun {
+		pvs = pvs[:maxVersionsPerRun]
+	}
+
+	olderThan := time.Now().AddDate(0, 0, -pcr.RemoveDays)
+	unusedSince := time.Now().AddDate(0, 0, -pcr.RemoveUnusedDays)
+
+	versionsToRemove := make([]*packages_model.PackageVersion, 0, len(pvs))
+	for _, pv := range pvs {
+		if pcr.Type == packages_model.TypeContainer {
+			if skip, err := container_service.ShouldBeSkipped(ctx, pcr, p, pv); err != nil {
+				return nil, fmt.Errorf("container.ShouldBeSkipped failed: %w", err)
+			} else if skip {
+				log.Debug("Rule[%d]: keep '%s/%s' (container)", pcr.ID, p.Name, pv.Version)
+				continue
+			}
+		}
+
+		if protectionRules.IsProtected(pv.Version) {
+			log.Debug("Rule[%d]: keep '%s/%s' (protected)", pcr.ID, p.Name, pv.Version)
+			continue
+		}
+
+		toMatch := pv.LowerVersion
+		if pcr.MatchFullName {
+			toMatch = p.LowerName + "/" + pv.LowerVersion
+		}
+
+		if pcr.KeepPatternMatcher != nil && pcr.KeepPatternMatcher.MatchString(toMatch) {
+			log.Debug("Rule[%d]: keep '%s/%s' (keep pattern)", pcr.ID, p.Name, pv.Version)
+			continue
+		}
+		if keepSemver.Contains(pv.ID) {
+			log.Debug("Rule[%d]: keep '%s/%s' (semver patches)", pcr.ID, p.Name, pv.Version)
+			continue
+		}
+		if pv.CreatedUnix.AsLocalTime().After(olderThan) {
+			log.Debug("Rule[%d]: keep '%s/%s' (remove days)", pcr.ID, p.Name, pv.Version)
+			continue
+		}
+		if pcr.RemoveUnusedDays > 0 {
+			lastUsed := pv.CreatedUnix
+			if pv.LastDownloadUnix > lastUsed {
+				lastUsed = pv.LastDownloadUnix
+			}
+			if lastUsed.AsLocalTime().After(unusedSince) {
+				log.Debug("Rule[%d]: keep '%s/%s' (recently downloaded)", pcr.ID, p.Name, pv.Version)
+				continue
+			}
+		}
+		if pcr.RemovePatternMatcher != nil && !pcr.RemovePatternMatcher.MatchString(toMatch) {
+			log.Debug("Rule[%d]: keep '%s/%s' (remove pattern)", pcr.ID, p.Name, pv.Version)
+			continue
+		}
+
+		versionsToRemove = append(versionsToRemove, pv)
+	}
+
+	return versionsToRemove, nil
+}
+
+// latestSemverPatches returns the ids of the n highest patch releases of every major.minor release line.
+// Versions which are no semantic versions (e.g. "latest" or "nightly") belong to no release line and are never
+// returned, whether they are removed is decided by the other settings of the rule.
+func latestSemverPatches(pvs []*packages_model.PackageVersion, n int) container.Set[int64] {
+	keep := make(container.Set[int64])
+	if n <= 0 {
+		return keep
+	}
+
+	type semverVersion struct {
+		id int64
+		v  *version.Version
+	}
+
+	lines := make(map[string][]*semverVersion)
+	for _, pv := range pvs {
+		v, err := version.NewSemver(pv.Version)
+		if err != nil {
+			continue
+		}
+		segments := v.Segments()
+		line := fmt.Sprintf("%d.%d", segments[0], segments[1])
+		lines[line] = append(lines[line], &semverVersion{id: pv.ID, v: v})
+	}
+
+	for _, versions := range lines {
+		sort.Slice(versions, func(i, j int) bool {
+			return versions[i].v.GreaterThan(versions[j].v)
+		})
+		for i := 0; i < n && i < len(versions); i++ {
+			keep.Add(versions[i].id)
+		}
+	}
+
+	return keep
+}
+
+// PreviewCleanupRule returns the versions which would be removed by the cleanup rule without removing them
+func PreviewCleanupRule(ctx context.Context, pcr *packages_model.PackageCleanupRule) ([]*packages_model.PackageDescriptor, error) {
+	packages, err := packages_model.GetPackagesByType(ctx, pcr.OwnerID, pcr.Type)
+	if err != nil {
+		return nil, fmt.Errorf("GetPackagesByType failed: %w", err)
+	}
+
+	pds := make([]*packages_model.PackageDescriptor, 0, 10)
+	for _, p := range packages {
+		pvs, err := GetVersionsToRemove(ctx, pcr, p)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pv := range pvs {
+			pd, err := packages_model.GetPackageDescriptor(ctx, pv)
+			if err != nil {
+				return nil, fmt.Errorf("GetPackageDescriptor failed: %w", err)
+			}
+			pds = append(pds, pd)
+		}
+	}
+
+	return pds, nil
+}
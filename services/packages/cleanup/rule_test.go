@@ -0,0 +1,47 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package container
+
+import (
+	"testing"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatestSemverPatches(t *testing.T) {
+	versions := []string{"1.0.0", "1.0.1", "1.0.2", "1.1.0", "1.1.1", "2.0.0-rc1", "2.0.0", "latest"}
+	pvs := make([]*packages_model.PackageVersion, 0, len(versions))
+	for i, v := range versions {
+		pvs = append(pvs, &packages_model.PackageVersion{ID: int64(i + 1), Version: v})
+	}
+
+	assert.Empty(t, latestSemverPatches(pvs, 0))
+
+	keep := latestSemverPatches(pvs, 1)
+	assert.ElementsMatch(t, []int64{3, 5, 7}, keep.Values())
+
+	keep = latestSemverPatches(pvs, 2)
+	assert.ElementsMatch(t, []int64{2, 3, 4, 5, 6, 7}, keep.Values())
+}
+
+func TestLatestSemverPatchesMixedVersions(t *testing.T) {
+	versions := []string{"nightly", "v1.0.0", "1.0.1", "latest", "1.0", "main", "sha-3f2a1b", "1.1.0-beta"}
+	pvs := make([]*packages_model.PackageVersion, 0, len(versions))
+	for i, v := range versions {
+		pvs = append(pvs, &packages_model.PackageVersion{ID: int64(i + 1), Version: v})
+	}
+
+	// versions which are no semantic versions are never kept
+	keep := latestSemverPatches(pvs, 10)
+	assert.ElementsMatch(t, []int64{2, 3, 5, 8}, keep.Values())
+
+	// "1.0" is the same release as "v1.0.0" and lower than "1.0.1"
+	keep = latestSemverPatches(pvs, 1)
+	assert.ElementsMatch(t, []int64{3, 8}, keep.Values())
+
+	keep = latestSemverPatches([]*packages_model.PackageVersion{{ID: 1, Version: "latest"}, {ID: 2, Version: "nightly"}}, 1)
+	assert.Empty(t, keep)
+}
@@ -105,6 +105,15 @@ func ShouldBeSkipped(ctx context.Context, pcr *packages_model.PackageCleanupRule
 		if has {
 			return true, nil
 		}
+
+		// Skip referrers of existing manifests, they are removed together with their subject
+		pps, err := packages_model.GetPropertiesByName(ctx, packages_model.PropertyTypeVersion, pv.ID, container_module.PropertyManifestSubject)
+		if err != nil {
+			return false, err
+		}
+		if len(pps) > 0 {
+			return subjectExists(ctx, p.ID, pps[0].Value)
+		}
 	}
 
 	return false, nil
@@ -10,6 +10,7 @@ import (
 
 	"code.gitea.io/gitea/models/db"
 	"code.gitea.io/gitea/models/packages"
+	container_model "code.gitea.io/gitea/models/packages/container"
 	"code.gitea.io/gitea/modules/json"
 	"code.gitea.io/gitea/modules/log"
 	container_module "code.gitea.io/gitea/modules/packages/container"
@@ -39,8 +40,10 @@ func cleanupSHA256(outerCtx context.Context, olderThan time.Duration) error {
 
 	foundAtLeastOneSHA256 := false
 	type packageVersion struct {
-		id      int64
-		created timeutil.TimeStamp
+		id        int64
+		packageID int64
+		created   timeutil.TimeStamp
+		subject   string
 	}
 	shaToPackageVersion := make(map[string]packageVersion, 100)
 	knownSHA := make(map[string]any, 100)
@@ -65,14 +68,23 @@ func cleanupSHA256(outerCtx context.Context, olderThan time.Duration) error {
 	// knownSHA will therefore be empty most of the time and
 	// shaToPackageVersion will only contain unreferenced sha256: versions.
 	if err := db.GetEngine(ctx).
-		Select("`package_version`.`id`, `package_version`.`created_unix`, `package_version`.`lower_version`, `package_version`.`metadata_json`").
+		Select("`package_version`.`id`, `package_version`.`package_id`, `package_version`.`created_unix`, `package_version`.`lower_version`, `package_version`.`metadata_json`").
 		Join("INNER", "`package`", "`package`.`id` = `package_version`.`package_id`").
 		Where("`package`.`type` = ?", packages.TypeContainer).
 		OrderBy("`package_version`.`id` ASC").
 		Iterate(new(packages.PackageVersion), func(_ int, bean any) error {
 			v := bean.(*packages.PackageVersion)
 			if strings.HasPrefix(v.LowerVersion, "sha256:") {
-				shaToPackageVersion[v.LowerVersion] = packageVersion{id: v.ID, created: v.CreatedUnix}
+				pv := packageVersion{id: v.ID, packageID: v.PackageID, created: v.CreatedUnix}
+				if strings.Contains(v.MetadataJSON, `"subject":`) {
+					var metadata container_module.Metadata
+					if err := json.Unmarshal([]byte(v.MetadataJSON), &metadata); err != nil {
+						log.Error("package_version.id = %d package_version.metadata_json %s is not a JSON string containing valid metadata. It was ignored but it is an inconsistency in the database that should be looked at. %v", v.ID, v.MetadataJSON, err)
+						return nil
+					}
+					pv.subject = metadata.Subject
+				}
+				shaToPackageVersion[v.LowerVersion] = pv
 				foundAtLeastOneSHA256 = true
 			} else if strings.Contains(v.MetadataJSON, `"manifests":[{`) {
 				var metadata container_module.Metadata
@@ -97,6 +109,34 @@ func cleanupSHA256(outerCtx context.Context, olderThan time.Duration) error {
 		delete(shaToPackageVersion, sha)
 	}
 
+	// Referrers (signatures, SBOMs, ...) are pushed without a tag and are
+	// not referenced by an index manifest. They are kept as long as the
+	// manifest they refer to as their subject exists. Keeping a referrer
+	// may keep other referrers that use it as their subject, hence the
+	// loop until nothing changes anymore.
+	for {
+		kept := false
+		for sha, p := range shaToPackageVersion {
+			if p.subject == "" {
+				continue
+			}
+			if _, ok := shaToPackageVersion[p.subject]; ok {
+				continue
+			}
+			exists, err := subjectExists(ctx, p.packageID, p.subject)
+			if err != nil {
+				return err
+			}
+			if exists {
+				delete(shaToPackageVersion, sha)
+				kept = true
+			}
+		}
+		if !kept {
+			break
+		}
+	}
+
 	if len(shaToPackageVersion) == 0 {
 		if foundAtLeastOneSHA256 {
 			log.Debug("All container images with a version matching sha256:* are referenced by an index manifest")
@@ -156,3 +196,27 @@ func cleanupSHA256(outerCtx context.Context, olderThan time.Duration) error {
 
 	return committer.Commit()
 }
+
+func subjectExists(ctx context.Context, packageID int64, subject string) (bool, error) {
+	p, err := packages.GetPackageByID(ctx, packageID)
+	if err != nil {
+		if err == packages.ErrPackageNotExist {
+			return false, nil
+		}
+		return false, err
+	}
+
+	_, err = container_model.GetContainerBlob(ctx, &container_model.BlobSearchOptions{
+		OwnerID:    p.OwnerID,
+		Image:      p.LowerName,
+		Digest:     subject,
+		IsManifest: true,
+	})
+	if err != nil {
+		if err == container_model.ErrContainerBlobNotExist {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package container
+
+import (
+	"context"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	container_model "code.gitea.io/gitea/models/packages/container"
+	user_model "code.gitea.io/gitea/models/user"
+	container_module "code.gitea.io/gitea/modules/packages/container"
+	packages_service "code.gitea.io/gitea/services/packages"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+// RemoveDanglingReferrers removes the untagged referrers of the subject digest and
+// the referrers tag created by clients without referrers API support, if the image
+// does not contain a manifest with the subject digest anymore.
+func RemoveDanglingReferrers(ctx context.Context, doer *user_model.User, ownerID int64, image, subject string) error {
+	_, err := container_model.GetContainerBlob(ctx, &container_model.BlobSearchOptions{
+		OwnerID:    ownerID,
+		Image:      image,
+		Digest:     subject,
+		IsManifest: true,
+	})
+	if err == nil {
+		return nil
+	}
+	if err != container_model.ErrContainerBlobNotExist {
+		return err
+	}
+
+	pvs, err := container_model.GetReferrerVersions(ctx, ownerID, image, subject)
+	if err != nil {
+		return err
+	}
+
+	for _, pv := range pvs {
+		// A tagged referrer is still referenced by its tag
+		if digest.Digest(pv.LowerVersion).Validate() != nil {
+			continue
+		}
+
+		if err := packages_service.RemovePackageVersion(ctx, doer, pv); err != nil {
+			return err
+		}
+
+		// Referrers may be the subject of other referrers (e.g. the signature of a SBOM)
+		if err := RemoveDanglingReferrers(ctx, doer, ownerID, image, pv.LowerVersion); err != nil {
+			return err
+		}
+	}
+
+	pv, err := packages_model.GetVersionByNameAndVersion(ctx, ownerID, packages_model.TypeContainer, image, container_module.ReferrersTag(digest.Digest(subject)))
+	if err != nil {
+		if err == packages_model.ErrPackageNotExist {
+			return nil
+		}
+		return err
+	}
+
+	return packages_service.RemovePackageVersion(ctx, doer, pv)
+}
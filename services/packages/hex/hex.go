@@ -0,0 +1,223 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package hex
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"sort"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	user_model "code.gitea.io/gitea/models/user"
+	hex_module "code.gitea.io/gitea/modules/packages/hex"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/hashicorp/go-version"
+)
+
+// GetOrCreateKeyPair gets or creates the RSA keys used to sign the registry resources
+func GetOrCreateKeyPair(ctx context.Context, ownerID int64) (string, string, error) {
+	priv, err := user_model.GetSetting(ctx, ownerID, hex_module.SettingKeyPrivate)
+	if err != nil && !errors.Is(err, util.ErrNotExist) {
+		return "", "", err
+	}
+
+	pub, err := user_model.GetSetting(ctx, ownerID, hex_module.SettingKeyPublic)
+	if err != nil && !errors.Is(err, util.ErrNotExist) {
+		return "", "", err
+	}
+
+	if priv == "" || pub == "" {
+		priv, pub, err = util.GenerateKeyPair(2048)
+		if err != nil {
+			return "", "", err
+		}
+
+		if err := user_model.SetUserSetting(ctx, ownerID, hex_module.SettingKeyPrivate, priv); err != nil {
+			return "", "", err
+		}
+
+		if err := user_model.SetUserSetting(ctx, ownerID, hex_module.SettingKeyPublic, pub); err != nil {
+			return "", "", err
+		}
+	}
+
+	return priv, pub, nil
+}
+
+// RepositoryName returns the name of the repository which clients must use for the registry of the owner
+func RepositoryName(owner *user_model.User) string {
+	return owner.LowerName
+}
+
+// BuildNames creates the signed /names resource which lists all packages of the owner
+func BuildNames(ctx context.Context, owner *user_model.User) ([]byte, error) {
+	versionsByPackage, names, err := getVersionsByPackage(ctx, owner.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	packages := make([]*hex_module.NamesPackage, 0, len(names))
+	for _, name := range names {
+		p := &hex_module.NamesPackage{
+			Name: name,
+		}
+		for _, pv := range versionsByPackage[name] {
+			if t := pv.CreatedUnix.AsTime(); t.After(p.UpdatedAt) {
+				p.UpdatedAt = t
+			}
+		}
+		packages = append(packages, p)
+	}
+
+	return sign(ctx, owner.ID, hex_module.EncodeNames(RepositoryName(owner), packages))
+}
+
+// BuildVersions creates the signed /versions resource which lists the versions of all packages of the owner
+func BuildVersions(ctx context.Context, owner *user_model.User) ([]byte, error) {
+	versionsByPackage, names, err := getVersionsByPackage(ctx, owner.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	packages := make([]*hex_module.VersionsPackage, 0, len(names))
+	for _, name := range names {
+		pvs := versionsByPackage[name]
+
+		versions := make([]string, 0, len(pvs))
+		for _, pv := range pvs {
+			versions = append(versions, pv.Version)
+		}
+
+		packages = append(packages, &hex_module.VersionsPackage{
+			Name:     name,
+			Versions: versions,
+		})
+	}
+
+	return sign(ctx, owner.ID, hex_module.EncodeVersions(RepositoryName(owner), packages))
+}
+
+// BuildPackage creates the signed /packages/{name} resource which lists all releases of the package
+func BuildPackage(ctx context.Context, owner *user_model.User, name string) ([]byte, error) {
+	pvs, err := packages_model.GetVersionsByPackageName(ctx, owner.ID, packages_model.TypeHex, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(pvs) == 0 {
+		return nil, packages_model.ErrPackageNotExist
+	}
+	sortVersions(pvs)
+
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+	if err != nil {
+		return nil, err
+	}
+
+	releases := make([]*hex_module.Release, 0, len(pds))
+	for _, pd := range pds {
+		if len(pd.Files) == 0 {
+			continue
+		}
+		pf := pd.Files[0]
+
+		innerChecksum, err := hex.DecodeString(pf.Properties.GetByName(hex_module.PropertyInnerChecksum))
+		if err != nil {
+			return nil, err
+		}
+		outerChecksum, err := hex.DecodeString(pf.Blob.HashSHA256)
+		if err != nil {
+			return nil, err
+		}
+
+		releases = append(releases, &hex_module.Release{
+			Version:       pd.Version.Version,
+			InnerChecksum: innerChecksum,
+			OuterChecksum: outerChecksum,
+			Dependencies:  pd.Metadata.(*hex_module.Metadata).Requirements,
+		})
+	}
+
+	return sign(ctx, owner.ID, hex_module.EncodePackage(RepositoryName(owner), name, releases))
+}
+
+// getVersionsByPackage returns the versions grouped by package name and the sorted package names
+func getVersionsByPackage(ctx context.Context, ownerID int64) (map[string][]*packages_model.PackageVersion, []string, error) {
+	ps, err := packages_model.GetPackagesByType(ctx, ownerID, packages_model.TypeHex)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pvs, err := packages_model.GetVersionsByPackageType(ctx, ownerID, packages_model.TypeHex)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	namesByID := make(map[int64]string, len(ps))
+	for _, p := range ps {
+		namesByID[p.ID] = p.Name
+	}
+
+	versionsByPackage := make(map[string][]*packages_model.PackageVersion)
+	for _, pv := range pvs {
+		name, ok := namesByID[pv.PackageID]
+		if !ok {
+			continue
+		}
+		versionsByPackage[name] = append(versionsByPackage[name], pv)
+	}
+
+	names := make([]string, 0, len(versionsByPackage))
+	for name, pvs := range versionsByPackage {
+		sortVersions(pvs)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return versionsByPackage, names, nil
+}
+
+func sortVersions(pvs []*packages_model.PackageVersion) {
+	sort.SliceStable(pvs, func(i, j int) bool {
+		vi, erri := version.NewSemver(pvs[i].Version)
+		vj, errj := version.NewSemver(pvs[j].Version)
+		if erri != nil || errj != nil {
+			return pvs[i].Version < pvs[j].Version
+		}
+		return vi.LessThan(vj)
+	})
+}
+
+// sign signs the payload with the key of the owner and wraps both in the gzipped Signed message
+func sign(ctx context.Context, ownerID int64, payload []byte) ([]byte, error) {
+	priv, _, err := GetOrCreateKeyPair(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode([]byte(priv))
+	if block == nil {
+		return nil, errors.New("failed to decode private key pem")
+	}
+
+	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha512.Sum512(payload)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA512, h[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return hex_module.EncodeSigned(payload, signature)
+}
@@ -14,14 +14,17 @@ import (
 
 	"code.gitea.io/gitea/models/db"
 	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/perm"
 	repo_model "code.gitea.io/gitea/models/repo"
 	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/json"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/optional"
 	packages_module "code.gitea.io/gitea/modules/packages"
+	"code.gitea.io/gitea/modules/packages/osv"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/storage"
+	"code.gitea.io/gitea/modules/util"
 	notify_service "code.gitea.io/gitea/services/notify"
 )
 
@@ -29,6 +32,7 @@ var (
 	ErrQuotaTypeSize   = errors.New("maximum allowed package type size exceeded")
 	ErrQuotaTotalSize  = errors.New("maximum allowed package storage quota exceeded")
 	ErrQuotaTotalCount = errors.New("maximum allowed package count exceeded")
+	ErrVersionBlocked  = util.NewPermissionDeniedErrorf("package version is affected by a critical vulnerability")
 )
 
 // PackageInfo describes a package
@@ -130,6 +134,9 @@ func createPackageAndVersion(ctx context.Context, pvci *PackageCreationInfo, all
 		LowerName:        strings.ToLower(pvci.Name),
 		SemverCompatible: pvci.SemverCompatible,
 	}
+	if err := LinkActionsTaskRepository(ctx, p); err != nil {
+		return nil, false, err
+	}
 	var err error
 	if p, err = packages_model.TryInsertPackage(ctx, p); err != nil {
 		if err == packages_model.ErrDuplicatePackage {
@@ -140,6 +147,10 @@ func createPackageAndVersion(ctx context.Context, pvci *PackageCreationInfo, all
 		}
 	}
 
+	if err := CheckRepositoryScope(ctx, p, perm.AccessModeWrite); err != nil {
+		return nil, false, err
+	}
+
 	if packageCreated {
 		for name, value := range pvci.PackageProperties {
 			if _, err := packages_model.InsertProperty(ctx, packages_model.PropertyTypePackage, p.ID, name, value); err != nil {
@@ -200,6 +211,10 @@ func AddFileToExistingPackage(ctx context.Context, pvi *PackageInfo, pfci *Packa
 			return nil, nil, false, err
 		}
 
+		if err := CheckRepositoryScopeByPackageID(ctx, pv.PackageID, perm.AccessModeWrite); err != nil {
+			return nil, nil, false, err
+		}
+
 		return addFileToPackageVersion(ctx, pv, pvi, pfci)
 	})
 }
@@ -260,6 +275,16 @@ func addFileToPackageVersion(ctx context.Context, pv *packages_model.PackageVers
 		return nil, nil, false, err
 	}
 
+	if pfci.OverwriteExisting {
+		if _, err := packages_model.GetFileForVersionByName(ctx, pv.ID, pfci.Filename, pfci.CompositeKey); err == nil {
+			if err := CheckVersionProtection(ctx, pfci.Creator, pvi.Owner.ID, pvi.PackageType, pv.Version); err != nil {
+				return nil, nil, false, err
+			}
+		} else if err != packages_model.ErrPackageFileNotExist {
+			return nil, nil, false, err
+		}
+	}
+
 	return addFileToPackageVersionUnchecked(ctx, pv, pfci)
 }
 
@@ -359,6 +384,8 @@ func CheckSizeQuotaExceeded(ctx context.Context, doer, owner *user_model.User, p
 	switch packageType {
 	case packages_model.TypeAlpine:
 		typeSpecificSize = setting.Packages.LimitSizeAlpine
+	case packages_model.TypeAnsible:
+		typeSpecificSize = setting.Packages.LimitSizeAnsible
 	case packages_model.TypeArch:
 		typeSpecificSize = setting.Packages.LimitSizeArch
 	case packages_model.TypeCargo:
@@ -383,6 +410,8 @@ func CheckSizeQuotaExceeded(ctx context.Context, doer, owner *user_model.User, p
 		typeSpecificSize = setting.Packages.LimitSizeGo
 	case packages_model.TypeHelm:
 		typeSpecificSize = setting.Packages.LimitSizeHelm
+	case packages_model.TypeHex:
+		typeSpecificSize = setting.Packages.LimitSizeHex
 	case packages_model.TypeMaven:
 		typeSpecificSize = setting.Packages.LimitSizeMaven
 	case packages_model.TypeNpm:
@@ -399,6 +428,8 @@ func CheckSizeQuotaExceeded(ctx context.Context, doer, owner *user_model.User, p
 		typeSpecificSize = setting.Packages.LimitSizeRubyGems
 	case packages_model.TypeSwift:
 		typeSpecificSize = setting.Packages.LimitSizeSwift
+	case packages_model.TypeTerraform:
+		typeSpecificSize = setting.Packages.LimitSizeTerraform
 	case packages_model.TypeVagrant:
 		typeSpecificSize = setting.Packages.LimitSizeVagrant
 	}
@@ -485,6 +516,13 @@ func RemovePackageVersion(ctx context.Context, doer *user_model.User, pv *packag
 		return err
 	}
 
+	if err := CheckRepositoryScope(dbCtx, pd.Package, perm.AccessModeWrite); err != nil {
+		return err
+	}
+	if err := CheckVersionProtection(dbCtx, doer, pd.Owner.ID, pd.Package.Type, pv.Version); err != nil {
+		return err
+	}
+
 	log.Trace("Deleting package: %v", pv.ID)
 
 	if err := DeletePackageVersionAndReferences(dbCtx, pv); err != nil {
@@ -505,6 +543,21 @@ func RemovePackageFileAndVersionIfUnreferenced(ctx context.Context, doer *user_m
 	var pd *packages_model.PackageDescriptor
 
 	if err := db.WithTx(ctx, func(ctx context.Context) error {
+		pv, err := packages_model.GetVersionByID(ctx, pf.VersionID)
+		if err != nil {
+			return err
+		}
+		p, err := packages_model.GetPackageByID(ctx, pv.PackageID)
+		if err != nil {
+			return err
+		}
+		if err := CheckRepositoryScope(ctx, p, perm.AccessModeWrite); err != nil {
+			return err
+		}
+		if err := CheckVersionProtection(ctx, doer, p.OwnerID, p.Type, pv.Version); err != nil {
+			return err
+		}
+
 		if err := DeletePackageFile(ctx, pf); err != nil {
 			return err
 		}
@@ -514,11 +567,6 @@ func RemovePackageFileAndVersionIfUnreferenced(ctx context.Context, doer *user_m
 			return err
 		}
 		if !has {
-			pv, err := packages_model.GetVersionByID(ctx, pf.VersionID)
-			if err != nil {
-				return err
-			}
-
 			pd, err = packages_model.GetPackageDescriptor(ctx, pv)
 			if err != nil {
 				return err
@@ -558,6 +606,14 @@ func DeletePackageVersionAndReferences(ctx context.Context, pv *packages_model.P
 		}
 	}
 
+	if err := packages_model.DeleteAttestationsByVersionID(ctx, pv.ID); err != nil {
+		return err
+	}
+
+	if err := packages_model.DeleteVersionAdvisoriesByVersionID(ctx, pv.ID); err != nil {
+		return err
+	}
+
 	return packages_model.DeleteVersionByID(ctx, pv.ID)
 }
 
@@ -608,6 +664,30 @@ func GetPackageFileStream(ctx context.Context, pf *packages_model.PackageFile) (
 // GetPackageBlobStream returns the content of the specific package blob
 // If the storage supports direct serving and it's enabled, only the direct serving url is returned.
 func GetPackageBlobStream(ctx context.Context, pf *packages_model.PackageFile, pb *packages_model.PackageBlob) (io.ReadSeekCloser, *url.URL, *packages_model.PackageFile, error) {
+	if repositoryScopeFromContext(ctx) != nil {
+		pv, err := packages_model.GetVersionByID(ctx, pf.VersionID)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if err := CheckRepositoryScopeByPackageID(ctx, pv.PackageID, perm.AccessModeRead); err != nil {
+			if errors.Is(err, ErrPackageAccessDenied) {
+				// don't reveal packages the doer has no access to
+				return nil, nil, nil, packages_model.ErrPackageNotExist
+			}
+			return nil, nil, nil, err
+		}
+	}
+
+	if setting.Packages.BlockCriticalVulnerabilities {
+		blocked, err := packages_model.HasVersionAdvisoryWithSeverity(ctx, pf.VersionID, osv.SeverityCritical)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if blocked {
+			return nil, nil, nil, ErrVersionBlocked
+		}
+	}
+
 	key := packages_module.BlobHash256Key(pb.HashSHA256)
 
 	cs := packages_module.NewContentStore()
@@ -0,0 +1,103 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package packages
+
+import (
+	"context"
+
+	org_model "code.gitea.io/gitea/models/organization"
+	packages_model "code.gitea.io/gitea/models/packages"
+	user_model "code.gitea.io/gitea/models/user"
+	container_module "code.gitea.io/gitea/modules/packages/container"
+	"code.gitea.io/gitea/modules/util"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+var ErrVersionProtected = util.NewPermissionDeniedErrorf("package version is protected")
+
+// ProtectionRules are the protection rules of an owner for a package type,
+// load them once with GetProtectionRules to check many versions
+type ProtectionRules struct {
+	packageType packages_model.Type
+	rules       []*packages_model.PackageProtectionRule
+}
+
+// GetProtectionRules loads the protection rules of the owner for the package type
+func GetProtectionRules(ctx context.Context, ownerID int64, packageType packages_model.Type) (*ProtectionRules, error) {
+	pprs, err := packages_model.GetProtectionRulesByOwnerAndType(ctx, ownerID, packageType)
+	if err != nil {
+		return nil, err
+	}
+	return &ProtectionRules{packageType: packageType, rules: pprs}, nil
+}
+
+// Matching returns the rules which protect the version
+func (r *ProtectionRules) Matching(version string) []*packages_model.PackageProtectionRule {
+	if r.packageType == packages_model.TypeContainer {
+		// Only tags can be protected. Manifests referenced by digest can't be overwritten
+		// and referrers tags are maintained by the registry.
+		if digest.Digest(version).Validate() == nil || container_module.IsReferrersTag(version) {
+			return nil
+		}
+	}
+
+	matching := make([]*packages_model.PackageProtectionRule, 0, len(r.rules))
+	for _, ppr := range r.rules {
+		if ppr.Match(version) {
+			matching = append(matching, ppr)
+		}
+	}
+	return matching
+}
+
+// IsProtected checks if the version is protected by one of the rules
+func (r *ProtectionRules) IsProtected(version string) bool {
+	return len(r.Matching(version)) > 0
+}
+
+// getMatchingProtectionRules returns the rules of the owner which protect the version
+func getMatchingProtectionRules(ctx context.Context, ownerID int64, packageType packages_model.Type, version string) ([]*packages_model.PackageProtectionRule, error) {
+	rules, err := GetProtectionRules(ctx, ownerID, packageType)
+	if err != nil {
+		return nil, err
+	}
+	return rules.Matching(version), nil
+}
+
+// IsVersionProtected checks if the version is protected by a rule of the owner
+func IsVersionProtected(ctx context.Context, ownerID int64, packageType packages_model.Type, version string) (bool, error) {
+	pprs, err := getMatchingProtectionRules(ctx, ownerID, packageType, version)
+	if err != nil {
+		return false, err
+	}
+	return len(pprs) > 0, nil
+}
+
+// CheckVersionProtection returns ErrVersionProtected if the version is protected and the doer is not allowed to overwrite or delete it.
+// Site admins and members of the teams allowed by every matching rule are not restricted.
+func CheckVersionProtection(ctx context.Context, doer *user_model.User, ownerID int64, packageType packages_model.Type, version string) error {
+	if doer != nil && doer.IsAdmin {
+		return nil
+	}
+
+	pprs, err := getMatchingProtectionRules(ctx, ownerID, packageType, version)
+	if err != nil {
+		return err
+	}
+
+	for _, ppr := range pprs {
+		if doer == nil || len(ppr.AllowedTeamIDs) == 0 {
+			return ErrVersionProtected
+		}
+		isAllowed, err := org_model.IsUserInTeams(ctx, doer.ID, ppr.AllowedTeamIDs)
+		if err != nil {
+			return err
+		}
+		if !isAllowed {
+			return ErrVersionProtected
+		}
+	}
+	return nil
+}
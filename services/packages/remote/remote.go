@@ -0,0 +1,338 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/modules/hostmatcher"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/proxy"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+)
+
+var (
+	// ErrUpstreamNotExist indicates the requested content does not exist in the upstream registry
+	ErrUpstreamNotExist = util.NewNotExistErrorf("content does not exist in the upstream registry")
+	// ErrInvalidRemoteURL indicates the url of the remote is not valid
+	ErrInvalidRemoteURL = util.NewInvalidArgumentErrorf("remote url is invalid")
+	// ErrInvalidAuthHost indicates the token service host of the remote is not valid
+	ErrInvalidAuthHost = util.NewInvalidArgumentErrorf("remote token service host is invalid")
+)
+
+var (
+	httpClient     *http.Client
+	httpClientOnce sync.Once
+)
+
+func getHTTPClient() *http.Client {
+	httpClientOnce.Do(func() {
+		allowedHostListValue := setting.Packages.RemoteAllowedHostList
+		if allowedHostListValue == "" {
+			allowedHostListValue = hostmatcher.MatchBuiltinExternal
+		}
+		allowedHostMatcher := hostmatcher.ParseHostMatchList("packages.REMOTE_ALLOWED_HOST_LIST", allowedHostListValue)
+
+		httpClient = &http.Client{
+			Timeout: 10 * time.Minute,
+			Transport: &http.Transport{
+				Proxy:       proxy.Proxy(),
+				DialContext: hostmatcher.NewDialContext("package remote", allowedHostMatcher, nil, nil),
+			},
+		}
+	})
+	return httpClient
+}
+
+// Client requests content from the upstream registry of a package remote
+type Client struct {
+	Remote   *packages_model.PackageRemote
+	baseURL  *url.URL
+	password string
+	token    string
+}
+
+// GetClient returns a client for the enabled remote of the owner for the package type.
+// If there is no such remote, ErrPackageRemoteNotExist is returned.
+func GetClient(ctx context.Context, ownerID int64, packageType packages_model.Type) (*Client, error) {
+	if !packageType.IsRemoteSupported() {
+		return nil, packages_model.ErrPackageRemoteNotExist
+	}
+
+	pr, err := packages_model.GetEnabledRemoteByOwnerAndType(ctx, ownerID, packageType)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewClient(pr)
+}
+
+// GetClientForPackage returns a client for the enabled remote of the owner for the package type.
+// Packages which contain versions not fetched from the upstream registry are never mixed with upstream content,
+// ErrPackageRemoteNotExist is returned for them.
+func GetClientForPackage(ctx context.Context, ownerID int64, packageType packages_model.Type, name string) (*Client, error) {
+	c, err := GetClient(ctx, ownerID, packageType)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := packages_model.GetPackageByName(ctx, ownerID, packageType, name)
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageNotExist) {
+			return c, nil
+		}
+		return nil, err
+	}
+
+	hasOtherVersions, err := packages_model.HasVersionsNotFromRemote(ctx, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	if hasOtherVersions {
+		return nil, packages_model.ErrPackageRemoteNotExist
+	}
+	return c, nil
+}
+
+// NewClient creates a client for the remote
+func NewClient(pr *packages_model.PackageRemote) (*Client, error) {
+	u, err := ParseRemoteURL(pr.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := pr.Password()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		Remote:   pr,
+		baseURL:  u,
+		password: password,
+	}, nil
+}
+
+// ParseRemoteURL parses and validates the url of an upstream registry
+func ParseRemoteURL(s string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return nil, ErrInvalidRemoteURL
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u, nil
+}
+
+// ParseAuthHost parses and validates the host of the token service of an upstream registry, an empty host is valid
+func ParseAuthHost(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return "", nil
+	}
+	u, err := url.Parse("https://" + s)
+	if err != nil || u.Host != s || u.Hostname() == "" {
+		return "", ErrInvalidAuthHost
+	}
+	return s, nil
+}
+
+// Host returns the host of the upstream registry
+func (c *Client) Host() string {
+	return c.baseURL.Host
+}
+
+// URL returns the absolute url of the path in the upstream registry
+func (c *Client) URL(p string) string {
+	return c.baseURL.String() + "/" + strings.TrimPrefix(p, "/")
+}
+
+// Get requests the path relative to the url of the upstream registry
+func (c *Client) Get(ctx context.Context, p string, header http.Header) (*http.Response, error) {
+	return c.GetURL(ctx, c.URL(p), header)
+}
+
+// Head requests the headers of the path relative to the url of the upstream registry
+func (c *Client) Head(ctx context.Context, p string, header http.Header) (*http.Response, error) {
+	resp, err := c.request(ctx, http.MethodHead, c.URL(p), header)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// GetURL requests the absolute url. The credentials of the remote are only sent to the host of the upstream registry.
+// The caller must close the body of the returned response.
+func (c *Client) GetURL(ctx context.Context, u string, header http.Header) (*http.Response, error) {
+	return c.request(ctx, http.MethodGet, u, header)
+}
+
+func (c *Client) request(ctx context.Context, method, u string, header http.Header) (*http.Response, error) {
+	resp, err := c.do(ctx, method, u, header)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && c.token == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		if err := c.requestToken(ctx, challenge); err != nil {
+			return nil, err
+		}
+
+		if resp, err = c.do(ctx, method, u, header); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrUpstreamNotExist
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		resp.Body.Close()
+		return nil, fmt.Errorf("upstream registry responded with status %d for %s", resp.StatusCode, u)
+	}
+
+	return resp, nil
+}
+
+func (c *Client) do(ctx context.Context, method, u string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("User-Agent", "Forgejo/"+setting.AppVer)
+
+	if req.URL.Host == c.baseURL.Host {
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		} else if c.Remote.Username != "" || c.password != "" {
+			req.SetBasicAuth(c.Remote.Username, c.password)
+		}
+	}
+
+	log.Trace("Requesting upstream registry: %s %s", method, u)
+
+	return getHTTPClient().Do(req)
+}
+
+// requestToken obtains a bearer token as announced by the challenge of the upstream registry
+// https://distribution.github.io/distribution/spec/auth/token/
+// The realm must use https unless it is on the upstream registry itself, the credentials are only sent to
+// the host of the upstream registry and the configured token service host, other realms get anonymous requests.
+func (c *Client) requestToken(ctx context.Context, challenge string) error {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return fmt.Errorf("upstream registry requires unsupported authentication %q", scheme)
+	}
+
+	values := parseChallengeParams(params)
+
+	realm, err := url.Parse(values["realm"])
+	if err != nil || realm.Host == "" {
+		return errors.New("upstream registry announced an invalid token realm")
+	}
+	isRegistryHost := realm.Host == c.baseURL.Host
+	if realm.Scheme != "https" && (!isRegistryHost || realm.Scheme != c.baseURL.Scheme) {
+		return fmt.Errorf("upstream registry announced an insecure token realm %q", realm.Redacted())
+	}
+
+	q := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if v, ok := values[key]; ok {
+			q.Set(key, v)
+		}
+	}
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "Forgejo/"+setting.AppVer)
+	if isRegistryHost || (c.Remote.AuthHost != "" && strings.EqualFold(realm.Host, c.Remote.AuthHost)) {
+		if c.Remote.Username != "" || c.password != "" {
+			req.SetBasicAuth(c.Remote.Username, c.password)
+		}
+	}
+
+	resp, err := getHTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("upstream registry token endpoint responded with status %d", resp.StatusCode)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return err
+	}
+
+	c.token = token.Token
+	if c.token == "" {
+		c.token = token.AccessToken
+	}
+	if c.token == "" {
+		return errors.New("upstream registry token endpoint returned no token")
+	}
+	return nil
+}
+
+// parseChallengeParams parses the comma separated key="value" parameters of a WWW-Authenticate header
+func parseChallengeParams(s string) map[string]string {
+	values := make(map[string]string)
+	for s != "" {
+		s = strings.TrimLeft(s, " ,")
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end == -1 {
+				value, s = rest[1:], ""
+			} else {
+				value, s = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			value, s, _ = strings.Cut(rest, ",")
+		}
+		values[key] = strings.TrimSpace(value)
+	}
+	return values
+}
+
+// VersionProperties returns the properties which mark a package version as fetched from the upstream url
+func VersionProperties(u string) map[string]string {
+	return map[string]string{
+		packages_model.PropertyRemoteURL: u,
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package vulnerability
+
+import (
+	"context"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/log"
+	notify_service "code.gitea.io/gitea/services/notify"
+)
+
+func init() {
+	notify_service.RegisterNotifier(&vulnerabilityNotifier{})
+}
+
+type vulnerabilityNotifier struct {
+	notify_service.NullNotifier
+}
+
+var _ notify_service.Notifier = &vulnerabilityNotifier{}
+
+// PackageCreate matches new package versions against the imported advisories
+func (v *vulnerabilityNotifier) PackageCreate(ctx context.Context, _ *user_model.User, pd *packages_model.PackageDescriptor) {
+	if !IsSupportedType(pd.Package.Type) {
+		return
+	}
+
+	if err := ScanVersion(ctx, pd.Package, pd.Version); err != nil {
+		log.Error("Error matching package version %d against advisories: %v", pd.Version.ID, err)
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package vulnerability
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/optional"
+	"code.gitea.io/gitea/modules/packages/osv"
+	"code.gitea.io/gitea/modules/timeutil"
+)
+
+// IsSupportedType checks if advisories are matched against packages of the type
+func IsSupportedType(pt packages_model.Type) bool {
+	for _, t := range osv.Ecosystems {
+		if t == string(pt) {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportAdvisories imports OSV vulnerabilities from a JSON file, a zip archive or a directory containing them.
+// It returns the number of new or updated advisories.
+func ImportAdvisories(ctx context.Context, path string) (int, error) {
+	count := 0
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		var n int
+		switch strings.ToLower(filepath.Ext(p)) {
+		case ".json":
+			n, err = importFile(ctx, p)
+		case ".zip":
+			n, err = importArchive(ctx, p)
+		default:
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		count += n
+		return nil
+	})
+	return count, err
+}
+
+func importFile(ctx context.Context, path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return importVulnerability(ctx, f)
+}
+
+func importArchive(ctx context.Context, path string) (int, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return 0, err
+	}
+	defer zr.Close()
+
+	count := 0
+	for _, file := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+		if !strings.EqualFold(filepath.Ext(file.Name), ".json") {
+			continue
+		}
+
+		r, err := file.Open()
+		if err != nil {
+			return count, err
+		}
+		n, err := importVulnerability(ctx, r)
+		r.Close()
+		if err != nil {
+			return count, fmt.Errorf("%s: %w", file.Name, err)
+		}
+		count += n
+	}
+	return count, nil
+}
+
+func importVulnerability(ctx context.Context, r io.Reader) (int, error) {
+	v, err := osv.ParseVulnerability(r)
+	if err != nil {
+		// Dumps may contain other files, skip them
+		if errors.Is(err, osv.ErrInvalidVulnerability) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	if v.Withdrawn != nil {
+		return 0, packages_model.DeleteAdvisoriesByAdvisoryID(ctx, v.ID)
+	}
+
+	type packageKey struct {
+		Type      packages_model.Type
+		LowerName string
+	}
+
+	affected := make(map[packageKey][]*osv.Affected)
+	for _, a := range v.Affected {
+		pt, ok := osv.Ecosystems[a.Package.Ecosystem]
+		if !ok {
+			continue
+		}
+		key := packageKey{packages_model.Type(pt), osv.NormalizeName(a.Package.Ecosystem, a.Package.Name)}
+		affected[key] = append(affected[key], a)
+	}
+
+	severity := v.GetSeverity()
+
+	count := 0
+	for key, as := range affected {
+		data, err := json.Marshal(as)
+		if err != nil {
+			return count, err
+		}
+
+		changed, err := packages_model.UpsertAdvisory(ctx, &packages_model.PackageAdvisory{
+			AdvisoryID:   v.ID,
+			PackageType:  key.Type,
+			LowerName:    key.LowerName,
+			Aliases:      v.Aliases,
+			Summary:      v.Summary,
+			Severity:     severity,
+			Affected:     string(data),
+			ModifiedUnix: timeutil.TimeStamp(v.Modified.Unix()),
+		})
+		if err != nil {
+			return count, err
+		}
+		if changed {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ScanPackage matches all versions of the package against the advisories
+func ScanPackage(ctx context.Context, p *packages_model.Package) error {
+	pas, err := packages_model.GetAdvisoriesByPackage(ctx, p.Type, p.LowerName)
+	if err != nil {
+		return err
+	}
+
+	pvs, _, err := packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
+		PackageID:  p.ID,
+		IsInternal: optional.Some(false),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, pv := range pvs {
+		if err := scanVersion(ctx, pv, pas); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScanVersion matches the package version against the advisories
+func ScanVersion(ctx context.Context, p *packages_model.Package, pv *packages_model.PackageVersion) error {
+	pas, err := packages_model.GetAdvisoriesByPackage(ctx, p.Type, p.LowerName)
+	if err != nil {
+		return err
+	}
+	return scanVersion(ctx, pv, pas)
+}
+
+func scanVersion(ctx context.Context, pv *packages_model.PackageVersion, pas []*packages_model.PackageAdvisory) error {
+	ids := make([]int64, 0, len(pas))
+	for _, pa := range pas {
+		var affected []*osv.Affected
+		if err := json.Unmarshal([]byte(pa.Affected), &affected); err != nil {
+			log.Error("Invalid affected versions of advisory %s: %v", pa.AdvisoryID, err)
+			continue
+		}
+		for _, a := range affected {
+			if a.IsAffected(pv.Version) {
+				ids = append(ids, pa.ID)
+				break
+			}
+		}
+	}
+	return packages_model.SetVersionAdvisories(ctx, pv.ID, ids)
+}
+
+// ScanTask matches all packages which have advisories against them
+func ScanTask(ctx context.Context) error {
+	return packages_model.IteratePackagesWithAdvisories(ctx, func(ctx context.Context, p *packages_model.Package) error {
+		if err := ScanPackage(ctx, p); err != nil {
+			return fmt.Errorf("ScanPackage[%d]: %w", p.ID, err)
+		}
+		return nil
+	})
+}
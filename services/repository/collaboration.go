@@ -6,13 +6,51 @@ package repository
 
 import (
 	"context"
+	"strconv"
 
 	"code.gitea.io/gitea/models"
+	audit_model "code.gitea.io/gitea/models/audit"
 	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/perm"
 	access_model "code.gitea.io/gitea/models/perm/access"
 	repo_model "code.gitea.io/gitea/models/repo"
+	user_model "code.gitea.io/gitea/models/user"
+	repo_module "code.gitea.io/gitea/modules/repository"
+	audit_service "code.gitea.io/gitea/services/audit"
 )
 
+// AddCollaborator adds the user as a collaborator of the repository
+func AddCollaborator(ctx context.Context, repo *repo_model.Repository, u *user_model.User) error {
+	if err := repo_module.AddCollaborator(ctx, repo, u); err != nil {
+		return err
+	}
+	audit_service.Record(ctx, audit_model.ActionRepoCollaboratorAdd, nil, repo.OwnerID, audit_service.RepositoryTarget(repo), audit_model.Diff{
+		"collaborator": {New: u.Name},
+	})
+	return nil
+}
+
+// ChangeCollaborationAccessMode sets the access mode of a collaborator of the repository
+func ChangeCollaborationAccessMode(ctx context.Context, repo *repo_model.Repository, uid int64, mode perm.AccessMode) error {
+	if err := repo_model.ChangeCollaborationAccessMode(ctx, repo, uid, mode); err != nil {
+		return err
+	}
+	audit_service.Record(ctx, audit_model.ActionRepoCollaboratorMode, nil, repo.OwnerID, audit_service.RepositoryTarget(repo), audit_model.Diff{
+		"collaborator": {New: collaboratorName(ctx, uid)},
+		"access_mode":  {New: mode.String()},
+	})
+	return nil
+}
+
+// collaboratorName returns the name of the collaborator for the audit log, or its ID if the user doesn't exist anymore
+func collaboratorName(ctx context.Context, uid int64) string {
+	u, err := user_model.GetUserByID(ctx, uid)
+	if err != nil {
+		return strconv.FormatInt(uid, 10)
+	}
+	return u.Name
+}
+
 // DeleteCollaboration removes collaboration relation between the user and repository.
 func DeleteCollaboration(ctx context.Context, repo *repo_model.Repository, uid int64) (err error) {
 	collaboration := &repo_model.Collaboration{
@@ -48,5 +86,9 @@ func DeleteCollaboration(ctx context.Context, repo *repo_model.Repository, uid i
 		return err
 	}
 
+	audit_service.Record(ctx, audit_model.ActionRepoCollaboratorRemove, nil, repo.OwnerID, audit_service.RepositoryTarget(repo), audit_model.Diff{
+		"collaborator": {Old: collaboratorName(ctx, uid)},
+	})
+
 	return committer.Commit()
 }
@@ -0,0 +1,83 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	audit_model "code.gitea.io/gitea/models/audit"
+	git_model "code.gitea.io/gitea/models/git"
+	repo_model "code.gitea.io/gitea/models/repo"
+	audit_service "code.gitea.io/gitea/services/audit"
+)
+
+// UpdateProtectBranch creates or updates a branch protection rule of the repository
+func UpdateProtectBranch(ctx context.Context, repo *repo_model.Repository, protectBranch *git_model.ProtectedBranch, opts git_model.WhitelistOptions) error {
+	var old map[string]any
+	if protectBranch.ID != 0 {
+		stored, err := git_model.GetProtectedBranchRuleByID(ctx, repo.ID, protectBranch.ID)
+		if err != nil {
+			return err
+		}
+		if stored != nil {
+			old = protectedBranchSettings(stored)
+		}
+	}
+
+	if err := git_model.UpdateProtectBranch(ctx, repo, protectBranch, opts); err != nil {
+		return err
+	}
+
+	diff := audit_model.Diff{}
+	for field, value := range protectedBranchSettings(protectBranch) {
+		oldValue, has := old[field]
+		if !has {
+			diff[field] = audit_model.Change{New: value}
+		} else if fmt.Sprint(oldValue) != fmt.Sprint(value) {
+			diff[field] = audit_model.Change{Old: oldValue, New: value}
+		}
+	}
+	audit_service.Record(ctx, audit_model.ActionBranchProtectionUpdate, nil, repo.OwnerID, audit_service.ProtectedBranchTarget(repo, protectBranch), diff)
+	return nil
+}
+
+// DeleteProtectedBranch deletes a branch protection rule of the repository
+func DeleteProtectedBranch(ctx context.Context, repo *repo_model.Repository, protectBranch *git_model.ProtectedBranch) error {
+	if err := git_model.DeleteProtectedBranch(ctx, repo, protectBranch.ID); err != nil {
+		return err
+	}
+	audit_service.Record(ctx, audit_model.ActionBranchProtectionDelete, nil, repo.OwnerID, audit_service.ProtectedBranchTarget(repo, protectBranch), nil)
+	return nil
+}
+
+// protectedBranchSettings returns the settings of a branch protection rule which are recorded in the audit log
+func protectedBranchSettings(pb *git_model.ProtectedBranch) map[string]any {
+	return map[string]any{
+		"rule_name":                         pb.RuleName,
+		"can_push":                          pb.CanPush,
+		"enable_whitelist":                  pb.EnableWhitelist,
+		"whitelist_user_ids":                pb.WhitelistUserIDs,
+		"whitelist_team_ids":                pb.WhitelistTeamIDs,
+		"whitelist_deploy_keys":             pb.WhitelistDeployKeys,
+		"enable_merge_whitelist":            pb.EnableMergeWhitelist,
+		"merge_whitelist_user_ids":          pb.MergeWhitelistUserIDs,
+		"merge_whitelist_team_ids":          pb.MergeWhitelistTeamIDs,
+		"enable_status_check":               pb.EnableStatusCheck,
+		"status_check_contexts":             pb.StatusCheckContexts,
+		"enable_approvals_whitelist":        pb.EnableApprovalsWhitelist,
+		"approvals_whitelist_user_ids":      pb.ApprovalsWhitelistUserIDs,
+		"approvals_whitelist_team_ids":      pb.ApprovalsWhitelistTeamIDs,
+		"required_approvals":                pb.RequiredApprovals,
+		"block_on_rejected_reviews":         pb.BlockOnRejectedReviews,
+		"block_on_official_review_requests": pb.BlockOnOfficialReviewRequests,
+		"block_on_outdated_branch":          pb.BlockOnOutdatedBranch,
+		"dismiss_stale_approvals":           pb.DismissStaleApprovals,
+		"ignore_stale_approvals":            pb.IgnoreStaleApprovals,
+		"require_signed_commits":            pb.RequireSignedCommits,
+		"protected_file_patterns":           pb.ProtectedFilePatterns,
+		"unprotected_file_patterns":         pb.UnprotectedFilePatterns,
+		"apply_to_admins":                   pb.ApplyToAdmins,
+	}
+}
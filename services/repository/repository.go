@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 
+	audit_model "code.gitea.io/gitea/models/audit"
 	"code.gitea.io/gitea/models/db"
 	"code.gitea.io/gitea/models/git"
 	issues_model "code.gitea.io/gitea/models/issues"
@@ -22,6 +23,7 @@ import (
 	repo_module "code.gitea.io/gitea/modules/repository"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/structs"
+	audit_service "code.gitea.io/gitea/services/audit"
 	federation_service "code.gitea.io/gitea/services/federation"
 	notify_service "code.gitea.io/gitea/services/notify"
 	pull_service "code.gitea.io/gitea/services/pull"
@@ -68,7 +70,7 @@ func DeleteRepository(ctx context.Context, doer *user_model.User, repo *repo_mod
 		return err
 	}
 
-	if err := federation_service.DeleteFollowingRepos(ctx, repo.ID); err != nil {
+	if err := federation_service.DeleteFollowingRepos(ctx, repo); err != nil {
 		return err
 	}
 
@@ -121,10 +123,26 @@ func UpdateRepository(ctx context.Context, repo *repo_model.Repository, visibili
 	}
 	defer committer.Close()
 
+	// visibilityChanged is also set when the visibility of the owner changed, only record actual flips
+	wasPrivate := repo.IsPrivate
+	if visibilityChanged {
+		stored, err := repo_model.GetRepositoryByID(ctx, repo.ID)
+		if err != nil {
+			return err
+		}
+		wasPrivate = stored.IsPrivate
+	}
+
 	if err = repo_module.UpdateRepository(ctx, repo, visibilityChanged); err != nil {
 		return fmt.Errorf("updateRepository: %w", err)
 	}
 
+	if wasPrivate != repo.IsPrivate {
+		audit_service.Record(ctx, audit_model.ActionRepoVisibility, nil, repo.OwnerID, audit_service.RepositoryTarget(repo), audit_model.Diff{
+			"private": {Old: wasPrivate, New: repo.IsPrivate},
+		})
+	}
+
 	return committer.Commit()
 }
 
@@ -15,6 +15,7 @@ import (
 	asymkey_model "code.gitea.io/gitea/models/asymkey"
 	auth_model "code.gitea.io/gitea/models/auth"
 	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/forgefed"
 	git_model "code.gitea.io/gitea/models/git"
 	issues_model "code.gitea.io/gitea/models/issues"
 	"code.gitea.io/gitea/models/organization"
@@ -72,14 +73,21 @@ func deleteUser(ctx context.Context, u *user_model.User, purge bool) (err error)
 	}
 	// ***** END: Follow *****
 
+	if err = auth_model.DeleteAccessTokenRepositoriesByUserID(ctx, u.ID); err != nil {
+		return fmt.Errorf("DeleteAccessTokenRepositoriesByUserID: %w", err)
+	}
+
 	if err = db.DeleteBeans(ctx,
 		&auth_model.AccessToken{UID: u.ID},
+		&auth_model.UserSession{UID: u.ID},
 		&repo_model.Collaboration{UserID: u.ID},
 		&access_model.Access{UserID: u.ID},
 		&repo_model.Watch{UserID: u.ID},
 		&repo_model.Star{UID: u.ID},
 		&user_model.Follow{UserID: u.ID},
 		&user_model.Follow{FollowID: u.ID},
+		&user_model.FederatedFollow{UserID: u.ID},
+		&user_model.FederatedFollow{FollowID: u.ID},
 		&activities_model.Action{UserID: u.ID},
 		&issues_model.IssueUser{UID: u.ID},
 		&user_model.EmailAddress{UID: u.ID},
@@ -104,6 +112,10 @@ func deleteUser(ctx context.Context, u *user_model.User, purge bool) (err error)
 		return err
 	}
 
+	if err := forgefed.DeleteOutboxActivitiesByUserID(ctx, u.ID); err != nil {
+		return err
+	}
+
 	if purge || (setting.Service.UserDeleteWithCommentsMaxTime != 0 &&
 		u.CreatedUnix.AsTime().Add(setting.Service.UserDeleteWithCommentsMaxTime).After(time.Now())) {
 		// Delete Comments
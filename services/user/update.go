@@ -8,12 +8,15 @@ import (
 	"fmt"
 
 	"code.gitea.io/gitea/models"
+	audit_model "code.gitea.io/gitea/models/audit"
 	auth_model "code.gitea.io/gitea/models/auth"
 	user_model "code.gitea.io/gitea/models/user"
 	password_module "code.gitea.io/gitea/modules/auth/password"
 	"code.gitea.io/gitea/modules/optional"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/util"
+	audit_service "code.gitea.io/gitea/services/audit"
 	"code.gitea.io/gitea/services/mailer"
 )
 
@@ -124,6 +127,7 @@ func UpdateUser(ctx context.Context, u *user_model.User, opts *UpdateOptions) er
 
 		cols = append(cols, "is_restricted")
 	}
+	wasAdmin := u.IsAdmin
 	if opts.IsAdmin.Has() {
 		if !opts.IsAdmin.Value() && user_model.IsLastAdminUser(ctx, u) {
 			return models.ErrDeleteLastAdminUser{UID: u.ID}
@@ -171,7 +175,20 @@ func UpdateUser(ctx context.Context, u *user_model.User, opts *UpdateOptions) er
 		cols = append(cols, "last_login_unix")
 	}
 
-	return user_model.UpdateUserCols(ctx, u, cols...)
+	if err := user_model.UpdateUserCols(ctx, u, cols...); err != nil {
+		return err
+	}
+
+	if wasAdmin != u.IsAdmin {
+		action := audit_model.ActionUserAdminGrant
+		if !u.IsAdmin {
+			action = audit_model.ActionUserAdminRevoke
+		}
+		audit_service.Record(ctx, action, nil, 0, audit_service.UserTarget(u), audit_model.Diff{
+			"is_admin": {Old: wasAdmin, New: u.IsAdmin},
+		})
+	}
+	return nil
 }
 
 type UpdateAuthOptions struct {
@@ -226,8 +243,40 @@ func UpdateAuth(ctx context.Context, u *user_model.User, opts *UpdateAuthOptions
 	}
 
 	if opts.Password.Has() {
+		// All sessions are signed out with the old password
+		if err := auth_model.RevokeUserSessionsByUID(ctx, u.ID); err != nil {
+			return err
+		}
 		return mailer.SendPasswordChange(u)
 	}
 
 	return nil
 }
+
+// RemovePassword removes the password of a local user, the user signs in with passkeys afterwards
+func RemovePassword(ctx context.Context, u *user_model.User) error {
+	if !u.IsLocal() {
+		return util.NewInvalidArgumentErrorf("only local users can remove their password")
+	}
+	has, err := auth_model.HasDiscoverableWebAuthnCredentialsByUID(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return util.NewInvalidArgumentErrorf("user %d has no passkey to sign in with", u.ID)
+	}
+
+	u.Passwd = ""
+	u.PasswdHashAlgo = ""
+	u.Salt = ""
+	u.MustChangePassword = false
+	if err := user_model.UpdateUserCols(ctx, u, "passwd", "passwd_hash_algo", "salt", "must_change_password"); err != nil {
+		return err
+	}
+
+	// All sessions are signed out with the removed password
+	if err := auth_model.RevokeUserSessionsByUID(ctx, u.ID); err != nil {
+		return err
+	}
+	return mailer.SendPasswordChange(u)
+}
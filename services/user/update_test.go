@@ -6,13 +6,16 @@ package user
 import (
 	"testing"
 
+	auth_model "code.gitea.io/gitea/models/auth"
 	"code.gitea.io/gitea/models/db"
 	"code.gitea.io/gitea/models/unittest"
 	user_model "code.gitea.io/gitea/models/user"
 	password_module "code.gitea.io/gitea/modules/auth/password"
 	"code.gitea.io/gitea/modules/optional"
 	"code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/util"
 
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -119,3 +122,31 @@ func TestUpdateAuth(t *testing.T) {
 		Password: optional.Some("aaaa"),
 	}), password_module.ErrMinLength)
 }
+
+func TestRemovePassword(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	require.True(t, user.IsPasswordSet())
+
+	// a passkey is needed to sign in without a password
+	require.ErrorIs(t, RemovePassword(db.DefaultContext, user), util.ErrInvalidArgument)
+	assert.True(t, user.IsPasswordSet())
+
+	// a security key which is only a second factor isn't enough
+	_, err := auth_model.CreateCredential(db.DefaultContext, user.ID, "Security key", &webauthn.Credential{ID: []byte("Security key")}, false)
+	require.NoError(t, err)
+	require.ErrorIs(t, RemovePassword(db.DefaultContext, user), util.ErrInvalidArgument)
+
+	_, err = auth_model.CreateCredential(db.DefaultContext, user.ID, "Passkey", &webauthn.Credential{ID: []byte("Passkey")}, true)
+	require.NoError(t, err)
+	require.NoError(t, auth_model.CreateUserSession(db.DefaultContext, &auth_model.UserSession{UID: user.ID, UserAgent: "browser"}))
+	require.NoError(t, RemovePassword(db.DefaultContext, user))
+	assert.False(t, user.IsPasswordSet())
+	// the sessions signed in with the password are revoked
+	unittest.AssertNotExistsBean(t, &auth_model.UserSession{UID: user.ID})
+
+	user = unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	assert.False(t, user.IsPasswordSet())
+	assert.Empty(t, user.Salt)
+}
@@ -13,7 +13,9 @@ import (
 
 	"code.gitea.io/gitea/models"
 	asymkey_model "code.gitea.io/gitea/models/asymkey"
+	audit_model "code.gitea.io/gitea/models/audit"
 	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/forgefed"
 	"code.gitea.io/gitea/models/organization"
 	packages_model "code.gitea.io/gitea/models/packages"
 	repo_model "code.gitea.io/gitea/models/repo"
@@ -25,6 +27,7 @@ import (
 	"code.gitea.io/gitea/modules/storage"
 	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/services/agit"
+	audit_service "code.gitea.io/gitea/services/audit"
 	org_service "code.gitea.io/gitea/services/org"
 	"code.gitea.io/gitea/services/packages"
 	container_service "code.gitea.io/gitea/services/packages/container"
@@ -255,6 +258,8 @@ func DeleteUser(ctx context.Context, u *user_model.User, purge bool) error {
 		return fmt.Errorf("DeleteUser: %w", err)
 	}
 
+	audit_service.Record(ctx, audit_model.ActionUserDelete, nil, 0, audit_service.UserTarget(u), nil)
+
 	if err := committer.Commit(); err != nil {
 		return err
 	}
@@ -314,3 +319,28 @@ func DeleteInactiveUsers(ctx context.Context, olderThan time.Duration) error {
 
 	return nil
 }
+
+// PurgeFederationHostUsers deletes the users of the federation hosts matching the rule
+// together with their stars, issues and comments
+func PurgeFederationHostUsers(ctx context.Context, rule *forgefed.FederationHostRule) error {
+	hosts, _, err := forgefed.FindFederationHosts(ctx, db.ListOptionsAll)
+	if err != nil {
+		return err
+	}
+	for _, host := range hosts {
+		if !rule.Matches(host.HostFqdn) {
+			continue
+		}
+		users, err := user_model.FindFederatedUsersByHostID(ctx, host.ID)
+		if err != nil {
+			return err
+		}
+		for _, u := range users {
+			if err := DeleteUser(ctx, u, true); err != nil {
+				return err
+			}
+		}
+		log.Info("Purged %d users of the federation host %s", len(users), host.HostFqdn)
+	}
+	return nil
+}
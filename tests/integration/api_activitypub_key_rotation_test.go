@@ -0,0 +1,200 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/forgefed"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/activitypub"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/routers"
+
+	ap "github.com/go-ap/activitypub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivityPubKeyRotation(t *testing.T) {
+	setting.Federation.Enabled = true
+	testWebRoutes = routers.NormalRoutes()
+	defer func() {
+		setting.Federation.Enabled = false
+		testWebRoutes = routers.NormalRoutes()
+	}()
+
+	srv := httptest.NewServer(testWebRoutes)
+	defer srv.Close()
+
+	// the remote actor signs its activities with the key of user1, later with the key of user4
+	var mu sync.Mutex
+	var publicKeyPem string
+	var received []*ap.Activity
+	receivedOfType := func(typ ap.ActivityVocabularyType) *ap.Activity {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, a := range received {
+			if a.Type == typ {
+				return a
+			}
+		}
+		return nil
+	}
+
+	federatedRoutes := http.NewServeMux()
+	federatedRoutes.HandleFunc("/.well-known/nodeinfo",
+		func(res http.ResponseWriter, req *http.Request) {
+			fmt.Fprintf(res, `{"links":[{"href":"http://%s/api/v1/nodeinfo","rel":"http://nodeinfo.diaspora.software/ns/schema/2.1"}]}`, req.Host)
+		})
+	federatedRoutes.HandleFunc("/api/v1/nodeinfo",
+		func(res http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(res, `{"version":"2.1","software":{"name":"forgejo","version":"1.20.0+dev-3183-g976d79044",`+
+				`"repository":"https://codeberg.org/forgejo/forgejo.git","homepage":"https://forgejo.org/"},`+
+				`"protocols":["activitypub"],"services":{"inbound":[],"outbound":["rss2.0"]},`+
+				`"openRegistrations":true,"usage":{"users":{"total":14,"activeHalfyear":2}},"metadata":{}}`)
+		})
+	federatedRoutes.HandleFunc("/api/v1/activitypub/user-id/15",
+		func(res http.ResponseWriter, req *http.Request) {
+			actor := fmt.Sprintf("http://%s/api/v1/activitypub/user-id/15", req.Host)
+			mu.Lock()
+			pem := publicKeyPem
+			mu.Unlock()
+			body, _ := json.Marshal(map[string]any{
+				"@context":          []string{"https://www.w3.org/ns/activitystreams", "https://w3id.org/security/v1"},
+				"id":                actor,
+				"type":              "Person",
+				"preferredUsername": "follower15",
+				"inbox":             actor + "/inbox",
+				"outbox":            actor + "/outbox",
+				"publicKey": map[string]string{
+					"id":           actor + "#main-key",
+					"owner":        actor,
+					"publicKeyPem": pem,
+				},
+			})
+			_, _ = res.Write(body)
+		})
+	federatedRoutes.HandleFunc("/api/v1/activitypub/user-id/15/inbox",
+		func(res http.ResponseWriter, req *http.Request) {
+			body, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			item, err := ap.UnmarshalJSON(body)
+			require.NoError(t, err)
+			activity, err := ap.ToActivity(item)
+			require.NoError(t, err)
+			mu.Lock()
+			received = append(received, activity)
+			mu.Unlock()
+			res.WriteHeader(http.StatusAccepted)
+		})
+	federatedRoutes.HandleFunc("/",
+		func(res http.ResponseWriter, req *http.Request) {
+			t.Errorf("Unhandled request: %q", req.URL.EscapedPath())
+		})
+	federatedSrv := httptest.NewServer(federatedRoutes)
+	defer federatedSrv.Close()
+
+	onGiteaRun(t, func(t *testing.T, _ *url.URL) {
+		appURL := setting.AppURL
+		setting.AppURL = srv.URL + "/"
+		defer func() {
+			setting.AppURL = appURL
+		}()
+
+		user1 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1})
+		pubKey, err := activitypub.GetPublicKey(db.DefaultContext, user1)
+		require.NoError(t, err)
+		mu.Lock()
+		publicKeyPem = pubKey
+		mu.Unlock()
+
+		remoteActor := federatedSrv.URL + "/api/v1/activitypub/user-id/15"
+		remoteKeyID := remoteActor + "#main-key"
+		user2 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+		user2Actor := fmt.Sprintf("%s/api/v1/activitypub/user-id/2", srv.URL)
+		user4 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 4})
+
+		cf, err := activitypub.GetClientFactory(db.DefaultContext)
+		require.NoError(t, err)
+		postToInbox := func(t *testing.T, signer *user_model.User, activity string, expectedStatus int) {
+			t.Helper()
+			c, err := cf.WithKeys(db.DefaultContext, signer, remoteKeyID)
+			require.NoError(t, err)
+			resp, err := c.Post([]byte(activity), user2Actor+"/inbox")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, expectedStatus, resp.StatusCode)
+		}
+
+		followID := remoteActor + "/outbox/1"
+		postToInbox(t, user1, fmt.Sprintf(`{"id":%q,"type":"Follow","actor":%q,"object":%q}`, followID, remoteActor, user2Actor), http.StatusNoContent)
+		federatedUser := unittest.AssertExistsAndLoadBean(t, &user_model.FederatedUser{ExternalID: "15"})
+		unittest.AssertExistsAndLoadBean(t, &user_model.Follow{UserID: federatedUser.UserID, FollowID: user2.ID})
+		cached := unittest.AssertExistsAndLoadBean(t, &forgefed.RemoteKey{KeyID: remoteKeyID})
+		assert.Equal(t, pubKey, cached.PublicKeyPem)
+
+		t.Run("RotateLocalKey", func(t *testing.T) {
+			oldKey, err := activitypub.GetPublicKey(db.DefaultContext, user2)
+			require.NoError(t, err)
+
+			token := getUserToken(t, user2.Name, auth_model.AccessTokenScopeWriteUser)
+			req := NewRequest(t, "POST", "/api/v1/user/activitypub/rotate-key").AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusNoContent)
+
+			newKey, err := activitypub.GetPublicKey(db.DefaultContext, user2)
+			require.NoError(t, err)
+			assert.NotEqual(t, oldKey, newKey)
+
+			var update *ap.Activity
+			assert.Eventually(t, func() bool {
+				update = receivedOfType(ap.UpdateType)
+				return update != nil
+			}, 10*time.Second, 100*time.Millisecond)
+			assert.Equal(t, user2Actor, update.Actor.GetID().String())
+			person, err := ap.ToActor(update.Object)
+			require.NoError(t, err)
+			assert.Equal(t, user2Actor+"#main-key", person.PublicKey.ID.String())
+			assert.Equal(t, newKey, person.PublicKey.PublicKeyPem)
+		})
+
+		t.Run("RotateRemoteKey", func(t *testing.T) {
+			// the cached key no longer matches, the new key is fetched
+			newKey, err := activitypub.GetPublicKey(db.DefaultContext, user4)
+			require.NoError(t, err)
+			mu.Lock()
+			publicKeyPem = newKey
+			mu.Unlock()
+
+			postToInbox(t, user4, fmt.Sprintf(`{"type":"Undo","actor":%q,"object":%q}`, remoteActor, followID), http.StatusNoContent)
+			unittest.AssertNotExistsBean(t, &user_model.Follow{UserID: federatedUser.UserID, FollowID: user2.ID})
+			cached := unittest.AssertExistsAndLoadBean(t, &forgefed.RemoteKey{KeyID: remoteKeyID})
+			assert.Equal(t, newKey, cached.PublicKeyPem)
+
+			// the previous key is rejected once the new key was fetched
+			postToInbox(t, user1, fmt.Sprintf(`{"id":%q,"type":"Follow","actor":%q,"object":%q}`, remoteActor+"/outbox/2", remoteActor, user2Actor), http.StatusForbidden)
+		})
+
+		t.Run("UpdateRemoteKey", func(t *testing.T) {
+			user5 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 5})
+			announcedKey, err := activitypub.GetPublicKey(db.DefaultContext, user5)
+			require.NoError(t, err)
+			postToInbox(t, user4, fmt.Sprintf(`{"type":"Update","actor":%q,"object":{"id":%q,"type":"Person","publicKey":{"id":%q,"owner":%q,"publicKeyPem":%q}}}`,
+				remoteActor, remoteActor, remoteKeyID, remoteActor, announcedKey), http.StatusNoContent)
+			cached := unittest.AssertExistsAndLoadBean(t, &forgefed.RemoteKey{KeyID: remoteKeyID})
+			assert.Equal(t, announcedKey, cached.PublicKeyPem)
+		})
+	})
+}
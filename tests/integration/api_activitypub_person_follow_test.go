@@ -0,0 +1,218 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/activitypub"
+	"code.gitea.io/gitea/modules/setting"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/routers"
+
+	ap "github.com/go-ap/activitypub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivityPubPersonFollow(t *testing.T) {
+	setting.Federation.Enabled = true
+	testWebRoutes = routers.NormalRoutes()
+	defer func() {
+		setting.Federation.Enabled = false
+		testWebRoutes = routers.NormalRoutes()
+	}()
+
+	srv := httptest.NewServer(testWebRoutes)
+	defer srv.Close()
+
+	// the remote actor signs its activities with the key of user1
+	var publicKeyPem string
+	var mu sync.Mutex
+	var received []*ap.Activity
+	receivedOfType := func(typ ap.ActivityVocabularyType) *ap.Activity {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, a := range received {
+			if a.Type == typ {
+				return a
+			}
+		}
+		return nil
+	}
+
+	federatedRoutes := http.NewServeMux()
+	federatedRoutes.HandleFunc("/.well-known/nodeinfo",
+		func(res http.ResponseWriter, req *http.Request) {
+			fmt.Fprintf(res, `{"links":[{"href":"http://%s/api/v1/nodeinfo","rel":"http://nodeinfo.diaspora.software/ns/schema/2.1"}]}`, req.Host)
+		})
+	federatedRoutes.HandleFunc("/api/v1/nodeinfo",
+		func(res http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(res, `{"version":"2.1","software":{"name":"forgejo","version":"1.20.0+dev-3183-g976d79044",`+
+				`"repository":"https://codeberg.org/forgejo/forgejo.git","homepage":"https://forgejo.org/"},`+
+				`"protocols":["activitypub"],"services":{"inbound":[],"outbound":["rss2.0"]},`+
+				`"openRegistrations":true,"usage":{"users":{"total":14,"activeHalfyear":2}},"metadata":{}}`)
+		})
+	federatedRoutes.HandleFunc("/api/v1/activitypub/user-id/15",
+		func(res http.ResponseWriter, req *http.Request) {
+			actor := fmt.Sprintf("http://%s/api/v1/activitypub/user-id/15", req.Host)
+			body, _ := json.Marshal(map[string]any{
+				"@context":          []string{"https://www.w3.org/ns/activitystreams", "https://w3id.org/security/v1"},
+				"id":                actor,
+				"type":              "Person",
+				"preferredUsername": "follower15",
+				"inbox":             actor + "/inbox",
+				"outbox":            actor + "/outbox",
+				"publicKey": map[string]string{
+					"id":           actor + "#main-key",
+					"owner":        actor,
+					"publicKeyPem": publicKeyPem,
+				},
+			})
+			_, _ = res.Write(body)
+		})
+	federatedRoutes.HandleFunc("/api/v1/activitypub/user-id/15/inbox",
+		func(res http.ResponseWriter, req *http.Request) {
+			body, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			item, err := ap.UnmarshalJSON(body)
+			require.NoError(t, err)
+			activity, err := ap.ToActivity(item)
+			require.NoError(t, err)
+			mu.Lock()
+			received = append(received, activity)
+			mu.Unlock()
+			res.WriteHeader(http.StatusAccepted)
+		})
+	federatedRoutes.HandleFunc("/",
+		func(res http.ResponseWriter, req *http.Request) {
+			t.Errorf("Unhandled request: %q", req.URL.EscapedPath())
+		})
+	federatedSrv := httptest.NewServer(federatedRoutes)
+	defer federatedSrv.Close()
+
+	onGiteaRun(t, func(t *testing.T, _ *url.URL) {
+		appURL := setting.AppURL
+		setting.AppURL = srv.URL + "/"
+		defer func() {
+			setting.AppURL = appURL
+		}()
+
+		user1 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1})
+		pubKey, err := activitypub.GetPublicKey(db.DefaultContext, user1)
+		require.NoError(t, err)
+		publicKeyPem = pubKey
+
+		remoteActor := federatedSrv.URL + "/api/v1/activitypub/user-id/15"
+		user2 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+		user2Actor := fmt.Sprintf("%s/api/v1/activitypub/user-id/2", srv.URL)
+
+		cf, err := activitypub.GetClientFactory(db.DefaultContext)
+		require.NoError(t, err)
+		c, err := cf.WithKeys(db.DefaultContext, user1, remoteActor+"#main-key")
+		require.NoError(t, err)
+		postToInbox := func(t *testing.T, activity string, expectedStatus int) {
+			t.Helper()
+			resp, err := c.Post([]byte(activity), user2Actor+"/inbox")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, expectedStatus, resp.StatusCode)
+		}
+
+		t.Run("FollowLocalUser", func(t *testing.T) {
+			followID := remoteActor + "/outbox/1"
+			postToInbox(t, fmt.Sprintf(`{"id":%q,"type":"Follow","actor":%q,"object":%q}`, followID, remoteActor, user2Actor), http.StatusNoContent)
+
+			federatedUser := unittest.AssertExistsAndLoadBean(t, &user_model.FederatedUser{ExternalID: "15"})
+			unittest.AssertExistsAndLoadBean(t, &user_model.FederatedFollow{UserID: federatedUser.UserID, FollowID: user2.ID, ActivityID: followID, Accepted: true})
+			unittest.AssertExistsAndLoadBean(t, &user_model.Follow{UserID: federatedUser.UserID, FollowID: user2.ID})
+
+			assert.Eventually(t, func() bool {
+				accept := receivedOfType(ap.AcceptType)
+				return accept != nil && accept.Object.GetID().String() == followID
+			}, 10*time.Second, 100*time.Millisecond)
+
+			// the Accept is only listed for the actor it is addressed to
+			req := NewRequest(t, "GET", "/api/v1/activitypub/user-id/2/outbox")
+			resp := MakeRequest(t, req, http.StatusOK)
+			var outbox ap.OrderedCollection
+			require.NoError(t, outbox.UnmarshalJSON(resp.Body.Bytes()))
+			assert.Empty(t, outbox.OrderedItems)
+
+			signedResp, err := c.Get(user2Actor + "/outbox")
+			require.NoError(t, err)
+			defer signedResp.Body.Close()
+			require.Equal(t, http.StatusOK, signedResp.StatusCode)
+			body, err := io.ReadAll(signedResp.Body)
+			require.NoError(t, err)
+			outbox = ap.OrderedCollection{}
+			require.NoError(t, outbox.UnmarshalJSON(body))
+			require.Len(t, outbox.OrderedItems, 1)
+			assert.Equal(t, ap.AcceptType, outbox.OrderedItems[0].GetType())
+
+			// the outboxes of private and limited users aren't listed
+			MakeRequest(t, NewRequest(t, "GET", "/api/v1/activitypub/user-id/31/outbox"), http.StatusNotFound)
+			MakeRequest(t, NewRequest(t, "GET", "/api/v1/activitypub/user-id/33/outbox"), http.StatusNotFound)
+
+			postToInbox(t, fmt.Sprintf(`{"type":"Undo","actor":%q,"object":%q}`, remoteActor, followID), http.StatusNoContent)
+			unittest.AssertNotExistsBean(t, &user_model.FederatedFollow{UserID: federatedUser.UserID, FollowID: user2.ID})
+			unittest.AssertNotExistsBean(t, &user_model.Follow{UserID: federatedUser.UserID, FollowID: user2.ID})
+		})
+
+		t.Run("FollowOfOtherUser", func(t *testing.T) {
+			postToInbox(t, fmt.Sprintf(`{"id":%q,"type":"Follow","actor":%q,"object":%q}`, remoteActor+"/outbox/2", remoteActor, srv.URL+"/api/v1/activitypub/user-id/4"), http.StatusNotAcceptable)
+		})
+
+		t.Run("ActorIsNotSigner", func(t *testing.T) {
+			postToInbox(t, fmt.Sprintf(`{"id":%q,"type":"Follow","actor":%q,"object":%q}`, remoteActor+"/outbox/3", srv.URL+"/api/v1/activitypub/user-id/1", user2Actor), http.StatusForbidden)
+		})
+
+		t.Run("FollowRemoteUser", func(t *testing.T) {
+			token := getUserToken(t, user2.Name, auth_model.AccessTokenScopeWriteUser)
+			req := NewRequestWithJSON(t, "POST", "/api/v1/user/following/federated", &api.FollowFederatedUserOption{ActorID: remoteActor}).
+				AddTokenAuth(token)
+			resp := MakeRequest(t, req, http.StatusCreated)
+			var apiUser api.User
+			DecodeJSON(t, resp, &apiUser)
+
+			// the follow is pending until the remote user accepts it
+			f := unittest.AssertExistsAndLoadBean(t, &user_model.FederatedFollow{UserID: user2.ID, FollowID: apiUser.ID})
+			assert.False(t, f.Accepted)
+			unittest.AssertNotExistsBean(t, &user_model.Follow{UserID: user2.ID, FollowID: apiUser.ID})
+
+			var follow *ap.Activity
+			assert.Eventually(t, func() bool {
+				follow = receivedOfType(ap.FollowType)
+				return follow != nil
+			}, 10*time.Second, 100*time.Millisecond)
+			assert.Equal(t, f.ActivityID, follow.ID.String())
+			assert.Equal(t, user2Actor, follow.Actor.GetID().String())
+
+			postToInbox(t, fmt.Sprintf(`{"type":"Accept","actor":%q,"object":%q}`, remoteActor, f.ActivityID), http.StatusNoContent)
+			unittest.AssertExistsAndLoadBean(t, &user_model.FederatedFollow{UserID: user2.ID, FollowID: apiUser.ID, Accepted: true})
+			unittest.AssertExistsAndLoadBean(t, &user_model.Follow{UserID: user2.ID, FollowID: apiUser.ID})
+
+			req = NewRequest(t, "DELETE", "/api/v1/user/following/"+url.PathEscape(apiUser.UserName)).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusNoContent)
+			unittest.AssertNotExistsBean(t, &user_model.FederatedFollow{UserID: user2.ID, FollowID: apiUser.ID})
+			unittest.AssertNotExistsBean(t, &user_model.Follow{UserID: user2.ID, FollowID: apiUser.ID})
+			assert.Eventually(t, func() bool {
+				return receivedOfType(ap.UndoType) != nil
+			}, 10*time.Second, 100*time.Millisecond)
+		})
+	})
+}
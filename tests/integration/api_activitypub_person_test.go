@@ -105,7 +105,8 @@ func TestActivityPubPersonInbox(t *testing.T) {
 		user2inboxurl := fmt.Sprintf("%s/api/v1/activitypub/user-id/2/inbox", srv.URL)
 
 		// Signed request succeeds
-		resp, err := c.Post([]byte{}, user2inboxurl)
+		activity := []byte(fmt.Sprintf(`{"type":"Like","actor":"%s/api/v1/activitypub/user-id/1","object":"%s/api/v1/activitypub/user-id/2"}`, srv.URL, srv.URL))
+		resp, err := c.Post(activity, user2inboxurl)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
 
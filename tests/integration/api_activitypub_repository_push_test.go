@@ -0,0 +1,243 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	activities_model "code.gitea.io/gitea/models/activities"
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/activitypub"
+	fm "code.gitea.io/gitea/modules/forgefed"
+	"code.gitea.io/gitea/modules/setting"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/routers"
+
+	ap "github.com/go-ap/activitypub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivityPubRepositoryPush(t *testing.T) {
+	setting.Federation.Enabled = true
+	testWebRoutes = routers.NormalRoutes()
+	defer func() {
+		setting.Federation.Enabled = false
+		testWebRoutes = routers.NormalRoutes()
+	}()
+
+	srv := httptest.NewServer(testWebRoutes)
+	defer srv.Close()
+
+	// the repository of the other instance is owned by the remote actor, who signs with the key of user1
+	var publicKeyPem string
+	var mu sync.Mutex
+	var received []*ap.Activity
+	receivedOfType := func(typ ap.ActivityVocabularyType) *ap.Activity {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, a := range received {
+			if a.Type == typ {
+				return a
+			}
+		}
+		return nil
+	}
+
+	federatedRoutes := http.NewServeMux()
+	federatedRoutes.HandleFunc("/.well-known/nodeinfo",
+		func(res http.ResponseWriter, req *http.Request) {
+			fmt.Fprintf(res, `{"links":[{"href":"http://%s/api/v1/nodeinfo","rel":"http://nodeinfo.diaspora.software/ns/schema/2.1"}]}`, req.Host)
+		})
+	federatedRoutes.HandleFunc("/api/v1/nodeinfo",
+		func(res http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(res, `{"version":"2.1","software":{"name":"forgejo","version":"1.20.0+dev-3183-g976d79044",`+
+				`"repository":"https://codeberg.org/forgejo/forgejo.git","homepage":"https://forgejo.org/"},`+
+				`"protocols":["activitypub"],"services":{"inbound":[],"outbound":["rss2.0"]},`+
+				`"openRegistrations":true,"usage":{"users":{"total":14,"activeHalfyear":2}},"metadata":{}}`)
+		})
+	federatedRoutes.HandleFunc("/api/v1/activitypub/user-id/15",
+		func(res http.ResponseWriter, req *http.Request) {
+			actor := fmt.Sprintf("http://%s/api/v1/activitypub/user-id/15", req.Host)
+			body, _ := json.Marshal(map[string]any{
+				"@context":          []string{"https://www.w3.org/ns/activitystreams", "https://w3id.org/security/v1"},
+				"id":                actor,
+				"type":              "Person",
+				"preferredUsername": "pusher15",
+				"inbox":             actor + "/inbox",
+				"outbox":            actor + "/outbox",
+				"publicKey": map[string]string{
+					"id":           actor + "#main-key",
+					"owner":        actor,
+					"publicKeyPem": publicKeyPem,
+				},
+			})
+			_, _ = res.Write(body)
+		})
+	federatedRoutes.HandleFunc("/api/v1/activitypub/repository-id/5/inbox",
+		func(res http.ResponseWriter, req *http.Request) {
+			body, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			item, err := ap.UnmarshalJSON(body)
+			require.NoError(t, err)
+			activity, err := ap.ToActivity(item)
+			require.NoError(t, err)
+			mu.Lock()
+			received = append(received, activity)
+			mu.Unlock()
+			res.WriteHeader(http.StatusAccepted)
+		})
+	federatedRoutes.HandleFunc("/",
+		func(res http.ResponseWriter, req *http.Request) {
+			t.Errorf("Unhandled request: %q", req.URL.EscapedPath())
+		})
+	federatedSrv := httptest.NewServer(federatedRoutes)
+	defer federatedSrv.Close()
+
+	onGiteaRun(t, func(t *testing.T, _ *url.URL) {
+		appURL := setting.AppURL
+		setting.AppURL = srv.URL + "/"
+		defer func() {
+			setting.AppURL = appURL
+		}()
+
+		user1 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1})
+		pubKey, err := activitypub.GetPublicKey(db.DefaultContext, user1)
+		require.NoError(t, err)
+		publicKeyPem = pubKey
+
+		remoteActor := federatedSrv.URL + "/api/v1/activitypub/user-id/15"
+		remoteRepo := federatedSrv.URL + "/api/v1/activitypub/repository-id/5"
+		user2 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+		repo1 := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1, OwnerID: user2.ID})
+		repoActor := srv.URL + "/api/v1/activitypub/repository-id/1"
+
+		cf, err := activitypub.GetClientFactory(db.DefaultContext)
+		require.NoError(t, err)
+		c, err := cf.WithKeys(db.DefaultContext, user1, remoteActor+"#main-key")
+		require.NoError(t, err)
+		postToInbox := func(t *testing.T, activity string, expectedStatus int) {
+			t.Helper()
+			resp, err := c.Post([]byte(activity), repoActor+"/inbox")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, expectedStatus, resp.StatusCode)
+		}
+
+		t.Run("FollowedByRemoteRepository", func(t *testing.T) {
+			follow := fmt.Sprintf(`{"type":"Follow","actor":%q,"object":%q}`, remoteRepo, repoActor)
+			postToInbox(t, follow, http.StatusNoContent)
+			unittest.AssertExistsAndLoadBean(t, &repo_model.FollowerRepo{RepoID: repo1.ID, URI: remoteRepo})
+
+			token := getUserToken(t, user2.Name, auth_model.AccessTokenScopeWriteRepository)
+			req := NewRequestWithJSON(t, "POST", fmt.Sprintf("/api/v1/repos/%s/contents/federated.txt", repo1.FullName()), &api.CreateFileOptions{
+				FileOptions:   api.FileOptions{Message: "Add federated.txt"},
+				ContentBase64: base64.StdEncoding.EncodeToString([]byte("federated")),
+			}).AddTokenAuth(token)
+			resp := MakeRequest(t, req, http.StatusCreated)
+			var fileResponse api.FileResponse
+			DecodeJSON(t, resp, &fileResponse)
+
+			var push *ap.Activity
+			assert.Eventually(t, func() bool {
+				push = receivedOfType(fm.PushType)
+				return push != nil
+			}, 10*time.Second, 100*time.Millisecond)
+			assert.Equal(t, user2.APActorID(), push.Actor.GetID().String())
+			assert.Equal(t, repoActor, push.Context.GetID().String())
+			target, err := ap.ToObject(push.Target)
+			require.NoError(t, err)
+			assert.Equal(t, repo1.DefaultBranch, target.Name.String())
+			commits, err := ap.ToOrderedCollection(push.Object)
+			require.NoError(t, err)
+			require.NotEmpty(t, commits.OrderedItems)
+			commit, err := fm.ToCommit(commits.OrderedItems[0])
+			require.NoError(t, err)
+			assert.Equal(t, fileResponse.Commit.SHA, commit.Hash)
+			assert.Equal(t, "Add federated.txt", commit.Summary.String())
+
+			undo := fmt.Sprintf(`{"type":"Undo","actor":%q,"object":{"type":"Follow","actor":%q,"object":%q}}`, remoteRepo, remoteRepo, repoActor)
+			postToInbox(t, undo, http.StatusNoContent)
+			unittest.AssertNotExistsBean(t, &repo_model.FollowerRepo{RepoID: repo1.ID, URI: remoteRepo})
+		})
+
+		t.Run("FollowOfOtherInstance", func(t *testing.T) {
+			// the key of the remote actor can't sign for repositories of other instances
+			follow := fmt.Sprintf(`{"type":"Follow","actor":%q,"object":%q}`, srv.URL+"/api/v1/activitypub/repository-id/2", repoActor)
+			postToInbox(t, follow, http.StatusForbidden)
+		})
+
+		t.Run("FollowingRemoteRepository", func(t *testing.T) {
+			session := loginUser(t, user2.Name)
+			link := fmt.Sprintf("/%s/settings", repo1.FullName())
+			req := NewRequestWithValues(t, "POST", link, map[string]string{
+				"_csrf":           GetCSRF(t, session, link),
+				"action":          "federation",
+				"following_repos": remoteRepo,
+			})
+			session.MakeRequest(t, req, http.StatusSeeOther)
+
+			var follow *ap.Activity
+			assert.Eventually(t, func() bool {
+				follow = receivedOfType(ap.FollowType)
+				return follow != nil
+			}, 10*time.Second, 100*time.Millisecond)
+			assert.Equal(t, repoActor, follow.Actor.GetID().String())
+			assert.Equal(t, remoteRepo, follow.Object.GetID().String())
+
+			remoteCommit := federatedSrv.URL + "/pusher15/repo5/commit/65f1bf27bc3bf70f64657658635e66094edbcb4d"
+			pushTo := func(context string) string {
+				return fmt.Sprintf(`{"type":"Push","actor":%q,"context":%q,"target":{"type":"Branch","name":"main"},`+
+					`"object":{"type":"OrderedCollection","totalItems":2,"orderedItems":[`+
+					`{"id":%q,"type":"Commit","hash":"65f1bf27bc3bf70f64657658635e66094edbcb4d","summary":"Fix the build","content":"Fix the build\n\nIt was broken."},`+
+					`{"id":"https://example.com/commit/b6a6e9e5","type":"Commit","hash":"b6a6e9e5a2e30b5a5c7ef30bc8a3bc1b8b1e0f7b","summary":"Break the build"}]}}`,
+					remoteActor, context, remoteCommit)
+			}
+			postToInbox(t, pushTo(remoteRepo), http.StatusNoContent)
+			federatedUser := unittest.AssertExistsAndLoadBean(t, &user_model.FederatedUser{ExternalID: "15"})
+			action := unittest.AssertExistsAndLoadBean(t, &activities_model.Action{
+				UserID:    user2.ID,
+				ActUserID: federatedUser.UserID,
+				RepoID:    repo1.ID,
+				OpType:    activities_model.ActionFederatedPush,
+			})
+			assert.Equal(t, "main", action.GetBranch())
+			assert.Contains(t, action.Content, "65f1bf27bc3bf70f64657658635e66094edbcb4d")
+
+			// the commits link to the remote repository unless their IRI is on another instance
+			resp := session.MakeRequest(t, NewRequest(t, "GET", "/"), http.StatusOK)
+			htmlDoc := NewHTMLParser(t, resp.Body)
+			assert.Equal(t, 1, htmlDoc.Find(fmt.Sprintf(`a.sha[href=%q]`, remoteCommit)).Length())
+			assert.Equal(t, 0, htmlDoc.Find(fmt.Sprintf(`a.sha[href="/%s/commit/65f1bf27bc3bf70f64657658635e66094edbcb4d"]`, repo1.FullName())).Length())
+			assert.Equal(t, 0, htmlDoc.Find(`a.sha[href*="b6a6e9e5"]`).Length())
+			assert.Equal(t, 1, htmlDoc.Find(`span.sha`).Length())
+
+			// pushes to repositories which aren't followed are rejected
+			postToInbox(t, pushTo(federatedSrv.URL+"/api/v1/activitypub/repository-id/6"), http.StatusNotAcceptable)
+
+			req = NewRequestWithValues(t, "POST", link, map[string]string{
+				"_csrf":           GetCSRF(t, session, link),
+				"action":          "federation",
+				"following_repos": "",
+			})
+			session.MakeRequest(t, req, http.StatusSeeOther)
+			assert.Eventually(t, func() bool {
+				return receivedOfType(ap.UndoType) != nil
+			}, 10*time.Second, 100*time.Millisecond)
+		})
+	})
+}
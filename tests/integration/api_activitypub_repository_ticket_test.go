@@ -0,0 +1,224 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	issues_model "code.gitea.io/gitea/models/issues"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/activitypub"
+	"code.gitea.io/gitea/modules/forgefed"
+	"code.gitea.io/gitea/modules/setting"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/routers"
+	user_service "code.gitea.io/gitea/services/user"
+
+	ap "github.com/go-ap/activitypub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivityPubRepositoryTicket(t *testing.T) {
+	setting.Federation.Enabled = true
+	testWebRoutes = routers.NormalRoutes()
+	defer func() {
+		setting.Federation.Enabled = false
+		testWebRoutes = routers.NormalRoutes()
+	}()
+
+	srv := httptest.NewServer(testWebRoutes)
+	defer srv.Close()
+
+	// the remote actor signs its activities with the key of user1
+	var publicKeyPem string
+	var mu sync.Mutex
+	var received []*ap.Activity
+	receivedOfType := func(typ ap.ActivityVocabularyType) *ap.Activity {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, a := range received {
+			if a.Type == typ {
+				return a
+			}
+		}
+		return nil
+	}
+
+	federatedRoutes := http.NewServeMux()
+	federatedRoutes.HandleFunc("/.well-known/nodeinfo",
+		func(res http.ResponseWriter, req *http.Request) {
+			fmt.Fprintf(res, `{"links":[{"href":"http://%s/api/v1/nodeinfo","rel":"http://nodeinfo.diaspora.software/ns/schema/2.1"}]}`, req.Host)
+		})
+	federatedRoutes.HandleFunc("/api/v1/nodeinfo",
+		func(res http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(res, `{"version":"2.1","software":{"name":"forgejo","version":"1.20.0+dev-3183-g976d79044",`+
+				`"repository":"https://codeberg.org/forgejo/forgejo.git","homepage":"https://forgejo.org/"},`+
+				`"protocols":["activitypub"],"services":{"inbound":[],"outbound":["rss2.0"]},`+
+				`"openRegistrations":true,"usage":{"users":{"total":14,"activeHalfyear":2}},"metadata":{}}`)
+		})
+	federatedRoutes.HandleFunc("/api/v1/activitypub/user-id/15",
+		func(res http.ResponseWriter, req *http.Request) {
+			actor := fmt.Sprintf("http://%s/api/v1/activitypub/user-id/15", req.Host)
+			body, _ := json.Marshal(map[string]any{
+				"@context":          []string{"https://www.w3.org/ns/activitystreams", "https://w3id.org/security/v1"},
+				"id":                actor,
+				"type":              "Person",
+				"preferredUsername": "reporter15",
+				"inbox":             actor + "/inbox",
+				"outbox":            actor + "/outbox",
+				"publicKey": map[string]string{
+					"id":           actor + "#main-key",
+					"owner":        actor,
+					"publicKeyPem": publicKeyPem,
+				},
+			})
+			_, _ = res.Write(body)
+		})
+	federatedRoutes.HandleFunc("/api/v1/activitypub/user-id/15/inbox",
+		func(res http.ResponseWriter, req *http.Request) {
+			body, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			item, err := ap.UnmarshalJSON(body)
+			require.NoError(t, err)
+			activity, err := ap.ToActivity(item)
+			require.NoError(t, err)
+			mu.Lock()
+			received = append(received, activity)
+			mu.Unlock()
+			res.WriteHeader(http.StatusAccepted)
+		})
+	federatedRoutes.HandleFunc("/",
+		func(res http.ResponseWriter, req *http.Request) {
+			t.Errorf("Unhandled request: %q", req.URL.EscapedPath())
+		})
+	federatedSrv := httptest.NewServer(federatedRoutes)
+	defer federatedSrv.Close()
+
+	onGiteaRun(t, func(t *testing.T, _ *url.URL) {
+		appURL := setting.AppURL
+		setting.AppURL = srv.URL + "/"
+		defer func() {
+			setting.AppURL = appURL
+		}()
+
+		user1 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1})
+		pubKey, err := activitypub.GetPublicKey(db.DefaultContext, user1)
+		require.NoError(t, err)
+		publicKeyPem = pubKey
+
+		remoteActor := federatedSrv.URL + "/api/v1/activitypub/user-id/15"
+		repoActor := srv.URL + "/api/v1/activitypub/repository-id/1"
+
+		cf, err := activitypub.GetClientFactory(db.DefaultContext)
+		require.NoError(t, err)
+		c, err := cf.WithKeys(db.DefaultContext, user1, remoteActor+"#main-key")
+		require.NoError(t, err)
+		postToInbox := func(t *testing.T, activity string, expectedStatus int) {
+			t.Helper()
+			resp, err := c.Post([]byte(activity), repoActor+"/inbox")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, expectedStatus, resp.StatusCode)
+		}
+		offerTicket := func(id, summary string) string {
+			return fmt.Sprintf(`{"id":%q,"type":"Offer","actor":%q,"target":%q,`+
+				`"object":{"type":"Ticket","attributedTo":%q,"summary":%q,"source":{"content":"Steps to reproduce","mediaType":"text/markdown"}}}`,
+				id, remoteActor, repoActor, remoteActor, summary)
+		}
+
+		var issue *issues_model.Issue
+		t.Run("OfferTicket", func(t *testing.T) {
+			offerID := remoteActor + "/outbox/1"
+			postToInbox(t, offerTicket(offerID, "Crash on startup"), http.StatusNoContent)
+
+			federatedUser := unittest.AssertExistsAndLoadBean(t, &user_model.FederatedUser{ExternalID: "15"})
+			issue = unittest.AssertExistsAndLoadBean(t, &issues_model.Issue{RepoID: 1, PosterID: federatedUser.UserID, Title: "Crash on startup"})
+			assert.Equal(t, "Steps to reproduce", issue.Content)
+			unittest.AssertExistsAndLoadBean(t, &issues_model.FederatedIssue{IssueID: issue.ID, OfferID: offerID})
+
+			ticketID := fmt.Sprintf("%s/issues/%d", repoActor, issue.Index)
+			assert.Eventually(t, func() bool {
+				accept := receivedOfType(ap.AcceptType)
+				return accept != nil && accept.Object.GetID().String() == offerID && accept.Result.GetID().String() == ticketID
+			}, 10*time.Second, 100*time.Millisecond)
+
+			// a replayed offer doesn't open another issue
+			postToInbox(t, offerTicket(offerID, "Crash on startup"), http.StatusNoContent)
+			assert.Equal(t, 1, unittest.GetCount(t, &issues_model.Issue{RepoID: 1, PosterID: federatedUser.UserID}))
+
+			req := NewRequest(t, "GET", fmt.Sprintf("/api/v1/activitypub/repository-id/1/issues/%d", issue.Index))
+			resp := MakeRequest(t, req, http.StatusOK)
+			var ticket forgefed.Ticket
+			require.NoError(t, ticket.UnmarshalJSON(resp.Body.Bytes()))
+			assert.Equal(t, ticketID, ticket.ID.String())
+			assert.Equal(t, forgefed.TicketType, ticket.Type)
+			assert.Equal(t, "Crash on startup", ticket.Summary.First().Value.String())
+			assert.Equal(t, remoteActor, ticket.AttributedTo.GetID().String())
+			assert.False(t, ticket.IsResolved)
+		})
+
+		t.Run("CreateNote", func(t *testing.T) {
+			ticketID := fmt.Sprintf("%s/issues/%d", repoActor, issue.Index)
+			noteID := remoteActor + "/outbox/2"
+			note := fmt.Sprintf(`{"id":%q,"type":"Create","actor":%q,"object":{"id":%q,"type":"Note","attributedTo":%q,"context":%q,"content":"It also crashes on shutdown"}}`,
+				noteID+"/create", remoteActor, noteID, remoteActor, ticketID)
+			postToInbox(t, note, http.StatusNoContent)
+			comment := unittest.AssertExistsAndLoadBean(t, &issues_model.Comment{IssueID: issue.ID, PosterID: issue.PosterID, Type: issues_model.CommentTypeComment})
+			assert.Equal(t, "It also crashes on shutdown", comment.Content)
+			unittest.AssertExistsAndLoadBean(t, &issues_model.FederatedComment{IssueID: issue.ID, CommentID: comment.ID, NoteID: noteID})
+
+			// a replayed note doesn't add another comment
+			postToInbox(t, note, http.StatusNoContent)
+			assert.Equal(t, 1, unittest.GetCount(t, &issues_model.Comment{IssueID: issue.ID, PosterID: issue.PosterID, Type: issues_model.CommentTypeComment}))
+		})
+
+		t.Run("SendComment", func(t *testing.T) {
+			token := getUserToken(t, "user2", auth_model.AccessTokenScopeWriteIssue)
+			req := NewRequestWithJSON(t, "POST", fmt.Sprintf("/api/v1/repos/user2/repo1/issues/%d/comments", issue.Index), &api.CreateIssueCommentOption{
+				Body: "Fixed in the next release",
+			}).AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusCreated)
+
+			assert.Eventually(t, func() bool {
+				create := receivedOfType(ap.CreateType)
+				if create == nil {
+					return false
+				}
+				note, err := ap.ToObject(create.Object)
+				return err == nil && note.Type == ap.NoteType && note.Source.Content.First().Value.String() == "Fixed in the next release"
+			}, 10*time.Second, 100*time.Millisecond)
+		})
+
+		t.Run("NotSigned", func(t *testing.T) {
+			req := NewRequestWithJSON(t, "POST", "/api/v1/activitypub/repository-id/1/inbox", json.RawMessage(offerTicket(remoteActor+"/outbox/3", "Unsigned")))
+			MakeRequest(t, req, http.StatusBadRequest)
+			unittest.AssertNotExistsBean(t, &issues_model.Issue{RepoID: 1, Title: "Unsigned"})
+		})
+
+		t.Run("Blocked", func(t *testing.T) {
+			federatedUser := unittest.AssertExistsAndLoadBean(t, &user_model.FederatedUser{ExternalID: "15"})
+			require.NoError(t, user_service.BlockUser(db.DefaultContext, 2, federatedUser.UserID))
+
+			offerID := remoteActor + "/outbox/4"
+			postToInbox(t, offerTicket(offerID, "Blocked"), http.StatusNoContent)
+			unittest.AssertNotExistsBean(t, &issues_model.Issue{RepoID: 1, Title: "Blocked"})
+			assert.Eventually(t, func() bool {
+				reject := receivedOfType(ap.RejectType)
+				return reject != nil && reject.Object.GetID().String() == offerID
+			}, 10*time.Second, 100*time.Millisecond)
+		})
+	})
+}
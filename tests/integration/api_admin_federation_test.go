@@ -0,0 +1,177 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/forgefed"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/activitypub"
+	"code.gitea.io/gitea/modules/setting"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/test"
+	"code.gitea.io/gitea/routers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIAdminFederationHostRules(t *testing.T) {
+	setting.Federation.Enabled = true
+	testWebRoutes = routers.NormalRoutes()
+	defer func() {
+		setting.Federation.Enabled = false
+		testWebRoutes = routers.NormalRoutes()
+	}()
+
+	srv := httptest.NewServer(testWebRoutes)
+	defer srv.Close()
+
+	federatedRoutes := http.NewServeMux()
+	federatedRoutes.HandleFunc("/.well-known/nodeinfo",
+		func(res http.ResponseWriter, req *http.Request) {
+			fmt.Fprintf(res, `{"links":[{"href":"http://%s/api/v1/nodeinfo","rel":"http://nodeinfo.diaspora.software/ns/schema/2.1"}]}`, req.Host)
+		})
+	federatedRoutes.HandleFunc("/api/v1/nodeinfo",
+		func(res http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(res, `{"version":"2.1","software":{"name":"forgejo","version":"1.20.0+dev-3183-g976d79044",`+
+				`"repository":"https://codeberg.org/forgejo/forgejo.git","homepage":"https://forgejo.org/"},`+
+				`"protocols":["activitypub"],"services":{"inbound":[],"outbound":["rss2.0"]},`+
+				`"openRegistrations":true,"usage":{"users":{"total":14,"activeHalfyear":2}},"metadata":{}}`)
+		})
+	federatedRoutes.HandleFunc("/api/v1/activitypub/user-id/15",
+		func(res http.ResponseWriter, req *http.Request) {
+			actor := fmt.Sprintf("http://%s/api/v1/activitypub/user-id/15", req.Host)
+			fmt.Fprintf(res, `{"@context":["https://www.w3.org/ns/activitystreams"],"id":%q,"type":"Person",`+
+				`"preferredUsername":"stargoose15","inbox":"%s/inbox","outbox":"%s/outbox"}`, actor, actor, actor)
+		})
+	federatedRoutes.HandleFunc("/",
+		func(res http.ResponseWriter, req *http.Request) {
+			t.Errorf("Unhandled request: %q", req.URL.EscapedPath())
+		})
+	federatedSrv := httptest.NewServer(federatedRoutes)
+	defer federatedSrv.Close()
+
+	onGiteaRun(t, func(t *testing.T, _ *url.URL) {
+		appURL := setting.AppURL
+		setting.AppURL = srv.URL + "/"
+		defer func() {
+			setting.AppURL = appURL
+		}()
+
+		user1 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1})
+		token := getUserToken(t, user1.Name, auth_model.AccessTokenScopeWriteAdmin)
+		remoteActor := federatedSrv.URL + "/api/v1/activitypub/user-id/15"
+
+		cf, err := activitypub.GetClientFactory(db.DefaultContext)
+		require.NoError(t, err)
+		c, err := cf.WithKeys(db.DefaultContext, user1, remoteActor+"#main-key")
+		require.NoError(t, err)
+		startTime := time.Now().UTC()
+		like := func(t *testing.T, repoID int64) *http.Response {
+			t.Helper()
+			startTime = startTime.Add(time.Second)
+			resp, err := c.Post([]byte(fmt.Sprintf(`{"type":"Like","startTime":%q,"actor":%q,"object":"%s/api/v1/activitypub/repository-id/%d"}`,
+				startTime.Format(time.RFC3339), remoteActor, srv.URL, repoID)), fmt.Sprintf("%s/api/v1/activitypub/repository-id/%d/inbox", srv.URL, repoID))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			return resp
+		}
+		setRule := func(t *testing.T, pattern, action string, expectedStatus int) {
+			t.Helper()
+			req := NewRequestWithJSON(t, "PUT", "/api/v1/admin/federation/rules/"+pattern, &api.SetFederationHostRuleOption{
+				Action: action,
+				Reason: "spam",
+			}).AddTokenAuth(token)
+			MakeRequest(t, req, expectedStatus)
+		}
+
+		assert.Equal(t, http.StatusNoContent, like(t, 2).StatusCode)
+		host := unittest.AssertExistsAndLoadBean(t, &forgefed.FederationHost{HostFqdn: "127.0.0.1"})
+		federatedUser := unittest.AssertExistsAndLoadBean(t, &user_model.FederatedUser{ExternalID: "15", FederationHostID: host.ID})
+		unittest.AssertExistsAndLoadBean(t, &repo_model.Star{UID: federatedUser.UserID, RepoID: 2})
+
+		t.Run("ListHosts", func(t *testing.T) {
+			req := NewRequest(t, "GET", "/api/v1/admin/federation/hosts").AddTokenAuth(token)
+			resp := MakeRequest(t, req, http.StatusOK)
+			var hosts []*api.FederationHost
+			DecodeJSON(t, resp, &hosts)
+			require.Len(t, hosts, 1)
+			assert.Equal(t, "127.0.0.1", hosts[0].Host)
+			assert.Equal(t, "forgejo", hosts[0].Software)
+			assert.Equal(t, "none", hosts[0].Action)
+		})
+
+		t.Run("Invalid", func(t *testing.T) {
+			setRule(t, "-example.com", "block", http.StatusUnprocessableEntity)
+			setRule(t, "example.com", "ignore", http.StatusUnprocessableEntity)
+		})
+
+		t.Run("Silence", func(t *testing.T) {
+			setRule(t, "127.0.0.1", "silence", http.StatusOK)
+			assert.Equal(t, http.StatusNoContent, like(t, 3).StatusCode)
+			unittest.AssertNotExistsBean(t, &repo_model.Star{UID: federatedUser.UserID, RepoID: 3})
+		})
+
+		t.Run("RateLimit", func(t *testing.T) {
+			defer test.MockVariableValue(&setting.Federation.InboxRateLimit, 1)()
+			// requests with a forged signature don't count towards the limit of the host of their key
+			for range 2 {
+				resp, err := c.Post([]byte(fmt.Sprintf(`{"type":"Follow","actor":%q,"object":"%s/api/v1/activitypub/user-id/2"}`, remoteActor, srv.URL)),
+					srv.URL+"/api/v1/activitypub/user-id/2/inbox")
+				require.NoError(t, err)
+				resp.Body.Close()
+				assert.NotEqual(t, http.StatusTooManyRequests, resp.StatusCode)
+			}
+			assert.Equal(t, http.StatusNoContent, like(t, 3).StatusCode)
+			resp := like(t, 3)
+			assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+			assert.Equal(t, "60", resp.Header.Get("Retry-After"))
+		})
+
+		t.Run("Block", func(t *testing.T) {
+			setRule(t, "127.0.0.1", "block", http.StatusOK)
+			unittest.AssertNotExistsBean(t, &user_model.FederatedUser{ExternalID: "15", FederationHostID: host.ID})
+			unittest.AssertNotExistsBean(t, &user_model.User{ID: federatedUser.UserID})
+			unittest.AssertNotExistsBean(t, &repo_model.Star{UID: federatedUser.UserID, RepoID: 2})
+
+			assert.Equal(t, http.StatusForbidden, like(t, 2).StatusCode)
+			// signed requests are rejected before the key of the actor is fetched
+			resp, err := c.Post([]byte(fmt.Sprintf(`{"type":"Follow","actor":%q,"object":"%s/api/v1/activitypub/user-id/2"}`, remoteActor, srv.URL)),
+				srv.URL+"/api/v1/activitypub/user-id/2/inbox")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+			req := NewRequest(t, "GET", "/api/v1/admin/federation/rules").AddTokenAuth(token)
+			var rules []*api.FederationHostRule
+			DecodeJSON(t, MakeRequest(t, req, http.StatusOK), &rules)
+			require.Len(t, rules, 1)
+			assert.Equal(t, "127.0.0.1", rules[0].Pattern)
+			assert.Equal(t, "block", rules[0].Action)
+			assert.Equal(t, "spam", rules[0].Reason)
+		})
+
+		t.Run("AllowlistMode", func(t *testing.T) {
+			defer test.MockVariableValue(&setting.Federation.AllowlistMode, true)()
+			req := NewRequest(t, "DELETE", "/api/v1/admin/federation/rules/127.0.0.1").AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusNoContent)
+			MakeRequest(t, req, http.StatusNotFound)
+			assert.Equal(t, http.StatusForbidden, like(t, 2).StatusCode)
+
+			setRule(t, "127.0.0.1", "allow", http.StatusOK)
+			assert.Equal(t, http.StatusNoContent, like(t, 2).StatusCode)
+		})
+	})
+}
@@ -0,0 +1,240 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	ansible_module "code.gitea.io/gitea/modules/packages/ansible"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageAnsible(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+
+	// ansible-galaxy sends the API token with the "Token" authentication scheme
+	token := "Token " + getUserToken(t, user.Name, auth_model.AccessTokenScopeWritePackage)
+
+	collectionNamespace := "my_namespace"
+	collectionName := "my_collection"
+	collectionVersion := "1.0.0"
+	collectionDescription := "Test collection"
+	collectionReadme := "# My collection"
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for name, content := range map[string]string{
+		"MANIFEST.json": `{"collection_info":{"namespace":"` + collectionNamespace + `","name":"` + collectionName + `","version":"` + collectionVersion + `","authors":["Forgejo"],"readme":"README.md","description":"` + collectionDescription + `","license":["MIT"],"dependencies":{"community.general":">=1.0.0"}},"format":1}`,
+		"README.md":     collectionReadme,
+	} {
+		tw.WriteHeader(&tar.Header{
+			Name: name,
+			Mode: 0o600,
+			Size: int64(len(content)),
+		})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	zw.Close()
+	content := buf.Bytes()
+
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	filename := fmt.Sprintf("%s-%s-%s.tar.gz", collectionNamespace, collectionName, collectionVersion)
+
+	root := fmt.Sprintf("/api/packages/%s/ansible", user.Name)
+	apiURL := root + "/api/v3"
+	collectionURL := fmt.Sprintf("%s/collections/%s/%s", apiURL, collectionNamespace, collectionName)
+
+	newUploadRequest := func(t *testing.T, data []byte, checksum string) *RequestWrapper {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", filename)
+		part.Write(data)
+		if checksum != "" {
+			writer.WriteField("sha256", checksum)
+		}
+		_ = writer.Close()
+
+		return NewRequestWithBody(t, "POST", apiURL+"/artifacts/collections", body).
+			SetHeader("Content-Type", writer.FormDataContentType())
+	}
+
+	t.Run("AvailableVersions", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", root+"/api")
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var result struct {
+			AvailableVersions map[string]string `json:"available_versions"`
+		}
+		DecodeJSON(t, resp, &result)
+		assert.Equal(t, "v3/", result.AvailableVersions["v3"])
+	})
+
+	t.Run("Upload", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		MakeRequest(t, newUploadRequest(t, content, checksum), http.StatusUnauthorized)
+
+		MakeRequest(t, newUploadRequest(t, []byte("invalid"), "").SetHeader("Authorization", token), http.StatusBadRequest)
+
+		MakeRequest(t, newUploadRequest(t, content, strings.Repeat("0", 64)).SetHeader("Authorization", token), http.StatusBadRequest)
+
+		resp := MakeRequest(t, newUploadRequest(t, content, checksum).SetHeader("Authorization", token), http.StatusAccepted)
+
+		var result struct {
+			Task string `json:"task"`
+		}
+		DecodeJSON(t, resp, &result)
+
+		pv, err := packages.GetVersionByNameAndVersion(db.DefaultContext, user.ID, packages.TypeAnsible, collectionNamespace+"."+collectionName, collectionVersion)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("%s/imports/collections/%d/", apiURL, pv.ID), result.Task)
+
+		pd, err := packages.GetPackageDescriptor(db.DefaultContext, pv)
+		require.NoError(t, err)
+		assert.NotNil(t, pd.SemVer)
+		assert.IsType(t, &ansible_module.Metadata{}, pd.Metadata)
+		metadata := pd.Metadata.(*ansible_module.Metadata)
+		assert.Equal(t, collectionDescription, metadata.Description)
+		assert.Equal(t, collectionReadme, metadata.Readme)
+		assert.Equal(t, []string{"Forgejo"}, metadata.Authors)
+		assert.Len(t, pd.Files, 1)
+		assert.Equal(t, filename, pd.Files[0].File.Name)
+		assert.True(t, pd.Files[0].File.IsLead)
+
+		req := NewRequest(t, "GET", result.Task)
+		resp = MakeRequest(t, req, http.StatusOK)
+
+		var task struct {
+			State string `json:"state"`
+		}
+		DecodeJSON(t, resp, &task)
+		assert.Equal(t, "completed", task.State)
+
+		MakeRequest(t, newUploadRequest(t, content, checksum).SetHeader("Authorization", token), http.StatusConflict)
+	})
+
+	t.Run("CollectionInfo", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", collectionURL)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var result struct {
+			Namespace      string `json:"namespace"`
+			Name           string `json:"name"`
+			HighestVersion struct {
+				Version string `json:"version"`
+			} `json:"highest_version"`
+		}
+		DecodeJSON(t, resp, &result)
+		assert.Equal(t, collectionNamespace, result.Namespace)
+		assert.Equal(t, collectionName, result.Name)
+		assert.Equal(t, collectionVersion, result.HighestVersion.Version)
+
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/collections/%s/other", apiURL, collectionNamespace))
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
+	t.Run("EnumerateVersions", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", collectionURL+"/versions")
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var result struct {
+			Meta struct {
+				Count int `json:"count"`
+			} `json:"meta"`
+			Data []struct {
+				Version string `json:"version"`
+			} `json:"data"`
+		}
+		DecodeJSON(t, resp, &result)
+		assert.Equal(t, 1, result.Meta.Count)
+		assert.Len(t, result.Data, 1)
+		assert.Equal(t, collectionVersion, result.Data[0].Version)
+	})
+
+	t.Run("VersionInfo", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", collectionURL+"/versions/"+collectionVersion)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var result struct {
+			Version     string `json:"version"`
+			DownloadURL string `json:"download_url"`
+			Artifact    struct {
+				Filename string `json:"filename"`
+				SHA256   string `json:"sha256"`
+				Size     int64  `json:"size"`
+			} `json:"artifact"`
+			Metadata struct {
+				Dependencies map[string]string `json:"dependencies"`
+			} `json:"metadata"`
+		}
+		DecodeJSON(t, resp, &result)
+		assert.Equal(t, collectionVersion, result.Version)
+		assert.Equal(t, fmt.Sprintf("%sapi/packages/%s/ansible/download/%s", setting.AppURL, user.Name, filename), result.DownloadURL)
+		assert.Equal(t, filename, result.Artifact.Filename)
+		assert.Equal(t, checksum, result.Artifact.SHA256)
+		assert.EqualValues(t, len(content), result.Artifact.Size)
+		assert.Equal(t, map[string]string{"community.general": ">=1.0.0"}, result.Metadata.Dependencies)
+
+		req = NewRequest(t, "GET", collectionURL+"/versions/9.9.9")
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
+	t.Run("Download", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", root+"/download/"+filename)
+		resp := MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, content, resp.Body.Bytes())
+
+		req = NewRequest(t, "GET", root+"/download/"+collectionNamespace+"-"+collectionName+"-9.9.9.tar.gz")
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		versionURL := collectionURL + "/versions/" + collectionVersion
+
+		req := NewRequest(t, "DELETE", versionURL)
+		MakeRequest(t, req, http.StatusUnauthorized)
+
+		req = NewRequest(t, "DELETE", versionURL).
+			SetHeader("Authorization", token)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		req = NewRequest(t, "GET", collectionURL)
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/json"
+	attestation_module "code.gitea.io/gitea/modules/packages/attestation"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageAttestation(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+
+	packageName := "attested"
+	packageVersion := "1.0.0"
+	filename := "attested.bin"
+	content := []byte{1, 2, 3, 4}
+
+	token := getUserToken(t, user.Name, auth_model.AccessTokenScopeWritePackage)
+
+	req := NewRequestWithBody(t, "PUT", fmt.Sprintf("/api/packages/%s/generic/%s/%s/%s", user.Name, packageName, packageVersion, filename), bytes.NewReader(content)).
+		AddTokenAuth(token)
+	MakeRequest(t, req, http.StatusCreated)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	createEnvelope := func(t *testing.T, digest string) []byte {
+		e, err := attestation_module.Sign(&attestation_module.Statement{
+			Type: attestation_module.StatementTypeV1,
+			Subject: []*attestation_module.Subject{
+				{Name: filename, Digest: map[string]string{"sha256": digest}},
+			},
+			PredicateType: attestation_module.PredicateTypeSLSAProvenanceV1,
+			Predicate:     map[string]any{},
+		}, key)
+		require.NoError(t, err)
+
+		data, err := json.Marshal(e)
+		require.NoError(t, err)
+		return data
+	}
+
+	hash := sha256.Sum256(content)
+	digest := hex.EncodeToString(hash[:])
+
+	url := fmt.Sprintf("/api/v1/packages/%s/generic/%s/%s/attestations", user.Name, packageName, packageVersion)
+
+	t.Run("Upload", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithBody(t, "POST", url, bytes.NewReader(createEnvelope(t, digest)))
+		MakeRequest(t, req, http.StatusUnauthorized)
+
+		req = NewRequestWithBody(t, "POST", url, strings.NewReader("invalid")).
+			AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusBadRequest)
+
+		req = NewRequestWithBody(t, "POST", url, bytes.NewReader(createEnvelope(t, strings.Repeat("0", 64)))).
+			AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusBadRequest)
+
+		req = NewRequestWithBody(t, "POST", url, bytes.NewReader(createEnvelope(t, digest))).
+			AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusCreated)
+
+		var attestation *api.PackageAttestation
+		DecodeJSON(t, resp, &attestation)
+		assert.Equal(t, attestation_module.PredicateTypeSLSAProvenanceV1, attestation.PredicateType)
+		assert.False(t, attestation.Verified)
+		assert.Empty(t, attestation.KeyID)
+	})
+
+	t.Run("UploadTrusted", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		require.NoError(t, err)
+		fingerprint, err := attestation_module.KeyFingerprint(&key.PublicKey)
+		require.NoError(t, err)
+
+		_, err = packages_model.InsertTrustedKey(db.DefaultContext, &packages_model.PackageTrustedKey{
+			OwnerID:     user.ID,
+			Name:        "ci",
+			Content:     string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+			Fingerprint: fingerprint,
+		})
+		require.NoError(t, err)
+
+		req := NewRequestWithBody(t, "POST", url, bytes.NewReader(createEnvelope(t, digest))).
+			AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusCreated)
+
+		var attestation *api.PackageAttestation
+		DecodeJSON(t, resp, &attestation)
+		assert.True(t, attestation.Verified)
+		assert.Equal(t, fingerprint, attestation.KeyID)
+
+		req = NewRequest(t, "GET", fmt.Sprintf("/%s/-/packages/generic/%s/%s", user.Name, packageName, packageVersion))
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Contains(t, resp.Body.String(), "octicon-verified")
+	})
+
+	t.Run("List", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", url).
+			AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var attestations []*api.PackageAttestation
+		DecodeJSON(t, resp, &attestations)
+		require.Len(t, attestations, 2)
+		assert.False(t, attestations[0].Verified)
+		assert.True(t, attestations[1].Verified)
+		assert.Equal(t, user.Name, attestations[1].Creator.UserName)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		pv, err := packages_model.GetVersionByNameAndVersion(db.DefaultContext, user.ID, packages_model.TypeGeneric, packageName, packageVersion)
+		require.NoError(t, err)
+
+		req := NewRequest(t, "DELETE", fmt.Sprintf("/api/packages/%s/generic/%s/%s", user.Name, packageName, packageVersion)).
+			AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		pas, err := packages_model.GetAttestationsByVersionID(db.DefaultContext, pv.ID)
+		require.NoError(t, err)
+		assert.Empty(t, pas)
+	})
+}
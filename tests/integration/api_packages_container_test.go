@@ -77,6 +77,13 @@ func TestPackageContainer(t *testing.T) {
 	indexManifestDigest := "sha256:bab112d6efb9e7f221995caaaa880352feb5bd8b1faf52fae8d12c113aa123ec"
 	indexManifestContent := `{"schemaVersion":2,"mediaType":"` + oci.MediaTypeImageIndex + `","manifests":[{"mediaType":"application/vnd.docker.distribution.manifest.v2+json","digest":"` + manifestDigest + `","platform":{"os":"linux","architecture":"arm","variant":"v7"}},{"mediaType":"` + oci.MediaTypeImageManifest + `","digest":"` + untaggedManifestDigest + `","platform":{"os":"linux","architecture":"arm64","variant":"v8"}}]}`
 
+	emptyConfigDigest := "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+	emptyConfigContent := `{}`
+
+	referrerArtifactType := "application/vnd.example.sbom"
+	referrerManifestContent := `{"schemaVersion":2,"mediaType":"` + oci.MediaTypeImageManifest + `","artifactType":"` + referrerArtifactType + `","config":{"mediaType":"` + oci.MediaTypeEmptyJSON + `","digest":"` + emptyConfigDigest + `","size":2},"layers":[{"mediaType":"application/vnd.docker.image.rootfs.diff.tar.gzip","digest":"` + blobDigest + `","size":32}],"subject":{"mediaType":"` + oci.MediaTypeImageManifest + `","digest":"` + untaggedManifestDigest + `","size":` + fmt.Sprint(len(untaggedManifestContent)) + `},"annotations":{"org.example.key":"value"}}`
+	referrerManifestDigest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(referrerManifestContent)))
+
 	anonymousToken := ""
 	readUserToken := ""
 	userToken := ""
@@ -639,6 +646,61 @@ func TestPackageContainer(t *testing.T) {
 				assert.Len(t, apiPackages, 4) // "latest", "main", "multi", "sha256:..."
 			})
 
+			t.Run("Referrers", func(t *testing.T) {
+				defer tests.PrintCurrentTest(t)()
+
+				req := NewRequestWithBody(t, "POST", fmt.Sprintf("%s/blobs/uploads?digest=%s", url, emptyConfigDigest), strings.NewReader(emptyConfigContent)).
+					AddTokenAuth(userToken)
+				MakeRequest(t, req, http.StatusCreated)
+
+				req = NewRequestWithBody(t, "PUT", fmt.Sprintf("%s/manifests/%s", url, referrerManifestDigest), strings.NewReader(referrerManifestContent)).
+					AddTokenAuth(userToken).
+					SetHeader("Content-Type", oci.MediaTypeImageManifest)
+				resp := MakeRequest(t, req, http.StatusCreated)
+
+				assert.Equal(t, referrerManifestDigest, resp.Header().Get("Docker-Content-Digest"))
+				assert.Equal(t, untaggedManifestDigest, resp.Header().Get("OCI-Subject"))
+
+				req = NewRequest(t, "GET", fmt.Sprintf("%s/referrers/%s", url, untaggedManifestDigest)).
+					AddTokenAuth(userToken)
+				resp = MakeRequest(t, req, http.StatusOK)
+
+				assert.Equal(t, oci.MediaTypeImageIndex, resp.Header().Get("Content-Type"))
+				assert.Empty(t, resp.Header().Get("OCI-Filters-Applied"))
+
+				var index oci.Index
+				DecodeJSON(t, resp, &index)
+				assert.Equal(t, 2, index.SchemaVersion)
+				require.Len(t, index.Manifests, 1)
+				assert.Equal(t, oci.MediaTypeImageManifest, index.Manifests[0].MediaType)
+				assert.EqualValues(t, referrerManifestDigest, index.Manifests[0].Digest)
+				assert.EqualValues(t, len(referrerManifestContent), index.Manifests[0].Size)
+				assert.Equal(t, referrerArtifactType, index.Manifests[0].ArtifactType)
+				assert.Equal(t, map[string]string{"org.example.key": "value"}, index.Manifests[0].Annotations)
+
+				req = NewRequest(t, "GET", fmt.Sprintf("%s/referrers/%s?artifactType=%s", url, untaggedManifestDigest, "application/vnd.example.signature")).
+					AddTokenAuth(userToken)
+				resp = MakeRequest(t, req, http.StatusOK)
+
+				assert.Equal(t, "artifactType", resp.Header().Get("OCI-Filters-Applied"))
+
+				index = oci.Index{}
+				DecodeJSON(t, resp, &index)
+				assert.Empty(t, index.Manifests)
+
+				req = NewRequest(t, "GET", fmt.Sprintf("%s/referrers/%s", url, unknownDigest)).
+					AddTokenAuth(userToken)
+				resp = MakeRequest(t, req, http.StatusOK)
+
+				index = oci.Index{}
+				DecodeJSON(t, resp, &index)
+				assert.Empty(t, index.Manifests)
+
+				req = NewRequest(t, "GET", fmt.Sprintf("%s/referrers/invalid", url)).
+					AddTokenAuth(userToken)
+				MakeRequest(t, req, http.StatusBadRequest)
+			})
+
 			t.Run("Delete", func(t *testing.T) {
 				t.Run("Blob", func(t *testing.T) {
 					defer tests.PrintCurrentTest(t)()
@@ -662,6 +724,19 @@ func TestPackageContainer(t *testing.T) {
 					req = NewRequest(t, "HEAD", fmt.Sprintf("%s/manifests/%s", url, untaggedManifestDigest)).
 						AddTokenAuth(userToken)
 					MakeRequest(t, req, http.StatusNotFound)
+
+					// The referrers of the manifest are removed with it
+					req = NewRequest(t, "HEAD", fmt.Sprintf("%s/manifests/%s", url, referrerManifestDigest)).
+						AddTokenAuth(userToken)
+					MakeRequest(t, req, http.StatusNotFound)
+
+					req = NewRequest(t, "GET", fmt.Sprintf("%s/referrers/%s", url, untaggedManifestDigest)).
+						AddTokenAuth(userToken)
+					resp := MakeRequest(t, req, http.StatusOK)
+
+					var index oci.Index
+					DecodeJSON(t, resp, &index)
+					assert.Empty(t, index.Manifests)
 				})
 
 				t.Run("ManifestByTag", func(t *testing.T) {
@@ -0,0 +1,225 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	hex_module "code.gitea.io/gitea/modules/packages/hex"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestPackageHex(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+
+	// the Hex client sends the API key without an authentication scheme
+	token := getUserToken(t, user.Name, auth_model.AccessTokenScopeWritePackage)
+
+	packageName := "my_lib"
+	packageVersion := "1.2.3"
+
+	createTarball := func(name, version string) []byte {
+		metadata := fmt.Sprintf(`{<<"name">>,<<"%s">>}.
+{<<"version">>,<<"%s">>}.
+{<<"description">>,<<"A test library">>}.
+{<<"licenses">>,[<<"MIT">>]}.
+{<<"requirements">>,[[{<<"name">>,<<"jason">>},{<<"app">>,<<"jason">>},{<<"optional">>,false},{<<"requirement">>,<<"~> 1.4">>},{<<"repository">>,<<"hexpm">>}]]}.
+`, name, version)
+		contents := "contents"
+
+		sum := sha256.Sum256([]byte("3" + metadata + contents))
+
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, f := range []struct{ Name, Content string }{
+			{"VERSION", "3"},
+			{"CHECKSUM", strings.ToUpper(hex.EncodeToString(sum[:]))},
+			{"metadata.config", metadata},
+			{"contents.tar.gz", contents},
+		} {
+			tw.WriteHeader(&tar.Header{
+				Name: f.Name,
+				Mode: 0o600,
+				Size: int64(len(f.Content)),
+			})
+			tw.Write([]byte(f.Content))
+		}
+		tw.Close()
+		return buf.Bytes()
+	}
+
+	content := createTarball(packageName, packageVersion)
+
+	root := fmt.Sprintf("/api/packages/%s/hex", user.Name)
+
+	// readSigned unpacks a registry resource and verifies its signature with the public key of the registry
+	readSigned := func(t *testing.T, data []byte) []byte {
+		t.Helper()
+
+		req := NewRequest(t, "GET", root+"/public_key")
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		block, _ := pem.Decode(resp.Body.Bytes())
+		require.NotNil(t, block)
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		require.NoError(t, err)
+
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		require.NoError(t, err)
+		b, err := io.ReadAll(zr)
+		require.NoError(t, err)
+
+		var payload, signature []byte
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			require.GreaterOrEqual(t, n, 0)
+			require.Equal(t, protowire.BytesType, typ)
+			b = b[n:]
+
+			v, n := protowire.ConsumeBytes(b)
+			require.GreaterOrEqual(t, n, 0)
+			b = b[n:]
+
+			switch num {
+			case 1:
+				payload = v
+			case 2:
+				signature = v
+			}
+		}
+
+		h := sha512.Sum512(payload)
+		require.NoError(t, rsa.VerifyPKCS1v15(pub.(*rsa.PublicKey), crypto.SHA512, h[:], signature))
+
+		return payload
+	}
+
+	t.Run("Upload", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		uploadURL := root + "/api/publish"
+
+		req := NewRequestWithBody(t, "POST", uploadURL, bytes.NewReader(content))
+		MakeRequest(t, req, http.StatusUnauthorized)
+
+		req = NewRequestWithBody(t, "POST", uploadURL, strings.NewReader("invalid")).
+			SetHeader("Authorization", token)
+		MakeRequest(t, req, http.StatusUnprocessableEntity)
+
+		req = NewRequestWithBody(t, "POST", uploadURL, bytes.NewReader(content)).
+			SetHeader("Authorization", token)
+		resp := MakeRequest(t, req, http.StatusCreated)
+		assert.Equal(t, "application/vnd.hex+erlang", resp.Header().Get("Content-Type"))
+
+		pv, err := packages.GetVersionByNameAndVersion(db.DefaultContext, user.ID, packages.TypeHex, packageName, packageVersion)
+		require.NoError(t, err)
+
+		pd, err := packages.GetPackageDescriptor(db.DefaultContext, pv)
+		require.NoError(t, err)
+		assert.NotNil(t, pd.SemVer)
+		assert.IsType(t, &hex_module.Metadata{}, pd.Metadata)
+		metadata := pd.Metadata.(*hex_module.Metadata)
+		assert.Equal(t, "A test library", metadata.Description)
+		assert.Equal(t, []string{"MIT"}, metadata.Licenses)
+		assert.Len(t, metadata.Requirements, 1)
+		assert.Len(t, pd.Files, 1)
+		assert.Equal(t, "my_lib-1.2.3.tar", pd.Files[0].File.Name)
+		assert.True(t, pd.Files[0].File.IsLead)
+		assert.NotEmpty(t, pd.Files[0].Properties.GetByName(hex_module.PropertyInnerChecksum))
+
+		req = NewRequestWithBody(t, "POST", uploadURL, bytes.NewReader(content)).
+			SetHeader("Authorization", token)
+		MakeRequest(t, req, http.StatusConflict)
+	})
+
+	t.Run("Registry", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", root+"/names")
+		resp := MakeRequest(t, req, http.StatusOK)
+		payload := readSigned(t, resp.Body.Bytes())
+		assert.Contains(t, string(payload), packageName)
+		assert.Contains(t, string(payload), user.LowerName)
+
+		req = NewRequest(t, "GET", root+"/versions")
+		resp = MakeRequest(t, req, http.StatusOK)
+		payload = readSigned(t, resp.Body.Bytes())
+		assert.Contains(t, string(payload), packageVersion)
+
+		req = NewRequest(t, "GET", root+"/packages/"+packageName)
+		resp = MakeRequest(t, req, http.StatusOK)
+		payload = readSigned(t, resp.Body.Bytes())
+		assert.Contains(t, string(payload), packageVersion)
+		assert.Contains(t, string(payload), "jason")
+
+		req = NewRequest(t, "GET", root+"/packages/other")
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
+	t.Run("Download", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", root+"/tarballs/my_lib-1.2.3.tar")
+		resp := MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, content, resp.Body.Bytes())
+
+		req = NewRequest(t, "GET", root+"/tarballs/my_lib-0.0.1.tar")
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
+	t.Run("PackageInfo", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", root+"/api/packages/"+packageName)
+		resp := MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, "application/vnd.hex+erlang", resp.Header().Get("Content-Type"))
+
+		req = NewRequest(t, "GET", root+"/api/packages/other")
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		deleteURL := fmt.Sprintf("%s/api/packages/%s/releases/%s", root, packageName, packageVersion)
+
+		req := NewRequest(t, "DELETE", deleteURL)
+		MakeRequest(t, req, http.StatusUnauthorized)
+
+		req = NewRequest(t, "DELETE", deleteURL).
+			SetHeader("Authorization", token)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		req = NewRequest(t, "DELETE", deleteURL).
+			SetHeader("Authorization", token)
+		MakeRequest(t, req, http.StatusNotFound)
+
+		req = NewRequest(t, "GET", root+"/packages/"+packageName)
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageProtection(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	org := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 3})
+
+	t.Run("Generic", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		_, err := packages_model.InsertProtectionRule(db.DefaultContext, &packages_model.PackageProtectionRule{
+			OwnerID:        user.ID,
+			Type:           packages_model.TypeGeneric,
+			VersionPattern: "1.*",
+		})
+		require.NoError(t, err)
+
+		root := fmt.Sprintf("/api/packages/%s/generic/protected", user.Name)
+
+		for _, version := range []string{"1.0.0", "2.0.0"} {
+			req := NewRequestWithBody(t, "PUT", fmt.Sprintf("%s/%s/file.bin", root, version), bytes.NewReader([]byte{1, 2, 3})).
+				AddBasicAuth(user.Name)
+			MakeRequest(t, req, http.StatusCreated)
+		}
+
+		req := NewRequest(t, "DELETE", fmt.Sprintf("%s/1.0.0/file.bin", root)).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusForbidden)
+
+		req = NewRequest(t, "DELETE", fmt.Sprintf("%s/1.0.0", root)).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusForbidden)
+
+		token := getUserToken(t, user.Name, auth_model.AccessTokenScopeWritePackage)
+
+		req = NewRequest(t, "DELETE", fmt.Sprintf("/api/v1/packages/%s/generic/protected/1.0.0", user.Name)).
+			AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusForbidden)
+
+		req = NewRequest(t, "DELETE", fmt.Sprintf("%s/2.0.0", root)).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		pvs, err := packages_model.GetVersionsByPackageName(db.DefaultContext, user.ID, packages_model.TypeGeneric, "protected")
+		require.NoError(t, err)
+		require.Len(t, pvs, 1)
+		assert.Equal(t, "1.0.0", pvs[0].Version)
+	})
+
+	t.Run("AllowedTeam", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		// team 1 is the owners team of the organization and contains user 2
+		_, err := packages_model.InsertProtectionRule(db.DefaultContext, &packages_model.PackageProtectionRule{
+			OwnerID:        org.ID,
+			Type:           packages_model.TypeGeneric,
+			VersionPattern: "*",
+			AllowedTeamIDs: []int64{1},
+		})
+		require.NoError(t, err)
+
+		url := fmt.Sprintf("/api/packages/%s/generic/protected/1.0.0", org.Name)
+
+		req := NewRequestWithBody(t, "PUT", url+"/file.bin", bytes.NewReader([]byte{1, 2, 3})).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusCreated)
+
+		req = NewRequest(t, "DELETE", url).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusNoContent)
+	})
+
+	t.Run("Container", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		_, err := packages_model.InsertProtectionRule(db.DefaultContext, &packages_model.PackageProtectionRule{
+			OwnerID:        user.ID,
+			Type:           packages_model.TypeContainer,
+			VersionPattern: "v*",
+		})
+		require.NoError(t, err)
+
+		blobDigest := "sha256:a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4"
+		blobContent, _ := base64.StdEncoding.DecodeString(`H4sIAAAJbogA/2IYBaNgFIxYAAgAAP//Lq+17wAEAAA=`)
+
+		configDigest := "sha256:4607e093bec406eaadb6f3a340f63400c9d3a7038680744c406903766b938f0d"
+		configContent := `{"architecture":"amd64","config":{"Env":["PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"],"Cmd":["/true"],"ArgsEscaped":true,"Image":"sha256:9bd8b88dc68b80cffe126cc820e4b52c6e558eb3b37680bfee8e5f3ed7b8c257"},"container":"b89fe92a887d55c0961f02bdfbfd8ac3ddf66167db374770d2d9e9fab3311510","container_config":{"Hostname":"b89fe92a887d","Env":["PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"],"Cmd":["/bin/sh","-c","#(nop) ","CMD [\"/true\"]"],"ArgsEscaped":true,"Image":"sha256:9bd8b88dc68b80cffe126cc820e4b52c6e558eb3b37680bfee8e5f3ed7b8c257"},"created":"2022-01-01T00:00:00.000000000Z","docker_version":"20.10.12","history":[{"created":"2022-01-01T00:00:00.000000000Z","created_by":"/bin/sh -c #(nop) COPY file:0e7589b0c800daaf6fa460d2677101e4676dd9491980210cb345480e513f3602 in /true "},{"created":"2022-01-01T00:00:00.000000001Z","created_by":"/bin/sh -c #(nop)  CMD [\"/true\"]","empty_layer":true}],"os":"linux","rootfs":{"type":"layers","diff_ids":["sha256:0ff3b91bdf21ecdf2f2f3d4372c2098a14dbe06cd678e8f0a85fd4902d00e2e2"]}}`
+
+		manifestContent := `{"schemaVersion":2,"mediaType":"application/vnd.docker.distribution.manifest.v2+json","config":{"mediaType":"application/vnd.docker.container.image.v1+json","digest":"` + configDigest + `","size":1069},"layers":[{"mediaType":"application/vnd.docker.image.rootfs.diff.tar.gzip","digest":"` + blobDigest + `","size":32}]}`
+		otherManifestContent := `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json","config":{"mediaType":"application/vnd.docker.container.image.v1+json","digest":"` + configDigest + `","size":1069},"layers":[{"mediaType":"application/vnd.docker.image.rootfs.diff.tar.gzip","digest":"` + blobDigest + `","size":32}]}`
+
+		req := NewRequest(t, "GET", fmt.Sprintf("%sv2/token", setting.AppURL)).
+			AddBasicAuth(user.Name)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		tokenResponse := &struct {
+			Token string `json:"token"`
+		}{}
+		DecodeJSON(t, resp, &tokenResponse)
+		userToken := fmt.Sprintf("Bearer %s", tokenResponse.Token)
+
+		url := fmt.Sprintf("%sv2/%s/protected", setting.AppURL, user.Name)
+
+		req = NewRequestWithBody(t, "POST", fmt.Sprintf("%s/blobs/uploads?digest=%s", url, blobDigest), bytes.NewReader(blobContent)).
+			AddTokenAuth(userToken)
+		MakeRequest(t, req, http.StatusCreated)
+
+		req = NewRequestWithBody(t, "POST", fmt.Sprintf("%s/blobs/uploads?digest=%s", url, configDigest), strings.NewReader(configContent)).
+			AddTokenAuth(userToken)
+		MakeRequest(t, req, http.StatusCreated)
+
+		pushManifest := func(tag, content, contentType string, expectedStatus int) {
+			req := NewRequestWithBody(t, "PUT", fmt.Sprintf("%s/manifests/%s", url, tag), strings.NewReader(content)).
+				AddTokenAuth(userToken).
+				SetHeader("Content-Type", contentType)
+			MakeRequest(t, req, expectedStatus)
+		}
+
+		// the same image can be pushed again to a protected tag
+		pushManifest("v1", manifestContent, "application/vnd.docker.distribution.manifest.v2+json", http.StatusCreated)
+		pushManifest("v1", manifestContent, "application/vnd.docker.distribution.manifest.v2+json", http.StatusCreated)
+		pushManifest("v1", otherManifestContent, "application/vnd.oci.image.manifest.v1+json", http.StatusForbidden)
+
+		// unprotected tags can still be moved
+		pushManifest("latest", manifestContent, "application/vnd.docker.distribution.manifest.v2+json", http.StatusCreated)
+		pushManifest("latest", otherManifestContent, "application/vnd.oci.image.manifest.v1+json", http.StatusCreated)
+
+		req = NewRequest(t, "DELETE", fmt.Sprintf("%s/manifests/v1", url)).
+			AddTokenAuth(userToken)
+		MakeRequest(t, req, http.StatusForbidden)
+
+		req = NewRequest(t, "DELETE", fmt.Sprintf("%s/manifests/latest", url)).
+			AddTokenAuth(userToken)
+		MakeRequest(t, req, http.StatusAccepted)
+	})
+}
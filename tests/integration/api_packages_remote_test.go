@@ -0,0 +1,151 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/hostmatcher"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/test"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageRemoteNpm(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+	defer test.MockVariableValue(&setting.Packages.RemoteAllowedHostList, hostmatcher.MatchBuiltinLoopback)()
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	token := getUserToken(t, user.Name, auth_model.AccessTokenScopeReadPackage)
+
+	packageName := "test-package"
+	content := []byte("remote package content")
+	hash := sha512.Sum512(content)
+	integrity := "sha512-" + base64.StdEncoding.EncodeToString(hash[:])
+
+	// the token service of the upstream registry only hands out tokens for the credentials of the remote
+	tokenRequests := 0
+	authRoutes := http.NewServeMux()
+	authRoutes.HandleFunc("/token", func(res http.ResponseWriter, req *http.Request) {
+		tokenRequests++
+		if username, password, ok := req.BasicAuth(); !ok || username != "remote-user" || password != "remote-password" {
+			res.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(res, `{"token":"upstream-token"}`)
+	})
+	authSrv := httptest.NewServer(authRoutes)
+	defer authSrv.Close()
+
+	var upstreamURL, realm string
+	upstreamRoutes := http.NewServeMux()
+	upstreamRoutes.Handle("/token", authRoutes)
+	upstreamRoutes.HandleFunc("/", func(res http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer upstream-token" {
+			res.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm=%q,service="registry"`, realm))
+			res.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch req.URL.Path {
+		case "/" + packageName:
+			fmt.Fprintf(res, `{"name":%q,"versions":{`, packageName)
+			for i, v := range []string{"1.0.0", "1.0.1"} {
+				if i > 0 {
+					fmt.Fprint(res, ",")
+				}
+				fmt.Fprintf(res, `%q:{"name":%q,"version":%q,"dist":{"integrity":%q,"tarball":"%s/%s/-/%s-%s.tgz"}}`,
+					v, packageName, v, integrity, upstreamURL, packageName, packageName, v)
+			}
+			fmt.Fprint(res, `}}`)
+		case "/" + packageName + "/-/" + packageName + "-1.0.0.tgz", "/" + packageName + "/-/" + packageName + "-1.0.1.tgz":
+			res.Write(content)
+		default:
+			res.WriteHeader(http.StatusNotFound)
+		}
+	})
+	upstreamSrv := httptest.NewServer(upstreamRoutes)
+	defer upstreamSrv.Close()
+	upstreamURL = upstreamSrv.URL
+	realm = upstreamURL + "/token"
+
+	pr := &packages_model.PackageRemote{
+		Enabled:  true,
+		OwnerID:  user.ID,
+		Type:     packages_model.TypeNpm,
+		URL:      upstreamURL,
+		Username: "remote-user",
+	}
+	require.NoError(t, pr.SetPassword("remote-password"))
+	_, err := packages_model.InsertRemote(db.DefaultContext, pr)
+	require.NoError(t, err)
+
+	url := fmt.Sprintf("/api/packages/%s/npm/%s", user.Name, packageName)
+	tarballURL := func(version string) string {
+		return fmt.Sprintf("%s/-/%s/%s-%s.tgz", url, version, packageName, version)
+	}
+
+	t.Run("ForeignRealm", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		// the credentials are never sent to an insecure realm on another host
+		defer test.MockVariableValue(&realm, authSrv.URL+"/token")()
+
+		req := NewRequest(t, "GET", tarballURL("1.0.0")).AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusInternalServerError)
+		assert.Zero(t, tokenRequests)
+		unittest.AssertNotExistsBean(t, &packages_model.PackageVersion{Version: "1.0.0"})
+	})
+
+	t.Run("SizeLimit", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+		defer test.MockVariableValue(&setting.Packages.LimitSizeNpm, int64(len(content)-1))()
+
+		req := NewRequest(t, "GET", tarballURL("1.0.1")).AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusForbidden)
+		unittest.AssertNotExistsBean(t, &packages_model.PackageVersion{Version: "1.0.1"})
+	})
+
+	t.Run("Fetch", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		tokenRequests = 0
+
+		req := NewRequest(t, "GET", tarballURL("1.0.0")).AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, content, resp.Body.Bytes())
+		assert.Positive(t, tokenRequests)
+
+		pvs, err := packages_model.GetVersionsByPackageType(db.DefaultContext, user.ID, packages_model.TypeNpm)
+		require.NoError(t, err)
+		require.Len(t, pvs, 1)
+		assert.Equal(t, "1.0.0", pvs[0].Version)
+
+		props, err := packages_model.GetPropertiesByName(db.DefaultContext, packages_model.PropertyTypeVersion, pvs[0].ID, packages_model.PropertyRemoteURL)
+		require.NoError(t, err)
+		require.Len(t, props, 1)
+		assert.True(t, strings.HasPrefix(props[0].Value, upstreamURL+"/"))
+
+		// cached versions are served without asking the upstream registry
+		tokenRequests = 0
+		upstreamSrv.Close()
+		req = NewRequest(t, "GET", tarballURL("1.0.0")).AddTokenAuth(token)
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, content, resp.Body.Bytes())
+		assert.Zero(t, tokenRequests)
+	})
+}
@@ -0,0 +1,199 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	npm_module "code.gitea.io/gitea/modules/packages/npm"
+	"code.gitea.io/gitea/modules/structs"
+	packages_service "code.gitea.io/gitea/services/packages"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageRepositoryPermissions(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	owner := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 5})
+	// user 4 is a collaborator with write access of the repository
+	collaborator := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 4})
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 4, OwnerID: owner.ID})
+
+	root := fmt.Sprintf("/api/packages/%s/generic", owner.Name)
+	content := []byte{1, 2, 3}
+
+	req := NewRequestWithBody(t, "PUT", root+"/linked/1.0.0/file.bin", bytes.NewReader(content)).
+		AddBasicAuth(owner.Name)
+	MakeRequest(t, req, http.StatusCreated)
+
+	p, err := packages_model.GetPackageByName(db.DefaultContext, owner.ID, packages_model.TypeGeneric, "linked")
+	require.NoError(t, err)
+	require.NoError(t, packages_model.SetRepositoryLink(db.DefaultContext, p.ID, repo.ID))
+
+	t.Run("NotInherited", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithBody(t, "PUT", root+"/linked/1.1.0/file.bin", bytes.NewReader(content)).
+			AddBasicAuth(collaborator.Name)
+		MakeRequest(t, req, http.StatusUnauthorized)
+	})
+
+	require.NoError(t, packages_model.SetRepositoryPermissionInheritance(db.DefaultContext, p.ID, true))
+
+	t.Run("Inherited", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithBody(t, "PUT", root+"/linked/1.1.0/file.bin", bytes.NewReader(content)).
+			AddBasicAuth(collaborator.Name)
+		MakeRequest(t, req, http.StatusCreated)
+
+		req = NewRequest(t, "GET", root+"/linked/1.1.0/file.bin").
+			AddBasicAuth(collaborator.Name)
+		MakeRequest(t, req, http.StatusOK)
+
+		req = NewRequest(t, "DELETE", root+"/linked/1.1.0").
+			AddBasicAuth(collaborator.Name)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		token := getUserToken(t, collaborator.Name, auth_model.AccessTokenScopeWritePackage)
+
+		req = NewRequest(t, "DELETE", fmt.Sprintf("/api/v1/packages/%s/generic/linked/1.0.0", owner.Name)).
+			AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusNoContent)
+	})
+
+	t.Run("OtherPackage", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		// packages which are not linked can't be created or modified
+		req := NewRequestWithBody(t, "PUT", root+"/unlinked/1.0.0/file.bin", bytes.NewReader(content)).
+			AddBasicAuth(collaborator.Name)
+		MakeRequest(t, req, http.StatusForbidden)
+
+		req = NewRequestWithBody(t, "PUT", root+"/unlinked/1.0.0/file.bin", bytes.NewReader(content)).
+			AddBasicAuth(owner.Name)
+		MakeRequest(t, req, http.StatusCreated)
+
+		req = NewRequest(t, "DELETE", root+"/unlinked/1.0.0").
+			AddBasicAuth(collaborator.Name)
+		MakeRequest(t, req, http.StatusForbidden)
+	})
+
+	t.Run("Settings", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithBody(t, "PUT", root+"/linked/2.0.0/file.bin", bytes.NewReader(content)).
+			AddBasicAuth(owner.Name)
+		MakeRequest(t, req, http.StatusCreated)
+
+		session := loginUser(t, collaborator.Name)
+
+		settingsURL := fmt.Sprintf("/%s/-/packages/generic/linked/2.0.0/settings", owner.Name)
+
+		resp := session.MakeRequest(t, NewRequest(t, "GET", settingsURL), http.StatusOK)
+		htmlDoc := NewHTMLParser(t, resp.Body)
+		htmlDoc.AssertElement(t, "input[name=inherit_repo_permissions]", false)
+
+		// inherited permissions are not sufficient to change the repository link
+		req = NewRequestWithValues(t, "POST", settingsURL, map[string]string{
+			"_csrf":   GetCSRF(t, session, settingsURL),
+			"action":  "link",
+			"repo_id": "0",
+		})
+		session.MakeRequest(t, req, http.StatusNotFound)
+	})
+	t.Run("ListOtherPackages", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		// the packages of a private owner are only readable through the linked repository
+		owner.Visibility = structs.VisibleTypePrivate
+		require.NoError(t, user_model.UpdateUserCols(db.DefaultContext, owner, "visibility"))
+		defer func() {
+			owner.Visibility = structs.VisibleTypePublic
+			require.NoError(t, user_model.UpdateUserCols(db.DefaultContext, owner, "visibility"))
+		}()
+
+		createNpmPackage := func(t *testing.T, name string) *packages_model.Package {
+			t.Helper()
+			buf, err := packages_module.CreateHashedBufferFromReader(bytes.NewReader(content))
+			require.NoError(t, err)
+			defer buf.Close()
+			pv, _, err := packages_service.CreatePackageAndAddFile(
+				db.DefaultContext,
+				&packages_service.PackageCreationInfo{
+					PackageInfo: packages_service.PackageInfo{
+						Owner:       owner,
+						PackageType: packages_model.TypeNpm,
+						Name:        name,
+						Version:     "1.0.0",
+					},
+					SemverCompatible: true,
+					Creator:          owner,
+					Metadata:         &npm_module.Metadata{},
+				},
+				&packages_service.PackageFileCreationInfo{
+					PackageFileInfo: packages_service.PackageFileInfo{Filename: name + "-1.0.0.tgz"},
+					Creator:         owner,
+					Data:            buf,
+					IsLead:          true,
+				},
+			)
+			require.NoError(t, err)
+			p, err := packages_model.GetPackageByID(db.DefaultContext, pv.PackageID)
+			require.NoError(t, err)
+			return p
+		}
+
+		linked := createNpmPackage(t, "linked-npm")
+		require.NoError(t, packages_model.SetRepositoryLink(db.DefaultContext, linked.ID, repo.ID))
+		require.NoError(t, packages_model.SetRepositoryPermissionInheritance(db.DefaultContext, linked.ID, true))
+		createNpmPackage(t, "other-npm")
+
+		npmRoot := fmt.Sprintf("/api/packages/%s/npm", owner.Name)
+
+		req := NewRequest(t, "GET", npmRoot+"/linked-npm").
+			AddBasicAuth(collaborator.Name)
+		MakeRequest(t, req, http.StatusOK)
+
+		req = NewRequest(t, "GET", npmRoot+"/other-npm").
+			AddBasicAuth(collaborator.Name)
+		MakeRequest(t, req, http.StatusNotFound)
+
+		req = NewRequest(t, "GET", npmRoot+"/-/package/other-npm/dist-tags").
+			AddBasicAuth(collaborator.Name)
+		resp := MakeRequest(t, req, http.StatusOK)
+		var tags map[string]string
+		DecodeJSON(t, resp, &tags)
+		assert.Empty(t, tags)
+
+		req = NewRequest(t, "GET", npmRoot+"/-/v1/search?text=npm").
+			AddBasicAuth(collaborator.Name)
+		resp = MakeRequest(t, req, http.StatusOK)
+		var result npm_module.PackageSearch
+		DecodeJSON(t, resp, &result)
+		assert.EqualValues(t, 1, result.Total)
+		if assert.Len(t, result.Objects, 1) {
+			assert.Equal(t, "linked-npm", result.Objects[0].Package.Name)
+		}
+
+		req = NewRequest(t, "GET", npmRoot+"/-/v1/search?text=npm").
+			AddBasicAuth(owner.Name)
+		resp = MakeRequest(t, req, http.StatusOK)
+		DecodeJSON(t, resp, &result)
+		assert.EqualValues(t, 2, result.Total)
+	})
+}
@@ -149,6 +149,44 @@ func TestPackageAPI(t *testing.T) {
 		assert.Equal(t, "cf83e1357eefb8bdf1542850d66d8007d620e4050b5715dc83f4a921d36ce9ce47d0d13c5d85f2b0ff8318d2877eec2f63b931bd47417a81a538327af927da3e", files[0].HashSHA512)
 	})
 
+	t.Run("PreviewCleanupRule", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		url := fmt.Sprintf("/api/v1/packages/%s/cleanup-rules/preview", user.Name)
+
+		req := NewRequestWithJSON(t, "POST", url, &api.PreviewPackageCleanupRuleOption{Type: "generic"}).
+			AddTokenAuth(tokenReadPackage)
+		MakeRequest(t, req, http.StatusForbidden)
+
+		req = NewRequestWithJSON(t, "POST", url, &api.PreviewPackageCleanupRuleOption{Type: "invalid"}).
+			AddTokenAuth(tokenDeletePackage)
+		MakeRequest(t, req, http.StatusUnprocessableEntity)
+
+		req = NewRequestWithJSON(t, "POST", url, &api.PreviewPackageCleanupRuleOption{Type: "generic", KeepPattern: "("}).
+			AddTokenAuth(tokenDeletePackage)
+		MakeRequest(t, req, http.StatusUnprocessableEntity)
+
+		req = NewRequestWithJSON(t, "POST", url, &api.PreviewPackageCleanupRuleOption{Type: "generic", KeepCount: 1}).
+			AddTokenAuth(tokenDeletePackage)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var apiPackages []*api.Package
+		DecodeJSON(t, resp, &apiPackages)
+		assert.Empty(t, apiPackages)
+
+		req = NewRequestWithJSON(t, "POST", url, &api.PreviewPackageCleanupRuleOption{Type: "generic", RemovePattern: `1\.0\.\d`}).
+			AddTokenAuth(tokenDeletePackage)
+		resp = MakeRequest(t, req, http.StatusOK)
+
+		DecodeJSON(t, resp, &apiPackages)
+		assert.Len(t, apiPackages, 1)
+		assert.Equal(t, packageName, apiPackages[0].Name)
+		assert.Equal(t, packageVersion, apiPackages[0].Version)
+
+		_, err := packages_model.GetVersionByNameAndVersion(db.DefaultContext, user.ID, packages_model.TypeGeneric, packageName, packageVersion)
+		require.NoError(t, err)
+	})
+
 	t.Run("DeletePackage", func(t *testing.T) {
 		defer tests.PrintCurrentTest(t)()
 
@@ -509,6 +547,7 @@ func TestPackageCleanup(t *testing.T) {
 			Version     string
 			ShouldExist bool
 			Created     int64
+			Downloaded  int64
 		}
 
 		cases := []struct {
@@ -560,6 +599,33 @@ func TestPackageCleanup(t *testing.T) {
 					RemoveDays: 60,
 				},
 			},
+			{
+				Name: "KeepSemverPatches",
+				Versions: []version{
+					{Version: "1.0.0", ShouldExist: false},
+					{Version: "1.0.1", ShouldExist: true},
+					{Version: "1.1.0", ShouldExist: true},
+					{Version: "2.0.0-rc1", ShouldExist: false},
+					{Version: "2.0.0", ShouldExist: true},
+				},
+				Rule: &packages_model.PackageCleanupRule{
+					Enabled:           true,
+					KeepSemverPatches: 1,
+				},
+			},
+			{
+				Name: "RemoveUnusedDays",
+				Versions: []version{
+					{Version: "new", ShouldExist: true},
+					{Version: "downloaded", ShouldExist: true, Created: 1, Downloaded: time.Now().Unix()},
+					{Version: "unused", ShouldExist: false, Created: 1, Downloaded: 1},
+					{Version: "never-downloaded", ShouldExist: false, Created: 1},
+				},
+				Rule: &packages_model.PackageCleanupRule{
+					Enabled:          true,
+					RemoveUnusedDays: 30,
+				},
+			},
 			{
 				Name: "RemovePattern",
 				Versions: []version{
@@ -615,7 +681,7 @@ func TestPackageCleanup(t *testing.T) {
 					if v.Created != 0 {
 						pv, err := packages_model.GetVersionByNameAndVersion(db.DefaultContext, user.ID, packages_model.TypeGeneric, "package", v.Version)
 						require.NoError(t, err)
-						_, err = db.GetEngine(db.DefaultContext).Exec("UPDATE package_version SET created_unix = ? WHERE id = ?", v.Created, pv.ID)
+						_, err = db.GetEngine(db.DefaultContext).Exec("UPDATE package_version SET created_unix = ?, last_download_unix = ? WHERE id = ?", v.Created, v.Downloaded, pv.ID)
 						require.NoError(t, err)
 					}
 				}
@@ -0,0 +1,145 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	npm_module "code.gitea.io/gitea/modules/packages/npm"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageVirtualRegistryNpm(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	user2 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	org3 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 3})
+	user4 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 4})
+	user5 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 5})
+	// user5 is a member of the private organization
+	privateOrg := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 23})
+
+	token2 := getUserToken(t, user2.Name, auth_model.AccessTokenScopeWritePackage)
+	token4 := getUserToken(t, user4.Name, auth_model.AccessTokenScopeWritePackage)
+	token5 := getUserToken(t, user5.Name, auth_model.AccessTokenScopeWritePackage)
+
+	upload := func(t *testing.T, owner, packageName, version, token string, expectedStatus int) {
+		t.Helper()
+		content := []byte(owner + "/" + packageName + "@" + version)
+		hash := sha512.Sum512(content)
+		body := fmt.Sprintf(`{"name":%q,"versions":{%q:{"name":%q,"version":%q,"dist":{"integrity":"sha512-%s"}}},"_attachments":{"%s-%s.tgz":{"data":%q}}}`,
+			packageName, version, packageName, version, base64.StdEncoding.EncodeToString(hash[:]),
+			packageName, version, base64.StdEncoding.EncodeToString(content))
+		req := NewRequestWithBody(t, "PUT", fmt.Sprintf("/api/packages/%s/npm/%s", owner, packageName), strings.NewReader(body)).
+			AddTokenAuth(token)
+		MakeRequest(t, req, expectedStatus)
+	}
+	// versions returns the versions of the package served by the virtual registry of user2
+	versions := func(t *testing.T, packageName, token string) []string {
+		t.Helper()
+		req := NewRequest(t, "GET", fmt.Sprintf("/api/packages/%s/npm/%s", user2.Name, packageName)).
+			AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusOK)
+		var metadata npm_module.PackageMetadata
+		DecodeJSON(t, resp, &metadata)
+		var versions []string
+		for v := range metadata.Versions {
+			versions = append(versions, v)
+		}
+		return versions
+	}
+
+	upload(t, user4.Name, "virtual-first", "1.0.0", token4, http.StatusCreated)
+	upload(t, user5.Name, "virtual-first", "2.0.0", token5, http.StatusCreated)
+	upload(t, privateOrg.Name, "virtual-private", "1.0.0", token5, http.StatusCreated)
+	upload(t, user5.Name, "virtual-private", "2.0.0", token5, http.StatusCreated)
+
+	pvr, err := packages_model.InsertVirtualRegistry(db.DefaultContext, &packages_model.PackageVirtualRegistry{
+		Enabled:        true,
+		OwnerID:        user2.ID,
+		Type:           packages_model.TypeNpm,
+		MemberIDs:      []int64{privateOrg.ID, user4.ID, user5.ID},
+		DefaultOwnerID: org3.ID,
+	})
+	require.NoError(t, err)
+
+	t.Run("MemberOrder", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		// the first member containing the package wins, the packages of the members are not merged
+		assert.Equal(t, []string{"1.0.0"}, versions(t, "virtual-first", token2))
+
+		req := NewRequest(t, "GET", fmt.Sprintf("/api/packages/%s/npm/virtual-first/-/2.0.0/virtual-first-2.0.0.tgz", user2.Name)).
+			AddTokenAuth(token2)
+		MakeRequest(t, req, http.StatusNotFound)
+		req = NewRequest(t, "GET", fmt.Sprintf("/api/packages/%s/npm/virtual-first/-/1.0.0/virtual-first-1.0.0.tgz", user2.Name)).
+			AddTokenAuth(token2)
+		resp := MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, "user4/virtual-first@1.0.0", resp.Body.String())
+	})
+
+	t.Run("InaccessibleMember", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		// members of the private organization get its package
+		assert.Equal(t, []string{"1.0.0"}, versions(t, "virtual-private", token5))
+		// the private member is skipped for everyone else and its package doesn't leak
+		assert.Equal(t, []string{"2.0.0"}, versions(t, "virtual-private", token2))
+
+		req := NewRequest(t, "GET", fmt.Sprintf("/api/packages/%s/npm/virtual-private/-/1.0.0/virtual-private-1.0.0.tgz", user2.Name)).
+			AddTokenAuth(token2)
+		MakeRequest(t, req, http.StatusNotFound)
+		req = NewRequest(t, "GET", fmt.Sprintf("/api/packages/%s/npm/virtual-private", user2.Name))
+		resp := MakeRequest(t, req, http.StatusOK)
+		assert.NotContains(t, resp.Body.String(), "1.0.0")
+	})
+
+	t.Run("FineGrainedToken", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		// the token of user5 only covers the packages of user5, the private organization is skipped
+		req := NewRequestWithJSON(t, "POST", "/api/v1/users/user5/tokens", api.CreateAccessTokenOption{
+			Name:         "virtual",
+			Repositories: []string{"user5/repo4"},
+			Permissions:  map[string]string{"packages": "read"},
+		}).AddBasicAuth(user5.Name)
+		var token api.AccessToken
+		DecodeJSON(t, MakeRequest(t, req, http.StatusCreated), &token)
+
+		assert.Equal(t, []string{"2.0.0"}, versions(t, "virtual-private", token.Token))
+	})
+
+	t.Run("Upload", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		// uploads are stored in the default owner
+		upload(t, user2.Name, "virtual-upload", "1.0.0", token2, http.StatusCreated)
+		_, err := packages_model.GetPackageByName(db.DefaultContext, org3.ID, packages_model.TypeNpm, "virtual-upload")
+		require.NoError(t, err)
+		_, err = packages_model.GetPackageByName(db.DefaultContext, user2.ID, packages_model.TypeNpm, "virtual-upload")
+		require.ErrorIs(t, err, packages_model.ErrPackageNotExist)
+
+		// the default owner isn't a member, so the package is served from it as fallback
+		assert.Equal(t, []string{"1.0.0"}, versions(t, "virtual-upload", token2))
+
+		// uploads are denied without write access to the default owner
+		pvr.DefaultOwnerID = user4.ID
+		require.NoError(t, packages_model.UpdateVirtualRegistry(db.DefaultContext, pvr))
+		upload(t, user2.Name, "virtual-upload", "2.0.0", token2, http.StatusForbidden)
+	})
+}
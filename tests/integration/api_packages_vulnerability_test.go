@@ -0,0 +1,137 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/packages/osv"
+	"code.gitea.io/gitea/modules/setting"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/test"
+	vulnerability_service "code.gitea.io/gitea/services/packages/vulnerability"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageVulnerability(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+
+	token := getUserToken(t, user.Name, auth_model.AccessTokenScopeWritePackage)
+
+	packageName := "@scope/vulnerable"
+	packageVersion := "1.0.0"
+	filename := "vulnerable-1.0.0.tgz"
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "GHSA-test.json"), []byte(`{
+  "id": "GHSA-test",
+  "modified": "2024-03-01T10:00:00Z",
+  "aliases": ["CVE-2024-0001"],
+  "summary": "Remote code execution",
+  "database_specific": {"severity": "CRITICAL"},
+  "affected": [
+    {
+      "package": {"ecosystem": "npm", "name": "@scope/vulnerable"},
+      "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "1.0.1"}]}]
+    }
+  ]
+}`), 0o644))
+
+	count, err := vulnerability_service.ImportAdvisories(db.DefaultContext, dir)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	data := "H4sIAAAAAAAA/ytITM5OTE/VL4DQelnF+XkMVAYGBgZmJiYK2MRBwNDcSIHB2NTMwNDQzMwAqA7IMDUxA9LUdgg2UFpcklgEdAql5kD8ogCnhwio5lJQUMpLzE1VslJQcihOzi9I1S9JLS7RhSYIJR2QgrLUouLM/DyQGkM9Az1D3YIiqExKanFyUWZBCVQ2BKhVwQVJDKwosbQkI78IJO/tZ+LsbRykxFXLNdA+HwWjYBSMgpENACgAbtAACAAA"
+	upload := `{
+  "_id": "` + packageName + `",
+  "name": "` + packageName + `",
+  "dist-tags": {"latest": "` + packageVersion + `"},
+  "versions": {
+    "` + packageVersion + `": {
+      "name": "` + packageName + `",
+      "version": "` + packageVersion + `",
+      "dist": {
+        "integrity": "sha512-yA4FJsVhetynGfOC1jFf79BuS+jrHbm0fhh+aHzCQkOaOBXKf9oBnC4a6DnLLnEsHQDRLYd00cwj8sCXpC+wIg==",
+        "shasum": "aaa7eaf852a948b0aa05afeda35b1badca155d90"
+      }
+    }
+  },
+  "_attachments": {
+    "` + filename + `": {"data": "` + data + `"}
+  }
+}`
+
+	root := fmt.Sprintf("/api/packages/%s/npm/%s", user.Name, url.QueryEscape(packageName))
+
+	req := NewRequestWithBody(t, "PUT", root, strings.NewReader(upload)).
+		AddTokenAuth(token)
+	MakeRequest(t, req, http.StatusCreated)
+
+	t.Run("API", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", fmt.Sprintf("/api/v1/packages/%s/npm/%s/%s/vulnerabilities", user.Name, url.PathEscape(packageName), packageVersion)).
+			AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var vulnerabilities []*api.PackageVulnerability
+		DecodeJSON(t, resp, &vulnerabilities)
+		require.Len(t, vulnerabilities, 1)
+		assert.Equal(t, "GHSA-test", vulnerabilities[0].ID)
+		assert.Equal(t, []string{"CVE-2024-0001"}, vulnerabilities[0].Aliases)
+		assert.Equal(t, osv.SeverityCritical, vulnerabilities[0].Severity)
+	})
+
+	t.Run("Download", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		downloadURL := fmt.Sprintf("%s/-/%s/%s", root, packageVersion, filename)
+
+		req := NewRequest(t, "GET", downloadURL).
+			AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusOK)
+
+		defer test.MockVariableValue(&setting.Packages.BlockCriticalVulnerabilities, true)()
+
+		req = NewRequest(t, "GET", downloadURL).
+			AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusForbidden)
+	})
+
+	t.Run("Withdrawn", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "GHSA-test.json"), []byte(`{
+  "id": "GHSA-test",
+  "modified": "2024-03-02T10:00:00Z",
+  "withdrawn": "2024-03-02T10:00:00Z"
+}`), 0o644))
+
+		_, err := vulnerability_service.ImportAdvisories(db.DefaultContext, dir)
+		require.NoError(t, err)
+
+		req := NewRequest(t, "GET", fmt.Sprintf("/api/v1/packages/%s/npm/%s/%s/vulnerabilities", user.Name, url.PathEscape(packageName), packageVersion)).
+			AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var vulnerabilities []*api.PackageVulnerability
+		DecodeJSON(t, resp, &vulnerabilities)
+		assert.Empty(t, vulnerabilities)
+	})
+}
@@ -0,0 +1,260 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/setting"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/test"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIFineGrainedToken(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+
+	expires := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+
+	createToken := func(t *testing.T, opts api.CreateAccessTokenOption, expectedStatus int) *api.AccessToken {
+		t.Helper()
+
+		req := NewRequestWithJSON(t, "POST", "/api/v1/users/user2/tokens", opts).
+			AddBasicAuth(user.Name)
+		resp := MakeRequest(t, req, expectedStatus)
+		if expectedStatus != http.StatusCreated {
+			return nil
+		}
+
+		var token api.AccessToken
+		DecodeJSON(t, resp, &token)
+		return &token
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		// permissions without a restriction
+		createToken(t, api.CreateAccessTokenOption{
+			Name:        "invalid",
+			Permissions: map[string]string{"contents": "read"},
+		}, http.StatusBadRequest)
+
+		// organization and repositories
+		createToken(t, api.CreateAccessTokenOption{
+			Name:         "invalid",
+			Organization: "org3",
+			Repositories: []string{"user2/repo1"},
+			Permissions:  map[string]string{"contents": "read"},
+		}, http.StatusBadRequest)
+
+		// unknown permission
+		createToken(t, api.CreateAccessTokenOption{
+			Name:         "invalid",
+			Repositories: []string{"user2/repo1"},
+			Permissions:  map[string]string{"wiki": "read"},
+		}, http.StatusBadRequest)
+
+		// inaccessible repository
+		createToken(t, api.CreateAccessTokenOption{
+			Name:         "invalid",
+			Repositories: []string{"user30/empty"},
+			Permissions:  map[string]string{"contents": "read"},
+		}, http.StatusBadRequest)
+
+		// expiry in the past
+		past := time.Now().Add(-time.Hour)
+		createToken(t, api.CreateAccessTokenOption{
+			Name:      "invalid",
+			Scopes:    []string{"read:repository"},
+			ExpiresAt: &past,
+		}, http.StatusBadRequest)
+	})
+
+	t.Run("ExpiryPolicy", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+		defer test.MockVariableValue(&setting.AccessTokenMaxExpiry, 48*time.Hour)()
+
+		createToken(t, api.CreateAccessTokenOption{
+			Name:   "policy",
+			Scopes: []string{"read:repository"},
+		}, http.StatusBadRequest)
+
+		tooLate := time.Now().Add(72 * time.Hour)
+		createToken(t, api.CreateAccessTokenOption{
+			Name:      "policy",
+			Scopes:    []string{"read:repository"},
+			ExpiresAt: &tooLate,
+		}, http.StatusBadRequest)
+
+		createToken(t, api.CreateAccessTokenOption{
+			Name:      "policy",
+			Scopes:    []string{"read:repository"},
+			ExpiresAt: &expires,
+		}, http.StatusCreated)
+	})
+
+	t.Run("Repositories", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		token := createToken(t, api.CreateAccessTokenOption{
+			Name:         "repositories",
+			Repositories: []string{"user2/repo2"},
+			Permissions:  map[string]string{"contents": "read"},
+			ExpiresAt:    &expires,
+		}, http.StatusCreated)
+		assert.Equal(t, []string{"user2/repo2"}, token.Repositories)
+		assert.Equal(t, map[string]string{"contents": "read"}, token.Permissions)
+		assert.Equal(t, []string{"read:repository"}, token.Scopes)
+		require.NotNil(t, token.ExpiresAt)
+		assert.Equal(t, expires.Unix(), token.ExpiresAt.Unix())
+
+		MakeRequest(t, NewRequest(t, "GET", "/api/v1/repos/user2/repo2").AddTokenAuth(token.Token), http.StatusOK)
+		MakeRequest(t, NewRequest(t, "GET", "/api/v1/repos/user2/repo2/contents/README.md").AddTokenAuth(token.Token), http.StatusOK)
+		// other private repositories of the owner are hidden
+		MakeRequest(t, NewRequest(t, "GET", "/api/v1/repos/user2/repo16").AddTokenAuth(token.Token), http.StatusNotFound)
+		// public repositories stay readable
+		MakeRequest(t, NewRequest(t, "GET", "/api/v1/repos/user2/repo1").AddTokenAuth(token.Token), http.StatusOK)
+		// the token has no issue permission
+		MakeRequest(t, NewRequest(t, "GET", "/api/v1/repos/user2/repo2/issues").AddTokenAuth(token.Token), http.StatusForbidden)
+		// the token may only read
+		req := NewRequestWithJSON(t, "POST", "/api/v1/repos/user2/repo2/branches", &api.CreateBranchRepoOption{
+			BranchName:    "fine-grained",
+			OldBranchName: "master",
+		}).AddTokenAuth(token.Token)
+		MakeRequest(t, req, http.StatusForbidden)
+
+		// the restriction is part of the token list
+		req = NewRequest(t, "GET", "/api/v1/users/user2/tokens").AddBasicAuth(user.Name)
+		resp := MakeRequest(t, req, http.StatusOK)
+		var tokens []*api.AccessToken
+		DecodeJSON(t, resp, &tokens)
+		found := false
+		for _, listed := range tokens {
+			if listed.ID == token.ID {
+				found = true
+				assert.Equal(t, []string{"user2/repo2"}, listed.Repositories)
+				assert.Empty(t, listed.Token)
+			}
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("OtherRepositories", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		token := createToken(t, api.CreateAccessTokenOption{
+			Name:         "other-repositories",
+			Repositories: []string{"user2/repo2"},
+			Permissions:  map[string]string{"contents": "write", "issues": "read"},
+			ExpiresAt:    &expires,
+		}, http.StatusCreated)
+
+		// private repositories are only listed if the token is restricted to them
+		assertRepos := func(t *testing.T, repos []*api.Repository) {
+			t.Helper()
+			for _, repo := range repos {
+				assert.True(t, !repo.Private || repo.FullName == "user2/repo2", repo.FullName)
+			}
+		}
+		var repos []*api.Repository
+		resp := MakeRequest(t, NewRequest(t, "GET", "/api/v1/user/repos").AddTokenAuth(token.Token), http.StatusOK)
+		DecodeJSON(t, resp, &repos)
+		assertRepos(t, repos)
+		found := false
+		for _, repo := range repos {
+			found = found || repo.FullName == "user2/repo2"
+		}
+		assert.True(t, found)
+
+		resp = MakeRequest(t, NewRequest(t, "GET", "/api/v1/users/user2/repos").AddTokenAuth(token.Token), http.StatusOK)
+		DecodeJSON(t, resp, &repos)
+		assertRepos(t, repos)
+
+		var results api.SearchResults
+		resp = MakeRequest(t, NewRequest(t, "GET", "/api/v1/repos/search?limit=50").AddTokenAuth(token.Token), http.StatusOK)
+		DecodeJSON(t, resp, &results)
+		assertRepos(t, results.Data)
+		// public repositories outside of the token are still found
+		resp = MakeRequest(t, NewRequest(t, "GET", "/api/v1/repos/search?q=repo1&uid=2&limit=50").AddTokenAuth(token.Token), http.StatusOK)
+		DecodeJSON(t, resp, &results)
+		assertRepos(t, results.Data)
+		found = false
+		for _, repo := range results.Data {
+			found = found || repo.FullName == "user2/repo1"
+		}
+		assert.True(t, found)
+
+		var issues []*api.Issue
+		resp = MakeRequest(t, NewRequest(t, "GET", "/api/v1/repos/issues/search?state=all&limit=50").AddTokenAuth(token.Token), http.StatusOK)
+		DecodeJSON(t, resp, &issues)
+		for _, issue := range issues {
+			repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: issue.Repo.ID})
+			assert.True(t, !repo.IsPrivate || repo.ID == 2, repo.FullName())
+		}
+
+		// repositories can't be created
+		req := NewRequestWithJSON(t, "POST", "/api/v1/user/repos", &api.CreateRepoOption{Name: "fine-grained"}).
+			AddTokenAuth(token.Token)
+		MakeRequest(t, req, http.StatusForbidden)
+		req = NewRequestWithJSON(t, "POST", "/api/v1/orgs/org3/repos", &api.CreateRepoOption{Name: "fine-grained"}).
+			AddTokenAuth(token.Token)
+		MakeRequest(t, req, http.StatusForbidden)
+		req = NewRequestWithJSON(t, "POST", "/api/v1/repos/migrate", &api.MigrateRepoOptions{
+			CloneAddr: "https://example.com/user2/repo1.git",
+			RepoOwner: user.Name,
+			RepoName:  "fine-grained",
+		}).AddTokenAuth(token.Token)
+		MakeRequest(t, req, http.StatusForbidden)
+		req = NewRequestWithJSON(t, "POST", "/api/v1/repos/user2/repo2/forks", &api.CreateForkOption{}).
+			AddTokenAuth(token.Token)
+		MakeRequest(t, req, http.StatusForbidden)
+		unittest.AssertNotExistsBean(t, &repo_model.Repository{OwnerID: user.ID, LowerName: "fine-grained"})
+	})
+
+	t.Run("Organization", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		token := createToken(t, api.CreateAccessTokenOption{
+			Name:         "organization",
+			Organization: "org3",
+			Permissions:  map[string]string{"contents": "read", "issues": "write"},
+		}, http.StatusCreated)
+		assert.Equal(t, "org3", token.Organization)
+
+		MakeRequest(t, NewRequest(t, "GET", "/api/v1/repos/org3/repo3").AddTokenAuth(token.Token), http.StatusOK)
+		MakeRequest(t, NewRequest(t, "GET", "/api/v1/repos/user2/repo2").AddTokenAuth(token.Token), http.StatusNotFound)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		token := createToken(t, api.CreateAccessTokenOption{
+			Name:      "expired",
+			Scopes:    []string{"read:user"},
+			ExpiresAt: &expires,
+		}, http.StatusCreated)
+
+		MakeRequest(t, NewRequest(t, "GET", "/api/v1/user").AddTokenAuth(token.Token), http.StatusOK)
+
+		_, err := db.GetEngine(db.DefaultContext).ID(token.ID).Cols("expires_unix").Update(&auth_model.AccessToken{
+			ExpiresUnix: timeutil.TimeStampNow().Add(-1),
+		})
+		require.NoError(t, err)
+
+		MakeRequest(t, NewRequest(t, "GET", "/api/v1/user").AddTokenAuth(token.Token), http.StatusUnauthorized)
+	})
+}
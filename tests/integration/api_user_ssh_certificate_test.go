@@ -0,0 +1,70 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/modules/setting"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/test"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func TestAPIUserSSHCertificate(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	session := loginUser(t, "user2")
+	token := getTokenForLoggedInUser(t, session, auth_model.AccessTokenScopeWriteUser)
+
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sshKey, err := gossh.NewPublicKey(publicKey)
+	require.NoError(t, err)
+	option := &api.CreateSSHCertificateOption{Key: string(gossh.MarshalAuthorizedKey(sshKey)), Validity: 600}
+
+	t.Run("Disabled", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithJSON(t, "POST", "/api/v1/user/ssh_certificates", option).AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
+	defer test.MockVariableValue(&setting.SSH.UserCAEnabled, true)()
+	defer test.MockVariableValue(&setting.SSH.UserCAKey, filepath.Join(t.TempDir(), "user-ca"))()
+
+	t.Run("Sign", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithJSON(t, "POST", "/api/v1/user/ssh_certificates", option).AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusCreated)
+		var apiCert api.SSHCertificate
+		DecodeJSON(t, resp, &apiCert)
+		assert.Equal(t, []string{"user2"}, apiCert.Principals)
+		assert.WithinDuration(t, time.Now().Add(10*time.Minute), apiCert.ValidBefore, time.Minute)
+
+		parsed, _, _, _, err := gossh.ParseAuthorizedKey([]byte(apiCert.Certificate))
+		require.NoError(t, err)
+		cert, ok := parsed.(*gossh.Certificate)
+		require.True(t, ok)
+		assert.Equal(t, sshKey.Marshal(), cert.Key.Marshal())
+	})
+
+	t.Run("InvalidKey", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithJSON(t, "POST", "/api/v1/user/ssh_certificates", &api.CreateSSHCertificateOption{Key: "invalid"}).AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusUnprocessableEntity)
+	})
+}
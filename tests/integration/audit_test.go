@@ -0,0 +1,111 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"bufio"
+	"net/http"
+	"testing"
+
+	audit_model "code.gitea.io/gitea/models/audit"
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/json"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+	// the audit log isn't reset by the fixtures, drop the events of previous tests
+	require.NoError(t, db.TruncateBeans(db.DefaultContext, &audit_model.Event{}))
+
+	adminSession := loginUser(t, "user1")
+	adminToken := getTokenForLoggedInUser(t, adminSession, auth_model.AccessTokenScopeWriteAdmin)
+	// user2 owns org3, user4 is a member of org3
+	ownerSession := loginUser(t, "user2")
+	ownerToken := getTokenForLoggedInUser(t, ownerSession, auth_model.AccessTokenScopeWriteRepository, auth_model.AccessTokenScopeReadOrganization)
+	memberToken := getTokenForLoggedInUser(t, loginUser(t, "user4"), auth_model.AccessTokenScopeReadOrganization)
+
+	isAdmin := true
+	req := NewRequestWithJSON(t, "PATCH", "/api/v1/admin/users/user5", &api.EditUserOption{Admin: &isAdmin}).AddTokenAuth(adminToken)
+	MakeRequest(t, req, http.StatusOK)
+
+	isPrivate := false
+	req = NewRequestWithJSON(t, "PATCH", "/api/v1/repos/org3/repo3", &api.EditRepoOption{Private: &isPrivate}).AddTokenAuth(ownerToken)
+	MakeRequest(t, req, http.StatusOK)
+
+	t.Run("Admin API", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", "/api/v1/admin/audit?action=user.admin.grant").AddTokenAuth(adminToken)
+		resp := MakeRequest(t, req, http.StatusOK)
+		var events []*api.AuditEvent
+		DecodeJSON(t, resp, &events)
+		require.Len(t, events, 1)
+		assert.Equal(t, "user1", events[0].ActorName)
+		assert.Equal(t, "user", events[0].TargetType)
+		assert.Equal(t, "user5", events[0].TargetName)
+		assert.Equal(t, map[string]api.AuditChange{"is_admin": {Old: false, New: true}}, events[0].Diff)
+
+		// the access tokens created by the test are recorded as well
+		req = NewRequest(t, "GET", "/api/v1/admin/audit?action=user.access_token.create&actor=user2").AddTokenAuth(adminToken)
+		resp = MakeRequest(t, req, http.StatusOK)
+		DecodeJSON(t, resp, &events)
+		assert.Len(t, events, 1)
+
+		req = NewRequest(t, "GET", "/api/v1/admin/audit").AddTokenAuth(ownerToken)
+		MakeRequest(t, req, http.StatusForbidden)
+	})
+
+	t.Run("Organization API", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", "/api/v1/orgs/org3/audit").AddTokenAuth(ownerToken)
+		resp := MakeRequest(t, req, http.StatusOK)
+		var events []*api.AuditEvent
+		DecodeJSON(t, resp, &events)
+		require.Len(t, events, 1)
+		assert.Equal(t, "repo.visibility", events[0].Action)
+		assert.Equal(t, "user2", events[0].ActorName)
+		assert.Equal(t, "org3/repo3", events[0].TargetName)
+		assert.Equal(t, map[string]api.AuditChange{"private": {Old: true, New: false}}, events[0].Diff)
+
+		req = NewRequest(t, "GET", "/api/v1/orgs/org3/audit").AddTokenAuth(memberToken)
+		MakeRequest(t, req, http.StatusForbidden)
+	})
+
+	t.Run("Web", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", "/admin/audit?action=user.admin.grant")
+		resp := adminSession.MakeRequest(t, req, http.StatusOK)
+		assert.Contains(t, resp.Body.String(), "user5")
+
+		req = NewRequest(t, "GET", "/org/org3/settings/audit")
+		resp = ownerSession.MakeRequest(t, req, http.StatusOK)
+		assert.Contains(t, resp.Body.String(), "org3/repo3")
+		assert.NotContains(t, resp.Body.String(), "user.admin.grant")
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", "/org/org3/settings/audit/export")
+		resp := ownerSession.MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, "application/jsonl", resp.Header().Get("Content-Type"))
+
+		var actions []string
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			var e api.AuditEvent
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+			actions = append(actions, e.Action)
+		}
+		assert.Equal(t, []string{"repo.visibility"}, actions)
+	})
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/routers/web/auth"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuth2DeviceAuthorizationGrant(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	const clientID = "ce5a1322-42a7-11ed-b878-0242ac120002"
+
+	req := NewRequestWithValues(t, "POST", "/login/oauth/device_authorization", map[string]string{
+		"client_id": clientID,
+		"scope":     "read:user",
+	})
+	resp := MakeRequest(t, req, http.StatusOK)
+	var deviceResp auth.DeviceAuthorizationResponse
+	DecodeJSON(t, resp, &deviceResp)
+	assert.Equal(t, setting.AppURL+"login/oauth/device", deviceResp.VerificationURI)
+	assert.EqualValues(t, 5, deviceResp.Interval)
+
+	poll := func(expectedStatus int) *httptest.ResponseRecorder {
+		req := NewRequestWithValues(t, "POST", "/login/oauth/access_token", map[string]string{
+			"grant_type":  auth.GrantTypeDeviceCode,
+			"client_id":   clientID,
+			"device_code": deviceResp.DeviceCode,
+		})
+		return MakeRequest(t, req, expectedStatus)
+	}
+	assertPollError := func(code auth.AccessTokenErrorCode) {
+		var acErr auth.AccessTokenError
+		DecodeJSON(t, poll(http.StatusBadRequest), &acErr)
+		assert.Equal(t, code, acErr.ErrorCode)
+	}
+
+	assertPollError(auth.AccessTokenErrorCodeAuthorizationPending)
+	assertPollError(auth.AccessTokenErrorCodeSlowDown)
+
+	// confidential clients must authenticate
+	req = NewRequestWithValues(t, "POST", "/login/oauth/device_authorization", map[string]string{
+		"client_id": "da7da3ba-9a13-4167-856f-3899de0b0138",
+	})
+	MakeRequest(t, req, http.StatusBadRequest)
+
+	session := loginUser(t, "user2")
+	verificationURL := "/login/oauth/device?user_code=" + url.QueryEscape(deviceResp.UserCode)
+	session.MakeRequest(t, NewRequest(t, "GET", verificationURL), http.StatusOK)
+	session.MakeRequest(t, NewRequestWithValues(t, "POST", "/login/oauth/device", map[string]string{
+		"_csrf":     GetCSRF(t, session, verificationURL),
+		"user_code": deviceResp.UserCode,
+		"granted":   "true",
+	}), http.StatusOK)
+	code := unittest.AssertExistsAndLoadBean(t, &auth_model.OAuth2DeviceCode{DeviceCode: deviceResp.DeviceCode})
+	assert.NotZero(t, code.GrantID)
+	unittest.AssertExistsAndLoadBean(t, &auth_model.OAuth2Grant{ID: code.GrantID, UserID: 2, Scope: "read:user"})
+
+	// wait for the interval
+	_, err := db.GetEngine(db.DefaultContext).ID(code.ID).Cols("last_polled_unix").Update(&auth_model.OAuth2DeviceCode{})
+	require.NoError(t, err)
+
+	var tokenResp auth.AccessTokenResponse
+	DecodeJSON(t, poll(http.StatusOK), &tokenResp)
+	assert.NotEmpty(t, tokenResp.AccessToken)
+	assert.NotEmpty(t, tokenResp.RefreshToken)
+
+	// the device code can only be used once
+	assertPollError(auth.AccessTokenErrorCodeInvalidGrant)
+}
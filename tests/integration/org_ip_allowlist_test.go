@@ -0,0 +1,164 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	asymkey_model "code.gitea.io/gitea/models/asymkey"
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/organization"
+	"code.gitea.io/gitea/models/perm"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/private"
+	api "code.gitea.io/gitea/modules/structs"
+	attachment_service "code.gitea.io/gitea/services/attachment"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrgIPAllowlist(t *testing.T) {
+	onGiteaRun(t, func(t *testing.T, _ *url.URL) {
+		const (
+			allowedIP = "203.0.113.5"
+			deniedIP  = "198.51.100.7"
+		)
+		from := func(req *RequestWrapper, ip string) *RequestWrapper {
+			req.RemoteAddr = ip + ":1234"
+			return req
+		}
+
+		// user2 owns org3, user4 is a member of a team with access to the private org3/repo3
+		ownerSession := loginUser(t, "user2")
+		memberSession := loginUser(t, "user4")
+		memberToken := getTokenForLoggedInUser(t, memberSession, auth_model.AccessTokenScopeReadRepository)
+
+		setAllowlist := func(t *testing.T, allowlist, ip string) {
+			t.Helper()
+			req := NewRequestWithValues(t, "POST", "/org/org3/settings/security/ip_allowlist", map[string]string{
+				"_csrf":     GetCSRF(t, ownerSession, "/org/org3/settings/security"),
+				"allowlist": allowlist,
+			})
+			ownerSession.MakeRequest(t, from(req, ip), http.StatusSeeOther)
+		}
+
+		t.Run("Invalid entry", func(t *testing.T) {
+			setAllowlist(t, "203.0.113.0/24\nexample.com", allowedIP)
+			unittest.AssertNotExistsBean(t, &organization.IPAllowlist{OrgID: 3})
+		})
+
+		t.Run("Owner locks themselves out", func(t *testing.T) {
+			setAllowlist(t, "10.0.0.0/8", allowedIP)
+			unittest.AssertNotExistsBean(t, &organization.IPAllowlist{OrgID: 3})
+		})
+
+		setAllowlist(t, "203.0.113.0/24\n127.0.0.1", allowedIP)
+		allowlist := unittest.AssertExistsAndLoadBean(t, &organization.IPAllowlist{OrgID: 3})
+		assert.Equal(t, "203.0.113.0/24,127.0.0.1/32", allowlist.Allowlist)
+
+		t.Run("Web", func(t *testing.T) {
+			memberSession.MakeRequest(t, from(NewRequest(t, "GET", "/org3/repo3"), allowedIP), http.StatusOK)
+			memberSession.MakeRequest(t, from(NewRequest(t, "GET", "/org3/repo3"), deniedIP), http.StatusNotFound)
+		})
+
+		t.Run("API", func(t *testing.T) {
+			MakeRequest(t, from(NewRequest(t, "GET", "/api/v1/repos/org3/repo3").AddTokenAuth(memberToken), allowedIP), http.StatusOK)
+			MakeRequest(t, from(NewRequest(t, "GET", "/api/v1/repos/org3/repo3").AddTokenAuth(memberToken), deniedIP), http.StatusNotFound)
+		})
+
+		t.Run("Git over HTTP", func(t *testing.T) {
+			MakeRequest(t, from(NewRequest(t, "GET", "/org3/repo3.git/info/refs").AddBasicAuth("user4"), allowedIP), http.StatusOK)
+			MakeRequest(t, from(NewRequest(t, "GET", "/org3/repo3.git/info/refs").AddBasicAuth("user4"), deniedIP), http.StatusNotFound)
+		})
+
+		t.Run("Git over SSH", func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// key 1 belongs to user2
+			_, extra := private.ServCommand(ctx, 1, "org3", "repo3", perm.AccessModeRead, allowedIP, "git-upload-pack", "")
+			require.NoError(t, extra.Error)
+			_, extra = private.ServCommand(ctx, 1, "org3", "repo3", perm.AccessModeRead, deniedIP, "git-upload-pack", "")
+			require.Error(t, extra.Error)
+			_, extra = private.ServCommand(ctx, 1, "org3", "repo3", perm.AccessModeRead, "", "git-upload-pack", "")
+			require.Error(t, extra.Error)
+
+			// deploy keys aren't restricted
+			deployKey, err := asymkey_model.AddDeployKey(ctx, 3, "test-deploy", "sk-ecdsa-sha2-nistp256@openssh.com AAAAInNrLWVjZHNhLXNoYTItbmlzdHAyNTZAb3BlbnNzaC5jb20AAAAIbmlzdHAyNTYAAABBBGXEEzWmm1dxb+57RoK5KVCL0w2eNv9cqJX2AGGVlkFsVDhOXHzsadS3LTK4VlEbbrDMJdoti9yM8vclA8IeRacAAAAEc3NoOg== nocomment", true)
+			require.NoError(t, err)
+			_, extra = private.ServCommand(ctx, deployKey.KeyID, "org3", "repo3", perm.AccessModeRead, deniedIP, "git-upload-pack", "")
+			require.NoError(t, extra.Error)
+		})
+
+		t.Run("Attachment", func(t *testing.T) {
+			// an attachment of issue6 of org3/repo3
+			attach, err := attachment_service.NewAttachment(db.DefaultContext, &repo_model.Attachment{
+				RepoID:     3,
+				IssueID:    6,
+				UploaderID: 4,
+				Name:       "allowlist.txt",
+			}, strings.NewReader("allowlist"), int64(len("allowlist")))
+			require.NoError(t, err)
+
+			memberSession.MakeRequest(t, from(NewRequest(t, "GET", "/attachments/"+attach.UUID), allowedIP), http.StatusOK)
+			memberSession.MakeRequest(t, from(NewRequest(t, "GET", "/attachments/"+attach.UUID), deniedIP), http.StatusNotFound)
+		})
+
+		t.Run("Issue search", func(t *testing.T) {
+			searchRepos := func(t *testing.T, ip string) []string {
+				t.Helper()
+				req := NewRequest(t, "GET", "/api/v1/repos/issues/search?owner=org3&state=all").AddTokenAuth(memberToken)
+				resp := MakeRequest(t, from(req, ip), http.StatusOK)
+				var issues []*api.Issue
+				DecodeJSON(t, resp, &issues)
+				repos := make([]string, 0, len(issues))
+				for _, issue := range issues {
+					repos = append(repos, issue.Repo.FullName)
+				}
+				return repos
+			}
+			assert.Contains(t, searchRepos(t, allowedIP), "org3/repo3")
+			assert.NotContains(t, searchRepos(t, deniedIP), "org3/repo3")
+
+			req := NewRequest(t, "GET", "/issues/search?owner=org3&state=all")
+			resp := memberSession.MakeRequest(t, from(req, deniedIP), http.StatusOK)
+			var issues []*api.Issue
+			DecodeJSON(t, resp, &issues)
+			for _, issue := range issues {
+				assert.NotEqual(t, "org3/repo3", issue.Repo.FullName)
+			}
+		})
+
+		t.Run("Repository search", func(t *testing.T) {
+			searchRepos := func(t *testing.T, ip string) []string {
+				t.Helper()
+				req := NewRequest(t, "GET", "/api/v1/repos/search?q=repo3").AddTokenAuth(memberToken)
+				resp := MakeRequest(t, from(req, ip), http.StatusOK)
+				var result api.SearchResults
+				DecodeJSON(t, resp, &result)
+				repos := make([]string, 0, len(result.Data))
+				for _, repo := range result.Data {
+					repos = append(repos, repo.FullName)
+				}
+				return repos
+			}
+			assert.Contains(t, searchRepos(t, allowedIP), "org3/repo3")
+			assert.NotContains(t, searchRepos(t, deniedIP), "org3/repo3")
+		})
+
+		t.Run("Remove", func(t *testing.T) {
+			setAllowlist(t, "", deniedIP)
+			unittest.AssertNotExistsBean(t, &organization.IPAllowlist{OrgID: 3})
+
+			memberSession.MakeRequest(t, from(NewRequest(t, "GET", "/org3/repo3"), deniedIP), http.StatusOK)
+		})
+	})
+}
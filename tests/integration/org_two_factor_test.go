@@ -0,0 +1,89 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"net/http"
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/organization"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrgTwoFactorPolicy(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	// user2 owns org3, user4 is a member of a team with access to the private org3/repo3
+	ownerSession := loginUser(t, "user2")
+	memberSession := loginUser(t, "user4")
+	memberToken := getTokenForLoggedInUser(t, memberSession, auth_model.AccessTokenScopeReadRepository)
+
+	req := NewRequest(t, "GET", "/api/v1/repos/org3/repo3").AddTokenAuth(memberToken)
+	MakeRequest(t, req, http.StatusOK)
+
+	t.Run("Owner without two-factor authentication", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithValues(t, "POST", "/org/org3/settings/security", map[string]string{
+			"_csrf":              GetCSRF(t, ownerSession, "/org/org3/settings/security"),
+			"require_two_factor": "on",
+		})
+		ownerSession.MakeRequest(t, req, http.StatusSeeOther)
+
+		unittest.AssertNotExistsBean(t, &organization.TwoFactorPolicy{OrgID: 3})
+	})
+
+	unittest.AssertSuccessfulInsert(t, &auth_model.TwoFactor{UID: 2})
+
+	t.Run("Require two-factor authentication", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithValues(t, "POST", "/org/org3/settings/security", map[string]string{
+			"_csrf":              GetCSRF(t, ownerSession, "/org/org3/settings/security"),
+			"require_two_factor": "on",
+		})
+		ownerSession.MakeRequest(t, req, http.StatusSeeOther)
+
+		unittest.AssertExistsAndLoadBean(t, &organization.TwoFactorPolicy{OrgID: 3})
+
+		// the non-compliant member is listed
+		req = NewRequest(t, "GET", "/org/org3/settings/security")
+		resp := ownerSession.MakeRequest(t, req, http.StatusOK)
+		assert.Contains(t, resp.Body.String(), "/user4")
+
+		// the owner who enrolled two-factor authentication keeps access
+		req = NewRequest(t, "GET", "/org3/repo3")
+		ownerSession.MakeRequest(t, req, http.StatusOK)
+
+		// the member without two-factor authentication loses access
+		req = NewRequest(t, "GET", "/org3/repo3")
+		memberSession.MakeRequest(t, req, http.StatusNotFound)
+		req = NewRequest(t, "GET", "/api/v1/repos/org3/repo3").AddTokenAuth(memberToken)
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
+	t.Run("Member enrolls two-factor authentication", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		unittest.AssertSuccessfulInsert(t, &auth_model.TwoFactor{UID: 4})
+
+		req := NewRequest(t, "GET", "/api/v1/repos/org3/repo3").AddTokenAuth(memberToken)
+		MakeRequest(t, req, http.StatusOK)
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithValues(t, "POST", "/org/org3/settings/security", map[string]string{
+			"_csrf": GetCSRF(t, ownerSession, "/org/org3/settings/security"),
+		})
+		ownerSession.MakeRequest(t, req, http.StatusSeeOther)
+
+		unittest.AssertNotExistsBean(t, &organization.TwoFactorPolicy{OrgID: 3})
+	})
+}
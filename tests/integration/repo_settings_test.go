@@ -9,7 +9,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"code.gitea.io/gitea/models/db"
 	"code.gitea.io/gitea/models/forgefed"
@@ -326,6 +328,23 @@ func TestRepoFollowing(t *testing.T) {
 
 			repo1InboxReceivedLike = true
 		})
+	var repo1InboxReceivedFollow atomic.Bool
+	federatedRoutes.HandleFunc("/api/v1/activitypub/repository-id/1/inbox",
+		func(res http.ResponseWriter, req *http.Request) {
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				t.Errorf("Error reading body: %q", err)
+			}
+			follow := fm.ForgeLike{}
+			if err := follow.UnmarshalJSON(body); err != nil {
+				t.Errorf("Error unmarshalling Follow: %q", err)
+			}
+			if follow.Type != "Follow" || !strings.HasSuffix(follow.Actor.GetID().String(), "/api/v1/activitypub/repository-id/1") {
+				t.Errorf("Activity is not a follow of this repo")
+			}
+			repo1InboxReceivedFollow.Store(true)
+			res.WriteHeader(http.StatusAccepted)
+		})
 	federatedRoutes.HandleFunc("/",
 		func(res http.ResponseWriter, req *http.Request) {
 			t.Errorf("Unhandled request: %q", req.URL.EscapedPath())
@@ -354,6 +373,8 @@ func TestRepoFollowing(t *testing.T) {
 			ExternalID:       "1",
 			FederationHostID: federationHost.ID,
 		})
+		// the followed repo is notified
+		assert.Eventually(t, repo1InboxReceivedFollow.Load, 10*time.Second, 100*time.Millisecond)
 	})
 
 	t.Run("Star a repo having a following repo", func(t *testing.T) {
@@ -0,0 +1,136 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/organization"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/saml"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const samlTestResponse = `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_response" Version="2.0" IssueInstant="%[1]s" Destination="%[3]sacs" InResponseTo="%[4]s">
+  <saml:Issuer>https://idp.example.com</saml:Issuer>
+  <samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status>
+  <saml:Assertion ID="_assertion" Version="2.0" IssueInstant="%[1]s">
+    <saml:Issuer>https://idp.example.com</saml:Issuer>
+    <saml:Subject>
+      <saml:NameID>8f2e1c</saml:NameID>
+      <saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">
+        <saml:SubjectConfirmationData NotOnOrAfter="%[2]s" Recipient="%[3]sacs" InResponseTo="%[4]s"/>
+      </saml:SubjectConfirmation>
+    </saml:Subject>
+    <saml:Conditions NotBefore="%[1]s" NotOnOrAfter="%[2]s">
+      <saml:AudienceRestriction><saml:Audience>%[3]smetadata</saml:Audience></saml:AudienceRestriction>
+    </saml:Conditions>
+    <saml:AuthnStatement AuthnInstant="%[1]s" SessionIndex="_session"/>
+    <saml:AttributeStatement>
+      <saml:Attribute Name="uid"><saml:AttributeValue>samluser</saml:AttributeValue></saml:Attribute>
+      <saml:Attribute Name="mail"><saml:AttributeValue>samluser@example.com</saml:AttributeValue></saml:Attribute>
+      <saml:Attribute Name="displayName"><saml:AttributeValue>SAML User</saml:AttributeValue></saml:Attribute>
+      <saml:Attribute Name="groups"><saml:AttributeValue>developers</saml:AttributeValue></saml:Attribute>
+    </saml:AttributeStatement>
+  </saml:Assertion>
+</samlp:Response>`
+
+func TestSAMLSignIn(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	idpKeyPEM, idpCertPEM, err := saml.GenerateKeyPair("idp.example.com")
+	require.NoError(t, err)
+	idpKey, err := saml.ParsePrivateKey(idpKeyPEM)
+	require.NoError(t, err)
+
+	source := addAuthSource(t, map[string]string{
+		"type":                     fmt.Sprintf("%d", auth_model.SAML),
+		"name":                     "corp",
+		"is_active":                "on",
+		"saml_idp_entity_id":       "https://idp.example.com",
+		"saml_idp_sso_url":         "https://idp.example.com/sso",
+		"saml_idp_certificate":     idpCertPEM,
+		"saml_sign_requests":       "on",
+		"saml_attribute_username":  "uid",
+		"saml_attribute_email":     "mail",
+		"saml_attribute_full_name": "displayName",
+		"saml_attribute_groups":    "groups",
+		"saml_group_team_map":      `{"developers": {"org3": ["team1"]}}`,
+	})
+	baseURL := setting.AppURL + "user/saml/corp/"
+
+	t.Run("Metadata", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		resp := MakeRequest(t, NewRequest(t, "GET", "/user/saml/corp/metadata"), http.StatusOK)
+		metadata, err := saml.ParseXML(resp.Body.Bytes())
+		require.NoError(t, err)
+		assert.Equal(t, baseURL+"metadata", metadata.Attr("entityID"))
+	})
+
+	session := emptyTestSession(t)
+
+	// start the sign in and extract the ID of the authentication request
+	resp := session.MakeRequest(t, NewRequest(t, "GET", "/user/saml/corp"), http.StatusSeeOther)
+	location, err := url.Parse(resp.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "idp.example.com", location.Host)
+	compressed, err := base64.StdEncoding.DecodeString(location.Query().Get("SAMLRequest"))
+	require.NoError(t, err)
+	data, err := io.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+	require.NoError(t, err)
+	authnRequest, err := saml.ParseXML(data)
+	require.NoError(t, err)
+	requestID := authnRequest.Attr("ID")
+	require.NotEmpty(t, requestID)
+	assert.NotEmpty(t, location.Query().Get("Signature"))
+
+	now := time.Now().UTC()
+	samlResponse, err := saml.ParseXML([]byte(fmt.Sprintf(samlTestResponse,
+		now.Format(time.RFC3339), now.Add(5*time.Minute).Format(time.RFC3339), baseURL, requestID)))
+	require.NoError(t, err)
+	require.NoError(t, saml.SignEnveloped(samlResponse, idpKey, nil))
+	encoded := base64.StdEncoding.EncodeToString(saml.Canonicalize(samlResponse, nil))
+
+	req := NewRequestWithValues(t, "POST", "/user/saml/corp/acs", map[string]string{"SAMLResponse": encoded})
+	resp = session.MakeRequest(t, req, http.StatusSeeOther)
+	assert.Equal(t, setting.AppSubURL+"/", resp.Header().Get("Location"))
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{Name: "samluser"})
+	assert.Equal(t, auth_model.SAML, user.LoginType)
+	assert.Equal(t, source.ID, user.LoginSource)
+	assert.Equal(t, "8f2e1c", user.LoginName)
+	assert.Equal(t, "samluser@example.com", user.Email)
+	assert.Equal(t, "SAML User", user.FullName)
+
+	isMember, err := organization.IsTeamMember(db.DefaultContext, 3, 2, user.ID)
+	require.NoError(t, err)
+	assert.True(t, isMember)
+
+	session.MakeRequest(t, NewRequest(t, "GET", "/user/settings"), http.StatusOK)
+
+	t.Run("Replay", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		replaySession := emptyTestSession(t)
+		req := NewRequestWithValues(t, "POST", "/user/saml/corp/acs", map[string]string{"SAMLResponse": encoded})
+		resp := replaySession.MakeRequest(t, req, http.StatusSeeOther)
+		assert.Equal(t, setting.AppSubURL+"/user/login", resp.Header().Get("Location"))
+	})
+}
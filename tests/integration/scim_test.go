@@ -0,0 +1,158 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/organization"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/saml"
+	"code.gitea.io/gitea/modules/scim"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSCIMProvisioning(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	_, idpCertPEM, err := saml.GenerateKeyPair("idp.example.com")
+	require.NoError(t, err)
+	source := addAuthSource(t, map[string]string{
+		"type":                 fmt.Sprintf("%d", auth_model.SAML),
+		"name":                 "scim",
+		"is_active":            "on",
+		"saml_idp_entity_id":   "https://idp.example.com",
+		"saml_idp_sso_url":     "https://idp.example.com/sso",
+		"saml_idp_certificate": idpCertPEM,
+	})
+	token, err := auth_model.GenerateSCIMToken(db.DefaultContext, source.ID)
+	require.NoError(t, err)
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		MakeRequest(t, NewRequest(t, "GET", "/api/scim/v2/Users"), http.StatusUnauthorized)
+		MakeRequest(t, NewRequest(t, "GET", "/api/scim/v2/Users").AddTokenAuth("0000000000000000000000000000000000000000"), http.StatusUnauthorized)
+	})
+
+	var user scim.User
+	t.Run("CreateUser", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithJSON(t, "POST", "/api/scim/v2/Users", &scim.User{
+			Schemas:     []string{scim.SchemaUser},
+			UserName:    "jane.doe@example.com",
+			DisplayName: "Jane Doe",
+			Emails:      []scim.Email{{Value: "jane.doe@example.com", Primary: true}},
+		}).AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusCreated)
+		assert.Equal(t, scim.ContentType, resp.Header().Get("Content-Type"))
+		DecodeJSON(t, resp, &user)
+		assert.Equal(t, "jane.doe@example.com", user.UserName)
+		assert.True(t, *user.Active)
+
+		u := unittest.AssertExistsAndLoadBean(t, &user_model.User{Name: "jane.doe"})
+		assert.Equal(t, user.ID, fmt.Sprint(u.ID))
+		assert.Equal(t, source.ID, u.LoginSource)
+		assert.Equal(t, "Jane Doe", u.FullName)
+
+		// the userName is unique in the source
+		MakeRequest(t, req, http.StatusConflict)
+	})
+
+	t.Run("ListUsers", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		filter := url.QueryEscape(`userName eq "JANE.DOE@example.com"`)
+		resp := MakeRequest(t, NewRequest(t, "GET", "/api/scim/v2/Users?filter="+filter).AddTokenAuth(token), http.StatusOK)
+		var list scim.ListResponse
+		DecodeJSON(t, resp, &list)
+		assert.EqualValues(t, 1, list.TotalResults)
+
+		// users of other sources are not listed
+		filter = url.QueryEscape(`userName eq "user2"`)
+		resp = MakeRequest(t, NewRequest(t, "GET", "/api/scim/v2/Users?filter="+filter).AddTokenAuth(token), http.StatusOK)
+		DecodeJSON(t, resp, &list)
+		assert.EqualValues(t, 0, list.TotalResults)
+		MakeRequest(t, NewRequest(t, "GET", "/api/scim/v2/Users/2").AddTokenAuth(token), http.StatusNotFound)
+	})
+
+	t.Run("Groups", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithJSON(t, "POST", "/api/scim/v2/Groups", &scim.Group{
+			Schemas:     []string{scim.SchemaGroup},
+			DisplayName: "org3/team1",
+			Members:     []scim.Reference{{Value: user.ID}},
+		}).AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusCreated)
+		var group scim.Group
+		DecodeJSON(t, resp, &group)
+		assert.Equal(t, "2", group.ID)
+		assert.Len(t, group.Members, 1)
+
+		userID := unittest.AssertExistsAndLoadBean(t, &user_model.User{Name: "jane.doe"}).ID
+		isMember, err := organization.IsTeamMember(db.DefaultContext, 3, 2, userID)
+		require.NoError(t, err)
+		assert.True(t, isMember)
+
+		req = NewRequestWithJSON(t, "PATCH", "/api/scim/v2/Groups/2", &scim.PatchRequest{
+			Schemas:    []string{scim.SchemaPatchOp},
+			Operations: []scim.PatchOperation{{Op: "Remove", Path: fmt.Sprintf(`members[value eq "%s"]`, user.ID)}},
+		}).AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusOK)
+		isMember, err = organization.IsTeamMember(db.DefaultContext, 3, 2, userID)
+		require.NoError(t, err)
+		assert.False(t, isMember)
+
+		// the owners team can't be provisioned
+		req = NewRequestWithJSON(t, "POST", "/api/scim/v2/Groups", &scim.Group{
+			Schemas:     []string{scim.SchemaGroup},
+			DisplayName: "org3/Owners",
+		}).AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusBadRequest)
+
+		MakeRequest(t, NewRequest(t, "DELETE", "/api/scim/v2/Groups/2").AddTokenAuth(token), http.StatusNoContent)
+		unittest.AssertExistsAndLoadBean(t, &organization.Team{ID: 2})
+		unittest.AssertNotExistsBean(t, &auth_model.SCIMGroup{TeamID: 2})
+	})
+
+	t.Run("DeactivateUser", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithJSON(t, "PATCH", "/api/scim/v2/Users/"+user.ID, &scim.PatchRequest{
+			Schemas: []string{scim.SchemaPatchOp},
+			Operations: []scim.PatchOperation{
+				{Op: "Replace", Path: "active", Value: "False"},
+				{Op: "replace", Value: map[string]any{"displayName": "Jane Roe"}},
+			},
+		}).AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusOK)
+		var patched scim.User
+		DecodeJSON(t, resp, &patched)
+		assert.False(t, *patched.Active)
+		assert.Equal(t, "Jane Roe", patched.DisplayName)
+
+		u := unittest.AssertExistsAndLoadBean(t, &user_model.User{Name: "jane.doe"})
+		assert.False(t, u.IsActive)
+		assert.Equal(t, "Jane Roe", u.FullName)
+	})
+
+	t.Run("DeleteUser", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		MakeRequest(t, NewRequest(t, "DELETE", "/api/scim/v2/Users/"+user.ID).AddTokenAuth(token), http.StatusNoContent)
+		unittest.AssertNotExistsBean(t, &user_model.User{Name: "jane.doe"})
+		MakeRequest(t, NewRequest(t, "GET", "/api/scim/v2/Users/"+user.ID).AddTokenAuth(token), http.StatusNotFound)
+	})
+}
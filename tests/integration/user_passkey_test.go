@@ -0,0 +1,136 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/test"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRemovePassword(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	session := loginUser(t, user.Name)
+
+	t.Run("Without passkey", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithValues(t, "POST", "/user/settings/account/remove_password", map[string]string{
+			"_csrf":        GetCSRF(t, session, "/user/settings/account"),
+			"old_password": userPassword,
+		})
+		session.MakeRequest(t, req, http.StatusSeeOther)
+
+		assert.True(t, unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: user.ID}).IsPasswordSet())
+	})
+
+	unittest.AssertSuccessfulInsert(t, &auth_model.WebAuthnCredential{UserID: user.ID, Name: "Passkey", Discoverable: true})
+	passkey := unittest.AssertExistsAndLoadBean(t, &auth_model.WebAuthnCredential{UserID: user.ID, Name: "Passkey"})
+
+	t.Run("Wrong password", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithValues(t, "POST", "/user/settings/account/remove_password", map[string]string{
+			"_csrf":        GetCSRF(t, session, "/user/settings/account"),
+			"old_password": "wrong",
+		})
+		session.MakeRequest(t, req, http.StatusSeeOther)
+
+		assert.True(t, unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: user.ID}).IsPasswordSet())
+	})
+
+	t.Run("Normal", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithValues(t, "POST", "/user/settings/account/remove_password", map[string]string{
+			"_csrf":        GetCSRF(t, session, "/user/settings/account"),
+			"old_password": userPassword,
+		})
+		session.MakeRequest(t, req, http.StatusSeeOther)
+
+		assert.False(t, unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: user.ID}).IsPasswordSet())
+
+		// the password can't be used to sign in anymore
+		req = NewRequestWithValues(t, "POST", "/user/login", map[string]string{
+			"_csrf":     GetCSRF(t, emptyTestSession(t), "/user/login"),
+			"user_name": user.Name,
+			"password":  userPassword,
+		})
+		MakeRequest(t, req, http.StatusOK)
+	})
+
+	t.Run("Delete last passkey", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithValues(t, "POST", "/user/settings/security/webauthn/delete", map[string]string{
+			"_csrf": GetCSRF(t, session, "/user/settings/security"),
+			"id":    strconv.FormatInt(passkey.ID, 10),
+		})
+		session.MakeRequest(t, req, http.StatusOK)
+
+		unittest.AssertExistsIf(t, true, &auth_model.WebAuthnCredential{ID: passkey.ID})
+	})
+}
+
+func TestOrgMembersRequireWebAuthn(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+	defer test.MockVariableValue(&setting.OrgMembersRequireWebAuthn, true)()
+
+	// user2 is a member of organizations
+	session := loginUser(t, "user2")
+
+	t.Run("Without security key", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", "/user2/repo1")
+		resp := session.MakeRequest(t, req, http.StatusSeeOther)
+		assert.Equal(t, "/user/settings/security", resp.Header().Get("Location"))
+
+		req = NewRequest(t, "GET", "/user/settings/security")
+		session.MakeRequest(t, req, http.StatusOK)
+	})
+
+	t.Run("With security key", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		unittest.AssertSuccessfulInsert(t, &auth_model.WebAuthnCredential{UserID: 2, Name: "Security key"})
+
+		req := NewRequest(t, "GET", "/user2/repo1")
+		session.MakeRequest(t, req, http.StatusOK)
+	})
+
+	t.Run("Basic auth", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		// passwords can be phished like TOTP codes, only access tokens are accepted
+		req := NewRequest(t, "GET", "/api/v1/user").AddBasicAuth("user2")
+		MakeRequest(t, req, http.StatusUnauthorized)
+		req = NewRequest(t, "GET", "/user2/repo1.git/info/refs").AddBasicAuth("user2")
+		MakeRequest(t, req, http.StatusUnauthorized)
+
+		token := getUserToken(t, "user2", auth_model.AccessTokenScopeReadUser)
+		req = NewRequest(t, "GET", "/api/v1/user").AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusOK)
+	})
+
+	t.Run("No organization member", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		// user8 is no member of an organization
+		session := loginUser(t, "user8")
+		req := NewRequest(t, "GET", "/user2/repo1")
+		session.MakeRequest(t, req, http.StatusOK)
+	})
+}
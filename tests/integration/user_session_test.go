@@ -0,0 +1,163 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserSessions(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+	// the user sessions aren't reset by the fixtures, drop the ones of previous tests
+	require.NoError(t, db.TruncateBeans(db.DefaultContext, &auth_model.UserSession{}))
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+
+	// signInTwice returns two sessions of the user, the user sessions of both are tracked
+	signInTwice := func(t *testing.T) (*TestSession, *TestSession, []*auth_model.UserSession) {
+		t.Helper()
+
+		first := loginUser(t, user.Name)
+		first.MakeRequest(t, NewRequest(t, "GET", "/user/settings"), http.StatusOK)
+		second := loginUser(t, user.Name)
+		second.MakeRequest(t, NewRequest(t, "GET", "/user/settings"), http.StatusOK)
+
+		sessions, err := db.Find[auth_model.UserSession](db.DefaultContext, auth_model.FindUserSessionsOptions{
+			UID:         user.ID,
+			ListOptions: db.ListOptions{ListAll: true},
+		})
+		require.NoError(t, err)
+		require.Len(t, sessions, 2)
+		// the newest first
+		return first, second, sessions
+	}
+
+	t.Run("List", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		first, _, sessions := signInTwice(t)
+		token := getTokenForLoggedInUser(t, first, auth_model.AccessTokenScopeReadUser)
+
+		req := NewRequest(t, "GET", "/api/v1/user/sessions").AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusOK)
+		var apiSessions []*api.UserSession
+		DecodeJSON(t, resp, &apiSessions)
+		require.Len(t, apiSessions, 2)
+		assert.Equal(t, sessions[0].ID, apiSessions[0].ID)
+		assert.Equal(t, sessions[1].ID, apiSessions[1].ID)
+
+		resp = first.MakeRequest(t, NewRequest(t, "GET", "/user/settings/security"), http.StatusOK)
+		htmlDoc := NewHTMLParser(t, resp.Body)
+		htmlDoc.AssertElement(t, `button[data-url$="/sessions/revoke"][data-id="`+strconv.FormatInt(sessions[0].ID, 10)+`"]`, true)
+		htmlDoc.AssertElement(t, `button[data-url$="/sessions/revoke"][data-id="`+strconv.FormatInt(sessions[1].ID, 10)+`"]`, true)
+		assert.EqualValues(t, 1, htmlDoc.Find(".flex-item-title .label").Length())
+
+		require.NoError(t, auth_model.RevokeUserSessionsByUID(db.DefaultContext, user.ID))
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		first, second, sessions := signInTwice(t)
+
+		req := NewRequestWithValues(t, "POST", "/user/settings/security/sessions/revoke", map[string]string{
+			"_csrf": GetCSRF(t, first, "/user/settings/security"),
+			"id":    strconv.FormatInt(sessions[0].ID, 10),
+		})
+		first.MakeRequest(t, req, http.StatusOK)
+
+		second.MakeRequest(t, NewRequest(t, "GET", "/user/settings"), http.StatusSeeOther)
+		first.MakeRequest(t, NewRequest(t, "GET", "/user/settings"), http.StatusOK)
+
+		// the sessions of other users can't be revoked
+		other := loginUser(t, "user4")
+		req = NewRequestWithValues(t, "POST", "/user/settings/security/sessions/revoke", map[string]string{
+			"_csrf": GetCSRF(t, other, "/user/settings/security"),
+			"id":    strconv.FormatInt(sessions[1].ID, 10),
+		})
+		other.MakeRequest(t, req, http.StatusOK)
+		first.MakeRequest(t, NewRequest(t, "GET", "/user/settings"), http.StatusOK)
+
+		require.NoError(t, auth_model.RevokeUserSessionsByUID(db.DefaultContext, user.ID))
+	})
+
+	t.Run("API", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		first, second, sessions := signInTwice(t)
+		token := getTokenForLoggedInUser(t, first, auth_model.AccessTokenScopeWriteUser)
+		otherToken := getUserToken(t, "user4", auth_model.AccessTokenScopeWriteUser)
+
+		req := NewRequestf(t, "DELETE", "/api/v1/user/sessions/%d", sessions[0].ID).AddTokenAuth(otherToken)
+		MakeRequest(t, req, http.StatusNotFound)
+
+		req = NewRequestf(t, "DELETE", "/api/v1/user/sessions/%d", sessions[0].ID).AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusNoContent)
+		second.MakeRequest(t, NewRequest(t, "GET", "/user/settings"), http.StatusSeeOther)
+		first.MakeRequest(t, NewRequest(t, "GET", "/user/settings"), http.StatusOK)
+
+		req = NewRequest(t, "DELETE", "/api/v1/user/sessions").AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusNoContent)
+		first.MakeRequest(t, NewRequest(t, "GET", "/user/settings"), http.StatusSeeOther)
+	})
+
+	t.Run("SignOutEverywhere", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		first, second, _ := signInTwice(t)
+
+		req := NewRequestWithValues(t, "POST", "/user/settings/security/sessions/revoke_all", map[string]string{
+			"_csrf": GetCSRF(t, first, "/user/settings/security"),
+		})
+		first.MakeRequest(t, req, http.StatusOK)
+
+		first.MakeRequest(t, NewRequest(t, "GET", "/user/settings"), http.StatusSeeOther)
+		second.MakeRequest(t, NewRequest(t, "GET", "/user/settings"), http.StatusSeeOther)
+		unittest.AssertNotExistsBean(t, &auth_model.UserSession{UID: user.ID})
+	})
+
+	t.Run("PasswordChange", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		first, second, _ := signInTwice(t)
+
+		req := NewRequestWithValues(t, "POST", "/user/settings/account", map[string]string{
+			"_csrf":        GetCSRF(t, first, "/user/settings/account"),
+			"old_password": userPassword,
+			"password":     "password2",
+			"retype":       "password2",
+		})
+		first.MakeRequest(t, req, http.StatusSeeOther)
+
+		// the session which changed the password is kept
+		first.MakeRequest(t, NewRequest(t, "GET", "/user/settings"), http.StatusOK)
+		second.MakeRequest(t, NewRequest(t, "GET", "/user/settings"), http.StatusSeeOther)
+	})
+
+	t.Run("SignOut", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		session := loginUserWithPasswordRemember(t, user.Name, "password2", true)
+		session.MakeRequest(t, NewRequest(t, "GET", "/user/settings"), http.StatusOK)
+		s := unittest.AssertExistsAndLoadBean(t, &auth_model.UserSession{UID: user.ID}, unittest.Cond("auth_token_id > 0"))
+		unittest.AssertExistsAndLoadBean(t, &auth_model.AuthorizationToken{ID: s.AuthTokenID})
+
+		session.MakeRequest(t, NewRequest(t, "POST", "/user/logout"), http.StatusOK)
+
+		unittest.AssertNotExistsBean(t, &auth_model.UserSession{ID: s.ID})
+		unittest.AssertNotExistsBean(t, &auth_model.AuthorizationToken{ID: s.AuthTokenID})
+	})
+}
@@ -119,9 +119,9 @@ export function initAdminCommon() {
   // New authentication
   if (document.querySelector('.admin.new.authentication')) {
     document.getElementById('auth_type')?.addEventListener('change', function () {
-      hideElem('.ldap, .dldap, .smtp, .pam, .oauth2, .has-tls, .search-page-size, .sspi');
+      hideElem('.ldap, .dldap, .smtp, .pam, .oauth2, .has-tls, .search-page-size, .sspi, .saml');
 
-      for (const input of document.querySelectorAll('.ldap input[required], .binddnrequired input[required], .dldap input[required], .smtp input[required], .pam input[required], .oauth2 input[required], .has-tls input[required], .sspi input[required]')) {
+      for (const input of document.querySelectorAll('.ldap input[required], .binddnrequired input[required], .dldap input[required], .smtp input[required], .pam input[required], .oauth2 input[required], .has-tls input[required], .sspi input[required], .saml input[required]')) {
         input.removeAttribute('required');
       }
 
@@ -168,6 +168,12 @@ export function initAdminCommon() {
             input.setAttribute('required', 'required');
           }
           break;
+        case '9': // SAML
+          showElem('.saml');
+          for (const input of document.querySelectorAll('.saml div.required input, .saml div.required textarea')) {
+            input.setAttribute('required', 'required');
+          }
+          break;
       }
       if (authType === '2' || authType === '5') {
         onSecurityProtocolChange();
@@ -46,7 +46,37 @@ export async function initUserAuthWebAuthn() {
   }
 }
 
-async function verifyAssertion(assertedCredential) {
+export function initUserAuthPasskey() {
+  const elPasskey = document.getElementById('signin-passkey');
+  if (!elPasskey) {
+    return;
+  }
+  elPasskey.addEventListener('click', async (e) => {
+    e.preventDefault();
+    if (!detectWebAuthnSupport()) {
+      return;
+    }
+
+    const res = await GET(`${appSubUrl}/user/webauthn/passkey`);
+    if (res.status !== 200) {
+      webAuthnError('unknown');
+      return;
+    }
+    const options = await res.json();
+    options.publicKey.challenge = decodeURLEncodedBase64(options.publicKey.challenge);
+    try {
+      const credential = await navigator.credentials.get({
+        publicKey: options.publicKey,
+      });
+      const remember = elPasskey.closest('form')?.querySelector('input[name=remember]')?.checked;
+      await verifyAssertion(credential, `${appSubUrl}/user/webauthn/passkey${remember ? '?remember=true' : ''}`);
+    } catch (err) {
+      webAuthnError('general', err.message);
+    }
+  });
+}
+
+async function verifyAssertion(assertedCredential, url = `${appSubUrl}/user/webauthn/assertion`) {
   // Move data into Arrays in case it is super long
   const authData = new Uint8Array(assertedCredential.response.authenticatorData);
   const clientDataJSON = new Uint8Array(assertedCredential.response.clientDataJSON);
@@ -54,7 +84,7 @@ async function verifyAssertion(assertedCredential) {
   const sig = new Uint8Array(assertedCredential.response.signature);
   const userHandle = new Uint8Array(assertedCredential.response.userHandle);
 
-  const res = await POST(`${appSubUrl}/user/webauthn/assertion`, {
+  const res = await POST(url, {
     data: {
       id: assertedCredential.id,
       rawId: encodeURLEncodedBase64(rawId),
@@ -159,6 +189,9 @@ async function webAuthnRegisterRequest() {
 
   const formData = new FormData();
   formData.append('name', elNickname.value);
+  if (document.getElementById('passkey')?.checked) {
+    formData.append('passkey', 'true');
+  }
 
   const res = await POST(`${appSubUrl}/user/settings/security/webauthn/request_register`, {
     data: formData,